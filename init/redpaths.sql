@@ -6,6 +6,7 @@ CREATE TABLE redpaths_modules (
                                author VARCHAR(255) NOT NULL,
                                description VARCHAR(255) NOT NULL,
                                attack_id VARCHAR(255) NOT NULL,
+                               mitre_techniques JSONB NOT NULL DEFAULT '[]',
                                loot_path VARCHAR(255) NOT NULL,
                                module_type VARCHAR(100) NOT NULL,
                                execution_metric VARCHAR(100) NOT NULL,
@@ -29,7 +30,8 @@ CREATE TABLE redpaths_changes (
                          changed_at TIMESTAMPTZ NOT NULL,
                          change_type TEXT NOT NULL,
                          changed_by TEXT,
-                         change_reason TEXT
+                         change_reason TEXT,
+                         run_id TEXT
 );
 
 CREATE INDEX idx_redpaths_changes_entity
@@ -38,6 +40,9 @@ CREATE INDEX idx_redpaths_changes_entity
 CREATE INDEX idx_redpaths_changes_time
     ON redpaths_changes(changed_at);
 
+CREATE INDEX idx_redpaths_changes_run
+    ON redpaths_changes(run_id);
+
 
 CREATE TABLE redpaths_modules_metadata (
     project_uid VARCHAR(255),
@@ -92,6 +97,48 @@ CREATE TABLE redpaths_modules_runs
     parameters jsonb
 );
 
+CREATE TABLE redpaths_import_runs
+(
+    import_id VARCHAR PRIMARY KEY,
+    project_uid VARCHAR,
+    source VARCHAR,
+    files jsonb,
+    actor VARCHAR,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    was_successful BOOLEAN,
+    summary jsonb,
+    rolled_back_at TIMESTAMP
+);
+
+CREATE TABLE redpaths_reports
+(
+    report_id VARCHAR PRIMARY KEY,
+    project_uid VARCHAR,
+    title VARCHAR,
+    format VARCHAR,
+    template VARCHAR,
+    file_name VARCHAR,
+    content_type VARCHAR,
+    size BIGINT,
+    created_at TIMESTAMP,
+    created_by VARCHAR,
+    content BYTEA
+);
+
+CREATE TABLE redpaths_saved_queries
+(
+    query_id VARCHAR PRIMARY KEY,
+    project_uid VARCHAR NOT NULL,
+    name VARCHAR NOT NULL,
+    description TEXT,
+    query TEXT NOT NULL,
+    created_at TIMESTAMP,
+    created_by VARCHAR,
+    updated_at TIMESTAMP,
+    UNIQUE (project_uid, name)
+);
+
 CREATE TABLE redpaths_module_last_runs
 (
     module_key VARCHAR,
@@ -16,6 +16,7 @@ const (
 
 	// Common module config keys
 	attackIDKey        = ".attack_id"
+	mitreTechniquesKey = ".mitre_techniques"
 	nameKey            = ".name"
 	versionKey         = ".version"
 	descriptionKey     = ".description"
@@ -114,10 +115,24 @@ func buildDependencyEdges(prefix, actualModuleKey string) []*redpaths.ModuleDepe
 	return dependencyEdges
 }
 
+// buildMitreTechniques reads the ATT&CK technique IDs of a module, invalid
+// IDs are logged and skipped
+func buildMitreTechniques(prefix, key string) []string {
+	techniques, invalid := redpaths.NormalizeMitreTechniques(viper.GetStringSlice(prefix + mitreTechniquesKey))
+	for _, id := range invalid {
+		log.Printf("Ignoring invalid MITRE ATT&CK technique %q of module %s", id, key)
+	}
+	if techniques == nil {
+		techniques = []string{}
+	}
+	return techniques
+}
+
 // buildEnumerationModule creates an enumeration module from config
 func buildEnumerationModule(prefix, key string) *redpaths.Module {
 	return &redpaths.Module{
 		AttackID:        viper.GetString(prefix + attackIDKey),
+		MitreTechniques: buildMitreTechniques(prefix, key),
 		ExecutionMetric: viper.GetString(prefix + executionMetricKey),
 		Description:     viper.GetString(prefix + descriptionKey),
 		Name:            viper.GetString(prefix + nameKey),
@@ -133,6 +148,7 @@ func buildEnumerationModule(prefix, key string) *redpaths.Module {
 func buildAttackModule(prefix, key string) *redpaths.Module {
 	return &redpaths.Module{
 		AttackID:        viper.GetString(prefix + attackIDKey),
+		MitreTechniques: buildMitreTechniques(prefix, key),
 		ExecutionMetric: viper.GetString(prefix + executionMetricKey),
 		Description:     viper.GetString(prefix + descriptionKey),
 		Name:            viper.GetString(prefix + nameKey),
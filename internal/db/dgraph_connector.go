@@ -97,6 +97,10 @@ func isDgraphInitialized(ctx context.Context, dg *dgo.Dgraph) (bool, error) {
 }
 
 func ExecuteInTransaction(ctx context.Context, db *dgo.Dgraph, op func(tx *dgo.Txn) error) error {
+	if shared := sharedTxnFrom(ctx); shared != nil {
+		return op(shared.tx)
+	}
+
 	tx := db.NewTxn()
 	defer tx.Discard(ctx)
 
@@ -111,12 +115,20 @@ func ExecuteInTransaction(ctx context.Context, db *dgo.Dgraph, op func(tx *dgo.T
 }
 
 func ExecuteRead[T any](ctx context.Context, db *dgo.Dgraph, op func(tx *dgo.Txn) (T, error)) (T, error) {
+	if shared := sharedTxnFrom(ctx); shared != nil {
+		return op(shared.tx)
+	}
+
 	tx := db.NewReadOnlyTxn()
 	defer tx.Discard(ctx)
 	return op(tx)
 }
 
 func ExecuteInTransactionWithResult[T any](ctx context.Context, db *dgo.Dgraph, op func(tx *dgo.Txn) (T, error)) (T, error) {
+	if shared := sharedTxnFrom(ctx); shared != nil {
+		return op(shared.tx)
+	}
+
 	var zero T
 	tx := db.NewTxn()
 	defer tx.Discard(ctx)
@@ -132,3 +144,50 @@ func ExecuteInTransactionWithResult[T any](ctx context.Context, db *dgo.Dgraph,
 
 	return result, nil
 }
+
+// ── Shared transactions ─────────────────────────────────────────────────────
+
+type sharedTxnKey struct{}
+
+type sharedTxn struct {
+	tx          *dgo.Txn
+	afterCommit []func()
+}
+
+func sharedTxnFrom(ctx context.Context) *sharedTxn {
+	shared, _ := ctx.Value(sharedTxnKey{}).(*sharedTxn)
+	return shared
+}
+
+// ExecuteShared runs op in one transaction. ExecuteRead and
+// ExecuteInTransaction calls under the context passed to op join it instead
+// of opening their own, so several service calls commit or fail together,
+// e.g. a batch of imported rows.
+func ExecuteShared(ctx context.Context, db *dgo.Dgraph, op func(ctx context.Context) error) error {
+	shared := &sharedTxn{tx: db.NewTxn()}
+	defer shared.tx.Discard(ctx)
+
+	if err := op(context.WithValue(ctx, sharedTxnKey{}, shared)); err != nil {
+		return err
+	}
+
+	if err := shared.tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	for _, fn := range shared.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the shared transaction of ctx is committed and
+// drops it if the transaction fails. Outside of a shared transaction fn runs
+// right away. fn must not use Dgraph through ctx, the transaction is closed
+// by then.
+func AfterCommit(ctx context.Context, fn func()) {
+	if shared := sharedTxnFrom(ctx); shared != nil {
+		shared.afterCommit = append(shared.afterCommit, fn)
+		return
+	}
+	fn()
+}
@@ -36,6 +36,7 @@ type ACLRepository interface {
 	UpdateADRight(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*priv.ADRight, error)
 
 	GetAllRightsByACE(ctx context.Context, tx *dgo.Txn, aceUID string) ([]*res.EntityResult[*priv.ADRight], error)
+	FindADRightByName(ctx context.Context, tx *dgo.Txn, name string) (*priv.ADRight, error)
 	// Finds
 	//FindByDistinguishedNameInDomain(ctx context.Context, tx *dgo.Txn, domainUID string, dsName string) (*active_directory.DirectoryNode, error)
 
@@ -228,6 +229,25 @@ func (r *DgraphACLRepository) GetAllRightsByACE(ctx context.Context, tx *dgo.Txn
 	)
 }
 
+func (r *DgraphACLRepository) FindADRightByName(ctx context.Context, tx *dgo.Txn, name string) (*priv.ADRight, error) {
+	fields := []string{
+		"uid",
+		"ad_right.name",
+		"ad_right.category",
+		"ad_right.risk_level",
+		"dgraph.type",
+	}
+
+	rights, err := dgraphutil2.GetEntityByField[*priv.ADRight](ctx, tx, "ad_right", "ad_right.name", name, fields)
+	if err != nil {
+		return nil, fmt.Errorf("find ad right by name %s: %w", name, err)
+	}
+	if len(rights) == 0 {
+		return nil, nil
+	}
+	return rights[0], nil
+}
+
 func (r *DgraphACLRepository) LinkACLToEntity(ctx context.Context, tx *dgo.Txn, aclUID, entityUID string) error {
 	relationName := "has_acl"
 	log.Printf("AUTO LINKING: acl %s and entity %s", aclUID, entityUID)
@@ -0,0 +1,46 @@
+package active_directory
+
+import (
+	dgraphutil2 "RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"context"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type DelegationRepository interface {
+	CreateDelegationConfig(ctx context.Context, tx *dgo.Txn, config *priv.DelegationConfig, actor string) (*priv.DelegationConfig, error)
+	GetDelegationConfig(ctx context.Context, tx *dgo.Txn, uid string) (*priv.DelegationConfig, error)
+	UpdateDelegationConfig(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*priv.DelegationConfig, error)
+}
+
+type DgraphDelegationRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphDelegationRepository(db *dgo.Dgraph) *DgraphDelegationRepository {
+	return &DgraphDelegationRepository{DB: db}
+}
+
+func (r *DgraphDelegationRepository) CreateDelegationConfig(ctx context.Context, tx *dgo.Txn, config *priv.DelegationConfig, actor string) (*priv.DelegationConfig, error) {
+	dgraphutil2.InitCreateMetadata(&config.RedPathsMetadata, actor)
+	return dgraphutil2.CreateEntity(ctx, tx, "DelegationConfig", config)
+}
+
+func (r *DgraphDelegationRepository) GetDelegationConfig(ctx context.Context, tx *dgo.Txn, uid string) (*priv.DelegationConfig, error) {
+	query := `
+        query DelegationConfig($uid: string) {
+            delegation_config(func: uid($uid)) {
+                uid
+                delegation_config.delegation_type
+                delegation_config.allowed_to_delegate_to
+                dgraph.type
+            }
+        }
+    `
+	return dgraphutil2.GetEntityByUID[priv.DelegationConfig](ctx, tx, uid, "delegation_config", query)
+}
+
+func (r *DgraphDelegationRepository) UpdateDelegationConfig(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*priv.DelegationConfig, error) {
+	return dgraphutil2.UpdateAndGet(ctx, tx, uid, actor, fields, r.GetDelegationConfig)
+}
@@ -6,6 +6,8 @@ import (
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/dgraph-io/dgo/v210"
@@ -20,6 +22,7 @@ type DirectoryNodeRepository interface {
 
 	// Finds
 	FindByDistinguishedNameInDomain(ctx context.Context, tx *dgo.Txn, domainUID string, dsName string) (*active_directory.DirectoryNode, error)
+	GetDomainUID(ctx context.Context, tx *dgo.Txn, directoryNodeUID string) (string, error)
 
 	GetAllByDomainUID(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*active_directory.DirectoryNode], error)
 
@@ -49,6 +52,7 @@ func (r *DgraphDirectoryNodeRepository) GetByProjectUID(ctx context.Context, tx
 		"directory_node.distinguished_name",
 		"directory_node.node_type",
 		"directory_node.is_builtin",
+		"directory_node.blocks_inheritance",
 		"created_at",
 		"modified_at",
 		"dgraph.type",
@@ -88,6 +92,70 @@ func (r *DgraphDirectoryNodeRepository) FindByDistinguishedNameInDomain(ctx cont
 	)
 }
 
+// maxDirectoryDepth begrenzt den Weg von einem DirectoryNode hoch zur Domain.
+const maxDirectoryDepth = 64
+
+// GetDomainUID returns the domain the directory node belongs to. It walks up
+// the contains/parent assertions until it reaches a Domain.
+func (r *DgraphDirectoryNodeRepository) GetDomainUID(ctx context.Context, tx *dgo.Txn, directoryNodeUID string) (string, error) {
+	query := `
+        query Parent($uid: string) {
+            node(func: uid($uid)) {
+                ~assertion.object @filter(eq(assertion.predicate, "contains") OR eq(assertion.predicate, "parent")) {
+                    assertion.subject @filter(type(Domain) OR type(DirectoryNode)) {
+                        uid
+                        dgraph.type
+                    }
+                }
+            }
+        }
+    `
+
+	var result struct {
+		Node []struct {
+			Assertions []struct {
+				Subject []struct {
+					UID   string   `json:"uid"`
+					DType []string `json:"dgraph.type"`
+				} `json:"assertion.subject"`
+			} `json:"~assertion.object"`
+		} `json:"node"`
+	}
+
+	uid := directoryNodeUID
+	for depth := 0; depth < maxDirectoryDepth; depth++ {
+		resp, err := tx.QueryWithVars(ctx, query, map[string]string{"$uid": uid})
+		if err != nil {
+			return "", fmt.Errorf("query parent of directory node %s failed: %w", uid, err)
+		}
+		result.Node = nil
+		if err := json.Unmarshal(resp.Json, &result); err != nil {
+			return "", fmt.Errorf("unmarshal parent of directory node %s failed: %w", uid, err)
+		}
+
+		parent := ""
+		for _, node := range result.Node {
+			for _, a := range node.Assertions {
+				for _, subject := range a.Subject {
+					for _, t := range subject.DType {
+						if t == "Domain" {
+							return subject.UID, nil
+						}
+					}
+					if parent == "" {
+						parent = subject.UID
+					}
+				}
+			}
+		}
+		if parent == "" {
+			break
+		}
+		uid = parent
+	}
+	return "", fmt.Errorf("no domain found for directory node %s", directoryNodeUID)
+}
+
 func NewDgraphDirectoryNodeRepository(db *dgo.Dgraph) *DgraphDirectoryNodeRepository {
 	return &DgraphDirectoryNodeRepository{DB: db}
 }
@@ -129,6 +197,7 @@ var directoryNodeFields = []string{
 	"directory_node.node_type",
 	"directory_node.object_class",
 	"directory_node.is_builtin",
+	"directory_node.blocks_inheritance",
 	"directory_node.is_protected",
 	"dgraph.type",
 }
@@ -172,6 +241,7 @@ func (r *DgraphDirectoryNodeRepository) GetAllByDomainUID(ctx context.Context, t
 		"directory_node.distinguished_name",
 		"directory_node.node_type",
 		"directory_node.is_builtin",
+		"directory_node.blocks_inheritance",
 		"dgraph.type",
 		"discovered_by",
 		"discovered_at",
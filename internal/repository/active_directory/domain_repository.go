@@ -65,6 +65,8 @@ func (r *DgraphDomainRepository) FindByNameInActiveDirectory(ctx context.Context
 		"domain.fsmo_role_owners",
 		"domain.linked_gpos",
 		"domain.default_containers",
+		"domain.risk_score",
+		"domain.risk_reasons",
 		"created_at",
 		"modified_at",
 		"dgraph.type",
@@ -95,6 +97,8 @@ func (r *DgraphDomainRepository) GetAllByProjectUID(ctx context.Context, tx *dgo
 		"domain.fsmo_role_owners",
 		"domain.linked_gpos",
 		"domain.default_containers",
+		"domain.risk_score",
+		"domain.risk_reasons",
 		"created_at",
 		"modified_at",
 		"dgraph.type",
@@ -127,6 +131,8 @@ func (r *DgraphDomainRepository) Get(ctx context.Context, tx *dgo.Txn, uid strin
 				domain.fsmo_role_owners
 				domain.linked_gpos
 				domain.default_containers
+				domain.risk_score
+				domain.risk_reasons
 				discovered_by
 				discovered_at
 				last_seen_at
@@ -150,6 +156,8 @@ func (r *DgraphDomainRepository) GetAllByActiveDirectoryUID(ctx context.Context,
 		"domain.fsmo_role_owners",
 		"domain.linked_gpos",
 		"domain.default_containers",
+		"domain.risk_score",
+		"domain.risk_reasons",
 		"dgraph.type",
 		"discovered_by",
 		"discovered_at",
@@ -5,6 +5,7 @@ import (
 	"RedPaths-server/pkg/model/active_directory/gpo"
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/schema"
 	"context"
 	"fmt"
 	"time"
@@ -25,8 +26,13 @@ type GPORepository interface {
 
 	AddGPOToLink(ctx context.Context, tx *dgo.Txn, linkUID, gpoUID string) error
 
+	//GPOSetting
+	CreateSetting(ctx context.Context, tx *dgo.Txn, setting *gpo.Setting, actor string) (*gpo.Setting, error)
+	GetSettingsByGPOUID(ctx context.Context, tx *dgo.Txn, gpoUID string) ([]*res.EntityResult[*gpo.Setting], error)
+
 	GetGPOResultsByDomain(ctx context.Context, tx *dgo.Txn, domainUID string) (*res.GPOQueryResult, error)
 	ExistsGPOByNameInContainer(ctx context.Context, tx *dgo.Txn, domainUID, gpoName string) (bool, string, error)
+	FindByNameInDomain(ctx context.Context, tx *dgo.Txn, domainUID, gpoName string) (*gpo.GPO, error)
 
 	GetGPOLinksWithGPO(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*gpo.Link], error)
 	FindGPOLinkByGPOName(ctx context.Context, tx *dgo.Txn, domainUID, gpoName string) (*res.EntityResult[*gpo.Link], error)
@@ -56,9 +62,9 @@ func (d *DgraphGPORepository) GetGPOLinksWithGPO(
 	linkFields := []string{
 		"uid",
 		"dgraph.type",
-		"gpo.link_order",
-		"gpo.is_enforced",
-		"gpo.is_enabled",
+		"gpo_link.link_order",
+		"gpo_link.is_enforced",
+		"gpo_link.is_enabled",
 		"created_at",
 		"modified_at",
 		"discovered_at",
@@ -233,6 +239,25 @@ func (d *DgraphGPORepository) ExistsGPOByNameInContainer(
 	return exists, gpoUID, nil
 }
 
+// FindByNameInDomain returns the GPO of the domain with the given name
+// (Domain --has_gpo--> GPO), nil if the domain does not know it.
+func (d *DgraphGPORepository) FindByNameInDomain(ctx context.Context, tx *dgo.Txn, domainUID, gpoName string) (*gpo.GPO, error) {
+	result, err := dgraphutil2.FindEntityByFieldViaAssertion[gpo.GPO](
+		ctx,
+		tx,
+		domainUID,
+		core.PredicateHasGPO,
+		"GPO",
+		"gpo.name",
+		gpoName,
+		[]string{"uid", "gpo.name", "gpo.description", "dgraph.type"},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error finding GPO %s in domain %s: %w", gpoName, domainUID, err)
+	}
+	return result, nil
+}
+
 func (d *DgraphGPORepository) CreateGPO(ctx context.Context, tx *dgo.Txn, gpo *gpo.GPO, actor string) (*gpo.GPO, error) {
 	return dgraphutil2.CreateEntity(ctx, tx, "GPO", gpo)
 }
@@ -289,13 +314,59 @@ func (d *DgraphGPORepository) GetGPOResultsByDomain(ctx context.Context, tx *dgo
 }
 
 func (d *DgraphGPORepository) GetGPO(ctx context.Context, tx *dgo.Txn, uid string) (*gpo.GPO, error) {
-	//TODO implement me
-	panic("implement me")
+	query := `
+        query GPO($uid: string) {
+            gpo(func: uid($uid)) {
+                uid
+                gpo.name
+                gpo.description
+                created_at
+                modified_at
+                dgraph.type
+            }
+        }
+    `
+	return dgraphutil2.GetEntityByUID[gpo.GPO](ctx, tx, uid, "gpo", query)
 }
 
 func (d *DgraphGPORepository) GetLink(ctx context.Context, tx *dgo.Txn, uid string) (*gpo.Link, error) {
-	//TODO implement me
-	panic("implement me")
+	query := `
+        query GPOLink($uid: string) {
+            link(func: uid($uid)) {
+                uid
+                gpo_link.link_order
+                gpo_link.is_enforced
+                gpo_link.is_enabled
+                created_at
+                modified_at
+                dgraph.type
+            }
+        }
+    `
+	return dgraphutil2.GetEntityByUID[gpo.Link](ctx, tx, uid, "link", query)
+}
+
+func (d *DgraphGPORepository) CreateSetting(ctx context.Context, tx *dgo.Txn, setting *gpo.Setting, actor string) (*gpo.Setting, error) {
+	dgraphutil2.InitCreateMetadata(&setting.RedPathsMetadata, actor)
+	return dgraphutil2.CreateEntity(ctx, tx, "GPOSetting", setting)
+}
+
+// GetSettingsByGPOUID lädt die Settings einer GPO (GPO --contains--> GPOSetting)
+func (d *DgraphGPORepository) GetSettingsByGPOUID(ctx context.Context, tx *dgo.Txn, gpoUID string) ([]*res.EntityResult[*gpo.Setting], error) {
+	fields, err := schema.DetailFields("GPOSetting")
+	if err != nil {
+		return nil, fmt.Errorf("GetSettingsByGPOUID: %w", err)
+	}
+
+	return dgraphutil2.GetEntitiesWithAssertions[*gpo.Setting](
+		ctx,
+		tx,
+		gpoUID,
+		core.PredicateContains,
+		"GPOSetting",
+		fields,
+		"getGPOSettings",
+	)
 }
 
 func (d *DgraphGPORepository) UpdateGPO(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*gpo.GPO, error) {
@@ -0,0 +1,105 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"context"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type GroupRepository interface {
+	Create(ctx context.Context, tx *dgo.Txn, incomingGroup *active_directory.Group, actor string) (*active_directory.Group, error)
+	Get(ctx context.Context, tx *dgo.Txn, uid string) (*active_directory.Group, error)
+	UpdateGroup(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*active_directory.Group, error)
+	FindExisting(ctx context.Context, tx *dgo.Txn, projectUID string, group *active_directory.Group) (*dgraph.ExistenceResult[*active_directory.Group], error)
+}
+
+type DgraphGroupRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphGroupRepository(db *dgo.Dgraph) *DgraphGroupRepository {
+	return &DgraphGroupRepository{DB: db}
+}
+
+func (r *DgraphGroupRepository) Create(ctx context.Context, tx *dgo.Txn, incomingGroup *active_directory.Group, actor string) (*active_directory.Group, error) {
+	dgraph.InitCreateMetadata(&incomingGroup.RedPathsMetadata, actor)
+	return dgraph.CreateEntity(ctx, tx, "Group", incomingGroup)
+}
+
+func (r *DgraphGroupRepository) Get(ctx context.Context, tx *dgo.Txn, uid string) (*active_directory.Group, error) {
+	query := `
+        query Group($uid: string) {
+            group(func: uid($uid)) {
+                uid
+                security_principal.name
+                security_principal.sid
+                security_principal.description
+                group.group_scope
+                group.group_type
+                group.is_privileged
+                group.is_builtin
+                group.risk_score
+                group.risk_reasons
+                dgraph.type
+            }
+        }
+    `
+	return dgraph.GetEntityByUID[active_directory.Group](ctx, tx, uid, "group", query)
+}
+
+func (r *DgraphGroupRepository) UpdateGroup(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*active_directory.Group, error) {
+	return dgraph.UpdateAndGet(ctx, tx, uid, actor, fields, r.Get)
+}
+
+var groupHierarchyHops = []dgraph.HopConfig{
+	{Predicate: core.PredicateHasActiveDirectory},
+	{Predicate: core.PredicateHasDomain, ObjectType: "Domain"},
+	{Predicate: core.PredicateContains, ObjectType: "DirectoryNode"},
+	{Predicate: core.PredicateContains, ObjectType: "Group"},
+}
+
+var groupFields = []string{
+	"uid",
+	"security_principal.name",
+	"security_principal.sid",
+	"security_principal.description",
+	"group.group_scope",
+	"group.group_type",
+	"group.is_privileged",
+	"group.is_builtin",
+	"dgraph.type",
+}
+
+func BuildGroupFilter(group *active_directory.Group) []dgraph.UniqueFieldFilter {
+	return []dgraph.UniqueFieldFilter{
+		{Field: "security_principal.sid", Value: group.SID},
+		{Field: "security_principal.name", Value: group.Name},
+	}
+}
+
+// FindExisting performs a two-phase existence check for a Group.
+//
+// Phase 1: Searches via the full AD hierarchy (Project → AD → Domain → DirectoryNode → Group).
+// Phase 2: Falls back to direct project-level search for orphaned groups.
+func (r *DgraphGroupRepository) FindExisting(
+	ctx context.Context,
+	tx *dgo.Txn,
+	projectUID string,
+	group *active_directory.Group,
+) (*dgraph.ExistenceResult[*active_directory.Group], error) {
+
+	filters := BuildGroupFilter(group)
+
+	return dgraph.CheckEntityExists[*active_directory.Group](
+		ctx, tx,
+		projectUID,
+		"Group",
+		filters,
+		dgraph.FilterModeOR,
+		groupFields,
+		groupHierarchyHops,
+	)
+}
@@ -52,6 +52,8 @@ var hostFields = []string{
 	"host.distinguished_name",
 	"host.operating_system",
 	"host.operating_system_version",
+	"host.risk_score",
+	"host.risk_reasons",
 	"created_at",
 	"modified_at",
 	"dgraph.type",
@@ -75,6 +77,8 @@ func (r *DraphHostRepository) Get(ctx context.Context, tx *dgo.Txn, uid string)
 				host.distinguished_name
 				host.operating_system
 				host.operating_system_version
+				host.risk_score
+				host.risk_reasons
             }
         }
     `
@@ -107,6 +111,8 @@ func (r *DraphHostRepository) GetByProjectIncludingDomains(ctx context.Context,
 		"host.dns_host_name",
 		"host.operating_system",
 		"host.operating_system_version",
+		"host.risk_score",
+		"host.risk_reasons",
 		"host.last_logon_timestamp",
 		"host.user_account_control",
 		"created_at",
@@ -151,6 +157,8 @@ func (r *DraphHostRepository) GetAllByDomainUID(ctx context.Context, tx *dgo.Txn
 		"host.dns_host_name",
 		"host.operating_system",
 		"host.operating_system_version",
+		"host.risk_score",
+		"host.risk_reasons",
 		"host.last_logon_timestamp",
 		"host.user_account_control",
 		"created_at",
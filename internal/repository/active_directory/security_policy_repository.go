@@ -0,0 +1,77 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/repository/util/dgraph"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/schema"
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type SecurityPolicyRepository interface {
+	Create(ctx context.Context, tx *dgo.Txn, policy *rpad.SecurityPolicy, actor string) (*rpad.SecurityPolicy, error)
+	Get(ctx context.Context, tx *dgo.Txn, uid string) (*rpad.SecurityPolicy, error)
+	UpdateSecurityPolicy(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*rpad.SecurityPolicy, error)
+	GetByDomainUID(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*rpad.SecurityPolicy], error)
+}
+
+type DgraphSecurityPolicyRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphSecurityPolicyRepository(db *dgo.Dgraph) *DgraphSecurityPolicyRepository {
+	return &DgraphSecurityPolicyRepository{DB: db}
+}
+
+func (r *DgraphSecurityPolicyRepository) Create(ctx context.Context, tx *dgo.Txn, policy *rpad.SecurityPolicy, actor string) (*rpad.SecurityPolicy, error) {
+	dgraph.InitCreateMetadata(&policy.RedPathsMetadata, actor)
+	return dgraph.CreateEntity(ctx, tx, "SecurityPolicy", policy)
+}
+
+func (r *DgraphSecurityPolicyRepository) Get(ctx context.Context, tx *dgo.Txn, uid string) (*rpad.SecurityPolicy, error) {
+	query := `
+        query SecurityPolicy($uid: string) {
+            securityPolicy(func: uid($uid)) {
+                uid
+                security_policy.min_pwd_length
+                security_policy.pwd_history_length
+                security_policy.lockout_threshold
+                security_policy.lockout_duration
+                security_policy.lockout_window
+                security_policy.max_pwd_age
+                security_policy.min_pwd_age
+                security_policy.pwd_complexity
+                security_policy.reversible_encryption
+                created_at
+                modified_at
+                dgraph.type
+            }
+        }
+    `
+	return dgraph.GetEntityByUID[rpad.SecurityPolicy](ctx, tx, uid, "securityPolicy", query)
+}
+
+func (r *DgraphSecurityPolicyRepository) UpdateSecurityPolicy(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*rpad.SecurityPolicy, error) {
+	return dgraph.UpdateAndGet(ctx, tx, uid, actor, fields, r.Get)
+}
+
+func (r *DgraphSecurityPolicyRepository) GetByDomainUID(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*rpad.SecurityPolicy], error) {
+	fields, err := schema.DetailFields("SecurityPolicy")
+	if err != nil {
+		return nil, fmt.Errorf("GetByDomainUID: %w", err)
+	}
+
+	return dgraph.GetEntitiesWithAssertions[*rpad.SecurityPolicy](
+		ctx,
+		tx,
+		domainUID,
+		core.PredicateHasSecurityPolicy,
+		"SecurityPolicy",
+		fields,
+		"getDomainSecurityPolicy",
+	)
+}
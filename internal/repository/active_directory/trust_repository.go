@@ -1 +1,72 @@
 package active_directory
+
+import (
+	"RedPaths-server/internal/repository/util/dgraph"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/schema"
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type TrustRepository interface {
+	Create(ctx context.Context, tx *dgo.Txn, trust *rpad.Trust, actor string) (*rpad.Trust, error)
+	Get(ctx context.Context, tx *dgo.Txn, uid string) (*rpad.Trust, error)
+	UpdateTrust(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*rpad.Trust, error)
+	GetByDomainUID(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*rpad.Trust], error)
+}
+
+type DgraphTrustRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphTrustRepository(db *dgo.Dgraph) *DgraphTrustRepository {
+	return &DgraphTrustRepository{DB: db}
+}
+
+func (r *DgraphTrustRepository) Create(ctx context.Context, tx *dgo.Txn, trust *rpad.Trust, actor string) (*rpad.Trust, error) {
+	dgraph.InitCreateMetadata(&trust.RedPathsMetadata, actor)
+	return dgraph.CreateEntity(ctx, tx, "Trust", trust)
+}
+
+func (r *DgraphTrustRepository) Get(ctx context.Context, tx *dgo.Txn, uid string) (*rpad.Trust, error) {
+	query := `
+        query Trust($uid: string) {
+            trust(func: uid($uid)) {
+                uid
+                trust.trust_type
+                trust.direction
+                trust.is_transitive
+                trust.sid_filtering_enabled
+                created_at
+                modified_at
+                dgraph.type
+            }
+        }
+    `
+	return dgraph.GetEntityByUID[rpad.Trust](ctx, tx, uid, "trust", query)
+}
+
+func (r *DgraphTrustRepository) UpdateTrust(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*rpad.Trust, error) {
+	return dgraph.UpdateAndGet(ctx, tx, uid, actor, fields, r.Get)
+}
+
+func (r *DgraphTrustRepository) GetByDomainUID(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*rpad.Trust], error) {
+	fields, err := schema.DetailFields("Trust")
+	if err != nil {
+		return nil, fmt.Errorf("GetByDomainUID: %w", err)
+	}
+
+	return dgraph.GetEntitiesWithAssertions[*rpad.Trust](
+		ctx,
+		tx,
+		domainUID,
+		core.PredicateHasTrust,
+		"Trust",
+		fields,
+		"getDomainTrusts",
+	)
+}
@@ -34,8 +34,29 @@ type DraphUserRepository struct {
 }
 
 func (r *DraphUserRepository) Get(ctx context.Context, tx *dgo.Txn, uid string) (*active_directory.User, error) {
-	//TODO implement me
-	panic("implement me")
+	query := `
+        query User($uid: string) {
+            user(func: uid($uid)) {
+                uid
+                security_principal.name
+                security_principal.sid
+                security_principal.description
+                user.sam_account_name
+                user.upn
+                user.is_disabled
+                user.is_locked
+                user.is_domain_admin
+                user.is_local_admin
+                user.is_privileged
+                user.can_dcsync
+                user.can_rdp
+                user.risk_score
+                user.risk_reasons
+                dgraph.type
+            }
+        }
+    `
+	return dgraph.GetEntityByUID[active_directory.User](ctx, tx, uid, "user", query)
 }
 
 func (r *DraphUserRepository) FindByUID(ctx context.Context, uid string) (*active_directory.User, error) {
@@ -295,6 +316,9 @@ var userFields = []string{
 	"user.asrep_roastable",
 	"user.is_domain_admin",
 	"user.is_local_admin",
+	"user.is_privileged",
+	"user.can_dcsync",
+	"user.can_rdp",
 	"dgraph.type",
 }
 
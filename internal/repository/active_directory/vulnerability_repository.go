@@ -0,0 +1,88 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/schema"
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type VulnerabilityRepository interface {
+	Create(ctx context.Context, tx *dgo.Txn, vulnerability *model.Vulnerability, actor string) (*model.Vulnerability, error)
+	Get(ctx context.Context, tx *dgo.Txn, uid string) (*model.Vulnerability, error)
+	UpdateVulnerability(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*model.Vulnerability, error)
+	GetByHostUID(ctx context.Context, tx *dgo.Txn, hostUID string) ([]*res.EntityResult[*model.Vulnerability], error)
+	GetBySubjectUID(ctx context.Context, tx *dgo.Txn, subjectUID string) ([]*res.EntityResult[*model.Vulnerability], error)
+}
+
+type DgraphVulnerabilityRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphVulnerabilityRepository(db *dgo.Dgraph) *DgraphVulnerabilityRepository {
+	return &DgraphVulnerabilityRepository{DB: db}
+}
+
+func (r *DgraphVulnerabilityRepository) Create(ctx context.Context, tx *dgo.Txn, vulnerability *model.Vulnerability, actor string) (*model.Vulnerability, error) {
+	dgraph.InitCreateMetadata(&vulnerability.RedPathsMetadata, actor)
+	return dgraph.CreateEntity(ctx, tx, "Vulnerability", vulnerability)
+}
+
+func (r *DgraphVulnerabilityRepository) Get(ctx context.Context, tx *dgo.Txn, uid string) (*model.Vulnerability, error) {
+	query := `
+        query Vulnerability($uid: string) {
+            vulnerability(func: uid($uid)) {
+                uid
+                vulnerability.name
+                vulnerability.cves
+                vulnerability.cvss
+                vulnerability.cvss_vector
+                vulnerability.severity
+                vulnerability.plugin_id
+                vulnerability.scanner
+                vulnerability.port
+                vulnerability.protocol
+                vulnerability.evidence
+                vulnerability.description
+                vulnerability.solution
+                vulnerability.exploit_available
+                created_at
+                modified_at
+                dgraph.type
+            }
+        }
+    `
+	return dgraph.GetEntityByUID[model.Vulnerability](ctx, tx, uid, "vulnerability", query)
+}
+
+func (r *DgraphVulnerabilityRepository) UpdateVulnerability(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*model.Vulnerability, error) {
+	return dgraph.UpdateAndGet(ctx, tx, uid, actor, fields, r.Get)
+}
+
+func (r *DgraphVulnerabilityRepository) GetByHostUID(ctx context.Context, tx *dgo.Txn, hostUID string) ([]*res.EntityResult[*model.Vulnerability], error) {
+	return r.GetBySubjectUID(ctx, tx, hostUID)
+}
+
+// GetBySubjectUID returns the findings linked via has_vulnerability to a
+// host, service or domain.
+func (r *DgraphVulnerabilityRepository) GetBySubjectUID(ctx context.Context, tx *dgo.Txn, subjectUID string) ([]*res.EntityResult[*model.Vulnerability], error) {
+	fields, err := schema.DetailFields("Vulnerability")
+	if err != nil {
+		return nil, fmt.Errorf("GetBySubjectUID: %w", err)
+	}
+
+	return dgraph.GetEntitiesWithAssertions[*model.Vulnerability](
+		ctx,
+		tx,
+		subjectUID,
+		core.PredicateHasVulnerability,
+		"Vulnerability",
+		fields,
+		"getSubjectVulnerabilities",
+	)
+}
@@ -2,6 +2,7 @@ package changes
 
 import (
 	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/model/utils/assertion"
 	"context"
 	"fmt"
 	"strings"
@@ -36,6 +37,8 @@ type RedPathsChangeRepository interface {
 	Save(ctx context.Context, tx *gorm.DB, change *history.Change) error
 	GetByEntity(ctx context.Context, tx *gorm.DB, entityType, entityUID string) ([]*history.Change, error)
 	GetByEntityWithOptions(ctx context.Context, tx *gorm.DB, entityType, entityUID string, opts *ChangeQueryOptions) (*PaginatedChangeResult, error)
+	GetByRun(ctx context.Context, tx *gorm.DB, runID string) ([]*history.Change, error)
+	GetByEntityUIDs(ctx context.Context, tx *gorm.DB, entityUIDs []string, limit int) ([]*history.Change, error)
 }
 
 type PostgresRedPathsChangesRepository struct {
@@ -62,6 +65,10 @@ func (r *PostgresRedPathsChangesRepository) Save(
 		change.ChangedAt = time.Now().UTC()
 	}
 
+	if change.RunID == "" {
+		change.RunID = assertion.RunID(ctx)
+	}
+
 	return tx.WithContext(ctx).Table(TableChanges).Create(change).Error
 }
 
@@ -85,6 +92,55 @@ func (r *PostgresRedPathsChangesRepository) GetByEntity(
 	return result, nil
 }
 
+// GetByRun returns the changes written by an import or module run, newest first.
+func (r *PostgresRedPathsChangesRepository) GetByRun(
+	ctx context.Context,
+	tx *gorm.DB,
+	runID string,
+) ([]*history.Change, error) {
+	var result []*history.Change
+
+	err := tx.WithContext(ctx).
+		Table(TableChanges).
+		Where("run_id = ?", runID).
+		Order("changed_at DESC").
+		Find(&result).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching changes of run %s failed: %w", runID, err)
+	}
+
+	return result, nil
+}
+
+// GetByEntityUIDs returns the newest changes of the given entities, at most
+// limit (0 = all).
+func (r *PostgresRedPathsChangesRepository) GetByEntityUIDs(
+	ctx context.Context,
+	tx *gorm.DB,
+	entityUIDs []string,
+	limit int,
+) ([]*history.Change, error) {
+	var result []*history.Change
+	if len(entityUIDs) == 0 {
+		return result, nil
+	}
+
+	q := tx.WithContext(ctx).
+		Table(TableChanges).
+		Where("entity_uid IN ?", entityUIDs).
+		Order("changed_at DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if err := q.Find(&result).Error; err != nil {
+		return nil, fmt.Errorf("fetching changes of %d entities failed: %w", len(entityUIDs), err)
+	}
+
+	return result, nil
+}
+
 func (r *PostgresRedPathsChangesRepository) GetByEntityWithOptions(
 	ctx context.Context,
 	tx *gorm.DB,
@@ -18,7 +18,9 @@ const (
 
 type RedPathsLogRepository interface {
 	CreateLogEntry(ctx context.Context, tx *gorm.DB, event *redpaths.LogEntry) error
+	CreateLogEntries(ctx context.Context, tx *gorm.DB, entries []*redpaths.LogEntry) error
 	GetLogsByProject(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.LogEntry, error)
+	GetProjectOnlyLogs(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.LogEntry, error)
 	GetLogsByRun(ctx context.Context, tx *gorm.DB, runUID string) ([]*redpaths.LogEntry, error)
 	GetLogsByModule(ctx context.Context, tx *gorm.DB, moduleKey string) ([]*redpaths.LogEntry, error)
 	GetEventTypeSet(ctx context.Context, tx *gorm.DB, projectUID string) ([]*string, error)
@@ -43,6 +45,18 @@ func (r *PostgresRedPathsLogRepository) CreateLogEntry(ctx context.Context, tx *
 	return nil
 }
 
+// CreateLogEntries inserts the entries in batches, ids are assigned by the database.
+func (r *PostgresRedPathsLogRepository) CreateLogEntries(ctx context.Context, tx *gorm.DB, entries []*redpaths.LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	result := tx.WithContext(ctx).Table(TableModuleLogs).CreateInBatches(entries, 500)
+	if result.Error != nil {
+		return fmt.Errorf("create of %d log entries failed: %w", len(entries), result.Error)
+	}
+	return nil
+}
+
 func (r *PostgresRedPathsLogRepository) GetLogsByProject(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.LogEntry, error) {
 	var logs []*redpaths.LogEntry
 
@@ -60,6 +74,23 @@ func (r *PostgresRedPathsLogRepository) GetLogsByProject(ctx context.Context, tx
 	return logs, nil
 }
 
+// GetProjectOnlyLogs returns the logs of the project without the SYSTEM
+// logs, oldest first.
+func (r *PostgresRedPathsLogRepository) GetProjectOnlyLogs(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.LogEntry, error) {
+	var logs []*redpaths.LogEntry
+
+	result := tx.WithContext(ctx).
+		Table(TableModuleLogs).
+		Where("project_uid = ?", projectUID).
+		Order("id ASC").
+		Find(&logs)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("fetching logs of project %s failed: %w", projectUID, result.Error)
+	}
+	return logs, nil
+}
+
 func (r *PostgresRedPathsLogRepository) GetLogsByRun(ctx context.Context, tx *gorm.DB, runUID string) ([]*redpaths.LogEntry, error) {
 	var logs []*redpaths.LogEntry
 
@@ -0,0 +1,54 @@
+package changes
+
+import (
+	"RedPaths-server/pkg/model/redpaths/history"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TableNodeSnapshots = "redpaths_node_snapshot"
+)
+
+type RedPathsSnapshotRepository interface {
+	GetByNodeUIDs(ctx context.Context, tx *gorm.DB, nodeUIDs []string) ([]*history.NodeSnapshot, error)
+	Create(ctx context.Context, tx *gorm.DB, snapshots []*history.NodeSnapshot) error
+}
+
+type PostgresRedPathsSnapshotRepository struct{}
+
+func NewPostgresRedPathsSnapshotRepository() *PostgresRedPathsSnapshotRepository {
+	return &PostgresRedPathsSnapshotRepository{}
+}
+
+func (r *PostgresRedPathsSnapshotRepository) GetByNodeUIDs(ctx context.Context, tx *gorm.DB, nodeUIDs []string) ([]*history.NodeSnapshot, error) {
+	var snapshots []*history.NodeSnapshot
+	if len(nodeUIDs) == 0 {
+		return snapshots, nil
+	}
+
+	err := tx.WithContext(ctx).
+		Table(TableNodeSnapshots).
+		Where("node_uid IN ?", nodeUIDs).
+		Find(&snapshots).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching snapshots of %d nodes failed: %w", len(nodeUIDs), err)
+	}
+
+	return snapshots, nil
+}
+
+func (r *PostgresRedPathsSnapshotRepository) Create(ctx context.Context, tx *gorm.DB, snapshots []*history.NodeSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	if err := tx.WithContext(ctx).Table(TableNodeSnapshots).CreateInBatches(snapshots, 500).Error; err != nil {
+		return fmt.Errorf("create of %d snapshots failed: %w", len(snapshots), err)
+	}
+
+	return nil
+}
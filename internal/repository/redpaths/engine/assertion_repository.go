@@ -4,6 +4,7 @@ import (
 	"RedPaths-server/internal/repository/util/dgraph"
 	"RedPaths-server/pkg/model/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -37,6 +38,9 @@ type AssertionRepository interface {
 
 	// Filtern nach Source
 	GetAssertionsBySource(ctx context.Context, tx *dgo.Txn, entityUID string, source string) ([]*core.Assertion, error)
+
+	// Exakte Kante Subject -[predicate]-> Object, nil wenn nicht vorhanden
+	FindLink(ctx context.Context, tx *dgo.Txn, subjectUID, objectUID string, predicate core.Predicate) (*core.Assertion, error)
 }
 
 type DgraphAssertionRepository struct {
@@ -49,18 +53,64 @@ func (r *DgraphAssertionRepository) GetAssertionsForEntity(ctx context.Context,
 }
 
 func (r *DgraphAssertionRepository) GetAssertionsWhereSubject(ctx context.Context, tx *dgo.Txn, entityUID string) ([]*core.Assertion, error) {
-	//TODO implement me
-	panic("implement me")
+	return r.GetBySubjectUID(ctx, tx, entityUID)
 }
 
 func (r *DgraphAssertionRepository) GetAssertionsWhereObject(ctx context.Context, tx *dgo.Txn, entityUID string) ([]*core.Assertion, error) {
-	//TODO implement me
-	panic("implement me")
+	return r.GetByObjectUID(ctx, tx, entityUID)
 }
 
 func (r *DgraphAssertionRepository) GetAssertionsByPredicate(ctx context.Context, tx *dgo.Txn, entityUID string, predicate core.Predicate) ([]*core.Assertion, error) {
-	//TODO implement me
-	panic("implement me")
+	assertions, err := r.GetBySubjectUID(ctx, tx, entityUID)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*core.Assertion, 0, len(assertions))
+	for _, a := range assertions {
+		if a.Predicate == predicate {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered, nil
+}
+
+func (r *DgraphAssertionRepository) FindLink(ctx context.Context, tx *dgo.Txn, subjectUID, objectUID string, predicate core.Predicate) (*core.Assertion, error) {
+	query := `
+		query FindLink($subject: string, $object: string, $predicate: string) {
+			link(func: eq(assertion.predicate, $predicate), first: 1)
+				@filter(uid_in(assertion.subject, $subject) AND uid_in(assertion.object, $object)) {
+				uid
+				assertion.predicate
+				assertion.method
+				assertion.source
+				assertion.confidence
+				assertion.status
+				assertion.timestamp
+				assertion.subject { uid }
+				assertion.object { uid }
+			}
+		}`
+
+	resp, err := tx.QueryWithVars(ctx, query, map[string]string{
+		"$subject":   subjectUID,
+		"$object":    objectUID,
+		"$predicate": string(predicate),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find link query failed: %w", err)
+	}
+
+	var result struct {
+		Link []*core.Assertion `json:"link"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal link failed: %w", err)
+	}
+	if len(result.Link) == 0 {
+		return nil, nil
+	}
+	return result.Link[0], nil
 }
 
 func (r *DgraphAssertionRepository) GetAssertionsByStatus(ctx context.Context, tx *dgo.Txn, entityUID string, status string) ([]*core.Assertion, error) {
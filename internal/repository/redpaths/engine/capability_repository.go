@@ -56,6 +56,7 @@ func (r *DgraphCapabilityRepository) Get(ctx context.Context, tx *dgo.Txn, uid s
                 uid
                 capability.name
 				capability.scope
+				capability.precondition
             }
         }
     `
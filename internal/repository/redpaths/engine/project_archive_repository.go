@@ -0,0 +1,290 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// legacyEdges are uid predicates outside of the dgraph types, which
+// expand(_all_) does not return.
+var legacyEdges = []string{"has_target"}
+
+// ArchiveNode is a node of a project archive with all its predicates. Edges
+// reference other archive nodes by their original UID.
+type ArchiveNode struct {
+	UID    string                 `json:"uid"`
+	Types  []string               `json:"types"`
+	Values map[string]interface{} `json:"values"`
+	Edges  map[string][]string    `json:"edges,omitempty"`
+}
+
+type ProjectArchiveRepository interface {
+	// Alle vom Projekt erreichbaren Knoten inkl. Assertions (auch invalidierte)
+	Dump(ctx context.Context, tx *dgo.Txn, projectUID string) ([]*ArchiveNode, error)
+	// Legt die Knoten neu an und liefert die Zuordnung alte UID → neue UID
+	Restore(ctx context.Context, tx *dgo.Txn, nodes []*ArchiveNode) (map[string]string, error)
+}
+
+type DgraphProjectArchiveRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphProjectArchiveRepository(db *dgo.Dgraph) *DgraphProjectArchiveRepository {
+	return &DgraphProjectArchiveRepository{DB: db}
+}
+
+// Dump walks the graph breadth first along the assertions a node is subject
+// of and along all uid predicates, so assertions are archived as nodes of
+// their own.
+func (r *DgraphProjectArchiveRepository) Dump(ctx context.Context, tx *dgo.Txn, projectUID string) ([]*ArchiveNode, error) {
+	if !strings.HasPrefix(projectUID, "0x") {
+		return nil, fmt.Errorf("invalid project uid %q", projectUID)
+	}
+
+	var legacy strings.Builder
+	for _, pred := range legacyEdges {
+		legacy.WriteString(pred + " { uid }\n")
+	}
+	query := fmt.Sprintf(`
+		query Dump($uids: string) {
+			nodes(func: uid($uids)) {
+				uid
+				dgraph.type
+				expand(_all_) { uid }
+				%s
+				subjectOf: ~assertion.subject { uid }
+			}
+		}`, legacy.String())
+
+	var nodes []*ArchiveNode
+	seen := map[string]bool{projectUID: true}
+	frontier := []string{projectUID}
+	visit := func(uid string, next *[]string) {
+		if !seen[uid] {
+			seen[uid] = true
+			*next = append(*next, uid)
+		}
+	}
+
+	for len(frontier) > 0 {
+		var next []string
+
+		for start := 0; start < len(frontier); start += uidBatchSize {
+			end := min(start+uidBatchSize, len(frontier))
+
+			resp, err := tx.QueryWithVars(ctx, query, map[string]string{
+				"$uids": strings.Join(frontier[start:end], ","),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("project dump query failed: %w", err)
+			}
+
+			var result struct {
+				Nodes []map[string]json.RawMessage `json:"nodes"`
+			}
+			if err := json.Unmarshal(resp.Json, &result); err != nil {
+				return nil, fmt.Errorf("unmarshal project dump failed: %w", err)
+			}
+
+			for _, raw := range result.Nodes {
+				node, subjectOf, err := decodeArchiveNode(raw)
+				if err != nil {
+					return nil, err
+				}
+				// uid without predicates (deleted node)
+				if len(node.Types) == 0 && len(node.Values) == 0 {
+					continue
+				}
+				nodes = append(nodes, node)
+
+				for _, uid := range subjectOf {
+					visit(uid, &next)
+				}
+				for _, targets := range node.Edges {
+					for _, uid := range targets {
+						visit(uid, &next)
+					}
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return nodes, nil
+}
+
+// decodeArchiveNode splits a dump result into scalar values and uid edges.
+func decodeArchiveNode(raw map[string]json.RawMessage) (*ArchiveNode, []string, error) {
+	node := &ArchiveNode{
+		Values: make(map[string]interface{}, len(raw)),
+		Edges:  make(map[string][]string),
+	}
+	var subjectOf []string
+
+	for key, value := range raw {
+		switch key {
+		case "uid":
+			if err := json.Unmarshal(value, &node.UID); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal uid failed: %w", err)
+			}
+		case "dgraph.type":
+			if err := json.Unmarshal(value, &node.Types); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal dgraph.type failed: %w", err)
+			}
+		case "subjectOf":
+			uids, err := decodeUIDRefs(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unmarshal assertions failed: %w", err)
+			}
+			subjectOf = uids
+		default:
+			var v interface{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal %s failed: %w", key, err)
+			}
+			if isScalarValue(v) {
+				node.Values[key] = v
+				continue
+			}
+			uids, err := decodeUIDRefs(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unmarshal %s failed: %w", key, err)
+			}
+			if len(uids) > 0 {
+				node.Edges[key] = uids
+			}
+		}
+	}
+	return node, subjectOf, nil
+}
+
+// decodeUIDRefs reads {"uid": ...} or [{"uid": ...}, ...].
+func decodeUIDRefs(value json.RawMessage) ([]string, error) {
+	type ref struct {
+		UID string `json:"uid"`
+	}
+	var refs []ref
+	if err := json.Unmarshal(value, &refs); err != nil {
+		var single ref
+		if err := json.Unmarshal(value, &single); err != nil {
+			return nil, err
+		}
+		refs = []ref{single}
+	}
+
+	uids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		if r.UID != "" {
+			uids = append(uids, r.UID)
+		}
+	}
+	return uids, nil
+}
+
+// Restore creates the nodes in two passes: first the nodes with their
+// values, which assigns the new UIDs, then the edges between them. Blank
+// nodes only resolve within one mutation, so this keeps the batches
+// independent of each other. Edges to nodes outside the archive are dropped.
+func (r *DgraphProjectArchiveRepository) Restore(ctx context.Context, tx *dgo.Txn, nodes []*ArchiveNode) (map[string]string, error) {
+	uidMap := make(map[string]string, len(nodes))
+
+	for start := 0; start < len(nodes); start += uidBatchSize {
+		end := min(start+uidBatchSize, len(nodes))
+
+		batch := make([]map[string]interface{}, 0, end-start)
+		blanks := make(map[string]string, end-start)
+		for i, node := range nodes[start:end] {
+			if _, dup := uidMap[node.UID]; dup {
+				return nil, fmt.Errorf("node %s is archived twice", node.UID)
+			}
+			uidMap[node.UID] = ""
+
+			blank := fmt.Sprintf("n%d", start+i)
+			blanks[blank] = node.UID
+
+			values := make(map[string]interface{}, len(node.Values)+2)
+			for k, v := range node.Values {
+				values[k] = v
+			}
+			values["uid"] = "_:" + blank
+			values["dgraph.type"] = node.Types
+			batch = append(batch, values)
+		}
+
+		setJSON, err := json.Marshal(batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal nodes: %w", err)
+		}
+		assigned, err := tx.Mutate(ctx, &api.Mutation{SetJson: setJSON})
+		if err != nil {
+			return nil, fmt.Errorf("restoring nodes failed: %w", err)
+		}
+		for blank, oldUID := range blanks {
+			newUID, ok := assigned.Uids[blank]
+			if !ok {
+				return nil, fmt.Errorf("no uid assigned for node %s", oldUID)
+			}
+			uidMap[oldUID] = newUID
+		}
+	}
+
+	var edges []map[string]interface{}
+	flush := func() error {
+		if len(edges) == 0 {
+			return nil
+		}
+		setJSON, err := json.Marshal(edges)
+		if err != nil {
+			return fmt.Errorf("failed to marshal edges: %w", err)
+		}
+		if _, err := tx.Mutate(ctx, &api.Mutation{SetJson: setJSON}); err != nil {
+			return fmt.Errorf("restoring edges failed: %w", err)
+		}
+		edges = edges[:0]
+		return nil
+	}
+
+	for _, node := range nodes {
+		if len(node.Edges) == 0 {
+			continue
+		}
+		update := map[string]interface{}{"uid": uidMap[node.UID]}
+		for pred, targets := range node.Edges {
+			refs := make([]map[string]string, 0, len(targets))
+			for _, target := range targets {
+				if newUID := uidMap[target]; newUID != "" {
+					refs = append(refs, map[string]string{"uid": newUID})
+				}
+			}
+			switch len(refs) {
+			case 0:
+			case 1:
+				// also valid for single uid predicates
+				update[pred] = refs[0]
+			default:
+				update[pred] = refs
+			}
+		}
+		if len(update) == 1 {
+			continue
+		}
+
+		edges = append(edges, update)
+		if len(edges) >= uidBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return uidMap, nil
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"RedPaths-server/pkg/model/core"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// GraphNode is an entity of the project graph with its scalar predicates.
+type GraphNode struct {
+	UID    string
+	Type   string
+	Values map[string]interface{}
+}
+
+// GraphEdge is an assertion between two entities of the project graph.
+type GraphEdge struct {
+	UID        string
+	Predicate  string
+	Subject    string
+	Object     string
+	Status     string
+	Confidence float64
+	Source     string
+	HighValue  bool
+}
+
+// ProjectGraph is everything reachable from a project via assertions. Nodes
+// are keyed by UID, the project itself is included.
+type ProjectGraph struct {
+	ProjectUID string
+	Nodes      map[string]*GraphNode
+	Edges      []*GraphEdge
+}
+
+// NodesOfType returns the nodes with the given dgraph type.
+func (g *ProjectGraph) NodesOfType(dtype string) []*GraphNode {
+	var nodes []*GraphNode
+	for _, n := range g.Nodes {
+		if n.Type == dtype {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+type ProjectGraphRepository interface {
+	// Läuft vom Projekt aus alle gültigen Assertions ab (Katalog,
+	// has_ad / has_domain / has_host / contains, Beziehungen, ACLs, ...)
+	GetProjectGraph(ctx context.Context, tx *dgo.Txn, projectUID string) (*ProjectGraph, error)
+}
+
+type DgraphProjectGraphRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphProjectGraphRepository(db *dgo.Dgraph) *DgraphProjectGraphRepository {
+	return &DgraphProjectGraphRepository{DB: db}
+}
+
+// GetProjectGraph walks the outgoing assertions breadth first, one query per
+// batch of the current frontier. Invalidated and expired assertions are not
+// followed.
+func (r *DgraphProjectGraphRepository) GetProjectGraph(ctx context.Context, tx *dgo.Txn, projectUID string) (*ProjectGraph, error) {
+	if !strings.HasPrefix(projectUID, "0x") {
+		return nil, fmt.Errorf("invalid project uid %q", projectUID)
+	}
+
+	query := `
+		query Graph($uids: string) {
+			nodes(func: uid($uids)) {
+				uid
+				dgraph.type
+				expand(_all_)
+				out: ~assertion.subject {
+					uid
+					assertion.predicate
+					assertion.status
+					assertion.confidence
+					assertion.source
+					assertion.high_value_marked
+					assertion.object { uid }
+				}
+			}
+		}`
+
+	type outEdge struct {
+		UID        string  `json:"uid"`
+		Predicate  string  `json:"assertion.predicate"`
+		Status     string  `json:"assertion.status"`
+		Confidence float64 `json:"assertion.confidence"`
+		Source     string  `json:"assertion.source"`
+		HighValue  bool    `json:"assertion.high_value_marked"`
+		Object     *struct {
+			UID string `json:"uid"`
+		} `json:"assertion.object"`
+	}
+
+	graph := &ProjectGraph{
+		ProjectUID: projectUID,
+		Nodes:      make(map[string]*GraphNode),
+	}
+	seen := map[string]bool{projectUID: true}
+	frontier := []string{projectUID}
+
+	for len(frontier) > 0 {
+		var next []string
+
+		for start := 0; start < len(frontier); start += uidBatchSize {
+			end := min(start+uidBatchSize, len(frontier))
+
+			resp, err := tx.QueryWithVars(ctx, query, map[string]string{
+				"$uids": strings.Join(frontier[start:end], ","),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("project graph query failed: %w", err)
+			}
+
+			var result struct {
+				Nodes []map[string]json.RawMessage `json:"nodes"`
+			}
+			if err := json.Unmarshal(resp.Json, &result); err != nil {
+				return nil, fmt.Errorf("unmarshal project graph failed: %w", err)
+			}
+
+			for _, raw := range result.Nodes {
+				node, out, err := decodeGraphNode[outEdge](raw)
+				if err != nil {
+					return nil, err
+				}
+				// uid without predicates (deleted node)
+				if node.Type == "" {
+					continue
+				}
+				graph.Nodes[node.UID] = node
+
+				for _, e := range out {
+					if e.Object == nil || e.Object.UID == "" {
+						continue
+					}
+					if e.Status == string(core.StatusInvalidated) || e.Status == string(core.StatusExpired) {
+						continue
+					}
+					graph.Edges = append(graph.Edges, &GraphEdge{
+						UID:        e.UID,
+						Predicate:  e.Predicate,
+						Subject:    node.UID,
+						Object:     e.Object.UID,
+						Status:     e.Status,
+						Confidence: e.Confidence,
+						Source:     e.Source,
+						HighValue:  e.HighValue,
+					})
+					if !seen[e.Object.UID] {
+						seen[e.Object.UID] = true
+						next = append(next, e.Object.UID)
+					}
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	// Edges to nodes that turned out empty are dropped
+	edges := graph.Edges[:0]
+	for _, e := range graph.Edges {
+		if graph.Nodes[e.Object] != nil {
+			edges = append(edges, e)
+		}
+	}
+	graph.Edges = edges
+
+	return graph, nil
+}
+
+// decodeGraphNode splits a query result into the node with its scalar values
+// and its outgoing assertions.
+func decodeGraphNode[E any](raw map[string]json.RawMessage) (*GraphNode, []E, error) {
+	node := &GraphNode{Values: make(map[string]interface{}, len(raw))}
+	var out []E
+
+	for key, value := range raw {
+		switch key {
+		case "uid":
+			if err := json.Unmarshal(value, &node.UID); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal uid failed: %w", err)
+			}
+		case "dgraph.type":
+			var types []string
+			if err := json.Unmarshal(value, &types); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal dgraph.type failed: %w", err)
+			}
+			if len(types) > 0 {
+				node.Type = types[0]
+			}
+		case "out":
+			if err := json.Unmarshal(value, &out); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal assertions failed: %w", err)
+			}
+		default:
+			var v interface{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal %s failed: %w", key, err)
+			}
+			if isScalarValue(v) {
+				node.Values[key] = v
+			}
+		}
+	}
+	return node, out, nil
+}
+
+// isScalarValue filters uid edges, which expand(_all_) may return as objects.
+func isScalarValue(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return false
+	case []interface{}:
+		for _, item := range val {
+			if _, ok := item.(map[string]interface{}); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// uidBatchSize limits the number of UIDs per query / mutation.
+const uidBatchSize = 1000
+
+// RunNode is a node created during an import or module run. Predicate,
+// Subject and Object are only set for assertions.
+type RunNode struct {
+	UID       string        `json:"uid"`
+	DType     []string      `json:"dgraph.type,omitempty"`
+	Predicate string        `json:"assertion.predicate,omitempty"`
+	Subject   *utils.UIDRef `json:"assertion.subject,omitempty"`
+	Object    *utils.UIDRef `json:"assertion.object,omitempty"`
+}
+
+// Type returns the dgraph type of the node, "" if it has none.
+func (n *RunNode) Type() string {
+	if len(n.DType) == 0 {
+		return ""
+	}
+	return n.DType[0]
+}
+
+func (n *RunNode) IsAssertion() bool {
+	return n.Type() == "Assertion"
+}
+
+// LinkedAssertion is an assertion pointing to or from a node.
+type LinkedAssertion struct {
+	UID   string        `json:"uid"`
+	RunID string        `json:"run_id,omitempty"`
+	Other *utils.UIDRef `json:"-"` // the node on the other end
+}
+
+// NodeLinks holds the assertions a node is subject or object of.
+type NodeLinks struct {
+	UID      string
+	Type     string
+	Incoming []*LinkedAssertion // node is object
+	Outgoing []*LinkedAssertion // node is subject
+}
+
+type ProvenanceRepository interface {
+	// Alle Knoten (Entities und Assertions), die ein Run im Projekt angelegt hat
+	GetRunNodes(ctx context.Context, tx *dgo.Txn, projectUID, runID string) ([]*RunNode, error)
+	// Ein- und ausgehende Assertions der Knoten
+	GetLinks(ctx context.Context, tx *dgo.Txn, uids []string) (map[string]*NodeLinks, error)
+	// Alle Prädikate eines Knotens
+	GetValues(ctx context.Context, tx *dgo.Txn, uid string) (map[string]interface{}, error)
+
+	DeleteNodes(ctx context.Context, tx *dgo.Txn, uids []string) error
+	ClearRunID(ctx context.Context, tx *dgo.Txn, uids []string) error
+	// SetValues replaces the given predicates, nil deletes a predicate
+	SetValues(ctx context.Context, tx *dgo.Txn, uid string, values map[string]interface{}) error
+}
+
+type DgraphProvenanceRepository struct {
+	DB *dgo.Dgraph
+}
+
+func NewDgraphProvenanceRepository(db *dgo.Dgraph) *DgraphProvenanceRepository {
+	return &DgraphProvenanceRepository{DB: db}
+}
+
+// GetRunNodes returns the nodes tagged with runID that are reachable from
+// the project, so a run ID of another project never yields its nodes. An
+// assertion belongs to the project when its subject does.
+func (r *DgraphProvenanceRepository) GetRunNodes(ctx context.Context, tx *dgo.Txn, projectUID, runID string) ([]*RunNode, error) {
+	if !strings.HasPrefix(projectUID, "0x") {
+		return nil, fmt.Errorf("invalid project uid %q", projectUID)
+	}
+
+	query := fmt.Sprintf(`
+		query RunNodes($runID: string) {
+			nodes(func: eq(%s, $runID)) {
+				uid
+				dgraph.type
+				assertion.predicate
+				assertion.subject { uid }
+				assertion.object { uid }
+			}
+		}`, assertion.RunIDField)
+
+	resp, err := tx.QueryWithVars(ctx, query, map[string]string{"$runID": runID})
+	if err != nil {
+		return nil, fmt.Errorf("run nodes query failed: %w", err)
+	}
+
+	var result struct {
+		Nodes []*RunNode `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal run nodes failed: %w", err)
+	}
+	if len(result.Nodes) == 0 {
+		return nil, nil
+	}
+
+	reachable, err := r.projectNodes(ctx, tx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*RunNode, 0, len(result.Nodes))
+	for _, n := range result.Nodes {
+		uid := n.UID
+		if n.IsAssertion() {
+			if n.Subject == nil {
+				continue
+			}
+			uid = n.Subject.UID
+		}
+		if reachable[uid] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// projectNodes returns the UIDs of all entities reachable from the project
+// over assertions, the project included.
+func (r *DgraphProvenanceRepository) projectNodes(ctx context.Context, tx *dgo.Txn, projectUID string) (map[string]bool, error) {
+	query := `
+		query Reachable($uids: string) {
+			nodes(func: uid($uids)) {
+				out: ~assertion.subject {
+					assertion.object { uid }
+				}
+			}
+		}`
+
+	reachable := map[string]bool{projectUID: true}
+	frontier := []string{projectUID}
+
+	for len(frontier) > 0 {
+		var next []string
+
+		for start := 0; start < len(frontier); start += uidBatchSize {
+			end := min(start+uidBatchSize, len(frontier))
+
+			resp, err := tx.QueryWithVars(ctx, query, map[string]string{
+				"$uids": strings.Join(frontier[start:end], ","),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("reachable nodes query failed: %w", err)
+			}
+
+			var result struct {
+				Nodes []struct {
+					Out []struct {
+						Object *utils.UIDRef `json:"assertion.object"`
+					} `json:"out"`
+				} `json:"nodes"`
+			}
+			if err := json.Unmarshal(resp.Json, &result); err != nil {
+				return nil, fmt.Errorf("unmarshal reachable nodes failed: %w", err)
+			}
+
+			for _, n := range result.Nodes {
+				for _, a := range n.Out {
+					if a.Object == nil || a.Object.UID == "" || reachable[a.Object.UID] {
+						continue
+					}
+					reachable[a.Object.UID] = true
+					next = append(next, a.Object.UID)
+				}
+			}
+		}
+		frontier = next
+	}
+	return reachable, nil
+}
+
+func (r *DgraphProvenanceRepository) GetLinks(ctx context.Context, tx *dgo.Txn, uids []string) (map[string]*NodeLinks, error) {
+	query := fmt.Sprintf(`
+		query Links($uids: string) {
+			nodes(func: uid($uids)) {
+				uid
+				dgraph.type
+				incoming: ~assertion.object {
+					uid
+					%[1]s
+					assertion.subject { uid dgraph.type }
+				}
+				outgoing: ~assertion.subject {
+					uid
+					%[1]s
+					assertion.object { uid dgraph.type }
+				}
+			}
+		}`, assertion.RunIDField)
+
+	type linkedNode struct {
+		UID   string   `json:"uid"`
+		DType []string `json:"dgraph.type"`
+	}
+	type link struct {
+		UID     string      `json:"uid"`
+		RunID   string      `json:"run_id"`
+		Subject *linkedNode `json:"assertion.subject"`
+		Object  *linkedNode `json:"assertion.object"`
+	}
+	toRef := func(n *linkedNode) *utils.UIDRef {
+		if n == nil {
+			return nil
+		}
+		ref := &utils.UIDRef{UID: n.UID}
+		if len(n.DType) > 0 {
+			ref.Type = n.DType[0]
+		}
+		return ref
+	}
+
+	links := make(map[string]*NodeLinks, len(uids))
+	for start := 0; start < len(uids); start += uidBatchSize {
+		end := min(start+uidBatchSize, len(uids))
+
+		resp, err := tx.QueryWithVars(ctx, query, map[string]string{
+			"$uids": strings.Join(uids[start:end], ","),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("links query failed: %w", err)
+		}
+
+		var result struct {
+			Nodes []struct {
+				UID      string   `json:"uid"`
+				DType    []string `json:"dgraph.type"`
+				Incoming []*link  `json:"incoming"`
+				Outgoing []*link  `json:"outgoing"`
+			} `json:"nodes"`
+		}
+		if err := json.Unmarshal(resp.Json, &result); err != nil {
+			return nil, fmt.Errorf("unmarshal links failed: %w", err)
+		}
+
+		for _, n := range result.Nodes {
+			nl := &NodeLinks{UID: n.UID}
+			if len(n.DType) > 0 {
+				nl.Type = n.DType[0]
+			}
+			for _, l := range n.Incoming {
+				nl.Incoming = append(nl.Incoming, &LinkedAssertion{UID: l.UID, RunID: l.RunID, Other: toRef(l.Subject)})
+			}
+			for _, l := range n.Outgoing {
+				nl.Outgoing = append(nl.Outgoing, &LinkedAssertion{UID: l.UID, RunID: l.RunID, Other: toRef(l.Object)})
+			}
+			links[n.UID] = nl
+		}
+	}
+	return links, nil
+}
+
+func (r *DgraphProvenanceRepository) GetValues(ctx context.Context, tx *dgo.Txn, uid string) (map[string]interface{}, error) {
+	query := `
+		query Values($uid: string) {
+			node(func: uid($uid)) {
+				expand(_all_)
+			}
+		}`
+
+	resp, err := tx.QueryWithVars(ctx, query, map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, fmt.Errorf("values query failed: %w", err)
+	}
+
+	var result struct {
+		Node []map[string]interface{} `json:"node"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal values failed: %w", err)
+	}
+	if len(result.Node) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return result.Node[0], nil
+}
+
+// DeleteNodes removes the nodes with all predicates of their type and the
+// run tag, which is not part of the entity types.
+func (r *DgraphProvenanceRepository) DeleteNodes(ctx context.Context, tx *dgo.Txn, uids []string) error {
+	return r.deleteBatched(ctx, tx, uids, func(uid string) string {
+		return fmt.Sprintf("<%s> * * .\n<%s> <%s> * .\n", uid, uid, assertion.RunIDField)
+	})
+}
+
+// ClearRunID removes the run tag, e.g. from nodes that survive a rollback
+// because later runs confirmed them.
+func (r *DgraphProvenanceRepository) ClearRunID(ctx context.Context, tx *dgo.Txn, uids []string) error {
+	return r.deleteBatched(ctx, tx, uids, func(uid string) string {
+		return fmt.Sprintf("<%s> <%s> * .\n", uid, assertion.RunIDField)
+	})
+}
+
+func (r *DgraphProvenanceRepository) deleteBatched(ctx context.Context, tx *dgo.Txn, uids []string, nquads func(uid string) string) error {
+	for start := 0; start < len(uids); start += uidBatchSize {
+		end := min(start+uidBatchSize, len(uids))
+
+		var sb strings.Builder
+		for _, uid := range uids[start:end] {
+			if !strings.HasPrefix(uid, "0x") {
+				return fmt.Errorf("invalid uid %q", uid)
+			}
+			sb.WriteString(nquads(uid))
+		}
+		if _, err := tx.Mutate(ctx, &api.Mutation{DelNquads: []byte(sb.String())}); err != nil {
+			return fmt.Errorf("delete mutation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *DgraphProvenanceRepository) SetValues(ctx context.Context, tx *dgo.Txn, uid string, values map[string]interface{}) error {
+	if !strings.HasPrefix(uid, "0x") {
+		return fmt.Errorf("invalid uid %q", uid)
+	}
+
+	// delete first, so list predicates are replaced instead of extended
+	var del strings.Builder
+	set := map[string]interface{}{"uid": uid}
+	for predicate, value := range values {
+		del.WriteString(fmt.Sprintf("<%s> <%s> * .\n", uid, predicate))
+		if value != nil {
+			set[predicate] = value
+		}
+	}
+	if del.Len() == 0 {
+		return nil
+	}
+
+	if _, err := tx.Mutate(ctx, &api.Mutation{DelNquads: []byte(del.String())}); err != nil {
+		return fmt.Errorf("delete mutation failed: %w", err)
+	}
+	if len(set) == 1 {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("marshal values failed: %w", err)
+	}
+	if _, err := tx.Mutate(ctx, &api.Mutation{SetJson: jsonData}); err != nil {
+		return fmt.Errorf("set mutation failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package imports
+
+import (
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TableImportRuns = "redpaths_import_runs"
+)
+
+type RedPathsImportRepository interface {
+	AddRun(ctx context.Context, tx *gorm.DB, run *redpaths.ImportRun) error
+	GetAllImportRuns(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.ImportRun, error)
+	GetImportRun(ctx context.Context, tx *gorm.DB, importID string) (*redpaths.ImportRun, error)
+	MarkRolledBack(ctx context.Context, tx *gorm.DB, importID string, at time.Time) error
+}
+
+type PostgresRedPathsImportRepository struct{}
+
+func NewPostgresRedPathsImportRepository() *PostgresRedPathsImportRepository {
+	return &PostgresRedPathsImportRepository{}
+}
+
+func (r *PostgresRedPathsImportRepository) AddRun(ctx context.Context, tx *gorm.DB, run *redpaths.ImportRun) error {
+	if run.ImportID == "" {
+		return fmt.Errorf("importID cannot be empty")
+	}
+
+	if err := tx.WithContext(ctx).Table(TableImportRuns).Create(run).Error; err != nil {
+		return fmt.Errorf("failed to register import run %s: %w", run.ImportID, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRedPathsImportRepository) GetAllImportRuns(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.ImportRun, error) {
+	var runs []*redpaths.ImportRun
+
+	err := tx.WithContext(ctx).
+		Table(TableImportRuns).
+		Where("project_uid = ?", projectUID).
+		Order("started_at DESC").
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import runs for project %s: %w", projectUID, err)
+	}
+
+	return runs, nil
+}
+
+// GetImportRun returns the run, nil if there is none with this id.
+func (r *PostgresRedPathsImportRepository) GetImportRun(ctx context.Context, tx *gorm.DB, importID string) (*redpaths.ImportRun, error) {
+	var run redpaths.ImportRun
+
+	err := tx.WithContext(ctx).
+		Table(TableImportRuns).
+		Where("import_id = ?", importID).
+		First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import run %s: %w", importID, err)
+	}
+
+	return &run, nil
+}
+
+func (r *PostgresRedPathsImportRepository) MarkRolledBack(ctx context.Context, tx *gorm.DB, importID string, at time.Time) error {
+	err := tx.WithContext(ctx).
+		Table(TableImportRuns).
+		Where("import_id = ?", importID).
+		Update("rolled_back_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark import run %s as rolled back: %w", importID, err)
+	}
+
+	return nil
+}
@@ -80,6 +80,7 @@ type RedPathsModuleRepository interface {
 	// module history
 	AddRun(ctx context.Context, tx *gorm.DB, runMetadata *redpaths.ModuleRun) error
 	GetAllModuleRuns(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.ModuleRun, error)
+	GetModuleRunsByRun(ctx context.Context, tx *gorm.DB, runUID string) ([]*redpaths.ModuleRun, error)
 }
 
 type PostgresRedPathsModuleRepository struct{}
@@ -355,3 +356,20 @@ func (r *PostgresRedPathsModuleRepository) GetAllModuleRuns(ctx context.Context,
 
 	return runs, nil
 }
+
+// GetModuleRunsByRun returns the module runs with runUID as module or vector
+// run, the run ID graph data of a module run is tagged with.
+func (r *PostgresRedPathsModuleRepository) GetModuleRunsByRun(ctx context.Context, tx *gorm.DB, runUID string) ([]*redpaths.ModuleRun, error) {
+	var runs []*redpaths.ModuleRun
+
+	result := tx.WithContext(ctx).
+		Table(TableModuleRuns).
+		Where("run_uid = ? OR vector_run_uid = ?", runUID, runUID).
+		Find(&runs)
+
+	if err := result.Error; err != nil {
+		return nil, fmt.Errorf("failed to get module runs for run %s: %w", runUID, err)
+	}
+
+	return runs, nil
+}
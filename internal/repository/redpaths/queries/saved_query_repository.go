@@ -0,0 +1,114 @@
+package queries
+
+import (
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TableSavedQueries = "redpaths_saved_queries"
+)
+
+type RedPathsSavedQueryRepository interface {
+	Add(ctx context.Context, tx *gorm.DB, query *redpaths.SavedQuery) error
+	GetAll(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.SavedQuery, error)
+	Get(ctx context.Context, tx *gorm.DB, projectUID, queryID string) (*redpaths.SavedQuery, error)
+	GetByName(ctx context.Context, tx *gorm.DB, projectUID, name string) (*redpaths.SavedQuery, error)
+	Update(ctx context.Context, tx *gorm.DB, query *redpaths.SavedQuery) error
+	Delete(ctx context.Context, tx *gorm.DB, projectUID, queryID string) (bool, error)
+}
+
+type PostgresRedPathsSavedQueryRepository struct{}
+
+func NewPostgresRedPathsSavedQueryRepository() *PostgresRedPathsSavedQueryRepository {
+	return &PostgresRedPathsSavedQueryRepository{}
+}
+
+func (r *PostgresRedPathsSavedQueryRepository) Add(ctx context.Context, tx *gorm.DB, query *redpaths.SavedQuery) error {
+	if query.QueryID == "" {
+		return fmt.Errorf("queryID cannot be empty")
+	}
+
+	if err := tx.WithContext(ctx).Table(TableSavedQueries).Create(query).Error; err != nil {
+		return fmt.Errorf("failed to store saved query %s: %w", query.QueryID, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRedPathsSavedQueryRepository) GetAll(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.SavedQuery, error) {
+	var queries []*redpaths.SavedQuery
+
+	err := tx.WithContext(ctx).
+		Table(TableSavedQueries).
+		Where("project_uid = ?", projectUID).
+		Order("name ASC").
+		Find(&queries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved queries for project %s: %w", projectUID, err)
+	}
+
+	return queries, nil
+}
+
+// Get returns the saved query, nil if it does not exist in the project.
+func (r *PostgresRedPathsSavedQueryRepository) Get(ctx context.Context, tx *gorm.DB, projectUID, queryID string) (*redpaths.SavedQuery, error) {
+	return r.first(ctx, tx, "project_uid = ? AND query_id = ?", projectUID, queryID)
+}
+
+// GetByName returns the saved query with this name, nil if the project has
+// none.
+func (r *PostgresRedPathsSavedQueryRepository) GetByName(ctx context.Context, tx *gorm.DB, projectUID, name string) (*redpaths.SavedQuery, error) {
+	return r.first(ctx, tx, "project_uid = ? AND name = ?", projectUID, name)
+}
+
+func (r *PostgresRedPathsSavedQueryRepository) first(ctx context.Context, tx *gorm.DB, where string, args ...interface{}) (*redpaths.SavedQuery, error) {
+	var query redpaths.SavedQuery
+
+	err := tx.WithContext(ctx).
+		Table(TableSavedQueries).
+		Where(where, args...).
+		First(&query).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved query: %w", err)
+	}
+
+	return &query, nil
+}
+
+func (r *PostgresRedPathsSavedQueryRepository) Update(ctx context.Context, tx *gorm.DB, query *redpaths.SavedQuery) error {
+	err := tx.WithContext(ctx).
+		Table(TableSavedQueries).
+		Where("project_uid = ? AND query_id = ?", query.ProjectUID, query.QueryID).
+		Updates(map[string]interface{}{
+			"name":        query.Name,
+			"description": query.Description,
+			"query":       query.Query,
+			"updated_at":  query.UpdatedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update saved query %s: %w", query.QueryID, err)
+	}
+
+	return nil
+}
+
+// Delete removes the saved query and reports whether it existed.
+func (r *PostgresRedPathsSavedQueryRepository) Delete(ctx context.Context, tx *gorm.DB, projectUID, queryID string) (bool, error) {
+	result := tx.WithContext(ctx).
+		Table(TableSavedQueries).
+		Where("project_uid = ? AND query_id = ?", projectUID, queryID).
+		Delete(&redpaths.SavedQuery{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete saved query %s: %w", queryID, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
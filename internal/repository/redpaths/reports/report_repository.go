@@ -0,0 +1,74 @@
+package reports
+
+import (
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TableReports = "redpaths_reports"
+)
+
+type RedPathsReportRepository interface {
+	Add(ctx context.Context, tx *gorm.DB, report *redpaths.Report) error
+	// Ohne Content, für Übersichten
+	GetAllReports(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.Report, error)
+	GetReport(ctx context.Context, tx *gorm.DB, projectUID, reportID string) (*redpaths.Report, error)
+}
+
+type PostgresRedPathsReportRepository struct{}
+
+func NewPostgresRedPathsReportRepository() *PostgresRedPathsReportRepository {
+	return &PostgresRedPathsReportRepository{}
+}
+
+func (r *PostgresRedPathsReportRepository) Add(ctx context.Context, tx *gorm.DB, report *redpaths.Report) error {
+	if report.ReportID == "" {
+		return fmt.Errorf("reportID cannot be empty")
+	}
+
+	if err := tx.WithContext(ctx).Table(TableReports).Create(report).Error; err != nil {
+		return fmt.Errorf("failed to store report %s: %w", report.ReportID, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRedPathsReportRepository) GetAllReports(ctx context.Context, tx *gorm.DB, projectUID string) ([]*redpaths.Report, error) {
+	var reports []*redpaths.Report
+
+	err := tx.WithContext(ctx).
+		Table(TableReports).
+		Omit("content").
+		Where("project_uid = ?", projectUID).
+		Order("created_at DESC").
+		Find(&reports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reports for project %s: %w", projectUID, err)
+	}
+
+	return reports, nil
+}
+
+// GetReport returns the report including its content, nil if it does not
+// exist in the project.
+func (r *PostgresRedPathsReportRepository) GetReport(ctx context.Context, tx *gorm.DB, projectUID, reportID string) (*redpaths.Report, error) {
+	var report redpaths.Report
+
+	err := tx.WithContext(ctx).
+		Table(TableReports).
+		Where("project_uid = ? AND report_id = ?", projectUID, reportID).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report %s: %w", reportID, err)
+	}
+
+	return &report, nil
+}
@@ -456,6 +456,15 @@ func reflectFieldMatches[T any](entity T, field, value string) bool {
 
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
+
+		// Embedded structs (e.g. core.BasePrincipal) carry their own tagged fields
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if reflectFieldMatches(ev.Field(i).Interface(), field, value) {
+				return true
+			}
+			continue
+		}
+
 		jsonTag := strings.Split(f.Tag.Get("json"), ",")[0]
 
 		if jsonTag == field || strings.ToLower(f.Name) == fieldLower || jsonTag == fieldLower {
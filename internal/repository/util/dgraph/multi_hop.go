@@ -21,6 +21,9 @@ type HopConfig struct {
 	Predicate    core.Predicate
 	ObjectType   string // only relevant for the last hop
 	AnyPredicate bool
+	// ObjectFilter is a DQL filter function on the objects of the last hop,
+	// e.g. eq(user.kerberoastable, true). It is combined with the type filter.
+	ObjectFilter string
 }
 
 type leafAssertion struct {
@@ -200,10 +203,17 @@ func buildNHopQuery(queryName string, hops []HopConfig, objectFields []string) s
 
 	lastHop := hops[len(hops)-1]
 
-	// Type filter for the leaf object (e.g. @filter(type(Host)))
-	typeFilter := ""
+	// Type and object filter for the leaf object (e.g. @filter(type(Host)))
+	var leafFilters []string
 	if strings.TrimSpace(string(lastHop.ObjectType)) != "" {
-		typeFilter = fmt.Sprintf("@filter(type(%s))", lastHop.ObjectType)
+		leafFilters = append(leafFilters, fmt.Sprintf("type(%s)", lastHop.ObjectType))
+	}
+	if strings.TrimSpace(lastHop.ObjectFilter) != "" {
+		leafFilters = append(leafFilters, fmt.Sprintf("(%s)", lastHop.ObjectFilter))
+	}
+	typeFilter := ""
+	if len(leafFilters) > 0 {
+		typeFilter = fmt.Sprintf("@filter(%s)", strings.Join(leafFilters, " AND "))
 	}
 
 	// Predicate filter for the last hop assertion
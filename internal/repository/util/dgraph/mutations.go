@@ -4,6 +4,7 @@ import (
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
 	"RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -342,6 +343,9 @@ func OldCreateEntity(ctx context.Context, tx *dgo.Txn, dtype string, entity inte
 	blankID := uuid.New().String()
 	entityMap["uid"] = fmt.Sprintf("_:%s", blankID)
 	entityMap["dgraph.type"] = dtype
+	if runID := assertion.RunID(ctx); runID != "" {
+		entityMap[assertion.RunIDField] = runID
+	}
 
 	jsonData, err := json.Marshal(entityMap)
 	if err != nil {
@@ -377,6 +381,9 @@ func CreateEntity[T any](ctx context.Context, tx *dgo.Txn, dtype string, entity
 	blankID := uuid.New().String()
 	entityMap["uid"] = fmt.Sprintf("_:%s", blankID)
 	entityMap["dgraph.type"] = dtype
+	if runID := assertion.RunID(ctx); runID != "" {
+		entityMap[assertion.RunIDField] = runID
+	}
 
 	jsonData, err := json.Marshal(entityMap)
 	if err != nil {
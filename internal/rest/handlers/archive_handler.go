@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/archive"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveHandler struct {
+	archiveService *archive.ArchiveService
+}
+
+func NewArchiveHandler(archiveService *archive.ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{
+		archiveService: archiveService,
+	}
+}
+
+// ExportArchive sends the project with its history as tar.gz archive.
+func (h *ArchiveHandler) ExportArchive(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	exported, err := h.archiveService.Export(c.Request.Context(), projectUID)
+	if err != nil {
+		if errors.Is(err, archive.ErrProjectMissing) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Sending 500 response while archiving project because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to archive project",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exported.FileName))
+	c.Data(http.StatusOK, "application/gzip", exported.Content)
+}
+
+// ImportArchive restores an uploaded archive (multipart field "file") as new
+// project. Optional form fields: "name" overrides the project name,
+// "includeUidMap" returns the old → new UID mapping.
+func (h *ArchiveHandler) ImportArchive(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid archive file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid archive file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	includeMap, _ := strconv.ParseBool(c.PostForm("includeUidMap"))
+	result, err := h.archiveService.Import(c.Request.Context(), data, archive.ImportOptions{
+		Name:       c.PostForm("name"),
+		IncludeMap: includeMap,
+	})
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Sending 500 response while restoring archive because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to restore archive",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
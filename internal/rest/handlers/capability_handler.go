@@ -2,41 +2,50 @@ package handlers
 
 import (
 	"RedPaths-server/pkg/service/engine"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CapabilityHandler struct {
-	capabilityService *engine.CapabilityService
+	capabilityService    *engine.CapabilityService
+	aclDerivationService *engine.ACLDerivationService
 }
 
-func NewCapabilityHandler(capabilityService *engine.CapabilityService) *CapabilityHandler {
+func NewCapabilityHandler(capabilityService *engine.CapabilityService, aclDerivationService *engine.ACLDerivationService) *CapabilityHandler {
 	return &CapabilityHandler{
-		capabilityService: capabilityService,
+		capabilityService:    capabilityService,
+		aclDerivationService: aclDerivationService,
 	}
 }
 
-// GetCatalogUsers retrieves all users for a project.
+// GetCatalogCapabilities retrieves all capabilities for a project, as JSON,
+// CSV or XLSX.
 func (h *CapabilityHandler) GetCatalogCapabilities(c *gin.Context) {
-	uid := c.Param("projectUID")
-	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Project UID is required",
-		})
-		return
-	}
+	handleCatalogGet(c, "projectUID", "Failed to retrieve capabilities",
+		h.capabilityService.GetCapabilitiesFromCatalog)
+}
 
-	domains, err := h.capabilityService.GetCapabilitiesFromCatalog(c.Request.Context(), uid)
+// DeriveCapabilities re-evaluates the capabilities derived from the ACEs of a
+// project: missing ones are created, stale ones invalidated.
+func (h *CapabilityHandler) DeriveCapabilities(c *gin.Context) {
+	projectUID := c.Param("projectUID")
 
+	summary, err := h.aclDerivationService.DeriveProject(c.Request.Context(), projectUID, "ACLDerivation")
 	if err != nil {
-		errReturn := gin.H{
-			"error":   "Failed to retrieve users",
-			"details": err.Error(),
+		if errors.Is(err, engine.ErrProjectMissing) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
 		}
-		c.JSON(http.StatusInternalServerError, errReturn)
+		log.Printf("Sending 500 response while deriving capabilities because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to derive capabilities",
+			"details": err.Error(),
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, domains)
+	c.JSON(http.StatusOK, summary)
 }
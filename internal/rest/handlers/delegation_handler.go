@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/engine"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DelegationHandler struct {
+	delegationAnalysisService *engine.DelegationAnalysisService
+}
+
+func NewDelegationHandler(delegationAnalysisService *engine.DelegationAnalysisService) *DelegationHandler {
+	return &DelegationHandler{
+		delegationAnalysisService: delegationAnalysisService,
+	}
+}
+
+// GetDelegations lists the Kerberos delegation findings of a project,
+// riskiest first, without deriving capabilities.
+func (h *DelegationHandler) GetDelegations(c *gin.Context) {
+	analysis, err := h.delegationAnalysisService.Analyse(c.Request.Context(), c.Param("projectUID"))
+	if err != nil {
+		sendDelegationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// AnalyseDelegations analyses the project and brings the delegation
+// capabilities in line with the findings.
+func (h *DelegationHandler) AnalyseDelegations(c *gin.Context) {
+	analysis, err := h.delegationAnalysisService.DeriveProject(c.Request.Context(), c.Param("projectUID"), "DelegationAnalysis")
+	if err != nil {
+		sendDelegationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+func sendDelegationError(c *gin.Context, err error) {
+	if errors.Is(err, engine.ErrProjectMissing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Sending 500 response while analysing delegations because: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "failed to analyse delegations",
+		"details": err.Error(),
+	})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"RedPaths-server/internal/rest/requests"
 	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/active_directory/gpo"
 	"RedPaths-server/pkg/service/active_directory"
 	"log"
 	"net/http"
@@ -246,3 +248,41 @@ func (h *DirectoryNodeHandler) UpdateDirectoryNode(c *gin.Context) {
 		"updated_project": updatedDirectoryNode,
 	})
 }
+
+// LinkDirectoryNodeGPO links a GPO to the OU/container, like LinkDomainGPO
+// does for domains.
+func (h *DirectoryNodeHandler) LinkDirectoryNodeGPO(c *gin.Context) {
+	var request requests.AddGPOLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request to add a GPO link",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	gpoLink := &gpo.Link{
+		LinkOrder:  request.GPOLink.LinkOrder,
+		IsEnforced: request.GPOLink.IsEnforced,
+		IsEnabled:  request.GPOLink.IsEnabled == nil || *request.GPOLink.IsEnabled,
+	}
+
+	result, err := h.directoryNodeService.AddGPOLink(
+		c.Request.Context(),
+		request.AssertionContext,
+		gpoLink,
+		&gpo.GPO{Name: request.GPO.Name},
+		c.Param("dirNodeUID"),
+		"UserInput",
+	)
+	if err != nil {
+		log.Printf("Sending 500 response while linking gpo because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to link GPO",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
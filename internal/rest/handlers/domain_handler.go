@@ -103,6 +103,23 @@ func (h *DomainHandler) GetDomainGPOLib(c *gin.Context) {
 	c.JSON(http.StatusOK, gpos)
 }
 
+// GetDomainSecurityPolicy returns the password/lockout policy of the domain,
+// 404 if none has been imported yet.
+func (h *DomainHandler) GetDomainSecurityPolicy(c *gin.Context) {
+	domainUID := c.Param("domainUID")
+
+	policy, err := h.domainService.GetSecurityPolicy(c.Request.Context(), domainUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no security policy known for this domain"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
 func (h *DomainHandler) GetDomainDirectoryNodes(c *gin.Context) {
 	domainUID := c.Param("domainUID")
 
@@ -239,7 +256,7 @@ func (h *DomainHandler) LinkDomainGPO(c *gin.Context) {
 	gpoLink := &gpo.Link{
 		LinkOrder:  request.GPOLink.LinkOrder,
 		IsEnforced: request.GPOLink.IsEnforced,
-		IsEnabled:  request.GPOLink.IsEnabled,
+		IsEnabled:  request.GPOLink.IsEnabled == nil || *request.GPOLink.IsEnabled,
 	}
 
 	gpoEntity := &gpo.GPO{
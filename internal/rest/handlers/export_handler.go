@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/exporter"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	bloodHoundExporter *exporter.BloodHoundExporter
+	graphExporter      *exporter.GraphExporter
+	stixExporter       *exporter.StixExporter
+}
+
+func NewExportHandler(bloodHoundExporter *exporter.BloodHoundExporter, graphExporter *exporter.GraphExporter, stixExporter *exporter.StixExporter) *ExportHandler {
+	return &ExportHandler{
+		bloodHoundExporter: bloodHoundExporter,
+		graphExporter:      graphExporter,
+		stixExporter:       stixExporter,
+	}
+}
+
+// ExportBloodHound sends the project graph as BloodHound ingest file,
+// ?format=legacy (SharpHound ZIP, default) or ?format=opengraph.
+func (h *ExportHandler) ExportBloodHound(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	export, err := h.bloodHoundExporter.Export(c.Request.Context(), projectUID, c.Query("format"))
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+
+	sendExport(c, export)
+}
+
+// ExportGraph sends the entity graph as ?format=graphml (default), gexf or
+// cypher. Filters: ?types=User,Group, ?predicates=has_member and
+// ?minConfidence=0.8; list parameters may also be repeated.
+func (h *ExportHandler) ExportGraph(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	filter := exporter.GraphFilter{
+		Types:      queryList(c, "types"),
+		Predicates: queryList(c, "predicates"),
+	}
+	if raw := c.Query("minConfidence"); raw != "" {
+		minConfidence, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid minConfidence",
+				"details": err.Error(),
+			})
+			return
+		}
+		filter.MinConfidence = minConfidence
+	}
+
+	export, err := h.graphExporter.Export(c.Request.Context(), projectUID, c.Query("format"), filter)
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+
+	sendExport(c, export)
+}
+
+// ExportStix sends hosts, services, findings and module runs of the project
+// as STIX 2.1 bundle with ATT&CK attack-patterns.
+func (h *ExportHandler) ExportStix(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	export, err := h.stixExporter.Export(c.Request.Context(), projectUID)
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+
+	sendExport(c, export)
+}
+
+// queryList reads a repeated and / or comma separated query parameter.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func writeExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, exporter.ErrUnknownFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, exporter.ErrProjectMissing):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while exporting project because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to export project",
+			"details": err.Error(),
+		})
+	}
+}
+
+func sendExport(c *gin.Context, export *exporter.Export) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName))
+	c.Data(http.StatusOK, export.ContentType, export.Content)
+}
@@ -1,22 +1,121 @@
 package handlers
 
 import (
+	"RedPaths-server/internal/rest/requests"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	utils2 "RedPaths-server/pkg/model/utils"
 	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/engine"
+	"errors"
+	"log"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type GPOHandler struct {
-	gpoService *active_directory.GPOService
+	gpoService         *active_directory.GPOService
+	gpoAnalysisService *engine.GPOAnalysisService
 }
 
-func NewPGOHandler(gpoService *active_directory.GPOService) *GPOHandler {
+func NewPGOHandler(gpoService *active_directory.GPOService, gpoAnalysisService *engine.GPOAnalysisService) *GPOHandler {
 	return &GPOHandler{
-		gpoService: gpoService,
+		gpoService:         gpoService,
+		gpoAnalysisService: gpoAnalysisService,
 	}
 }
 
 func (h *GPOHandler) GetGPOSettings(c *gin.Context) {
-	//domain := restcontext.Domain(c)
-	panic("implement me")
+	settings, err := h.gpoService.GetGPOSettings(c.Request.Context(), c.Param("gpoUID"))
+	if err != nil {
+		sendGPOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// AddGPOSetting stores a setting (restricted groups, scheduled task, user
+// right, ...) of a GPO, optionally with the principal it empowers.
+func (h *GPOHandler) AddGPOSetting(c *gin.Context) {
+	var request requests.AddGPOSettingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request to add a GPO setting",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var principal *utils2.UIDRef
+	if request.PrincipalUID != "" {
+		principal = &utils2.UIDRef{UID: request.PrincipalUID, Type: request.PrincipalType}
+	}
+
+	result, err := h.gpoService.AddSetting(
+		c.Request.Context(),
+		request.AssertionContext,
+		c.Param("gpoUID"),
+		&gpo.Setting{SettingType: request.SettingType, Value: request.Value},
+		principal,
+		"UserInput",
+	)
+	if err != nil {
+		sendGPOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetGPOAnalysis returns the effective GPOs of every container and the GPO
+// findings of a project without deriving capabilities.
+func (h *GPOHandler) GetGPOAnalysis(c *gin.Context) {
+	analysis, err := h.gpoAnalysisService.Analyse(c.Request.Context(), c.Param("projectUID"))
+	if err != nil {
+		sendGPOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// GetEffectivePolicy returns the GPOs applying to one domain, OU, host or
+// user in precedence order.
+func (h *GPOHandler) GetEffectivePolicy(c *gin.Context) {
+	policy, err := h.gpoAnalysisService.EffectivePolicy(c.Request.Context(), c.Param("projectUID"), c.Param("objectUID"))
+	if err != nil {
+		sendGPOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// AnalyseGPOs analyses the project and brings the GPO capabilities in line
+// with the findings.
+func (h *GPOHandler) AnalyseGPOs(c *gin.Context) {
+	analysis, err := h.gpoAnalysisService.DeriveProject(c.Request.Context(), c.Param("projectUID"), "GPOAnalysis")
+	if err != nil {
+		sendGPOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+func sendGPOError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, active_directory.ErrInvalidGPOSetting):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, engine.ErrProjectMissing), errors.Is(err, engine.ErrPolicyObjectMissing),
+		errors.Is(err, active_directory.ErrGPONotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while handling gpos because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to handle gpos",
+			"details": err.Error(),
+		})
+	}
 }
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/active_directory"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	membershipService *active_directory.MembershipService
+}
+
+func NewGroupHandler(membershipService *active_directory.MembershipService) *GroupHandler {
+	return &GroupHandler{
+		membershipService: membershipService,
+	}
+}
+
+// GetGroupMembers returns who is effectively in a group: direct members and
+// the members of nested groups, including principals of other domains.
+// ?recursive=false limits the result to the direct members.
+func (h *GroupHandler) GetGroupMembers(c *gin.Context) {
+	members, err := h.membershipService.GroupMembers(
+		c.Request.Context(), c.Param("projectUID"), c.Param("groupUID"), c.Query("recursive") != "false")
+	if err != nil {
+		sendMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// GetGroupMemberships returns the groups a group is nested in.
+func (h *GroupHandler) GetGroupMemberships(c *gin.Context) {
+	memberships, err := h.membershipService.PrincipalMemberships(
+		c.Request.Context(), c.Param("projectUID"), c.Param("groupUID"), c.Query("recursive") != "false")
+	if err != nil {
+		sendMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
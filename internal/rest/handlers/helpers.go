@@ -2,15 +2,21 @@
 package handlers
 
 import (
+	"RedPaths-server/pkg/service/exporter"
 	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // handleCatalogGet ist ein generischer Helper für alle Catalog-GET-Methoden.
 // Er extrahiert die projectUID, ruft die Service-Funktion auf und gibt das
-// Ergebnis als JSON zurück.
+// Ergebnis als JSON zurück – oder als CSV / XLSX, wenn ?format= bzw. der
+// Accept-Header das verlangen.
 func handleCatalogGet[T any](
 	c *gin.Context,
 	paramName string,
@@ -25,6 +31,12 @@ func handleCatalogGet[T any](
 		return
 	}
 
+	format, err := exporter.TableFormat(c.Query("format"), c.GetHeader("Accept"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	result, err := serviceFn(c.Request.Context(), uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -34,9 +46,49 @@ func handleCatalogGet[T any](
 		return
 	}
 
+	if format != "" {
+		sendCatalogTable(c, format, result)
+		return
+	}
+
 	c.JSON(http.StatusOK, result)
 }
 
+// sendCatalogTable streamt eine Catalog-Ansicht als CSV / XLSX. Der Name der
+// Ansicht (z.B. "hosts_orphaned") kommt aus der Route.
+func sendCatalogTable(c *gin.Context, format string, result interface{}) {
+	view := catalogViewName(c.FullPath())
+
+	table, err := exporter.NewTable(view, result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build " + format + " export",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fileName := exporter.TableFileName(view, format, time.Now().UTC())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Content-Type", exporter.TableContentType(format))
+	c.Status(http.StatusOK)
+
+	// headers are sent at this point, errors can only be logged
+	if err := exporter.WriteTable(c.Writer, format, table); err != nil {
+		log.Printf("Writing %s export of %s failed: %v", format, view, err)
+	}
+}
+
+func catalogViewName(fullPath string) string {
+	if i := strings.Index(fullPath, "/catalog/"); i >= 0 {
+		return strings.ReplaceAll(fullPath[i+len("/catalog/"):], "/", "_")
+	}
+	if i := strings.LastIndex(fullPath, "/"); i >= 0 && i < len(fullPath)-1 {
+		return fullPath[i+1:]
+	}
+	return "catalog"
+}
+
 func EntityType(entityType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Set("entityType", entityType)
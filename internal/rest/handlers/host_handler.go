@@ -179,6 +179,24 @@ func (h *HostHandler) AddCapability(c *gin.Context) {
 	})
 }
 
+func (h *HostHandler) GetLinkedVulnerabilities(c *gin.Context) {
+	hostUID := c.Param("hostUID")
+
+	vulnerabilities, err := h.hostService.GetVulnerabilities(
+		c.Request.Context(),
+		hostUID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get vulnerabilities from host",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, vulnerabilities)
+}
+
 func (h *HostHandler) GetLinkedCapabilities(c *gin.Context) {
 
 	dirNodeUID := c.Param("hostUID")
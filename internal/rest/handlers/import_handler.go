@@ -0,0 +1,594 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/adapter/scan"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/service/importer"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits uploaded collection files (SharpHound ZIPs can get large).
+const maxImportFileSize = 512 << 20
+
+// maxImportFiles limits the number of files of a multi-file upload.
+const maxImportFiles = 64
+
+type ImportHandler struct {
+	bloodHoundImporter *importer.BloodHoundImporter
+	nmapImporter       *importer.NmapImporter
+	vulnImporter       *importer.VulnerabilityImporter
+	ldapImporter       *importer.LDAPImporter
+	bulkImporter       *importer.BulkImporter
+	riskImporter       *importer.RiskImporter
+	importRunService   *importer.ImportRunService
+	rollbackService    *importer.RollbackService
+}
+
+func NewImportHandler(
+	bloodHoundImporter *importer.BloodHoundImporter,
+	nmapImporter *importer.NmapImporter,
+	vulnImporter *importer.VulnerabilityImporter,
+	ldapImporter *importer.LDAPImporter,
+	bulkImporter *importer.BulkImporter,
+	riskImporter *importer.RiskImporter,
+	importRunService *importer.ImportRunService,
+	rollbackService *importer.RollbackService,
+) *ImportHandler {
+	return &ImportHandler{
+		bloodHoundImporter: bloodHoundImporter,
+		nmapImporter:       nmapImporter,
+		vulnImporter:       vulnImporter,
+		ldapImporter:       ldapImporter,
+		bulkImporter:       bulkImporter,
+		riskImporter:       riskImporter,
+		importRunService:   importRunService,
+		rollbackService:    rollbackService,
+	}
+}
+
+// GetImportRuns returns the import history of the project.
+func (h *ImportHandler) GetImportRuns(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	runs, err := h.importRunService.GetAll(c.Request.Context(), projectUID)
+	if err != nil {
+		log.Printf("Sending 500 response while loading import runs because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to load import runs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// PreviewRollback shows what rolling back the import or module run ":runID"
+// would delete and revert, without changing anything.
+func (h *ImportHandler) PreviewRollback(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	plan, err := h.rollbackService.Preview(c.Request.Context(), projectUID, c.Param("runID"))
+	if err != nil {
+		rollbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Rollback removes the assertions of the import or module run ":runID",
+// deletes entities left without assertion and restores the fields the run
+// merged into existing entities.
+func (h *ImportHandler) Rollback(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	plan, err := h.rollbackService.Rollback(c.Request.Context(), projectUID, c.Param("runID"), importer.RollbackSource)
+	if err != nil {
+		rollbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func rollbackError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, importer.ErrRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, importer.ErrRunOtherProject):
+		c.JSON(http.StatusNotFound, gin.H{"error": importer.ErrRunNotFound.Error()})
+	case errors.Is(err, importer.ErrRunRolledBack):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while rolling back run because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to roll back run",
+			"details": err.Error(),
+		})
+	}
+}
+
+// ImportBloodHound imports a SharpHound collection (ZIP or single JSON file)
+// uploaded as multipart field "file". Progress is streamed on the SSE run given
+// by the optional form field "runId".
+func (h *ImportHandler) ImportBloodHound(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	collection, err := importer.ParseBloodHound(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to parse BloodHound collection",
+			"details": err.Error(),
+		})
+		return
+	}
+	collection.Files = []string{fileHeader.Filename}
+
+	summary, err := h.bloodHoundImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), collection)
+	if err != nil {
+		log.Printf("Sending 500 response while importing BloodHound collection because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to import BloodHound collection",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ImportNmap imports one or more nmap output files, XML (-oX) or grepable
+// (-oG), uploaded as repeated multipart field "file" (or "files"). All files
+// are parsed before anything is written, so one broken file rejects the upload.
+func (h *ImportHandler) ImportNmap(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	fileHeaders, ok := importFileHeaders(c)
+	if !ok {
+		return
+	}
+
+	files := make([]importer.NmapFile, 0, len(fileHeaders))
+	for _, fileHeader := range fileHeaders {
+		data, err := readImportFile(fileHeader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid import file",
+				"file":    fileHeader.Filename,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		result, err := scan.ParseNmapOutput(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "failed to parse nmap output",
+				"file":    fileHeader.Filename,
+				"details": err.Error(),
+			})
+			return
+		}
+		files = append(files, importer.NmapFile{Name: fileHeader.Filename, Result: result})
+	}
+
+	summary, err := h.nmapImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), files)
+	if err != nil {
+		log.Printf("Sending 500 response while importing nmap output because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to import nmap output",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ImportLDAPDomainDump imports the JSON output of ldapdomaindump, uploaded as
+// the domain_*.json files (repeated multipart field "file") or as a ZIP of the
+// output directory.
+func (h *ImportHandler) ImportLDAPDomainDump(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	fileHeaders, ok := importFileHeaders(c)
+	if !ok {
+		return
+	}
+
+	files := make([]importer.LDAPDumpFile, 0, len(fileHeaders))
+	for _, fileHeader := range fileHeaders {
+		data, err := readImportFile(fileHeader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid import file",
+				"file":    fileHeader.Filename,
+				"details": err.Error(),
+			})
+			return
+		}
+		files = append(files, importer.LDAPDumpFile{Name: fileHeader.Filename, Data: data})
+	}
+
+	snapshot, err := importer.ParseLDAPDomainDump(files...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to parse ldapdomaindump output",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.importLDAPSnapshot(c, projectUID, snapshot)
+}
+
+// ImportADExplorer imports a Sysinternals ADExplorer snapshot (.dat) uploaded
+// as multipart field "file".
+func (h *ImportHandler) ImportADExplorer(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	snapshot, err := importer.ParseADExplorer(fileHeader.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to parse ADExplorer snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.importLDAPSnapshot(c, projectUID, snapshot)
+}
+
+func (h *ImportHandler) importLDAPSnapshot(c *gin.Context, projectUID string, snapshot *importer.LDAPSnapshot) {
+	summary, err := h.ldapImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), snapshot)
+	if err != nil {
+		log.Printf("Sending 500 response while importing %s snapshot because: %v", snapshot.Source, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fmt.Sprintf("failed to import %s snapshot", snapshot.Source),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ImportNessus imports a .nessus export uploaded as multipart field "file".
+// Optional form fields: "minSeverity" (info|low|medium|high|critical, default
+// low) and "deriveCapabilities" (true derives capabilities from known
+// exploitable CVEs).
+func (h *ImportHandler) ImportNessus(c *gin.Context) {
+	h.importVulnerabilityReport(c, "Nessus", importer.ParseNessus)
+}
+
+// ImportOpenVAS imports an OpenVAS / Greenbone XML report, see ImportNessus.
+func (h *ImportHandler) ImportOpenVAS(c *gin.Context) {
+	h.importVulnerabilityReport(c, "OpenVAS", importer.ParseOpenVAS)
+}
+
+func (h *ImportHandler) importVulnerabilityReport(
+	c *gin.Context,
+	scanner string,
+	parse func(data []byte) (*importer.VulnerabilityReport, error),
+) {
+	projectUID := c.Param("projectUID")
+
+	minSeverity := strings.ToLower(c.DefaultPostForm("minSeverity", model.SeverityLow))
+	if model.SeverityRank(minSeverity) == 0 && minSeverity != model.SeverityInfo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid minSeverity %q", minSeverity)})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fmt.Sprintf("failed to parse %s report", scanner),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.vulnImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), report,
+		importer.VulnerabilityImportOptions{
+			MinSeverity:        minSeverity,
+			DeriveCapabilities: c.PostForm("deriveCapabilities") == "true",
+			Files:              []string{fileHeader.Filename},
+		})
+	if err != nil {
+		log.Printf("Sending 500 response while importing %s report because: %v", scanner, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fmt.Sprintf("failed to import %s report", scanner),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ImportPingCastle imports a PingCastle health check (ad_hc_<domain>.xml)
+// uploaded as multipart field "file". Triggered rules become findings on the
+// domain, affected users and groups get risk score and reasons, the password
+// policy becomes the domain SecurityPolicy.
+func (h *ImportHandler) ImportPingCastle(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := importer.ParsePingCastle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to parse PingCastle report",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.riskImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), report, []string{fileHeader.Filename})
+	if err != nil {
+		log.Printf("Sending 500 response while importing PingCastle report because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to import PingCastle report",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetBulkImportFields returns the predicates the columns of a bulk import
+// can be mapped to for the entity type given as query parameter "entityType".
+func (h *ImportHandler) GetBulkImportFields(c *gin.Context) {
+	fields, err := importer.BulkMappableFields(c.Query("entityType"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// PreviewBulkImport reports per row whether a bulk import would create, merge
+// or flag a possible duplicate, without writing anything. See ImportBulk for
+// the request format.
+func (h *ImportHandler) PreviewBulkImport(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	rows, columns, opts, ok := bulkImportRequest(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.bulkImporter.Preview(c.Request.Context(), projectUID, rows, columns, opts)
+	if err != nil {
+		bulkImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// ImportBulk imports a CSV or JSON file (multipart field "file") of entities
+// of one type. Form fields: "entityType" (a schema type), "mapping" (JSON
+// object column → predicate), optional "format" (csv|json, default by file
+// extension), "parentUID"/"parentType", "batchSize" (rows per transaction)
+// and "runId".
+func (h *ImportHandler) ImportBulk(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	rows, columns, opts, ok := bulkImportRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.bulkImporter.Import(c.Request.Context(), projectUID, c.PostForm("runId"), rows, columns, opts)
+	if err != nil {
+		bulkImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func bulkImportError(c *gin.Context, err error) {
+	if errors.Is(err, importer.ErrInvalidBulkImport) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid bulk import",
+			"details": err.Error(),
+		})
+		return
+	}
+	log.Printf("Sending 500 response while importing bulk rows because: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "failed to import bulk rows",
+		"details": err.Error(),
+	})
+}
+
+// bulkImportRequest reads file and options of a bulk import; on error the
+// response has been written already.
+func bulkImportRequest(c *gin.Context) ([]importer.BulkRow, []string, importer.BulkImportOptions, bool) {
+	var opts importer.BulkImportOptions
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": fmt.Sprintf("multipart field 'file' is required: %v", err),
+		})
+		return nil, nil, opts, false
+	}
+
+	data, err := readImportFile(fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import file",
+			"details": err.Error(),
+		})
+		return nil, nil, opts, false
+	}
+
+	if err := json.Unmarshal([]byte(c.PostForm("mapping")), &opts.Mapping); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid mapping",
+			"details": fmt.Sprintf("form field 'mapping' must be a JSON object column → predicate: %v", err),
+		})
+		return nil, nil, opts, false
+	}
+	opts.EntityType = c.PostForm("entityType")
+	opts.ParentUID = c.PostForm("parentUID")
+	opts.ParentType = c.PostForm("parentType")
+	opts.Files = []string{fileHeader.Filename}
+	if batchSize := c.PostForm("batchSize"); batchSize != "" {
+		opts.BatchSize, err = strconv.Atoi(batchSize)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid batchSize %q", batchSize)})
+			return nil, nil, opts, false
+		}
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = importer.DetectBulkFormat(fileHeader.Filename, data)
+	}
+
+	rows, columns, err := importer.ParseBulkRows(format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to parse bulk import file",
+			"details": err.Error(),
+		})
+		return nil, nil, opts, false
+	}
+	return rows, columns, opts, true
+}
+
+// importFileHeaders returns the files of a multi-file upload (repeated field
+// "file" or "files"); on error the response has been written already.
+func importFileHeaders(c *gin.Context) ([]*multipart.FileHeader, bool) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid multipart form",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+
+	fileHeaders := append(form.File["file"], form.File["files"]...)
+	if len(fileHeaders) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one file is required in multipart field 'file'"})
+		return nil, false
+	}
+	if len(fileHeaders) > maxImportFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d files can be imported at once", maxImportFiles)})
+		return nil, false
+	}
+	return fileHeaders, true
+}
+
+// readImportFile reads an uploaded multipart file into memory.
+func readImportFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	if fileHeader.Size > maxImportFileSize {
+		return nil, fmt.Errorf("file exceeds the maximum size of %d bytes", maxImportFileSize)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(file multipart.File) {
+		_ = file.Close()
+	}(file)
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	return data, nil
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/paths"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PathHandler struct {
+	pathService *paths.PathService
+}
+
+func NewPathHandler(pathService *paths.PathService) *PathHandler {
+	return &PathHandler{
+		pathService: pathService,
+	}
+}
+
+// GetPaths computes attack paths ?from=<uid> to ?to=<uid|high-value|domain-admins>
+// (default: high-value targets and Domain Admins). ?mode=cheapest|shortest,
+// ?limit= caps the number of paths.
+func (h *PathHandler) GetPaths(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	query := paths.PathQuery{
+		From: c.Query("from"),
+		To:   c.Query("to"),
+		Mode: c.Query("mode"),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid limit",
+				"details": err.Error(),
+			})
+			return
+		}
+		query.Limit = limit
+	}
+
+	result, err := h.pathService.FindPaths(c.Request.Context(), projectUID, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, paths.ErrInvalidQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, paths.ErrProjectMissing), errors.Is(err, paths.ErrUnknownNode):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Sending 500 response while computing attack paths because: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to compute attack paths",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
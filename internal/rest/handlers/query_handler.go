@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/query"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// querySource is recorded as author of saved queries.
+const querySource = "GraphQuery"
+
+type QueryHandler struct {
+	queryService *query.QueryService
+}
+
+func NewQueryHandler(queryService *query.QueryService) *QueryHandler {
+	return &QueryHandler{
+		queryService: queryService,
+	}
+}
+
+// RunQuery runs the query in the body against the project, e.g.
+// {"query": "users where kerberoastable and member_of* Domain Admins"}.
+func (h *QueryHandler) RunQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	var req struct {
+		Query string `json:"query" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid query request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.queryService.Run(c.Request.Context(), projectUID, req.Query)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetVocabulary lists the entity types, fields and relations queries can use.
+func (h *QueryHandler) GetVocabulary(c *gin.Context) {
+	c.JSON(http.StatusOK, query.GetVocabulary())
+}
+
+func (h *QueryHandler) GetSavedQueries(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	saved, err := h.queryService.GetSavedQueries(c.Request.Context(), projectUID)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+func (h *QueryHandler) GetSavedQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+	queryID := c.Param("queryID")
+
+	saved, err := h.queryService.GetSavedQuery(c.Request.Context(), projectUID, queryID)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// SaveQuery stores a named query in the project after validating it.
+func (h *QueryHandler) SaveQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	var input query.SavedQueryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid saved query",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	saved, err := h.queryService.SaveQuery(c.Request.Context(), projectUID, input, querySource)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+func (h *QueryHandler) UpdateSavedQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+	queryID := c.Param("queryID")
+
+	var input query.SavedQueryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid saved query",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	saved, err := h.queryService.UpdateSavedQuery(c.Request.Context(), projectUID, queryID, input, querySource)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+func (h *QueryHandler) DeleteSavedQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+	queryID := c.Param("queryID")
+
+	if err := h.queryService.DeleteSavedQuery(c.Request.Context(), projectUID, queryID); err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// RunSavedQuery runs a saved query against the current state of the project.
+func (h *QueryHandler) RunSavedQuery(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+	queryID := c.Param("queryID")
+
+	result, err := h.queryService.RunSavedQuery(c.Request.Context(), projectUID, queryID)
+	if err != nil {
+		sendQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func sendQueryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, query.ErrInvalidQuery), errors.Is(err, query.ErrInvalidSavedQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, query.ErrSavedQueryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, query.ErrDuplicateSavedQuery):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while querying the graph because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to query the graph",
+			"details": err.Error(),
+		})
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"RedPaths-server/pkg/input"
+	"RedPaths-server/pkg/module_exec"
 	"RedPaths-server/pkg/service/redpaths"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -67,6 +69,10 @@ func (h *RedPathsModuleHandler) RunAttackVector(c *gin.Context) {
 
 	runUid, err := h.redPathsModuleService.RunAttackVector(c.Request.Context(), moduleKey, &params)
 	if err != nil {
+		if errors.Is(err, module_exec.ErrMissingTools) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
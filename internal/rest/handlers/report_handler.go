@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/report"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	reportService *report.ReportService
+}
+
+func NewReportHandler(reportService *report.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// CreateReport renders and stores a report of the project. The body selects
+// the format (markdown, html, pdf) and optionally a title and a custom Go
+// template; PDF reports do not accept a custom template.
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	var opts report.Options
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	created, err := h.reportService.Create(c.Request.Context(), projectUID, opts, report.ReportSource)
+	if err != nil {
+		switch {
+		case errors.Is(err, report.ErrUnknownFormat), errors.Is(err, report.ErrCustomPDFTemplate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, report.ErrNoPDFRenderer):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Sending 500 response while creating report because: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to create report",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetReports lists the stored reports of the project.
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	reports, err := h.reportService.GetAll(c.Request.Context(), projectUID)
+	if err != nil {
+		log.Printf("Sending 500 response while loading reports because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to load reports",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// DownloadReport sends the stored artifact as attachment.
+func (h *ReportHandler) DownloadReport(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+	reportID := c.Param("reportID")
+
+	stored, err := h.reportService.Get(c.Request.Context(), projectUID, reportID)
+	if err != nil {
+		log.Printf("Sending 500 response while loading report because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to load report",
+			"details": err.Error(),
+		})
+		return
+	}
+	if stored == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", stored.FileName))
+	c.Data(http.StatusOK, stored.ContentType, stored.Content)
+}
+
+// GetReportTemplate returns the built-in template of ?format=, as starting
+// point for custom templates.
+func (h *ReportHandler) GetReportTemplate(c *gin.Context) {
+	tmpl, err := report.DefaultTemplate(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(tmpl))
+}
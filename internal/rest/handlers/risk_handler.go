@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/paths"
+	"RedPaths-server/pkg/service/risk"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RiskHandler struct {
+	riskService *risk.RiskService
+}
+
+func NewRiskHandler(riskService *risk.RiskService) *RiskHandler {
+	return &RiskHandler{
+		riskService: riskService,
+	}
+}
+
+// GetTopRisks returns the riskiest entities of the project with the reasons
+// of their score. ?type=User|Group|Host|Domain filters, ?limit= caps the
+// number of entries.
+func (h *RiskHandler) GetTopRisks(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	query := risk.RiskQuery{Type: c.Query("type")}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid limit",
+				"details": err.Error(),
+			})
+			return
+		}
+		query.Limit = limit
+	}
+
+	result, err := h.riskService.TopRisks(c.Request.Context(), projectUID, query)
+	if err != nil {
+		sendRiskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RecomputeRisks rescores every entity of the project.
+func (h *RiskHandler) RecomputeRisks(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	summary, err := h.riskService.Recompute(c.Request.Context(), projectUID, "RiskScoring")
+	if err != nil {
+		sendRiskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// AddFoothold records the entity in the body as compromised and rescores the
+// project.
+func (h *RiskHandler) AddFoothold(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	var req struct {
+		EntityUID string `json:"entity_uid" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid foothold request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.riskService.AddFoothold(c.Request.Context(), projectUID, req.EntityUID, "RiskScoring")
+	if err != nil {
+		sendRiskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, summary)
+}
+
+func sendRiskError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, risk.ErrInvalidQuery), errors.Is(err, risk.ErrInvalidFoothold):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, paths.ErrProjectMissing), errors.Is(err, paths.ErrUnknownNode):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while scoring risks because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to score risks",
+			"details": err.Error(),
+		})
+	}
+}
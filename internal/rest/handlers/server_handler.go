@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"RedPaths-server/pkg/adapter"
 	"RedPaths-server/pkg/service/active_directory"
 	"net/http"
 
@@ -20,3 +21,10 @@ func (h *ServerHandler) GetHealth(c *gin.Context) {
 		"message": "hello",
 	})
 }
+
+// GetTools lists all registered tool adapters with their availability,
+// version, resolved binary path and capabilities.
+func (h *ServerHandler) GetTools(c *gin.Context) {
+	inventory := adapter.GetAdapterFactory().Inventory(c.Request.Context())
+	c.JSON(http.StatusOK, inventory)
+}
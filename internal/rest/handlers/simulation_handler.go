@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"RedPaths-server/pkg/service/paths"
+	"RedPaths-server/pkg/service/simulation"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SimulationHandler struct {
+	simulationService *simulation.SimulationService
+}
+
+func NewSimulationHandler(simulationService *simulation.SimulationService) *SimulationHandler {
+	return &SimulationHandler{
+		simulationService: simulationService,
+	}
+}
+
+// Simulate runs an attack simulation from the footholds in the body within
+// the given noise (and optional cost) budget.
+func (h *SimulationHandler) Simulate(c *gin.Context) {
+	projectUID := c.Param("projectUID")
+
+	var req simulation.SimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid simulation request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.simulationService.Simulate(c.Request.Context(), projectUID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, simulation.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, paths.ErrProjectMissing), errors.Is(err, paths.ErrUnknownNode):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Sending 500 response while simulating attacks because: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to run simulation",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
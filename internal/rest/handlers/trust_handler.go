@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"RedPaths-server/internal/rest/requests"
+	"RedPaths-server/internal/utils"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/service/active_directory"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrustHandler struct {
+	trustService *active_directory.TrustService
+}
+
+func NewTrustHandler(trustService *active_directory.TrustService) *TrustHandler {
+	return &TrustHandler{
+		trustService: trustService,
+	}
+}
+
+// GetDomainTrusts lists the trusts of a domain with their trusted domain.
+func (h *TrustHandler) GetDomainTrusts(c *gin.Context) {
+	trusts, err := h.trustService.GetDomainTrusts(c.Request.Context(), c.Param("domainUID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trusts)
+}
+
+// AddDomainTrust creates or updates the trust of a domain towards another.
+func (h *TrustHandler) AddDomainTrust(c *gin.Context) {
+	domainUID := c.Param("domainUID")
+
+	var request requests.AddTrustRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data to add a trust",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	trust := rpad.NewTrustBuilder().
+		WithTrustType(request.TrustType).
+		WithDirection(request.Direction).
+		WithTransitivity(request.IsTransitive).
+		WithSIDFiltering(request.SIDFiltering).
+		Build()
+
+	result, err := h.trustService.UpsertTrust(
+		c.Request.Context(),
+		request.AssertionContext,
+		c.Param("projectUID"),
+		domainUID,
+		&trust,
+		&rpad.Domain{UID: request.TrustedDomainUID, Name: request.TrustedDomainName},
+		"user",
+	)
+	if err != nil {
+		if errors.Is(err, active_directory.ErrInvalidTrust) || errors.Is(err, utils.ErrUIDRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Sending client 500 error response for adding trust to domain %s with message %s", domainUID, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add trust",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Trust has been stored",
+		"trust":   result,
+	})
+}
+
+// AnalyseTrusts lists all trusts of the project with their weaknesses
+// (SID filtering gaps, transitive and external trusts) and the trust paths
+// between the domains.
+func (h *TrustHandler) AnalyseTrusts(c *gin.Context) {
+	analysis, err := h.trustService.AnalyseTrusts(c.Request.Context(), c.Param("projectUID"))
+	if err != nil {
+		if errors.Is(err, active_directory.ErrTrustProjectMissing) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Sending 500 response while analysing trusts because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to analyse trusts",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
@@ -4,6 +4,7 @@ import (
 	restcontext "RedPaths-server/internal/rest/context"
 	rpad "RedPaths-server/pkg/model/active_directory"
 	"RedPaths-server/pkg/service/active_directory"
+	"errors"
 	"log"
 	"net/http"
 
@@ -11,12 +12,14 @@ import (
 )
 
 type UserHandler struct {
-	userService *active_directory.UserService
+	userService       *active_directory.UserService
+	membershipService *active_directory.MembershipService
 }
 
-func NewUserHandler(userService *active_directory.UserService) *UserHandler {
+func NewUserHandler(userService *active_directory.UserService, membershipService *active_directory.MembershipService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:       userService,
+		membershipService: membershipService,
 	}
 }
 
@@ -88,3 +91,42 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		"updated_user": updatedUser,
 	})
 }
+
+// GetUserMemberships returns the groups a user is effectively a member of,
+// nested groups resolved, with the privileges they grant. ?recursive=false
+// limits the result to the direct groups.
+func (h *UserHandler) GetUserMemberships(c *gin.Context) {
+	memberships, err := h.membershipService.PrincipalMemberships(
+		c.Request.Context(), c.Param("projectUID"), c.Param("userUID"), c.Query("recursive") != "false")
+	if err != nil {
+		sendMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// PropagatePrivileges writes the effective privileges (is_privileged,
+// can_dcsync, can_rdp) of the nested group memberships onto all users.
+func (h *UserHandler) PropagatePrivileges(c *gin.Context) {
+	summary, err := h.membershipService.PropagatePrivileges(c.Request.Context(), c.Param("projectUID"), "UserInput")
+	if err != nil {
+		sendMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func sendMembershipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, active_directory.ErrMembershipProjectMissing), errors.Is(err, active_directory.ErrPrincipalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Sending 500 response while resolving memberships because: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to resolve memberships",
+			"details": err.Error(),
+		})
+	}
+}
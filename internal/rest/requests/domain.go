@@ -6,3 +6,15 @@ type AddDomainRequest struct {
 	Name             string            `json:"name" binding:"required" validate:"required"`
 	AssertionContext assertion.Context `json:"assertion_ctx" binding:"required"`
 }
+
+// AddTrustRequest describes a trust of the domain in the path. The trusted
+// domain is given by UID or, if not in the project yet, by name.
+type AddTrustRequest struct {
+	TrustedDomainUID  string            `json:"trusted_domain_uid"`
+	TrustedDomainName string            `json:"trusted_domain_name"`
+	TrustType         string            `json:"trust_type"`
+	Direction         string            `json:"direction" binding:"required"`
+	IsTransitive      bool              `json:"is_transitive"`
+	SIDFiltering      bool              `json:"sid_filtering_enabled"`
+	AssertionContext  assertion.Context `json:"assertion_ctx" binding:"required"`
+}
@@ -8,12 +8,24 @@ type AddGPOLinkRequest struct {
 	AssertionContext assertion.Context `json:"assertion_ctx" binding:"required"`
 }
 
+// GPOLinkPayload describes a link; a link without is_enabled is enabled.
 type GPOLinkPayload struct {
-	LinkOrder  int  `json:"link_order" binding:"required" validate:"required"`
-	IsEnforced bool `json:"is_enforced"`
-	IsEnabled  bool `json:"is_enabled"`
+	LinkOrder  int   `json:"link_order" binding:"required" validate:"required"`
+	IsEnforced bool  `json:"is_enforced"`
+	IsEnabled  *bool `json:"is_enabled"`
 }
 
 type GPOPayload struct {
 	Name string `json:"name" binding:"required" validate:"required"`
 }
+
+// AddGPOSettingRequest describes a setting of a GPO. The principal is whom
+// the setting empowers (restricted group member, holder of a user right),
+// given by UID and type (User, Group, Host).
+type AddGPOSettingRequest struct {
+	SettingType      string            `json:"setting_type" binding:"required"`
+	Value            string            `json:"value" binding:"required"`
+	PrincipalUID     string            `json:"principal_uid"`
+	PrincipalType    string            `json:"principal_type"`
+	AssertionContext assertion.Context `json:"assertion_ctx" binding:"required"`
+}
@@ -5,9 +5,17 @@ import (
 	"RedPaths-server/internal/rest/middleware"
 	rpservice "RedPaths-server/pkg/service"
 	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/archive"
 	"RedPaths-server/pkg/service/change"
 	"RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/exporter"
+	"RedPaths-server/pkg/service/importer"
+	"RedPaths-server/pkg/service/paths"
+	"RedPaths-server/pkg/service/query"
 	"RedPaths-server/pkg/service/redpaths"
+	"RedPaths-server/pkg/service/report"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/simulation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +25,7 @@ func RegisterServerHandlers(router *gin.Engine) {
 	serverGroup := router.Group("/server")
 	{
 		serverGroup.GET("/health", serverHandler.GetHealth)
+		serverGroup.GET("/tools", serverHandler.GetTools)
 	}
 }
 
@@ -31,7 +40,12 @@ func RegisterProjectHandlers(
 	dirNodeService *active_directory.DirectoryNodeService,
 	activeDirectoryService *active_directory.ActiveDirectoryService,
 	gpoService *active_directory.GPOService,
+	trustService *active_directory.TrustService,
 	capabilityService *engine.CapabilityService,
+	aclDerivationService *engine.ACLDerivationService,
+	delegationAnalysisService *engine.DelegationAnalysisService,
+	gpoAnalysisService *engine.GPOAnalysisService,
+	membershipService *active_directory.MembershipService,
 	changeService *change.ChangeService,
 ) {
 
@@ -40,10 +54,14 @@ func RegisterProjectHandlers(
 	domainHandler := handlers.NewDomainHandler(projectService, dirNodeService, domainService, gpoService)
 	hostHandler := handlers.NewHostHandler(hostService, capabilityService)
 	serviceHandler := handlers.NewServiceHandler(serviceService)
-	userHandler := handlers.NewUserHandler(userService)
+	userHandler := handlers.NewUserHandler(userService, membershipService)
+	groupHandler := handlers.NewGroupHandler(membershipService)
 	dirNodeHandler := handlers.NewDirectoryNodeHandler(dirNodeService)
 	adHandler := handlers.NewActiveDirectoryHandler(activeDirectoryService)
-	capabilityHandler := handlers.NewCapabilityHandler(capabilityService)
+	capabilityHandler := handlers.NewCapabilityHandler(capabilityService, aclDerivationService)
+	delegationHandler := handlers.NewDelegationHandler(delegationAnalysisService)
+	trustHandler := handlers.NewTrustHandler(trustService)
+	gpoHandler := handlers.NewPGOHandler(gpoService, gpoAnalysisService)
 	changeHandler := handlers.NewChangeHandler(changeService)
 
 	router.Use(middleware.StripDgraphPrefixMiddleware)
@@ -130,10 +148,15 @@ func RegisterProjectHandlers(
 			// project.DELETE("/domains/:domainUID/directory-nodes/:dirNodeUID", domainHandler.RemoveDirectoryNode)
 
 			project.GET("/domains/:domainUID/gpos", domainHandler.GetDomainGPOs)
+			project.GET("/domains/:domainUID/security-policy", domainHandler.GetDomainSecurityPolicy)
 			project.GET("/domains/:domainUID/gpos/all", domainHandler.GetDomainGPOLib)
 			project.POST("/domains/:domainUID/gpos", domainHandler.LinkDomainGPO)
 			// project.DELETE("/domains/:domainUID/gpos/:gpoUID", domainHandler.UnlinkDomainGPO)
 
+			project.GET("/domains/:domainUID/trusts", trustHandler.GetDomainTrusts)
+			project.POST("/domains/:domainUID/trusts", trustHandler.AddDomainTrust)
+			project.GET("/trusts/analysis", trustHandler.AnalyseTrusts)
+
 			// =========================================================
 			// DIRECTORY NODES (OU / Container)
 			// =========================================================
@@ -151,6 +174,8 @@ func RegisterProjectHandlers(
 			project.POST("/directory-nodes/:dirNodeUID/children", dirNodeHandler.AddChildDirectoryNode)
 			// project.DELETE("/directory-nodes/:dirNodeUID/children/:childUID", dirNodeHandler.RemoveChildDirectoryNode)
 
+			project.POST("/directory-nodes/:dirNodeUID/gpos", dirNodeHandler.LinkDirectoryNodeGPO)
+
 			// project.GET("/directory-nodes/:dirNodeUID/acls", dirNodeHandler.GetACLs) // TODO implement
 
 			// =========================================================
@@ -170,6 +195,8 @@ func RegisterProjectHandlers(
 			project.POST("/hosts/:hostUID/capabilities", hostHandler.AddCapability)
 			// project.DELETE("/hosts/:hostUID/capabilities/:capabilityUID", hostHandler.RemoveCapability)
 
+			project.GET("/hosts/:hostUID/vulnerabilities", hostHandler.GetLinkedVulnerabilities)
+
 			// =========================================================
 			// SERVICES
 			// =========================================================
@@ -184,12 +211,36 @@ func RegisterProjectHandlers(
 			// project.DELETE("/users/:userUID", userHandler.DeleteUser)
 
 			project.GET("/users/:userUID/changes", handlers.EntityType("User"), changeHandler.GetChanges)
+			project.GET("/users/:userUID/memberships", userHandler.GetUserMemberships)
+			project.POST("/users/privileges", userHandler.PropagatePrivileges)
+
+			// =========================================================
+			// GROUPS
+			// =========================================================
+			project.GET("/groups/:groupUID/members", groupHandler.GetGroupMembers)
+			project.GET("/groups/:groupUID/memberships", groupHandler.GetGroupMemberships)
 
 			// =========================================================
 			// CAPABILITIES
 			// =========================================================
+			project.POST("/capabilities/derive", capabilityHandler.DeriveCapabilities)
 			// project.DELETE("/capabilities/:capabilityUID", capabilityHandler.DeleteCapability)
 
+			// =========================================================
+			// DELEGATIONS
+			// =========================================================
+			project.GET("/delegations", delegationHandler.GetDelegations)
+			project.POST("/delegations/analyze", delegationHandler.AnalyseDelegations)
+
+			// =========================================================
+			// GPOS
+			// =========================================================
+			project.GET("/gpos/analysis", gpoHandler.GetGPOAnalysis)
+			project.POST("/gpos/analyze", gpoHandler.AnalyseGPOs)
+			project.GET("/gpos/effective/:objectUID", gpoHandler.GetEffectivePolicy) // Domain, OU, Host oder User
+			project.GET("/gpos/:gpoUID/settings", gpoHandler.GetGPOSettings)
+			project.POST("/gpos/:gpoUID/settings", gpoHandler.AddGPOSetting)
+
 			// =========================================================
 			// TARGETS
 			// =========================================================
@@ -208,6 +259,132 @@ func RegisterProjectHandlers(
 	}
 }
 
+func RegisterImportHandlers(
+	router *gin.Engine,
+	projectService *active_directory.ProjectService,
+	bloodHoundImporter *importer.BloodHoundImporter,
+	nmapImporter *importer.NmapImporter,
+	vulnImporter *importer.VulnerabilityImporter,
+	ldapImporter *importer.LDAPImporter,
+	bulkImporter *importer.BulkImporter,
+	riskImporter *importer.RiskImporter,
+	importRunService *importer.ImportRunService,
+	rollbackService *importer.RollbackService,
+) {
+	importHandler := handlers.NewImportHandler(bloodHoundImporter, nmapImporter, vulnImporter, ldapImporter, bulkImporter, riskImporter, importRunService, rollbackService)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		imports := project.Group("/imports")
+		{
+			imports.GET("", importHandler.GetImportRuns)
+			imports.GET("/:runID/rollback", importHandler.PreviewRollback)
+			imports.POST("/:runID/rollback", importHandler.Rollback)
+			imports.POST("/bloodhound", importHandler.ImportBloodHound)
+			imports.POST("/nmap", importHandler.ImportNmap)
+			imports.POST("/nessus", importHandler.ImportNessus)
+			imports.POST("/openvas", importHandler.ImportOpenVAS)
+			imports.POST("/ldapdomaindump", importHandler.ImportLDAPDomainDump)
+			imports.POST("/adexplorer", importHandler.ImportADExplorer)
+			imports.POST("/pingcastle", importHandler.ImportPingCastle)
+			imports.GET("/bulk/fields", importHandler.GetBulkImportFields)
+			imports.POST("/bulk/preview", importHandler.PreviewBulkImport)
+			imports.POST("/bulk", importHandler.ImportBulk)
+		}
+	}
+}
+
+func RegisterReportHandlers(router *gin.Engine, projectService *active_directory.ProjectService, reportService *report.ReportService) {
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		reports := project.Group("/reports")
+		{
+			reports.GET("", reportHandler.GetReports)
+			reports.POST("", reportHandler.CreateReport)
+			reports.GET("/template", reportHandler.GetReportTemplate)
+			reports.GET("/:reportID", reportHandler.DownloadReport)
+		}
+	}
+}
+
+func RegisterExportHandlers(router *gin.Engine, projectService *active_directory.ProjectService, bloodHoundExporter *exporter.BloodHoundExporter, graphExporter *exporter.GraphExporter, stixExporter *exporter.StixExporter) {
+	exportHandler := handlers.NewExportHandler(bloodHoundExporter, graphExporter, stixExporter)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		exports := project.Group("/exports")
+		{
+			exports.GET("/bloodhound", exportHandler.ExportBloodHound)
+			exports.GET("/graph", exportHandler.ExportGraph)
+			exports.GET("/stix", exportHandler.ExportStix)
+		}
+	}
+}
+
+func RegisterArchiveHandlers(router *gin.Engine, projectService *active_directory.ProjectService, archiveService *archive.ArchiveService) {
+	archiveHandler := handlers.NewArchiveHandler(archiveService)
+
+	router.POST("/projects/archive", archiveHandler.ImportArchive)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		project.GET("/archive", archiveHandler.ExportArchive)
+	}
+}
+
+func RegisterPathHandlers(router *gin.Engine, projectService *active_directory.ProjectService, pathService *paths.PathService, simulationService *simulation.SimulationService) {
+	pathHandler := handlers.NewPathHandler(pathService)
+	simulationHandler := handlers.NewSimulationHandler(simulationService)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		project.GET("/paths", pathHandler.GetPaths)
+		project.POST("/simulations", simulationHandler.Simulate)
+	}
+}
+
+func RegisterRiskHandlers(router *gin.Engine, projectService *active_directory.ProjectService, riskService *risk.RiskService) {
+	riskHandler := handlers.NewRiskHandler(riskService)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		risks := project.Group("/risks")
+		{
+			risks.GET("/top", riskHandler.GetTopRisks)
+			risks.POST("/recompute", riskHandler.RecomputeRisks)
+			risks.POST("/footholds", riskHandler.AddFoothold)
+		}
+	}
+}
+
+func RegisterQueryHandlers(router *gin.Engine, projectService *active_directory.ProjectService, queryService *query.QueryService) {
+	queryHandler := handlers.NewQueryHandler(queryService)
+
+	project := router.Group("/projects/:projectUID")
+	project.Use(middleware.ProjectContext(projectService))
+	{
+		queries := project.Group("/queries")
+		{
+			queries.POST("/run", queryHandler.RunQuery)
+			queries.GET("/vocabulary", queryHandler.GetVocabulary)
+			queries.GET("", queryHandler.GetSavedQueries)
+			queries.POST("", queryHandler.SaveQuery)
+			queries.GET("/:queryID", queryHandler.GetSavedQuery)
+			queries.PUT("/:queryID", queryHandler.UpdateSavedQuery)
+			queries.DELETE("/:queryID", queryHandler.DeleteSavedQuery)
+			queries.POST("/:queryID/run", queryHandler.RunSavedQuery)
+		}
+	}
+}
+
 func RegisterRedPathsModuleHandlers(router *gin.Engine, redPathsModuleService *redpaths.ModuleService, projectService *active_directory.ProjectService) {
 	moduleHandler := handlers.NewRedPathsModuleHandler(redPathsModuleService)
 
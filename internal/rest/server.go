@@ -4,9 +4,17 @@ import (
 	"RedPaths-server/pkg/module_exec"
 	"RedPaths-server/pkg/service"
 	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/archive"
 	"RedPaths-server/pkg/service/change"
 	"RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/exporter"
+	"RedPaths-server/pkg/service/importer"
+	"RedPaths-server/pkg/service/paths"
+	"RedPaths-server/pkg/service/query"
 	"RedPaths-server/pkg/service/redpaths"
+	"RedPaths-server/pkg/service/report"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/simulation"
 	"fmt"
 	"io"
 	"log"
@@ -57,16 +65,63 @@ func StartServer(port string, postgresCon *gorm.DB, dgraphCon *dgo.Dgraph) {
 	dirNodeService, err := active_directory.NewDirectoryNodeService(dgraphCon)
 	activeDirectoryService, err := active_directory.NewActiveDirectoryService(dgraphCon)
 	gpoService, err := active_directory.NewGPOService(dgraphCon)
+	trustService, err := active_directory.NewTrustService(dgraphCon)
 	capabilityService, err := engine.NewCapabilityService(dgraphCon, postgresCon)
+	aclDerivationService, err := engine.NewACLDerivationService(dgraphCon, postgresCon)
+	delegationAnalysisService, err := engine.NewDelegationAnalysisService(dgraphCon, postgresCon)
+	gpoAnalysisService, err := engine.NewGPOAnalysisService(dgraphCon, postgresCon)
+	membershipService, err := active_directory.NewMembershipService(dgraphCon)
 	moduleExecutor := module_exec.GlobalRegistry
 	redPathsModuleService, err := redpaths.NewModuleService(moduleExecutor, moduleExecutor.RecommendationEngine, postgresCon)
 	logService, err := service.NewLogService(postgresCon)
 	changeService, err := change.NewChangeService(postgresCon)
+	bloodHoundImporter, err := importer.NewBloodHoundImporter(dgraphCon, postgresCon)
 	if err != nil {
 		log.Fatalf("Failed to initialize ProjectService: %v", err)
 	}
-	RegisterProjectHandlers(router, projectService, logService, domainService, hostService, serviceService, userService, dirNodeService, activeDirectoryService, gpoService, capabilityService, changeService)
+	nmapImporter := importer.NewNmapImporter(dgraphCon, postgresCon)
+	vulnImporter, err := importer.NewVulnerabilityImporter(dgraphCon, postgresCon)
+	if err != nil {
+		log.Fatalf("Failed to initialize VulnerabilityImporter: %v", err)
+	}
+	ldapImporter, err := importer.NewLDAPImporter(dgraphCon, postgresCon)
+	if err != nil {
+		log.Fatalf("Failed to initialize LDAPImporter: %v", err)
+	}
+	bulkImporter, err := importer.NewBulkImporter(dgraphCon, postgresCon)
+	if err != nil {
+		log.Fatalf("Failed to initialize BulkImporter: %v", err)
+	}
+	riskImporter, err := importer.NewRiskImporter(dgraphCon, postgresCon)
+	if err != nil {
+		log.Fatalf("Failed to initialize RiskImporter: %v", err)
+	}
+	importRunService := importer.NewImportRunService(postgresCon)
+	rollbackService, err := importer.NewRollbackService(dgraphCon, postgresCon)
+	if err != nil {
+		log.Fatalf("Failed to initialize RollbackService: %v", err)
+	}
+	reportService, err := report.NewReportService(dgraphCon, postgresCon, redPathsModuleService)
+	if err != nil {
+		log.Fatalf("Failed to initialize ReportService: %v", err)
+	}
+	bloodHoundExporter := exporter.NewBloodHoundExporter(dgraphCon)
+	graphExporter := exporter.NewGraphExporter(dgraphCon)
+	stixExporter := exporter.NewStixExporter(dgraphCon, postgresCon)
+	archiveService := archive.NewArchiveService(dgraphCon, postgresCon)
+	pathService := paths.NewPathService(dgraphCon)
+	simulationService := simulation.NewSimulationService(dgraphCon)
+	riskService := risk.NewRiskService(dgraphCon)
+	queryService := query.NewQueryService(dgraphCon, postgresCon)
+	RegisterProjectHandlers(router, projectService, logService, domainService, hostService, serviceService, userService, dirNodeService, activeDirectoryService, gpoService, trustService, capabilityService, aclDerivationService, delegationAnalysisService, gpoAnalysisService, membershipService, changeService)
 	RegisterRedPathsModuleHandlers(router, redPathsModuleService, projectService)
+	RegisterImportHandlers(router, projectService, bloodHoundImporter, nmapImporter, vulnImporter, ldapImporter, bulkImporter, riskImporter, importRunService, rollbackService)
+	RegisterReportHandlers(router, projectService, reportService)
+	RegisterExportHandlers(router, projectService, bloodHoundExporter, graphExporter, stixExporter)
+	RegisterArchiveHandlers(router, projectService, archiveService)
+	RegisterPathHandlers(router, projectService, pathService, simulationService)
+	RegisterRiskHandlers(router, projectService, riskService)
+	RegisterQueryHandlers(router, projectService, queryService)
 	RegisterServerHandlers(router)
 	logger.Info("Starting server")
 
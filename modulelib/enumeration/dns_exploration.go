@@ -10,7 +10,6 @@ import (
 	plugin "RedPaths-server/pkg/module_exec"
 	engine4 "RedPaths-server/pkg/service/upsert"
 	"RedPaths-server/pkg/sse"
-	"context"
 	"fmt"
 )
 
@@ -94,7 +93,7 @@ func (n *DNSExplorer) GetMetadata() *interfaces.ModuleMetadata {
 }
 
 func (n *DNSExplorer) ExecuteModule(params *input.Parameter, logger *sse.SSELogger) error {
-	ctx := context.Background()
+	ctx := params.Context()
 	n.logger = logger
 
 	projectUID := params.ProjectUID
@@ -1,29 +1,24 @@
 package enumeration
 
 import (
-	"RedPaths-server/modulelib/enumeration/internal"
 	"RedPaths-server/pkg/adapter"
 	"RedPaths-server/pkg/adapter/scan"
-	"RedPaths-server/pkg/adapter/serializable"
 	"RedPaths-server/pkg/interfaces"
 	"RedPaths-server/pkg/interfaces/module"
 	"RedPaths-server/pkg/model"
-	"RedPaths-server/pkg/model/active_directory"
 	"RedPaths-server/pkg/model/events"
 	"RedPaths-server/pkg/model/redpaths/input"
 	"RedPaths-server/pkg/model/rpsdk"
-	"RedPaths-server/pkg/model/utils/assertion"
 	plugin "RedPaths-server/pkg/module_exec"
-	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/service/network"
 	"RedPaths-server/pkg/sse"
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"regexp"
 	"strings"
 	"time"
-
-	"github.com/antchfx/xmlquery"
 )
 
 func init() {
@@ -35,6 +30,7 @@ type NetworkExplorer struct {
 	configKey string
 	services  *rpsdk.Services
 	logger    *sse.SSELogger
+	processor *network.ScanProcessor
 }
 
 func (n *NetworkExplorer) SetServices(services *rpsdk.Services) { n.services = services }
@@ -48,6 +44,9 @@ func (n *NetworkExplorer) GetMetadata() *interfaces.ModuleMetadata {
 		Prerequisites: []*module.Prerequisite{
 			{Type: module.PrereqNetworkAccess, Name: "Network Reachability", Required: true, Conditions: "network.reachable = true"},
 			{Type: module.PrereqKnowledge, Name: "Target Network Range", Required: true, Conditions: "target.cidr != null"},
+			{Type: module.PrereqTool, Name: "nmap", Description: "Nmap binary for port and service scanning", Required: true},
+			{Type: module.PrereqTool, Name: "masscan", Description: "Optional fast port discovery before the nmap service scan", Required: false},
+			{Type: module.PrereqTool, Name: "rustscan", Description: "Optional fast port discovery if masscan is not available", Required: false},
 		},
 		Provides: []*module.Capability{
 			{Type: "network_discovery", Name: "Host Discovery", Confidence: 0.95, Metadata: map[string]interface{}{"method": "icmp,tcp,syn"}},
@@ -58,578 +57,386 @@ func (n *NetworkExplorer) GetMetadata() *interfaces.ModuleMetadata {
 	}
 }
 
-// ── Assertion contexts ────────────────────────────────────────────────────────
-
-var (
-	assertCtxAD = assertion.Context{
-		Confidence: float64Ptr(0.85),
-		Status:     strPtr("scan_detected"),
-		HighValue:  boolPtr(false),
-	}
-	assertCtxHost = assertion.Context{
-		Confidence: float64Ptr(0.95),
-		Status:     strPtr("scan_detected"),
-		HighValue:  boolPtr(false),
-	}
-	assertCtxService = assertion.Context{
-		Confidence: float64Ptr(0.90),
-		Status:     strPtr("scan_detected"),
-		HighValue:  boolPtr(false),
-	}
-)
-
-func float64Ptr(v float64) *float64 { return &v }
-func strPtr(v string) *string       { return &v }
-func boolPtr(v bool) *bool          { return &v }
-
-// ── DC port scoring ───────────────────────────────────────────────────────────
-
-type dcPortRule struct {
-	port       string
-	score      int
-	definitive bool
-}
-
-var dcPortRules = []dcPortRule{
-	{port: "88", score: 3, definitive: true},
-	{port: "389", score: 2},
-	{port: "636", score: 2},
-	{port: "3268", score: 2},
-	{port: "3269", score: 2},
-	{port: "464", score: 1},
-	{port: "53", score: 1},
-	{port: "445", score: 0},
-}
-
-const dcScoreThreshold = 3
-
-func isDomainController(document *xmlquery.Node, xb *internal.XPathBuilder) (bool, int, []string) {
-	score := 0
-	var matched []string
-
-	for _, rule := range dcPortRules {
-		if xmlquery.FindOne(document, xb.OpenPort(rule.port)) != nil {
-			matched = append(matched, rule.port)
-			score += rule.score
-			if rule.definitive {
-				return true, score, matched
-			}
-		}
-	}
-
-	return score >= dcScoreThreshold, score, matched
-}
-
 // ── ExecuteModule ─────────────────────────────────────────────────────────────
 
+// discoveryAdapters are tried in order for the fast port-discovery stage.
+// If none is available, or the discovery scan fails, the module falls back to
+// a single full nmap scan.
+var discoveryAdapters = []string{"masscan", "rustscan"}
+
 func (n *NetworkExplorer) ExecuteModule(params *input.Parameter, logger *sse.SSELogger) error {
 	n.logger = logger
 	log.Printf("Executing module key: %s", n.configKey)
 	logger.Info("Starting module: %s", n.configKey)
 
+	scanOpts, err := n.resolveScanOptions(params.Context(), params)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid scan configuration: %v", err))
+		return err
+	}
+
 	sse.NewEvent(events.ScanStart).
-		WithData("target_network", params.Inputs["network"]).
-		WithData("ports", "1-1024").
+		WithData("target_network", strings.Join(scanOpts.targets, ",")).
+		WithData("ports", scanOpts.portRange).
+		WithData("udp", scanOpts.udp).
 		Log(logger)
 
 	factory := adapter.GetAdapterFactory()
-	scanAdapter, err := factory.GetScanAdapter("nmap")
+	nmapAdapter, err := factory.GetScanAdapter("nmap")
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to get scan adapter: %v", err))
 		return err
 	}
 
-	scanCtx, scanCancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer scanCancel()
-
-	targetNetwork := "127.0.0.1" // Testing only
-
-	scanResult, err := scanAdapter.Scan(
-		scanCtx,
-		scan.WithTargets([]string{targetNetwork}),
-		scan.WithPortRange("1-1024"),
-		scan.WithServiceScan(),
-		scan.WithScriptScan(),
-		interfaces.WithTimeout(30*time.Minute),
-	)
+	n.processor, err = network.NewScanProcessor(n.services, network.Options{
+		ProjectUID: params.ProjectUID,
+		Actor:      n.configKey,
+		Logger:     logger,
+	})
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		logger.Error(fmt.Sprintf("Failed to prepare scan processing: %v", err))
+		return err
 	}
 
-	nmapResult, ok := scanResult.(*scan.NmapScanResult)
-	if !ok {
-		return fmt.Errorf("could not map scan result to nmap result: %v", scanResult)
+	scanCtx, scanCancel := context.WithTimeout(params.Context(), scanOpts.timeout)
+	defer scanCancel()
+
+	// The fast discovery stage is TCP only and only understands IPs and
+	// CIDRs. UDP scans, hostnames and octet ranges go straight to nmap.
+	var discoveryAdapter interfaces.ScanAdapter
+	discoverable, direct := splitDiscoveryTargets(scanOpts.targets)
+	if !scanOpts.udp && len(discoverable) > 0 {
+		discoveryAdapter = n.selectDiscoveryAdapter(scanCtx, factory)
+	}
+	if discoveryAdapter == nil {
+		discoverable, direct = nil, scanOpts.targets
 	}
 
-	log.Printf("[DEBUG] ExecuteModule: scan done, starting processScanResults")
+	if len(discoverable) > 0 {
+		discovery, err := n.runDiscovery(scanCtx, discoveryAdapter, discoverable, scanOpts)
+		if err != nil {
+			logger.Warning(fmt.Sprintf("Discovery with %s failed, falling back to a full nmap scan: %v",
+				discoveryAdapter.GetName(), err))
+			direct = append(direct, discoverable...)
+		} else {
+			n.runStagedScan(scanCtx, discovery, nmapAdapter, scanOpts)
+		}
+	}
 
-	if err := n.processScanResults(context.Background(), *nmapResult, params); err != nil {
-		return fmt.Errorf("processing scan results failed: %w", err)
+	if len(direct) > 0 {
+		if err := n.runFullScan(scanCtx, nmapAdapter, direct, scanOpts); err != nil {
+			return err
+		}
 	}
 
 	sse.NewEvent(events.ScanComplete).WithData("timestamp", time.Now().Unix()).Log(logger)
 	return nil
 }
 
-// ── processScanResults ────────────────────────────────────────────────────────
+// selectDiscoveryAdapter returns the first available fast port scanner or nil.
+func (n *NetworkExplorer) selectDiscoveryAdapter(ctx context.Context, factory *adapter.AdapterRegistry) interfaces.ScanAdapter {
+	for _, name := range discoveryAdapters {
+		if err := factory.EnsureAvailable(ctx, name); err != nil {
+			log.Printf("[NetworkExplorer] discovery adapter %s unavailable: %v", name, err)
+			continue
+		}
+		scanAdapter, err := factory.GetScanAdapter(name)
+		if err != nil {
+			continue
+		}
+		return scanAdapter
+	}
+	return nil
+}
 
-func (n *NetworkExplorer) processScanResults(
+// runFullScan runs a single nmap service/script scan over the given targets.
+func (n *NetworkExplorer) runFullScan(
 	ctx context.Context,
-	nmapResult scan.NmapScanResult,
-	params *input.Parameter,
+	nmapAdapter interfaces.ScanAdapter,
+	targets []string,
+	scanOpts *networkScanOptions,
 ) error {
-	document, err := nmapResult.GetXMLDocument()
+	scanResult, err := nmapAdapter.Scan(ctx, scanOpts.nmapOptions(targets, scanOpts.portRange)...)
 	if err != nil {
-		return fmt.Errorf("failed to parse nmap XML: %w", err)
+		return fmt.Errorf("scan failed: %w", err)
 	}
 
-	hosts := nmapResult.GetNmapResult().Host
-	log.Printf("[DEBUG] processScanResults: hostCount=%d projectUID=%s",
-		len(hosts), params.ProjectUID)
-
-	if len(hosts) == 0 {
-		log.Printf("[DEBUG] WARNING: host list empty — nothing to process")
-		return nil
+	nmapResult, ok := scanResult.(*scan.NmapScanResult)
+	if !ok {
+		return fmt.Errorf("could not map scan result to nmap result: %v", scanResult)
 	}
 
-	adCache := make(map[string]string)
-	domainCache := make(map[string]string)
-
-	for i, host := range hosts {
-		ip := host.Address[0].Addr
-		log.Printf("[DEBUG] ── host[%d] ip=%s ──────────────────────────", i, ip)
-
-		xb := internal.NewXPathBuilder(ip)
-
-		domainName, strategy := n.extractDomainName(document, xb)
-		log.Printf("[DEBUG] host[%d] ip=%s extractDomainName result: domainName=%q strategy=%q",
-			i, ip, domainName, strategy)
-
-		forestRoot := n.extractForestRoot(xb, document)
-		if forestRoot == "" {
-			forestRoot = domainName
-		}
-
-		log.Printf("[DEBUG] host[%d] ip=%s domainName=%q forestRoot=%q strategy=%q",
-			i, ip, domainName, forestRoot, strategy)
-
-		// ── ActiveDirectory (Forest) ──────────────────────────────────────────
-		var adUID string
-		if forestRoot != "" {
-			if cached, ok := adCache[forestRoot]; ok {
-				adUID = cached
-				log.Printf("[DEBUG] host[%d] AD cache hit forest=%s uid=%s", i, forestRoot, adUID)
-			} else {
-				log.Printf("[DEBUG] host[%d] calling upsertActiveDirectory forest=%s", i, forestRoot)
-				adUID, err = n.upsertActiveDirectory(ctx, params.ProjectUID, forestRoot)
-				if err != nil {
-					log.Printf("[ERROR] host[%d] upsertActiveDirectory failed forest=%s err=%v",
-						i, forestRoot, err)
-				} else {
-					adCache[forestRoot] = adUID
-					log.Printf("[DEBUG] host[%d] upsertActiveDirectory ok forest=%s uid=%s",
-						i, forestRoot, adUID)
-				}
-			}
-		} else {
-			log.Printf("[DEBUG] host[%d] ip=%s no forestRoot — skipping AD upsert", i, ip)
-		}
-
-		// ── Domain ───────────────────────────────────────────────────────────
-		var domainUID string
-		if domainName != "" {
-			if cached, ok := domainCache[domainName]; ok {
-				domainUID = cached
-				log.Printf("[DEBUG] host[%d] Domain cache hit domain=%s uid=%s", i, domainName, domainUID)
-			} else {
-				log.Printf("[DEBUG] host[%d] calling upsertDomain domain=%s adUID=%s", i, domainName, adUID)
-				domainUID, err = n.upsertDomain(ctx, params.ProjectUID, adUID, domainName, strategy)
-				if err != nil {
-					log.Printf("[ERROR] host[%d] upsertDomain failed domain=%s err=%v",
-						i, domainName, err)
-				} else {
-					domainCache[domainName] = domainUID
-					log.Printf("[DEBUG] host[%d] upsertDomain ok domain=%s uid=%s",
-						i, domainName, domainUID)
-				}
-			}
-		} else {
-			log.Printf("[DEBUG] host[%d] ip=%s no domainName — host will be orphaned", i, ip)
-		}
-
-		// ── Host ─────────────────────────────────────────────────────────────
-		log.Printf("[DEBUG] host[%d] calling upsertHost ip=%s domainUID=%s", i, ip, domainUID)
-		hostUID, err := n.upsertHost(ctx, document, xb, ip, domainUID, params)
-		if err != nil {
-			log.Printf("[ERROR] host[%d] upsertHost failed ip=%s err=%v", i, ip, err)
-			continue
-		}
-		log.Printf("[DEBUG] host[%d] upsertHost ok ip=%s uid=%s", i, ip, hostUID)
-
-		// ── Services ─────────────────────────────────────────────────────────
-		log.Printf("[DEBUG] host[%d] calling upsertServices hostUID=%s", i, hostUID)
-		n.upsertServices(ctx, host, hostUID, params.ProjectUID)
+	if err := n.processor.ProcessScanResult(ctx, *nmapResult); err != nil {
+		return fmt.Errorf("processing scan results failed: %w", err)
 	}
-
-	log.Printf("[DEBUG] processScanResults: done")
 	return nil
 }
 
-// ── upsertActiveDirectory ─────────────────────────────────────────────────────
-
-func (n *NetworkExplorer) upsertActiveDirectory(
+// runDiscovery runs the fast discovery scanner over the given targets.
+func (n *NetworkExplorer) runDiscovery(
 	ctx context.Context,
-	projectUID string,
-	forestRoot string,
-) (string, error) {
-	log.Printf("[DEBUG] upsertActiveDirectory: projectUID=%s forestRoot=%s", projectUID, forestRoot)
+	discoveryAdapter interfaces.ScanAdapter,
+	targets []string,
+	scanOpts *networkScanOptions,
+) (*scan.DiscoveryScanResult, error) {
+	n.logger.Info(fmt.Sprintf("Running fast port discovery with %s", discoveryAdapter.GetName()))
 
-	result, err := n.services.ActiveDirectoryService.UpsertActiveDirectory(
+	scanResult, err := discoveryAdapter.Scan(
 		ctx,
-		upsert.Input[*active_directory.ActiveDirectory]{
-			Entity:       &active_directory.ActiveDirectory{ForestName: forestRoot},
-			ProjectUID:   projectUID,
-			ParentUID:    nil,
-			ParentType:   "Project",
-			AssertionCtx: assertCtxAD,
-			Actor:        n.configKey,
-		},
+		scan.WithTargets(targets),
+		scan.WithPortRange(scanOpts.portRange),
 	)
 	if err != nil {
-		log.Printf("[ERROR] upsertActiveDirectory: UpsertActiveDirectory returned err=%v", err)
-		return "", fmt.Errorf("UpsertActiveDirectory failed for forest %s: %w", forestRoot, err)
+		return nil, fmt.Errorf("discovery scan failed: %w", err)
 	}
 
-	log.Printf("[DEBUG] upsertActiveDirectory: result uid=%s", result.Entity.UID)
+	discovery, ok := scanResult.(*scan.DiscoveryScanResult)
+	if !ok {
+		return nil, fmt.Errorf("could not map scan result to discovery result: %v", scanResult)
+	}
 
-	sse.NewEvent(events.DomainDiscovered).
-		WithData("type", "active_directory").
-		WithData("forest", forestRoot).
+	sse.NewEvent(events.ScanProgress).
+		WithData("stage", "discovery").
+		WithData("adapter", discoveryAdapter.GetName()).
+		WithData("live_hosts", len(discovery.LiveHosts())).
+		WithData("open_ports", len(discovery.Candidates)).
 		WithData("timestamp", time.Now().Unix()).
 		Log(n.logger)
 
-	log.Printf("[NetworkExplorer] AD upserted uid=%s forest=%s", result.Entity.UID, forestRoot)
-	return result.Entity.UID, nil
+	return discovery, nil
 }
 
-// ── upsertDomain ──────────────────────────────────────────────────────────────
-
-func (n *NetworkExplorer) upsertDomain(
+// runStagedScan writes the live hosts of the discovery stage and then runs an
+// nmap service/script scan per live host, restricted to the ports found open.
+// Each host is written to the graph as soon as its stage finishes.
+func (n *NetworkExplorer) runStagedScan(
 	ctx context.Context,
-	projectUID string,
-	adUID string,
-	domainName string,
-	strategy string,
-) (string, error) {
-	var parentUID *string
-	parentType := "Project"
-	if adUID != "" {
-		parentUID = &adUID
-		parentType = "ActiveDirectory"
-	}
-
-	log.Printf("[DEBUG] upsertDomain: projectUID=%s adUID=%s domainName=%s parentType=%s",
-		projectUID, adUID, domainName, parentType)
-
-	result, err := n.services.DomainService.UpsertDomain(
-		ctx,
-		upsert.Input[*active_directory.Domain]{
-			Entity:       &active_directory.Domain{Name: domainName},
-			ProjectUID:   projectUID,
-			ParentUID:    parentUID,
-			ParentType:   parentType,
-			AssertionCtx: assertCtxAD,
-			Actor:        n.configKey,
-		},
-	)
-	if err != nil {
-		log.Printf("[ERROR] upsertDomain: UpsertDomain returned err=%v", err)
-		return "", fmt.Errorf("UpsertDomain failed for %s: %w", domainName, err)
+	discovery *scan.DiscoveryScanResult,
+	nmapAdapter interfaces.ScanAdapter,
+	scanOpts *networkScanOptions,
+) {
+	liveHosts := discovery.LiveHosts()
+	portsByHost := discovery.PortsByHost()
+
+	// ── Stage 1: live hosts ───────────────────────────────────────────────────
+	hostUIDs := make(map[string]string, len(liveHosts))
+	for _, ip := range liveHosts {
+		hostUID, err := n.processor.UpsertDiscoveredHost(ctx, ip, portsByHost[ip])
+		if err != nil {
+			log.Printf("[ERROR] runStagedScan: upsertDiscoveredHost failed ip=%s err=%v", ip, err)
+			continue
+		}
+		hostUIDs[ip] = hostUID
 	}
 
-	log.Printf("[DEBUG] upsertDomain: result uid=%s", result.Entity.UID)
-
-	sse.NewEvent(events.DomainDiscovered).
-		WithData("domain", domainName).
-		WithData("strategy", strategy).
-		WithData("timestamp", time.Now().Unix()).
-		Log(n.logger)
+	// ── Stage 2: nmap against live ports ──────────────────────────────────────
+	for i, ip := range liveHosts {
+		ports := portsByHost[ip]
 
-	log.Printf("[NetworkExplorer] Domain upserted name=%s uid=%s strategy=%s",
-		domainName, result.Entity.UID, strategy)
-	return result.Entity.UID, nil
-}
+		sse.NewEvent(events.ScanProgress).
+			WithData("stage", "service_scan").
+			WithData("ip", ip).
+			WithData("ports", strings.Join(ports, ",")).
+			WithData("progress", fmt.Sprintf("%d/%d", i+1, len(liveHosts))).
+			WithData("timestamp", time.Now().Unix()).
+			Log(n.logger)
 
-// ── upsertHost ────────────────────────────────────────────────────────────────
+		result, err := nmapAdapter.Scan(ctx, scanOpts.nmapOptions([]string{ip}, strings.Join(ports, ","))...)
+		nmapResult, ok := result.(*scan.NmapScanResult)
+		if err != nil || !ok {
+			// Keep the discovered ports even if the service scan failed
+			n.logger.Warning(fmt.Sprintf("Service scan failed for %s, keeping discovered ports: %v", ip, err))
+			n.processor.AddDiscoveredServices(ctx, ip, hostUIDs[ip], ports)
+			continue
+		}
 
-func (n *NetworkExplorer) upsertHost(
-	ctx context.Context,
-	document *xmlquery.Node,
-	xb *internal.XPathBuilder,
-	ip string,
-	domainUID string,
-	params *input.Parameter,
-) (string, error) {
-	if ip == "" {
-		return "", fmt.Errorf("IP cannot be empty")
-	}
-	if params == nil {
-		return "", fmt.Errorf("parameters cannot be nil")
+		if err := n.processor.ProcessScanResult(ctx, *nmapResult); err != nil {
+			log.Printf("[ERROR] runStagedScan: processing nmap result failed ip=%s err=%v", ip, err)
+		}
 	}
+}
 
-	log.Printf("[DEBUG] upsertHost: ip=%s domainUID=%s projectUID=%s", ip, domainUID, params.ProjectUID)
+// ── Targets & options ─────────────────────────────────────────────────────────
 
-	b := model.NewHostBuilder().WithIP(ip)
+const (
+	defaultPortRange = "1-1024"
+	fullPortRange    = "1-65535"
+)
 
-	// ── Name ──────────────────────────────────────────────────────────────────
-	if hostNode := xmlquery.FindOne(document, xb.Host()); hostNode != nil {
-		if node := xmlquery.FindOne(hostNode, xb.Hostname()); node != nil {
-			if v := strings.TrimSpace(node.InnerText()); v != "" {
-				b.WithName(v)
-			}
-		}
-	}
-	if netbios := firstText(document, xb.NetBIOSComputerNameRDP(), xb.NetBIOSComputerNameSQL()); netbios != "" {
-		b.WithName(netbios)
-	}
+// networkScanOptions is the module configuration after all inputs have been
+// resolved and validated.
+type networkScanOptions struct {
+	targets     []string
+	portRange   string
+	fullScan    bool
+	udp         bool
+	customFlags []string
+	timeout     time.Duration
+}
 
-	// ── DNS FQDN ──────────────────────────────────────────────────────────────
-	if fqdn := firstText(document, xb.DNSComputerNameRDP(), xb.DNSComputerNameSQL(), xb.SMBFQDN()); fqdn != "" {
-		b.WithDNSHostName(fqdn)
+// nmapOptions translates the module configuration into nmap scan options.
+func (o *networkScanOptions) nmapOptions(targets []string, portRange string) []interfaces.ScanOption {
+	opts := []interfaces.ScanOption{
+		scan.WithTargets(targets),
+		scan.WithPortRange(portRange),
+		scan.WithServiceScan(),
+		scan.WithScriptScan(),
+		interfaces.WithTimeout(o.timeout),
 	}
-
-	// ── Operating System ──────────────────────────────────────────────────────
-	if osStr := firstText(document, xb.SMBOS()); osStr != "" {
-		b.WithOperatingSystem(osStr)
-	} else if osType := firstText(document, xb.ServiceOSType()); osType != "" {
-		b.WithOperatingSystem(osType)
+	if o.fullScan {
+		opts = append(opts, scan.WithOSScan())
 	}
-
-	// ── OS Version ────────────────────────────────────────────────────────────
-	if version := firstText(document, xb.ProductVersionRDP(), xb.ProductVersionSQL()); version != "" {
-		b.WithOperatingSystemVersion(version)
+	if o.udp {
+		opts = append(opts, scan.WithUDPScan())
+	}
+	if len(o.customFlags) > 0 {
+		opts = append(opts, scan.WithCustomFlags(o.customFlags))
 	}
+	return opts
+}
 
-	// ── Domain Controller ─────────────────────────────────────────────────────
-	dc, score, matchedPorts := isDomainController(document, xb)
-	if dc {
-		b.AsDomainController()
-		log.Printf("[NetworkExplorer] %s: DC detected (score=%d, ports=%v)", ip, score, matchedPorts)
-	} else {
-		log.Printf("[NetworkExplorer] %s: not a DC (score=%d, ports=%v)", ip, score, matchedPorts)
+// resolveScanOptions reads the module options declared in modules.yaml.
+//
+// Targets are taken, in order of precedence, from the targets option (type
+// targetSelection), the free-text target option and finally the project's
+// Target nodes.
+func (n *NetworkExplorer) resolveScanOptions(ctx context.Context, params *input.Parameter) (*networkScanOptions, error) {
+	opts := &networkScanOptions{
+		portRange: defaultPortRange,
+		timeout:   30 * time.Minute,
 	}
 
-	host, err := b.Build()
+	targets, err := n.resolveTargets(ctx, params)
 	if err != nil {
-		log.Printf("[ERROR] upsertHost: Build failed ip=%s err=%v", ip, err)
-		return "", fmt.Errorf("failed to build host model: %w", err)
+		return nil, err
 	}
+	opts.targets = targets
 
-	log.Printf("[DEBUG] upsertHost: host built ip=%s hostname=%s os=%q dc=%v",
-		host.IP, host.DNSHostName, host.OperatingSystem, host.IsDomainController)
-
-	var parentUID *string
-	if domainUID != "" {
-		parentUID = &domainUID
+	if inputBool(params, "fullscan") {
+		opts.fullScan = true
+		opts.portRange = fullPortRange
+		opts.timeout = 4 * time.Hour
 	}
+	opts.udp = inputBool(params, "udp")
 
-	log.Printf("[DEBUG] upsertHost: calling HostService.UpsertHost ip=%s parentUID=%v",
-		ip, parentUID)
-
-	result, err := n.services.HostService.UpsertHost(
-		ctx,
-		upsert.Input[*model.Host]{
-			Entity:       host,
-			ProjectUID:   params.ProjectUID,
-			ParentUID:    parentUID,
-			ParentType:   "Domain",
-			AssertionCtx: assertCtxHost,
-			Actor:        n.configKey,
-		},
-	)
-	if err != nil {
-		log.Printf("[ERROR] upsertHost: HostService.UpsertHost failed ip=%s err=%v", ip, err)
-		return "", fmt.Errorf("failed to upsert host: %w", err)
+	if additional := params.GetTextInput("additional"); additional != nil && strings.TrimSpace(*additional) != "" {
+		flags, err := scan.SanitizeNmapFlags(*additional)
+		if err != nil {
+			return nil, fmt.Errorf("additional flags rejected: %w", err)
+		}
+		// A -p of the user replaces the port range, so discovery scans the same ports
+		if ports, rest := scan.ExtractPortSpec(flags); ports != "" {
+			opts.portRange = ports
+			flags = rest
+		}
+		opts.customFlags = flags
 	}
 
-	log.Printf("[DEBUG] upsertHost: ok ip=%s uid=%s", ip, result.Entity.UID)
-
-	sse.NewEvent(events.HostDiscovered).
-		WithData("ip", ip).
-		WithData("hostname", host.DNSHostName).
-		WithData("os", host.OperatingSystem).
-		WithData("os_version", host.OperatingSystemVersion).
-		WithData("dc", host.IsDomainController).
-		WithData("timestamp", time.Now().Unix()).
-		Log(n.logger)
-
-	log.Printf("[NetworkExplorer] Host upserted ip=%s dns=%s os=%q dc=%v uid=%s",
-		ip, host.DNSHostName, host.OperatingSystem, host.IsDomainController, result.Entity.UID)
-
-	return result.Entity.UID, nil
+	return opts, nil
 }
 
-// ── upsertServices ────────────────────────────────────────────────────────────
+func (n *NetworkExplorer) resolveTargets(ctx context.Context, params *input.Parameter) ([]string, error) {
+	var targets []string
 
-func (n *NetworkExplorer) upsertServices(ctx context.Context, host serializable.Host, hostUID, projectUID string) {
-	if hostUID == "" {
-		return
+	if selected := params.GetTargetInput("targets"); selected != nil {
+		for _, t := range *selected {
+			targets = append(targets, targetSpec(t))
+		}
 	}
 
-	log.Printf("[DEBUG] upsertServices: hostUID=%s portCount=%d", hostUID, len(host.Ports.Port))
-
-	for _, port := range host.Ports.Port {
-		if port.State.State != "open" {
-			continue
+	if len(targets) == 0 {
+		if text := params.GetTextInput("target"); text != nil {
+			targets = append(targets, strings.FieldsFunc(*text, func(r rune) bool {
+				return r == ',' || r == ' ' || r == ';' || r == '\n'
+			})...)
 		}
+	}
 
-		service := model.NewServiceBuilder().
-			WithName(port.Service.Name).
-			WithPort(port.Portid).
-			Build()
-
-		_, err := n.services.HostService.AddService(
-			ctx,
-			assertCtxService,
-			projectUID,
-			hostUID,
-			service,
-			n.configKey,
-		)
+	if len(targets) == 0 {
+		projectTargets, err := n.services.ProjectService.GetTargets(ctx, params.ProjectUID)
 		if err != nil {
-			log.Printf("[ERROR] upsertServices: AddService failed port=%s host=%s err=%v",
-				port.Portid, hostUID, err)
-			continue
+			return nil, fmt.Errorf("loading project targets failed: %w", err)
 		}
-
-		log.Printf("[DEBUG] upsertServices: ok port=%s service=%s host=%s",
-			port.Portid, port.Service.Name, hostUID)
-
-		sse.NewEvent(events.ServiceDetected).
-			WithData("port", port.Portid).
-			WithData("service", port.Service.Name).
-			WithData("product", port.Service.Product).
-			WithData("version", port.Service.Version).
-			WithData("timestamp", time.Now().Unix()).
-			Log(n.logger)
-	}
-}
-
-// ── Domain / Forest extraction ────────────────────────────────────────────────
-
-// extractDomainName returns the DNS domain name the host belongs to.
-//
-// Strategy priority (highest confidence first):
-//  1. RDP NTLM-Info  — DNS_Domain_Name reflects the host's own domain exactly,
-//     even on DCs where LDAP extrainfo returns the forest root instead.
-//  2. SQL NTLM-Info  — same reliability as RDP, used when RDP is not open.
-//  3. LDAP ExtraInfo — fallback for hosts with LDAP but no RDP/SQL.
-//     NOTE: on forest-root DCs this returns the forest name, not a child domain.
-//  4. SSL Cert CommonName  — extract domain from subject FQDN.
-//  5. SSL Cert SAN-DNS     — extract domain from SAN DNS entry.
-//  6. SSL Cert DomainComponent — issuer DC field as last resort.
-func (n *NetworkExplorer) extractDomainName(document *xmlquery.Node, xb *internal.XPathBuilder) (string, string) {
-
-	// Strategy 1: RDP NTLM-Info — most reliable, DNS_Domain_Name = host's own domain
-	if node := xmlquery.FindOne(document, xb.DNSDomainNameRDP()); node != nil {
-		if v := strings.TrimSpace(node.InnerText()); v != "" {
-			log.Printf("[NetworkExplorer] Domain=%s via strategy=RDP NTLM", v)
-			return v, "RDP NTLM"
+		for _, t := range projectTargets {
+			targets = append(targets, targetSpec(*t))
 		}
 	}
 
-	// Strategy 2: SQL NTLM-Info
-	if node := xmlquery.FindOne(document, xb.DNSDomainNameSQL()); node != nil {
-		if v := strings.TrimSpace(node.InnerText()); v != "" {
-			log.Printf("[NetworkExplorer] Domain=%s via strategy=SQL NTLM", v)
-			return v, "SQL NTLM"
+	var valid []string
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !isValidTarget(t) {
+			return nil, fmt.Errorf("invalid scan target: %q", t)
 		}
+		valid = append(valid, t)
 	}
 
-	// Strategies 3–6: LDAP-based fallback (hosts without RDP/SQL open)
-	ldapPorts := []string{"389", "636", "3268", "3269"}
-
-	ldapStrategies := []struct {
-		name     string
-		getXPath func(port string) string
-		extract  func(string) string
-	}{
-		{name: "LDAP ExtraInfo", getXPath: xb.LDAPExtraInfo, extract: extractDomainFromExtrainfo},
-		{name: "SSL Cert CommonName", getXPath: xb.SSLCertCommonName, extract: extractDomainFromFQDN},
-		{
-			name:     "SSL Cert SAN-DNS",
-			getXPath: xb.SSLCertSANDNS,
-			extract:  func(text string) string { return extractDomainFromFQDN(strings.TrimPrefix(text, "DNS:")) },
-		},
-		{
-			name:     "SSL Cert DomainComponent",
-			getXPath: xb.SSLCertDomainComponent,
-			extract: func(text string) string {
-				if text == "" {
-					return ""
-				}
-				return text + ".local"
-			},
-		},
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("no scan targets: select targets or add targets to project %s", params.ProjectUID)
 	}
+	return valid, nil
+}
 
-	for _, s := range ldapStrategies {
-		for _, port := range ldapPorts {
-			nodes, err := xmlquery.QueryAll(document, s.getXPath(port))
-			if err != nil || len(nodes) == 0 || nodes[0] == nil {
-				continue
-			}
-			domain := s.extract(nodes[0].InnerText())
-			if domain != "" {
-				log.Printf("[NetworkExplorer] Domain=%s via strategy=%s port=%s", domain, s.name, port)
-				return domain, s.name
-			}
-		}
+// targetSpec renders a Target node as nmap target notation.
+func targetSpec(t model.Target) string {
+	if t.CIDR > 0 && !strings.Contains(t.IP, "/") {
+		return fmt.Sprintf("%s/%d", t.IP, t.CIDR)
 	}
-
-	return "", ""
+	return t.IP
 }
 
-func (n *NetworkExplorer) extractForestRoot(xb *internal.XPathBuilder, document *xmlquery.Node) string {
-	if node := xmlquery.FindOne(document, xb.DNSTreeNameRDP()); node != nil {
-		if v := strings.TrimSpace(node.InnerText()); v != "" {
-			return v
-		}
+var (
+	ipRangePattern  = regexp.MustCompile(`^[0-9]{1,3}(\.[0-9]{1,3}(-[0-9]{1,3})?){3}$`)
+	hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+)
+
+// isValidTarget accepts IPs, CIDRs, nmap octet ranges and hostnames. Anything
+// starting with '-' would be read as an nmap flag and is rejected.
+func isValidTarget(t string) bool {
+	if strings.HasPrefix(t, "-") {
+		return false
 	}
-	if node := xmlquery.FindOne(document, xb.DNSTreeNameSQL()); node != nil {
-		if v := strings.TrimSpace(node.InnerText()); v != "" {
-			return v
-		}
+	if net.ParseIP(t) != nil {
+		return true
+	}
+	if _, _, err := net.ParseCIDR(t); err == nil {
+		return true
 	}
-	return ""
+	return ipRangePattern.MatchString(t) || hostnamePattern.MatchString(t)
 }
 
-// ── Helpers ───────────────────────────────────────────────────────────────────
-
-func firstText(document *xmlquery.Node, xpaths ...string) string {
-	for _, xpath := range xpaths {
-		if node := xmlquery.FindOne(document, xpath); node != nil {
-			if v := strings.TrimSpace(node.InnerText()); v != "" {
-				return v
-			}
+// splitDiscoveryTargets separates the targets the discovery scanners accept
+// (IPs and CIDRs) from hostnames and nmap octet ranges, which only nmap
+// understands.
+func splitDiscoveryTargets(targets []string) (discoverable, direct []string) {
+	for _, t := range targets {
+		if net.ParseIP(t) != nil {
+			discoverable = append(discoverable, t)
+			continue
 		}
+		if _, _, err := net.ParseCIDR(t); err == nil {
+			discoverable = append(discoverable, t)
+			continue
+		}
+		direct = append(direct, t)
 	}
-	return ""
+	return discoverable, direct
 }
 
-func extractDomainFromExtrainfo(info string) string {
-	re := regexp.MustCompile(`Domain:\s*([a-zA-Z0-9.-]+)`)
-	match := re.FindStringSubmatch(info)
-	if len(match) > 1 {
-		return strings.Replace(match[1], ".local0.", ".local", 1)
+// inputBool reads a boolean option that may be declared as checkbox or as
+// free-text ("yes", "true", "1").
+func inputBool(params *input.Parameter, key string) bool {
+	if cb := params.GetCheckbox(key); cb != nil {
+		return *cb
 	}
-	return ""
-}
-
-func extractDomainFromFQDN(fqdn string) string {
-	fqdn = strings.TrimPrefix(fqdn, "*.")
-	if parts := strings.SplitN(fqdn, ".", 2); len(parts) == 2 {
-		return parts[1]
+	if text := params.GetTextInput(key); text != nil {
+		switch strings.ToLower(strings.TrimSpace(*text)) {
+		case "yes", "y", "true", "1", "on":
+			return true
+		}
 	}
-	return ""
+	return false
 }
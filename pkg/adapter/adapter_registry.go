@@ -2,8 +2,11 @@ package adapter
 
 import (
 	"RedPaths-server/pkg/adapter/scan"
+	"RedPaths-server/pkg/adapter/util"
 	"RedPaths-server/pkg/interfaces"
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -24,6 +27,8 @@ func GetAdapterFactory() *AdapterRegistry {
 		}
 
 		factory.RegisterAdapter(scan.NewNmapAdapter())
+		factory.RegisterAdapter(scan.NewMasscanAdapter())
+		factory.RegisterAdapter(scan.NewRustScanAdapter())
 	})
 
 	return factory
@@ -73,3 +78,75 @@ func (f *AdapterRegistry) ListAvailableAdapters() []string {
 
 	return names
 }
+
+// ToolStatus describes the runtime state of a registered adapter.
+type ToolStatus struct {
+	Name         string   `json:"name"`
+	Available    bool     `json:"available"`
+	Version      string   `json:"version,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Capabilities []string `json:"capabilities"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// GetToolStatus probes a single adapter for availability, version and binary path.
+func (f *AdapterRegistry) GetToolStatus(ctx context.Context, name string) (*ToolStatus, error) {
+	adapter, err := f.GetAdapter(name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ToolStatus{
+		Name:         adapter.GetName(),
+		Capabilities: adapter.GetCapabilities(),
+	}
+
+	if execAdapter, ok := adapter.(util.ExecutableAdapter); ok {
+		path, err := util.ResolvePath(execAdapter, adapter.GetName())
+		if err != nil {
+			status.Error = err.Error()
+			return status, nil
+		}
+		status.Path = path
+	}
+
+	status.Available = adapter.IsAvailable(ctx)
+	if status.Available {
+		status.Version = adapter.GetVersion()
+	}
+
+	return status, nil
+}
+
+// Inventory returns the status of all registered adapters sorted by name.
+func (f *AdapterRegistry) Inventory(ctx context.Context) []*ToolStatus {
+	names := f.ListAvailableAdapters()
+	sort.Strings(names)
+
+	inventory := make([]*ToolStatus, 0, len(names))
+	for _, name := range names {
+		status, err := f.GetToolStatus(ctx, name)
+		if err != nil {
+			continue
+		}
+		inventory = append(inventory, status)
+	}
+
+	return inventory
+}
+
+// EnsureAvailable returns an error if the adapter is not registered or its
+// binary cannot be executed on this host.
+func (f *AdapterRegistry) EnsureAvailable(ctx context.Context, name string) error {
+	status, err := f.GetToolStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !status.Available {
+		if status.Error != "" {
+			return fmt.Errorf("tool '%s' is not available: %s", name, status.Error)
+		}
+		return fmt.Errorf("tool '%s' is not available", name)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package scan
+
+import (
+	"RedPaths-server/pkg/adapter/serializable"
+	"RedPaths-server/pkg/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortCandidate is a single live host:port pair found by a fast discovery scanner.
+type PortCandidate struct {
+	IP       string
+	Port     string
+	Protocol string
+}
+
+// DiscoveryScanResult holds the candidate host:port pairs of a fast discovery
+// scan (masscan, rustscan). It carries no service information; that is left
+// to a follow-up nmap scan against the live ports.
+type DiscoveryScanResult struct {
+	Raw        []byte
+	Candidates []PortCandidate
+}
+
+func (r *DiscoveryScanResult) GetRawOutput() []byte {
+	return r.Raw
+}
+
+// LiveHosts returns the distinct IPs in the order they were discovered.
+func (r *DiscoveryScanResult) LiveHosts() []string {
+	seen := make(map[string]bool)
+	var ips []string
+	for _, c := range r.Candidates {
+		if !seen[c.IP] {
+			seen[c.IP] = true
+			ips = append(ips, c.IP)
+		}
+	}
+	return ips
+}
+
+// PortsByHost groups the candidate ports by IP, sorted numerically.
+func (r *DiscoveryScanResult) PortsByHost() map[string][]string {
+	grouped := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, c := range r.Candidates {
+		key := c.IP + ":" + c.Port
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		grouped[c.IP] = append(grouped[c.IP], c.Port)
+	}
+	for ip := range grouped {
+		sort.Slice(grouped[ip], func(i, j int) bool {
+			a, _ := strconv.Atoi(grouped[ip][i])
+			b, _ := strconv.Atoi(grouped[ip][j])
+			return a < b
+		})
+	}
+	return grouped
+}
+
+func (r *DiscoveryScanResult) GetHosts() []model.Host {
+	var hosts []model.Host
+	for _, ip := range r.LiveHosts() {
+		host, err := model.NewHostBuilder().WithIP(ip).Build()
+		if err != nil {
+			log.Printf("Fehler beim Erstellen des Hosts: %v", err)
+			continue
+		}
+		hosts = append(hosts, *host)
+	}
+	return hosts
+}
+
+func (r *DiscoveryScanResult) GetServices() []model.Service {
+	var services []model.Service
+	for _, c := range r.Candidates {
+		services = append(services, *model.NewServiceBuilder().WithPort(c.Port).Build())
+	}
+	return services
+}
+
+// masscanJSONEntry mirrors one record of masscan's -oJ output.
+type masscanJSONEntry struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port   int    `json:"port"`
+		Proto  string `json:"proto"`
+		Status string `json:"status"`
+	} `json:"ports"`
+}
+
+// ParseDiscoveryOutput parses masscan JSON (-oJ), masscan/nmap XML (-oX) or
+// rustscan greppable (-g) output into port candidates.
+func ParseDiscoveryOutput(output []byte) ([]PortCandidate, error) {
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case '<':
+		return parseDiscoveryXML(trimmed)
+	case '[', '{':
+		return parseDiscoveryJSON(trimmed)
+	default:
+		return parseDiscoveryGreppable(trimmed)
+	}
+}
+
+func parseDiscoveryXML(output []byte) ([]PortCandidate, error) {
+	var result serializable.NmapResult
+	if err := xml.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery XML output: %w", err)
+	}
+
+	var candidates []PortCandidate
+	for _, host := range result.Host {
+		if len(host.Address) == 0 {
+			continue
+		}
+		for _, port := range host.Ports.Port {
+			if port.State.State != "open" {
+				continue
+			}
+			candidates = append(candidates, PortCandidate{
+				IP:       host.Address[0].Addr,
+				Port:     port.Portid,
+				Protocol: port.Protocol,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+func parseDiscoveryJSON(output []byte) ([]PortCandidate, error) {
+	var entries []masscanJSONEntry
+
+	if output[0] == '[' {
+		// masscan writes a trailing comma before the closing bracket on some versions
+		cleaned := bytes.Replace(output, []byte(",\n]"), []byte("\n]"), 1)
+		if err := json.Unmarshal(cleaned, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse discovery JSON output: %w", err)
+		}
+	} else {
+		// NDJSON (masscan -oD)
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+			if line == "" {
+				continue
+			}
+			var entry masscanJSONEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				return nil, fmt.Errorf("failed to parse discovery JSON line: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	var candidates []PortCandidate
+	for _, entry := range entries {
+		for _, p := range entry.Ports {
+			if p.Status != "" && p.Status != "open" {
+				continue
+			}
+			candidates = append(candidates, PortCandidate{
+				IP:       entry.IP,
+				Port:     strconv.Itoa(p.Port),
+				Protocol: p.Proto,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// parseDiscoveryGreppable handles rustscan's "10.0.0.1 -> [22,80,443]" format.
+func parseDiscoveryGreppable(output []byte) ([]PortCandidate, error) {
+	var candidates []PortCandidate
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		ip, ports, ok := strings.Cut(line, "->")
+		if !ok {
+			continue
+		}
+		ip = strings.TrimSpace(ip)
+		ports = strings.Trim(strings.TrimSpace(ports), "[]")
+		for _, port := range strings.Split(ports, ",") {
+			port = strings.TrimSpace(port)
+			if _, err := strconv.Atoi(port); err != nil {
+				continue
+			}
+			candidates = append(candidates, PortCandidate{IP: ip, Port: port, Protocol: "tcp"})
+		}
+	}
+	return candidates, scanner.Err()
+}
@@ -0,0 +1,93 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiscoveryOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []PortCandidate
+		wantErr bool
+	}{
+		{name: "empty", output: "  \n"},
+		{
+			name: "masscan json with trailing comma",
+			output: `[
+{"ip": "10.0.0.1", "ports": [{"port": 445, "proto": "tcp", "status": "open"}]},
+{"ip": "10.0.0.2", "ports": [{"port": 22, "proto": "tcp", "status": "closed"}]},
+]`,
+			want: []PortCandidate{{IP: "10.0.0.1", Port: "445", Protocol: "tcp"}},
+		},
+		{
+			name: "masscan ndjson",
+			output: `{"ip": "10.0.0.1", "ports": [{"port": 80, "proto": "tcp"}]},
+{"ip": "10.0.0.3", "ports": [{"port": 53, "proto": "udp", "status": "open"}]}`,
+			want: []PortCandidate{
+				{IP: "10.0.0.1", Port: "80", Protocol: "tcp"},
+				{IP: "10.0.0.3", Port: "53", Protocol: "udp"},
+			},
+		},
+		{
+			name: "xml",
+			output: `<?xml version="1.0"?>
+<nmaprun>
+<host><address addr="10.0.0.5" addrtype="ipv4"/><ports>
+<port protocol="tcp" portid="88"><state state="open"/></port>
+<port protocol="tcp" portid="8080"><state state="filtered"/></port>
+</ports></host>
+</nmaprun>`,
+			want: []PortCandidate{{IP: "10.0.0.5", Port: "88", Protocol: "tcp"}},
+		},
+		{
+			name: "rustscan greppable",
+			output: `Open 10.0.0.7:22
+10.0.0.7 -> [22,389,x]
+10.0.0.8 -> [3389]`,
+			want: []PortCandidate{
+				{IP: "10.0.0.7", Port: "22", Protocol: "tcp"},
+				{IP: "10.0.0.7", Port: "389", Protocol: "tcp"},
+				{IP: "10.0.0.8", Port: "3389", Protocol: "tcp"},
+			},
+		},
+		{name: "broken json", output: `[{"ip": }]`, wantErr: true},
+		{name: "broken xml", output: `<nmaprun><host>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDiscoveryOutput([]byte(tt.output))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDiscoveryOutput() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDiscoveryOutput() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDiscoveryOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoveryScanResultGrouping(t *testing.T) {
+	result := &DiscoveryScanResult{Candidates: []PortCandidate{
+		{IP: "10.0.0.2", Port: "445"},
+		{IP: "10.0.0.1", Port: "80"},
+		{IP: "10.0.0.2", Port: "22"},
+		{IP: "10.0.0.2", Port: "445"},
+	}}
+
+	if got, want := result.LiveHosts(), []string{"10.0.0.2", "10.0.0.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LiveHosts() = %v, want %v", got, want)
+	}
+	want := map[string][]string{"10.0.0.1": {"80"}, "10.0.0.2": {"22", "445"}}
+	if got := result.PortsByHost(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PortsByHost() = %v, want %v", got, want)
+	}
+}
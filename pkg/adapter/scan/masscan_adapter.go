@@ -0,0 +1,127 @@
+package scan
+
+import (
+	"RedPaths-server/pkg/adapter/util"
+	"RedPaths-server/pkg/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MasscanAdapter struct {
+	*util.ExecutableHelper
+	version string
+}
+
+type MasscanScanOptions struct {
+	interfaces.ScanOptions
+	PortRange string
+	Rate      int // packets per second
+}
+
+func NewMasscanAdapter() interfaces.ScanAdapter {
+	return &MasscanAdapter{
+		ExecutableHelper: util.NewExecutableHelper("masscan"),
+	}
+}
+
+func (m *MasscanAdapter) GetName() string {
+	return "masscan"
+}
+
+func (m *MasscanAdapter) GetVersion() string {
+	if m.version == "" {
+		output, err := util.ExecWithFallback(context.Background(), m, "masscan", "--version")
+		if err == nil {
+			// "Masscan version 1.3.2 ( https://github.com/robertdavidgraham/masscan )"
+			fields := strings.Fields(string(output))
+			for i, f := range fields {
+				if f == "version" && i+1 < len(fields) {
+					m.version = fields[i+1]
+					break
+				}
+			}
+		}
+	}
+	return m.version
+}
+
+func (m *MasscanAdapter) IsAvailable(ctx context.Context) bool {
+	_, err := util.ExecWithFallback(ctx, m, "masscan", "--version")
+	return err == nil
+}
+
+func (m *MasscanAdapter) GetCapabilities() []string {
+	return []string{"port_discovery"}
+}
+
+func (m *MasscanAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption) (interfaces.ScanResult, error) {
+	opts := &MasscanScanOptions{
+		ScanOptions: interfaces.ScanOptions{
+			Timeout:      30 * time.Minute,
+			OutputFormat: "json",
+		},
+		PortRange: "1-65535",
+		Rate:      1000,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	if len(opts.Targets) == 0 {
+		return nil, errors.New("no targets specified")
+	}
+
+	var cancel context.CancelFunc
+	if ctx == nil {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+	} else if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	args := []string{"-p", opts.PortRange, "--rate", strconv.Itoa(opts.Rate)}
+
+	if opts.OutputFormat == "xml" {
+		args = append(args, "-oX", "-")
+	} else {
+		args = append(args, "-oJ", "-")
+	}
+
+	args = append(args, opts.CustomFlags...)
+	args = append(args, opts.Targets...)
+
+	log.Printf("Executing masscan with args: %v", args)
+
+	output, err := util.ExecWithFallback(ctx, m, "masscan", args...)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("masscan scan timed out after %v", opts.Timeout)
+		}
+		return nil, fmt.Errorf("masscan execution failed: %w, output: %s", err, string(output))
+	}
+
+	candidates, err := ParseDiscoveryOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiscoveryScanResult{
+		Raw:        output,
+		Candidates: candidates,
+	}, nil
+}
+
+func WithRate(rate int) interfaces.ScanOption {
+	return func(opts interface{}) {
+		if masscanOpts, ok := opts.(*MasscanScanOptions); ok && rate > 0 {
+			masscanOpts.Rate = rate
+		}
+	}
+}
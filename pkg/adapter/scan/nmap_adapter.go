@@ -6,11 +6,9 @@ import (
 	"RedPaths-server/pkg/interfaces"
 	"RedPaths-server/pkg/model"
 	"context"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 	"time"
 
@@ -176,6 +174,10 @@ func (n *NmapAdapter) IsAvailable(ctx context.Context) bool {
 	return err == nil
 }
 
+func (n *NmapAdapter) GetCapabilities() []string {
+	return []string{"port_scan", "service_detection", "script_scan", "os_detection", "udp_scan", "host_discovery"}
+}
+
 func (n *NmapAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption) (interfaces.ScanResult, error) {
 	opts := &NmapScanOptions{
 		ScanOptions: interfaces.ScanOptions{
@@ -206,7 +208,8 @@ func (n *NmapAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption
 
 	args = append(args, "-oX", "-")
 
-	if opts.PortRange != "" {
+	// Ports chosen by the custom flags replace the port range of the options
+	if opts.PortRange != "" && !hasPortSelection(opts.CustomFlags) {
 		args = append(args, "-p", opts.PortRange)
 	}
 
@@ -256,12 +259,7 @@ func (n *NmapAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption
 
 	log.Printf("Executing nmap with args: %v", args)
 
-	//output, err := util.ExecWithFallback(ctx, n, "nmap", args...)
-
-	//TODO REMOVE - Only for local Testing
-	xmlFilePath := "/workspace/assets/nmap_homelab.xml"
-	output, err := os.ReadFile(xmlFilePath)
-
+	output, err := util.ExecWithFallback(ctx, n, "nmap", args...)
 	if err != nil {
 		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 			return nil, fmt.Errorf("nmap scan timed out after %v", opts.Timeout)
@@ -269,21 +267,18 @@ func (n *NmapAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption
 		return nil, fmt.Errorf("nmap execution failed: %w, output: %s", err, string(output))
 	}
 
-	var nmapResult serializable.NmapResult
-	if err := xml.Unmarshal(output, &nmapResult); err != nil {
-		return nil, fmt.Errorf("failed to parse nmap XML output: %w", err)
-	}
-
-	return &NmapScanResult{
-		Raw:  output,
-		Data: nmapResult,
-	}, nil
+	return ParseNmapXML(output)
 }
 
 func WithTargets(targets []string) interfaces.ScanOption {
 	return func(opts interface{}) {
-		if nmapOpts, ok := opts.(*NmapScanOptions); ok {
-			nmapOpts.Targets = targets
+		switch o := opts.(type) {
+		case *NmapScanOptions:
+			o.Targets = targets
+		case *MasscanScanOptions:
+			o.Targets = targets
+		case *RustScanOptions:
+			o.Targets = targets
 		}
 	}
 }
@@ -314,8 +309,13 @@ func WithUDPScan() interfaces.ScanOption {
 
 func WithPortRange(ports string) interfaces.ScanOption {
 	return func(opts interface{}) {
-		if nmapOpts, ok := opts.(*NmapScanOptions); ok {
-			nmapOpts.PortRange = ports
+		switch o := opts.(type) {
+		case *NmapScanOptions:
+			o.PortRange = ports
+		case *MasscanScanOptions:
+			o.PortRange = ports
+		case *RustScanOptions:
+			o.PortRange = ports
 		}
 	}
 }
@@ -337,3 +337,26 @@ func WithAggressiveScan() interfaces.ScanOption {
 		}
 	}
 }
+
+func WithOSScan() interfaces.ScanOption {
+	return func(opts interface{}) {
+		if nmapOpts, ok := opts.(*NmapScanOptions); ok {
+			nmapOpts.OSScan = true
+		}
+	}
+}
+
+// WithCustomFlags appends extra arguments. Callers must run user input through
+// SanitizeNmapFlags first.
+func WithCustomFlags(flags []string) interfaces.ScanOption {
+	return func(opts interface{}) {
+		switch o := opts.(type) {
+		case *NmapScanOptions:
+			o.CustomFlags = append(o.CustomFlags, flags...)
+		case *MasscanScanOptions:
+			o.CustomFlags = append(o.CustomFlags, flags...)
+		case *RustScanOptions:
+			o.CustomFlags = append(o.CustomFlags, flags...)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmapSwitches are flags without a value that may be passed as custom flags.
+var nmapSwitches = map[string]bool{
+	"-sS": true, "-sT": true, "-sV": true, "-sC": true, "-sU": true, "-sn": true,
+	"-O": true, "-A": true, "-Pn": true, "-n": true, "-R": true,
+	"-6": true, "-r": true, "-v": true, "-vv": true,
+	"-T0": true, "-T1": true, "-T2": true, "-T3": true, "-T4": true, "-T5": true,
+	"--open": true, "--reason": true, "--traceroute": true,
+	"--version-all": true, "--version-light": true, "--osscan-guess": true,
+}
+
+// nmapValueFlags are flags that take exactly one value, validated per flag.
+var nmapValueFlags = map[string]func(string) bool{
+	"-p":                  isPortSpec,
+	"--min-rate":          isPositiveInt,
+	"--max-rate":          isPositiveInt,
+	"--max-retries":       isNonNegativeInt,
+	"--min-parallelism":   isPositiveInt,
+	"--max-parallelism":   isPositiveInt,
+	"--version-intensity": func(v string) bool { i, err := strconv.Atoi(v); return err == nil && i >= 0 && i <= 9 },
+	"--host-timeout":      isNmapDuration,
+	"--scan-delay":        isNmapDuration,
+	"--max-rtt-timeout":   isNmapDuration,
+}
+
+// nmapPortShortcuts choose ports nmap picks itself. The discovery stage cannot
+// scan those, so only -p is accepted to choose the ports.
+var nmapPortShortcuts = map[string]bool{"-F": true, "--top-ports": true}
+
+var portSpecPattern = regexp.MustCompile(`^([TU]:)?[0-9]+(-[0-9]+)?(,([TU]:)?[0-9]+(-[0-9]+)?)*$`)
+
+func isPortSpec(v string) bool { return portSpecPattern.MatchString(v) }
+
+func isPositiveInt(v string) bool {
+	i, err := strconv.Atoi(v)
+	return err == nil && i > 0
+}
+
+func isNonNegativeInt(v string) bool {
+	i, err := strconv.Atoi(v)
+	return err == nil && i >= 0
+}
+
+func isNmapDuration(v string) bool {
+	if isNonNegativeInt(v) {
+		return true
+	}
+	if strings.HasSuffix(v, "ms") || strings.HasSuffix(v, "s") || strings.HasSuffix(v, "m") || strings.HasSuffix(v, "h") {
+		_, err := time.ParseDuration(v)
+		return err == nil
+	}
+	return false
+}
+
+// SanitizeNmapFlags splits user supplied nmap arguments and rejects anything
+// not on the allowlist. Output, script, input-file and target flags are never
+// accepted so that a module option cannot write files or run arbitrary scripts.
+func SanitizeNmapFlags(raw string) ([]string, error) {
+	tokens := strings.Fields(raw)
+	flags := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		if nmapSwitches[token] {
+			flags = append(flags, token)
+			continue
+		}
+
+		name, value, inline := strings.Cut(token, "=")
+		if nmapPortShortcuts[name] {
+			return nil, fmt.Errorf("nmap flag '%s' is not supported, choose the ports with -p", name)
+		}
+		validate, ok := nmapValueFlags[name]
+		if !ok {
+			return nil, fmt.Errorf("nmap flag '%s' is not allowed", token)
+		}
+
+		if !inline {
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("nmap flag '%s' requires a value", name)
+			}
+			i++
+			value = tokens[i]
+		}
+
+		if !validate(value) {
+			return nil, fmt.Errorf("invalid value '%s' for nmap flag '%s'", value, name)
+		}
+		flags = append(flags, name, value)
+	}
+
+	return flags, nil
+}
+
+// hasPortSelection reports whether the custom flags select the ports with a
+// -p of their own, which cannot be combined with the -p of the scan options.
+func hasPortSelection(flags []string) bool {
+	for _, flag := range flags {
+		name, _, _ := strings.Cut(flag, "=")
+		if name == "-p" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractPortSpec removes a -p flag from sanitized flags and returns its port
+// specification, so that callers can use it as the port range of the scan.
+func ExtractPortSpec(flags []string) (string, []string) {
+	var ports string
+	rest := make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		name, value, inline := strings.Cut(flags[i], "=")
+		if name != "-p" {
+			rest = append(rest, flags[i])
+			continue
+		}
+		if !inline && i+1 < len(flags) {
+			i++
+			value = flags[i]
+		}
+		ports = value
+	}
+	return ports, rest
+}
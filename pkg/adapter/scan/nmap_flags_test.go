@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeNmapFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: "", want: []string{}},
+		{name: "switches", raw: "-sV -Pn -T4", want: []string{"-sV", "-Pn", "-T4"}},
+		{name: "separate value", raw: "-p 22,80-90", want: []string{"-p", "22,80-90"}},
+		{name: "inline value", raw: "--min-rate=100", want: []string{"--min-rate", "100"}},
+		{name: "protocol port spec", raw: "-p T:80,U:53", want: []string{"-p", "T:80,U:53"}},
+		{name: "duration", raw: "--host-timeout 30m --scan-delay 0", want: []string{"--host-timeout", "30m", "--scan-delay", "0"}},
+		{name: "zero retries", raw: "--max-retries 0", want: []string{"--max-retries", "0"}},
+		{name: "zero rate", raw: "--min-rate 0", wantErr: true},
+		{name: "top ports", raw: "--top-ports 100", wantErr: true},
+		{name: "inline top ports", raw: "--top-ports=100", wantErr: true},
+		{name: "fast mode", raw: "-F -T4", wantErr: true},
+		{name: "negative rate", raw: "--max-rate -5", wantErr: true},
+		{name: "missing value", raw: "-p", wantErr: true},
+		{name: "invalid port spec", raw: "-p 80;rm", wantErr: true},
+		{name: "version intensity out of range", raw: "--version-intensity 10", wantErr: true},
+		{name: "invalid duration", raw: "--host-timeout 5x", wantErr: true},
+		{name: "output flag", raw: "-oN /tmp/out", wantErr: true},
+		{name: "script flag", raw: "--script=vuln", wantErr: true},
+		{name: "input file", raw: "-iL targets.txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeNmapFlags(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SanitizeNmapFlags(%q) = %v, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SanitizeNmapFlags(%q) error = %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SanitizeNmapFlags(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPortSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		wantPorts string
+		wantRest  []string
+	}{
+		{name: "no port flag", flags: []string{"-sV", "-Pn"}, wantRest: []string{"-sV", "-Pn"}},
+		{name: "separate value", flags: []string{"-sV", "-p", "22,80", "-Pn"}, wantPorts: "22,80", wantRest: []string{"-sV", "-Pn"}},
+		{name: "inline value", flags: []string{"-p=443", "-T4"}, wantPorts: "443", wantRest: []string{"-T4"}},
+		{name: "last one wins", flags: []string{"-p", "22", "-p", "80"}, wantPorts: "80", wantRest: []string{}},
+		{name: "other values stay", flags: []string{"--min-rate", "100"}, wantRest: []string{"--min-rate", "100"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, rest := ExtractPortSpec(tt.flags)
+			if ports != tt.wantPorts {
+				t.Errorf("ports = %q, want %q", ports, tt.wantPorts)
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestHasPortSelection(t *testing.T) {
+	tests := []struct {
+		flags []string
+		want  bool
+	}{
+		{flags: []string{"-sV", "-Pn"}, want: false},
+		{flags: []string{"-p", "80"}, want: true},
+		{flags: []string{"-p=80"}, want: true},
+		{flags: []string{"--min-rate", "100"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hasPortSelection(tt.flags); got != tt.want {
+			t.Errorf("hasPortSelection(%v) = %v, want %v", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package scan
+
+import (
+	"RedPaths-server/pkg/adapter/serializable"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grepableVersionRe splits the version column of a grepable port entry into
+// product and the trailing "(...)" extrainfo nmap appends.
+var grepableVersionRe = regexp.MustCompile(`^(.*?)\s*\((.*)\)$`)
+
+var grepablePortStartRe = regexp.MustCompile(`^\d+/`)
+
+// ParseNmapOutput parses nmap output that was produced outside the adapter,
+// either XML (-oX) or grepable (-oG).
+func ParseNmapOutput(data []byte) (*NmapScanResult, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty nmap output")
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return ParseNmapXML(trimmed)
+	}
+	return ParseNmapGrepable(trimmed)
+}
+
+// ParseNmapXML parses nmap XML output (-oX).
+func ParseNmapXML(data []byte) (*NmapScanResult, error) {
+	var nmapResult serializable.NmapResult
+	if err := xml.Unmarshal(data, &nmapResult); err != nil {
+		return nil, fmt.Errorf("failed to parse nmap XML output: %w", err)
+	}
+
+	return &NmapScanResult{
+		Raw:  data,
+		Data: nmapResult,
+	}, nil
+}
+
+// ParseNmapGrepable parses grepable nmap output (-oG). The result is converted
+// into the XML structure so the xpath based extraction works on it as well; it
+// just carries less information (no scripts, no separate hostname attributes).
+func ParseNmapGrepable(data []byte) (*NmapScanResult, error) {
+	type grepHost struct {
+		hostname string
+		status   string
+		ports    []string
+	}
+
+	hosts := make(map[string]*grepHost)
+	var order []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Host: ") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		head := strings.Fields(strings.TrimPrefix(fields[0], "Host: "))
+		if len(head) == 0 {
+			continue
+		}
+		ip := head[0]
+
+		h, ok := hosts[ip]
+		if !ok {
+			h = &grepHost{status: "up"}
+			hosts[ip] = h
+			order = append(order, ip)
+		}
+		if len(head) > 1 {
+			if name := strings.Trim(head[1], "()"); name != "" {
+				h.hostname = name
+			}
+		}
+
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "Status: "):
+				h.status = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(field, "Status: ")))
+			case strings.HasPrefix(field, "Ports: "):
+				h.ports = append(h.ports, splitGrepablePorts(strings.TrimPrefix(field, "Ports: "))...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grepable nmap output: %w", err)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no hosts found in grepable nmap output")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<nmaprun scanner="nmap" args="imported from grepable output">` + "\n")
+	for _, ip := range order {
+		h := hosts[ip]
+		if h.status == "down" {
+			continue
+		}
+
+		addrType := "ipv4"
+		if strings.Contains(ip, ":") {
+			addrType = "ipv6"
+		}
+
+		buf.WriteString("<host>")
+		fmt.Fprintf(&buf, `<status state="%s"/>`, xmlAttr(h.status))
+		fmt.Fprintf(&buf, `<address addr="%s" addrtype="%s"/>`, xmlAttr(ip), addrType)
+		buf.WriteString("<hostnames>")
+		if h.hostname != "" {
+			fmt.Fprintf(&buf, `<hostname name="%s" type="PTR"/>`, xmlAttr(h.hostname))
+		}
+		buf.WriteString("</hostnames><ports>")
+		for _, entry := range h.ports {
+			writeGrepablePort(&buf, entry)
+		}
+		buf.WriteString("</ports></host>\n")
+	}
+	buf.WriteString("</nmaprun>\n")
+
+	return ParseNmapXML(buf.Bytes())
+}
+
+// splitGrepablePorts splits the "Ports:" column. Entries are separated by
+// ", ", but the version column may contain ", " itself (e.g. LDAP extrainfo),
+// so a piece only starts a new entry if it begins with "<port>/".
+func splitGrepablePorts(column string) []string {
+	var entries []string
+	for _, piece := range strings.Split(column, ", ") {
+		if len(entries) > 0 && !grepablePortStartRe.MatchString(piece) {
+			entries[len(entries)-1] += ", " + piece
+			continue
+		}
+		entries = append(entries, piece)
+	}
+
+	result := entries[:0]
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// writeGrepablePort converts "port/state/protocol/owner/service/rpc/version/"
+// into a <port> element.
+func writeGrepablePort(buf *bytes.Buffer, entry string) {
+	parts := strings.Split(entry, "/")
+	if len(parts) < 3 || parts[0] == "" {
+		return
+	}
+	get := func(i int) string {
+		if i < len(parts) {
+			return strings.TrimSpace(parts[i])
+		}
+		return ""
+	}
+
+	product, extrainfo := get(6), ""
+	if m := grepableVersionRe.FindStringSubmatch(product); m != nil {
+		product, extrainfo = m[1], m[2]
+	}
+
+	fmt.Fprintf(buf, `<port protocol="%s" portid="%s">`, xmlAttr(get(2)), xmlAttr(get(0)))
+	fmt.Fprintf(buf, `<state state="%s"/>`, xmlAttr(get(1)))
+	fmt.Fprintf(buf, `<service name="%s" product="%s" extrainfo="%s"/>`,
+		xmlAttr(strings.TrimSuffix(get(4), "?")), xmlAttr(product), xmlAttr(extrainfo))
+	buf.WriteString("</port>")
+}
+
+func xmlAttr(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
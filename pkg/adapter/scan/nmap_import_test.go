@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"testing"
+)
+
+func TestParseNmapGrepable(t *testing.T) {
+	type wantPort struct {
+		id, protocol, state, service, product, extrainfo string
+	}
+	type wantHost struct {
+		ip, status string
+		ports      []wantPort
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []wantHost
+		wantErr bool
+	}{
+		{
+			name: "status and ports on separate lines",
+			data: `# Nmap 7.94 scan initiated
+Host: 10.0.0.10 (dc01.corp.local)	Status: Up
+Host: 10.0.0.10 (dc01.corp.local)	Ports: 88/open/tcp//kerberos-sec//Microsoft Windows Kerberos/, 389/open/tcp//ldap//Microsoft Windows Active Directory LDAP (Domain: corp.local, Site: Default)/	Ignored State: filtered (998)
+# Nmap done`,
+			want: []wantHost{{ip: "10.0.0.10", status: "up", ports: []wantPort{
+				{id: "88", protocol: "tcp", state: "open", service: "kerberos-sec", product: "Microsoft Windows Kerberos"},
+				{id: "389", protocol: "tcp", state: "open", service: "ldap",
+					product: "Microsoft Windows Active Directory LDAP", extrainfo: "Domain: corp.local, Site: Default"},
+			}}},
+		},
+		{
+			name: "down hosts are skipped",
+			data: `Host: 10.0.0.1 ()	Status: Down
+Host: 10.0.0.2 ()	Ports: 445/open/tcp//microsoft-ds?///`,
+			want: []wantHost{{ip: "10.0.0.2", status: "up", ports: []wantPort{
+				{id: "445", protocol: "tcp", state: "open", service: "microsoft-ds"},
+			}}},
+		},
+		{
+			name: "ipv6 and escaped values",
+			data: `Host: fe80::1 ()	Ports: 80/open/tcp//http//Apache <httpd> & co/`,
+			want: []wantHost{{ip: "fe80::1", status: "up", ports: []wantPort{
+				{id: "80", protocol: "tcp", state: "open", service: "http", product: "Apache <httpd> & co"},
+			}}},
+		},
+		{name: "no hosts", data: "# Nmap done at ...", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseNmapGrepable([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseNmapGrepable() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseNmapGrepable() error = %v", err)
+			}
+
+			hosts := result.Data.Host
+			if len(hosts) != len(tt.want) {
+				t.Fatalf("got %d hosts, want %d", len(hosts), len(tt.want))
+			}
+			for h, want := range tt.want {
+				host := hosts[h]
+				if len(host.Address) == 0 || host.Address[0].Addr != want.ip {
+					t.Errorf("host %d address = %v, want %s", h, host.Address, want.ip)
+				}
+				if host.Status.State != want.status {
+					t.Errorf("host %s status = %q, want %q", want.ip, host.Status.State, want.status)
+				}
+				if len(host.Ports.Port) != len(want.ports) {
+					t.Fatalf("host %s has %d ports, want %d", want.ip, len(host.Ports.Port), len(want.ports))
+				}
+				for p, wp := range want.ports {
+					port := host.Ports.Port[p]
+					got := wantPort{port.Portid, port.Protocol, port.State.State,
+						port.Service.Name, port.Service.Product, port.Service.Extrainfo}
+					if got != wp {
+						t.Errorf("port %d of %s = %+v, want %+v", p, want.ip, got, wp)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitGrepablePorts(t *testing.T) {
+	tests := []struct {
+		column string
+		want   int
+	}{
+		{column: "22/open/tcp//ssh///", want: 1},
+		{column: "22/open/tcp//ssh///, 80/open/tcp//http///", want: 2},
+		{column: "389/open/tcp//ldap//LDAP (Domain: a, Site: b)/, 636/open/tcp//ldapssl///", want: 2},
+		{column: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := splitGrepablePorts(tt.column); len(got) != tt.want {
+			t.Errorf("splitGrepablePorts(%q) = %q, want %d entries", tt.column, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package scan
+
+import (
+	"RedPaths-server/pkg/adapter/util"
+	"RedPaths-server/pkg/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RustScanAdapter struct {
+	*util.ExecutableHelper
+	version string
+}
+
+type RustScanOptions struct {
+	interfaces.ScanOptions
+	PortRange string
+	BatchSize int
+}
+
+func NewRustScanAdapter() interfaces.ScanAdapter {
+	return &RustScanAdapter{
+		ExecutableHelper: util.NewExecutableHelper("rustscan"),
+	}
+}
+
+func (r *RustScanAdapter) GetName() string {
+	return "rustscan"
+}
+
+func (r *RustScanAdapter) GetVersion() string {
+	if r.version == "" {
+		output, err := util.ExecWithFallback(context.Background(), r, "rustscan", "--version")
+		if err == nil {
+			// "rustscan 2.1.1"
+			fields := strings.Fields(string(output))
+			if len(fields) > 1 {
+				r.version = fields[1]
+			}
+		}
+	}
+	return r.version
+}
+
+func (r *RustScanAdapter) IsAvailable(ctx context.Context) bool {
+	_, err := util.ExecWithFallback(ctx, r, "rustscan", "--version")
+	return err == nil
+}
+
+func (r *RustScanAdapter) GetCapabilities() []string {
+	return []string{"port_discovery"}
+}
+
+func (r *RustScanAdapter) Scan(ctx context.Context, options ...interfaces.ScanOption) (interfaces.ScanResult, error) {
+	opts := &RustScanOptions{
+		ScanOptions: interfaces.ScanOptions{
+			Timeout:      30 * time.Minute,
+			OutputFormat: "greppable",
+		},
+		PortRange: "1-65535",
+		BatchSize: 4500,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	if len(opts.Targets) == 0 {
+		return nil, errors.New("no targets specified")
+	}
+
+	var cancel context.CancelFunc
+	if ctx == nil {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+	} else if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	portArgs, err := rustscanPortArgs(opts.PortRange)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"-a", strings.Join(opts.Targets, ",")}
+	args = append(args, portArgs...)
+	args = append(args, "-b", fmt.Sprintf("%d", opts.BatchSize), "-g")
+	args = append(args, opts.CustomFlags...)
+
+	log.Printf("Executing rustscan with args: %v", args)
+
+	output, err := util.ExecWithFallback(ctx, r, "rustscan", args...)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("rustscan scan timed out after %v", opts.Timeout)
+		}
+		return nil, fmt.Errorf("rustscan execution failed: %w, output: %s", err, string(output))
+	}
+
+	candidates, err := ParseDiscoveryOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiscoveryScanResult{
+		Raw:        output,
+		Candidates: candidates,
+	}, nil
+}
+
+// maxRustScanPorts limits the ports of a port list, rustscan takes the list as
+// one argument.
+const maxRustScanPorts = 10000
+
+// rustscanPortArgs translates a port specification into rustscan arguments.
+// rustscan only takes a single range with -r; everything else becomes a -p
+// list in which ranges are expanded to single ports. rustscan scans TCP only,
+// so a T: prefix is dropped and a U: prefix is rejected.
+func rustscanPortArgs(spec string) ([]string, error) {
+	if !isPortSpec(spec) {
+		return nil, fmt.Errorf("invalid port specification '%s'", spec)
+	}
+
+	parts := strings.Split(spec, ",")
+	if len(parts) == 1 && !strings.Contains(spec, ":") && strings.Contains(spec, "-") {
+		return []string{"-r", spec}, nil
+	}
+
+	var ports []string
+	seen := make(map[int]bool)
+	for _, part := range parts {
+		if strings.HasPrefix(part, "U:") {
+			return nil, fmt.Errorf("rustscan cannot scan udp ports '%s'", part)
+		}
+		part = strings.TrimPrefix(part, "T:")
+
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port '%s': %w", part, err)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid port '%s': %w", part, err)
+			}
+		}
+		if first < 1 || last > 65535 || first > last {
+			return nil, fmt.Errorf("invalid port range '%s'", part)
+		}
+		if len(ports)+last-first+1 > maxRustScanPorts {
+			return nil, fmt.Errorf("port list '%s' has more than %d ports, use a single range", spec, maxRustScanPorts)
+		}
+
+		for port := first; port <= last; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, strconv.Itoa(port))
+			}
+		}
+	}
+	return []string{"-p", strings.Join(ports, ",")}, nil
+}
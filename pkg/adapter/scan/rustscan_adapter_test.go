@@ -0,0 +1,48 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRustscanPortArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single range", spec: "1-65535", want: []string{"-r", "1-65535"}},
+		{name: "single port", spec: "443", want: []string{"-p", "443"}},
+		{name: "port list", spec: "22,80,443", want: []string{"-p", "22,80,443"}},
+		{name: "ranges in list", spec: "22,80-82", want: []string{"-p", "22,80,81,82"}},
+		{name: "duplicates", spec: "80,79-81", want: []string{"-p", "80,79,81"}},
+		{name: "tcp prefix", spec: "T:22,T:80", want: []string{"-p", "22,80"}},
+		{name: "tcp prefixed range", spec: "T:1-3", want: []string{"-p", "1,2,3"}},
+		{name: "udp", spec: "U:53", wantErr: true},
+		{name: "reversed range", spec: "22,90-80", wantErr: true},
+		{name: "port zero", spec: "0,22", wantErr: true},
+		{name: "out of range", spec: "22,70000", wantErr: true},
+		{name: "too many ports", spec: "22,1-65535", wantErr: true},
+		{name: "invalid", spec: "80;rm", wantErr: true},
+		{name: "empty", spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rustscanPortArgs(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("rustscanPortArgs(%q) = %v, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rustscanPortArgs(%q) error = %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rustscanPortArgs(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
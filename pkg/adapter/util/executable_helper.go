@@ -2,6 +2,11 @@ package util
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
 	"sync"
 )
 
@@ -49,32 +54,44 @@ func (h *ExecutableHelper) SetPathChecked(checked bool) {
 	h.pathChecked = checked
 }
 
-func ExecWithFallback(ctx context.Context, adapter ExecutableAdapter, defaultPath string, args ...string) ([]byte, error) {
-	/*var execPath string
+// ResolvePath returns the absolute path of the configured executable, falling
+// back to defaultPath when the configured one cannot be found in $PATH.
+func ResolvePath(adapter ExecutableAdapter, defaultPath string) (string, error) {
+	if resolved, err := exec.LookPath(adapter.GetExecutablePath()); err == nil {
+		return resolved, nil
+	}
+
+	resolved, err := exec.LookPath(defaultPath)
+	if err != nil {
+		return "", fmt.Errorf("executable '%s' not found: %w", defaultPath, err)
+	}
+	return resolved, nil
+}
 
+func ExecWithFallback(ctx context.Context, adapter ExecutableAdapter, defaultPath string, args ...string) ([]byte, error) {
 	if !adapter.IsPathChecked() {
 		configuredPath := adapter.GetExecutablePath()
 
-		cmd := exec.CommandContext(ctx, configuredPath, "--version")
-		err := cmd.Run()
-
-		if err != nil {
+		if _, err := exec.LookPath(configuredPath); err != nil {
 			log.Printf("Configured Path '%s' is not found, trying fallback cmd '%s'", configuredPath, defaultPath)
-			fallbackCmd := exec.CommandContext(ctx, defaultPath, "--version")
-			if fallbackCmd.Run() == nil {
-				log.Printf("Using '%s' as Fallback", defaultPath)
-				adapter.SetExecutablePath(defaultPath)
+			if _, err := exec.LookPath(defaultPath); err != nil {
+				return nil, fmt.Errorf("executable '%s' not found: %w", defaultPath, err)
 			}
+			log.Printf("Using '%s' as Fallback", defaultPath)
+			adapter.SetExecutablePath(defaultPath)
 		}
 
 		adapter.SetPathChecked(true)
 	}
-	*/
-	/*execPath = adapter.GetExecutablePath()
 
-	cmd := exec.CommandContext(ctx, execPath, args...)*/
-	//return cmd.CombinedOtput()
-	return nil, nil
+	// stdout only, so structured output (XML/JSON) is not mixed with warnings
+	cmd := exec.CommandContext(ctx, adapter.GetExecutablePath(), args...)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return output, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return output, err
 }
 
 func ExecWithFallbackSimple(adapter ExecutableAdapter, defaultPath string, args ...string) ([]byte, error) {
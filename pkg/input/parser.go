@@ -19,7 +19,7 @@ func UnmarshalInput(raw json.RawMessage, inputType string) (input.InputValue, er
 		}
 		return c, nil
 
-	case "targetInput":
+	case "targetInput", "targetSelection":
 		var tmp struct {
 			input.CommonFields
 			Value json.RawMessage `json:"value"`
@@ -31,7 +31,7 @@ func UnmarshalInput(raw json.RawMessage, inputType string) (input.InputValue, er
 		if err := json.Unmarshal(tmp.Value, &list); err != nil {
 			return nil, err
 		}
-		return input.TargetListValue{tmp.CommonFields, list}, nil
+		return input.TargetListValue{CommonFields: tmp.CommonFields, Value: list}, nil
 
 	default:
 		var t input.TextInputValue
@@ -10,6 +10,7 @@ type ToolAdapter interface {
 	GetName() string
 	GetVersion() string
 	IsAvailable(ctx context.Context) bool
+	GetCapabilities() []string
 }
 
 type ScanAdapter interface {
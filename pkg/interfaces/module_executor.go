@@ -8,4 +8,7 @@ import (
 
 type ModuleExecutor interface {
 	ExecuteModule(key string, params *input.Parameter, logger *sse.SSELogger) error
+	// CheckPrerequisites reports all required tools of the modules that are
+	// missing on this host.
+	CheckPrerequisites(keys []string) error
 }
@@ -27,6 +27,7 @@ type DirectoryNode struct {
 	ObjectClass       string            `json:"directory_node.object_class,omitempty"`
 	IsBuiltin         bool              `json:"directory_node.is_builtin,omitempty"`
 	IsProtected       bool              `json:"directory_node.is_protected,omitempty"`
+	BlocksInheritance bool              `json:"directory_node.blocks_inheritance,omitempty"`
 
 	// Relations
 	Parent     *utils.UIDRef   `json:"directory_node.parent,omitempty"`
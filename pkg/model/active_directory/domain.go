@@ -24,6 +24,8 @@ type Domain struct {
 	FSMORoleOwners        []string `json:"domain.fsmo_role_owners,omitempty"`
 	LinkedGPOs            []string `json:"domain.linked_gpos,omitempty"`
 	DefaultContainers     []string `json:"domain.default_containers,omitempty"`
+	RiskScore             int      `json:"domain.risk_score,omitempty"`
+	RiskReasons           []string `json:"domain.risk_reasons,omitempty"`
 
 	// Relations
 	ContainsDirNodes  []*utils.UIDRef `json:"domain.contains_dir_nodes,omitempty"`
@@ -210,6 +212,11 @@ func (b *TrustBuilder) WithTransitivity(isTransitive bool) *TrustBuilder {
 	return b
 }
 
+func (b *TrustBuilder) WithSIDFiltering(enabled bool) *TrustBuilder {
+	b.trust.SIDFiltering = enabled
+	return b
+}
+
 func (b *TrustBuilder) Build() Trust {
 	return b.trust
 }
@@ -11,9 +11,11 @@ type Link struct {
 	DType []string `json:"dgraph.type,omitempty"`
 
 	//Specific
-	LinkOrder  int  `json:"gpo.link_order"`
-	IsEnforced bool `json:"gpo.is_enforced,omitempty"`
-	IsEnabled  bool `json:"gpo.is_enabled,omitempty"`
+	LinkOrder  int  `json:"gpo_link.link_order"`
+	IsEnforced bool `json:"gpo_link.is_enforced,omitempty"`
+	// kein omitempty: ein deaktivierter Link muss gespeichert werden, fehlt
+	// das Feld (ältere Daten), gilt der Link als aktiv
+	IsEnabled bool `json:"gpo_link.is_enabled"`
 
 	// Relations
 	/*	Links []*utils.UIDRef `json:"active_directory.has_domain,omitempty"`
@@ -4,6 +4,14 @@ import (
 	"RedPaths-server/pkg/model/core"
 )
 
+// Setting types the GPO analysis evaluates. Other types are stored as they
+// come but not analysed.
+const (
+	SettingRestrictedGroups     = "RestrictedGroups"
+	SettingScheduledTask        = "ScheduledTask"
+	SettingUserRightsAssignment = "UserRightsAssignment"
+)
+
 type Setting struct {
 
 	// Internal
@@ -11,7 +19,7 @@ type Setting struct {
 	DType []string `json:"dgraph.type,omitempty"`
 
 	//Specific
-	SettingType int    `json:"gpo_setting.setting_type,omitempty"`
+	SettingType string `json:"gpo_setting.setting_type,omitempty"`
 	Value       string `json:"gpo_setting.value,omitempty"`
 
 	// Meta
@@ -12,7 +12,7 @@ type Group struct {
 	GroupScope      string   `json:"group.group_scope,omitempty"`
 	GroupType       string   `json:"group.group_type,omitempty"`
 	IsPrivileged    bool     `json:"group.is_privileged,omitempty"`
-	IsBuiltIn       bool     `json:"group.is_built_in,omitempty"`
+	IsBuiltIn       bool     `json:"group.is_builtin,omitempty"`
 	Privileges      []string `json:"group.privileges,omitempty"`
 	CanDCSync       bool     `json:"group.can_dcsync,omitempty"`
 	CanRDP          bool     `json:"group.can_rdp,omitempty"`
@@ -12,8 +12,8 @@ type ACE struct {
 
 	//Specific
 	Name       string `json:"ace.name,omitempty"`
-	AccessType string `json:"ace.accesss_type,omitempty"`
-	Inherit    bool   `json:"ace.inherit,omitempty"`
+	AccessType string `json:"ace.access_type,omitempty"`
+	Inherit    bool   `json:"ace.inherited,omitempty"`
 	AppliesTo  string `json:"ace.applies_to,omitempty"`
 
 	// Meta
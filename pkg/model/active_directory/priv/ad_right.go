@@ -11,7 +11,7 @@ type ADRight struct {
 	//Specific
 	Name      string `json:"ad_right.name,omitempty"`
 	Category  string `json:"ad_right.category,omitempty"`
-	RistLevel int    `json:"ace.inherit,omitempty"`
+	RistLevel int    `json:"ad_right.risk_level,omitempty"`
 
 	// Meta
 	RedPathsMetadata core.RedPathsMetadata `json:"-"`
@@ -0,0 +1,54 @@
+package priv
+
+import (
+	"RedPaths-server/pkg/model/core"
+	"strings"
+)
+
+// Delegation types of a DelegationConfig.
+const (
+	DelegationUnconstrained         = "unconstrained"
+	DelegationConstrained           = "constrained"
+	DelegationConstrainedTransition = "constrained_protocol_transition" // TRUSTED_TO_AUTH_FOR_DELEGATION
+)
+
+// DelegationConfig is the Kerberos delegation setting of a user or computer
+// account. AllowedToDelegateTo holds the SPNs of msDS-AllowedToDelegateTo for
+// constrained delegation.
+type DelegationConfig struct {
+
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	//Specific
+	DelegationType      string   `json:"delegation_config.delegation_type,omitempty"`
+	AllowedToDelegateTo []string `json:"delegation_config.allowed_to_delegate_to,omitempty"`
+
+	// Meta
+	RedPathsMetadata core.RedPathsMetadata `json:"-"`
+}
+
+func (d *DelegationConfig) UnmarshalJSON(data []byte) error {
+	type Alias DelegationConfig
+	aux := (*Alias)(d)
+	return core.UnmarshalWithMetadata(data, aux, &d.RedPathsMetadata)
+}
+
+func (d DelegationConfig) MarshalJSON() ([]byte, error) {
+	type Alias DelegationConfig
+	return core.MarshalWithMetadata(Alias(d), d.RedPathsMetadata)
+}
+
+// SPNHost returns the host part of a service principal name,
+// "cifs/fs01.corp.local:445/corp.local" → "fs01.corp.local".
+func SPNHost(spn string) string {
+	_, rest, ok := strings.Cut(spn, "/")
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexAny(rest, ":/"); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.ToLower(rest)
+}
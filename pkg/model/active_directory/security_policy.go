@@ -10,10 +10,15 @@ type SecurityPolicy struct {
 	DType []string `json:"dgraph.type,omitempty"`
 
 	// Specific
-	MinPwdLength     int `json:"security_policy.min_pwd_length,omitempty"`
-	PwdHistoryLength int `json:"security_policy.pwd_history_length,omitempty"`
-	LockoutThreshold int `json:"security_policy.lockout_threshold,omitempty"`
-	LockoutDuration  int `json:"security_policy.lockout_duration,omitempty"`
+	MinPwdLength         int  `json:"security_policy.min_pwd_length,omitempty"`
+	PwdHistoryLength     int  `json:"security_policy.pwd_history_length,omitempty"`
+	LockoutThreshold     int  `json:"security_policy.lockout_threshold,omitempty"`
+	LockoutDuration      int  `json:"security_policy.lockout_duration,omitempty"` // minutes
+	LockoutWindow        int  `json:"security_policy.lockout_window,omitempty"`   // minutes
+	MaxPwdAge            int  `json:"security_policy.max_pwd_age,omitempty"`      // days, 0 = never expires
+	MinPwdAge            int  `json:"security_policy.min_pwd_age,omitempty"`      // days
+	PwdComplexity        bool `json:"security_policy.pwd_complexity,omitempty"`
+	ReversibleEncryption bool `json:"security_policy.reversible_encryption,omitempty"`
 
 	// Meta
 	RedPathsMetadata core.RedPathsMetadata `json:"-"`
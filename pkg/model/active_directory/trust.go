@@ -3,8 +3,30 @@ package active_directory
 import (
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/utils"
+	"strings"
 )
 
+// Trust types (BloodHound naming).
+const (
+	TrustTypeParentChild = "ParentChild"
+	TrustTypeCrossLink   = "CrossLink"
+	TrustTypeTreeRoot    = "TreeRoot"
+	TrustTypeForest      = "Forest"
+	TrustTypeExternal    = "External"
+	TrustTypeUnknown     = "Unknown"
+)
+
+// Trust directions, seen from the domain holding the trust: Outbound means
+// the domain trusts the target, principals of the target can access it.
+const (
+	TrustDirectionInbound       = "Inbound"
+	TrustDirectionOutbound      = "Outbound"
+	TrustDirectionBidirectional = "Bidirectional"
+	TrustDirectionDisabled      = "Disabled"
+)
+
+// Trust is a trust of a domain ("has_trust") towards the trusted domain
+// ("trusts"). TrustedDomain is filled when reading, not stored.
 type Trust struct {
 
 	// Internal
@@ -15,6 +37,7 @@ type Trust struct {
 	TrustType    string `json:"trust.trust_type,omitempty"`
 	Direction    string `json:"trust.direction,omitempty"`
 	IsTransitive bool   `json:"trust.is_transitive,omitempty"`
+	SIDFiltering bool   `json:"trust.sid_filtering_enabled,omitempty"`
 
 	// Relations
 	TrustedDomain *utils.UIDRef `json:"trust.trusted_domain,omitempty"`
@@ -33,3 +56,27 @@ func (t Trust) MarshalJSON() ([]byte, error) {
 	type Alias Trust
 	return core.MarshalWithMetadata(Alias(t), t.RedPathsMetadata)
 }
+
+// WithinForest reports whether the trust type links domains of the same
+// forest. Inside a forest SID filtering does not apply.
+func (t *Trust) WithinForest() bool {
+	switch t.TrustType {
+	case TrustTypeParentChild, TrustTypeCrossLink, TrustTypeTreeRoot:
+		return true
+	}
+	return false
+}
+
+// AccessDirections reports whether principals of the target can access the
+// domain holding the trust (outbound) and vice versa (inbound).
+func (t *Trust) AccessDirections() (targetToDomain, domainToTarget bool) {
+	switch strings.ToLower(t.Direction) {
+	case strings.ToLower(TrustDirectionOutbound):
+		return true, false
+	case strings.ToLower(TrustDirectionInbound):
+		return false, true
+	case strings.ToLower(TrustDirectionBidirectional):
+		return true, true
+	}
+	return false, false
+}
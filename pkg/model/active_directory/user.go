@@ -17,7 +17,7 @@ type User struct {
 	IsDisabled        bool            `json:"user.is_disabled,omitempty"`
 	IsLocked          bool            `json:"user.is_locked,omitempty"`
 	IsServiceAccount  bool            `json:"user.is_service_account,omitempty"`
-	LastLogon         time.Time       `json:"user.last_login,omitempty"`
+	LastLogon         time.Time       `json:"user.last_logon,omitempty"`
 	PwdLastSet        time.Time       `json:"user.pwd_last_set,omitempty"`
 	BadPwdCount       int             `json:"user.bad_pwd_count,omitempty"`
 	AllowedToDelegate bool            `json:"user.allowed_to_delegate,omitempty"`
@@ -29,8 +29,8 @@ type User struct {
 	ASREPRoastable bool `json:"user.asrep_roastable,omitempty"`
 
 	// Risk
-	RiskScore   int      `json:"risk_score,omitempty"`
-	RiskReasons []string `json:"risk_reason,omitempty"`
+	RiskScore   int      `json:"user.risk_score,omitempty"`
+	RiskReasons []string `json:"user.risk_reasons,omitempty"`
 
 	//// Credentials
 	//Password       string `json:"password,omitempty"`
@@ -41,6 +41,11 @@ type User struct {
 	IsLocalAdmin  bool `json:"user.is_local_admin,omitempty"`
 	IsDomainAdmin bool `json:"user.is_domain_admin,omitempty"`
 
+	// Effective privileges, propagated from the (nested) groups
+	IsPrivileged bool `json:"user.is_privileged,omitempty"`
+	CanDCSync    bool `json:"user.can_dcsync,omitempty"`
+	CanRDP       bool `json:"user.can_rdp,omitempty"`
+
 	// Delegation
 	/*	TrustedForDelegation    bool `json:"trusted_for_delegation,omitempty"`
 		UnconstrainedDelegation bool `json:"unconstrained_delegation,omitempty"`
@@ -24,6 +24,7 @@ const (
 	PredicateDerives            Predicate = "derives"
 	PredicateLocates            Predicate = "locates"
 	PredicateHasGPOLink         Predicate = "has_gpo_link"
+	PredicateHasGPO             Predicate = "has_gpo" // z.B. Domain → GPO
 	PredicateParent             Predicate = "parent"
 	PredicateRuns               Predicate = "runs"
 	PredicateLinksTo            Predicate = "links_to"
@@ -31,6 +32,18 @@ const (
 	PredicatePossibleDuplicate  Predicate = "possible_duplicate"
 	PredicateHasGroup           Predicate = "has_group"
 	PredicateHasUser            Predicate = "has_user"
+	PredicateHasMember          Predicate = "has_member"  // z.B. Group → User/Group/Host
+	PredicateHasSession         Predicate = "has_session" // z.B. Host → User
+	PredicateAdminTo            Predicate = "admin_to"    // z.B. User/Group → Host
+	PredicateGrantedTo          Predicate = "granted_to"  // z.B. ACE → Principal
+	PredicateHasACL             Predicate = "has_acl"
+	PredicateHasVulnerability   Predicate = "has_vulnerability"   // z.B. Host/Service → Vulnerability
+	PredicateHasSecurityPolicy  Predicate = "has_security_policy" // z.B. Domain → SecurityPolicy
+	PredicateHasCapability      Predicate = "has_capability"      // z.B. Principal → Capability
+	PredicateAppliesTo          Predicate = "applies_to"          // z.B. Capability → Zielobjekt
+	PredicateHasDelegation      Predicate = "has_delegation"      // z.B. Host/User → DelegationConfig
+	PredicateHasTrust           Predicate = "has_trust"           // z.B. Domain → Trust
+	PredicateTrusts             Predicate = "trusts"              // z.B. Trust → vertraute Domain
 )
 
 // ----------------------
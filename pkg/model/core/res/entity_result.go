@@ -4,6 +4,7 @@ import (
 	"RedPaths-server/pkg/model/active_directory/gpo"
 	"RedPaths-server/pkg/model/active_directory/priv"
 	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/redpaths/history"
 	"time"
 )
 
@@ -40,4 +41,17 @@ type ResultMetadata struct {
 	ScanTimestamp  time.Time `json:"scan_timestamp"`
 	EntityCount    int       `json:"entity_count"`
 	AssertionCount int       `json:"assertion_count"`
+	Outcome        Outcome   `json:"outcome,omitempty"`
+
+	// Changes lists the fields a merge actually modified (empty for created).
+	Changes []history.FieldChange `json:"changes,omitempty"`
 }
+
+// Outcome tells how an upsert resolved the incoming entity.
+type Outcome string
+
+const (
+	OutcomeCreated   Outcome = "created"
+	OutcomeMerged    Outcome = "merged"
+	OutcomeDuplicate Outcome = "duplicate"
+)
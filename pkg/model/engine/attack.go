@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"RedPaths-server/pkg/model/utils"
+	"time"
+)
+
+// Attack is an action of the attacker: it needs the Requires capabilities,
+// acts on Targets and yields the Grants capabilities.
+type Attack struct {
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	// Specific
+	AttackID    string  `json:"attack.attack_id"`
+	Name        string  `json:"attack.name"`
+	Cost        int     `json:"attack.cost"`
+	Noise       float64 `json:"attack.noise"`
+	Reliability float64 `json:"attack.reliability"`
+
+	// Relations
+	Requires []*utils.UIDRef `json:"attack.requires,omitempty"`
+	Grants   []*utils.UIDRef `json:"attack.grants,omitempty"`
+	Targets  []*utils.UIDRef `json:"attack.targets,omitempty"`
+}
+
+// AttackResult is one execution of an attack.
+type AttackResult struct {
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	// Specific
+	Success        bool      `json:"attack_result.success"`
+	NoiseGenerated float64   `json:"attack_result.noise_generated"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Relations
+	ExecutedAttack *utils.UIDRef `json:"attack_result.executed_attack,omitempty"`
+	Compromised    *utils.UIDRef `json:"attack_result.compromised,omitempty"`
+}
+
+// AttackState is the state of a simulation: the accumulated noise and cost,
+// the executed attacks and the capabilities held so far.
+type AttackState struct {
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	// Specific
+	Timestamp  time.Time `json:"attack_state.timestamp"`
+	TotalNoise float64   `json:"attack_state.total_noise"`
+	TotalCost  int       `json:"attack_state.total_cost"`
+
+	// Relations
+	HasHistory    []*utils.UIDRef `json:"attack_state.has_history,omitempty"`
+	HasCapability []*utils.UIDRef `json:"attack_state.has_capability,omitempty"`
+}
+
+// CompromiseRecord notes that a principal or host fell to an attack.
+// ParentOf links the records an attack chain continues with.
+type CompromiseRecord struct {
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	// Specific
+	CompromisedAt  time.Time `json:"compromise_record.compromised_at"`
+	NoiseGenerated float64   `json:"compromise_record.noise_generated"`
+	Cost           int       `json:"compromise_record.cost"`
+
+	// Relations
+	CompromisedPrincipal *utils.UIDRef   `json:"compromise_record.compromised_principal,omitempty"`
+	CompromisedHost      *utils.UIDRef   `json:"compromise_record.compromised_host,omitempty"`
+	ViaAttack            *utils.UIDRef   `json:"compromise_record.via_attack,omitempty"`
+	ParentOf             []*utils.UIDRef `json:"compromise_record.parent_of,omitempty"`
+}
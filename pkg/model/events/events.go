@@ -59,6 +59,11 @@ const (
 
 	VulnFound    EventType = "vulnerability_found"
 	VulnAnalyzed EventType = "vulnerability_analyzed"
+
+	ImportStart    EventType = "import_start"
+	ImportProgress EventType = "import_progress"
+	ImportComplete EventType = "import_complete"
+	ImportError    EventType = "import_error"
 )
 
 // String gibt den String-Wert des EventType zurück
@@ -72,7 +77,8 @@ func (e EventType) IsValid() bool {
 	case ScanStart, ScanProgress, ScanComplete, ScanError,
 		ModuleStart, ModuleComplete, ModuleError,
 		HostDiscovered, PortFound, ServiceDetected, DomainDiscovered,
-		VulnFound, VulnAnalyzed:
+		VulnFound, VulnAnalyzed,
+		ImportStart, ImportProgress, ImportComplete, ImportError:
 		return true
 	}
 	return false
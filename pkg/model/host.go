@@ -30,6 +30,8 @@ type Host struct {
 	OperatingSystemVersion string    `json:"host.operating_system_version"`
 	LastLogonTimestamp     time.Time `json:"host.last_logon_timestamp"`
 	UserAccountControl     int       `json:"host.user_account_control"`
+	RiskScore              int       `json:"host.risk_score,omitempty"`
+	RiskReasons            []string  `json:"host.risk_reasons,omitempty"`
 
 	// Relations
 	Runs   []*utils.UIDRef `json:"host.runs,omitempty"`
@@ -13,6 +13,7 @@ const (
 	ChangeTypeCreated     ChangeType = "created"
 	ChangeTypeUpdated     ChangeType = "updated"
 	ChangeTypePossibleDup ChangeType = "possible_duplicate"
+	ChangeTypeReverted    ChangeType = "reverted"
 )
 
 type Change struct {
@@ -24,6 +25,7 @@ type Change struct {
 	ChangedAt    time.Time     `gorm:"column:changed_at;not null"     json:"changed_at"`
 	ChangedBy    string        `gorm:"column:changed_by"              json:"changed_by"`
 	ChangeReason string        `gorm:"column:change_reason"           json:"change_reason"`
+	RunID        string        `gorm:"column:run_id"                  json:"run_id,omitempty"`
 }
 type FieldChange struct {
 	Field    string `json:"field"`
@@ -0,0 +1,20 @@
+package redpaths
+
+import (
+	"time"
+)
+
+// ImportRun records one offline import (BloodHound collection, nmap files, ...)
+// so imported data can be traced back to the files it came from.
+type ImportRun struct {
+	ImportID      string      `gorm:"column:import_id" json:"import_id"`
+	ProjectUID    string      `gorm:"column:project_uid" json:"project_uid"`
+	Source        string      `gorm:"column:source" json:"source"`
+	Files         []string    `gorm:"column:files;type:jsonb;serializer:json" json:"files"`
+	Actor         string      `gorm:"column:actor" json:"actor"`
+	StartedAt     time.Time   `gorm:"column:started_at" json:"started_at"`
+	FinishedAt    time.Time   `gorm:"column:finished_at" json:"finished_at"`
+	WasSuccessful bool        `gorm:"column:was_successful" json:"was_successful"`
+	Summary       interface{} `gorm:"column:summary;type:jsonb;serializer:json" json:"summary"`
+	RolledBackAt  *time.Time  `gorm:"column:rolled_back_at" json:"rolled_back_at,omitempty"`
+}
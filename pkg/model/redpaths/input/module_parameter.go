@@ -1,6 +1,10 @@
 package input
 
-import "RedPaths-server/pkg/model"
+import (
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+)
 
 type InputValue interface {
 	typeName() string
@@ -12,6 +16,12 @@ type Parameter struct {
 	Metadata   map[string]string     `json:"metadata"`
 }
 
+// Context returns the context modules write with; it tags the created nodes
+// with the run, so the run can be rolled back.
+func (p *Parameter) Context() context.Context {
+	return assertion.WithRunID(context.Background(), p.RunID)
+}
+
 func (p *Parameter) GetTextInput(key string) *string {
 	if iv, ok := p.Inputs[key]; ok {
 		if ti, ok := iv.(TextInputValue); ok {
@@ -0,0 +1,34 @@
+package redpaths
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mitreTechniquePattern matches ATT&CK technique and sub-technique IDs
+// (T1087, T1087.002).
+var mitreTechniquePattern = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+// NormalizeMitreTechniques uppercases and deduplicates technique IDs. IDs
+// that are no valid ATT&CK technique are returned separately.
+func NormalizeMitreTechniques(ids []string) (valid []string, invalid []string) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if mitreTechniquePattern.MatchString(id) {
+			valid = append(valid, id)
+		} else {
+			invalid = append(invalid, id)
+		}
+	}
+	return valid, invalid
+}
+
+// MitreTechniqueURL links a technique on attack.mitre.org.
+func MitreTechniqueURL(id string) string {
+	return "https://attack.mitre.org/techniques/" + strings.ReplaceAll(id, ".", "/") + "/"
+}
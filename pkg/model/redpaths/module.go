@@ -6,6 +6,7 @@ import (
 
 type Module struct {
 	AttackID         string                 `gorm:"column:attack_id" json:"attack_id"`
+	MitreTechniques  []string               `gorm:"column:mitre_techniques;type:jsonb;serializer:json" json:"mitre_techniques"`
 	ExecutionMetric  string                 `gorm:"column:execution_metric" json:"execution_metric"`
 	Description      string                 `gorm:"column:description" json:"description"`
 	Name             string                 `gorm:"column:name" json:"name"`
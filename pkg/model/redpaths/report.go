@@ -0,0 +1,21 @@
+package redpaths
+
+import (
+	"time"
+)
+
+// Report is a rendered engagement report of a project. Content holds the
+// artifact and is only loaded for downloads.
+type Report struct {
+	ReportID    string    `gorm:"column:report_id" json:"report_id"`
+	ProjectUID  string    `gorm:"column:project_uid" json:"project_uid"`
+	Title       string    `gorm:"column:title" json:"title"`
+	Format      string    `gorm:"column:format" json:"format"`
+	Template    string    `gorm:"column:template" json:"template"`
+	FileName    string    `gorm:"column:file_name" json:"file_name"`
+	ContentType string    `gorm:"column:content_type" json:"content_type"`
+	Size        int64     `gorm:"column:size" json:"size"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	CreatedBy   string    `gorm:"column:created_by" json:"created_by"`
+	Content     []byte    `gorm:"column:content" json:"-"`
+}
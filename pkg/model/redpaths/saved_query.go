@@ -0,0 +1,17 @@
+package redpaths
+
+import (
+	"time"
+)
+
+// SavedQuery is a graph query stored under a name in a project.
+type SavedQuery struct {
+	QueryID     string    `gorm:"column:query_id" json:"query_id"`
+	ProjectUID  string    `gorm:"column:project_uid" json:"project_uid"`
+	Name        string    `gorm:"column:name" json:"name"`
+	Description string    `gorm:"column:description" json:"description,omitempty"`
+	Query       string    `gorm:"column:query" json:"query"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	CreatedBy   string    `gorm:"column:created_by" json:"created_by"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
@@ -4,6 +4,7 @@ type Context struct {
 	Confidence *float64 `json:"confidence,omitempty"`
 	Status     *string  `json:"status,omitempty"`
 	HighValue  *bool    `json:"high_value,omitempty"`
+	Method     *string  `json:"method,omitempty"`
 }
 
 func NewContext(opts ...func(*Context)) Context {
@@ -28,6 +29,9 @@ func FromRequest(c *Context) Context {
 	if c.HighValue != nil {
 		defaults.HighValue = c.HighValue
 	}
+	if c.Method != nil {
+		defaults.Method = c.Method
+	}
 	return defaults
 }
 
@@ -55,3 +59,12 @@ func (c Context) IsHighValue() bool {
 	}
 	return false
 }
+
+// GetMethod returns the assertion method, "direct_add" unless set otherwise
+// (e.g. "imported" for data coming from an import).
+func (c Context) GetMethod() string {
+	if c.Method != nil {
+		return *c.Method
+	}
+	return "direct_add"
+}
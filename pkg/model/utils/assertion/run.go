@@ -0,0 +1,26 @@
+package assertion
+
+import "context"
+
+// RunIDField is the predicate every node created during an import or module
+// run carries, so the run can be rolled back later.
+const RunIDField = "run_id"
+
+type runIDKey struct{}
+
+// WithRunID marks ctx as belonging to the import or module run runID. Nodes
+// and change records written with the returned context are tagged with it.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	if runID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunID returns the run ctx belongs to, "" outside of a run.
+func RunID(ctx context.Context) string {
+	if runID, ok := ctx.Value(runIDKey{}).(string); ok {
+		return runID
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"RedPaths-server/pkg/model/core"
+	"strings"
+)
+
+// Vulnerability is a single scanner finding on a host (and optionally one of
+// its services). It is linked via "has_vulnerability" assertions, so the same
+// plugin on two hosts results in two vulnerability nodes carrying their own
+// evidence.
+type Vulnerability struct {
+	// Internal
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	// Specific
+	Name             string   `json:"vulnerability.name,omitempty"`
+	CVEs             []string `json:"vulnerability.cves,omitempty"`
+	CVSS             float64  `json:"vulnerability.cvss,omitempty"`
+	CVSSVector       string   `json:"vulnerability.cvss_vector,omitempty"`
+	Severity         string   `json:"vulnerability.severity,omitempty"`
+	PluginID         string   `json:"vulnerability.plugin_id,omitempty"`
+	Scanner          string   `json:"vulnerability.scanner,omitempty"`
+	Port             string   `json:"vulnerability.port,omitempty"`
+	Protocol         string   `json:"vulnerability.protocol,omitempty"`
+	Evidence         string   `json:"vulnerability.evidence,omitempty"`
+	Description      string   `json:"vulnerability.description,omitempty"`
+	Solution         string   `json:"vulnerability.solution,omitempty"`
+	ExploitAvailable bool     `json:"vulnerability.exploit_available,omitempty"`
+
+	// Meta
+	RedPathsMetadata core.RedPathsMetadata `json:"-"`
+}
+
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRanks = map[string]int{
+	SeverityInfo:     0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SeverityRank orders severities from info (0) to critical (4); unknown
+// values rank as info.
+func SeverityRank(severity string) int {
+	return severityRanks[strings.ToLower(severity)]
+}
+
+// SeverityFromCVSS maps a CVSS base score onto the qualitative rating.
+func SeverityFromCVSS(score float64) string {
+	switch {
+	case score >= 9.0:
+		return SeverityCritical
+	case score >= 7.0:
+		return SeverityHigh
+	case score >= 4.0:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+// Key identifies a finding on one host: the same plugin reported for the same
+// port by the same scanner is the same vulnerability.
+func (v *Vulnerability) Key() string {
+	id := v.PluginID
+	if id == "" {
+		id = strings.ToLower(v.Name)
+	}
+	return strings.ToLower(v.Scanner) + "|" + id + "|" + v.Port + "/" + strings.ToLower(v.Protocol)
+}
+
+func (v *Vulnerability) UnmarshalJSON(data []byte) error {
+	type Alias Vulnerability
+	aux := (*Alias)(v)
+	return core.UnmarshalWithMetadata(data, aux, &v.RedPathsMetadata)
+}
+
+func (v Vulnerability) MarshalJSON() ([]byte, error) {
+	type Alias Vulnerability
+	return core.MarshalWithMetadata(Alias(v), v.RedPathsMetadata)
+}
@@ -1,9 +1,20 @@
 package module_exec
 
 import (
+	"RedPaths-server/pkg/adapter"
+	"RedPaths-server/pkg/interfaces"
+	"RedPaths-server/pkg/interfaces/module"
 	"RedPaths-server/pkg/model/redpaths/input"
 	"RedPaths-server/pkg/sse"
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrMissingTools = errors.New("required tools are not available")
 )
 
 // ExecuteModule executes a registered module by key
@@ -20,7 +31,87 @@ func (r *Registry) ExecuteModule(key string, params *input.Parameter, moduleLogg
 		}
 	}
 
-	return impl.ExecuteModule(params, moduleLogger)
+	if err := checkToolPrerequisites(context.Background(), impl); err != nil {
+		moduleLogger.Error(fmt.Sprintf("Module %s cannot run: %v", key, err))
+		return fmt.Errorf("[Executor] Prerequisite check failed for module %s: %w", key, err)
+	}
+
+	if err := impl.ExecuteModule(params, moduleLogger); err != nil {
+		return err
+	}
+
+	// the results of the module change what is at risk
+	r.riskService.Rescore(context.Background(), params.ProjectUID, key)
+	return nil
+}
+
+// checkToolPrerequisites fails fast if a required tool adapter of the module
+// is not registered or its binary is not available on this host.
+func checkToolPrerequisites(ctx context.Context, impl interfaces.RedPathsModule) error {
+	metadata := impl.GetMetadata()
+	if metadata == nil {
+		return nil
+	}
+
+	registry := adapter.GetAdapterFactory()
+	for _, prereq := range metadata.Prerequisites {
+		if prereq == nil || prereq.Type != module.PrereqTool || !prereq.Required {
+			continue
+		}
+		if err := registry.EnsureAvailable(ctx, prereq.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPrerequisites checks the required tools of all given modules before
+// any of them runs. Unlike the per-module check it does not stop at the first
+// missing tool but reports every missing tool together with the modules that
+// need it.
+func (r *Registry) CheckPrerequisites(keys []string) error {
+	ctx := context.Background()
+	registry := adapter.GetAdapterFactory()
+
+	// tool name -> modules requiring it
+	missing := make(map[string][]string)
+	for _, key := range keys {
+		impl, exists := r.implementations[key]
+		if !exists {
+			return fmt.Errorf("[Executor] No implementation found for module key: %s", key)
+		}
+		metadata := impl.GetMetadata()
+		if metadata == nil {
+			continue
+		}
+		for _, prereq := range metadata.Prerequisites {
+			if prereq == nil || prereq.Type != module.PrereqTool || !prereq.Required {
+				continue
+			}
+			if _, known := missing[prereq.Name]; !known {
+				if err := registry.EnsureAvailable(ctx, prereq.Name); err == nil {
+					continue
+				}
+			}
+			missing[prereq.Name] = append(missing[prereq.Name], key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tools := make([]string, 0, len(missing))
+	for name := range missing {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+
+	details := make([]string, 0, len(tools))
+	for _, name := range tools {
+		details = append(details, fmt.Sprintf("%s (required by %s)", name, strings.Join(missing[name], ", ")))
+	}
+	return fmt.Errorf("%w: %s", ErrMissingTools, strings.Join(details, "; "))
 }
 
 // ExecuteModule is a global shortcut to execute a module
@@ -7,6 +7,7 @@ import (
 	"RedPaths-server/pkg/model/redpaths"
 	"RedPaths-server/pkg/model/rpsdk"
 	redpaths2 "RedPaths-server/pkg/service/redpaths"
+	"RedPaths-server/pkg/service/risk"
 	"context"
 	"fmt"
 	"log"
@@ -25,6 +26,7 @@ type Registry struct {
 	pendingModules       map[string]*pendingModuleInfo
 	mu                   sync.RWMutex // Race Condition Protection
 	RecommendationEngine *recommendation.Engine
+	riskService          *risk.RiskService
 }
 
 type pendingModuleInfo struct {
@@ -56,6 +58,7 @@ func InitializeRegistry(postgresCon *gorm.DB, dgraphCon *dgo.Dgraph) error {
 
 	GlobalRegistry.moduleService = moduleService
 	GlobalRegistry.RecommendationEngine = recomEngine
+	GlobalRegistry.riskService = risk.NewRiskService(dgraphCon)
 	GlobalRegistry.serviceFactory = func() *rpsdk.Services {
 		return rpsdk.NewServicesContainer(dgraphCon, postgresCon)
 	}
@@ -27,6 +27,7 @@ var Registry = map[string]EntitySchema{
 			"host.distinguished_name",
 			"host.operating_system",
 			"host.operating_system_version",
+			"host.risk_score",
 			"created_at",
 			"modified_at",
 			"dgraph.type",
@@ -44,6 +45,8 @@ var Registry = map[string]EntitySchema{
 			"host.description",
 			"host.last_logon_timestamp",
 			"host.user_account_control",
+			"host.risk_score",
+			"host.risk_reasons",
 			"created_at",
 			"modified_at",
 			"dgraph.type",
@@ -78,6 +81,124 @@ var Registry = map[string]EntitySchema{
 		},
 		CatalogPredicate: core.PredicateRuns,
 	},
+	"Vulnerability": {
+		DgraphType: "Vulnerability",
+		DefaultFields: []string{
+			"uid",
+			"vulnerability.name",
+			"vulnerability.cves",
+			"vulnerability.cvss",
+			"vulnerability.severity",
+			"vulnerability.plugin_id",
+			"vulnerability.scanner",
+			"vulnerability.port",
+			"vulnerability.protocol",
+			"vulnerability.exploit_available",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		DetailFields: []string{
+			"uid",
+			"vulnerability.name",
+			"vulnerability.cves",
+			"vulnerability.cvss",
+			"vulnerability.cvss_vector",
+			"vulnerability.severity",
+			"vulnerability.plugin_id",
+			"vulnerability.scanner",
+			"vulnerability.port",
+			"vulnerability.protocol",
+			"vulnerability.evidence",
+			"vulnerability.description",
+			"vulnerability.solution",
+			"vulnerability.exploit_available",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		CatalogPredicate: core.PredicateHasVulnerability,
+	},
+	"SecurityPolicy": {
+		DgraphType: "SecurityPolicy",
+		DefaultFields: []string{
+			"uid",
+			"security_policy.min_pwd_length",
+			"security_policy.pwd_history_length",
+			"security_policy.lockout_threshold",
+			"security_policy.lockout_duration",
+			"security_policy.lockout_window",
+			"security_policy.max_pwd_age",
+			"security_policy.min_pwd_age",
+			"security_policy.pwd_complexity",
+			"security_policy.reversible_encryption",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		DetailFields: []string{
+			"uid",
+			"security_policy.min_pwd_length",
+			"security_policy.pwd_history_length",
+			"security_policy.lockout_threshold",
+			"security_policy.lockout_duration",
+			"security_policy.lockout_window",
+			"security_policy.max_pwd_age",
+			"security_policy.min_pwd_age",
+			"security_policy.pwd_complexity",
+			"security_policy.reversible_encryption",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		CatalogPredicate: core.PredicateHasSecurityPolicy,
+	},
+	"Trust": {
+		DgraphType: "Trust",
+		DefaultFields: []string{
+			"uid",
+			"trust.trust_type",
+			"trust.direction",
+			"trust.is_transitive",
+			"trust.sid_filtering_enabled",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		DetailFields: []string{
+			"uid",
+			"trust.trust_type",
+			"trust.direction",
+			"trust.is_transitive",
+			"trust.sid_filtering_enabled",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		CatalogPredicate: core.PredicateHasTrust,
+	},
+	"GPOSetting": {
+		DgraphType: "GPOSetting",
+		DefaultFields: []string{
+			"uid",
+			"gpo_setting.setting_type",
+			"gpo_setting.value",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		DetailFields: []string{
+			"uid",
+			"gpo_setting.setting_type",
+			"gpo_setting.value",
+			"created_at",
+			"modified_at",
+			"discovered_at",
+			"discovered_by",
+			"dgraph.type",
+		},
+		CatalogPredicate: core.PredicateContains,
+	},
 	"ActiveDirectory": {
 		DgraphType: "ActiveDirectory",
 		DefaultFields: []string{
@@ -109,6 +230,7 @@ var Registry = map[string]EntitySchema{
 			"domain.domain_guid",
 			"domain.domain_functional_level",
 			"domain.forest_functional_level",
+			"domain.risk_score",
 			"created_at",
 			"modified_at",
 			"dgraph.type",
@@ -124,6 +246,8 @@ var Registry = map[string]EntitySchema{
 			"domain.forest_functional_level",
 			"domain.fsmo_role_owners",
 			"domain.default_containers",
+			"domain.risk_score",
+			"domain.risk_reasons",
 			"created_at",
 			"modified_at",
 			"dgraph.type",
@@ -156,6 +280,9 @@ var Registry = map[string]EntitySchema{
 			"user.is_locked",
 			"user.is_domain_admin",
 			"user.is_local_admin",
+			"user.is_privileged",
+			"user.can_dcsync",
+			"user.can_rdp",
 			"user.kerberoastable",
 			"user.asrep_roastable",
 			"user.allowed_to_delegate",
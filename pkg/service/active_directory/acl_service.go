@@ -8,6 +8,7 @@ import (
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
 	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
 	"context"
 	"fmt"
 	"log"
@@ -240,3 +241,131 @@ func (s *ACLService) GetACL(ctx context.Context, aclUID string) (*priv.ACL, erro
 		return s.aclRepo.GetACL(ctx, tx, aclUID)
 	})
 }
+
+// -----------------------------------------------------------------------------
+// ImportACE
+// -----------------------------------------------------------------------------
+
+// ImportACE maps a single collected access control entry onto the model:
+//
+//	Object -has_acl-> ACL -contains-> ACE -contains-> ADRight
+//	                                  ACE -granted_to-> Principal
+//
+// The ACL and the ADRight are reused if they already exist, and an ACE with the
+// same right for the same principal is not created twice. Returns false if the
+// entry was already known.
+func (s *ACLService) ImportACE(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	object *utils2.UIDRef,
+	principal *utils2.UIDRef,
+	rightName string,
+	inherited bool,
+	actor string,
+) (bool, error) {
+	var created bool
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		aclUID, err := s.ensureACL(ctx, tx, assertionCtx, object, actor)
+		if err != nil {
+			return err
+		}
+
+		// Known entry? Same right name granted to the same principal.
+		aces, err := s.aclRepo.GetAllACEByACL(ctx, tx, aclUID)
+		if err != nil {
+			return fmt.Errorf("loading aces of acl %s: %w", aclUID, err)
+		}
+		for _, existing := range aces {
+			if existing.Entity == nil || existing.Entity.Name != rightName {
+				continue
+			}
+			link, err := s.assertionRepo.FindLink(ctx, tx, existing.Entity.UID, principal.UID, core.PredicateGrantedTo)
+			if err != nil {
+				return err
+			}
+			if link != nil {
+				return nil
+			}
+		}
+
+		adRight, err := s.aclRepo.FindADRightByName(ctx, tx, rightName)
+		if err != nil {
+			return err
+		}
+		if adRight == nil {
+			adRight, err = s.aclRepo.CreateADRight(ctx, tx, &priv.ADRight{Name: rightName}, actor)
+			if err != nil {
+				return fmt.Errorf("creating ad right %s: %w", rightName, err)
+			}
+		}
+
+		// Collectors only report granted rights
+		ace, err := s.aclRepo.CreateACE(ctx, tx, &priv.ACE{
+			Name:       rightName,
+			AccessType: "Allow",
+			Inherit:    inherited,
+		}, actor)
+		if err != nil {
+			return fmt.Errorf("creating ace: %w", err)
+		}
+
+		links := []struct {
+			subject, object *utils2.UIDRef
+			predicate       core.Predicate
+		}{
+			{&utils2.UIDRef{UID: aclUID, Type: "ACL"}, &utils2.UIDRef{UID: ace.UID, Type: "ACE"}, core.PredicateContains},
+			{&utils2.UIDRef{UID: ace.UID, Type: "ACE"}, &utils2.UIDRef{UID: adRight.UID, Type: "ADRight"}, core.PredicateContains},
+			{&utils2.UIDRef{UID: ace.UID, Type: "ACE"}, principal, core.PredicateGrantedTo},
+		}
+		for _, l := range links {
+			if _, _, err := linkOnce(ctx, tx, s.assertionRepo, l.subject, l.object, l.predicate, assertionCtx, actor); err != nil {
+				return err
+			}
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("ImportACE failed: %w", err)
+	}
+
+	return created, nil
+}
+
+// ensureACL returns the ACL attached to the object, creating it on first use.
+func (s *ACLService) ensureACL(
+	ctx context.Context,
+	tx *dgo.Txn,
+	assertionCtx assertion.Context,
+	object *utils2.UIDRef,
+	actor string,
+) (string, error) {
+	existing, err := s.assertionRepo.GetAssertionsByPredicate(ctx, tx, object.UID, core.PredicateHasACL)
+	if err != nil {
+		return "", fmt.Errorf("loading acl of %s: %w", object.UID, err)
+	}
+	for _, a := range existing {
+		if a.Object != nil && a.Object.UID != "" {
+			return a.Object.UID, nil
+		}
+	}
+
+	acl, err := s.aclRepo.CreateACL(ctx, tx, &priv.ACL{Name: object.UID}, actor)
+	if err != nil {
+		return "", fmt.Errorf("creating acl for %s: %w", object.UID, err)
+	}
+
+	if _, _, err := linkOnce(ctx, tx, s.assertionRepo,
+		object,
+		&utils2.UIDRef{UID: acl.UID, Type: "ACL"},
+		core.PredicateHasACL,
+		assertionCtx,
+		actor,
+	); err != nil {
+		return "", err
+	}
+
+	return acl.UID, nil
+}
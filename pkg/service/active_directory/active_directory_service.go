@@ -178,6 +178,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 	subjectUID, subjectType, hasParent := input.Resolved()
 
 	var result *res.EntityResult[*rpap.ActiveDirectory]
+	var outcome res.Outcome
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
 		// --- Existence Check ---
@@ -198,6 +199,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 				return fmt.Errorf("creating active directory: %w", err)
 			}
 			actualAD = createdAD
+			outcome = res.OutcomeCreated
 			log.Printf("[UpsertActiveDirectory] Created uid=%s forest=%s", actualAD.UID, actualAD.ForestName)
 
 		case dgraphutil.ExistenceSourceHierarchy,
@@ -212,6 +214,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 					return fmt.Errorf("creating active directory (low score): %w", err)
 				}
 				actualAD = createdAD
+				outcome = res.OutcomeCreated
 				log.Printf("[UpsertActiveDirectory] Low score, created uid=%s", actualAD.UID)
 
 			} else if best.Score >= 0.8 {
@@ -231,6 +234,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 					return fmt.Errorf("merging active directory: %w", err)
 				}
 				actualAD = updated
+				outcome = res.OutcomeMerged
 				log.Printf("[UpsertActiveDirectory] Merged uid=%s score=%.2f",
 					actualAD.UID, best.Score)
 
@@ -261,6 +265,12 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 				}
 
 				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
 				return nil
 			}
 
@@ -271,7 +281,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 		// --- Create assertion ---
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateHasActiveDirectory,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
 			Source:              input.Actor,
 			Confidence:          input.AssertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -295,6 +305,7 @@ func (s *ActiveDirectoryService) UpsertActiveDirectory(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        outcome,
 			},
 		}
 
@@ -0,0 +1,49 @@
+package active_directory
+
+import (
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// linkOnce creates the assertion subject -[predicate]-> object unless the exact
+// edge already exists. Relationship imports (memberships, sessions, admin rights)
+// are replayed on every run, so the edge must be idempotent.
+func linkOnce(
+	ctx context.Context,
+	tx *dgo.Txn,
+	assertionRepo engine2.AssertionRepository,
+	subject, object *utils2.UIDRef,
+	predicate core.Predicate,
+	assertionCtx assertion.Context,
+	actor string,
+) (*core.Assertion, bool, error) {
+	existing, err := assertionRepo.FindLink(ctx, tx, subject.UID, object.UID, predicate)
+	if err != nil {
+		return nil, false, fmt.Errorf("checking existing %s link: %w", predicate, err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	created, err := assertionRepo.Create(ctx, tx, &core.Assertion{
+		Predicate:  predicate,
+		Method:     core.Method(assertionCtx.GetMethod()),
+		Source:     actor,
+		Confidence: assertionCtx.GetConfidence(),
+		Status:     core.Status(assertionCtx.GetStatus()),
+		Timestamp:  time.Now(),
+		Subject:    subject,
+		Object:     object,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("creating %s assertion: %w", predicate, err)
+	}
+	return created, true, nil
+}
@@ -0,0 +1,122 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/active_directory"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"RedPaths-server/pkg/model/core"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type DelegationService struct {
+	delegationRepo active_directory.DelegationRepository
+	assertionRepo  engine2.AssertionRepository
+	db             *dgo.Dgraph
+}
+
+func NewDelegationService(dgraphCon *dgo.Dgraph) (*DelegationService, error) {
+	return &DelegationService{
+		db:             dgraphCon,
+		delegationRepo: active_directory.NewDgraphDelegationRepository(dgraphCon),
+		assertionRepo:  engine2.NewDgraphAssertionRepository(dgraphCon),
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// ImportDelegations
+// -----------------------------------------------------------------------------
+
+// ImportDelegations brings the delegation settings of an account in line with
+// configs: one DelegationConfig per delegation type, linked via has_delegation.
+// Known configs get their SPNs updated, types the account no longer has are
+// invalidated. Returns the number of created configs.
+func (s *DelegationService) ImportDelegations(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	principal *utils2.UIDRef,
+	configs []*priv.DelegationConfig,
+	actor string,
+) (int, error) {
+	created := 0
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		links, err := s.assertionRepo.GetAssertionsByPredicate(ctx, tx, principal.UID, core.PredicateHasDelegation)
+		if err != nil {
+			return fmt.Errorf("loading delegations of %s: %w", principal.UID, err)
+		}
+
+		known := make(map[string]*priv.DelegationConfig)
+		for _, link := range links {
+			if link.Object == nil || link.Status == core.StatusInvalidated || link.Status == core.StatusExpired {
+				continue
+			}
+			existing, err := s.delegationRepo.GetDelegationConfig(ctx, tx, link.Object.UID)
+			if err != nil {
+				return fmt.Errorf("loading delegation config %s: %w", link.Object.UID, err)
+			}
+
+			wanted := findDelegation(configs, existing.DelegationType)
+			if wanted == nil {
+				_, err := s.assertionRepo.Update(ctx, tx, link.UID, map[string]interface{}{
+					"assertion.status": string(core.StatusInvalidated),
+				})
+				if err != nil {
+					return fmt.Errorf("invalidating delegation %s: %w", link.UID, err)
+				}
+				continue
+			}
+			known[existing.DelegationType] = existing
+
+			if !slices.Equal(existing.AllowedToDelegateTo, wanted.AllowedToDelegateTo) {
+				_, err := s.delegationRepo.UpdateDelegationConfig(ctx, tx, existing.UID, actor, map[string]interface{}{
+					"delegation_config.allowed_to_delegate_to": wanted.AllowedToDelegateTo,
+				})
+				if err != nil {
+					return fmt.Errorf("updating delegation config %s: %w", existing.UID, err)
+				}
+			}
+		}
+
+		for _, config := range configs {
+			if known[config.DelegationType] != nil {
+				continue
+			}
+			createdConfig, err := s.delegationRepo.CreateDelegationConfig(ctx, tx, config, actor)
+			if err != nil {
+				return fmt.Errorf("creating delegation config: %w", err)
+			}
+			if _, _, err := linkOnce(ctx, tx, s.assertionRepo,
+				principal,
+				&utils2.UIDRef{UID: createdConfig.UID, Type: "DelegationConfig"},
+				core.PredicateHasDelegation,
+				assertionCtx,
+				actor,
+			); err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ImportDelegations failed: %w", err)
+	}
+
+	return created, nil
+}
+
+func findDelegation(configs []*priv.DelegationConfig, delegationType string) *priv.DelegationConfig {
+	for _, c := range configs {
+		if c.DelegationType == delegationType {
+			return c
+		}
+	}
+	return nil
+}
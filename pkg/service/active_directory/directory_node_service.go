@@ -11,8 +11,11 @@ import (
 	"RedPaths-server/pkg/model/active_directory/priv"
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths/history"
 	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
 	engine3 "RedPaths-server/pkg/service/catalog"
+	engine5 "RedPaths-server/pkg/service/change"
 	engine4 "RedPaths-server/pkg/service/upsert"
 	"context"
 	"fmt"
@@ -30,6 +33,7 @@ type DirectoryNodeService struct {
 	directoryNodeRepo   active_directory.DirectoryNodeRepository
 	assertionRepo       engine.AssertionRepository
 	aclRepo             active_directory.ACLRepository
+	gpoRepo             active_directory.GPORepository
 	catalogService      *engine3.CatalogService
 	db                  *dgo.Dgraph
 }
@@ -51,6 +55,7 @@ func NewDirectoryNodeService(dgraphCon *dgo.Dgraph) (*DirectoryNodeService, erro
 		userRepo:            userRepo,
 		directoryNodeRepo:   directoryNodeRepo,
 		assertionRepo:       assertionRepo,
+		gpoRepo:             active_directory.NewDgraphGPORepository(dgraphCon),
 		catalogService:      catalogService,
 	}, nil
 }
@@ -155,14 +160,33 @@ func (s *DirectoryNodeService) AddSecurityPrincipal(
 	return result, nil
 }
 
+// AddGPOLink links a GPO to the directory node, like DomainService.LinkGPO
+// does for domains. The GPO is looked up in the domain of the node.
 func (s *DirectoryNodeService) AddGPOLink(
 	ctx context.Context,
-	directoryNodeUID string,
+	assertionCtx assertion.Context,
 	incomingGPOLink *gpo.Link,
+	incomingGPO *gpo.GPO,
+	directoryNodeUID string,
 	actor string,
-) (*res.EntityResult[*gpo.Link], error) {
+) (*res.GPOResult[*gpo.Link], error) {
+	var result *res.GPOResult[*gpo.Link]
 
-	panic("implement me")
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		domainUID, err := s.directoryNodeRepo.GetDomainUID(ctx, tx, directoryNodeUID)
+		if err != nil {
+			return err
+		}
+		result, err = linkGPO(ctx, tx, s.gpoRepo, s.assertionRepo, assertionCtx,
+			incomingGPOLink, incomingGPO, &utils2.UIDRef{UID: directoryNodeUID, Type: "DirectoryNode"}, domainUID, actor)
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("AddGPOLink failed: %w", err)
+	}
+
+	return result, nil
 }
 
 func (s *DirectoryNodeService) GetDirectoryNodeSecurityPrincipals(ctx context.Context, directoryNodeUID string) ([]*rpad.SecurityPrincipal, error) {
@@ -187,6 +211,8 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 	subjectUID, subjectType, hasParent := input.Resolved()
 
 	var result *res.EntityResult[*rpad.DirectoryNode]
+	var outcome res.Outcome
+	var changes []history.FieldChange // field-level diff of a merge, nil otherwise
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
 		// --- Existence Check ---
@@ -207,6 +233,7 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 				return fmt.Errorf("creating directory node: %w", err)
 			}
 			actualNode = createdNode
+			outcome = res.OutcomeCreated
 			log.Printf("[UpsertDirectoryNode] Created uid=%s dn=%s", actualNode.UID, actualNode.DistinguishedName)
 
 		case dgraph.ExistenceSourceHierarchy,
@@ -221,6 +248,7 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 					return fmt.Errorf("creating directory node (low score): %w", err)
 				}
 				actualNode = createdNode
+				outcome = res.OutcomeCreated
 				log.Printf("[UpsertDirectoryNode] Low score, created uid=%s", actualNode.UID)
 
 			} else if best.Score >= 0.8 {
@@ -230,6 +258,7 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 					input.Entity,
 					input.AssertionCtx.GetConfidence(),
 				)
+				changes = engine5.DiffMergeFields(best.Result.Entity, mergeFields)
 				updated, err := s.directoryNodeRepo.Update(
 					ctx, tx,
 					best.Result.Entity.UID,
@@ -240,6 +269,7 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 					return fmt.Errorf("merging directory node: %w", err)
 				}
 				actualNode = updated
+				outcome = res.OutcomeMerged
 				log.Printf("[UpsertDirectoryNode] Merged uid=%s score=%.2f",
 					actualNode.UID, best.Score)
 
@@ -270,6 +300,12 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 				}
 
 				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
 				return nil
 			}
 
@@ -280,7 +316,7 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 		// --- Create assertion ---
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateContains,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
 			Source:              input.Actor,
 			Confidence:          input.AssertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -304,6 +340,8 @@ func (s *DirectoryNodeService) UpsertDirectoryNode(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        outcome,
+				Changes:        changes,
 			},
 		}
 
@@ -398,6 +436,11 @@ func buildDirectoryNodeMergeFields(
 	if incoming.IsProtected && !existing.IsProtected {
 		fields["directory_node.is_protected"] = true
 	}
+	// Block inheritance: an import only reports it when set, clearing it
+	// goes through UpdateDirectoryNode
+	if incoming.BlocksInheritance && !existing.BlocksInheritance {
+		fields["directory_node.blocks_inheritance"] = true
+	}
 
 	return fields
 }
@@ -506,6 +549,7 @@ func (s *DirectoryNodeService) GetAllDirectoryNodesInDomain(
 		"directory_node.distinguished_name",
 		"directory_node.node_type",
 		"directory_node.is_builtin",
+		"directory_node.blocks_inheritance",
 		"dgraph.type",
 		"discovered_by",
 		"discovered_at",
@@ -555,6 +599,7 @@ func (s *DirectoryNodeService) GetAllChildDirectoryNodes(
 			"directory_node.distinguished_name",
 			"directory_node.node_type",
 			"directory_node.is_builtin",
+			"directory_node.blocks_inheritance",
 			"dgraph.type",
 			"discovered_by",
 			"discovered_at",
@@ -12,9 +12,11 @@ import (
 	"RedPaths-server/pkg/model/active_directory/priv"
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths/history"
 	utils2 "RedPaths-server/pkg/model/utils"
 	"RedPaths-server/pkg/model/utils/assertion"
 	engine3 "RedPaths-server/pkg/service/catalog"
+	engine5 "RedPaths-server/pkg/service/change"
 	engine4 "RedPaths-server/pkg/service/upsert"
 	"context"
 	"fmt"
@@ -32,6 +34,7 @@ type DomainService struct {
 	assertionRepo        engine.AssertionRepository
 	aclRepo              active_directory.ACLRepository
 	gpoRepo              active_directory.GPORepository
+	securityPolicyRepo   active_directory.SecurityPolicyRepository
 	catalogService       *engine3.CatalogService
 	directoryNodeService *DirectoryNodeService // neu
 
@@ -46,6 +49,7 @@ func NewDomainService(dgraphCon *dgo.Dgraph) (*DomainService, error) {
 	assertionRepo := engine.NewDgraphAssertionRepository(dgraphCon)
 	aclRepo := active_directory.NewDgraphDgraphACLRepository(dgraphCon)
 	gpoRepo := active_directory.NewDgraphGPORepository(dgraphCon)
+	securityPolicyRepo := active_directory.NewDgraphSecurityPolicyRepository(dgraphCon)
 	catalogService := engine3.NewCatalogService(dgraphCon)
 	directoryNodeService, _ := NewDirectoryNodeService(dgraphCon)
 
@@ -58,6 +62,7 @@ func NewDomainService(dgraphCon *dgo.Dgraph) (*DomainService, error) {
 		assertionRepo:        assertionRepo,
 		aclRepo:              aclRepo,
 		gpoRepo:              gpoRepo,
+		securityPolicyRepo:   securityPolicyRepo,
 		catalogService:       catalogService,
 		directoryNodeService: directoryNodeService,
 	}, nil
@@ -369,110 +374,19 @@ func (s *DomainService) LinkGPO(ctx context.Context, assertionCtx assertion.Cont
 	log.Println("[AddGPOLink]")
 
 	var result *res.GPOResult[*gpo.Link]
-	var linkedGPO *gpo.GPO
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
-
-		// Check if gpo already exists in domain scope
-		existingGPO, err := s.domainRepo.GetGPOIfKnown(ctx, tx, incomingGPO.Name)
-		if err != nil {
-			return fmt.Errorf("error while checking existing directory node: %w", err)
-		}
-
-		if len(existingGPO) == 0 {
-			log.Println("[AddGPOLink] No existing GPO found. Creating new one")
-			linkedGPO, err = s.gpoRepo.CreateGPO(ctx, tx, incomingGPO, actor)
-			if err != nil {
-				return fmt.Errorf("error while creating gpo: %w", err)
-			}
-		} else {
-			// names of gpos are unique, so is only one result gpo
-			log.Println("Found GPO in Domain. Using existing one...")
-			linkedGPO = existingGPO[0]
-		}
-
-		var gpoLink *gpo.Link
-		var gpoLinkAssertion []*core.Assertion
-		var gpoAssertion []*core.Assertion
-
-		//TODO: Exist Check for links
-
-		gpoLink, err = s.gpoRepo.CreateLink(
-			ctx,
-			tx,
-			incomingGPOLink,
-			actor,
-		)
-
-		if err != nil {
-			return fmt.Errorf("failed creating gpo link: %w", err)
-		}
-
-		log.Printf(
-			"[AddGPOLink] Created gpo link to domain uid=%s",
-			gpoLink.UID,
-		)
-		// Create assertion to gpo link
-		assertionEntity := &core.Assertion{
-			Predicate:           core.PredicateHasGPOLink,
-			Method:              core.MethodDirectAdd,
-			Source:              actor,
-			Confidence:          assertionCtx.GetConfidence(),
-			Status:              core.StatusValidated,
-			Timestamp:           time.Now(),
-			HasDiscoveredParent: true,
-			MarkedAsHighValue:   assertionCtx.IsHighValue(),
-			Subject:             &utils2.UIDRef{UID: domainUID, Type: "Domain"},
-			Object:              &utils2.UIDRef{UID: gpoLink.UID, Type: "GPOLink"},
-		}
-
-		createdAssertion, err := s.assertionRepo.Create(ctx, tx, assertionEntity)
-		if err != nil {
-			return fmt.Errorf("creating assertion from domain to gpo link: %w", err)
-		}
-		gpoLinkAssertion = append(gpoLinkAssertion, createdAssertion)
-
-		// Create assertion to gpo
-		assertionEntity = &core.Assertion{
-			Predicate:           core.PredicateLinksTo,
-			Method:              core.MethodDirectAdd,
-			Source:              actor,
-			Confidence:          assertionCtx.GetConfidence(),
-			Status:              core.StatusValidated,
-			Timestamp:           time.Now(),
-			HasDiscoveredParent: true,
-			MarkedAsHighValue:   assertionCtx.IsHighValue(),
-			Subject:             &utils2.UIDRef{UID: gpoLink.UID, Type: "GPOLink"},
-			Object:              &utils2.UIDRef{UID: linkedGPO.UID, Type: "GPO"},
-		}
-
-		createdAssertion, err = s.assertionRepo.Create(ctx, tx, assertionEntity)
-		if err != nil {
-			return fmt.Errorf("creating assertion: %w", err)
-		}
-		gpoAssertion = append(gpoAssertion, createdAssertion)
-
-		result = &res.GPOResult[*gpo.Link]{
-			GPOLink:           gpoLink,
-			GPOLinkAssertions: gpoLinkAssertion,
-			GPO:               linkedGPO,
-			GPOAssertions:     gpoAssertion,
-			Metadata: &res.ResultMetadata{
-				Source:         actor,
-				ScanTimestamp:  time.Now(),
-				EntityCount:    2,
-				AssertionCount: len(gpoAssertion) + len(gpoLinkAssertion),
-			},
-		}
-		return nil
+		var err error
+		result, err = linkGPO(ctx, tx, s.gpoRepo, s.assertionRepo, assertionCtx,
+			incomingGPOLink, incomingGPO, &utils2.UIDRef{UID: domainUID, Type: "Domain"}, domainUID, actor)
+		return err
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("AddDomainDirectoryNode failed: %w", err)
+		return nil, fmt.Errorf("LinkGPO failed: %w", err)
 	}
 
 	return result, nil
-
 }
 
 func (s *DomainService) GetDomainHosts(ctx context.Context, domainUID string) ([]*res.EntityResult[*model.Host], error) {
@@ -505,6 +419,8 @@ func (s *DomainService) UpsertDomain(
 	subjectUID, subjectType, hasParent := input.Resolved()
 
 	var result *res.EntityResult[*rpad.Domain]
+	var outcome res.Outcome
+	var changes []history.FieldChange // field-level diff of a merge, nil otherwise
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
 		// --- Existence Check ---
@@ -525,6 +441,7 @@ func (s *DomainService) UpsertDomain(
 				return fmt.Errorf("creating domain: %w", err)
 			}
 			actualDomain = createdDomain
+			outcome = res.OutcomeCreated
 			log.Printf("[UpsertDomain] Created uid=%s name=%s", actualDomain.UID, actualDomain.Name)
 
 			defaultDirNodes, err := s.directoryNodeService.CreateBuildDefaultDirectoryNodes(ctx, tx, input.Actor, actualDomain.UID)
@@ -545,6 +462,7 @@ func (s *DomainService) UpsertDomain(
 					return fmt.Errorf("creating domain (low score): %w", err)
 				}
 				actualDomain = createdDomain
+				outcome = res.OutcomeCreated
 				log.Printf("[UpsertDomain] Low score, created uid=%s", actualDomain.UID)
 
 				defaultDirNodes, err := s.directoryNodeService.CreateBuildDefaultDirectoryNodes(ctx, tx, input.Actor, actualDomain.UID)
@@ -560,6 +478,7 @@ func (s *DomainService) UpsertDomain(
 					input.Entity,
 					input.AssertionCtx.GetConfidence(),
 				)
+				changes = engine5.DiffMergeFields(best.Result.Entity, mergeFields)
 				updated, err := s.domainRepo.Update(
 					ctx, tx,
 					best.Result.Entity.UID,
@@ -570,6 +489,7 @@ func (s *DomainService) UpsertDomain(
 					return fmt.Errorf("merging domain: %w", err)
 				}
 				actualDomain = updated
+				outcome = res.OutcomeMerged
 				log.Printf("[UpsertDomain] Merged uid=%s score=%.2f",
 					actualDomain.UID, best.Score)
 
@@ -600,6 +520,12 @@ func (s *DomainService) UpsertDomain(
 				}
 
 				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
 				return nil
 			}
 
@@ -610,7 +536,7 @@ func (s *DomainService) UpsertDomain(
 		// --- Create assertion ---
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateHasDomain,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
 			Source:              input.Actor,
 			Confidence:          input.AssertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -634,6 +560,8 @@ func (s *DomainService) UpsertDomain(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        outcome,
+				Changes:        changes,
 			},
 		}
 
@@ -760,3 +688,114 @@ func (s *DomainService) UpdateDomain(ctx context.Context, uid, actor string, fie
 		return s.domainRepo.Update(ctx, tx, uid, actor, fields)
 	})
 }
+
+// -----------------------------------------------------------------------------
+// SecurityPolicy
+// -----------------------------------------------------------------------------
+
+// GetSecurityPolicy returns the password/lockout policy of a domain, nil if
+// none is known yet.
+func (s *DomainService) GetSecurityPolicy(ctx context.Context, domainUID string) (*res.EntityResult[*rpad.SecurityPolicy], error) {
+	policies, err := db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*rpad.SecurityPolicy], error) {
+		return s.securityPolicyRepo.GetByDomainUID(ctx, tx, domainUID)
+	})
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+	return policies[0], nil
+}
+
+// SetSecurityPolicy stores the domain policy. A domain has exactly one policy,
+// so an existing node is updated in place (outcome merged, with the changed
+// fields in the metadata) instead of linking a second one.
+func (s *DomainService) SetSecurityPolicy(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	domainUID string,
+	incoming *rpad.SecurityPolicy,
+	actor string,
+) (*res.EntityResult[*rpad.SecurityPolicy], error) {
+	if domainUID == "" {
+		return nil, utils.ErrUIDRequired
+	}
+
+	var result *res.EntityResult[*rpad.SecurityPolicy]
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		existing, err := s.securityPolicyRepo.GetByDomainUID(ctx, tx, domainUID)
+		if err != nil {
+			return fmt.Errorf("loading security policy: %w", err)
+		}
+
+		var current *rpad.SecurityPolicy
+		var assertions []*core.Assertion
+		var changes []history.FieldChange
+		outcome := res.OutcomeCreated
+
+		if len(existing) > 0 && existing[0].Entity != nil {
+			current = existing[0].Entity
+			assertions = existing[0].Assertions
+			outcome = res.OutcomeMerged
+
+			fields := buildSecurityPolicyFields(incoming)
+			changes = engine5.DiffMergeFields(current, fields)
+			if len(changes) > 0 {
+				current, err = s.securityPolicyRepo.UpdateSecurityPolicy(ctx, tx, current.UID, actor, fields)
+				if err != nil {
+					return fmt.Errorf("updating security policy: %w", err)
+				}
+			}
+		} else {
+			incoming.DType = []string{"SecurityPolicy"}
+			current, err = s.securityPolicyRepo.Create(ctx, tx, incoming, actor)
+			if err != nil {
+				return fmt.Errorf("creating security policy: %w", err)
+			}
+
+			link, _, err := linkOnce(ctx, tx, s.assertionRepo,
+				&utils2.UIDRef{UID: domainUID, Type: "Domain"},
+				&utils2.UIDRef{UID: current.UID, Type: "SecurityPolicy"},
+				core.PredicateHasSecurityPolicy, assertionCtx, actor,
+			)
+			if err != nil {
+				return err
+			}
+			assertions = []*core.Assertion{link}
+		}
+
+		result = &res.EntityResult[*rpad.SecurityPolicy]{
+			Entity:     current,
+			Assertions: assertions,
+			Metadata: &res.ResultMetadata{
+				Source:         actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: len(assertions),
+				Outcome:        outcome,
+				Changes:        changes,
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SetSecurityPolicy failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// buildSecurityPolicyFields writes every field explicitly: 0 is a meaningful
+// value here (e.g. lockout_threshold 0 = no lockout).
+func buildSecurityPolicyFields(incoming *rpad.SecurityPolicy) map[string]interface{} {
+	return map[string]interface{}{
+		"security_policy.min_pwd_length":        incoming.MinPwdLength,
+		"security_policy.pwd_history_length":    incoming.PwdHistoryLength,
+		"security_policy.lockout_threshold":     incoming.LockoutThreshold,
+		"security_policy.lockout_duration":      incoming.LockoutDuration,
+		"security_policy.lockout_window":        incoming.LockoutWindow,
+		"security_policy.max_pwd_age":           incoming.MaxPwdAge,
+		"security_policy.min_pwd_age":           incoming.MinPwdAge,
+		"security_policy.pwd_complexity":        incoming.PwdComplexity,
+		"security_policy.reversible_encryption": incoming.ReversibleEncryption,
+	}
+}
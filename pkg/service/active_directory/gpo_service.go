@@ -1,14 +1,35 @@
 package active_directory
 
 import (
+	"RedPaths-server/internal/db"
 	"RedPaths-server/internal/repository/active_directory"
 	"RedPaths-server/internal/repository/redpaths/engine"
 	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/dgraph-io/dgo/v210"
 )
 
+var (
+	ErrGPONotFound       = errors.New("gpo not found")
+	ErrInvalidGPOSetting = errors.New("invalid gpo setting")
+)
+
+// settingPrincipalTypes are the principals a GPOSetting can be granted to.
+var settingPrincipalTypes = map[string]bool{
+	"User":  true,
+	"Group": true,
+	"Host":  true,
+}
+
 type GPOService struct {
 	domainRepo        active_directory.DomainRepository
 	hostRepo          active_directory.HostRepository
@@ -38,3 +59,335 @@ func NewGPOService(dgraphCon *dgo.Dgraph) (*GPOService, error) {
 func (s *GPOService) CreateAndLinkGPO(ctx context.Context, sourceObjectUID string, incomingGPOLink gpo.Link, actor string) (*gpo.Link, error) {
 	panic("implement me")
 }
+
+// UpsertGPO returns the GPO of the domain with the name of incoming and
+// creates it if the domain does not know it yet. GPOs are identified by name
+// within their domain like in linkGPO, so a later link reuses the node and
+// equally named GPOs of other domains or projects stay separate. A changed
+// description is updated.
+func (s *GPOService) UpsertGPO(ctx context.Context, assertionCtx assertion.Context, domainUID string, incoming *gpo.GPO, actor string) (*res.EntityResult[*gpo.GPO], error) {
+	if incoming.Name == "" {
+		return nil, fmt.Errorf("gpo name is required")
+	}
+	if domainUID == "" {
+		return nil, fmt.Errorf("domain of gpo %s is required", incoming.Name)
+	}
+
+	var result *res.EntityResult[*gpo.GPO]
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		var err error
+		result, err = upsertDomainGPO(ctx, tx, s.gpoRepo, s.assertionRepo, assertionCtx, domainUID, incoming, actor)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("UpsertGPO failed: %w", err)
+	}
+	return result, nil
+}
+
+// upsertDomainGPO is UpsertGPO within a transaction.
+func upsertDomainGPO(
+	ctx context.Context,
+	tx *dgo.Txn,
+	gpoRepo active_directory.GPORepository,
+	assertionRepo engine.AssertionRepository,
+	assertionCtx assertion.Context,
+	domainUID string,
+	incoming *gpo.GPO,
+	actor string,
+) (*res.EntityResult[*gpo.GPO], error) {
+	merged, owner, err := domainGPO(ctx, tx, gpoRepo, assertionRepo, assertionCtx, domainUID, incoming, actor)
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil {
+		return &res.EntityResult[*gpo.GPO]{
+			Entity:     merged,
+			Assertions: []*core.Assertion{owner},
+			Metadata: &res.ResultMetadata{
+				Source:         actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: 1,
+				Outcome:        res.OutcomeCreated,
+			},
+		}, nil
+	}
+
+	if incoming.Description != "" && incoming.Description != merged.Description {
+		uid := merged.UID
+		merged, err = gpoRepo.UpdateGPO(ctx, tx, uid, actor, map[string]interface{}{
+			"gpo.description": incoming.Description,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("updating gpo %s: %w", uid, err)
+		}
+	}
+	return &res.EntityResult[*gpo.GPO]{
+		Entity: merged,
+		Metadata: &res.ResultMetadata{
+			Source:        actor,
+			ScanTimestamp: time.Now(),
+			EntityCount:   1,
+			Outcome:       res.OutcomeMerged,
+		},
+	}, nil
+}
+
+// domainGPO returns the GPO of the domain named like incoming. An unknown GPO
+// is created and owned by the domain (Domain -has_gpo-> GPO); the ownership
+// assertion is returned then, nil for a known GPO.
+func domainGPO(
+	ctx context.Context,
+	tx *dgo.Txn,
+	gpoRepo active_directory.GPORepository,
+	assertionRepo engine.AssertionRepository,
+	assertionCtx assertion.Context,
+	domainUID string,
+	incoming *gpo.GPO,
+	actor string,
+) (*gpo.GPO, *core.Assertion, error) {
+	existing, err := gpoRepo.FindByNameInDomain(ctx, tx, domainUID, incoming.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while checking existing gpo: %w", err)
+	}
+	if existing != nil {
+		return existing, nil, nil
+	}
+
+	created, err := gpoRepo.CreateGPO(ctx, tx, incoming, actor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while creating gpo: %w", err)
+	}
+	owner, _, err := linkOnce(ctx, tx, assertionRepo,
+		&utils2.UIDRef{UID: domainUID, Type: "Domain"},
+		&utils2.UIDRef{UID: created.UID, Type: "GPO"},
+		core.PredicateHasGPO, assertionCtx, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, owner, nil
+}
+
+// GetGPOSettings returns the settings of a GPO.
+func (s *GPOService) GetGPOSettings(ctx context.Context, gpoUID string) ([]*res.EntityResult[*gpo.Setting], error) {
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*gpo.Setting], error) {
+		if _, err := s.gpoRepo.GetGPO(ctx, tx, gpoUID); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrGPONotFound, gpoUID)
+		}
+		return s.gpoRepo.GetSettingsByGPOUID(ctx, tx, gpoUID)
+	})
+}
+
+// AddSetting stores a setting of a GPO: GPO -contains-> GPOSetting. The
+// principal is whom the setting empowers, e.g. the member a restricted group
+// adds to the local administrators or the holder of a user right; it is
+// linked as GPOSetting -granted_to-> principal.
+func (s *GPOService) AddSetting(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	gpoUID string,
+	incoming *gpo.Setting,
+	principal *utils2.UIDRef,
+	actor string,
+) (*res.EntityResult[*gpo.Setting], error) {
+	if incoming.SettingType == "" || incoming.Value == "" {
+		return nil, fmt.Errorf("%w: setting type and value are required", ErrInvalidGPOSetting)
+	}
+	if principal != nil && !settingPrincipalTypes[principal.Type] {
+		return nil, fmt.Errorf("%w: principal type %q", ErrInvalidGPOSetting, principal.Type)
+	}
+
+	var result *res.EntityResult[*gpo.Setting]
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		if _, err := s.gpoRepo.GetGPO(ctx, tx, gpoUID); err != nil {
+			return fmt.Errorf("%w: %s", ErrGPONotFound, gpoUID)
+		}
+
+		setting, err := s.gpoRepo.CreateSetting(ctx, tx, incoming, actor)
+		if err != nil {
+			return fmt.Errorf("creating gpo setting: %w", err)
+		}
+		settingRef := &utils2.UIDRef{UID: setting.UID, Type: "GPOSetting"}
+
+		type link struct {
+			subject, object *utils2.UIDRef
+			predicate       core.Predicate
+		}
+		links := []link{{&utils2.UIDRef{UID: gpoUID, Type: "GPO"}, settingRef, core.PredicateContains}}
+		if principal != nil {
+			links = append(links, link{settingRef, principal, core.PredicateGrantedTo})
+		}
+
+		var assertions []*core.Assertion
+		for _, link := range links {
+			created, err := s.assertionRepo.Create(ctx, tx, &core.Assertion{
+				Predicate:           link.predicate,
+				Method:              core.Method(assertionCtx.GetMethod()),
+				Source:              actor,
+				Confidence:          assertionCtx.GetConfidence(),
+				Status:              core.Status(assertionCtx.GetStatus()),
+				Timestamp:           time.Now(),
+				HasDiscoveredParent: true,
+				MarkedAsHighValue:   assertionCtx.IsHighValue(),
+				Subject:             link.subject,
+				Object:              link.object,
+			})
+			if err != nil {
+				return fmt.Errorf("creating %s assertion: %w", link.predicate, err)
+			}
+			assertions = append(assertions, created)
+		}
+
+		result = &res.EntityResult[*gpo.Setting]{
+			Entity:     setting,
+			Assertions: assertions,
+			Metadata: &res.ResultMetadata{
+				Source:         actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: len(assertions),
+				Outcome:        res.OutcomeCreated,
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AddSetting failed: %w", err)
+	}
+
+	log.Printf("[%s] Added %s setting %s to gpo %s", actor, incoming.SettingType, result.Entity.UID, gpoUID)
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// linkGPO
+// -----------------------------------------------------------------------------
+
+// linkGPO links a GPO to a Domain or DirectoryNode:
+// container -has_gpo_link-> GPOLink -links_to-> GPO. GPOs are known by name
+// within the domain of the container, an unknown one is created there. If the
+// container already links the GPO, that link is reused and its order,
+// enforcement and state are updated, so imports can be replayed.
+func linkGPO(
+	ctx context.Context,
+	tx *dgo.Txn,
+	gpoRepo active_directory.GPORepository,
+	assertionRepo engine.AssertionRepository,
+	assertionCtx assertion.Context,
+	incomingGPOLink *gpo.Link,
+	incomingGPO *gpo.GPO,
+	container *utils2.UIDRef,
+	domainUID string,
+	actor string,
+) (*res.GPOResult[*gpo.Link], error) {
+	linkedGPO, _, err := domainGPO(ctx, tx, gpoRepo, assertionRepo, assertionCtx, domainUID, incomingGPO, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	existingLink, err := findGPOLink(ctx, tx, assertionRepo, container.UID, linkedGPO.UID)
+	if err != nil {
+		return nil, err
+	}
+	if existingLink != "" {
+		link, err := gpoRepo.GetLink(ctx, tx, existingLink)
+		if err != nil {
+			return nil, fmt.Errorf("loading gpo link %s: %w", existingLink, err)
+		}
+		fields := map[string]interface{}{}
+		if link.LinkOrder != incomingGPOLink.LinkOrder {
+			fields["gpo_link.link_order"] = incomingGPOLink.LinkOrder
+		}
+		if link.IsEnforced != incomingGPOLink.IsEnforced {
+			fields["gpo_link.is_enforced"] = incomingGPOLink.IsEnforced
+		}
+		if link.IsEnabled != incomingGPOLink.IsEnabled {
+			fields["gpo_link.is_enabled"] = incomingGPOLink.IsEnabled
+		}
+		if len(fields) > 0 {
+			if link, err = gpoRepo.UpdateLink(ctx, tx, existingLink, actor, fields); err != nil {
+				return nil, fmt.Errorf("updating gpo link %s: %w", existingLink, err)
+			}
+		}
+		return &res.GPOResult[*gpo.Link]{
+			GPOLink: link,
+			GPO:     linkedGPO,
+			Metadata: &res.ResultMetadata{
+				Source:        actor,
+				ScanTimestamp: time.Now(),
+				EntityCount:   2,
+				Outcome:       res.OutcomeMerged,
+			},
+		}, nil
+	}
+
+	gpoLink, err := gpoRepo.CreateLink(ctx, tx, incomingGPOLink, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating gpo link: %w", err)
+	}
+	linkRef := &utils2.UIDRef{UID: gpoLink.UID, Type: "GPOLink"}
+
+	var assertions [2]*core.Assertion
+	for i, link := range []struct {
+		subject, object *utils2.UIDRef
+		predicate       core.Predicate
+	}{
+		{container, linkRef, core.PredicateHasGPOLink},
+		{linkRef, &utils2.UIDRef{UID: linkedGPO.UID, Type: "GPO"}, core.PredicateLinksTo},
+	} {
+		assertions[i], err = assertionRepo.Create(ctx, tx, &core.Assertion{
+			Predicate:           link.predicate,
+			Method:              core.Method(assertionCtx.GetMethod()),
+			Source:              actor,
+			Confidence:          assertionCtx.GetConfidence(),
+			Status:              core.StatusValidated,
+			Timestamp:           time.Now(),
+			HasDiscoveredParent: true,
+			MarkedAsHighValue:   assertionCtx.IsHighValue(),
+			Subject:             link.subject,
+			Object:              link.object,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating %s assertion: %w", link.predicate, err)
+		}
+	}
+
+	return &res.GPOResult[*gpo.Link]{
+		GPOLink:           gpoLink,
+		GPOLinkAssertions: []*core.Assertion{assertions[0]},
+		GPO:               linkedGPO,
+		GPOAssertions:     []*core.Assertion{assertions[1]},
+		Metadata: &res.ResultMetadata{
+			Source:         actor,
+			ScanTimestamp:  time.Now(),
+			EntityCount:    2,
+			AssertionCount: len(assertions),
+			Outcome:        res.OutcomeCreated,
+		},
+	}, nil
+}
+
+// findGPOLink returns the uid of the active GPOLink through which the
+// container links the GPO, "" if there is none.
+func findGPOLink(ctx context.Context, tx *dgo.Txn, assertionRepo engine.AssertionRepository, containerUID, gpoUID string) (string, error) {
+	links, err := assertionRepo.GetAssertionsByPredicate(ctx, tx, containerUID, core.PredicateHasGPOLink)
+	if err != nil {
+		return "", fmt.Errorf("loading gpo links of %s: %w", containerUID, err)
+	}
+	for _, a := range links {
+		if a.Object == nil || a.Status == core.StatusInvalidated || a.Status == core.StatusExpired {
+			continue
+		}
+		target, err := assertionRepo.FindLink(ctx, tx, a.Object.UID, gpoUID, core.PredicateLinksTo)
+		if err != nil {
+			return "", fmt.Errorf("checking gpo link %s: %w", a.Object.UID, err)
+		}
+		if target != nil && target.Status != core.StatusInvalidated && target.Status != core.StatusExpired {
+			return a.Object.UID, nil
+		}
+	}
+	return "", nil
+}
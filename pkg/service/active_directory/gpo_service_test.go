@@ -0,0 +1,101 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/repository/active_directory"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// fakeGPOGraph keeps GPOs and has_gpo assertions in memory. It serves as
+// GPORepository and AssertionRepository for the calls of upsertDomainGPO.
+type fakeGPOGraph struct {
+	active_directory.GPORepository
+	engine.AssertionRepository
+	gpos  map[string]*gpo.GPO
+	owner map[string]string // gpo uid → domain uid
+}
+
+func newFakeGPOGraph() *fakeGPOGraph {
+	return &fakeGPOGraph{gpos: make(map[string]*gpo.GPO), owner: make(map[string]string)}
+}
+
+func (f *fakeGPOGraph) FindByNameInDomain(ctx context.Context, tx *dgo.Txn, domainUID, gpoName string) (*gpo.GPO, error) {
+	for uid, g := range f.gpos {
+		if f.owner[uid] == domainUID && g.Name == gpoName {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeGPOGraph) CreateGPO(ctx context.Context, tx *dgo.Txn, g *gpo.GPO, actor string) (*gpo.GPO, error) {
+	created := *g
+	created.UID = fmt.Sprintf("0x%d", 100+len(f.gpos))
+	f.gpos[created.UID] = &created
+	return &created, nil
+}
+
+func (f *fakeGPOGraph) UpdateGPO(ctx context.Context, tx *dgo.Txn, uid, actor string, fields map[string]interface{}) (*gpo.GPO, error) {
+	f.gpos[uid].Description = fields["gpo.description"].(string)
+	return f.gpos[uid], nil
+}
+
+func (f *fakeGPOGraph) FindLink(ctx context.Context, tx *dgo.Txn, subjectUID, objectUID string, predicate core.Predicate) (*core.Assertion, error) {
+	if predicate == core.PredicateHasGPO && f.owner[objectUID] == subjectUID {
+		return &core.Assertion{Predicate: predicate}, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeGPOGraph) Create(ctx context.Context, tx *dgo.Txn, a *core.Assertion) (*core.Assertion, error) {
+	if a.Predicate == core.PredicateHasGPO {
+		f.owner[a.Object.UID] = a.Subject.UID
+	}
+	return a, nil
+}
+
+func TestUpsertDomainGPOSeparatesDomains(t *testing.T) {
+	graph := newFakeGPOGraph()
+	upsert := func(domainUID, description string) *res.EntityResult[*gpo.GPO] {
+		t.Helper()
+		result, err := upsertDomainGPO(context.Background(), nil, graph, graph, assertion.Context{}, domainUID,
+			&gpo.GPO{Name: "Default Domain Policy", Description: description}, "test")
+		if err != nil {
+			t.Fatalf("upsert in %s: %v", domainUID, err)
+		}
+		return result
+	}
+
+	// 0xa liegt in Projekt A, 0xb in Projekt B
+	a := upsert("0xa", "project a")
+	b := upsert("0xb", "project b")
+
+	if a.Metadata.Outcome != res.OutcomeCreated || b.Metadata.Outcome != res.OutcomeCreated {
+		t.Fatalf("outcomes = %s, %s, want both created", a.Metadata.Outcome, b.Metadata.Outcome)
+	}
+	if a.Entity.UID == b.Entity.UID {
+		t.Fatalf("both domains share gpo %s", a.Entity.UID)
+	}
+	if got := graph.gpos[a.Entity.UID].Description; got != "project a" {
+		t.Errorf("description of project a = %q, want %q", got, "project a")
+	}
+	if graph.owner[a.Entity.UID] != "0xa" || graph.owner[b.Entity.UID] != "0xb" {
+		t.Errorf("owners = %v", graph.owner)
+	}
+
+	again := upsert("0xa", "updated")
+	if again.Metadata.Outcome != res.OutcomeMerged || again.Entity.UID != a.Entity.UID {
+		t.Errorf("replay in 0xa = %s %s, want merged into %s", again.Metadata.Outcome, again.Entity.UID, a.Entity.UID)
+	}
+	if got := graph.gpos[b.Entity.UID].Description; got != "project b" {
+		t.Errorf("description of project b = %q, want %q", got, "project b")
+	}
+}
@@ -0,0 +1,317 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/active_directory"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/repository/util/dgraph"
+	active_directory2 "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths/history"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	engine3 "RedPaths-server/pkg/service/catalog"
+	engine5 "RedPaths-server/pkg/service/change"
+	engine4 "RedPaths-server/pkg/service/upsert"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+type GroupService struct {
+	groupRepo      active_directory.GroupRepository
+	assertionRepo  engine2.AssertionRepository
+	catalogService *engine3.CatalogService
+	db             *dgo.Dgraph
+}
+
+func NewGroupService(dgraphCon *dgo.Dgraph) (*GroupService, error) {
+	return &GroupService{
+		db:             dgraphCon,
+		groupRepo:      active_directory.NewDgraphGroupRepository(dgraphCon),
+		assertionRepo:  engine2.NewDgraphAssertionRepository(dgraphCon),
+		catalogService: engine3.NewCatalogService(dgraphCon),
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// UpsertGroup
+// -----------------------------------------------------------------------------
+
+func (s *GroupService) UpsertGroup(
+	ctx context.Context,
+	input engine4.Input[*active_directory2.Group],
+) (*res.EntityResult[*active_directory2.Group], error) {
+
+	subjectUID, subjectType, hasParent := input.Resolved()
+
+	var result *res.EntityResult[*active_directory2.Group]
+	var outcome res.Outcome
+	var changes []history.FieldChange // field-level diff of a merge, nil otherwise
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		// --- Existence Check ---
+		existence, err := s.groupRepo.FindExisting(ctx, tx, input.ProjectUID, input.Entity)
+		if err != nil {
+			return fmt.Errorf("existence check failed: %w", err)
+		}
+
+		filters := active_directory.BuildGroupFilter(input.Entity)
+		var actualGroup *active_directory2.Group
+
+		switch existence.FoundVia {
+
+		// --- Case 1: Not found → create new ---
+		case dgraph.ExistenceSourceNotFound:
+			createdGroup, err := s.groupRepo.Create(ctx, tx, input.Entity, input.Actor)
+			if err != nil {
+				return fmt.Errorf("creating group: %w", err)
+			}
+			actualGroup = createdGroup
+			outcome = res.OutcomeCreated
+			log.Printf("[UpsertGroup] Created uid=%s name=%s", actualGroup.UID, actualGroup.Name)
+
+		case dgraph.ExistenceSourceHierarchy,
+			dgraph.ExistenceSourceProject:
+
+			best := dgraph.BestCandidate(existence.Entities, filters, 0.5)
+
+			if best == nil {
+				// Candidates found but score too low → create new
+				createdGroup, err := s.groupRepo.Create(ctx, tx, input.Entity, input.Actor)
+				if err != nil {
+					return fmt.Errorf("creating group (low score): %w", err)
+				}
+				actualGroup = createdGroup
+				outcome = res.OutcomeCreated
+				log.Printf("[UpsertGroup] Low score, created uid=%s", actualGroup.UID)
+
+			} else if best.Score >= 0.8 {
+				// --- Case 2: High Confidence → Merge ---
+				mergeFields := buildGroupMergeFields(
+					best.Result.Entity,
+					input.Entity,
+					input.AssertionCtx.GetConfidence(),
+				)
+				changes = engine5.DiffMergeFields(best.Result.Entity, mergeFields)
+				updated, err := s.groupRepo.UpdateGroup(
+					ctx, tx,
+					best.Result.Entity.UID,
+					input.Actor,
+					mergeFields,
+				)
+				if err != nil {
+					return fmt.Errorf("merging group: %w", err)
+				}
+				actualGroup = updated
+				outcome = res.OutcomeMerged
+				log.Printf("[UpsertGroup] Merged uid=%s score=%.2f",
+					actualGroup.UID, best.Score)
+
+			} else {
+				// --- Case 3: Medium Confidence (0.5–0.8) → Possible Duplicate ---
+				log.Printf("[UpsertGroup] Possible duplicate uid=%s score=%.2f",
+					best.Result.Entity.UID, best.Score)
+
+				duplicateAssertion := &core.Assertion{
+					Predicate:  core.PredicatePossibleDuplicate,
+					Method:     core.MethodInferred,
+					Source:     input.Actor,
+					Confidence: best.Score,
+					Status:     core.StatusTentative,
+					Timestamp:  time.Now(),
+					Note: fmt.Sprintf(
+						"Possible duplicate detected with score %.2f — manual review required",
+						best.Score,
+					),
+					HasDiscoveredParent: false,
+					MarkedAsHighValue:   false,
+					Subject:             &utils2.UIDRef{UID: best.Result.Entity.UID, Type: "Group"},
+					Object:              &utils2.UIDRef{UID: input.Entity.UID, Type: "Group"},
+				}
+
+				if _, err := s.assertionRepo.Create(ctx, tx, duplicateAssertion); err != nil {
+					return fmt.Errorf("creating duplicate assertion: %w", err)
+				}
+
+				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
+				return nil
+			}
+
+		default:
+			return fmt.Errorf("unhandled existence state: %s", existence.FoundVia)
+		}
+
+		// --- Create assertion (for both Create and Merge) ---
+		assertionSchema := &core.Assertion{
+			Predicate:           core.PredicateContains,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
+			Source:              input.Actor,
+			Confidence:          input.AssertionCtx.GetConfidence(),
+			Status:              core.StatusValidated,
+			Timestamp:           time.Now(),
+			HasDiscoveredParent: hasParent,
+			MarkedAsHighValue:   input.AssertionCtx.IsHighValue(),
+			Subject:             &utils2.UIDRef{UID: subjectUID, Type: subjectType},
+			Object:              &utils2.UIDRef{UID: actualGroup.UID, Type: "Group"},
+		}
+
+		createdAssertion, err := s.assertionRepo.Create(ctx, tx, assertionSchema)
+		if err != nil {
+			return fmt.Errorf("creating assertion: %w", err)
+		}
+
+		result = &res.EntityResult[*active_directory2.Group]{
+			Entity:     actualGroup,
+			Assertions: []*core.Assertion{createdAssertion},
+			Metadata: &res.ResultMetadata{
+				Source:         input.Actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: 1,
+				Outcome:        outcome,
+				Changes:        changes,
+			},
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("UpsertGroup failed: %w", err)
+	}
+
+	// --- Catalog Integration (outside the transaction) ---
+	if result == nil || len(result.Assertions) == 0 {
+		return result, nil
+	}
+
+	_, catalogErr := engine3.AddToCatalog(
+		ctx,
+		s.catalogService,
+		input.ProjectUID,
+		result.Entity.UID,
+		"Group",
+		result.Assertions[0],
+		input.Actor,
+	)
+	if catalogErr != nil {
+		log.Printf("[UpsertGroup] Warning: failed to add group %s to catalog: %v",
+			result.Entity.UID, catalogErr)
+	}
+
+	if hasParent {
+		promoteErr := engine3.PromoteInCatalog(
+			ctx,
+			s.catalogService,
+			input.ProjectUID,
+			result.Entity.UID,
+			"Group",
+			core.PredicateContains,
+			input.Actor,
+		)
+		if promoteErr != nil {
+			log.Printf("[UpsertGroup] Warning: failed to promote group %s in catalog: %v",
+				result.Entity.UID, promoteErr)
+		}
+	}
+
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// buildGroupMergeFields
+// -----------------------------------------------------------------------------
+
+func buildGroupMergeFields(
+	existing *active_directory2.Group,
+	incoming *active_directory2.Group,
+	incomingConfidence float64,
+) map[string]interface{} {
+	fields := map[string]interface{}{
+		"last_seen_at": time.Now(),
+	}
+
+	// Name: only overwrite if existing is empty
+	if incoming.Name != "" && existing.Name == "" {
+		fields["security_principal.name"] = incoming.Name
+	}
+
+	// SID: truly unique, overwrite only if existing is empty
+	if incoming.SID != "" && existing.SID == "" {
+		fields["security_principal.sid"] = incoming.SID
+	}
+
+	if incoming.Description != "" && existing.Description == "" {
+		fields["security_principal.description"] = incoming.Description
+	}
+
+	// Privilege flags: once set, they should not be revoked by lower-confidence data
+	if incoming.IsPrivileged && !existing.IsPrivileged {
+		fields["group.is_privileged"] = true
+	}
+	if incoming.IsBuiltIn && !existing.IsBuiltIn {
+		fields["group.is_builtin"] = true
+	}
+
+	if incomingConfidence >= 0.8 {
+		if incoming.GroupScope != "" {
+			fields["group.group_scope"] = incoming.GroupScope
+		}
+		if incoming.GroupType != "" {
+			fields["group.group_type"] = incoming.GroupType
+		}
+	}
+
+	// Risk is set by assessment imports; the latest assessment replaces the previous one
+	if incoming.RiskScore > 0 || len(incoming.RiskReasons) > 0 {
+		fields["group.risk_score"] = incoming.RiskScore
+		fields["group.risk_reasons"] = incoming.RiskReasons
+	}
+
+	return fields
+}
+
+// -----------------------------------------------------------------------------
+// AddMember
+// -----------------------------------------------------------------------------
+
+// AddMember links a member (User, Group or Host) to a group via a has_member
+// assertion. Returns false if the membership was already known.
+func (s *GroupService) AddMember(
+	ctx context.Context,
+	groupUID string,
+	memberUID string,
+	memberType string,
+	assertionCtx assertion.Context,
+	actor string,
+) (bool, error) {
+	var created bool
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		var err error
+		_, created, err = linkOnce(ctx, tx, s.assertionRepo,
+			&utils2.UIDRef{UID: groupUID, Type: "Group"},
+			&utils2.UIDRef{UID: memberUID, Type: memberType},
+			core.PredicateHasMember,
+			assertionCtx,
+			actor,
+		)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("AddMember failed: %w", err)
+	}
+
+	return created, nil
+}
@@ -38,6 +38,7 @@ type HostService struct {
 	domainRepo     active_directory.DomainRepository
 	assertionRepo  engine2.AssertionRepository
 	capabilityRepo engine2.CapabilityRepository
+	vulnRepo       active_directory.VulnerabilityRepository
 	catalogService *engine3.CatalogService
 
 	changeRepo changes.RedPathsChangeRepository
@@ -56,6 +57,7 @@ func NewHostService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*HostService,
 		domainRepo:     active_directory.NewDgraphDomainRepository(dgraphCon),
 		assertionRepo:  assertionRepo,
 		capabilityRepo: engine2.NewDgraphCapabilityRepository(dgraphCon),
+		vulnRepo:       active_directory.NewDgraphVulnerabilityRepository(dgraphCon),
 		changeRepo:     changes.NewPostgresRedPathsChangesRepository(postgresCon),
 		catalogService: catalogService,
 		db:             dgraphCon,
@@ -76,6 +78,19 @@ func (s *HostService) GetCapabilities(
 	})
 }
 
+// -----------------------------------------------------------------------------
+// GetVulnerabilities
+// -----------------------------------------------------------------------------
+
+func (s *HostService) GetVulnerabilities(
+	ctx context.Context,
+	hostUID string,
+) ([]*res.EntityResult[*model.Vulnerability], error) {
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*model.Vulnerability], error) {
+		return s.vulnRepo.GetByHostUID(ctx, tx, hostUID)
+	})
+}
+
 // -----------------------------------------------------------------------------
 // UpsertHost
 // -----------------------------------------------------------------------------
@@ -105,6 +120,7 @@ func (s *HostService) UpsertHost(
 	subjectUID, subjectType, hasParent := input.Resolved()
 
 	var result *res.EntityResult[*model.Host]
+	var outcome res.Outcome
 	var pendingChange *history.Change // saved outside Dgraph-Tx, best-effort
 	// Track which existence branch was taken so post-transaction cleanup can
 	// decide whether to search for stale orphaned duplicates.
@@ -129,6 +145,7 @@ func (s *HostService) UpsertHost(
 				return fmt.Errorf("creating host: %w", err)
 			}
 			actualHost = createdHost
+			outcome = res.OutcomeCreated
 			log.Printf("[UpsertHost] Created uid=%s ip=%s", actualHost.UID, actualHost.IP)
 
 			pendingChange = engine5.BuildCreatedChange(actualHost, input.Actor)
@@ -146,6 +163,7 @@ func (s *HostService) UpsertHost(
 					return fmt.Errorf("creating host (low score): %w", err)
 				}
 				actualHost = createdHost
+				outcome = res.OutcomeCreated
 				log.Printf("[UpsertHost] Low score, created uid=%s", actualHost.UID)
 
 				pendingChange = engine5.BuildCreatedChange(actualHost, input.Actor)
@@ -167,6 +185,7 @@ func (s *HostService) UpsertHost(
 					return fmt.Errorf("merging host: %w", err)
 				}
 				actualHost = updated
+				outcome = res.OutcomeMerged
 				log.Printf("[UpsertHost] Merged uid=%s score=%.2f",
 					actualHost.UID, best.Score)
 
@@ -227,6 +246,12 @@ func (s *HostService) UpsertHost(
 				}
 
 				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
 				return nil
 			}
 
@@ -237,7 +262,7 @@ func (s *HostService) UpsertHost(
 		// Record the assertion that links this host to its parent (domain, project, etc.)
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateHasHost,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
 			Source:              input.Actor,
 			Confidence:          input.AssertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -261,6 +286,7 @@ func (s *HostService) UpsertHost(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        outcome,
 			},
 		}
 
@@ -273,7 +299,7 @@ func (s *HostService) UpsertHost(
 
 	// --- Change History (outside Dgraph-Tx, best-effort) ---
 	if pendingChange != nil {
-		s.saveChangeAsync(ctx, pendingChange)
+		db.AfterCommit(ctx, func() { s.saveChangeAsync(ctx, pendingChange) })
 	}
 
 	// --- Catalog Integration ---
@@ -316,6 +342,10 @@ func (s *HostService) UpsertHost(
 }
 
 func (s *HostService) saveChangeAsync(ctx context.Context, change *history.Change) {
+	// the goroutine outlives the request context, keep the run the change belongs to
+	if change.RunID == "" {
+		change.RunID = assertion.RunID(ctx)
+	}
 	go func() {
 		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -460,7 +490,7 @@ func (s *HostService) AddService(
 
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateRuns,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(assertionCtx.GetMethod()),
 			Source:              actor,
 			Confidence:          assertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -508,6 +538,103 @@ func (s *HostService) AddService(
 	return result, nil
 }
 
+// EnsureService returns the service already running on the given port of the
+// host, or adds it. Used by imports that reference services by port only.
+func (s *HostService) EnsureService(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID string,
+	hostUID string,
+	incomingService *model.Service,
+	actor string,
+) (*res.EntityResult[model.Service], error) {
+	services, err := s.GetAllServicesByHost(ctx, hostUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading services of host %s: %w", hostUID, err)
+	}
+
+	for _, service := range services {
+		if service.Entity == nil || service.Entity.Port != incomingService.Port {
+			continue
+		}
+		return &res.EntityResult[model.Service]{
+			Entity:     *service.Entity,
+			Assertions: service.Assertions,
+			Metadata: &res.ResultMetadata{
+				Source:        actor,
+				ScanTimestamp: time.Now(),
+				EntityCount:   1,
+				Outcome:       res.OutcomeMerged,
+			},
+		}, nil
+	}
+
+	return s.AddService(ctx, assertionCtx, projectUID, hostUID, incomingService, actor)
+}
+
+// -----------------------------------------------------------------------------
+// AddSession / AddAdmin
+// -----------------------------------------------------------------------------
+
+// AddSession records that a user has (or had) a logon session on the host.
+// Returns false if the session was already known.
+func (s *HostService) AddSession(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	hostUID string,
+	userUID string,
+	actor string,
+) (bool, error) {
+	var created bool
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		var err error
+		_, created, err = linkOnce(ctx, tx, s.assertionRepo,
+			&utils2.UIDRef{UID: hostUID, Type: "Host"},
+			&utils2.UIDRef{UID: userUID, Type: "User"},
+			core.PredicateHasSession,
+			assertionCtx,
+			actor,
+		)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("AddSession failed: %w", err)
+	}
+
+	return created, nil
+}
+
+// AddAdmin records that a principal (User or Group) holds local admin rights
+// on the host. Returns false if the right was already known.
+func (s *HostService) AddAdmin(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	hostUID string,
+	principalUID string,
+	principalType string,
+	actor string,
+) (bool, error) {
+	var created bool
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		var err error
+		_, created, err = linkOnce(ctx, tx, s.assertionRepo,
+			&utils2.UIDRef{UID: principalUID, Type: principalType},
+			&utils2.UIDRef{UID: hostUID, Type: "Host"},
+			core.PredicateAdminTo,
+			assertionCtx,
+			actor,
+		)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("AddAdmin failed: %w", err)
+	}
+
+	return created, nil
+}
+
 // -----------------------------------------------------------------------------
 // GetAllServicesByHost / GetServiceByHost
 // -----------------------------------------------------------------------------
@@ -0,0 +1,417 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/active_directory"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+var (
+	ErrMembershipProjectMissing = errors.New("project not found")
+	ErrPrincipalNotFound        = errors.New("principal not found in project")
+)
+
+// Well-known RIDs. Domain groups carry the domain SID (S-1-5-21-...), builtin
+// groups S-1-5-32 (BloodHound prefixes those with the domain name).
+var (
+	privilegedDomainRIDs  = []string{"512", "516", "518", "519"}
+	privilegedBuiltinRIDs = []string{"544", "548", "549", "550", "551"}
+	domainAdminRIDs       = []string{"512", "519"}
+	dcsyncDomainRIDs      = []string{"512", "516", "519"}
+	dcsyncBuiltinRIDs     = []string{"544"}
+	rdpBuiltinRIDs        = []string{"544", "555"}
+)
+
+// dcsyncCapability is the name of the capability the ACL derivation creates
+// for principals holding both replication rights.
+const dcsyncCapability = "DCSync"
+
+// MemberRef is a principal in a membership listing.
+type MemberRef struct {
+	UID  string `json:"uid"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	SID  string `json:"sid,omitempty"`
+	// Foreign is set when the principal belongs to another domain than the
+	// entity the listing was requested for (foreign security principal).
+	Foreign bool `json:"foreign"`
+}
+
+// EffectiveMembership is one group of a principal, or one member of a group.
+// Depth 1 is a direct membership; Via lists the groups in between, starting
+// next to the requested entity.
+type EffectiveMembership struct {
+	Principal *MemberRef `json:"principal"`
+	Depth     int        `json:"depth"`
+	Via       []string   `json:"via,omitempty"`
+}
+
+// EffectivePrivileges are derived from the effective groups of a principal.
+type EffectivePrivileges struct {
+	IsPrivileged  bool     `json:"is_privileged"`
+	IsDomainAdmin bool     `json:"is_domain_admin"`
+	CanDCSync     bool     `json:"can_dcsync"`
+	CanRDP        bool     `json:"can_rdp"`
+	Reasons       []string `json:"reasons"`
+}
+
+// PrincipalMemberships answers "what is this principal effectively a member of".
+type PrincipalMemberships struct {
+	Principal  *MemberRef             `json:"principal"`
+	Groups     []*EffectiveMembership `json:"groups"`
+	Privileges *EffectivePrivileges   `json:"privileges"`
+}
+
+// GroupMembers answers "who is effectively in this group".
+type GroupMembers struct {
+	Group   *MemberRef             `json:"group"`
+	Members []*EffectiveMembership `json:"members"`
+}
+
+// PrivilegeSummary reports a propagation run over the users of a project.
+type PrivilegeSummary struct {
+	ProjectUID string   `json:"project_uid"`
+	Users      int      `json:"users"`
+	Updated    []string `json:"updated"`
+	Privileged int      `json:"privileged"`
+	Failed     int      `json:"failed"`
+}
+
+// -----------------------------------------------------------------------------
+// MembershipService
+// -----------------------------------------------------------------------------
+
+// MembershipService resolves nested group memberships over the has_member
+// assertions of a project and propagates the resulting privileges onto users.
+type MembershipService struct {
+	graphRepo engine2.ProjectGraphRepository
+	userRepo  active_directory.UserRepository
+	db        *dgo.Dgraph
+}
+
+func NewMembershipService(dgraphCon *dgo.Dgraph) (*MembershipService, error) {
+	return &MembershipService{
+		db:        dgraphCon,
+		graphRepo: engine2.NewDgraphProjectGraphRepository(dgraphCon),
+		userRepo:  active_directory.NewDgraphUserRepository(dgraphCon),
+	}, nil
+}
+
+// membershipGraph indexes the has_member edges of a project graph in both
+// directions.
+type membershipGraph struct {
+	graph        *engine2.ProjectGraph
+	memberOf     map[string][]string
+	members      map[string][]string
+	capabilities map[string][]string
+}
+
+func newMembershipGraph(graph *engine2.ProjectGraph) *membershipGraph {
+	g := &membershipGraph{
+		graph:        graph,
+		memberOf:     make(map[string][]string),
+		members:      make(map[string][]string),
+		capabilities: make(map[string][]string),
+	}
+	for _, e := range graph.Edges {
+		switch e.Predicate {
+		case string(core.PredicateHasMember):
+			if n := graph.Nodes[e.Subject]; n == nil || n.Type != "Group" {
+				continue
+			}
+			g.members[e.Subject] = append(g.members[e.Subject], e.Object)
+			g.memberOf[e.Object] = append(g.memberOf[e.Object], e.Subject)
+		case string(core.PredicateHasCapability):
+			g.capabilities[e.Subject] = append(g.capabilities[e.Subject], e.Object)
+		}
+	}
+	return g
+}
+
+func (s *MembershipService) loadGraph(ctx context.Context, projectUID string) (*membershipGraph, error) {
+	graph, err := db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) (*engine2.ProjectGraph, error) {
+		return s.graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if project := graph.Nodes[projectUID]; project == nil || project.Type != "Project" {
+		return nil, ErrMembershipProjectMissing
+	}
+	return newMembershipGraph(graph), nil
+}
+
+// expand walks breadth first from start along next. Every principal is
+// visited once, so membership cycles terminate; the shortest chain wins.
+func (g *membershipGraph) expand(start string, next map[string][]string, recursive bool) []*EffectiveMembership {
+	via := map[string][]string{start: nil}
+	frontier := []string{start}
+	var result []*EffectiveMembership
+
+	for depth := 1; len(frontier) > 0; depth++ {
+		var following []string
+		for _, uid := range frontier {
+			for _, nextUID := range next[uid] {
+				if _, seen := via[nextUID]; seen {
+					continue
+				}
+				chain := via[uid]
+				if uid != start {
+					chain = append(append([]string{}, chain...), uid)
+				}
+				via[nextUID] = chain
+
+				result = append(result, &EffectiveMembership{
+					Principal: g.ref(nextUID, start),
+					Depth:     depth,
+					Via:       chain,
+				})
+				following = append(following, nextUID)
+			}
+		}
+		if !recursive {
+			break
+		}
+		frontier = following
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Depth != result[j].Depth {
+			return result[i].Depth < result[j].Depth
+		}
+		return strings.ToLower(result[i].Principal.Name) < strings.ToLower(result[j].Principal.Name)
+	})
+	return result
+}
+
+func (g *membershipGraph) ref(uid, relativeTo string) *MemberRef {
+	n := g.graph.Nodes[uid]
+	if n == nil {
+		return &MemberRef{UID: uid}
+	}
+	sid := nodeString(n, "security_principal.sid")
+	ref := &MemberRef{UID: uid, Type: n.Type, Name: principalName(n), SID: sid}
+	if other := g.graph.Nodes[relativeTo]; other != nil {
+		a, b := domainSID(sid), domainSID(nodeString(other, "security_principal.sid"))
+		ref.Foreign = a != "" && b != "" && a != b
+	}
+	return ref
+}
+
+// privileges folds the flags and well-known RIDs of the effective groups. A
+// DCSync capability held by the principal or one of its groups counts as well.
+func (g *membershipGraph) privileges(uid string, groups []*EffectiveMembership) *EffectivePrivileges {
+	p := &EffectivePrivileges{Reasons: []string{}}
+	add := func(flag *bool, reason string) {
+		*flag = true
+		p.Reasons = append(p.Reasons, reason)
+	}
+
+	holders := []string{uid}
+	for _, m := range groups {
+		n := g.graph.Nodes[m.Principal.UID]
+		if n == nil || n.Type != "Group" {
+			continue
+		}
+		holders = append(holders, n.UID)
+		name, sid := m.Principal.Name, m.Principal.SID
+
+		if nodeBool(n, "group.is_privileged") || hasDomainRID(sid, privilegedDomainRIDs) || hasBuiltinRID(sid, privilegedBuiltinRIDs) {
+			add(&p.IsPrivileged, "member of privileged group "+name)
+		}
+		if hasDomainRID(sid, domainAdminRIDs) {
+			add(&p.IsDomainAdmin, "member of "+name)
+		}
+		if nodeBool(n, "group.can_dcsync") || hasDomainRID(sid, dcsyncDomainRIDs) || hasBuiltinRID(sid, dcsyncBuiltinRIDs) {
+			add(&p.CanDCSync, "DCSync via "+name)
+		}
+		if nodeBool(n, "group.can_rdp") || hasBuiltinRID(sid, rdpBuiltinRIDs) {
+			add(&p.CanRDP, "RDP via "+name)
+		}
+	}
+
+	for _, holder := range holders {
+		for _, capUID := range g.capabilities[holder] {
+			if c := g.graph.Nodes[capUID]; c != nil && nodeString(c, "capability.name") == dcsyncCapability {
+				add(&p.CanDCSync, "DCSync capability of "+principalName(g.graph.Nodes[holder]))
+			}
+		}
+	}
+	return p
+}
+
+// PrincipalMemberships returns the groups a user, group or host is a member
+// of, transitively unless recursive is false.
+func (s *MembershipService) PrincipalMemberships(ctx context.Context, projectUID, principalUID string, recursive bool) (*PrincipalMemberships, error) {
+	g, err := s.loadGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	if g.graph.Nodes[principalUID] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPrincipalNotFound, principalUID)
+	}
+
+	groups := g.expand(principalUID, g.memberOf, recursive)
+	return &PrincipalMemberships{
+		Principal:  g.ref(principalUID, ""),
+		Groups:     groups,
+		Privileges: g.privileges(principalUID, groups),
+	}, nil
+}
+
+// GroupMembers returns the members of a group, transitively unless recursive
+// is false. Nested groups are listed along with their members.
+func (s *MembershipService) GroupMembers(ctx context.Context, projectUID, groupUID string, recursive bool) (*GroupMembers, error) {
+	g, err := s.loadGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	if n := g.graph.Nodes[groupUID]; n == nil || n.Type != "Group" {
+		return nil, fmt.Errorf("%w: group %s", ErrPrincipalNotFound, groupUID)
+	}
+
+	return &GroupMembers{
+		Group:   g.ref(groupUID, ""),
+		Members: g.expand(groupUID, g.members, recursive),
+	}, nil
+}
+
+// PropagatePrivileges writes the effective privileges onto every user of the
+// project. Only users whose flags change are updated.
+func (s *MembershipService) PropagatePrivileges(ctx context.Context, projectUID, actor string) (*PrivilegeSummary, error) {
+	g, err := s.loadGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PrivilegeSummary{ProjectUID: projectUID, Updated: []string{}}
+	users := g.graph.NodesOfType("User")
+	sort.Slice(users, func(i, j int) bool { return users[i].UID < users[j].UID })
+
+	for _, user := range users {
+		summary.Users++
+		p := g.privileges(user.UID, g.expand(user.UID, g.memberOf, true))
+		if p.IsPrivileged {
+			summary.Privileged++
+		}
+
+		fields := map[string]interface{}{}
+		for key, value := range map[string]bool{
+			"user.is_privileged": p.IsPrivileged,
+			"user.can_dcsync":    p.CanDCSync,
+			"user.can_rdp":       p.CanRDP,
+		} {
+			if nodeBool(user, key) != value {
+				fields[key] = value
+			}
+		}
+		// is_domain_admin may also be set by hand, it is only ever raised
+		if p.IsDomainAdmin && !nodeBool(user, "user.is_domain_admin") {
+			fields["user.is_domain_admin"] = true
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+			_, err := s.userRepo.UpdateUser(ctx, tx, user.UID, actor, fields)
+			return err
+		})
+		if err != nil {
+			log.Printf("[%s] Warning: propagating privileges to user %s failed: %v", actor, user.UID, err)
+			summary.Failed++
+			continue
+		}
+		summary.Updated = append(summary.Updated, user.UID)
+	}
+
+	log.Printf("[%s] Privilege propagation project=%s users=%d updated=%d privileged=%d failed=%d",
+		actor, projectUID, summary.Users, len(summary.Updated), summary.Privileged, summary.Failed)
+
+	return summary, nil
+}
+
+// PrincipalsBySID indexes the users, groups and computers of the project by
+// SID. Imports use it to resolve members from other domains (foreign security
+// principals) that are not part of the imported collection.
+func (s *MembershipService) PrincipalsBySID(ctx context.Context, projectUID string) (map[string]*utils2.UIDRef, error) {
+	g, err := s.loadGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	principals := make(map[string]*utils2.UIDRef)
+	for _, n := range g.graph.Nodes {
+		sid := strings.ToUpper(nodeString(n, "security_principal.sid"))
+		if sid == "" {
+			continue
+		}
+		principals[sid] = &utils2.UIDRef{UID: n.UID, Type: n.Type}
+	}
+	return principals, nil
+}
+
+// ── Helpers ─────────────────────────────────────────────────────────────────
+
+func principalName(n *engine2.GraphNode) string {
+	if n == nil {
+		return ""
+	}
+	for _, key := range []string{"security_principal.name", "user.sam_account_name", "host.dns_host_name", "host.name"} {
+		if v := nodeString(n, key); v != "" {
+			return v
+		}
+	}
+	return n.UID
+}
+
+func nodeString(n *engine2.GraphNode, key string) string {
+	s, _ := n.Values[key].(string)
+	return s
+}
+
+func nodeBool(n *engine2.GraphNode, key string) bool {
+	b, _ := n.Values[key].(bool)
+	return b
+}
+
+// domainSID strips the RID of a domain account SID, "" for other SIDs.
+func domainSID(sid string) string {
+	sid = strings.ToUpper(sid)
+	i := strings.LastIndex(sid, "-")
+	if !strings.HasPrefix(sid, "S-1-5-21-") || i < 0 {
+		return ""
+	}
+	return sid[:i]
+}
+
+func rid(sid string) string {
+	return sid[strings.LastIndex(sid, "-")+1:]
+}
+
+func hasDomainRID(sid string, rids []string) bool {
+	return domainSID(sid) != "" && containsString(rids, rid(sid))
+}
+
+func hasBuiltinRID(sid string, rids []string) bool {
+	return strings.Contains(strings.ToUpper(sid), "S-1-5-32-") && containsString(rids, rid(sid))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
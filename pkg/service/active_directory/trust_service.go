@@ -0,0 +1,588 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/active_directory"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/utils"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths/history"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	engine5 "RedPaths-server/pkg/service/change"
+	engine4 "RedPaths-server/pkg/service/upsert"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+var (
+	ErrTrustProjectMissing = errors.New("project not found")
+	ErrInvalidTrust        = errors.New("invalid trust")
+)
+
+// Kinds of a TrustFinding.
+const (
+	TrustFindingSIDFilteringDisabled = "sid_filtering_disabled"
+	TrustFindingIntraForest          = "intra_forest"
+	TrustFindingTransitive           = "transitive"
+	TrustFindingExternal             = "external"
+)
+
+// DomainRef is a domain in a trust listing.
+type DomainRef struct {
+	UID    string `json:"uid"`
+	Name   string `json:"name"`
+	Forest string `json:"forest,omitempty"`
+}
+
+// TrustInfo is one trust of the project with both domains resolved.
+type TrustInfo struct {
+	UID           string     `json:"uid"`
+	Domain        *DomainRef `json:"domain"`
+	TrustedDomain *DomainRef `json:"trusted_domain"`
+	TrustType     string     `json:"trust_type"`
+	Direction     string     `json:"direction"`
+	IsTransitive  bool       `json:"is_transitive"`
+	SIDFiltering  bool       `json:"sid_filtering_enabled"`
+	WithinForest  bool       `json:"within_forest"`
+}
+
+// TrustFinding is a weakness of a single trust. Source is the domain whose
+// principals gain access, Target the domain they gain access to.
+type TrustFinding struct {
+	Kind        string     `json:"kind"`
+	TrustUID    string     `json:"trust_uid"`
+	Source      *DomainRef `json:"source"`
+	Target      *DomainRef `json:"target"`
+	RiskLevel   int        `json:"risk_level"`
+	Explanation string     `json:"explanation"`
+}
+
+// TrustHop is one trust on a trust path.
+type TrustHop struct {
+	TrustUID  string     `json:"trust_uid"`
+	From      *DomainRef `json:"from"`
+	To        *DomainRef `json:"to"`
+	TrustType string     `json:"trust_type"`
+}
+
+// TrustPath is a chain of trusts over which principals of From can
+// authenticate to resources in To.
+type TrustPath struct {
+	From          *DomainRef  `json:"from"`
+	To            *DomainRef  `json:"to"`
+	CrossesForest bool        `json:"crosses_forest"`
+	Hops          []*TrustHop `json:"hops"`
+}
+
+// TrustAnalysis is the trust picture of a project.
+type TrustAnalysis struct {
+	ProjectUID string          `json:"project_uid"`
+	Trusts     []*TrustInfo    `json:"trusts"`
+	Findings   []*TrustFinding `json:"findings"`
+	Paths      []*TrustPath    `json:"paths"`
+}
+
+// -----------------------------------------------------------------------------
+// TrustService
+// -----------------------------------------------------------------------------
+
+// TrustService stores the trusts of a domain ("Domain has_trust Trust trusts
+// Domain") and analyses the trusts of a project.
+type TrustService struct {
+	trustRepo     active_directory.TrustRepository
+	assertionRepo engine2.AssertionRepository
+	graphRepo     engine2.ProjectGraphRepository
+	domainService *DomainService
+	db            *dgo.Dgraph
+}
+
+func NewTrustService(dgraphCon *dgo.Dgraph) (*TrustService, error) {
+	domainService, err := NewDomainService(dgraphCon)
+	if err != nil {
+		return nil, fmt.Errorf("error creating domain service in trust service: %v", err)
+	}
+
+	return &TrustService{
+		db:            dgraphCon,
+		trustRepo:     active_directory.NewDgraphTrustRepository(dgraphCon),
+		assertionRepo: engine2.NewDgraphAssertionRepository(dgraphCon),
+		graphRepo:     engine2.NewDgraphProjectGraphRepository(dgraphCon),
+		domainService: domainService,
+	}, nil
+}
+
+// GetDomainTrusts returns the active trusts of a domain, TrustedDomain set.
+func (s *TrustService) GetDomainTrusts(ctx context.Context, domainUID string) ([]*res.EntityResult[*rpad.Trust], error) {
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*rpad.Trust], error) {
+		return s.activeTrusts(ctx, tx, domainUID)
+	})
+}
+
+func (s *TrustService) activeTrusts(ctx context.Context, tx *dgo.Txn, domainUID string) ([]*res.EntityResult[*rpad.Trust], error) {
+	results, err := s.trustRepo.GetByDomainUID(ctx, tx, domainUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading trusts of %s: %w", domainUID, err)
+	}
+
+	trusts := make([]*res.EntityResult[*rpad.Trust], 0, len(results))
+	for _, result := range results {
+		if result.Entity == nil || !hasActiveAssertion(result.Assertions) {
+			continue
+		}
+		links, err := s.assertionRepo.GetAssertionsByPredicate(ctx, tx, result.Entity.UID, core.PredicateTrusts)
+		if err != nil {
+			return nil, fmt.Errorf("loading trusted domain of %s: %w", result.Entity.UID, err)
+		}
+		for _, link := range links {
+			if link.Object != nil && link.Status != core.StatusInvalidated && link.Status != core.StatusExpired {
+				result.Entity.TrustedDomain = link.Object
+				break
+			}
+		}
+		trusts = append(trusts, result)
+	}
+	return trusts, nil
+}
+
+// -----------------------------------------------------------------------------
+// UpsertTrust
+// -----------------------------------------------------------------------------
+
+// UpsertTrust stores the trust of domainUID towards the trusted domain. The
+// trusted domain is looked up by UID, or upserted into the project by name
+// (it may lie in a forest nobody collected yet). A domain has one trust per
+// trusted domain: an existing one is updated in place.
+func (s *TrustService) UpsertTrust(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID, domainUID string,
+	incoming *rpad.Trust,
+	trusted *rpad.Domain,
+	actor string,
+) (*res.EntityResult[*rpad.Trust], error) {
+	if domainUID == "" {
+		return nil, utils.ErrUIDRequired
+	}
+	if err := normaliseTrust(incoming); err != nil {
+		return nil, err
+	}
+
+	trustedUID, err := s.resolveTrustedDomain(ctx, assertionCtx, projectUID, trusted, actor)
+	if err != nil {
+		return nil, err
+	}
+	if trustedUID == domainUID {
+		return nil, fmt.Errorf("%w: a domain cannot trust itself", ErrInvalidTrust)
+	}
+	trustedRef := &utils2.UIDRef{UID: trustedUID, Type: "Domain"}
+
+	var result *res.EntityResult[*rpad.Trust]
+
+	err = db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		existing, err := s.activeTrusts(ctx, tx, domainUID)
+		if err != nil {
+			return err
+		}
+
+		var current *rpad.Trust
+		var assertions []*core.Assertion
+		var changes []history.FieldChange
+		outcome := res.OutcomeCreated
+
+		for _, e := range existing {
+			if e.Entity.TrustedDomain != nil && e.Entity.TrustedDomain.UID == trustedUID {
+				current = e.Entity
+				assertions = e.Assertions
+				break
+			}
+		}
+
+		if current != nil {
+			outcome = res.OutcomeMerged
+			fields := buildTrustFields(incoming)
+			changes = engine5.DiffMergeFields(current, fields)
+			if len(changes) > 0 {
+				current, err = s.trustRepo.UpdateTrust(ctx, tx, current.UID, actor, fields)
+				if err != nil {
+					return fmt.Errorf("updating trust: %w", err)
+				}
+			}
+		} else {
+			incoming.DType = []string{"Trust"}
+			incoming.TrustedDomain = nil
+			current, err = s.trustRepo.Create(ctx, tx, incoming, actor)
+			if err != nil {
+				return fmt.Errorf("creating trust: %w", err)
+			}
+			trustRef := &utils2.UIDRef{UID: current.UID, Type: "Trust"}
+
+			hasTrust, _, err := linkOnce(ctx, tx, s.assertionRepo,
+				&utils2.UIDRef{UID: domainUID, Type: "Domain"}, trustRef,
+				core.PredicateHasTrust, assertionCtx, actor,
+			)
+			if err != nil {
+				return err
+			}
+			trusts, _, err := linkOnce(ctx, tx, s.assertionRepo,
+				trustRef, trustedRef,
+				core.PredicateTrusts, assertionCtx, actor,
+			)
+			if err != nil {
+				return err
+			}
+			assertions = []*core.Assertion{hasTrust, trusts}
+		}
+		current.TrustedDomain = trustedRef
+
+		result = &res.EntityResult[*rpad.Trust]{
+			Entity:     current,
+			Assertions: assertions,
+			Metadata: &res.ResultMetadata{
+				Source:         actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: len(assertions),
+				Outcome:        outcome,
+				Changes:        changes,
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("UpsertTrust failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// resolveTrustedDomain returns the UID of the trusted domain, upserting it
+// into the project if only its name is known.
+func (s *TrustService) resolveTrustedDomain(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID string,
+	trusted *rpad.Domain,
+	actor string,
+) (string, error) {
+	if trusted == nil || (trusted.UID == "" && trusted.Name == "") {
+		return "", fmt.Errorf("%w: trusted domain uid or name required", ErrInvalidTrust)
+	}
+	if trusted.UID != "" {
+		return trusted.UID, nil
+	}
+
+	result, err := s.domainService.UpsertDomain(ctx, engine4.Input[*rpad.Domain]{
+		Entity:       &rpad.Domain{Name: strings.ToLower(trusted.Name)},
+		ProjectUID:   projectUID,
+		ParentType:   "Project",
+		AssertionCtx: assertionCtx,
+		Actor:        actor,
+	})
+	if err != nil {
+		return "", fmt.Errorf("upserting trusted domain %s: %w", trusted.Name, err)
+	}
+	return result.Entity.UID, nil
+}
+
+// normaliseTrust maps type and direction onto the known values.
+func normaliseTrust(t *rpad.Trust) error {
+	if t == nil {
+		return fmt.Errorf("%w: trust required", ErrInvalidTrust)
+	}
+
+	t.TrustType = canonical(t.TrustType, rpad.TrustTypeUnknown,
+		rpad.TrustTypeParentChild, rpad.TrustTypeCrossLink, rpad.TrustTypeTreeRoot,
+		rpad.TrustTypeForest, rpad.TrustTypeExternal, rpad.TrustTypeUnknown)
+	if t.TrustType == "" {
+		return fmt.Errorf("%w: unknown trust type", ErrInvalidTrust)
+	}
+	t.Direction = canonical(t.Direction, "",
+		rpad.TrustDirectionInbound, rpad.TrustDirectionOutbound,
+		rpad.TrustDirectionBidirectional, rpad.TrustDirectionDisabled)
+	if t.Direction == "" {
+		return fmt.Errorf("%w: direction must be Inbound, Outbound, Bidirectional or Disabled", ErrInvalidTrust)
+	}
+	return nil
+}
+
+// canonical returns the known value matching v case-insensitively, fallback
+// for an empty v and "" for an unknown one.
+func canonical(v, fallback string, known ...string) string {
+	if v == "" {
+		return fallback
+	}
+	for _, k := range known {
+		if strings.EqualFold(v, k) {
+			return k
+		}
+	}
+	return ""
+}
+
+// buildTrustFields writes every field explicitly: false is meaningful here
+// (SID filtering off).
+func buildTrustFields(incoming *rpad.Trust) map[string]interface{} {
+	return map[string]interface{}{
+		"trust.trust_type":            incoming.TrustType,
+		"trust.direction":             incoming.Direction,
+		"trust.is_transitive":         incoming.IsTransitive,
+		"trust.sid_filtering_enabled": incoming.SIDFiltering,
+	}
+}
+
+func hasActiveAssertion(assertions []*core.Assertion) bool {
+	if len(assertions) == 0 {
+		return true
+	}
+	for _, a := range assertions {
+		if a.Status != core.StatusInvalidated && a.Status != core.StatusExpired {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// AnalyseTrusts
+// -----------------------------------------------------------------------------
+
+// trustRisks is the risk level of each finding kind.
+var trustRisks = map[string]int{
+	TrustFindingSIDFilteringDisabled: 9,
+	TrustFindingIntraForest:          7,
+	TrustFindingTransitive:           5,
+	TrustFindingExternal:             4,
+}
+
+// trustAccess is one direction of a trust: principals of From can
+// authenticate to To.
+type trustAccess struct {
+	info     *TrustInfo
+	from, to *DomainRef
+}
+
+// AnalyseTrusts lists the trusts of a project, flags their weaknesses and
+// computes the trust paths between its domains.
+func (s *TrustService) AnalyseTrusts(ctx context.Context, projectUID string) (*TrustAnalysis, error) {
+	graph, err := db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) (*engine2.ProjectGraph, error) {
+		return s.graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if project := graph.Nodes[projectUID]; project == nil || project.Type != "Project" {
+		return nil, ErrTrustProjectMissing
+	}
+
+	analysis := &TrustAnalysis{
+		ProjectUID: projectUID,
+		Trusts:     projectTrusts(graph),
+		Findings:   []*TrustFinding{},
+	}
+
+	var access []*trustAccess
+	for _, info := range analysis.Trusts {
+		trust := &rpad.Trust{TrustType: info.TrustType, Direction: info.Direction}
+		targetToDomain, domainToTarget := trust.AccessDirections()
+		if targetToDomain {
+			access = append(access, &trustAccess{info: info, from: info.TrustedDomain, to: info.Domain})
+		}
+		if domainToTarget {
+			access = append(access, &trustAccess{info: info, from: info.Domain, to: info.TrustedDomain})
+		}
+	}
+
+	for _, a := range access {
+		analysis.Findings = append(analysis.Findings, trustFindings(a)...)
+	}
+	sort.SliceStable(analysis.Findings, func(i, j int) bool {
+		return analysis.Findings[i].RiskLevel > analysis.Findings[j].RiskLevel
+	})
+	analysis.Paths = trustPaths(access)
+
+	return analysis, nil
+}
+
+// projectTrusts collects "Domain has_trust Trust trusts Domain" from the graph.
+func projectTrusts(graph *engine2.ProjectGraph) []*TrustInfo {
+	trusted := make(map[string]string)
+	forests := make(map[string]string)
+	for _, e := range graph.Edges {
+		switch e.Predicate {
+		case string(core.PredicateTrusts):
+			trusted[e.Subject] = e.Object
+		case string(core.PredicateHasDomain):
+			if ad := graph.Nodes[e.Subject]; ad != nil && ad.Type == "ActiveDirectory" {
+				forests[e.Object] = nodeString(ad, "active_directory.forest_name")
+			}
+		}
+	}
+	domainRef := func(uid string) *DomainRef {
+		ref := &DomainRef{UID: uid, Name: uid, Forest: forests[uid]}
+		if n := graph.Nodes[uid]; n != nil {
+			for _, key := range []string{"domain.dns_name", "domain.name"} {
+				if name := nodeString(n, key); name != "" {
+					ref.Name = name
+					break
+				}
+			}
+		}
+		return ref
+	}
+
+	trusts := []*TrustInfo{}
+	for _, e := range graph.Edges {
+		node := graph.Nodes[e.Object]
+		if e.Predicate != string(core.PredicateHasTrust) || node == nil || node.Type != "Trust" || trusted[e.Object] == "" {
+			continue
+		}
+		trust := &rpad.Trust{
+			TrustType:    nodeString(node, "trust.trust_type"),
+			Direction:    nodeString(node, "trust.direction"),
+			IsTransitive: nodeBool(node, "trust.is_transitive"),
+			SIDFiltering: nodeBool(node, "trust.sid_filtering_enabled"),
+		}
+		info := &TrustInfo{
+			UID:           node.UID,
+			Domain:        domainRef(e.Subject),
+			TrustedDomain: domainRef(trusted[e.Object]),
+			TrustType:     trust.TrustType,
+			Direction:     trust.Direction,
+			IsTransitive:  trust.IsTransitive,
+			SIDFiltering:  trust.SIDFiltering,
+			WithinForest:  trust.WithinForest(),
+		}
+		// Unknown trust types: the forests of both domains decide
+		if trust.TrustType == rpad.TrustTypeUnknown && info.Domain.Forest != "" {
+			info.WithinForest = strings.EqualFold(info.Domain.Forest, info.TrustedDomain.Forest)
+		}
+		trusts = append(trusts, info)
+	}
+
+	sort.Slice(trusts, func(i, j int) bool {
+		if trusts[i].Domain.Name != trusts[j].Domain.Name {
+			return trusts[i].Domain.Name < trusts[j].Domain.Name
+		}
+		return trusts[i].TrustedDomain.Name < trusts[j].TrustedDomain.Name
+	})
+	return trusts
+}
+
+// trustFindings flags one access direction of a trust.
+func trustFindings(a *trustAccess) []*TrustFinding {
+	var findings []*TrustFinding
+	add := func(kind, explanation string) {
+		findings = append(findings, &TrustFinding{
+			Kind:        kind,
+			TrustUID:    a.info.UID,
+			Source:      a.from,
+			Target:      a.to,
+			RiskLevel:   trustRisks[kind],
+			Explanation: explanation,
+		})
+	}
+
+	if a.info.WithinForest {
+		add(TrustFindingIntraForest, fmt.Sprintf(
+			"%s and %s share a forest, SID filtering does not apply: a compromise of %s extends to %s via SID history",
+			a.from.Name, a.to.Name, a.from.Name, a.to.Name))
+		return findings
+	}
+
+	if !a.info.SIDFiltering {
+		add(TrustFindingSIDFilteringDisabled, fmt.Sprintf(
+			"SID filtering is disabled on the %s trust: %s honours SID history of %s principals, a compromise of %s extends to %s",
+			a.info.TrustType, a.to.Name, a.from.Name, a.from.Name, a.to.Name))
+	}
+	if a.info.IsTransitive {
+		add(TrustFindingTransitive, fmt.Sprintf(
+			"The %s trust is transitive: principals of %s and its forest can reach every domain in the forest of %s",
+			a.info.TrustType, a.from.Name, a.to.Name))
+	}
+	if a.info.TrustType == rpad.TrustTypeExternal {
+		add(TrustFindingExternal, fmt.Sprintf(
+			"External trust: principals of %s can authenticate to %s across the forest boundary",
+			a.from.Name, a.to.Name))
+	}
+	return findings
+}
+
+// trustPaths finds for every domain the domains its principals can reach.
+// After the first hop a path only continues over transitive trusts, and it
+// crosses at most one forest boundary: forest trusts are not transitive
+// beyond the trusted forest.
+func trustPaths(access []*trustAccess) []*TrustPath {
+	outgoing := make(map[string][]*trustAccess)
+	starts := make(map[string]*DomainRef)
+	for _, a := range access {
+		outgoing[a.from.UID] = append(outgoing[a.from.UID], a)
+		starts[a.from.UID] = a.from
+	}
+
+	type state struct {
+		domain  *DomainRef
+		hops    []*TrustHop
+		crossed bool
+		open    bool // the last hop allows continuing
+	}
+
+	paths := []*TrustPath{}
+	for _, start := range starts {
+		seen := map[string]bool{start.UID: true}
+		frontier := []*state{{domain: start, open: true}}
+
+		for len(frontier) > 0 {
+			var next []*state
+			for _, current := range frontier {
+				if !current.open {
+					continue
+				}
+				for _, a := range outgoing[current.domain.UID] {
+					if seen[a.to.UID] {
+						continue
+					}
+					if len(current.hops) > 0 && !a.info.IsTransitive {
+						continue
+					}
+					if current.crossed && !a.info.WithinForest {
+						continue
+					}
+					seen[a.to.UID] = true
+
+					hops := append(append([]*TrustHop{}, current.hops...), &TrustHop{
+						TrustUID:  a.info.UID,
+						From:      a.from,
+						To:        a.to,
+						TrustType: a.info.TrustType,
+					})
+					reached := &state{
+						domain:  a.to,
+						hops:    hops,
+						crossed: current.crossed || !a.info.WithinForest,
+						open:    a.info.IsTransitive,
+					}
+					paths = append(paths, &TrustPath{From: start, To: a.to, CrossesForest: reached.crossed, Hops: hops})
+					next = append(next, reached)
+				}
+			}
+			frontier = next
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].From.Name != paths[j].From.Name {
+			return paths[i].From.Name < paths[j].From.Name
+		}
+		return paths[i].To.Name < paths[j].To.Name
+	})
+	return paths
+}
@@ -9,8 +9,10 @@ import (
 	active_directory2 "RedPaths-server/pkg/model/active_directory"
 	"RedPaths-server/pkg/model/core"
 	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths/history"
 	utils2 "RedPaths-server/pkg/model/utils"
 	engine3 "RedPaths-server/pkg/service/catalog"
+	engine5 "RedPaths-server/pkg/service/change"
 	engine4 "RedPaths-server/pkg/service/upsert"
 	"context"
 	"fmt"
@@ -75,6 +77,8 @@ func (s *UserService) UpsertUser(
 	subjectUID, subjectType, hasParent := input.Resolved()
 
 	var result *res.EntityResult[*active_directory2.User]
+	var outcome res.Outcome
+	var changes []history.FieldChange // field-level diff of a merge, nil otherwise
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
 		// --- Existence Check ---
@@ -95,6 +99,7 @@ func (s *UserService) UpsertUser(
 				return fmt.Errorf("creating user: %w", err)
 			}
 			actualUser = createdUser
+			outcome = res.OutcomeCreated
 			log.Printf("[UpsertUser] Created uid=%s name=%s", actualUser.UID, actualUser.Name)
 
 		case dgraph.ExistenceSourceHierarchy,
@@ -109,6 +114,7 @@ func (s *UserService) UpsertUser(
 					return fmt.Errorf("creating user (low score): %w", err)
 				}
 				actualUser = createdUser
+				outcome = res.OutcomeCreated
 				log.Printf("[UpsertUser] Low score, created uid=%s", actualUser.UID)
 
 			} else if best.Score >= 0.8 {
@@ -118,6 +124,7 @@ func (s *UserService) UpsertUser(
 					input.Entity,
 					input.AssertionCtx.GetConfidence(),
 				)
+				changes = engine5.DiffMergeFields(best.Result.Entity, mergeFields)
 				updated, err := s.userRepo.UpdateUser(
 					ctx, tx,
 					best.Result.Entity.UID,
@@ -128,6 +135,7 @@ func (s *UserService) UpsertUser(
 					return fmt.Errorf("merging user: %w", err)
 				}
 				actualUser = updated
+				outcome = res.OutcomeMerged
 				log.Printf("[UpsertUser] Merged uid=%s score=%.2f",
 					actualUser.UID, best.Score)
 
@@ -158,6 +166,12 @@ func (s *UserService) UpsertUser(
 				}
 
 				result = best.Result
+				result.Metadata = &res.ResultMetadata{
+					Source:        input.Actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeDuplicate,
+				}
 				return nil
 			}
 
@@ -168,7 +182,7 @@ func (s *UserService) UpsertUser(
 		// --- Create assertion (for both Create and Merge) ---
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateContains,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(input.AssertionCtx.GetMethod()),
 			Source:              input.Actor,
 			Confidence:          input.AssertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -192,6 +206,8 @@ func (s *UserService) UpsertUser(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        outcome,
+				Changes:        changes,
 			},
 		}
 
@@ -287,6 +303,22 @@ func buildUserMergeFields(
 		fields["user.asrep_roastable"] = incoming.ASREPRoastable
 		fields["user.is_disabled"] = incoming.IsDisabled
 		fields["user.is_locked"] = incoming.IsLocked
+		fields["user.has_spn"] = incoming.HasSPN
+		fields["user.allowed_to_delegate"] = incoming.AllowedToDelegate
+
+		// Timestamps only move forward, an older dump must not roll them back
+		if incoming.PwdLastSet.After(existing.PwdLastSet) {
+			fields["user.pwd_last_set"] = incoming.PwdLastSet
+		}
+		if incoming.LastLogon.After(existing.LastLogon) {
+			fields["user.last_logon"] = incoming.LastLogon
+		}
+	}
+
+	// Risk is set by assessment imports; the latest assessment replaces the previous one
+	if incoming.RiskScore > 0 || len(incoming.RiskReasons) > 0 {
+		fields["user.risk_score"] = incoming.RiskScore
+		fields["user.risk_reasons"] = incoming.RiskReasons
 	}
 
 	return fields
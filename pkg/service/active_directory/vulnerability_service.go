@@ -0,0 +1,259 @@
+package active_directory
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/active_directory"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	engine3 "RedPaths-server/pkg/service/catalog"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// -----------------------------------------------------------------------------
+// VulnerabilityService
+// -----------------------------------------------------------------------------
+
+type VulnerabilityService struct {
+	vulnRepo       active_directory.VulnerabilityRepository
+	assertionRepo  engine2.AssertionRepository
+	catalogService *engine3.CatalogService
+	db             *dgo.Dgraph
+}
+
+func NewVulnerabilityService(dgraphCon *dgo.Dgraph) (*VulnerabilityService, error) {
+	return &VulnerabilityService{
+		vulnRepo:       active_directory.NewDgraphVulnerabilityRepository(dgraphCon),
+		assertionRepo:  engine2.NewDgraphAssertionRepository(dgraphCon),
+		catalogService: engine3.NewCatalogService(dgraphCon),
+		db:             dgraphCon,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// AddVulnerability
+// -----------------------------------------------------------------------------
+
+// AddVulnerability attaches a finding to a host and, if serviceUID is set, to
+// the affected service. Findings are matched per host by Vulnerability.Key, so
+// re-importing a report updates the existing node (outcome merged) instead of
+// creating a second one.
+func (s *VulnerabilityService) AddVulnerability(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID string,
+	hostUID string,
+	serviceUID string,
+	incoming *model.Vulnerability,
+	actor string,
+) (*res.EntityResult[*model.Vulnerability], error) {
+	if hostUID == "" {
+		return nil, fmt.Errorf("hostUID cannot be empty")
+	}
+	return s.addVulnerability(ctx, assertionCtx, projectUID, &utils2.UIDRef{UID: hostUID, Type: "Host"}, serviceUID, incoming, actor)
+}
+
+// AddDomainVulnerability attaches a domain-wide finding (e.g. from an AD
+// health check) to the domain. Matching works as for AddVulnerability.
+func (s *VulnerabilityService) AddDomainVulnerability(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID string,
+	domainUID string,
+	incoming *model.Vulnerability,
+	actor string,
+) (*res.EntityResult[*model.Vulnerability], error) {
+	if domainUID == "" {
+		return nil, fmt.Errorf("domainUID cannot be empty")
+	}
+	return s.addVulnerability(ctx, assertionCtx, projectUID, &utils2.UIDRef{UID: domainUID, Type: "Domain"}, "", incoming, actor)
+}
+
+func (s *VulnerabilityService) addVulnerability(
+	ctx context.Context,
+	assertionCtx assertion.Context,
+	projectUID string,
+	subject *utils2.UIDRef,
+	serviceUID string,
+	incoming *model.Vulnerability,
+	actor string,
+) (*res.EntityResult[*model.Vulnerability], error) {
+	var result *res.EntityResult[*model.Vulnerability]
+
+	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		existing, err := s.vulnRepo.GetBySubjectUID(ctx, tx, subject.UID)
+		if err != nil {
+			return fmt.Errorf("loading %s vulnerabilities: %w", strings.ToLower(subject.Type), err)
+		}
+
+		var current *model.Vulnerability
+		for _, e := range existing {
+			if e.Entity != nil && e.Entity.Key() == incoming.Key() {
+				current = e.Entity
+				break
+			}
+		}
+
+		var assertions []*core.Assertion
+		outcome := res.OutcomeCreated
+
+		if current != nil {
+			outcome = res.OutcomeMerged
+			if fields := buildVulnerabilityMergeFields(current, incoming); len(fields) > 0 {
+				current, err = s.vulnRepo.UpdateVulnerability(ctx, tx, current.UID, actor, fields)
+				if err != nil {
+					return fmt.Errorf("merging vulnerability: %w", err)
+				}
+			}
+
+			// a run that sees the finding again records the sighting, tagged
+			// with the run, so the run history and rollback know about it
+			if assertion.RunID(ctx) != "" {
+				sighting, err := s.assertionRepo.Create(ctx, tx, &core.Assertion{
+					Predicate:           core.PredicateDetectedByScan,
+					Method:              core.Method(assertionCtx.GetMethod()),
+					Source:              actor,
+					Confidence:          assertionCtx.GetConfidence(),
+					Status:              core.Status(assertionCtx.GetStatus()),
+					Timestamp:           time.Now(),
+					HasDiscoveredParent: true,
+					MarkedAsHighValue:   assertionCtx.IsHighValue(),
+					Subject:             subject,
+					Object:              &utils2.UIDRef{UID: current.UID, Type: "Vulnerability"},
+				})
+				if err != nil {
+					return fmt.Errorf("creating vulnerability sighting assertion: %w", err)
+				}
+				assertions = append(assertions, sighting)
+			}
+		} else {
+			current, err = s.vulnRepo.Create(ctx, tx, incoming, actor)
+			if err != nil {
+				return fmt.Errorf("creating vulnerability: %w", err)
+			}
+
+			subjectAssertion, err := s.assertionRepo.Create(ctx, tx, &core.Assertion{
+				Predicate:           core.PredicateHasVulnerability,
+				Method:              core.Method(assertionCtx.GetMethod()),
+				Source:              actor,
+				Confidence:          assertionCtx.GetConfidence(),
+				Status:              core.Status(assertionCtx.GetStatus()),
+				Timestamp:           time.Now(),
+				HasDiscoveredParent: true,
+				MarkedAsHighValue:   assertionCtx.IsHighValue(),
+				Subject:             subject,
+				Object:              &utils2.UIDRef{UID: current.UID, Type: "Vulnerability"},
+			})
+			if err != nil {
+				return fmt.Errorf("creating vulnerability assertion: %w", err)
+			}
+			assertions = append(assertions, subjectAssertion)
+		}
+
+		if serviceUID != "" {
+			serviceAssertion, _, err := linkOnce(ctx, tx, s.assertionRepo,
+				&utils2.UIDRef{UID: serviceUID, Type: "Service"},
+				&utils2.UIDRef{UID: current.UID, Type: "Vulnerability"},
+				core.PredicateHasVulnerability, assertionCtx, actor,
+			)
+			if err != nil {
+				return err
+			}
+			assertions = append(assertions, serviceAssertion)
+		}
+
+		result = &res.EntityResult[*model.Vulnerability]{
+			Entity:     current,
+			Assertions: assertions,
+			Metadata: &res.ResultMetadata{
+				Source:         actor,
+				ScanTimestamp:  time.Now(),
+				EntityCount:    1,
+				AssertionCount: len(assertions),
+				Outcome:        outcome,
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AddVulnerability failed: %w", err)
+	}
+
+	if result.Metadata.Outcome == res.OutcomeCreated && len(result.Assertions) > 0 {
+		if _, catalogErr := engine3.AddToCatalog(
+			ctx, s.catalogService,
+			projectUID, result.Entity.UID, "Vulnerability",
+			result.Assertions[0], actor,
+		); catalogErr != nil {
+			log.Printf("[AddVulnerability] Warning: failed to add vulnerability %s to catalog: %v", result.Entity.UID, catalogErr)
+		}
+	}
+
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// GetVulnerabilitiesByHost
+// -----------------------------------------------------------------------------
+
+func (s *VulnerabilityService) GetVulnerabilitiesByHost(
+	ctx context.Context,
+	hostUID string,
+) ([]*res.EntityResult[*model.Vulnerability], error) {
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*model.Vulnerability], error) {
+		return s.vulnRepo.GetByHostUID(ctx, tx, hostUID)
+	})
+}
+
+// GetVulnerabilitiesByDomain returns the domain-wide findings, see
+// AddDomainVulnerability.
+func (s *VulnerabilityService) GetVulnerabilitiesByDomain(
+	ctx context.Context,
+	domainUID string,
+) ([]*res.EntityResult[*model.Vulnerability], error) {
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) ([]*res.EntityResult[*model.Vulnerability], error) {
+		return s.vulnRepo.GetBySubjectUID(ctx, tx, domainUID)
+	})
+}
+
+// buildVulnerabilityMergeFields returns the fields of a re-reported finding
+// that differ from the stored one. Scanner output is authoritative, so newer
+// values simply replace older ones.
+func buildVulnerabilityMergeFields(existing, incoming *model.Vulnerability) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	setString := func(field, oldValue, newValue string) {
+		if newValue != "" && newValue != oldValue {
+			fields[field] = newValue
+		}
+	}
+
+	setString("vulnerability.name", existing.Name, incoming.Name)
+	setString("vulnerability.cvss_vector", existing.CVSSVector, incoming.CVSSVector)
+	setString("vulnerability.severity", existing.Severity, incoming.Severity)
+	setString("vulnerability.evidence", existing.Evidence, incoming.Evidence)
+	setString("vulnerability.description", existing.Description, incoming.Description)
+	setString("vulnerability.solution", existing.Solution, incoming.Solution)
+
+	if incoming.CVSS > 0 && incoming.CVSS != existing.CVSS {
+		fields["vulnerability.cvss"] = incoming.CVSS
+	}
+	if incoming.ExploitAvailable && !existing.ExploitAvailable {
+		fields["vulnerability.exploit_available"] = true
+	}
+	if len(incoming.CVEs) > 0 && !slices.Equal(incoming.CVEs, existing.CVEs) {
+		fields["vulnerability.cves"] = incoming.CVEs
+	}
+
+	return fields
+}
@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// FormatName identifies RedPaths project archives in the manifest.
+	FormatName = "redpaths-project-archive"
+	// FormatVersion is bumped on incompatible changes of the archive layout.
+	FormatVersion = 1
+
+	fileManifest      = "manifest.json"
+	fileNodes         = "dgraph/nodes.ndjson"
+	fileModuleRuns    = "postgres/module_runs.ndjson"
+	fileVectorRuns    = "postgres/vector_runs.ndjson"
+	fileModuleLogs    = "postgres/module_logs.ndjson"
+	fileChanges       = "postgres/changes.ndjson"
+	fileNodeSnapshots = "postgres/node_snapshots.ndjson"
+
+	// maxArchiveSize limits the decompressed size of all files together and
+	// guards against decompression bombs.
+	maxArchiveSize = 2 << 30
+)
+
+var ErrInvalidArchive = errors.New("invalid project archive")
+
+// Manifest describes an archive. Counts holds the number of records per file.
+type Manifest struct {
+	Format      string         `json:"format"`
+	Version     int            `json:"version"`
+	ExportedAt  time.Time      `json:"exported_at"`
+	ProjectUID  string         `json:"project_uid"`
+	ProjectName string         `json:"project_name"`
+	Counts      map[string]int `json:"counts"`
+}
+
+// ── Writing ─────────────────────────────────────────────────────────────────
+
+// archiveWriter writes a gzipped tar. Every file is built in memory, tar
+// needs the size up front.
+type archiveWriter struct {
+	buf bytes.Buffer
+	gz  *gzip.Writer
+	tw  *tar.Writer
+	at  time.Time
+}
+
+func newArchiveWriter(at time.Time) *archiveWriter {
+	w := &archiveWriter{at: at}
+	w.gz = gzip.NewWriter(&w.buf)
+	w.tw = tar.NewWriter(w.gz)
+	return w
+}
+
+func (w *archiveWriter) writeFile(name string, content []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: w.at,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := w.tw.Write(content); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *archiveWriter) writeJSON(name string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return w.writeFile(name, content)
+}
+
+// writeNDJSON writes one record per line and returns the number of records.
+func writeNDJSON[T any](w *archiveWriter, name string, records []T) (int, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return 0, fmt.Errorf("encoding %s: %w", name, err)
+		}
+	}
+	return len(records), w.writeFile(name, buf.Bytes())
+}
+
+func (w *archiveWriter) close() ([]byte, error) {
+	if err := w.tw.Close(); err != nil {
+		return nil, fmt.Errorf("closing tar: %w", err)
+	}
+	if err := w.gz.Close(); err != nil {
+		return nil, fmt.Errorf("closing gzip: %w", err)
+	}
+	return w.buf.Bytes(), nil
+}
+
+// ── Reading ─────────────────────────────────────────────────────────────────
+
+// readArchive returns the files of a gzipped tar by name. Reading stops once
+// the files together exceed limit bytes.
+func readArchive(data []byte, limit int64) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not gzip compressed: %v", ErrInvalidArchive, err)
+	}
+	defer func(gz *gzip.Reader) {
+		_ = gz.Close()
+	}(gz)
+
+	files := make(map[string][]byte)
+	remaining := limit
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > remaining {
+			return nil, fmt.Errorf("%w: exceeds the maximum decompressed size of %d bytes at %s", ErrInvalidArchive, limit, header.Name)
+		}
+
+		content, err := io.ReadAll(io.LimitReader(tr, remaining+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidArchive, header.Name, err)
+		}
+		if int64(len(content)) > remaining {
+			return nil, fmt.Errorf("%w: exceeds the maximum decompressed size of %d bytes at %s", ErrInvalidArchive, limit, header.Name)
+		}
+		remaining -= int64(len(content))
+		files[header.Name] = content
+	}
+	return files, nil
+}
+
+func readManifest(files map[string][]byte) (*Manifest, error) {
+	content, ok := files[fileManifest]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s missing", ErrInvalidArchive, fileManifest)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, fileManifest, err)
+	}
+	if manifest.Format != FormatName {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidArchive, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d (supported: %d)", ErrInvalidArchive, manifest.Version, FormatVersion)
+	}
+	if manifest.ProjectUID == "" {
+		return nil, fmt.Errorf("%w: manifest without project uid", ErrInvalidArchive)
+	}
+	return &manifest, nil
+}
+
+// readNDJSON decodes the records of a file, a missing file has none.
+func readNDJSON[T any](files map[string][]byte, name string) ([]T, error) {
+	var records []T
+	content, ok := files[name]
+	if !ok {
+		return records, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d: %v", ErrInvalidArchive, name, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	return records, nil
+}
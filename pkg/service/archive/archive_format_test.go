@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadArchiveLimit(t *testing.T) {
+	w := newArchiveWriter(time.Now())
+	for _, name := range []string{"a.ndjson", "b.ndjson", "c.ndjson"} {
+		if err := w.writeFile(name, []byte(strings.Repeat("x", 40))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := w.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := readArchive(data, 120)
+	if err != nil {
+		t.Fatalf("readArchive() at the limit error = %v", err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got %d files, want 3", len(files))
+	}
+
+	// every entry is below the limit, only their sum exceeds it
+	if _, err := readArchive(data, 100); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("readArchive() over the limit error = %v, want %v", err, ErrInvalidArchive)
+	}
+}
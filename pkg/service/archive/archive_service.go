@@ -0,0 +1,497 @@
+package archive
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/changes"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/repository/redpaths/modules"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ArchiveSource = "ProjectArchive"
+
+	// postgresBatchSize limits the number of UIDs per IN clause
+	postgresBatchSize = 1000
+)
+
+var ErrProjectMissing = errors.New("project not found")
+
+// Archive is a rendered project archive.
+type Archive struct {
+	FileName string    `json:"file_name"`
+	Content  []byte    `json:"-"`
+	Manifest *Manifest `json:"manifest"`
+}
+
+// ImportResult describes a restored archive.
+type ImportResult struct {
+	ProjectUID         string            `json:"project_uid"`
+	ProjectName        string            `json:"project_name"`
+	OriginalProjectUID string            `json:"original_project_uid"`
+	Counts             map[string]int    `json:"counts"`
+	UIDMap             map[string]string `json:"uid_map,omitempty"`
+}
+
+// ImportOptions of an archive import. Name replaces the archived project
+// name, useful when the archive is restored next to the original.
+type ImportOptions struct {
+	Name       string
+	IncludeMap bool
+}
+
+// archiveContent is everything an archive holds besides the manifest.
+type archiveContent struct {
+	Nodes         []*engine.ArchiveNode
+	ModuleRuns    []*redpaths.ModuleRun
+	VectorRuns    []*redpaths.VectorRun
+	ModuleLogs    []*redpaths.LogEntry
+	Changes       []*history.Change
+	NodeSnapshots []*history.NodeSnapshot
+}
+
+// ArchiveService exports a project with its history into a single archive
+// and restores such archives as new projects, also on another server.
+type ArchiveService struct {
+	dgraphCon    *dgo.Dgraph
+	postgresCon  *gorm.DB
+	archiveRepo  engine.ProjectArchiveRepository
+	moduleRepo   modules.RedPathsModuleRepository
+	vectorRepo   modules.RedPathsVectorRepository
+	logRepo      changes.RedPathsLogRepository
+	changeRepo   changes.RedPathsChangeRepository
+	snapshotRepo changes.RedPathsSnapshotRepository
+}
+
+func NewArchiveService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) *ArchiveService {
+	return &ArchiveService{
+		dgraphCon:    dgraphCon,
+		postgresCon:  postgresCon,
+		archiveRepo:  engine.NewDgraphProjectArchiveRepository(dgraphCon),
+		moduleRepo:   modules.NewPostgresRedPathsModuleRepository(),
+		vectorRepo:   modules.NewPostgresRedPathsVectorRepository(),
+		logRepo:      changes.NewPostgresrRedPathsLogRepository(),
+		changeRepo:   changes.NewPostgresRedPathsChangesRepository(postgresCon),
+		snapshotRepo: changes.NewPostgresRedPathsSnapshotRepository(),
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Export
+// -----------------------------------------------------------------------------
+
+// Export writes the project into a tar.gz of NDJSON files: every Dgraph node
+// and assertion reachable from the project plus its module runs, vector runs,
+// logs, changes and node snapshots from Postgres.
+func (s *ArchiveService) Export(ctx context.Context, projectUID string) (*Archive, error) {
+	nodes, err := db.ExecuteRead(ctx, s.dgraphCon, func(tx *dgo.Txn) ([]*engine.ArchiveNode, error) {
+		return s.archiveRepo.Dump(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dumping project graph: %w", err)
+	}
+
+	var project *engine.ArchiveNode
+	uids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		uids = append(uids, n.UID)
+		if n.UID == projectUID && hasType(n, "Project") {
+			project = n
+		}
+	}
+	if project == nil {
+		return nil, ErrProjectMissing
+	}
+
+	content := &archiveContent{Nodes: nodes}
+	err = db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		return s.readHistory(ctx, tx, projectUID, uids, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	name, _ := project.Values["project.name"].(string)
+	manifest := &Manifest{
+		Format:      FormatName,
+		Version:     FormatVersion,
+		ExportedAt:  now,
+		ProjectUID:  projectUID,
+		ProjectName: name,
+		Counts:      make(map[string]int),
+	}
+
+	w := newArchiveWriter(now)
+	if err := writeContent(w, content, manifest.Counts); err != nil {
+		return nil, err
+	}
+	if err := w.writeJSON(fileManifest, manifest); err != nil {
+		return nil, err
+	}
+	data, err := w.close()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[%s] Exported project %s: %v", ArchiveSource, projectUID, manifest.Counts)
+
+	return &Archive{
+		FileName: archiveFileName(name, projectUID, now),
+		Content:  data,
+		Manifest: manifest,
+	}, nil
+}
+
+// readHistory collects the Postgres records of the project. Changes and
+// snapshots are keyed by node, so they are read for the archived nodes.
+func (s *ArchiveService) readHistory(ctx context.Context, tx *gorm.DB, projectUID string, uids []string, content *archiveContent) error {
+	var err error
+	if content.ModuleRuns, err = s.moduleRepo.GetAllModuleRuns(ctx, tx, projectUID); err != nil {
+		return err
+	}
+	if content.VectorRuns, err = s.vectorRepo.GetAllVectorRuns(ctx, tx, projectUID); err != nil {
+		return err
+	}
+	if content.ModuleLogs, err = s.logRepo.GetProjectOnlyLogs(ctx, tx, projectUID); err != nil {
+		return err
+	}
+	for _, entry := range content.ModuleLogs {
+		entry.Payload = rawPayload(entry.Payload)
+	}
+
+	for start := 0; start < len(uids); start += postgresBatchSize {
+		batch := uids[start:min(start+postgresBatchSize, len(uids))]
+
+		changeBatch, err := s.changeRepo.GetByEntityUIDs(ctx, tx, batch, 0)
+		if err != nil {
+			return err
+		}
+		content.Changes = append(content.Changes, changeBatch...)
+
+		snapshotBatch, err := s.snapshotRepo.GetByNodeUIDs(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		content.NodeSnapshots = append(content.NodeSnapshots, snapshotBatch...)
+	}
+	return nil
+}
+
+func writeContent(w *archiveWriter, content *archiveContent, counts map[string]int) error {
+	var err error
+	if counts[fileNodes], err = writeNDJSON(w, fileNodes, content.Nodes); err != nil {
+		return err
+	}
+	if counts[fileModuleRuns], err = writeNDJSON(w, fileModuleRuns, content.ModuleRuns); err != nil {
+		return err
+	}
+	if counts[fileVectorRuns], err = writeNDJSON(w, fileVectorRuns, content.VectorRuns); err != nil {
+		return err
+	}
+	if counts[fileModuleLogs], err = writeNDJSON(w, fileModuleLogs, content.ModuleLogs); err != nil {
+		return err
+	}
+	if counts[fileChanges], err = writeNDJSON(w, fileChanges, content.Changes); err != nil {
+		return err
+	}
+	if counts[fileNodeSnapshots], err = writeNDJSON(w, fileNodeSnapshots, content.NodeSnapshots); err != nil {
+		return err
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Import
+// -----------------------------------------------------------------------------
+
+// Import restores an archive as a new project. All nodes get new UIDs and
+// every reference to them (assertions, uid edges, Postgres records) is
+// remapped. Runs get new IDs as well, so a rollback in the restored project
+// never reaches the original. The Postgres records are written before the
+// Dgraph transaction commits, so a failing history import leaves no half
+// restored project.
+func (s *ArchiveService) Import(ctx context.Context, data []byte, opts ImportOptions) (*ImportResult, error) {
+	files, err := readArchive(data, maxArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readManifest(files)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readContent(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var project *engine.ArchiveNode
+	for _, n := range content.Nodes {
+		if n.UID == manifest.ProjectUID && hasType(n, "Project") {
+			project = n
+		}
+	}
+	if project == nil {
+		return nil, fmt.Errorf("%w: project node %s missing", ErrInvalidArchive, manifest.ProjectUID)
+	}
+	if name := strings.TrimSpace(opts.Name); name != "" {
+		project.Values["project.name"] = name
+	}
+
+	result := &ImportResult{
+		OriginalProjectUID: manifest.ProjectUID,
+		Counts:             make(map[string]int),
+	}
+	result.ProjectName, _ = project.Values["project.name"].(string)
+
+	renewRunIDs(content)
+
+	err = db.ExecuteInTransaction(ctx, s.dgraphCon, func(tx *dgo.Txn) error {
+		uidMap, err := s.archiveRepo.Restore(ctx, tx, content.Nodes)
+		if err != nil {
+			return err
+		}
+		result.ProjectUID = uidMap[manifest.ProjectUID]
+		result.Counts[fileNodes] = len(uidMap)
+		if opts.IncludeMap {
+			result.UIDMap = uidMap
+		}
+
+		return db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(pgTx *gorm.DB) error {
+			return s.writeHistory(ctx, pgTx, result.ProjectUID, content, uidMap, result.Counts)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[%s] Imported project %s as %s: %v", ArchiveSource, manifest.ProjectUID, result.ProjectUID, result.Counts)
+	return result, nil
+}
+
+func readContent(files map[string][]byte) (*archiveContent, error) {
+	content := &archiveContent{}
+	var err error
+	if content.Nodes, err = readNDJSON[*engine.ArchiveNode](files, fileNodes); err != nil {
+		return nil, err
+	}
+	if content.ModuleRuns, err = readNDJSON[*redpaths.ModuleRun](files, fileModuleRuns); err != nil {
+		return nil, err
+	}
+	if content.VectorRuns, err = readNDJSON[*redpaths.VectorRun](files, fileVectorRuns); err != nil {
+		return nil, err
+	}
+	if content.ModuleLogs, err = readNDJSON[*redpaths.LogEntry](files, fileModuleLogs); err != nil {
+		return nil, err
+	}
+	if content.Changes, err = readNDJSON[*history.Change](files, fileChanges); err != nil {
+		return nil, err
+	}
+	if content.NodeSnapshots, err = readNDJSON[*history.NodeSnapshot](files, fileNodeSnapshots); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// writeHistory inserts the Postgres records with remapped UIDs. Records of
+// nodes that were not restored are skipped.
+func (s *ArchiveService) writeHistory(ctx context.Context, tx *gorm.DB, projectUID string, content *archiveContent, uidMap map[string]string, counts map[string]int) error {
+	for _, run := range content.ModuleRuns {
+		run.ProjectUID = projectUID
+		for i := range run.Targets {
+			if newUID, ok := uidMap[run.Targets[i].UID]; ok {
+				run.Targets[i].UID = newUID
+			}
+		}
+		if err := s.moduleRepo.AddRun(ctx, tx, run); err != nil {
+			return err
+		}
+		counts[fileModuleRuns]++
+	}
+
+	for _, run := range content.VectorRuns {
+		run.ProjectUID = projectUID
+		if err := s.vectorRepo.AddRun(ctx, tx, run); err != nil {
+			return err
+		}
+		counts[fileVectorRuns]++
+	}
+
+	for _, entry := range content.ModuleLogs {
+		entry.ID = ""
+		entry.ProjectUID = projectUID
+		entry.Payload = rawPayload(entry.Payload)
+	}
+	if err := s.logRepo.CreateLogEntries(ctx, tx, content.ModuleLogs); err != nil {
+		return err
+	}
+	counts[fileModuleLogs] = len(content.ModuleLogs)
+
+	for _, c := range content.Changes {
+		newUID, ok := uidMap[c.EntityUID]
+		if !ok {
+			continue
+		}
+		// ids are global, the original may live on this server
+		c.ID = uuid.New()
+		c.EntityUID = newUID
+		if err := s.changeRepo.Save(ctx, tx, c); err != nil {
+			return fmt.Errorf("restoring change of %s: %w", newUID, err)
+		}
+		counts[fileChanges]++
+	}
+
+	snapshots := make([]*history.NodeSnapshot, 0, len(content.NodeSnapshots))
+	for _, snap := range content.NodeSnapshots {
+		newUID, ok := uidMap[snap.NodeUID]
+		if !ok {
+			continue
+		}
+		snap.NodeUID = newUID
+		if data, ok := remapUIDs(map[string]interface{}(snap.Data), uidMap).(map[string]interface{}); ok {
+			snap.Data = data
+		}
+		for i, edge := range snap.Edges {
+			if remapped, ok := remapUIDs(edge, uidMap).(map[string]interface{}); ok {
+				snap.Edges[i] = remapped
+			}
+		}
+		if snap.Edges == nil {
+			snap.Edges = history.JSONBArr{}
+		}
+		snapshots = append(snapshots, snap)
+	}
+	if err := s.snapshotRepo.Create(ctx, tx, snapshots); err != nil {
+		return err
+	}
+	counts[fileNodeSnapshots] = len(snapshots)
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+// -----------------------------------------------------------------------------
+
+func hasType(n *engine.ArchiveNode, dtype string) bool {
+	for _, t := range n.Types {
+		if t == dtype {
+			return true
+		}
+	}
+	return false
+}
+
+// renewRunIDs gives every run a new ID, on the nodes it tagged and in the
+// run, log and change records alike.
+func renewRunIDs(content *archiveContent) {
+	runIDs := make(map[string]string)
+	renew := func(runID string) string {
+		if runID == "" {
+			return ""
+		}
+		newID, ok := runIDs[runID]
+		if !ok {
+			newID = uuid.New().String()
+			runIDs[runID] = newID
+		}
+		return newID
+	}
+
+	for _, n := range content.Nodes {
+		if runID, ok := n.Values[assertion.RunIDField].(string); ok {
+			n.Values[assertion.RunIDField] = renew(runID)
+		}
+	}
+	for _, run := range content.ModuleRuns {
+		run.RunUID = renew(run.RunUID)
+		run.VectorRunUID = renew(run.VectorRunUID)
+	}
+	for _, run := range content.VectorRuns {
+		run.RunUID = renew(run.RunUID)
+	}
+	for _, entry := range content.ModuleLogs {
+		entry.RunID = renew(entry.RunID)
+	}
+	for _, c := range content.Changes {
+		c.RunID = renew(c.RunID)
+	}
+}
+
+// remapUIDs replaces every string that is an archived UID, the snapshots
+// store uids in their data ("uid") and edges ("target_uid").
+func remapUIDs(v interface{}, uidMap map[string]string) interface{} {
+	switch val := v.(type) {
+	case string:
+		if newUID, ok := uidMap[val]; ok {
+			return newUID
+		}
+		return val
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = remapUIDs(item, uidMap)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = remapUIDs(item, uidMap)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// rawPayload normalizes a jsonb payload to raw JSON: Postgres returns it as
+// text, the archive carries it decoded.
+func rawPayload(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		return p
+	case []byte:
+		if json.Valid(p) {
+			return json.RawMessage(p)
+		}
+		payload = string(p)
+	case string:
+		if json.Valid([]byte(p)) {
+			return json.RawMessage(p)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// archiveFileName builds "<project>_archive_<timestamp>.tar.gz".
+func archiveFileName(projectName, projectUID string, at time.Time) string {
+	name := strings.Trim(fileNameUnsafe.ReplaceAllString(projectName, "_"), "_.")
+	if name == "" {
+		name = projectUID
+	}
+	if len(name) > 60 {
+		name = name[:60]
+	}
+	return fmt.Sprintf("%s_archive_%s.tar.gz", name, at.Format("20060102-150405"))
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"testing"
+)
+
+func TestRenewRunIDs(t *testing.T) {
+	content := &archiveContent{
+		Nodes: []*engine.ArchiveNode{
+			{UID: "0x1", Values: map[string]interface{}{"project.name": "p"}},
+			{UID: "0x2", Values: map[string]interface{}{assertion.RunIDField: "vector-1"}},
+			{UID: "0x3", Values: map[string]interface{}{assertion.RunIDField: "import-1"}},
+		},
+		ModuleRuns: []*redpaths.ModuleRun{{RunUID: "module-1", VectorRunUID: "vector-1"}},
+		VectorRuns: []*redpaths.VectorRun{{RunUID: "vector-1"}},
+		ModuleLogs: []*redpaths.LogEntry{{RunID: "vector-1"}},
+		Changes:    []*history.Change{{RunID: "import-1"}, {}},
+	}
+
+	renewRunIDs(content)
+
+	vectorRun := content.Nodes[1].Values[assertion.RunIDField]
+	importRun := content.Nodes[2].Values[assertion.RunIDField]
+	if vectorRun == "vector-1" || importRun == "import-1" || vectorRun == importRun {
+		t.Fatalf("run ids not renewed: %v, %v", vectorRun, importRun)
+	}
+	if _, ok := content.Nodes[0].Values[assertion.RunIDField]; ok {
+		t.Errorf("untagged node got a run id")
+	}
+
+	if content.ModuleRuns[0].VectorRunUID != vectorRun || content.VectorRuns[0].RunUID != vectorRun || content.ModuleLogs[0].RunID != vectorRun {
+		t.Errorf("vector run records not renewed like the nodes: %+v %+v %+v", content.ModuleRuns[0], content.VectorRuns[0], content.ModuleLogs[0])
+	}
+	if content.ModuleRuns[0].RunUID == "module-1" {
+		t.Errorf("module run id not renewed")
+	}
+	if content.Changes[0].RunID != importRun || content.Changes[1].RunID != "" {
+		t.Errorf("change run ids = %q, %q, want %q, \"\"", content.Changes[0].RunID, content.Changes[1].RunID, importRun)
+	}
+}
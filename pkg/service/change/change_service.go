@@ -41,6 +41,28 @@ func (s *ChangeService) GetChangesByEntityWithOptions(
 	})
 }
 
+// GetChangesByRun returns the changes written by an import or module run.
+func (s *ChangeService) GetChangesByRun(
+	ctx context.Context,
+	runID string,
+) ([]*history.Change, error) {
+	return db.ExecutePostgresRead(ctx, s.db, func(tx *gorm.DB) ([]*history.Change, error) {
+		return s.redPathsChangeRepo.GetByRun(ctx, tx, runID)
+	})
+}
+
+// GetChangesByEntities returns the newest changes of the given entities, e.g.
+// for the timeline of a project; limit 0 returns all.
+func (s *ChangeService) GetChangesByEntities(
+	ctx context.Context,
+	entityUIDs []string,
+	limit int,
+) ([]*history.Change, error) {
+	return db.ExecutePostgresRead(ctx, s.db, func(tx *gorm.DB) ([]*history.Change, error) {
+		return s.redPathsChangeRepo.GetByEntityUIDs(ctx, tx, entityUIDs, limit)
+	})
+}
+
 func (s *ChangeService) SaveChange(
 	ctx context.Context,
 	change *history.Change,
@@ -3,6 +3,10 @@ package change
 import (
 	"RedPaths-server/pkg/model"
 	"RedPaths-server/pkg/model/redpaths/history"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
 )
 
 // buildCreatedChange erstellt einen Change-Eintrag für einen neu erstellten Host.
@@ -95,3 +99,58 @@ func BuildUpdatedChange(
 		Changes:      fields,
 	}
 }
+
+// DiffMergeFields vergleicht die Merge-Felder eines Upserts mit der bestehenden
+// Entity und liefert nur die Felder, deren Wert sich tatsächlich ändert.
+// last_seen_at wird ignoriert, fehlende Felder gelten als Nullwert.
+func DiffMergeFields(existing any, mergeFields map[string]interface{}) []history.FieldChange {
+	current := map[string]json.RawMessage{}
+	if raw, err := json.Marshal(existing); err == nil {
+		_ = json.Unmarshal(raw, &current)
+	}
+
+	var fields []history.FieldChange
+	for key, newVal := range mergeFields {
+		if key == "last_seen_at" {
+			continue
+		}
+		newRaw, err := json.Marshal(newVal)
+		if err != nil {
+			continue
+		}
+
+		oldRaw, ok := current[key]
+		if !ok {
+			if IsZeroJSON(newRaw) {
+				continue
+			}
+		} else if bytes.Equal(oldRaw, newRaw) {
+			continue
+		}
+
+		var oldVal any
+		if ok {
+			_ = json.Unmarshal(oldRaw, &oldVal)
+		}
+
+		// Lesbarer Feldname ohne Typ-Prefix ("user.is_disabled" → "is_disabled")
+		name := key
+		if idx := strings.Index(key, "."); idx >= 0 {
+			name = key[idx+1:]
+		}
+		fields = append(fields, history.FieldChange{Field: name, OldValue: oldVal, NewValue: newVal})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// IsZeroJSON reports whether raw is the JSON form of a zero value, which
+// Dgraph does not distinguish from an unset predicate.
+func IsZeroJSON(raw []byte) bool {
+	switch string(raw) {
+	case "null", "false", "0", `""`, "[]", `"0001-01-01T00:00:00Z"`:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/engine"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+// aclCapability is what holding an AD right on an object allows.
+type aclCapability struct {
+	Name      string
+	RiskLevel int
+}
+
+// aclCapabilities maps the abusable AD rights (BloodHound right names) to the
+// capability they grant over the target object. Rights not listed here do not
+// derive anything; the RBCD rights (AddAllowedToAct, WriteAccountRestrictions)
+// are covered by the delegation analysis.
+var aclCapabilities = map[string]aclCapability{
+	"GenericAll":           {"Full Control", 9},
+	"GenericWrite":         {"Write Attributes", 7},
+	"WriteDacl":            {"Modify Permissions", 8},
+	"WriteOwner":           {"Take Ownership", 8},
+	"Owns":                 {"Object Ownership", 8},
+	"ForceChangePassword":  {"Reset Password", 7},
+	"AddMember":            {"Add Group Member", 7},
+	"AddSelf":              {"Add Group Member", 7},
+	"AllExtendedRights":    {"All Extended Rights", 8},
+	"ReadLAPSPassword":     {"Read LAPS Password", 8},
+	"ReadGMSAPassword":     {"Read gMSA Password", 8},
+	"AddKeyCredentialLink": {"Shadow Credentials", 8},
+	"WriteSPN":             {"Targeted Kerberoast", 6},
+	"WriteGPLink":          {"Link GPO", 7},
+	"DCSync":               {"DCSync", 10},
+}
+
+// DCSync needs both replication rights; GetChangesAll alone is not enough.
+const (
+	rightGetChanges    = "GetChanges"
+	rightGetChangesAll = "GetChangesAll"
+	rightDCSync        = "DCSync"
+)
+
+// -----------------------------------------------------------------------------
+// ACLDerivationService
+// -----------------------------------------------------------------------------
+
+// ACLDerivationService turns the ACEs of a project into capabilities of the
+// grantee: "principal has_capability Capability applies_to object", derived
+// from the ACE. Running it again brings the capabilities in line with the
+// current ACEs: missing ones are created, those whose ACE is gone are
+// invalidated.
+type ACLDerivationService struct {
+	db        *dgo.Dgraph
+	graphRepo engine2.ProjectGraphRepository
+	deriver   *capabilityDeriver
+}
+
+func NewACLDerivationService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*ACLDerivationService, error) {
+	capabilityService, err := NewCapabilityService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, fmt.Errorf("error creating capability service in acl derivation service: %v", err)
+	}
+
+	owned := make(map[string]bool, len(aclCapabilities))
+	for _, c := range aclCapabilities {
+		owned[c.Name] = true
+	}
+
+	return &ACLDerivationService{
+		db:        dgraphCon,
+		graphRepo: engine2.NewDgraphProjectGraphRepository(dgraphCon),
+		deriver: &capabilityDeriver{
+			db:                dgraphCon,
+			assertionRepo:     engine2.NewDgraphAssertionRepository(dgraphCon),
+			capabilityService: capabilityService,
+			owned:             owned,
+		},
+	}, nil
+}
+
+// DeriveProject re-evaluates the ACL derived capabilities of a project.
+func (s *ACLDerivationService) DeriveProject(ctx context.Context, projectUID, actor string) (*DerivationSummary, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := newDerivationSummary(projectUID)
+	s.deriver.sync(ctx, graph, aclGrants(graph, summary), summary, actor)
+
+	log.Printf("[%s] ACL derivation project=%s aces=%d derived=%d kept=%d invalidated=%d failed=%d",
+		actor, projectUID, summary.Evaluated, len(summary.Derived), summary.Kept, len(summary.Invalidated), summary.Failed)
+
+	return summary, nil
+}
+
+// aclGrants walks "object has_acl ACL contains ACE granted_to principal" and
+// maps the abusable rights to capabilities. Deny ACEs grant nothing.
+func aclGrants(graph *engine2.ProjectGraph, summary *DerivationSummary) map[string]*derivedGrant {
+	grants := make(map[string]*derivedGrant)
+	add := func(capability aclCapability, right, principal string, object *engine2.GraphNode, ace string) {
+		scope := engine.ScopeDomain
+		if object.Type == "Host" {
+			scope = engine.ScopeHost
+		}
+		grant := &derivedGrant{
+			name:         capability.Name,
+			risk:         capability.RiskLevel,
+			scope:        scope,
+			reason:       right,
+			precondition: fmt.Sprintf("%s on %s", right, nodeLabel(object)),
+			principal:    principal,
+			object:       object.UID,
+			objectType:   object.Type,
+			source:       ace,
+			sourceType:   "ACE",
+		}
+		if _, ok := grants[grant.key()]; !ok {
+			grants[grant.key()] = grant
+		}
+	}
+	// principal|object → ACE holding GetChanges / GetChangesAll
+	replication := map[string]map[string]string{}
+
+	forEachACE(graph, func(object, ace *engine2.GraphNode, right, principal string) {
+		summary.Evaluated++
+		if right == rightGetChanges || right == rightGetChangesAll {
+			key := principal + "|" + object.UID
+			if replication[key] == nil {
+				replication[key] = map[string]string{}
+			}
+			replication[key][right] = ace.UID
+			return
+		}
+		if capability, ok := aclCapabilities[right]; ok {
+			add(capability, right, principal, object, ace.UID)
+		}
+	})
+
+	for key, rights := range replication {
+		if rights[rightGetChanges] == "" || rights[rightGetChangesAll] == "" {
+			continue
+		}
+		principal, objectUID, _ := strings.Cut(key, "|")
+		add(aclCapabilities[rightDCSync], rightGetChanges+"+"+rightGetChangesAll,
+			principal, graph.Nodes[objectUID], rights[rightGetChangesAll])
+	}
+
+	return grants
+}
+
+// forEachACE calls fn for every allowing ACE of the graph and principal it is
+// granted to.
+func forEachACE(graph *engine2.ProjectGraph, fn func(object, ace *engine2.GraphNode, right, principal string)) {
+	outgoing := outgoingEdges(graph)
+
+	for _, aclEdge := range graph.Edges {
+		if aclEdge.Predicate != string(core.PredicateHasACL) {
+			continue
+		}
+		object := graph.Nodes[aclEdge.Subject]
+		if object == nil {
+			continue
+		}
+
+		for _, aceEdge := range outgoing[aclEdge.Object] {
+			ace := graph.Nodes[aceEdge.Object]
+			if aceEdge.Predicate != string(core.PredicateContains) || ace == nil || ace.Type != "ACE" {
+				continue
+			}
+			right, _ := ace.Values["ace.name"].(string)
+			accessType, _ := ace.Values["ace.access_type"].(string)
+			if strings.EqualFold(accessType, "Deny") {
+				continue
+			}
+
+			for _, grantEdge := range outgoing[ace.UID] {
+				if grantEdge.Predicate != string(core.PredicateGrantedTo) || graph.Nodes[grantEdge.Object] == nil {
+					continue
+				}
+				fn(object, ace, right, grantEdge.Object)
+			}
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"RedPaths-server/internal/db"
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/engine"
+	utils2 "RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+var ErrProjectMissing = errors.New("project not found")
+
+// DerivedCapability is a capability a derivation created or invalidated.
+// Reason is what it rests on: the AD right or the delegation type.
+type DerivedCapability struct {
+	CapabilityUID string `json:"capability_uid,omitempty"`
+	Name          string `json:"name"`
+	Reason        string `json:"reason,omitempty"`
+	PrincipalUID  string `json:"principal_uid"`
+	ObjectUID     string `json:"object_uid"`
+	SourceUID     string `json:"source_uid,omitempty"`
+}
+
+// DerivationSummary reports one derivation run over a project.
+type DerivationSummary struct {
+	ProjectUID  string               `json:"project_uid"`
+	Evaluated   int                  `json:"evaluated"`
+	Derived     []*DerivedCapability `json:"derived"`
+	Kept        int                  `json:"kept"`
+	Invalidated []*DerivedCapability `json:"invalidated"`
+	Failed      int                  `json:"failed"`
+}
+
+func newDerivationSummary(projectUID string) *DerivationSummary {
+	return &DerivationSummary{
+		ProjectUID:  projectUID,
+		Derived:     []*DerivedCapability{},
+		Invalidated: []*DerivedCapability{},
+	}
+}
+
+// derivedGrant is one capability a principal should hold on an object,
+// derived from the source node (an ACE, a DelegationConfig).
+type derivedGrant struct {
+	name         string
+	risk         int
+	scope        engine.ScopeType
+	reason       string
+	precondition string
+	principal    string
+	object       string
+	objectType   string
+	source       string
+	sourceType   string
+}
+
+func (g *derivedGrant) key() string {
+	return grantKey(g.principal, g.name, g.object)
+}
+
+func grantKey(principal, capability, object string) string {
+	return principal + "|" + capability + "|" + object
+}
+
+// derivedNode is a derived capability already in the graph.
+type derivedNode struct {
+	uid       string
+	name      string
+	principal string
+	object    string
+}
+
+// capabilityDeriver keeps the capabilities with the owned names in line with
+// the grants a derivation computed: "principal has_capability Capability
+// applies_to object", derived from the source. Each derivation owns its
+// capability names, so derivations never invalidate each other's results.
+type capabilityDeriver struct {
+	db                *dgo.Dgraph
+	assertionRepo     engine2.AssertionRepository
+	capabilityService *CapabilityService
+	owned             map[string]bool
+}
+
+// sync creates the missing capabilities and invalidates those no grant backs
+// any more.
+func (d *capabilityDeriver) sync(
+	ctx context.Context,
+	graph *engine2.ProjectGraph,
+	grants map[string]*derivedGrant,
+	summary *DerivationSummary,
+	actor string,
+) {
+	existing := d.existing(graph)
+
+	// ── Create missing capabilities ─────────────────────────────────────────
+	keys := make([]string, 0, len(grants))
+	for key := range grants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		grant := grants[key]
+		if _, ok := existing[key]; ok {
+			summary.Kept++
+			continue
+		}
+		uid, err := d.create(ctx, graph, grant, summary.ProjectUID, actor)
+		if err != nil {
+			log.Printf("[%s] Warning: deriving %s of %s on %s failed: %v", actor, grant.name, grant.principal, grant.object, err)
+			summary.Failed++
+			continue
+		}
+		summary.Derived = append(summary.Derived, &DerivedCapability{
+			CapabilityUID: uid,
+			Name:          grant.name,
+			Reason:        grant.reason,
+			PrincipalUID:  grant.principal,
+			ObjectUID:     grant.object,
+			SourceUID:     grant.source,
+		})
+	}
+
+	// ── Invalidate capabilities whose source is gone ────────────────────────
+	var stale []*derivedNode
+	for key, node := range existing {
+		if _, ok := grants[key]; !ok {
+			stale = append(stale, node)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].uid < stale[j].uid })
+
+	for _, node := range stale {
+		if err := d.invalidate(ctx, node); err != nil {
+			log.Printf("[%s] Warning: invalidating capability %s failed: %v", actor, node.uid, err)
+			summary.Failed++
+			continue
+		}
+		summary.Invalidated = append(summary.Invalidated, &DerivedCapability{
+			CapabilityUID: node.uid,
+			Name:          node.name,
+			PrincipalUID:  node.principal,
+			ObjectUID:     node.object,
+		})
+	}
+}
+
+// existing collects the owned AD capabilities of the graph that apply to an
+// object, keyed like the grants. Only derivations link applies_to, so CVE or
+// host capabilities are never touched.
+func (d *capabilityDeriver) existing(graph *engine2.ProjectGraph) map[string]*derivedNode {
+	nodes := make(map[string]*derivedNode)
+	for _, n := range graph.NodesOfType("Capability") {
+		if source, _ := n.Values["capability.source_type"].(string); source != string(engine.SourceAD) {
+			continue
+		}
+		name, _ := n.Values["capability.name"].(string)
+		if !d.owned[name] {
+			continue
+		}
+		nodes[n.UID] = &derivedNode{uid: n.UID, name: name}
+	}
+
+	for _, e := range graph.Edges {
+		if node := nodes[e.Object]; node != nil && e.Predicate == string(core.PredicateHasCapability) {
+			node.principal = e.Subject
+		}
+		if node := nodes[e.Subject]; node != nil && e.Predicate == string(core.PredicateAppliesTo) {
+			node.object = e.Object
+		}
+	}
+
+	existing := make(map[string]*derivedNode)
+	for _, node := range nodes {
+		if node.principal == "" || node.object == "" {
+			continue
+		}
+		key := grantKey(node.principal, node.name, node.object)
+		// duplicates from concurrent runs: the first one is kept, the rest
+		// is invalidated as stale
+		if _, ok := existing[key]; ok {
+			existing[key+"|"+node.uid] = node
+			continue
+		}
+		existing[key] = node
+	}
+	return existing
+}
+
+// create creates the capability derived from the source and links it to the
+// principal and the object.
+func (d *capabilityDeriver) create(
+	ctx context.Context,
+	graph *engine2.ProjectGraph,
+	grant *derivedGrant,
+	projectUID, actor string,
+) (string, error) {
+	method := string(core.MethodInferred)
+	assertionCtx := assertion.FromRequest(&assertion.Context{Method: &method})
+
+	result, err := d.capabilityService.CreateAndLinkCapability(ctx, assertionCtx, &engine.Capability{
+		Name:         grant.name,
+		Scope:        grant.scope,
+		SourceType:   engine.SourceAD,
+		Precondition: grant.precondition,
+		RiskLevel:    grant.risk,
+	}, grant.source, grant.sourceType, projectUID, actor)
+	if err != nil {
+		return "", err
+	}
+	capabilityRef := &utils2.UIDRef{UID: result.Entity.UID, Type: "Capability"}
+
+	err = db.ExecuteInTransaction(ctx, d.db, func(tx *dgo.Txn) error {
+		links := []struct {
+			subject, object *utils2.UIDRef
+			predicate       core.Predicate
+		}{
+			{&utils2.UIDRef{UID: grant.principal, Type: graph.Nodes[grant.principal].Type}, capabilityRef, core.PredicateHasCapability},
+			{capabilityRef, &utils2.UIDRef{UID: grant.object, Type: grant.objectType}, core.PredicateAppliesTo},
+		}
+		for _, link := range links {
+			_, err := d.assertionRepo.Create(ctx, tx, &core.Assertion{
+				Predicate:           link.predicate,
+				Method:              core.MethodInferred,
+				Source:              actor,
+				Confidence:          1.0,
+				Status:              core.StatusValidated,
+				Timestamp:           time.Now(),
+				HasDiscoveredParent: true,
+				Subject:             link.subject,
+				Object:              link.object,
+			})
+			if err != nil {
+				return fmt.Errorf("creating %s assertion: %w", link.predicate, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Entity.UID, nil
+}
+
+// invalidate sets every active assertion of the capability to invalidated,
+// the derives edge and the catalog entry included. Nothing is deleted, the
+// audit trail stays.
+func (d *capabilityDeriver) invalidate(ctx context.Context, node *derivedNode) error {
+	return db.ExecuteInTransaction(ctx, d.db, func(tx *dgo.Txn) error {
+		outgoing, err := d.assertionRepo.GetAssertionsWhereSubject(ctx, tx, node.uid)
+		if err != nil {
+			return fmt.Errorf("fetching assertions of %s failed: %w", node.uid, err)
+		}
+		incoming, err := d.assertionRepo.GetAssertionsWhereObject(ctx, tx, node.uid)
+		if err != nil {
+			return fmt.Errorf("fetching assertions of %s failed: %w", node.uid, err)
+		}
+
+		for _, a := range append(outgoing, incoming...) {
+			if a.Status == core.StatusInvalidated || a.Status == core.StatusExpired {
+				continue
+			}
+			_, err := d.assertionRepo.Update(ctx, tx, a.UID, map[string]interface{}{
+				"assertion.status": string(core.StatusInvalidated),
+			})
+			if err != nil {
+				return fmt.Errorf("invalidating assertion %s failed: %w", a.UID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// loadProjectGraph reads the project graph in one read-only transaction.
+func loadProjectGraph(ctx context.Context, dgraphCon *dgo.Dgraph, graphRepo engine2.ProjectGraphRepository, projectUID string) (*engine2.ProjectGraph, error) {
+	graph, err := db.ExecuteRead(ctx, dgraphCon, func(tx *dgo.Txn) (*engine2.ProjectGraph, error) {
+		return graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if project := graph.Nodes[projectUID]; project == nil || project.Type != "Project" {
+		return nil, ErrProjectMissing
+	}
+	return graph, nil
+}
+
+func outgoingEdges(graph *engine2.ProjectGraph) map[string][]*engine2.GraphEdge {
+	outgoing := make(map[string][]*engine2.GraphEdge)
+	for _, e := range graph.Edges {
+		outgoing[e.Subject] = append(outgoing[e.Subject], e)
+	}
+	return outgoing
+}
+
+// labelPredicates are tried in order to name a node in preconditions.
+var labelPredicates = []string{
+	"user.sam_account_name",
+	"security_principal.name",
+	"host.dns_host_name",
+	"host.hostname",
+	"host.name",
+	"domain.dns_name",
+	"domain.name",
+	"directory_node.name",
+	"gpo.name",
+}
+
+func nodeLabel(n *engine2.GraphNode) string {
+	for _, pred := range labelPredicates {
+		if v, ok := n.Values[pred].(string); ok && v != "" {
+			return v
+		}
+	}
+	return n.Type + " " + n.UID
+}
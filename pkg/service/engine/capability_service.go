@@ -61,6 +61,34 @@ func NewCapabilityService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*Capabil
 		db:             dgraphCon}, nil
 }
 
+// findDerivedCapability returns the active capability with the name, scope and
+// precondition of incoming the subject already derives, nil if there is none.
+func (s *CapabilityService) findDerivedCapability(
+	ctx context.Context,
+	tx *dgo.Txn,
+	subjectUID string,
+	incoming *engine.Capability,
+) (*engine.Capability, *core.Assertion, error) {
+	derives, err := s.assertionRepo.GetAssertionsByPredicate(ctx, tx, subjectUID, core.PredicateDerives)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading derived capabilities of %s: %w", subjectUID, err)
+	}
+	for _, a := range derives {
+		if a.Object == nil || a.Status == core.StatusInvalidated || a.Status == core.StatusExpired {
+			continue
+		}
+		capability, err := s.capabilityRepo.Get(ctx, tx, a.Object.UID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading capability %s: %w", a.Object.UID, err)
+		}
+		if capability != nil && capability.Name == incoming.Name && capability.Scope == incoming.Scope &&
+			capability.Precondition == incoming.Precondition {
+			return capability, a, nil
+		}
+	}
+	return nil, nil, nil
+}
+
 func (s *CapabilityService) GetCapabilitiesFromCatalog(
 	ctx context.Context,
 	projectUID string,
@@ -81,6 +109,26 @@ func (s *CapabilityService) CreateAndLinkCapability(
 	var result *res.EntityResult[engine.Capability]
 
 	err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+		// the subject derives every capability only once, linking it again
+		// returns the existing one
+		existing, existingAssertion, err := s.findDerivedCapability(ctx, tx, subjectUID, incomingCapability)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			result = &res.EntityResult[engine.Capability]{
+				Entity:     *existing,
+				Assertions: []*core.Assertion{existingAssertion},
+				Metadata: &res.ResultMetadata{
+					Source:        actor,
+					ScanTimestamp: time.Now(),
+					EntityCount:   1,
+					Outcome:       res.OutcomeMerged,
+				},
+			}
+			return nil
+		}
+
 		capability, err := s.capabilityRepo.Create(ctx, tx, incomingCapability, actor)
 		if err != nil {
 			return fmt.Errorf("failed to create capability: %w", err)
@@ -88,7 +136,7 @@ func (s *CapabilityService) CreateAndLinkCapability(
 
 		assertionSchema := &core.Assertion{
 			Predicate:           core.PredicateDerives,
-			Method:              core.MethodDirectAdd,
+			Method:              core.Method(assertionCtx.GetMethod()),
 			Source:              actor,
 			Confidence:          assertionCtx.GetConfidence(),
 			Status:              core.StatusValidated,
@@ -112,6 +160,7 @@ func (s *CapabilityService) CreateAndLinkCapability(
 				ScanTimestamp:  time.Now(),
 				EntityCount:    1,
 				AssertionCount: 1,
+				Outcome:        res.OutcomeCreated,
 			},
 		}
 		return nil
@@ -119,6 +168,9 @@ func (s *CapabilityService) CreateAndLinkCapability(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create and link capability: %w", err)
 	}
+	if result.Metadata.Outcome == res.OutcomeMerged {
+		return result, nil
+	}
 
 	_, err = s.projectService.AddEntityToProjectCatalog(
 		ctx, result.Assertions[0], projectUID, result.Entity.UID, actor,
@@ -0,0 +1,412 @@
+package engine
+
+import (
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/engine"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+// Kinds of a DelegationFinding.
+const (
+	FindingUnconstrained      = "unconstrained"
+	FindingConstrained        = "constrained"
+	FindingProtocolTransition = "constrained_protocol_transition"
+	FindingResourceBasedWrite = "rbcd_write"
+)
+
+// Capability names of the delegation analysis. The attack path engine
+// matches on them.
+const (
+	CapabilityUnconstrainedDelegation = "Unconstrained Delegation"
+	CapabilityConstrainedDelegation   = "Constrained Delegation"
+	CapabilityProtocolTransition      = "Constrained Delegation (Protocol Transition)"
+	CapabilityResourceBasedDelegation = "Resource-Based Constrained Delegation"
+)
+
+// delegationRisks is the risk level of each finding kind.
+var delegationRisks = map[string]int{
+	FindingUnconstrained:      9,
+	FindingProtocolTransition: 8,
+	FindingResourceBasedWrite: 8,
+	FindingConstrained:        6,
+}
+
+// rbcdRights are the ACE rights that allow writing
+// msDS-AllowedToActOnBehalfOfOtherIdentity of a computer.
+var rbcdRights = map[string]bool{
+	"AddAllowedToAct":          true,
+	"WriteAccountRestrictions": true,
+	"GenericWrite":             true,
+	"GenericAll":               true,
+}
+
+// FindingNode is an entity a finding refers to.
+type FindingNode struct {
+	UID   string `json:"uid"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// DelegationFinding is one delegation an attacker holding the principal can
+// abuse against the targets.
+type DelegationFinding struct {
+	Kind           string         `json:"kind"`
+	Principal      *FindingNode   `json:"principal"`
+	Targets        []*FindingNode `json:"targets"`
+	SPNs           []string       `json:"spns,omitempty"`
+	UnresolvedSPNs []string       `json:"unresolved_spns,omitempty"`
+	Rights         []string       `json:"rights,omitempty"`
+	RiskLevel      int            `json:"risk_level"`
+	Explanation    string         `json:"explanation"`
+}
+
+// DelegationAnalysis is the result of analysing a project: the findings,
+// riskiest first, and, if capabilities were derived, the derivation summary.
+type DelegationAnalysis struct {
+	ProjectUID   string               `json:"project_uid"`
+	Findings     []*DelegationFinding `json:"findings"`
+	Capabilities *DerivationSummary   `json:"capabilities,omitempty"`
+}
+
+// -----------------------------------------------------------------------------
+// DelegationAnalysisService
+// -----------------------------------------------------------------------------
+
+// DelegationAnalysisService finds abusable Kerberos delegation in a project:
+// hosts and accounts with unconstrained delegation, constrained delegation
+// (with and without protocol transition) and principals that may configure
+// resource-based constrained delegation on a host. Each finding becomes a
+// capability of the principal that applies to its target.
+type DelegationAnalysisService struct {
+	db        *dgo.Dgraph
+	graphRepo engine2.ProjectGraphRepository
+	deriver   *capabilityDeriver
+}
+
+func NewDelegationAnalysisService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*DelegationAnalysisService, error) {
+	capabilityService, err := NewCapabilityService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, fmt.Errorf("error creating capability service in delegation analysis service: %v", err)
+	}
+
+	return &DelegationAnalysisService{
+		db:        dgraphCon,
+		graphRepo: engine2.NewDgraphProjectGraphRepository(dgraphCon),
+		deriver: &capabilityDeriver{
+			db:                dgraphCon,
+			assertionRepo:     engine2.NewDgraphAssertionRepository(dgraphCon),
+			capabilityService: capabilityService,
+			owned: map[string]bool{
+				CapabilityUnconstrainedDelegation: true,
+				CapabilityConstrainedDelegation:   true,
+				CapabilityProtocolTransition:      true,
+				CapabilityResourceBasedDelegation: true,
+			},
+		},
+	}, nil
+}
+
+// Analyse returns the delegation findings of a project without changing
+// anything.
+func (s *DelegationAnalysisService) Analyse(ctx context.Context, projectUID string) (*DelegationAnalysis, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, _ := analyseDelegations(graph)
+	return &DelegationAnalysis{ProjectUID: projectUID, Findings: findings}, nil
+}
+
+// DeriveProject analyses the project and brings the delegation capabilities
+// in line with the findings.
+func (s *DelegationAnalysisService) DeriveProject(ctx context.Context, projectUID, actor string) (*DelegationAnalysis, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, grants := analyseDelegations(graph)
+	summary := newDerivationSummary(projectUID)
+	summary.Evaluated = len(findings)
+	s.deriver.sync(ctx, graph, grants, summary, actor)
+
+	log.Printf("[%s] Delegation analysis project=%s findings=%d derived=%d kept=%d invalidated=%d failed=%d",
+		actor, projectUID, len(findings), len(summary.Derived), summary.Kept, len(summary.Invalidated), summary.Failed)
+
+	return &DelegationAnalysis{ProjectUID: projectUID, Findings: findings, Capabilities: summary}, nil
+}
+
+// analyseDelegations computes the findings of the graph and the capabilities
+// they grant.
+func analyseDelegations(graph *engine2.ProjectGraph) ([]*DelegationFinding, map[string]*derivedGrant) {
+	a := &delegationAnalyser{
+		graph:    graph,
+		incoming: make(map[string][]*engine2.GraphEdge),
+		hosts:    make(map[string]*engine2.GraphNode),
+		grants:   make(map[string]*derivedGrant),
+	}
+	for _, e := range graph.Edges {
+		a.incoming[e.Object] = append(a.incoming[e.Object], e)
+	}
+	a.indexHosts()
+
+	for _, e := range graph.Edges {
+		if e.Predicate != string(core.PredicateHasDelegation) {
+			continue
+		}
+		principal, config := graph.Nodes[e.Subject], graph.Nodes[e.Object]
+		if principal == nil || config == nil || config.Type != "DelegationConfig" {
+			continue
+		}
+		switch delegationType, _ := config.Values["delegation_config.delegation_type"].(string); delegationType {
+		case priv.DelegationUnconstrained:
+			a.unconstrained(principal, config)
+		case priv.DelegationConstrained, priv.DelegationConstrainedTransition:
+			a.constrained(principal, config, delegationType == priv.DelegationConstrainedTransition)
+		}
+	}
+	a.resourceBased()
+
+	sort.SliceStable(a.findings, func(i, j int) bool {
+		if a.findings[i].RiskLevel != a.findings[j].RiskLevel {
+			return a.findings[i].RiskLevel > a.findings[j].RiskLevel
+		}
+		if a.findings[i].Kind != a.findings[j].Kind {
+			return a.findings[i].Kind < a.findings[j].Kind
+		}
+		return a.findings[i].Principal.Label < a.findings[j].Principal.Label
+	})
+	if a.findings == nil {
+		a.findings = []*DelegationFinding{}
+	}
+	return a.findings, a.grants
+}
+
+type delegationAnalyser struct {
+	graph    *engine2.ProjectGraph
+	incoming map[string][]*engine2.GraphEdge
+	// lower-case host names (FQDN and short name) → host
+	hosts    map[string]*engine2.GraphNode
+	findings []*DelegationFinding
+	grants   map[string]*derivedGrant
+}
+
+func (a *delegationAnalyser) indexHosts() {
+	hosts := a.graph.NodesOfType("Host")
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].UID < hosts[j].UID })
+
+	for _, host := range hosts {
+		for _, pred := range []string{"host.dns_host_name", "host.hostname", "host.name"} {
+			name, _ := host.Values[pred].(string)
+			name = strings.ToLower(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := a.hosts[name]; !ok {
+				a.hosts[name] = host
+			}
+			if short, _, ok := strings.Cut(name, "."); ok {
+				if _, known := a.hosts[short]; !known {
+					a.hosts[short] = host
+				}
+			}
+		}
+	}
+}
+
+// unconstrained: every TGT sent to the principal is cached there, coerced
+// authentication of a domain controller compromises the domain. Domain
+// controllers have it by design and are skipped.
+func (a *delegationAnalyser) unconstrained(principal, config *engine2.GraphNode) {
+	if principal.Type == "Host" {
+		if dc, _ := principal.Values["host.is_domain_controller"].(bool); dc {
+			return
+		}
+	}
+	target := a.domainOf(principal)
+	risk := delegationRisks[FindingUnconstrained]
+
+	a.findings = append(a.findings, &DelegationFinding{
+		Kind:        FindingUnconstrained,
+		Principal:   findingNode(principal),
+		Targets:     []*FindingNode{findingNode(target)},
+		RiskLevel:   risk,
+		Explanation: fmt.Sprintf("%s caches the TGT of every account authenticating to it; coercing a domain controller yields domain compromise", nodeLabel(principal)),
+	})
+	a.grant(CapabilityUnconstrainedDelegation, risk, engine.ScopeDomain, priv.DelegationUnconstrained, principal, target, config)
+}
+
+// constrained: the principal may impersonate users against the listed
+// services. With protocol transition it needs no ticket of the user, so
+// any user, admins included, can be impersonated.
+func (a *delegationAnalyser) constrained(principal, config *engine2.GraphNode, transition bool) {
+	kind, name := FindingConstrained, CapabilityConstrainedDelegation
+	delegationType := priv.DelegationConstrained
+	explanation := "%s may impersonate users towards services on %s, given a service ticket of the user"
+	if transition {
+		kind, name = FindingProtocolTransition, CapabilityProtocolTransition
+		delegationType = priv.DelegationConstrainedTransition
+		explanation = "%s may impersonate any user, administrators included, towards services on %s"
+	}
+	risk := delegationRisks[kind]
+
+	finding := &DelegationFinding{
+		Kind:      kind,
+		Principal: findingNode(principal),
+		Targets:   []*FindingNode{},
+		SPNs:      stringValues(config, "delegation_config.allowed_to_delegate_to"),
+		RiskLevel: risk,
+	}
+	seen := map[string]bool{}
+	for _, spn := range finding.SPNs {
+		host := a.hosts[priv.SPNHost(spn)]
+		if host == nil {
+			finding.UnresolvedSPNs = append(finding.UnresolvedSPNs, spn)
+			continue
+		}
+		if seen[host.UID] {
+			continue
+		}
+		seen[host.UID] = true
+		finding.Targets = append(finding.Targets, findingNode(host))
+		a.grant(name, risk, engine.ScopeHost, delegationType, principal, host, config)
+	}
+
+	labels := make([]string, 0, len(finding.Targets))
+	for _, target := range finding.Targets {
+		labels = append(labels, target.Label)
+	}
+	if len(labels) == 0 {
+		labels = finding.SPNs
+	}
+	finding.Explanation = fmt.Sprintf(explanation, nodeLabel(principal), strings.Join(labels, ", "))
+	a.findings = append(a.findings, finding)
+}
+
+// resourceBased: principals allowed to write the RBCD attribute of a host
+// can let an account they control impersonate any user towards it.
+func (a *delegationAnalyser) resourceBased() {
+	type holder struct {
+		principal, host *engine2.GraphNode
+		rights          []string
+		ace             *engine2.GraphNode
+	}
+	holders := map[string]*holder{}
+	var order []string
+
+	forEachACE(a.graph, func(object, ace *engine2.GraphNode, right, principal string) {
+		if object.Type != "Host" || !rbcdRights[right] || principal == object.UID {
+			return
+		}
+		key := principal + "|" + object.UID
+		h := holders[key]
+		if h == nil {
+			h = &holder{principal: a.graph.Nodes[principal], host: object, ace: ace}
+			holders[key] = h
+			order = append(order, key)
+		}
+		if !slices.Contains(h.rights, right) {
+			h.rights = append(h.rights, right)
+		}
+	})
+
+	risk := delegationRisks[FindingResourceBasedWrite]
+	for _, key := range order {
+		h := holders[key]
+		sort.Strings(h.rights)
+		a.findings = append(a.findings, &DelegationFinding{
+			Kind:      FindingResourceBasedWrite,
+			Principal: findingNode(h.principal),
+			Targets:   []*FindingNode{findingNode(h.host)},
+			Rights:    h.rights,
+			RiskLevel: risk,
+			Explanation: fmt.Sprintf("%s may configure resource-based constrained delegation on %s (%s) and impersonate any user towards it",
+				nodeLabel(h.principal), nodeLabel(h.host), strings.Join(h.rights, ", ")),
+		})
+		a.grant(CapabilityResourceBasedDelegation, risk, engine.ScopeHost, strings.Join(h.rights, "+"), h.principal, h.host, h.ace)
+	}
+}
+
+func (a *delegationAnalyser) grant(name string, risk int, scope engine.ScopeType, reason string, principal, object, source *engine2.GraphNode) {
+	grant := &derivedGrant{
+		name:         name,
+		risk:         risk,
+		scope:        scope,
+		reason:       reason,
+		precondition: fmt.Sprintf("%s on %s", name, nodeLabel(object)),
+		principal:    principal.UID,
+		object:       object.UID,
+		objectType:   object.Type,
+		source:       source.UID,
+		sourceType:   source.Type,
+	}
+	if _, ok := a.grants[grant.key()]; !ok {
+		a.grants[grant.key()] = grant
+	}
+}
+
+// domainParents are the predicates linking an entity to its container.
+var domainParents = map[string]bool{
+	string(core.PredicateHasHost):  true,
+	string(core.PredicateHasUser):  true,
+	string(core.PredicateHasGroup): true,
+	string(core.PredicateContains): true,
+}
+
+// domainOf climbs the containers of a node up to its domain. Nodes outside
+// any domain stand for themselves.
+func (a *delegationAnalyser) domainOf(n *engine2.GraphNode) *engine2.GraphNode {
+	seen := map[string]bool{n.UID: true}
+	frontier := []*engine2.GraphNode{n}
+
+	for len(frontier) > 0 {
+		var next []*engine2.GraphNode
+		for _, current := range frontier {
+			for _, e := range a.incoming[current.UID] {
+				parent := a.graph.Nodes[e.Subject]
+				if !domainParents[e.Predicate] || parent == nil || seen[parent.UID] {
+					continue
+				}
+				if parent.Type == "Domain" {
+					return parent
+				}
+				seen[parent.UID] = true
+				next = append(next, parent)
+			}
+		}
+		frontier = next
+	}
+	return n
+}
+
+func findingNode(n *engine2.GraphNode) *FindingNode {
+	return &FindingNode{UID: n.UID, Type: n.Type, Label: nodeLabel(n)}
+}
+
+// stringValues reads a [string] predicate of a graph node.
+func stringValues(n *engine2.GraphNode, key string) []string {
+	switch v := n.Values[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,701 @@
+package engine
+
+import (
+	engine2 "RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/engine"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+var ErrPolicyObjectMissing = errors.New("object not found in project")
+
+// Kinds of a GPOFinding.
+const (
+	FindingGPOEdit          = "gpo_edit"
+	FindingRestrictedGroups = "restricted_groups"
+	FindingScheduledTask    = "scheduled_task"
+	FindingUserRights       = "user_rights"
+)
+
+// Capability names of the GPO analysis. The attack path engine matches on
+// them.
+const (
+	CapabilityGPOEdit          = "GPO Abuse"
+	CapabilityGPOLocalAdmin    = "Local Admin via GPO"
+	CapabilityGPOScheduledTask = "Code Execution via GPO"
+	CapabilityGPOUserRight     = "Privileged User Right via GPO"
+)
+
+// gpoRisks is the risk level of each finding kind, on ordinary hosts and
+// once a domain controller is affected.
+var gpoRisks = map[string]struct{ base, dc int }{
+	FindingGPOEdit:          {8, 10},
+	FindingRestrictedGroups: {8, 10},
+	FindingScheduledTask:    {7, 9},
+	FindingUserRights:       {6, 9},
+}
+
+// gpoFindingCapabilities maps the finding kinds to the capability their
+// principal gains on the affected hosts.
+var gpoFindingCapabilities = map[string]string{
+	FindingGPOEdit:          CapabilityGPOEdit,
+	FindingRestrictedGroups: CapabilityGPOLocalAdmin,
+	FindingScheduledTask:    CapabilityGPOScheduledTask,
+	FindingUserRights:       CapabilityGPOUserRight,
+}
+
+// gpoEditRights are the ACE rights that allow changing the settings of a GPO.
+var gpoEditRights = map[string]bool{
+	"GenericAll":   true,
+	"GenericWrite": true,
+	"WriteDacl":    true,
+	"WriteOwner":   true,
+	"Owns":         true,
+}
+
+// dangerousUserRights are the user rights that lead to SYSTEM on the host or
+// to the secrets stored on it.
+var dangerousUserRights = map[string]bool{
+	"sedebugprivilege":              true,
+	"sebackupprivilege":             true,
+	"serestoreprivilege":            true,
+	"setakeownershipprivilege":      true,
+	"seloaddriverprivilege":         true,
+	"seimpersonateprivilege":        true,
+	"seassignprimarytokenprivilege": true,
+	"setcbprivilege":                true,
+	"secreatetokenprivilege":        true,
+	"semanagevolumeprivilege":       true,
+	"seenabledelegationprivilege":   true,
+}
+
+// localAdminGroups are the names a restricted groups setting may use for the
+// local Administrators group.
+var localAdminGroups = map[string]bool{
+	"administrators":  true,
+	"administratoren": true,
+	"s-1-5-32-544":    true,
+}
+
+// EffectiveGPO is a GPO applying to a container. Precedence 1 wins over all
+// others.
+type EffectiveGPO struct {
+	GPO        *FindingNode `json:"gpo"`
+	LinkedAt   *FindingNode `json:"linked_at"`
+	LinkOrder  int          `json:"link_order"`
+	Enforced   bool         `json:"enforced"`
+	Precedence int          `json:"precedence"`
+}
+
+// ContainerPolicy is the effective policy of a Domain or DirectoryNode, which
+// the hosts and users directly in it receive.
+type ContainerPolicy struct {
+	Container         *FindingNode    `json:"container"`
+	Parent            *FindingNode    `json:"parent,omitempty"`
+	BlocksInheritance bool            `json:"blocks_inheritance"`
+	GPOs              []*EffectiveGPO `json:"gpos"`
+	Hosts             int             `json:"hosts"`
+	Users             int             `json:"users"`
+}
+
+// ObjectPolicy is the effective policy of one object of the project.
+type ObjectPolicy struct {
+	ProjectUID string          `json:"project_uid"`
+	Object     *FindingNode    `json:"object"`
+	Container  *FindingNode    `json:"container,omitempty"`
+	GPOs       []*EffectiveGPO `json:"gpos"`
+}
+
+// GPOFinding is one GPO an attacker can abuse, or that already hands the
+// principal control over the targets.
+type GPOFinding struct {
+	Kind              string         `json:"kind"`
+	GPO               *FindingNode   `json:"gpo"`
+	Principal         *FindingNode   `json:"principal,omitempty"`
+	Setting           string         `json:"setting,omitempty"`
+	Rights            []string       `json:"rights,omitempty"`
+	Targets           []*FindingNode `json:"targets"`
+	DomainControllers int            `json:"domain_controllers"`
+	Users             int            `json:"users"`
+	RiskLevel         int            `json:"risk_level"`
+	Explanation       string         `json:"explanation"`
+}
+
+// GPOAnalysis is the result of analysing a project: the effective policy of
+// every container, the findings, riskiest first, and, if capabilities were
+// derived, the derivation summary.
+type GPOAnalysis struct {
+	ProjectUID   string             `json:"project_uid"`
+	Containers   []*ContainerPolicy `json:"containers"`
+	Findings     []*GPOFinding      `json:"findings"`
+	Capabilities *DerivationSummary `json:"capabilities,omitempty"`
+}
+
+// -----------------------------------------------------------------------------
+// GPOAnalysisService
+// -----------------------------------------------------------------------------
+
+// GPOAnalysisService resolves which GPOs apply to the containers, hosts and
+// users of a project, honouring link order, enforced and disabled links and
+// blocked inheritance, and finds abusable GPOs: GPOs a principal may edit
+// and GPOs whose settings (restricted groups, scheduled tasks, user rights)
+// hand a principal control over the hosts they apply to. Each finding
+// becomes a capability of the principal on the affected hosts.
+type GPOAnalysisService struct {
+	db        *dgo.Dgraph
+	graphRepo engine2.ProjectGraphRepository
+	deriver   *capabilityDeriver
+}
+
+func NewGPOAnalysisService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*GPOAnalysisService, error) {
+	capabilityService, err := NewCapabilityService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, fmt.Errorf("error creating capability service in gpo analysis service: %v", err)
+	}
+
+	owned := make(map[string]bool, len(gpoFindingCapabilities))
+	for _, name := range gpoFindingCapabilities {
+		owned[name] = true
+	}
+
+	return &GPOAnalysisService{
+		db:        dgraphCon,
+		graphRepo: engine2.NewDgraphProjectGraphRepository(dgraphCon),
+		deriver: &capabilityDeriver{
+			db:                dgraphCon,
+			assertionRepo:     engine2.NewDgraphAssertionRepository(dgraphCon),
+			capabilityService: capabilityService,
+			owned:             owned,
+		},
+	}, nil
+}
+
+// Analyse returns the effective policies and GPO findings of a project
+// without changing anything.
+func (s *GPOAnalysisService) Analyse(ctx context.Context, projectUID string) (*GPOAnalysis, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newGPOResolver(graph)
+	findings, _ := r.findings()
+	return &GPOAnalysis{ProjectUID: projectUID, Containers: r.containerPolicies(), Findings: findings}, nil
+}
+
+// EffectivePolicy returns the GPOs applying to one Domain, DirectoryNode,
+// Host or User, in precedence order.
+func (s *GPOAnalysisService) EffectivePolicy(ctx context.Context, projectUID, objectUID string) (*ObjectPolicy, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	object := graph.Nodes[objectUID]
+	if object == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyObjectMissing, objectUID)
+	}
+
+	r := newGPOResolver(graph)
+	policy := &ObjectPolicy{ProjectUID: projectUID, Object: findingNode(object), GPOs: []*EffectiveGPO{}}
+
+	container := object
+	if !gpoContainerTypes[object.Type] {
+		container = r.parentOf(object.UID)
+		if container == nil {
+			return policy, nil
+		}
+		policy.Container = findingNode(container)
+	}
+	policy.GPOs = r.effective(container.UID)
+	return policy, nil
+}
+
+// DeriveProject analyses the project and brings the GPO capabilities in line
+// with the findings.
+func (s *GPOAnalysisService) DeriveProject(ctx context.Context, projectUID, actor string) (*GPOAnalysis, error) {
+	graph, err := loadProjectGraph(ctx, s.db, s.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newGPOResolver(graph)
+	findings, grants := r.findings()
+	summary := newDerivationSummary(projectUID)
+	summary.Evaluated = len(findings)
+	s.deriver.sync(ctx, graph, grants, summary, actor)
+
+	log.Printf("[%s] GPO analysis project=%s findings=%d derived=%d kept=%d invalidated=%d failed=%d",
+		actor, projectUID, len(findings), len(summary.Derived), summary.Kept, len(summary.Invalidated), summary.Failed)
+
+	return &GPOAnalysis{ProjectUID: projectUID, Containers: r.containerPolicies(), Findings: findings, Capabilities: summary}, nil
+}
+
+// ── Resolver ────────────────────────────────────────────────────────────────
+
+// gpoContainerTypes can link GPOs and hold objects.
+var gpoContainerTypes = map[string]bool{
+	"Domain":        true,
+	"DirectoryNode": true,
+}
+
+// gpoParentPredicates link a container to what sits in it.
+var gpoParentPredicates = map[string]bool{
+	string(core.PredicateContains): true,
+	string(core.PredicateParent):   true,
+	string(core.PredicateHasHost):  true,
+	string(core.PredicateHasUser):  true,
+}
+
+// gpoLinkEntry is an enabled or disabled link of a container.
+type gpoLinkEntry struct {
+	gpo       *engine2.GraphNode
+	order     int
+	enforced  bool
+	enabled   bool
+	container *engine2.GraphNode
+}
+
+type gpoResolver struct {
+	graph    *engine2.ProjectGraph
+	outgoing map[string][]*engine2.GraphEdge
+	// object or container → the container it sits in
+	parents map[string]*engine2.GraphNode
+	// container → its links by link order
+	links map[string][]*gpoLinkEntry
+	// container → resolved GPOs
+	resolved map[string][]*EffectiveGPO
+}
+
+func newGPOResolver(graph *engine2.ProjectGraph) *gpoResolver {
+	r := &gpoResolver{
+		graph:    graph,
+		outgoing: outgoingEdges(graph),
+		parents:  make(map[string]*engine2.GraphNode),
+		links:    make(map[string][]*gpoLinkEntry),
+		resolved: make(map[string][]*EffectiveGPO),
+	}
+	r.indexParents()
+	r.indexLinks()
+	return r
+}
+
+// indexParents places every node in its container. The deepest candidate
+// wins: a principal imported into an OU may also hang at its domain.
+func (r *gpoResolver) indexParents() {
+	for _, e := range r.graph.Edges {
+		if !gpoParentPredicates[e.Predicate] {
+			continue
+		}
+		parent, child := r.graph.Nodes[e.Subject], r.graph.Nodes[e.Object]
+		if parent == nil || child == nil || !gpoContainerTypes[parent.Type] || parent.UID == child.UID {
+			continue
+		}
+		if current := r.parents[child.UID]; current == nil || deeperContainer(parent, current) {
+			r.parents[child.UID] = parent
+		}
+	}
+}
+
+// deeperContainer tells whether a is the more specific container: OUs beat
+// domains, a longer distinguished name beats a shorter one.
+func deeperContainer(a, b *engine2.GraphNode) bool {
+	if a.Type != b.Type {
+		return a.Type == "DirectoryNode"
+	}
+	dnA, _ := a.Values["directory_node.distinguished_name"].(string)
+	dnB, _ := b.Values["directory_node.distinguished_name"].(string)
+	if len(dnA) != len(dnB) {
+		return len(dnA) > len(dnB)
+	}
+	return a.UID < b.UID
+}
+
+// indexLinks collects "container has_gpo_link GPOLink links_to GPO".
+func (r *gpoResolver) indexLinks() {
+	for _, e := range r.graph.Edges {
+		container, link := r.graph.Nodes[e.Subject], r.graph.Nodes[e.Object]
+		if e.Predicate != string(core.PredicateHasGPOLink) || container == nil || link == nil ||
+			!gpoContainerTypes[container.Type] || link.Type != "GPOLink" {
+			continue
+		}
+		for _, target := range r.outgoing[link.UID] {
+			policy := r.graph.Nodes[target.Object]
+			if target.Predicate != string(core.PredicateLinksTo) || policy == nil || policy.Type != "GPO" {
+				continue
+			}
+			order, _ := link.Values["gpo_link.link_order"].(float64)
+			enforced, _ := link.Values["gpo_link.is_enforced"].(bool)
+			// links stored before the state was kept count as enabled
+			enabled, known := link.Values["gpo_link.is_enabled"].(bool)
+			r.links[container.UID] = append(r.links[container.UID], &gpoLinkEntry{
+				gpo:       policy,
+				order:     int(order),
+				enforced:  enforced,
+				enabled:   enabled || !known,
+				container: container,
+			})
+		}
+	}
+	for _, links := range r.links {
+		sort.SliceStable(links, func(i, j int) bool {
+			if links[i].order != links[j].order {
+				return links[i].order < links[j].order
+			}
+			return links[i].gpo.UID < links[j].gpo.UID
+		})
+	}
+}
+
+func (r *gpoResolver) parentOf(uid string) *engine2.GraphNode {
+	return r.parents[uid]
+}
+
+// effective returns the GPOs applying to a container, resolved once.
+func (r *gpoResolver) effective(containerUID string) []*EffectiveGPO {
+	if gpos, ok := r.resolved[containerUID]; ok {
+		return gpos
+	}
+	gpos := r.resolve(containerUID)
+	r.resolved[containerUID] = gpos
+	return gpos
+}
+
+// chain returns the containers from the root (the domain) down to the
+// container itself.
+func (r *gpoResolver) chain(containerUID string) []*engine2.GraphNode {
+	var chain []*engine2.GraphNode
+	seen := map[string]bool{}
+	for current := r.graph.Nodes[containerUID]; current != nil && !seen[current.UID]; current = r.parents[current.UID] {
+		seen[current.UID] = true
+		chain = append(chain, current)
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// resolve computes the GPOs applying to a container like the client side
+// processing does: enforced links first, the higher the container the
+// stronger; then the inherited links, the nearer the container the stronger,
+// cut off above a container that blocks inheritance. Within a container the
+// lower link order wins. Disabled links do not apply, a GPO linked twice
+// counts with its strongest link.
+func (r *gpoResolver) resolve(containerUID string) []*EffectiveGPO {
+	chain := r.chain(containerUID)
+
+	var ordered []*gpoLinkEntry
+	for _, container := range chain {
+		for _, link := range r.links[container.UID] {
+			if link.enabled && link.enforced {
+				ordered = append(ordered, link)
+			}
+		}
+	}
+	blocked := false
+	for i := len(chain) - 1; i >= 0 && !blocked; i-- {
+		for _, link := range r.links[chain[i].UID] {
+			if link.enabled && !link.enforced {
+				ordered = append(ordered, link)
+			}
+		}
+		blocked, _ = chain[i].Values["directory_node.blocks_inheritance"].(bool)
+	}
+
+	gpos := []*EffectiveGPO{}
+	seen := map[string]bool{}
+	for _, link := range ordered {
+		if seen[link.gpo.UID] {
+			continue
+		}
+		seen[link.gpo.UID] = true
+		gpos = append(gpos, &EffectiveGPO{
+			GPO:        findingNode(link.gpo),
+			LinkedAt:   findingNode(link.container),
+			LinkOrder:  link.order,
+			Enforced:   link.enforced,
+			Precedence: len(gpos) + 1,
+		})
+	}
+	return gpos
+}
+
+// containerPolicies lists the effective policy of every container, domains
+// first, then by depth and name.
+func (r *gpoResolver) containerPolicies() []*ContainerPolicy {
+	hosts, users := map[string]int{}, map[string]int{}
+	for child, parent := range r.parents {
+		switch r.graph.Nodes[child].Type {
+		case "Host":
+			hosts[parent.UID]++
+		case "User":
+			users[parent.UID]++
+		}
+	}
+
+	type entry struct {
+		policy *ContainerPolicy
+		depth  int
+	}
+	var entries []entry
+	for _, n := range r.graph.Nodes {
+		if !gpoContainerTypes[n.Type] {
+			continue
+		}
+		blocks, _ := n.Values["directory_node.blocks_inheritance"].(bool)
+		policy := &ContainerPolicy{
+			Container:         findingNode(n),
+			BlocksInheritance: blocks,
+			GPOs:              r.effective(n.UID),
+			Hosts:             hosts[n.UID],
+			Users:             users[n.UID],
+		}
+		if parent := r.parents[n.UID]; parent != nil {
+			policy.Parent = findingNode(parent)
+		}
+		entries = append(entries, entry{policy, len(r.chain(n.UID))})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].depth != entries[j].depth {
+			return entries[i].depth < entries[j].depth
+		}
+		if entries[i].policy.Container.Label != entries[j].policy.Container.Label {
+			return entries[i].policy.Container.Label < entries[j].policy.Container.Label
+		}
+		return entries[i].policy.Container.UID < entries[j].policy.Container.UID
+	})
+
+	policies := make([]*ContainerPolicy, 0, len(entries))
+	for _, e := range entries {
+		policies = append(policies, e.policy)
+	}
+	return policies
+}
+
+// ── Findings ────────────────────────────────────────────────────────────────
+
+// gpoScope is what a GPO applies to.
+type gpoScope struct {
+	hosts []*engine2.GraphNode
+	dcs   int
+	users int
+}
+
+// scopes maps every GPO to the hosts and users it applies to.
+func (r *gpoResolver) scopes() map[string]*gpoScope {
+	scopes := make(map[string]*gpoScope)
+	objects := append(r.graph.NodesOfType("Host"), r.graph.NodesOfType("User")...)
+	sort.Slice(objects, func(i, j int) bool { return objects[i].UID < objects[j].UID })
+
+	for _, object := range objects {
+		container := r.parents[object.UID]
+		if container == nil {
+			continue
+		}
+		for _, applied := range r.effective(container.UID) {
+			scope := scopes[applied.GPO.UID]
+			if scope == nil {
+				scope = &gpoScope{}
+				scopes[applied.GPO.UID] = scope
+			}
+			if object.Type == "User" {
+				scope.users++
+				continue
+			}
+			scope.hosts = append(scope.hosts, object)
+			if dc, _ := object.Values["host.is_domain_controller"].(bool); dc {
+				scope.dcs++
+			}
+		}
+	}
+	return scopes
+}
+
+// findings computes the GPO findings of the graph and the capabilities they
+// grant on the affected hosts.
+func (r *gpoResolver) findings() ([]*GPOFinding, map[string]*derivedGrant) {
+	scopes := r.scopes()
+	grants := make(map[string]*derivedGrant)
+	var findings []*GPOFinding
+
+	add := func(kind string, policy, principal *engine2.GraphNode, setting string, rights []string, source *engine2.GraphNode, explanation string) {
+		scope := scopes[policy.UID]
+		risk := gpoRisks[kind].base
+		if scope.dcs > 0 {
+			risk = gpoRisks[kind].dc
+		}
+		finding := &GPOFinding{
+			Kind:              kind,
+			GPO:               findingNode(policy),
+			Setting:           setting,
+			Rights:            rights,
+			Targets:           make([]*FindingNode, 0, len(scope.hosts)),
+			DomainControllers: scope.dcs,
+			Users:             scope.users,
+			RiskLevel:         risk,
+			Explanation:       explanation,
+		}
+		if principal != nil {
+			finding.Principal = findingNode(principal)
+		}
+		for _, host := range scope.hosts {
+			finding.Targets = append(finding.Targets, findingNode(host))
+			if principal == nil || principal.UID == host.UID {
+				continue
+			}
+			hostRisk := gpoRisks[kind].base
+			if dc, _ := host.Values["host.is_domain_controller"].(bool); dc {
+				hostRisk = gpoRisks[kind].dc
+			}
+			reason := setting
+			if len(rights) > 0 {
+				reason = strings.Join(rights, "+")
+			}
+			grant := &derivedGrant{
+				name:         gpoFindingCapabilities[kind],
+				risk:         hostRisk,
+				scope:        engine.ScopeHost,
+				reason:       reason,
+				precondition: fmt.Sprintf("%s of %s on %s", gpoFindingCapabilities[kind], nodeLabel(policy), nodeLabel(host)),
+				principal:    principal.UID,
+				object:       host.UID,
+				objectType:   host.Type,
+				source:       source.UID,
+				sourceType:   source.Type,
+			}
+			if _, ok := grants[grant.key()]; !ok {
+				grants[grant.key()] = grant
+			}
+		}
+		findings = append(findings, finding)
+	}
+
+	// ── Principals allowed to edit a GPO ────────────────────────────────────
+	type editor struct {
+		principal, policy, ace *engine2.GraphNode
+		rights                 []string
+	}
+	editors := map[string]*editor{}
+	var order []string
+	forEachACE(r.graph, func(object, ace *engine2.GraphNode, right, principal string) {
+		if object.Type != "GPO" || !gpoEditRights[right] || scopes[object.UID] == nil {
+			return
+		}
+		key := principal + "|" + object.UID
+		e := editors[key]
+		if e == nil {
+			e = &editor{principal: r.graph.Nodes[principal], policy: object, ace: ace}
+			editors[key] = e
+			order = append(order, key)
+		}
+		if !slices.Contains(e.rights, right) {
+			e.rights = append(e.rights, right)
+		}
+	})
+	for _, key := range order {
+		e := editors[key]
+		sort.Strings(e.rights)
+		scope := scopes[e.policy.UID]
+		add(FindingGPOEdit, e.policy, e.principal, "", e.rights, e.ace,
+			fmt.Sprintf("%s may edit %s (%s), which applies to %d hosts (%d domain controllers) and %d users; a malicious setting runs code on all of them",
+				nodeLabel(e.principal), nodeLabel(e.policy), strings.Join(e.rights, ", "), len(scope.hosts), scope.dcs, scope.users))
+	}
+
+	// ── Dangerous settings ──────────────────────────────────────────────────
+	policies := r.graph.NodesOfType("GPO")
+	sort.Slice(policies, func(i, j int) bool { return policies[i].UID < policies[j].UID })
+	for _, policy := range policies {
+		scope := scopes[policy.UID]
+		if scope == nil || len(scope.hosts) == 0 {
+			continue
+		}
+		for _, contains := range r.outgoing[policy.UID] {
+			setting := r.graph.Nodes[contains.Object]
+			if contains.Predicate != string(core.PredicateContains) || setting == nil || setting.Type != "GPOSetting" {
+				continue
+			}
+			kind, describe := settingFinding(setting)
+			if kind == "" {
+				continue
+			}
+
+			var principals []*engine2.GraphNode
+			for _, granted := range r.outgoing[setting.UID] {
+				if granted.Predicate == string(core.PredicateGrantedTo) && r.graph.Nodes[granted.Object] != nil {
+					principals = append(principals, r.graph.Nodes[granted.Object])
+				}
+			}
+			value, _ := setting.Values["gpo_setting.value"].(string)
+			// a setting without a known principal is reported but grants nothing
+			if len(principals) == 0 {
+				add(kind, policy, nil, value, nil, setting,
+					fmt.Sprintf("%s %s on %d hosts (%d domain controllers)", nodeLabel(policy), describe(""), len(scope.hosts), scope.dcs))
+				continue
+			}
+			for _, principal := range principals {
+				add(kind, policy, principal, value, nil, setting,
+					fmt.Sprintf("%s %s on %d hosts (%d domain controllers)", nodeLabel(policy), describe(nodeLabel(principal)), len(scope.hosts), scope.dcs))
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].RiskLevel != findings[j].RiskLevel {
+			return findings[i].RiskLevel > findings[j].RiskLevel
+		}
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].GPO.Label < findings[j].GPO.Label
+	})
+	if findings == nil {
+		findings = []*GPOFinding{}
+	}
+	return findings, grants
+}
+
+// settingFinding classifies a GPOSetting. describe phrases what the setting
+// does for the principal ("" if unknown); settings that are not dangerous
+// return "".
+func settingFinding(setting *engine2.GraphNode) (string, func(principal string) string) {
+	settingType, _ := setting.Values["gpo_setting.setting_type"].(string)
+	value, _ := setting.Values["gpo_setting.value"].(string)
+
+	switch settingType {
+	case gpo.SettingRestrictedGroups:
+		group := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "*"))
+		if _, name, ok := strings.Cut(group, `\`); ok {
+			group = name
+		}
+		if !localAdminGroups[group] {
+			return "", nil
+		}
+		return FindingRestrictedGroups, func(principal string) string {
+			return fmt.Sprintf("adds %s to the local Administrators", cmp.Or(principal, "an unresolved principal"))
+		}
+	case gpo.SettingUserRightsAssignment:
+		if !dangerousUserRights[strings.ToLower(strings.TrimSpace(value))] {
+			return "", nil
+		}
+		return FindingUserRights, func(principal string) string {
+			return fmt.Sprintf("grants %s %s", cmp.Or(principal, "an unresolved principal"), value)
+		}
+	case gpo.SettingScheduledTask:
+		return FindingScheduledTask, func(principal string) string {
+			if principal == "" {
+				return fmt.Sprintf("runs the scheduled task %q", value)
+			}
+			return fmt.Sprintf("runs the scheduled task %q, controlled by %s,", value, principal)
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+// ProjectDerivation re-derives every family of derived capabilities of a
+// project after its graph changed (import, rollback). The steps run in a
+// fixed order; a new derivation is added here instead of in every caller.
+type ProjectDerivation struct {
+	steps []derivationStep
+}
+
+// derivationStep is one derivation of the project, e.g. the ACL derivation.
+type derivationStep struct {
+	name   string
+	derive func(ctx context.Context, projectUID, actor string) (*DerivationSummary, error)
+}
+
+func NewProjectDerivation(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*ProjectDerivation, error) {
+	aclDerivation, err := NewACLDerivationService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	delegations, err := NewDelegationAnalysisService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	gpoAnalysis, err := NewGPOAnalysisService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectDerivation{
+		steps: []derivationStep{
+			{name: "acl", derive: aclDerivation.DeriveProject},
+			{name: "delegations", derive: func(ctx context.Context, projectUID, actor string) (*DerivationSummary, error) {
+				analysis, err := delegations.DeriveProject(ctx, projectUID, actor)
+				if err != nil {
+					return nil, err
+				}
+				return analysis.Capabilities, nil
+			}},
+			{name: "gpos", derive: func(ctx context.Context, projectUID, actor string) (*DerivationSummary, error) {
+				analysis, err := gpoAnalysis.DeriveProject(ctx, projectUID, actor)
+				if err != nil {
+					return nil, err
+				}
+				return analysis.Capabilities, nil
+			}},
+		},
+	}, nil
+}
+
+// Run runs the derivations in order and returns the summaries of those that
+// succeeded. A failing step does not stop the following ones; the failures
+// are returned joined.
+func (d *ProjectDerivation) Run(ctx context.Context, projectUID, actor string) ([]*DerivationSummary, error) {
+	summaries := make([]*DerivationSummary, 0, len(d.steps))
+	var errs []error
+
+	for _, step := range d.steps {
+		summary, err := step.derive(ctx, projectUID, actor)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s derivation: %w", step.name, err))
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, errors.Join(errs...)
+}
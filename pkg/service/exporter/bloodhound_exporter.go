@@ -0,0 +1,250 @@
+package exporter
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/service/importer"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+const (
+	// BloodHoundLegacy is the SharpHound v6 collection format (ZIP with one
+	// JSON file per object type), ingestible by BloodHound CE and legacy.
+	BloodHoundLegacy = "legacy"
+	// BloodHoundOpenGraph is BloodHound CE's generic OpenGraph JSON format.
+	BloodHoundOpenGraph = "opengraph"
+
+	// bloodHoundVersion of the written collection files (see the importer)
+	bloodHoundVersion = 6
+)
+
+// BloodHoundExporter converts the project graph into BloodHound ingest files.
+type BloodHoundExporter struct {
+	dgraphCon *dgo.Dgraph
+	graphRepo engine.ProjectGraphRepository
+}
+
+func NewBloodHoundExporter(dgraphCon *dgo.Dgraph) *BloodHoundExporter {
+	return &BloodHoundExporter{
+		dgraphCon: dgraphCon,
+		graphRepo: engine.NewDgraphProjectGraphRepository(dgraphCon),
+	}
+}
+
+// Export renders the project in the given format (BloodHoundLegacy if empty).
+func (e *BloodHoundExporter) Export(ctx context.Context, projectUID, format string) (*Export, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = BloodHoundLegacy
+	}
+	if format != BloodHoundLegacy && format != BloodHoundOpenGraph {
+		return nil, fmt.Errorf("%w: %q (supported: %s, %s)", ErrUnknownFormat, format, BloodHoundLegacy, BloodHoundOpenGraph)
+	}
+
+	graph, err := loadProjectGraph(ctx, e.dgraphCon, e.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	bh := buildBloodHoundGraph(graph)
+	now := time.Now().UTC()
+
+	export := &Export{Stats: bh.stats}
+	if format == BloodHoundOpenGraph {
+		export.Content, err = writeOpenGraph(bh)
+		export.FileName = exportFileName(graph, "opengraph", now, ".json")
+		export.ContentType = "application/json"
+	} else {
+		export.Content, err = writeLegacyCollection(bh, now)
+		export.FileName = exportFileName(graph, "bloodhound", now, ".zip")
+		export.ContentType = "application/zip"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BloodHoundExport] project=%s format=%s nodes=%v edges=%d",
+		projectUID, format, bh.stats.Nodes, len(bh.edges))
+	return export, nil
+}
+
+// ── Legacy JSON ──────────────────────────────────────────────────────────────
+
+type bhLegacyObject struct {
+	ObjectIdentifier string                      `json:"ObjectIdentifier"`
+	Properties       map[string]interface{}      `json:"Properties"`
+	Aces             []importer.BloodHoundACE    `json:"Aces"`
+	IsDeleted        bool                        `json:"IsDeleted"`
+	IsACLProtected   bool                        `json:"IsACLProtected"`
+	ContainedBy      *importer.BloodHoundTypedID `json:"ContainedBy"`
+
+	Members      []importer.BloodHoundTypedID    `json:"Members,omitempty"`
+	ChildObjects []importer.BloodHoundTypedID    `json:"ChildObjects,omitempty"`
+	Links        []importer.BloodHoundGPOLink    `json:"Links,omitempty"`
+	Sessions     *importer.BloodHoundSessionList `json:"Sessions,omitempty"`
+	LocalGroups  []importer.BloodHoundLocalGrp   `json:"LocalGroups,omitempty"`
+}
+
+type bhLegacyFile struct {
+	Data []*bhLegacyObject `json:"data"`
+	Meta struct {
+		Methods int    `json:"methods"`
+		Type    string `json:"type"`
+		Count   int    `json:"count"`
+		Version int    `json:"version"`
+	} `json:"meta"`
+}
+
+// legacy file type per node kind, in SharpHound's order
+var bhLegacyTypes = []struct{ kind, fileType string }{
+	{bhDomain, "domains"},
+	{bhOU, "ous"},
+	{bhContainer, "containers"},
+	{bhGPO, "gpos"},
+	{bhGroup, "groups"},
+	{bhUser, "users"},
+	{bhComputer, "computers"},
+}
+
+func writeLegacyCollection(bh *bhGraph, at time.Time) ([]byte, error) {
+	files := make(map[string]*bhLegacyFile, len(bhLegacyTypes))
+	for _, t := range bhLegacyTypes {
+		f := &bhLegacyFile{Data: []*bhLegacyObject{}}
+		f.Meta.Type = t.fileType
+		f.Meta.Version = bloodHoundVersion
+		files[t.kind] = f
+	}
+
+	for _, n := range bh.nodes {
+		f := files[n.Kind]
+		f.Data = append(f.Data, legacyObject(n))
+		f.Meta.Count++
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	prefix := at.Format("20060102150405")
+	for _, t := range bhLegacyTypes {
+		w, err := zw.Create(fmt.Sprintf("%s_%s.json", prefix, t.fileType))
+		if err != nil {
+			return nil, fmt.Errorf("creating %s: %w", t.fileType, err)
+		}
+		if err := json.NewEncoder(w).Encode(files[t.kind]); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", t.fileType, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("closing zip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func legacyObject(n *bhNode) *bhLegacyObject {
+	obj := &bhLegacyObject{
+		ObjectIdentifier: n.ID,
+		Properties:       n.Properties,
+		Aces:             n.Aces,
+		ContainedBy:      typedID(n.ContainedBy),
+		Links:            n.Links,
+	}
+	if obj.Aces == nil {
+		obj.Aces = []importer.BloodHoundACE{}
+	}
+	for _, child := range n.Children {
+		obj.ChildObjects = append(obj.ChildObjects, *typedID(child))
+	}
+	for _, member := range n.Members {
+		obj.Members = append(obj.Members, *typedID(member))
+	}
+
+	if n.Kind == bhComputer {
+		sessions := &importer.BloodHoundSessionList{Collected: true, Results: []importer.BloodHoundSession{}}
+		for _, user := range n.Sessions {
+			sessions.Results = append(sessions.Results, importer.BloodHoundSession{UserSID: user.ID, ComputerSID: n.ID})
+		}
+		obj.Sessions = sessions
+
+		// v6 reports local admins as members of BUILTIN\Administrators (-544)
+		admins := importer.BloodHoundLocalGrp{
+			Name:             "ADMINISTRATORS@" + n.Name,
+			ObjectIdentifier: n.ID + "-544",
+			Collected:        true,
+			Results:          []importer.BloodHoundTypedID{},
+		}
+		for _, admin := range n.Admins {
+			admins.Results = append(admins.Results, *typedID(admin))
+		}
+		obj.LocalGroups = []importer.BloodHoundLocalGrp{admins}
+	}
+	return obj
+}
+
+func typedID(n *bhNode) *importer.BloodHoundTypedID {
+	if n == nil {
+		return nil
+	}
+	return &importer.BloodHoundTypedID{ObjectIdentifier: n.ID, ObjectType: n.Kind}
+}
+
+// ── OpenGraph ────────────────────────────────────────────────────────────────
+
+type ogNode struct {
+	ID         string                 `json:"id"`
+	Kinds      []string               `json:"kinds"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type ogEndpoint struct {
+	Value   string `json:"value"`
+	MatchBy string `json:"match_by"`
+}
+
+type ogEdge struct {
+	Kind       string                 `json:"kind"`
+	Start      ogEndpoint             `json:"start"`
+	End        ogEndpoint             `json:"end"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type ogFile struct {
+	Graph struct {
+		Nodes []ogNode `json:"nodes"`
+		Edges []ogEdge `json:"edges"`
+	} `json:"graph"`
+}
+
+func writeOpenGraph(bh *bhGraph) ([]byte, error) {
+	var file ogFile
+	file.Graph.Nodes = make([]ogNode, 0, len(bh.nodes))
+	file.Graph.Edges = make([]ogEdge, 0, len(bh.edges))
+
+	for _, n := range bh.nodes {
+		// "Base" lets BloodHound treat the nodes like collected AD objects
+		file.Graph.Nodes = append(file.Graph.Nodes, ogNode{
+			ID:         n.ID,
+			Kinds:      []string{n.Kind, "Base"},
+			Properties: n.Properties,
+		})
+	}
+	for _, e := range bh.edges {
+		file.Graph.Edges = append(file.Graph.Edges, ogEdge{
+			Kind:       e.Kind,
+			Start:      ogEndpoint{Value: e.Start.ID, MatchBy: "id"},
+			End:        ogEndpoint{Value: e.End.ID, MatchBy: "id"},
+			Properties: e.Properties,
+		})
+	}
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		return nil, fmt.Errorf("writing opengraph: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,414 @@
+package exporter
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/service/importer"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BloodHound node kinds
+const (
+	bhDomain    = "Domain"
+	bhOU        = "OU"
+	bhContainer = "Container"
+	bhGPO       = "GPO"
+	bhGroup     = "Group"
+	bhUser      = "User"
+	bhComputer  = "Computer"
+)
+
+// BloodHound edge kinds besides the ACE right names
+const (
+	bhContains   = "Contains"
+	bhMemberOf   = "MemberOf"
+	bhHasSession = "HasSession"
+	bhAdminTo    = "AdminTo"
+	bhGPLink     = "GPLink"
+)
+
+// bhNode is an exported object. The relation slices are the legacy JSON view
+// of the same data bhGraph.edges holds.
+type bhNode struct {
+	ID         string
+	Kind       string
+	UID        string
+	Name       string // without domain suffix
+	Domain     *bhNode
+	Properties map[string]interface{}
+
+	ContainedBy *bhNode
+	Children    []*bhNode
+	Members     []*bhNode
+	Sessions    []*bhNode
+	Admins      []*bhNode
+	Aces        []importer.BloodHoundACE
+	Links       []importer.BloodHoundGPOLink
+}
+
+type bhEdge struct {
+	Kind       string
+	Start      *bhNode
+	End        *bhNode
+	Properties map[string]interface{}
+}
+
+// bhGraph is the project graph flattened onto BloodHound's model: assertion
+// nodes, ACL/ACE and GPO link nodes disappear, their meaning becomes a native
+// edge between two objects.
+type bhGraph struct {
+	nodes []*bhNode
+	byUID map[string]*bhNode
+	edges []*bhEdge
+	stats *ExportStats
+}
+
+// containment predicates, placing an object in a domain / OU / container
+var bhContainment = map[string]bool{
+	string(core.PredicateContains): true,
+	string(core.PredicateHasHost):  true,
+	string(core.PredicateHasUser):  true,
+	string(core.PredicateHasGroup): true,
+}
+
+func buildBloodHoundGraph(graph *engine.ProjectGraph) *bhGraph {
+	g := &bhGraph{
+		byUID: make(map[string]*bhNode),
+		stats: newExportStats(),
+	}
+
+	out := make(map[string][]*engine.GraphEdge)
+	for _, e := range graph.Edges {
+		out[e.Subject] = append(out[e.Subject], e)
+	}
+
+	uids := make([]string, 0, len(graph.Nodes))
+	for uid := range graph.Nodes {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	ids := make(map[string]bool)
+	for _, uid := range uids {
+		node, err := newBHNode(graph.Nodes[uid])
+		if err != nil {
+			log.Printf("[BloodHoundExport] Skipping %s: %v", uid, err)
+			continue
+		}
+		if node == nil {
+			continue
+		}
+		// SIDs can collide for possible duplicates, BloodHound would merge them
+		if node.ID == "" || ids[node.ID] {
+			node.ID = generatedID(uid)
+		}
+		ids[node.ID] = true
+		g.nodes = append(g.nodes, node)
+		g.byUID[uid] = node
+	}
+
+	g.placeNodes(out)
+	g.addRelations(graph, out)
+	g.finishProperties()
+
+	for _, n := range g.nodes {
+		g.stats.Nodes[n.Kind]++
+	}
+	return g
+}
+
+func newBHNode(n *engine.GraphNode) (*bhNode, error) {
+	node := &bhNode{UID: n.UID, Properties: make(map[string]interface{})}
+	props := node.Properties
+	setString := func(key, value string) {
+		if value != "" {
+			props[key] = value
+		}
+	}
+	setTime := func(key string, t time.Time) {
+		if !t.IsZero() {
+			props[key] = t.Unix()
+		}
+	}
+
+	switch n.Type {
+	case "Domain":
+		d, err := decodeNode[rpad.Domain](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhDomain
+		node.ID = strings.ToUpper(d.DomainSID)
+		node.Name = strings.ToUpper(firstNonEmpty(d.DNSName, d.Name, d.NetBiosName))
+		setString("description", d.Description)
+		setString("functionallevel", d.DomainFunctionalLevel)
+
+	case "DirectoryNode":
+		dn, err := decodeNode[rpad.DirectoryNode](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhContainer
+		if dn.NodeType == rpad.DirectoryNodeTypeOU {
+			node.Kind = bhOU
+		}
+		node.Name = strings.ToUpper(dn.Name)
+		setString("description", dn.Description)
+		setString("distinguishedname", dn.DistinguishedName)
+
+	case "GPO":
+		p, err := decodeNode[gpo.GPO](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhGPO
+		node.Name = strings.ToUpper(p.Name)
+		setString("description", p.Description)
+
+	case "Group":
+		grp, err := decodeNode[rpad.Group](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhGroup
+		node.ID = strings.ToUpper(grp.SID)
+		node.Name = strings.ToUpper(grp.Name)
+		setString("description", grp.Description)
+		props["admincount"] = grp.IsPrivileged
+		props["highvalue"] = grp.IsPrivileged
+		if grp.RiskScore > 0 {
+			props["redpaths_risk_score"] = grp.RiskScore
+		}
+
+	case "User":
+		u, err := decodeNode[rpad.User](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhUser
+		node.ID = strings.ToUpper(u.SID)
+		node.Name = strings.ToUpper(firstNonEmpty(u.SAMAccountName, u.Name))
+		setString("description", u.Description)
+		setString("samaccountname", u.SAMAccountName)
+		setString("userprincipalname", u.UPN)
+		props["enabled"] = !u.IsDisabled
+		props["hasspn"] = u.HasSPN || u.Kerberoastable
+		props["dontreqpreauth"] = u.ASREPRoastable
+		props["admincount"] = u.IsDomainAdmin
+		setTime("pwdlastset", u.PwdLastSet)
+		setTime("lastlogontimestamp", u.LastLogon)
+		if u.RiskScore > 0 {
+			props["redpaths_risk_score"] = u.RiskScore
+		}
+
+	case "Host":
+		h, err := decodeNode[model.Host](n)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = bhComputer
+		node.Name = strings.ToUpper(firstNonEmpty(h.DNSHostName, h.Hostname, h.Name, h.IP))
+		setString("description", h.Description)
+		setString("distinguishedname", h.DistinguishedName)
+		setString("operatingsystem", strings.TrimSpace(h.OperatingSystem+" "+h.OperatingSystemVersion))
+		setString("redpaths_ip", h.IP)
+		props["isdc"] = h.IsDomainController
+		props["enabled"] = true
+
+	default:
+		// Project, ActiveDirectory, ACL/ACE, GPOLink, services, findings, ...
+		return nil, nil
+	}
+
+	props["redpaths_uid"] = n.UID
+	return node, nil
+}
+
+// placeNodes assigns every object its container (ContainedBy) and domain. A
+// DirectoryNode beats the domain as container, since principals are linked to
+// both when their OU is known.
+func (g *bhGraph) placeNodes(out map[string][]*engine.GraphEdge) {
+	for _, parent := range g.nodes {
+		if parent.Kind != bhDomain && parent.Kind != bhOU && parent.Kind != bhContainer {
+			continue
+		}
+		for _, e := range out[parent.UID] {
+			child := g.byUID[e.Object]
+			if !bhContainment[e.Predicate] || child == nil || child == parent || child.Kind == bhDomain {
+				continue
+			}
+			if child.ContainedBy == nil || (child.ContainedBy.Kind == bhDomain && parent.Kind != bhDomain) {
+				child.ContainedBy = parent
+			}
+		}
+	}
+
+	// Domain of an object = domain at the top of its container chain
+	for _, n := range g.nodes {
+		if n.Kind == bhDomain {
+			continue
+		}
+		seen := map[*bhNode]bool{n: true}
+		for p := n.ContainedBy; p != nil && !seen[p]; p = p.ContainedBy {
+			seen[p] = true
+			if p.Kind == bhDomain {
+				n.Domain = p
+				break
+			}
+		}
+	}
+
+	for _, n := range g.nodes {
+		if n.ContainedBy != nil {
+			n.ContainedBy.Children = append(n.ContainedBy.Children, n)
+			g.addEdge(bhContains, n.ContainedBy, n, nil)
+		}
+	}
+}
+
+func (g *bhGraph) addRelations(graph *engine.ProjectGraph, out map[string][]*engine.GraphEdge) {
+	for _, n := range g.nodes {
+		for _, e := range out[n.UID] {
+			target := g.byUID[e.Object]
+
+			switch core.Predicate(e.Predicate) {
+			case core.PredicateHasMember:
+				if n.Kind == bhGroup && target != nil {
+					n.Members = append(n.Members, target)
+					g.addEdge(bhMemberOf, target, n, nil)
+				}
+
+			case core.PredicateHasSession:
+				if n.Kind == bhComputer && target != nil && target.Kind == bhUser {
+					n.Sessions = append(n.Sessions, target)
+					g.addEdge(bhHasSession, n, target, nil)
+				}
+
+			case core.PredicateAdminTo:
+				if target != nil && target.Kind == bhComputer {
+					target.Admins = append(target.Admins, n)
+					g.addEdge(bhAdminTo, n, target, nil)
+				}
+
+			case core.PredicateHasACL:
+				g.addACEs(graph, out, n, e.Object)
+
+			case core.PredicateHasGPOLink:
+				g.addGPOLinks(graph, out, n, e.Object)
+			}
+		}
+	}
+}
+
+// addACEs flattens object -has_acl-> ACL -contains-> ACE -granted_to->
+// principal into "principal -[right]-> object".
+func (g *bhGraph) addACEs(graph *engine.ProjectGraph, out map[string][]*engine.GraphEdge, target *bhNode, aclUID string) {
+	for _, aceEdge := range out[aclUID] {
+		aceNode := graph.Nodes[aceEdge.Object]
+		if aceEdge.Predicate != string(core.PredicateContains) || aceNode == nil || aceNode.Type != "ACE" {
+			continue
+		}
+		ace, err := decodeNode[priv.ACE](aceNode)
+		if err != nil || ace.Name == "" || strings.EqualFold(ace.AccessType, "Deny") {
+			continue
+		}
+
+		for _, grant := range out[aceNode.UID] {
+			principal := g.byUID[grant.Object]
+			if grant.Predicate != string(core.PredicateGrantedTo) || principal == nil {
+				continue
+			}
+			target.Aces = append(target.Aces, importer.BloodHoundACE{
+				PrincipalSID:  principal.ID,
+				PrincipalType: principal.Kind,
+				RightName:     ace.Name,
+				IsInherited:   ace.Inherit,
+			})
+			g.addEdge(ace.Name, principal, target, map[string]interface{}{"isinherited": ace.Inherit})
+		}
+	}
+}
+
+// addGPOLinks flattens container -has_gpo_link-> GPOLink -links_to-> GPO into
+// "GPO -GPLink-> container".
+func (g *bhGraph) addGPOLinks(graph *engine.ProjectGraph, out map[string][]*engine.GraphEdge, target *bhNode, linkUID string) {
+	linkNode := graph.Nodes[linkUID]
+	if linkNode == nil || linkNode.Type != "GPOLink" {
+		return
+	}
+	link, err := decodeNode[gpo.Link](linkNode)
+	if err != nil {
+		return
+	}
+
+	for _, e := range out[linkUID] {
+		policy := g.byUID[e.Object]
+		if e.Predicate != string(core.PredicateLinksTo) || policy == nil || policy.Kind != bhGPO {
+			continue
+		}
+		target.Links = append(target.Links, importer.BloodHoundGPOLink{GUID: policy.ID, IsEnforced: link.IsEnforced})
+		g.addEdge(bhGPLink, policy, target, map[string]interface{}{"enforced": link.IsEnforced})
+	}
+}
+
+func (g *bhGraph) addEdge(kind string, start, end *bhNode, props map[string]interface{}) {
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	g.edges = append(g.edges, &bhEdge{Kind: kind, Start: start, End: end, Properties: props})
+	g.stats.Edges[kind]++
+}
+
+// finishProperties sets the properties that depend on the domain.
+func (g *bhGraph) finishProperties() {
+	for _, n := range g.nodes {
+		props := n.Properties
+		props["objectid"] = n.ID
+
+		domain := n
+		if n.Kind != bhDomain {
+			domain = n.Domain
+		}
+		if domain != nil {
+			props["domain"] = domain.Name
+			if domain.ID != generatedID(domain.UID) {
+				props["domainsid"] = domain.ID
+			}
+		}
+
+		// BloodHound names principals and containers NAME@DOMAIN
+		switch {
+		case n.Kind == bhDomain:
+			props["name"] = n.Name
+		case n.Kind == bhComputer && domain != nil && !strings.Contains(n.Name, "."):
+			props["name"] = n.Name + "." + domain.Name
+		case n.Kind != bhComputer && domain != nil:
+			props["name"] = n.Name + "@" + domain.Name
+		default:
+			props["name"] = n.Name
+		}
+	}
+}
+
+// generatedID identifies objects without SID. It is stable per RedPaths node,
+// so repeated exports update the same BloodHound objects.
+func generatedID(uid string) string {
+	return fmt.Sprintf("REDPATHS-%s", strings.ToUpper(uid))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("unknown export format")
+	ErrProjectMissing = errors.New("project not found")
+)
+
+// Export is a rendered export file.
+type Export struct {
+	FileName    string       `json:"file_name"`
+	ContentType string       `json:"content_type"`
+	Content     []byte       `json:"-"`
+	Stats       *ExportStats `json:"stats"`
+}
+
+// ExportStats counts the exported nodes and edges per kind.
+type ExportStats struct {
+	Nodes map[string]int `json:"nodes"`
+	Edges map[string]int `json:"edges"`
+}
+
+func newExportStats() *ExportStats {
+	return &ExportStats{
+		Nodes: make(map[string]int),
+		Edges: make(map[string]int),
+	}
+}
+
+// loadProjectGraph reads everything reachable from the project in one
+// read-only transaction, so the export is a consistent snapshot.
+func loadProjectGraph(ctx context.Context, dgraphCon *dgo.Dgraph, graphRepo engine.ProjectGraphRepository, projectUID string) (*engine.ProjectGraph, error) {
+	graph, err := db.ExecuteRead(ctx, dgraphCon, func(tx *dgo.Txn) (*engine.ProjectGraph, error) {
+		return graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if project := graph.Nodes[projectUID]; project == nil || project.Type != "Project" {
+		return nil, ErrProjectMissing
+	}
+	return graph, nil
+}
+
+// decodeNode maps the predicates of a graph node onto its model type.
+func decodeNode[T any](node *engine.GraphNode) (*T, error) {
+	values := make(map[string]interface{}, len(node.Values)+2)
+	for k, v := range node.Values {
+		values[k] = v
+	}
+	values["uid"] = node.UID
+	values["dgraph.type"] = []string{node.Type}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var entity T
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return nil, fmt.Errorf("decoding %s %s: %w", node.Type, node.UID, err)
+	}
+	return &entity, nil
+}
+
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportFileName builds "<project>_<suffix>_<timestamp><extension>".
+func exportFileName(graph *engine.ProjectGraph, suffix string, at time.Time, extension string) string {
+	name, _ := graph.Nodes[graph.ProjectUID].Values["project.name"].(string)
+	name = strings.Trim(fileNameUnsafe.ReplaceAllString(name, "_"), "_.")
+	if name == "" {
+		name = graph.ProjectUID
+	}
+	if len(name) > 60 {
+		name = name[:60]
+	}
+	return fmt.Sprintf("%s_%s_%s%s", name, suffix, at.Format("20060102-150405"), extension)
+}
@@ -0,0 +1,360 @@
+package exporter
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// edgeAttributes are the assertion fields every exported edge carries.
+var edgeAttributes = []struct{ name, kind string }{
+	{"predicate", "string"},
+	{"confidence", "double"},
+	{"status", "string"},
+	{"source", "string"},
+}
+
+func edgeAttributeValue(e *engine.GraphEdge, name string) string {
+	switch name {
+	case "predicate":
+		return e.Predicate
+	case "confidence":
+		return strconv.FormatFloat(e.Confidence, 'f', -1, 64)
+	case "status":
+		return e.Status
+	case "source":
+		return e.Source
+	}
+	return ""
+}
+
+func writeXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// ── GraphML ─────────────────────────────────────────────────────────────────
+
+type graphMLFile struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+func writeGraphML(eg *exportGraph) ([]byte, error) {
+	file := graphMLFile{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: eg.projectUID, EdgeDefault: "directed"},
+	}
+
+	// node keys: type and label first, then one per predicate
+	file.Keys = append(file.Keys,
+		graphMLKey{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+		graphMLKey{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+	)
+	names, kinds := nodeAttributes(eg.nodes)
+	keyIDs := make(map[string]string, len(names))
+	for i, name := range names {
+		keyIDs[name] = fmt.Sprintf("n%d", i)
+		file.Keys = append(file.Keys, graphMLKey{ID: keyIDs[name], For: "node", AttrName: name, AttrType: kinds[name]})
+	}
+	for _, attr := range edgeAttributes {
+		file.Keys = append(file.Keys, graphMLKey{ID: attr.name, For: "edge", AttrName: attr.name, AttrType: attr.kind})
+	}
+
+	for _, n := range eg.nodes {
+		node := graphMLNode{ID: n.UID, Data: []graphMLData{
+			{Key: "type", Value: n.Type},
+			{Key: "label", Value: nodeLabel(n)},
+		}}
+		for _, name := range names {
+			if v, ok := n.Values[name]; ok {
+				node.Data = append(node.Data, graphMLData{Key: keyIDs[name], Value: attributeValue(v)})
+			}
+		}
+		file.Graph.Nodes = append(file.Graph.Nodes, node)
+	}
+
+	for _, e := range eg.edges {
+		edge := graphMLEdge{ID: e.UID, Source: e.Subject, Target: e.Object}
+		for _, attr := range edgeAttributes {
+			edge.Data = append(edge.Data, graphMLData{Key: attr.name, Value: edgeAttributeValue(e, attr.name)})
+		}
+		file.Graph.Edges = append(file.Graph.Edges, edge)
+	}
+
+	return writeXML(file)
+}
+
+// ── GEXF ────────────────────────────────────────────────────────────────────
+
+type gexfFile struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+	LastModified string `xml:"lastmodifieddate,attr"`
+	Creator      string `xml:"creator"`
+	Description  string `xml:"description"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string      `xml:"id,attr"`
+	Label     string      `xml:"label,attr"`
+	AttValues []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string      `xml:"id,attr"`
+	Source    string      `xml:"source,attr"`
+	Target    string      `xml:"target,attr"`
+	Label     string      `xml:"label,attr"`
+	Weight    float64     `xml:"weight,attr,omitempty"`
+	AttValues []gexfValue `xml:"attvalues>attvalue"`
+}
+
+func writeGEXF(eg *exportGraph) ([]byte, error) {
+	file := gexfFile{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta: gexfMeta{
+			LastModified: eg.exportedAt.Format("2006-01-02"),
+			Creator:      "RedPaths",
+			Description:  "Project " + eg.projectUID,
+		},
+		Graph: gexfGraph{DefaultEdgeType: "directed", Mode: "static"},
+	}
+
+	nodeAttrs := gexfAttributes{Class: "node", Attributes: []gexfAttribute{{ID: "type", Title: "type", Type: "string"}}}
+	names, kinds := nodeAttributes(eg.nodes)
+	attrIDs := make(map[string]string, len(names))
+	for i, name := range names {
+		attrIDs[name] = fmt.Sprintf("n%d", i)
+		nodeAttrs.Attributes = append(nodeAttrs.Attributes, gexfAttribute{ID: attrIDs[name], Title: name, Type: kinds[name]})
+	}
+	edgeAttrs := gexfAttributes{Class: "edge"}
+	for _, attr := range edgeAttributes {
+		edgeAttrs.Attributes = append(edgeAttrs.Attributes, gexfAttribute{ID: attr.name, Title: attr.name, Type: attr.kind})
+	}
+	file.Graph.Attributes = []gexfAttributes{nodeAttrs, edgeAttrs}
+
+	for _, n := range eg.nodes {
+		node := gexfNode{ID: n.UID, Label: nodeLabel(n), AttValues: []gexfValue{{For: "type", Value: n.Type}}}
+		for _, name := range names {
+			if v, ok := n.Values[name]; ok {
+				node.AttValues = append(node.AttValues, gexfValue{For: attrIDs[name], Value: attributeValue(v)})
+			}
+		}
+		file.Graph.Nodes = append(file.Graph.Nodes, node)
+	}
+
+	for _, e := range eg.edges {
+		edge := gexfEdge{ID: e.UID, Source: e.Subject, Target: e.Object, Label: e.Predicate, Weight: e.Confidence}
+		for _, attr := range edgeAttributes {
+			edge.AttValues = append(edge.AttValues, gexfValue{For: attr.name, Value: edgeAttributeValue(e, attr.name)})
+		}
+		file.Graph.Edges = append(file.Graph.Edges, edge)
+	}
+
+	return writeXML(file)
+}
+
+// ── Cypher ──────────────────────────────────────────────────────────────────
+
+// cypherBaseLabel is set on every node, so relationships can MATCH by uid
+// through a single index.
+const cypherBaseLabel = "RedPaths"
+
+var cypherUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// writeCypher writes one statement per line: the uid index, the nodes, then
+// the relationships, which MATCH their ends by uid.
+func writeCypher(eg *exportGraph) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// RedPaths project %s, exported %s\n", eg.projectUID, eg.exportedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "CREATE INDEX redpaths_uid IF NOT EXISTS FOR (n:%s) ON (n.uid);\n\n", cypherBaseLabel)
+
+	for _, n := range eg.nodes {
+		props := map[string]interface{}{"uid": n.UID, "label": nodeLabel(n)}
+		for k, v := range n.Values {
+			props[k] = v
+		}
+		fmt.Fprintf(&b, "CREATE (:%s:%s %s);\n", cypherBaseLabel, cypherName(n.Type), cypherMap(props))
+	}
+	b.WriteString("\n")
+
+	for _, e := range eg.edges {
+		props := map[string]interface{}{
+			"uid":        e.UID,
+			"predicate":  e.Predicate,
+			"confidence": e.Confidence,
+			"status":     e.Status,
+			"source":     e.Source,
+		}
+		fmt.Fprintf(&b, "MATCH (a:%s {uid: %s}), (b:%s {uid: %s}) CREATE (a)-[:%s %s]->(b);\n",
+			cypherBaseLabel, cypherString(e.Subject), cypherBaseLabel, cypherString(e.Object),
+			strings.ToUpper(cypherName(e.Predicate)), cypherMap(props))
+	}
+
+	return []byte(b.String()), nil
+}
+
+// cypherName turns a type or predicate into a label / relationship type.
+func cypherName(name string) string {
+	name = strings.Trim(cypherUnsafe.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
+
+func cypherMap(props map[string]interface{}) string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if v := cypherValue(props[k]); v != "" {
+			parts = append(parts, fmt.Sprintf("`%s`: %s", strings.ReplaceAll(k, "`", "``"), v))
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// cypherValue renders a literal, "" for values Neo4j cannot store (null,
+// nested maps, mixed lists).
+func cypherValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return cypherString(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		kind := ""
+		for _, item := range val {
+			lit := cypherValue(item)
+			if lit == "" {
+				return ""
+			}
+			if k := fmt.Sprintf("%T", item); kind == "" {
+				kind = k
+			} else if kind != k {
+				return ""
+			}
+			items = append(items, lit)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return ""
+	}
+}
+
+func cypherString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,584 @@
+package exporter
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/repository/redpaths/modules"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	stixSpecVersion = "2.1"
+	stixTimeFormat  = "2006-01-02T15:04:05.000Z"
+)
+
+// stixNamespace is the namespace STIX 2.1 defines for deterministic SCO ids.
+// RedPaths derives its SDO ids from it as well, so repeated exports of a
+// project update the same objects in the receiving platform.
+var stixNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+type stixObject map[string]interface{}
+
+// StixExporter produces a STIX 2.1 bundle of a project: identities for the
+// project, infrastructure and observed-data for the discovered hosts and
+// services, vulnerabilities and one sighting of an ATT&CK attack-pattern per
+// executed module run.
+type StixExporter struct {
+	dgraphCon   *dgo.Dgraph
+	postgresCon *gorm.DB
+	graphRepo   engine.ProjectGraphRepository
+	moduleRepo  modules.RedPathsModuleRepository
+}
+
+func NewStixExporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) *StixExporter {
+	return &StixExporter{
+		dgraphCon:   dgraphCon,
+		postgresCon: postgresCon,
+		graphRepo:   engine.NewDgraphProjectGraphRepository(dgraphCon),
+		moduleRepo:  modules.NewPostgresRedPathsModuleRepository(),
+	}
+}
+
+type stixRunData struct {
+	runs    []*redpaths.ModuleRun
+	modules map[string]*redpaths.Module
+}
+
+func (e *StixExporter) Export(ctx context.Context, projectUID string) (*Export, error) {
+	graph, err := loadProjectGraph(ctx, e.dgraphCon, e.graphRepo, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	runData, err := db.ExecutePostgresRead(ctx, e.postgresCon, func(tx *gorm.DB) (*stixRunData, error) {
+		runs, err := e.moduleRepo.GetAllModuleRuns(ctx, tx, projectUID)
+		if err != nil {
+			return nil, err
+		}
+		all, err := e.moduleRepo.GetAll(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		data := &stixRunData{runs: runs, modules: make(map[string]*redpaths.Module, len(all))}
+		for _, m := range all {
+			data.modules[m.Key] = m
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading module runs: %w", err)
+	}
+
+	now := time.Now().UTC()
+	b := newStixBundle(graph, now)
+	b.addIdentities()
+	if err := b.addHosts(); err != nil {
+		return nil, err
+	}
+	if err := b.addVulnerabilities(); err != nil {
+		return nil, err
+	}
+	b.addRuns(runData.runs, runData.modules)
+
+	bundle := map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--" + uuid.NewString(),
+		"objects": b.objects,
+	}
+	content, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("writing stix bundle: %w", err)
+	}
+
+	log.Printf("[StixExport] project=%s objects=%v relations=%v", projectUID, b.stats.Nodes, b.stats.Edges)
+
+	return &Export{
+		FileName:    exportFileName(graph, "stix", now, ".json"),
+		ContentType: "application/stix+json;version=2.1",
+		Content:     content,
+		Stats:       b.stats,
+	}, nil
+}
+
+// ── Bundle ──────────────────────────────────────────────────────────────────
+
+type stixHost struct {
+	uid          string
+	ip           net.IP
+	infraID      string
+	observedID   string
+	observations []string // SCO ids
+}
+
+type stixBundle struct {
+	graph   *engine.ProjectGraph
+	ns      uuid.UUID
+	now     string
+	objects []stixObject
+	seen    map[string]bool
+	stats   *ExportStats
+
+	producerID string
+	projectID  string
+	hosts      map[string]*stixHost // by host uid
+	outgoing   map[string][]*engine.GraphEdge
+	patterns   map[string][]string // attack-pattern ids by module key
+}
+
+func newStixBundle(graph *engine.ProjectGraph, now time.Time) *stixBundle {
+	b := &stixBundle{
+		graph:    graph,
+		ns:       uuid.NewSHA1(stixNamespace, []byte("redpaths-project:"+graph.ProjectUID)),
+		now:      now.Format(stixTimeFormat),
+		seen:     make(map[string]bool),
+		stats:    newExportStats(),
+		hosts:    make(map[string]*stixHost),
+		outgoing: make(map[string][]*engine.GraphEdge),
+		patterns: make(map[string][]string),
+	}
+	for _, e := range graph.Edges {
+		b.outgoing[e.Subject] = append(b.outgoing[e.Subject], e)
+	}
+	return b
+}
+
+// sdo creates a domain object with an id unique within the project.
+func (b *stixBundle) sdo(stixType, key string) stixObject {
+	return b.sdoWithID(stixType, stixType+"--"+uuid.NewSHA1(b.ns, []byte(stixType+":"+key)).String())
+}
+
+// globalSDO creates a domain object with an id shared by all projects.
+func (b *stixBundle) globalSDO(stixType, key string) stixObject {
+	return b.sdoWithID(stixType, stixType+"--"+uuid.NewSHA1(stixNamespace, []byte(stixType+":"+key)).String())
+}
+
+func (b *stixBundle) sdoWithID(stixType, id string) stixObject {
+	obj := stixObject{
+		"type":         stixType,
+		"spec_version": stixSpecVersion,
+		"id":           id,
+		"created":      b.now,
+		"modified":     b.now,
+	}
+	if b.producerID != "" {
+		obj["created_by_ref"] = b.producerID
+	}
+	return obj
+}
+
+// sco creates a cyber observable, its id derives from the id contributing
+// properties as the spec requires.
+func (b *stixBundle) sco(stixType string, idProps map[string]interface{}) stixObject {
+	key, _ := json.Marshal(idProps)
+	obj := stixObject{
+		"type":         stixType,
+		"spec_version": stixSpecVersion,
+		"id":           stixType + "--" + uuid.NewSHA1(stixNamespace, key).String(),
+	}
+	for k, v := range idProps {
+		obj[k] = v
+	}
+	return obj
+}
+
+// add appends the object once and returns its id.
+func (b *stixBundle) add(obj stixObject) string {
+	id := obj["id"].(string)
+	if b.seen[id] {
+		return id
+	}
+	b.seen[id] = true
+	b.objects = append(b.objects, obj)
+
+	stixType := obj["type"].(string)
+	switch stixType {
+	case "relationship":
+		b.stats.Edges[obj["relationship_type"].(string)]++
+	case "sighting":
+		b.stats.Edges[stixType]++
+	default:
+		b.stats.Nodes[stixType]++
+	}
+	return id
+}
+
+func (b *stixBundle) relate(sourceID, relType, targetID string) {
+	rel := b.sdo("relationship", sourceID+"|"+relType+"|"+targetID)
+	rel["relationship_type"] = relType
+	rel["source_ref"] = sourceID
+	rel["target_ref"] = targetID
+	b.add(rel)
+}
+
+// ── Identities ──────────────────────────────────────────────────────────────
+
+func (b *stixBundle) addIdentities() {
+	producer := b.globalSDO("identity", "RedPaths")
+	producer["name"] = "RedPaths"
+	producer["identity_class"] = "system"
+	b.producerID = b.add(producer)
+
+	values := b.graph.Nodes[b.graph.ProjectUID].Values
+	project := b.sdo("identity", "project")
+	project["name"] = firstNonEmpty(stringValue(values, "project.name"), b.graph.ProjectUID)
+	project["identity_class"] = "organization"
+	if desc := stringValue(values, "project.description"); desc != "" {
+		project["description"] = desc
+	}
+	project["x_redpaths_project_uid"] = b.graph.ProjectUID
+	b.projectID = b.add(project)
+}
+
+// ── Hosts and services ──────────────────────────────────────────────────────
+
+var stixProtocolName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func (b *stixBundle) addHosts() error {
+	nodes := b.graph.NodesOfType("Host")
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].UID < nodes[j].UID })
+
+	for _, n := range nodes {
+		h, err := decodeNode[model.Host](n)
+		if err != nil {
+			return err
+		}
+		host := &stixHost{uid: n.UID}
+
+		// the address or the name is the destination of the services
+		var dstRef string
+		if ip := net.ParseIP(strings.TrimSpace(h.IP)); ip != nil {
+			host.ip = ip
+			addrType := "ipv6-addr"
+			if ip.To4() != nil {
+				addrType = "ipv4-addr"
+			}
+			dstRef = b.add(b.sco(addrType, map[string]interface{}{"value": ip.String()}))
+			host.observations = append(host.observations, dstRef)
+		}
+		if name := strings.ToLower(firstNonEmpty(h.DNSHostName, h.Hostname)); name != "" {
+			nameRef := b.add(b.sco("domain-name", map[string]interface{}{"value": name}))
+			host.observations = append(host.observations, nameRef)
+			if dstRef == "" {
+				dstRef = nameRef
+			}
+		}
+		if h.OperatingSystem != "" {
+			props := map[string]interface{}{"name": h.OperatingSystem}
+			if h.OperatingSystemVersion != "" {
+				props["version"] = h.OperatingSystemVersion
+			}
+			host.observations = append(host.observations, b.add(b.sco("software", props)))
+		}
+
+		for _, e := range b.outgoing[n.UID] {
+			if e.Predicate != string(core.PredicateRuns) || dstRef == "" {
+				continue
+			}
+			svcNode := b.graph.Nodes[e.Object]
+			if svcNode == nil || svcNode.Type != "Service" {
+				continue
+			}
+			svc, err := decodeNode[model.Service](svcNode)
+			if err != nil {
+				return err
+			}
+			port, err := strconv.Atoi(strings.TrimSpace(svc.Port))
+			if err != nil || port <= 0 || port > 65535 {
+				continue
+			}
+			protocols := []interface{}{"tcp"}
+			if name := strings.ToLower(svc.Name); stixProtocolName.MatchString(name) && name != "tcp" {
+				protocols = append(protocols, name)
+			}
+			traffic := b.sco("network-traffic", map[string]interface{}{
+				"dst_ref":   dstRef,
+				"dst_port":  port,
+				"protocols": protocols,
+			})
+			host.observations = append(host.observations, b.add(traffic))
+		}
+
+		firstSeen, lastSeen := b.seenRange(n)
+
+		infra := b.sdo("infrastructure", n.UID)
+		infra["name"] = firstNonEmpty(h.DNSHostName, h.Hostname, h.Name, h.IP, n.UID)
+		infra["infrastructure_types"] = []string{"unknown"}
+		if desc := strings.TrimSpace(h.OperatingSystem + " " + h.OperatingSystemVersion); desc != "" {
+			infra["description"] = desc
+		}
+		infra["first_seen"] = firstSeen
+		infra["last_seen"] = lastSeen
+		infra["x_redpaths_uid"] = n.UID
+		infra["x_redpaths_is_domain_controller"] = h.IsDomainController
+		host.infraID = b.add(infra)
+
+		if len(host.observations) > 0 {
+			observed := b.sdo("observed-data", n.UID)
+			observed["first_observed"] = firstSeen
+			observed["last_observed"] = lastSeen
+			observed["number_observed"] = 1
+			observed["object_refs"] = host.observations
+			host.observedID = b.add(observed)
+
+			for _, ref := range host.observations {
+				b.relate(host.infraID, "consists-of", ref)
+			}
+		}
+
+		b.hosts[n.UID] = host
+	}
+	return nil
+}
+
+// seenRange returns first / last seen of a node, the export time if unknown.
+func (b *stixBundle) seenRange(n *engine.GraphNode) (string, string) {
+	first := parseStixTime(firstNonEmpty(stringValue(n.Values, "discovered_at"), stringValue(n.Values, "created_at")))
+	last := parseStixTime(firstNonEmpty(stringValue(n.Values, "last_seen_at"), stringValue(n.Values, "modified_at")))
+	switch {
+	case first.IsZero() && last.IsZero():
+		return b.now, b.now
+	case first.IsZero():
+		first = last
+	case last.IsZero() || last.Before(first):
+		last = first
+	}
+	return first.Format(stixTimeFormat), last.Format(stixTimeFormat)
+}
+
+func parseStixTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// ── Findings ────────────────────────────────────────────────────────────────
+
+func (b *stixBundle) addVulnerabilities() error {
+	// services are attributed to the host running them
+	serviceHost := make(map[string]string)
+	for _, e := range b.graph.Edges {
+		if e.Predicate == string(core.PredicateRuns) {
+			serviceHost[e.Object] = e.Subject
+		}
+	}
+
+	nodes := b.graph.NodesOfType("Vulnerability")
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].UID < nodes[j].UID })
+	vulnIDs := make(map[string]string, len(nodes))
+
+	for _, n := range nodes {
+		v, err := decodeNode[model.Vulnerability](n)
+		if err != nil {
+			return err
+		}
+		vuln := b.sdo("vulnerability", n.UID)
+		vuln["name"] = firstNonEmpty(v.Name, firstOf(v.CVEs), n.UID)
+		if v.Description != "" {
+			vuln["description"] = v.Description
+		}
+		var refs []map[string]string
+		for _, cve := range v.CVEs {
+			refs = append(refs, map[string]string{"source_name": "cve", "external_id": cve})
+		}
+		if len(refs) > 0 {
+			vuln["external_references"] = refs
+		}
+		vuln["x_redpaths_uid"] = n.UID
+		if v.Severity != "" {
+			vuln["x_redpaths_severity"] = v.Severity
+		}
+		if v.CVSS > 0 {
+			vuln["x_redpaths_cvss"] = v.CVSS
+		}
+		vulnIDs[n.UID] = b.add(vuln)
+	}
+
+	for _, e := range b.graph.Edges {
+		vulnID := vulnIDs[e.Object]
+		if vulnID == "" || e.Predicate != string(core.PredicateHasVulnerability) {
+			continue
+		}
+		hostUID := e.Subject
+		if owner, ok := serviceHost[hostUID]; ok {
+			hostUID = owner
+		}
+		if host := b.hosts[hostUID]; host != nil {
+			b.relate(host.infraID, "has", vulnID)
+		}
+	}
+	return nil
+}
+
+// ── Module runs ─────────────────────────────────────────────────────────────
+
+func (b *stixBundle) addRuns(runs []*redpaths.ModuleRun, modules map[string]*redpaths.Module) {
+	sort.Slice(runs, func(i, j int) bool { return runs[i].RanAt.Before(runs[j].RanAt) })
+
+	for _, run := range runs {
+		patterns := b.attackPatterns(run.ModuleKey, modules[run.ModuleKey])
+		observed := b.targetObservations(run.Targets)
+		ranAt := run.RanAt.UTC().Format(stixTimeFormat)
+
+		for _, patternID := range patterns {
+			sighting := b.sdo("sighting", run.RunUID+"|"+patternID)
+			sighting["sighting_of_ref"] = patternID
+			sighting["first_seen"] = ranAt
+			sighting["last_seen"] = ranAt
+			sighting["count"] = 1
+			sighting["where_sighted_refs"] = []string{b.projectID}
+			if len(observed) > 0 {
+				sighting["observed_data_refs"] = observed
+			}
+			sighting["x_redpaths_run_uid"] = run.RunUID
+			sighting["x_redpaths_module_key"] = run.ModuleKey
+			sighting["x_redpaths_successful"] = run.WasSuccessful
+			b.add(sighting)
+		}
+	}
+}
+
+// attackPatterns returns the attack-patterns of a module: one per ATT&CK
+// technique, or a module specific one if the module maps to none.
+func (b *stixBundle) attackPatterns(moduleKey string, module *redpaths.Module) []string {
+	if ids, ok := b.patterns[moduleKey]; ok {
+		return ids
+	}
+
+	var ids []string
+	if module != nil {
+		for _, technique := range module.MitreTechniques {
+			pattern := b.globalSDO("attack-pattern", technique)
+			pattern["name"] = firstNonEmpty(mitreTechniqueNames[technique], technique)
+			pattern["external_references"] = []map[string]string{{
+				"source_name": "mitre-attack",
+				"external_id": technique,
+				"url":         redpaths.MitreTechniqueURL(technique),
+			}}
+			ids = append(ids, b.add(pattern))
+		}
+	}
+
+	if len(ids) == 0 {
+		pattern := b.sdo("attack-pattern", "module:"+moduleKey)
+		pattern["name"] = moduleKey
+		if module != nil {
+			pattern["name"] = firstNonEmpty(module.Name, moduleKey)
+			if module.Description != "" {
+				pattern["description"] = module.Description
+			}
+			if module.AttackID != "" {
+				pattern["external_references"] = []map[string]string{{
+					"source_name": "redpaths",
+					"external_id": module.AttackID,
+				}}
+			}
+		}
+		pattern["x_redpaths_module_key"] = moduleKey
+		ids = append(ids, b.add(pattern))
+	}
+
+	b.patterns[moduleKey] = ids
+	return ids
+}
+
+// targetObservations returns the observed-data of the hosts inside the run
+// targets (single IPs or CIDR ranges).
+func (b *stixBundle) targetObservations(targets []model.Target) []string {
+	var networks []*net.IPNet
+	for _, t := range targets {
+		ip := strings.TrimSpace(t.IP)
+		if ip == "" {
+			continue
+		}
+		if !strings.Contains(ip, "/") {
+			bits := t.CIDR
+			if bits <= 0 {
+				bits = 128
+				if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+					bits = 32
+				}
+			}
+			ip = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		if _, network, err := net.ParseCIDR(ip); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	var refs []string
+	uids := make([]string, 0, len(b.hosts))
+	for uid := range b.hosts {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	for _, uid := range uids {
+		host := b.hosts[uid]
+		if host.ip == nil || host.observedID == "" {
+			continue
+		}
+		for _, network := range networks {
+			if network.Contains(host.ip) {
+				refs = append(refs, host.observedID)
+				break
+			}
+		}
+	}
+	return refs
+}
+
+func stringValue(values map[string]interface{}, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// mitreTechniqueNames names the ATT&CK techniques relevant for AD
+// engagements; unknown techniques are exported with their ID as name.
+var mitreTechniqueNames = map[string]string{
+	"T1018":     "Remote System Discovery",
+	"T1046":     "Network Service Discovery",
+	"T1068":     "Exploitation for Privilege Escalation",
+	"T1069.002": "Permission Groups Discovery: Domain Groups",
+	"T1087.002": "Account Discovery: Domain Account",
+	"T1110.003": "Brute Force: Password Spraying",
+	"T1135":     "Network Share Discovery",
+	"T1187":     "Forced Authentication",
+	"T1201":     "Password Policy Discovery",
+	"T1207":     "Rogue Domain Controller",
+	"T1210":     "Exploitation of Remote Services",
+	"T1482":     "Domain Trust Discovery",
+	"T1484.001": "Domain or Tenant Policy Modification: Group Policy Modification",
+	"T1550.002": "Use Alternate Authentication Material: Pass the Hash",
+	"T1550.003": "Use Alternate Authentication Material: Pass the Ticket",
+	"T1557.001": "Adversary-in-the-Middle: LLMNR/NBT-NS Poisoning and SMB Relay",
+	"T1558.001": "Steal or Forge Kerberos Tickets: Golden Ticket",
+	"T1558.003": "Steal or Forge Kerberos Tickets: Kerberoasting",
+	"T1558.004": "Steal or Forge Kerberos Tickets: AS-REP Roasting",
+	"T1590.002": "Gather Victim Network Information: DNS",
+	"T1615":     "Group Policy Discovery",
+	"T1003.006": "OS Credential Dumping: DCSync",
+}
@@ -0,0 +1,295 @@
+package exporter
+
+import (
+	"RedPaths-server/pkg/model/core"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+
+	csvContentType  = "text/csv; charset=utf-8"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// TableFormat picks the tabular format of a catalog view from ?format= or,
+// if absent, from the Accept header. An empty result means JSON.
+func TableFormat(format, accept string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case CSV, XLSX:
+		return f, nil
+	case "json":
+		return "", nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w: %q (supported: json, %s, %s)", ErrUnknownFormat, format, CSV, XLSX)
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return CSV, nil
+		case xlsxContentType:
+			return XLSX, nil
+		case "application/json":
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// TableContentType returns the MIME type of a tabular format.
+func TableContentType(format string) string {
+	if format == XLSX {
+		return xlsxContentType
+	}
+	return csvContentType
+}
+
+// TableFileName builds "<view>_<timestamp>.<format>".
+func TableFileName(view, format string, at time.Time) string {
+	view = strings.Trim(fileNameUnsafe.ReplaceAllString(view, "_"), "_.")
+	return fmt.Sprintf("%s_%s.%s", view, at.Format("20060102-150405"), format)
+}
+
+// ── Table ───────────────────────────────────────────────────────────────────
+
+// assertionColumns carry the metadata of the assertion placing an entity in
+// the catalog, metadataColumns the discovery metadata of the entity itself.
+var (
+	assertionColumns = []string{
+		"assertion.predicate",
+		"assertion.status",
+		"assertion.confidence",
+		"assertion.source",
+		"assertion.method",
+		"assertion.timestamp",
+		"assertion.high_value_marked",
+		"assertion_count",
+	}
+	metadataColumns = []string{
+		"discovered_by",
+		"discovered_at",
+		"last_seen_by",
+		"last_seen_at",
+	}
+)
+
+// Table is a catalog view flattened to one row per entity. Rows are
+// rendered while writing, so a sheet is never held twice in memory.
+type Table struct {
+	Name    string
+	Columns []string
+	rows    []tableRow
+}
+
+type tableRow struct {
+	entity     map[string]interface{}
+	assertions []*core.Assertion
+}
+
+// NewTable flattens the JSON form of a catalog result. Entity results
+// ({entity, assertions}) yield the entity fields plus the metadata of their
+// primary assertion, any other list element is exported as is.
+func NewTable(name string, result interface{}) (*Table, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		// single object views (e.g. project) become a one row table
+		elements = []json.RawMessage{data}
+	}
+
+	t := &Table{Name: name}
+	fields := make(map[string]bool)
+	hasAssertions := false
+
+	for _, raw := range elements {
+		var element map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &element); err != nil || element == nil {
+			continue
+		}
+
+		row := tableRow{}
+		entityRaw, isResult := element["entity"]
+		if !isResult {
+			entityRaw = raw
+		}
+		if err := decodeTableValues(entityRaw, &row.entity); err != nil {
+			return nil, fmt.Errorf("decoding %s row: %w", name, err)
+		}
+		if assertionsRaw, ok := element["assertions"]; isResult && ok {
+			hasAssertions = true
+			if err := json.Unmarshal(assertionsRaw, &row.assertions); err != nil {
+				return nil, fmt.Errorf("decoding %s assertions: %w", name, err)
+			}
+		}
+
+		for key := range row.entity {
+			fields[key] = true
+		}
+		t.rows = append(t.rows, row)
+	}
+
+	t.Columns = tableColumns(fields, hasAssertions)
+	return t, nil
+}
+
+func decodeTableValues(raw json.RawMessage, values *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(values)
+}
+
+// tableColumns orders uid and type first, then the entity fields, then the
+// assertion and discovery metadata.
+func tableColumns(fields map[string]bool, hasAssertions bool) []string {
+	columns := []string{"uid", "dgraph.type"}
+
+	fixed := map[string]bool{"uid": true, "dgraph.type": true}
+	for _, c := range metadataColumns {
+		fixed[c] = true
+	}
+
+	var entityColumns []string
+	for field := range fields {
+		if !fixed[field] {
+			entityColumns = append(entityColumns, field)
+		}
+	}
+	sort.Strings(entityColumns)
+	columns = append(columns, entityColumns...)
+
+	if hasAssertions {
+		columns = append(columns, assertionColumns...)
+	}
+	return append(columns, metadataColumns...)
+}
+
+// Len returns the number of data rows.
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// cells renders row i in column order.
+func (t *Table) cells(i int) []string {
+	row := t.rows[i]
+	primary := primaryAssertion(row.assertions)
+
+	cells := make([]string, len(t.Columns))
+	for c, column := range t.Columns {
+		if strings.HasPrefix(column, "assertion.") || column == "assertion_count" {
+			cells[c] = assertionCell(primary, column, len(row.assertions))
+			continue
+		}
+		if v, ok := row.entity[column]; ok {
+			cells[c] = cellValue(v)
+		}
+	}
+	return cells
+}
+
+// primaryAssertion is the most confident assertion that is still in force.
+func primaryAssertion(assertions []*core.Assertion) *core.Assertion {
+	var primary *core.Assertion
+	for _, a := range assertions {
+		if a == nil {
+			continue
+		}
+		inactive := a.Status == core.StatusInvalidated || a.Status == core.StatusExpired
+		if primary != nil {
+			primaryInactive := primary.Status == core.StatusInvalidated || primary.Status == core.StatusExpired
+			if inactive && !primaryInactive {
+				continue
+			}
+			if inactive == primaryInactive && a.Confidence <= primary.Confidence {
+				continue
+			}
+		}
+		primary = a
+	}
+	return primary
+}
+
+func assertionCell(a *core.Assertion, column string, count int) string {
+	if column == "assertion_count" {
+		return fmt.Sprint(count)
+	}
+	if a == nil {
+		return ""
+	}
+	switch column {
+	case "assertion.predicate":
+		return string(a.Predicate)
+	case "assertion.status":
+		return string(a.Status)
+	case "assertion.confidence":
+		return fmt.Sprint(a.Confidence)
+	case "assertion.source":
+		return a.Source
+	case "assertion.method":
+		return string(a.Method)
+	case "assertion.timestamp":
+		if a.Timestamp.IsZero() {
+			return ""
+		}
+		return a.Timestamp.UTC().Format(time.RFC3339)
+	case "assertion.high_value_marked":
+		return fmt.Sprint(a.MarkedAsHighValue)
+	}
+	return ""
+}
+
+// cellValue renders lists joined by "; " and nested objects as JSON. Unset
+// timestamps stay empty.
+func cellValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if strings.HasPrefix(val, "0001-01-01T") {
+			return ""
+		}
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return fmt.Sprint(val)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			parts = append(parts, cellValue(item))
+		}
+		return strings.Join(parts, "; ")
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// WriteTable streams the table to w as CSV or XLSX.
+func WriteTable(w io.Writer, format string, t *Table) error {
+	switch format {
+	case CSV:
+		return writeTableCSV(w, t)
+	case XLSX:
+		return writeTableXLSX(w, t)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
@@ -0,0 +1,210 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tableFlushRows is the number of rows after which the output is flushed to
+// the client, so large catalogs start downloading right away.
+const tableFlushRows = 1000
+
+// flusher is implemented by http.ResponseWriter (and gin's writer).
+type flusher interface {
+	Flush()
+}
+
+func flushOutput(w io.Writer) {
+	if f, ok := w.(flusher); ok {
+		f.Flush()
+	}
+}
+
+// ── CSV ─────────────────────────────────────────────────────────────────────
+
+func writeTableCSV(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	for i := 0; i < t.Len(); i++ {
+		cells := t.cells(i)
+		for c, cell := range cells {
+			cells[c] = csvSafe(cell)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+		if (i+1)%tableFlushRows == 0 {
+			cw.Flush()
+			flushOutput(w)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe keeps spreadsheet applications from evaluating cells as formulas.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// ── XLSX ────────────────────────────────────────────────────────────────────
+
+// xlsxMaxCell is the maximum number of characters Excel accepts in a cell.
+const xlsxMaxCell = 32767
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+	// style 1 is the bold header row
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+)
+
+// writeTableXLSX writes a single sheet workbook with inline strings, so rows
+// go straight into the zip stream without a shared string table.
+func writeTableXLSX(w io.Writer, t *Table) error {
+	zw := zip.NewWriter(w)
+
+	static := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(xlsxSheetName(t.Name)))},
+	}
+	for _, f := range static {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(fw)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sheet.WriteString(`<sheetData>`)
+
+	writeXLSXRow(sheet, 1, t.Columns, 1)
+	for i := 0; i < t.Len(); i++ {
+		writeXLSXRow(sheet, i+2, t.cells(i), 0)
+		if (i+1)%tableFlushRows == 0 {
+			if err := sheet.Flush(); err != nil {
+				return err
+			}
+			if err := zw.Flush(); err != nil {
+				return err
+			}
+			flushOutput(w)
+		}
+	}
+
+	sheet.WriteString(`</sheetData>`)
+	if len(t.Columns) > 0 {
+		fmt.Fprintf(sheet, `<autoFilter ref="A1:%s%d"/>`, xlsxColumn(len(t.Columns)-1), t.Len()+1)
+	}
+	sheet.WriteString(`</worksheet>`)
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeXLSXRow(w *bufio.Writer, row int, cells []string, style int) {
+	fmt.Fprintf(w, `<row r="%d">`, row)
+	for c, cell := range cells {
+		if cell == "" {
+			continue
+		}
+		if len(cell) > xlsxMaxCell {
+			cell = cell[:xlsxMaxCell]
+		}
+		fmt.Fprintf(w, `<c r="%s%d" t="inlineStr"`, xlsxColumn(c), row)
+		if style > 0 {
+			fmt.Fprintf(w, ` s="%d"`, style)
+		}
+		w.WriteString(`><is><t xml:space="preserve">`)
+		w.WriteString(xmlEscape(cell))
+		w.WriteString(`</t></is></c>`)
+	}
+	w.WriteString(`</row>`)
+}
+
+// xlsxColumn converts a zero based index to the column letters (0 → A, 26 → AA).
+func xlsxColumn(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName strips the characters Excel forbids and caps the length.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "Sheet1"
+	}
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
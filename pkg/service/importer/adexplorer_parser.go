@@ -0,0 +1,361 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const adExplorerSource = "ADExplorer"
+
+// Layout of a Sysinternals ADExplorer snapshot (.dat):
+//
+//	header (0x43e bytes)
+//	  char    signature[10]
+//	  int32   marker
+//	  uint64  filetime
+//	  wchar   description[260]
+//	  wchar   server[260]
+//	  uint32  numObjects
+//	  uint32  numAttributes
+//	  uint32  propertiesOffsetLow, propertiesOffsetHigh, end
+//	  int32   unknown
+//	objects, back to back
+//	  uint32  objSize
+//	  uint32  tableSize
+//	  {uint32 attrIndex, int32 attrOffset}[tableSize]   offsets relative to the object
+//	properties table at propertiesOffset
+//	  uint32  numProperties
+//	  {uint32 lenName, wchar name, int32, uint32 adsType, uint32 lenDN, wchar DN,
+//	   byte schemaIDGUID[16], byte securityGUID[16], byte blob[4]}[numProperties]
+const (
+	adExplorerHeaderSize       = 0x43e
+	adExplorerServerOffset     = 10 + 4 + 8 + 520
+	adExplorerNumObjectsOffset = adExplorerServerOffset + 520
+)
+
+// ADSTYPE values used by ADExplorer for attribute values.
+const (
+	adsTypeDNString         = 1
+	adsTypeCaseExactString  = 2
+	adsTypeCaseIgnoreString = 3
+	adsTypePrintableString  = 4
+	adsTypeNumericString    = 5
+	adsTypeBoolean          = 6
+	adsTypeInteger          = 7
+	adsTypeOctetString      = 8
+	adsTypeUTCTime          = 9
+	adsTypeLargeInteger     = 10
+	adsTypeObjectClass      = 12
+)
+
+// adExplorerAttributes limits decoding to what the importer maps; snapshots
+// of large domains are several GB and mostly security descriptors.
+var adExplorerAttributes = map[string]bool{
+	"distinguishedname":        true,
+	"objectclass":              true,
+	"objectsid":                true,
+	"name":                     true,
+	"samaccountname":           true,
+	"userprincipalname":        true,
+	"description":              true,
+	"useraccountcontrol":       true,
+	"serviceprincipalname":     true,
+	"admincount":               true,
+	"pwdlastset":               true,
+	"lastlogon":                true,
+	"lastlogontimestamp":       true,
+	"lockouttime":              true,
+	"msds-allowedtodelegateto": true,
+	"member":                   true,
+	"memberof":                 true,
+	"primarygroupid":           true,
+	"dnshostname":              true,
+	"operatingsystem":          true,
+	"operatingsystemversion":   true,
+	"msds-behavior-version":    true,
+	"minpwdlength":             true,
+	"pwdhistorylength":         true,
+	"pwdproperties":            true,
+	"maxpwdage":                true,
+	"minpwdage":                true,
+	"lockoutthreshold":         true,
+	"lockoutduration":          true,
+	"lockoutobservationwindow": true,
+}
+
+type adExplorerProperty struct {
+	name    string
+	adsType uint32
+}
+
+// datReader reads little-endian values with bounds checks; the first
+// out-of-range access sets err and all further reads return zero values.
+type datReader struct {
+	data []byte
+	err  error
+}
+
+func (r *datReader) bytes(off int64, n int64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if off < 0 || n < 0 || off+n > int64(len(r.data)) {
+		r.err = fmt.Errorf("truncated snapshot: read of %d bytes at offset %d", n, off)
+		return nil
+	}
+	return r.data[off : off+n]
+}
+
+func (r *datReader) u16(off int64) uint16 {
+	if b := r.bytes(off, 2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *datReader) u32(off int64) uint32 {
+	if b := r.bytes(off, 4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *datReader) i32(off int64) int32 {
+	return int32(r.u32(off))
+}
+
+func (r *datReader) i64(off int64) int64 {
+	if b := r.bytes(off, 8); b != nil {
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// wstring decodes n bytes of UTF-16LE, trimming trailing NULs.
+func (r *datReader) wstring(off int64, n int64) string {
+	b := r.bytes(off, n&^1)
+	if b == nil {
+		return ""
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// cwstring decodes a NUL-terminated UTF-16LE string.
+func (r *datReader) cwstring(off int64) string {
+	end := off
+	for {
+		if end+2 > int64(len(r.data)) {
+			r.err = fmt.Errorf("unterminated string at offset %d", off)
+			return ""
+		}
+		if r.data[end] == 0 && r.data[end+1] == 0 {
+			break
+		}
+		end += 2
+	}
+	return r.wstring(off, end-off)
+}
+
+// ParseADExplorer reads a Sysinternals ADExplorer snapshot.
+func ParseADExplorer(name string, data []byte) (*LDAPSnapshot, error) {
+	if len(data) < adExplorerHeaderSize {
+		return nil, fmt.Errorf("%s: not an ADExplorer snapshot (file too small)", name)
+	}
+
+	r := &datReader{data: data}
+	numObjects := r.u32(adExplorerNumObjectsOffset)
+	propertiesOffset := int64(r.u32(adExplorerNumObjectsOffset+8)) |
+		int64(r.u32(adExplorerNumObjectsOffset+12))<<32
+	if propertiesOffset < adExplorerHeaderSize || propertiesOffset >= int64(len(data)) {
+		return nil, fmt.Errorf("%s: not an ADExplorer snapshot (invalid properties offset)", name)
+	}
+
+	snapshot := &LDAPSnapshot{
+		Source:  adExplorerSource,
+		Server:  r.wstring(adExplorerServerOffset, 520),
+		TakenAt: fileTimeToTime(r.i64(14)),
+		Files:   []string{name},
+	}
+
+	properties, err := readADExplorerProperties(r, propertiesOffset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	offset := int64(adExplorerHeaderSize)
+	for i := uint32(0); i < numObjects; i++ {
+		objSize := int64(r.u32(offset))
+		tableSize := int64(r.u32(offset + 4))
+		if r.err != nil {
+			return nil, fmt.Errorf("%s: object %d: %w", name, i, r.err)
+		}
+		if objSize <= 0 || offset+objSize > propertiesOffset {
+			return nil, fmt.Errorf("%s: object %d has an invalid size", name, i)
+		}
+
+		// the attribute table and the values it points to lie within the object
+		if 8+tableSize*8 > objSize {
+			return nil, fmt.Errorf("%s: object %d: attribute table exceeds the object size", name, i)
+		}
+
+		entry := newLDAPEntry("")
+		for k := int64(0); k < tableSize; k++ {
+			attrIndex := r.u32(offset + 8 + k*8)
+			attrOffset := int64(r.i32(offset + 12 + k*8))
+			if r.err != nil {
+				break
+			}
+			if attrOffset < 8+tableSize*8 || attrOffset >= objSize {
+				return nil, fmt.Errorf("%s: object %d: attribute %d points outside the object", name, i, k)
+			}
+			if int(attrIndex) >= len(properties) {
+				continue
+			}
+			prop := properties[attrIndex]
+			if !adExplorerAttributes[prop.name] {
+				continue
+			}
+			if values := readADExplorerValues(r, offset+attrOffset, prop.adsType); len(values) > 0 {
+				entry.add(prop.name, values...)
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s: object %d: %w", name, i, r.err)
+		}
+
+		entry.DN = entry.String("distinguishedname")
+		if entry.DN != "" && !isDirectoryPartition(entry.DN) {
+			entry.Kind = entry.classify()
+			if entry.Kind != LDAPKindUnknown {
+				snapshot.Entries = append(snapshot.Entries, entry)
+			}
+		}
+		offset += objSize
+	}
+
+	if snapshot.Count() == 0 {
+		return nil, fmt.Errorf("%s: snapshot contains no domain objects", name)
+	}
+	return snapshot, nil
+}
+
+func readADExplorerProperties(r *datReader, offset int64) ([]adExplorerProperty, error) {
+	count := r.u32(offset)
+	offset += 4
+	if int64(count)*48 > int64(len(r.data))-offset {
+		return nil, fmt.Errorf("invalid property count %d", count)
+	}
+
+	properties := make([]adExplorerProperty, 0, count)
+	for i := uint32(0); i < count; i++ {
+		lenName := int64(r.u32(offset))
+		name := r.wstring(offset+4, lenName)
+		offset += 4 + lenName
+
+		adsType := r.u32(offset + 4)
+		lenDN := int64(r.u32(offset + 8))
+		offset += 12 + lenDN + 16 + 16 + 4
+
+		if r.err != nil {
+			return nil, fmt.Errorf("property %d: %w", i, r.err)
+		}
+		properties = append(properties, adExplorerProperty{name: strings.ToLower(name), adsType: adsType})
+	}
+	return properties, nil
+}
+
+// adExplorerEntrySizes is the minimal size of one value per supported ADS
+// type: the offset or length of strings and octet strings, the value itself
+// otherwise.
+var adExplorerEntrySizes = map[uint32]int64{
+	adsTypeDNString:         4,
+	adsTypeCaseExactString:  4,
+	adsTypeCaseIgnoreString: 4,
+	adsTypePrintableString:  4,
+	adsTypeNumericString:    4,
+	adsTypeObjectClass:      4,
+	adsTypeBoolean:          4,
+	adsTypeInteger:          4,
+	adsTypeOctetString:      4,
+	adsTypeLargeInteger:     8,
+	adsTypeUTCTime:          16,
+}
+
+// readADExplorerValues decodes the values of one attribute. Unsupported ADS
+// types (security descriptors, provider specific ...) are skipped.
+func readADExplorerValues(r *datReader, offset int64, adsType uint32) []interface{} {
+	entrySize, ok := adExplorerEntrySizes[adsType]
+	if !ok {
+		return nil
+	}
+	count := int64(r.u32(offset))
+	if r.err != nil || count <= 0 {
+		return nil
+	}
+	// a crafted count must not allocate more than the file can hold
+	if count*entrySize > int64(len(r.data))-offset-4 {
+		r.err = fmt.Errorf("invalid value count %d at offset %d", count, offset)
+		return nil
+	}
+	values := make([]interface{}, 0, count)
+
+	switch adsType {
+	case adsTypeDNString, adsTypeCaseExactString, adsTypeCaseIgnoreString,
+		adsTypePrintableString, adsTypeNumericString, adsTypeObjectClass:
+		for k := int64(0); k < count; k++ {
+			values = append(values, r.cwstring(offset+int64(r.u32(offset+4+k*4))))
+		}
+
+	case adsTypeBoolean:
+		for k := int64(0); k < count; k++ {
+			values = append(values, r.u32(offset+4+k*4) != 0)
+		}
+
+	case adsTypeInteger:
+		for k := int64(0); k < count; k++ {
+			values = append(values, int64(r.u32(offset+4+k*4)))
+		}
+
+	case adsTypeLargeInteger:
+		for k := int64(0); k < count; k++ {
+			values = append(values, r.i64(offset+4+k*8))
+		}
+
+	case adsTypeOctetString:
+		// all lengths first, then the data back to back
+		dataOffset := offset + 4 + count*4
+		for k := int64(0); k < count; k++ {
+			length := int64(r.u32(offset + 4 + k*4))
+			if b := r.bytes(dataOffset, length); b != nil {
+				values = append(values, append([]byte(nil), b...))
+			}
+			dataOffset += length
+		}
+
+	case adsTypeUTCTime:
+		// SYSTEMTIME: year, month, weekday, day, hour, minute, second, ms
+		for k := int64(0); k < count; k++ {
+			base := offset + 4 + k*16
+			values = append(values, time.Date(
+				int(r.u16(base)), time.Month(r.u16(base+2)), int(r.u16(base+6)),
+				int(r.u16(base+8)), int(r.u16(base+10)), int(r.u16(base+12)),
+				int(r.u16(base+14))*int(time.Millisecond), time.UTC,
+			))
+		}
+
+	default:
+		return nil
+	}
+
+	if r.err != nil {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// datAttr is one attribute of a synthetic snapshot object: the index into the
+// property table and the encoded values.
+type datAttr struct {
+	prop  int
+	value []byte
+}
+
+func le32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+// utf16z encodes s as NUL-terminated UTF-16LE.
+func utf16z(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return append(b, 0, 0)
+}
+
+func datStrings(values ...string) []byte {
+	b := le32(uint32(len(values)))
+	offset := 4 + 4*len(values)
+	var data []byte
+	for _, v := range values {
+		b = append(b, le32(uint32(offset+len(data)))...)
+		data = append(data, utf16z(v)...)
+	}
+	return append(b, data...)
+}
+
+func datInteger(v uint32) []byte {
+	return append(le32(1), le32(v)...)
+}
+
+func datOctets(v []byte) []byte {
+	return append(append(le32(1), le32(uint32(len(v)))...), v...)
+}
+
+// buildSnapshot writes a minimal ADExplorer snapshot with the given property
+// table and objects.
+func buildSnapshot(server string, props []adExplorerProperty, objects [][]datAttr) []byte {
+	data := make([]byte, adExplorerHeaderSize)
+	copy(data, "win-ad-ob\x00")
+	copy(data[adExplorerServerOffset:], utf16z(server))
+	binary.LittleEndian.PutUint32(data[adExplorerNumObjectsOffset:], uint32(len(objects)))
+
+	for _, attrs := range objects {
+		tableEnd := 8 + 8*len(attrs)
+		var table, values []byte
+		for _, a := range attrs {
+			table = append(table, le32(uint32(a.prop))...)
+			table = append(table, le32(uint32(tableEnd+len(values)))...)
+			values = append(values, a.value...)
+		}
+		object := append(le32(uint32(tableEnd+len(values))), le32(uint32(len(attrs)))...)
+		object = append(append(object, table...), values...)
+		data = append(data, object...)
+	}
+
+	binary.LittleEndian.PutUint32(data[adExplorerNumObjectsOffset+8:], uint32(len(data)))
+	data = append(data, le32(uint32(len(props)))...)
+	for _, p := range props {
+		name := utf16z(p.name)
+		data = append(data, le32(uint32(len(name)))...)
+		data = append(data, name...)
+		data = append(data, le32(0)...)
+		data = append(data, le32(p.adsType)...)
+		data = append(data, le32(0)...)
+		data = append(data, make([]byte, 16+16+4)...)
+	}
+	return data
+}
+
+func TestParseADExplorer(t *testing.T) {
+	props := []adExplorerProperty{
+		{name: "distinguishedName", adsType: adsTypeDNString},
+		{name: "objectClass", adsType: adsTypeObjectClass},
+		{name: "sAMAccountName", adsType: adsTypeCaseIgnoreString},
+		{name: "userAccountControl", adsType: adsTypeInteger},
+		{name: "objectSid", adsType: adsTypeOctetString},
+		{name: "nTSecurityDescriptor", adsType: adsTypeOctetString},
+	}
+	sid := []byte{1, 5, 0, 0, 0, 0, 0, 5, 21, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 0x51, 0x04, 0, 0}
+
+	domain := []datAttr{
+		{0, datStrings("DC=corp,DC=local")},
+		{1, datStrings("top", "domain", "domainDNS")},
+	}
+	user := []datAttr{
+		{0, datStrings("CN=Alice,CN=Users,DC=corp,DC=local")},
+		{1, datStrings("top", "person", "user")},
+		{2, datStrings("alice")},
+		{3, datInteger(512)},
+		{4, datOctets(sid)},
+		{5, datOctets([]byte{1, 2, 3})},
+	}
+	configuration := []datAttr{
+		{0, datStrings("CN=Sites,CN=Configuration,DC=corp,DC=local")},
+		{1, datStrings("top", "container")},
+	}
+
+	t.Run("domain and user", func(t *testing.T) {
+		snapshot, err := ParseADExplorer("corp.dat", buildSnapshot("dc01.corp.local", props, [][]datAttr{domain, configuration, user}))
+		if err != nil {
+			t.Fatalf("ParseADExplorer() error = %v", err)
+		}
+		if snapshot.Server != "dc01.corp.local" {
+			t.Errorf("Server = %q, want dc01.corp.local", snapshot.Server)
+		}
+		if len(snapshot.Entries) != 2 {
+			t.Fatalf("got %d entries, want 2", len(snapshot.Entries))
+		}
+
+		tests := []struct {
+			entry *LDAPEntry
+			dn    string
+			kind  LDAPEntryKind
+		}{
+			{snapshot.Entries[0], "DC=corp,DC=local", LDAPKindDomain},
+			{snapshot.Entries[1], "CN=Alice,CN=Users,DC=corp,DC=local", LDAPKindUser},
+		}
+		for _, tt := range tests {
+			if tt.entry.DN != tt.dn || tt.entry.Kind != tt.kind {
+				t.Errorf("entry = %s (%s), want %s (%s)", tt.entry.DN, tt.entry.Kind, tt.dn, tt.kind)
+			}
+		}
+
+		alice := snapshot.Entries[1]
+		if got := alice.String("samaccountname"); got != "alice" {
+			t.Errorf("sAMAccountName = %q, want alice", got)
+		}
+		if got := alice.UAC(); got != 512 {
+			t.Errorf("UAC() = %d, want 512", got)
+		}
+		if got := alice.SID(); got != "S-1-5-21-1-2-3-1105" {
+			t.Errorf("SID() = %q, want S-1-5-21-1-2-3-1105", got)
+		}
+		if _, ok := alice.Attributes["ntsecuritydescriptor"]; ok {
+			t.Error("attributes the importer does not map must not be decoded")
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		data    func() []byte
+		wantErr string
+	}{
+		{
+			name:    "too small",
+			data:    func() []byte { return []byte("win-ad-ob") },
+			wantErr: "file too small",
+		},
+		{
+			name: "invalid properties offset",
+			data: func() []byte {
+				data := buildSnapshot("dc01", props, [][]datAttr{domain})
+				binary.LittleEndian.PutUint32(data[adExplorerNumObjectsOffset+8:], uint32(len(data)+10))
+				return data
+			},
+			wantErr: "invalid properties offset",
+		},
+		{
+			name: "object overlapping the property table",
+			data: func() []byte {
+				data := buildSnapshot("dc01", props, [][]datAttr{domain})
+				binary.LittleEndian.PutUint32(data[adExplorerHeaderSize:], 1<<20)
+				return data
+			},
+			wantErr: "invalid size",
+		},
+		{
+			name: "attribute table larger than the object",
+			data: func() []byte {
+				data := buildSnapshot("dc01", props, [][]datAttr{domain})
+				binary.LittleEndian.PutUint32(data[adExplorerHeaderSize+4:], 1<<30)
+				return data
+			},
+			wantErr: "attribute table exceeds",
+		},
+		{
+			name: "attribute outside the object",
+			data: func() []byte {
+				data := buildSnapshot("dc01", props, [][]datAttr{domain})
+				binary.LittleEndian.PutUint32(data[adExplorerHeaderSize+12:], 1<<20)
+				return data
+			},
+			wantErr: "points outside the object",
+		},
+		{
+			name: "value count beyond the file",
+			data: func() []byte {
+				data := buildSnapshot("dc01", props, [][]datAttr{domain})
+				binary.LittleEndian.PutUint32(data[adExplorerHeaderSize+8+8*len(domain):], 1<<30)
+				return data
+			},
+			wantErr: "invalid value count",
+		},
+		{
+			name:    "only directory partitions",
+			data:    func() []byte { return buildSnapshot("dc01", props, [][]datAttr{configuration}) },
+			wantErr: "no domain objects",
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseADExplorer("corp.dat", tt.data())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseADExplorer() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,941 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/change"
+	engineservice "RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	BloodHoundSource = "BloodHoundImport"
+
+	// progressEvery controls how often an ImportProgress event is emitted.
+	progressEvery = 50
+)
+
+// BloodHoundImporter maps a SharpHound collection onto the project graph using
+// the regular upsert services. Every assertion written by the import carries
+// MethodImported so it can be told apart from scan or manual data.
+type BloodHoundImporter struct {
+	activeDirectoryService *active_directory.ActiveDirectoryService
+	domainService          *active_directory.DomainService
+	dirNodeService         *active_directory.DirectoryNodeService
+	userService            *active_directory.UserService
+	groupService           *active_directory.GroupService
+	hostService            *active_directory.HostService
+	aclService             *active_directory.ACLService
+	delegationService      *active_directory.DelegationService
+	trustService           *active_directory.TrustService
+	gpoService             *active_directory.GPOService
+	derivation             *engineservice.ProjectDerivation
+	membershipService      *active_directory.MembershipService
+	runService             *ImportRunService
+	riskService            *risk.RiskService
+	changeService          *change.ChangeService
+	postgresCon            *gorm.DB
+}
+
+func NewBloodHoundImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*BloodHoundImporter, error) {
+	activeDirectoryService, err := active_directory.NewActiveDirectoryService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	domainService, err := active_directory.NewDomainService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	dirNodeService, err := active_directory.NewDirectoryNodeService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	userService, err := active_directory.NewUserService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	groupService, err := active_directory.NewGroupService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	hostService, err := active_directory.NewHostService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	aclService, err := active_directory.NewACLService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	delegationService, err := active_directory.NewDelegationService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	trustService, err := active_directory.NewTrustService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	gpoService, err := active_directory.NewGPOService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := engineservice.NewProjectDerivation(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	membershipService, err := active_directory.NewMembershipService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	changeService, err := change.NewChangeService(postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BloodHoundImporter{
+		activeDirectoryService: activeDirectoryService,
+		domainService:          domainService,
+		dirNodeService:         dirNodeService,
+		userService:            userService,
+		groupService:           groupService,
+		hostService:            hostService,
+		aclService:             aclService,
+		delegationService:      delegationService,
+		trustService:           trustService,
+		gpoService:             gpoService,
+		derivation:             derivation,
+		membershipService:      membershipService,
+		runService:             NewImportRunService(postgresCon),
+		riskService:            risk.NewRiskService(dgraphCon),
+		changeService:          changeService,
+		postgresCon:            postgresCon,
+	}, nil
+}
+
+// importedContext returns the assertion context used for all imported data.
+func importedContext() assertion.Context {
+	confidence := 0.9
+	status := string(core.StatusValidated)
+	highValue := false
+	method := string(core.MethodImported)
+	return assertion.Context{
+		Confidence: &confidence,
+		Status:     &status,
+		HighValue:  &highValue,
+		Method:     &method,
+	}
+}
+
+// bloodHoundRun holds the state of one import run.
+type bloodHoundRun struct {
+	projectUID string
+	actor      string
+	ctx        assertion.Context
+	summary    *ImportSummary
+	logger     *sse.SSELogger
+
+	// ObjectIdentifier (upper case) → graph node
+	nodes map[string]*utils.UIDRef
+	// domain name (lower case) → Domain UID
+	domains map[string]string
+	// child ObjectIdentifier → containing OU/Container ObjectIdentifier
+	parents map[string]string
+
+	processed int
+	total     int
+}
+
+func (r *bloodHoundRun) emit(eventType events.EventType, data map[string]interface{}) {
+	emitImportEvent(r.logger, r.summary, eventType, data)
+}
+
+func (r *bloodHoundRun) step(stage string) {
+	r.processed++
+	if r.processed%progressEvery == 0 || r.processed == r.total {
+		r.emit(events.ImportProgress, map[string]interface{}{
+			"stage":    stage,
+			"progress": fmt.Sprintf("%d/%d", r.processed, r.total),
+		})
+	}
+}
+
+// fail records a failed entity and reports it on the SSE stream.
+func (r *bloodHoundRun) fail(entityType, key string, err error) {
+	r.summary.RecordFailure(entityType, key, err)
+	log.Printf("[%s] %s %s failed: %v", r.actor, entityType, key, err)
+	r.emit(events.ImportError, map[string]interface{}{
+		"type":  entityType,
+		"key":   key,
+		"error": err.Error(),
+	})
+}
+
+// record counts an upsert outcome, see recordUpsert.
+func (r *bloodHoundRun) record(ctx context.Context, changes *change.ChangeService, entityType, uid string, metadata *res.ResultMetadata) {
+	recordUpsert(ctx, changes, r.summary, r.actor, entityType, uid, metadata)
+}
+
+// recordUpsert counts an upsert outcome. Merges that changed fields are
+// written to the change history, so re-importing newer data shows what moved.
+func recordUpsert(
+	ctx context.Context,
+	changes *change.ChangeService,
+	summary *ImportSummary,
+	actor, entityType, uid string,
+	metadata *res.ResultMetadata,
+) {
+	summary.RecordOutcome(entityType, metadata)
+	if metadata == nil || metadata.Outcome != res.OutcomeMerged || len(metadata.Changes) == 0 {
+		return
+	}
+
+	err := changes.SaveChange(ctx, &history.Change{
+		EntityType:   entityType,
+		EntityUID:    uid,
+		ChangeType:   history.ChangeTypeUpdated,
+		Changes:      metadata.Changes,
+		ChangedBy:    actor,
+		ChangeReason: fmt.Sprintf("Updated by import %s", summary.ImportID),
+	})
+	if err != nil {
+		log.Printf("[%s] Warning: failed to save change for %s %s: %v", actor, entityType, uid, err)
+		return
+	}
+	summary.RecordChanged()
+}
+
+func (r *bloodHoundRun) node(objectID string) *utils.UIDRef {
+	return r.nodes[strings.ToUpper(objectID)]
+}
+
+func (r *bloodHoundRun) remember(objectID, uid, entityType string) {
+	r.nodes[strings.ToUpper(objectID)] = &utils.UIDRef{UID: uid, Type: entityType}
+}
+
+// Import writes the collection into the project and returns the summary. runID
+// selects the SSE stream progress is reported on.
+func (i *BloodHoundImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	collection *BloodHoundCollection,
+) (*ImportSummary, error) {
+	return i.importCollection(ctx, projectUID, runID, BloodHoundSource, collection)
+}
+
+// importCollection runs the import; source is used as actor of all assertions
+// and as source of the import run, so other formats mapped onto a collection
+// (see LDAPImporter) stay distinguishable.
+func (i *BloodHoundImporter) importCollection(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	source string,
+	collection *BloodHoundCollection,
+) (*ImportSummary, error) {
+	if collection == nil {
+		return nil, fmt.Errorf("no collection to import")
+	}
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	ctx = assertion.WithRunID(ctx, runID)
+
+	run := &bloodHoundRun{
+		projectUID: projectUID,
+		actor:      source,
+		ctx:        importedContext(),
+		summary:    newImportSummary(runID, source, projectUID),
+		logger:     sse.GetLogger(runID, projectUID, i.postgresCon),
+		nodes:      make(map[string]*utils.UIDRef),
+		domains:    make(map[string]string),
+		parents:    make(map[string]string),
+		total:      collection.Count(),
+	}
+
+	run.emit(events.ImportStart, map[string]interface{}{
+		"version": collection.Version,
+		"objects": collection.Count(),
+	})
+	log.Printf("[%s] Start project=%s objects=%d version=%d",
+		source, projectUID, collection.Count(), collection.Version)
+
+	// ── Entities: containers first so principals can be placed ───────────────
+	for _, obj := range collection.Domains {
+		i.importDomain(ctx, run, obj)
+	}
+	i.importDirectoryNodes(ctx, run, collection)
+	for _, obj := range collection.Groups {
+		i.importGroup(ctx, run, obj)
+	}
+	for _, obj := range collection.Users {
+		i.importUser(ctx, run, obj)
+	}
+	for _, obj := range collection.Computers {
+		i.importComputer(ctx, run, obj)
+	}
+	for _, obj := range collection.GPOs {
+		i.importGPO(ctx, run, obj)
+	}
+	i.importGPOLinks(ctx, run, collection)
+
+	// ── Relations: every endpoint is known now ───────────────────────────────
+	run.emit(events.ImportProgress, map[string]interface{}{"stage": "relations"})
+	i.resolveForeignPrincipals(ctx, run, collection)
+
+	for _, obj := range collection.Domains {
+		i.importTrusts(ctx, run, obj)
+	}
+	for _, obj := range collection.Groups {
+		i.importMemberships(ctx, run, obj)
+	}
+	for _, obj := range collection.Computers {
+		i.importSessions(ctx, run, obj)
+		i.importLocalAdmins(ctx, run, obj)
+	}
+	for _, objs := range [][]BloodHoundObject{
+		collection.Domains, collection.OUs, collection.Containers, collection.GPOs,
+		collection.Groups, collection.Users, collection.Computers,
+	} {
+		for _, obj := range objs {
+			i.importACEs(ctx, run, obj)
+		}
+	}
+	i.deriveCapabilities(ctx, run)
+	i.propagatePrivileges(ctx, run)
+
+	i.riskService.Rescore(ctx, run.summary.ProjectUID, run.actor)
+
+	run.summary.finish()
+	i.runService.Record(ctx, run.summary, collection.Files, source, true)
+	run.emit(events.ImportComplete, map[string]interface{}{
+		"created":    run.summary.Created,
+		"merged":     run.summary.Merged,
+		"duplicates": run.summary.Duplicates,
+		"failed":     run.summary.Failed,
+		"changed":    run.summary.Changed,
+	})
+	log.Printf("[%s] Done project=%s created=%d merged=%d duplicates=%d failed=%d changed=%d",
+		source, projectUID, run.summary.Created, run.summary.Merged, run.summary.Duplicates,
+		run.summary.Failed, run.summary.Changed)
+
+	return run.summary, nil
+}
+
+// ── Entities ─────────────────────────────────────────────────────────────────
+
+func (i *BloodHoundImporter) importDomain(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	defer run.step("domains")
+
+	name := strings.ToLower(obj.String("name"))
+	if name == "" {
+		name = strings.ToLower(obj.String("domain"))
+	}
+	if name == "" {
+		run.fail("Domain", obj.ObjectIdentifier, fmt.Errorf("domain without name"))
+		return
+	}
+
+	// Without trust data every domain is its own forest root
+	adResult, err := i.activeDirectoryService.UpsertActiveDirectory(ctx, upsert.Input[*rpad.ActiveDirectory]{
+		Entity:       &rpad.ActiveDirectory{ForestName: name},
+		ProjectUID:   run.projectUID,
+		ParentType:   "Project",
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("ActiveDirectory", name, err)
+		return
+	}
+	run.record(ctx, i.changeService, "ActiveDirectory", adResult.Entity.UID, adResult.Metadata)
+
+	adUID := adResult.Entity.UID
+	result, err := i.domainService.UpsertDomain(ctx, upsert.Input[*rpad.Domain]{
+		Entity: &rpad.Domain{
+			Name:                  name,
+			Description:           obj.String("description"),
+			DomainFunctionalLevel: obj.String("functionallevel"),
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    &adUID,
+		ParentType:   "ActiveDirectory",
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("Domain", name, err)
+		return
+	}
+	run.record(ctx, i.changeService, "Domain", result.Entity.UID, result.Metadata)
+
+	run.domains[name] = result.Entity.UID
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "Domain")
+	i.importSecurityPolicy(ctx, run, obj, result.Entity.UID)
+	for _, child := range obj.ChildObjects {
+		run.parents[strings.ToUpper(child.ObjectIdentifier)] = strings.ToUpper(obj.ObjectIdentifier)
+	}
+}
+
+// importSecurityPolicy stores the password/lockout policy if the domain object
+// carries one (LDAP dumps always, SharpHound only in newer versions).
+func (i *BloodHoundImporter) importSecurityPolicy(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject, domainUID string) {
+	minPwdLength, ok := obj.Number("minpwdlength")
+	if !ok {
+		return
+	}
+
+	number := func(key string) int {
+		v, _ := obj.Number(key)
+		return int(v)
+	}
+	result, err := i.domainService.SetSecurityPolicy(ctx, run.ctx, domainUID, &rpad.SecurityPolicy{
+		MinPwdLength:         int(minPwdLength),
+		PwdHistoryLength:     number("pwdhistorylength"),
+		LockoutThreshold:     number("lockoutthreshold"),
+		LockoutDuration:      number("lockoutduration"),
+		LockoutWindow:        number("lockoutobservationwindow"),
+		MaxPwdAge:            number("maxpwdage"),
+		MinPwdAge:            number("minpwdage"),
+		PwdComplexity:        obj.Bool("pwdcomplexity"),
+		ReversibleEncryption: obj.Bool("reversibleencryption"),
+	}, run.actor)
+	if err != nil {
+		run.fail("SecurityPolicy", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "SecurityPolicy", result.Entity.UID, result.Metadata)
+}
+
+// importDirectoryNodes imports OUs and containers parents first (ordered by
+// the depth of their distinguished name), so nested ones hang below their
+// parent and the GPO inheritance can follow the hierarchy.
+func (i *BloodHoundImporter) importDirectoryNodes(ctx context.Context, run *bloodHoundRun, collection *BloodHoundCollection) {
+	type directoryNodeObject struct {
+		obj         BloodHoundObject
+		nodeType    rpad.DirectoryNodeType
+		objectClass string
+	}
+
+	nodes := make([]directoryNodeObject, 0, len(collection.OUs)+len(collection.Containers))
+	for _, obj := range collection.OUs {
+		nodes = append(nodes, directoryNodeObject{obj, rpad.DirectoryNodeTypeOU, "organizationalUnit"})
+	}
+	for _, obj := range collection.Containers {
+		nodes = append(nodes, directoryNodeObject{obj, rpad.DirectoryNodeTypeContainer, "container"})
+	}
+	sort.SliceStable(nodes, func(a, b int) bool {
+		return dnDepth(nodes[a].obj) < dnDepth(nodes[b].obj)
+	})
+
+	for _, node := range nodes {
+		i.importDirectoryNode(ctx, run, node.obj, node.nodeType, node.objectClass)
+	}
+}
+
+func (i *BloodHoundImporter) importDirectoryNode(
+	ctx context.Context,
+	run *bloodHoundRun,
+	obj BloodHoundObject,
+	nodeType rpad.DirectoryNodeType,
+	objectClass string,
+) {
+	defer run.step("directory_nodes")
+
+	for _, child := range obj.ChildObjects {
+		run.parents[strings.ToUpper(child.ObjectIdentifier)] = strings.ToUpper(obj.ObjectIdentifier)
+	}
+
+	name := principalName(obj.String("name"))
+	parentUID, parentType := run.principalParent(obj)
+
+	result, err := i.dirNodeService.UpsertDirectoryNode(ctx, upsert.Input[*rpad.DirectoryNode]{
+		Entity: &rpad.DirectoryNode{
+			Name:              name,
+			Description:       obj.String("description"),
+			DistinguishedName: obj.String("distinguishedname"),
+			NodeType:          nodeType,
+			ObjectClass:       objectClass,
+			BlocksInheritance: obj.Bool("blocksinheritance"),
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    parentUID,
+		ParentType:   parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("DirectoryNode", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "DirectoryNode", result.Entity.UID, result.Metadata)
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "DirectoryNode")
+}
+
+func (i *BloodHoundImporter) importGroup(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	defer run.step("groups")
+
+	parentUID, parentType := run.principalParent(obj)
+	result, err := i.groupService.UpsertGroup(ctx, upsert.Input[*rpad.Group]{
+		Entity: &rpad.Group{
+			BasePrincipal: core.BasePrincipal{
+				Name:        principalName(obj.String("name")),
+				SID:         obj.ObjectIdentifier,
+				Description: obj.String("description"),
+			},
+			IsPrivileged: obj.Bool("admincount") || obj.Bool("highvalue"),
+			IsBuiltIn:    strings.Contains(obj.ObjectIdentifier, "S-1-5-32-"),
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    parentUID,
+		ParentType:   parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("Group", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "Group", result.Entity.UID, result.Metadata)
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "Group")
+}
+
+func (i *BloodHoundImporter) importUser(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	defer run.step("users")
+
+	enabled := obj.BoolOr("enabled", true)
+	hasSPN := obj.Bool("hasspn")
+	sam := obj.String("samaccountname")
+	name := principalName(obj.String("name"))
+	if sam == "" {
+		sam = name
+	}
+	allowedToDelegate, _ := obj.Properties["allowedtodelegate"].([]interface{})
+	lastLogon := obj.Time("lastlogon")
+	if t := obj.Time("lastlogontimestamp"); t.After(lastLogon) {
+		lastLogon = t
+	}
+
+	parentUID, parentType := run.principalParent(obj)
+	result, err := i.userService.UpsertUser(ctx, upsert.Input[*rpad.User]{
+		Entity: &rpad.User{
+			BasePrincipal: core.BasePrincipal{
+				Name:        name,
+				SID:         obj.ObjectIdentifier,
+				Description: obj.String("description"),
+			},
+			SAMAccountName: sam,
+			UPN:            obj.String("userprincipalname"),
+			IsDisabled:     !enabled,
+			IsLocked:       obj.Bool("lockedout"),
+			HasSPN:         hasSPN,
+			Kerberoastable: hasSPN && enabled,
+			ASREPRoastable: obj.Bool("dontreqpreauth") && enabled,
+			AllowedToDelegate: obj.Bool("unconstraineddelegation") || obj.Bool("trustedtoauth") ||
+				len(allowedToDelegate) > 0,
+			PwdLastSet: obj.Time("pwdlastset"),
+			LastLogon:  lastLogon,
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    parentUID,
+		ParentType:   parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("User", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "User", result.Entity.UID, result.Metadata)
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "User")
+	i.importDelegations(ctx, run, obj)
+}
+
+func (i *BloodHoundImporter) importComputer(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	defer run.step("computers")
+
+	dnsHostName := strings.ToLower(obj.String("name"))
+	dn := obj.String("distinguishedname")
+	hostname := dnsHostName
+	if idx := strings.Index(hostname, "."); idx > 0 {
+		hostname = hostname[:idx]
+	}
+
+	// Hosts hang below the domain, not below their OU (see hostHierarchyHops)
+	parentUID, parentType := run.domainParent(obj)
+	result, err := i.hostService.UpsertHost(ctx, upsert.Input[*model.Host]{
+		Entity: &model.Host{
+			DType:                  []string{"Host"},
+			Name:                   hostname,
+			Hostname:               hostname,
+			Description:            obj.String("description"),
+			DNSHostName:            dnsHostName,
+			DistinguishedName:      dn,
+			OperatingSystem:        obj.String("operatingsystem"),
+			OperatingSystemVersion: obj.String("operatingsystemversion"),
+			IsDomainController: obj.Bool("isdc") ||
+				strings.Contains(strings.ToUpper(dn), "OU=DOMAIN CONTROLLERS,"),
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    parentUID,
+		ParentType:   parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		run.fail("Host", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "Host", result.Entity.UID, result.Metadata)
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "Host")
+	i.importDelegations(ctx, run, obj)
+}
+
+// importDelegations records the Kerberos delegation of a user or computer
+// account. Settings the account lost since the last import are invalidated.
+func (i *BloodHoundImporter) importDelegations(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	account := run.node(obj.ObjectIdentifier)
+	if account == nil {
+		return
+	}
+
+	var configs []*priv.DelegationConfig
+	if obj.Bool("unconstraineddelegation") {
+		configs = append(configs, &priv.DelegationConfig{DelegationType: priv.DelegationUnconstrained})
+	}
+	if allowed, _ := obj.Properties["allowedtodelegate"].([]interface{}); len(allowed) > 0 {
+		delegationType := priv.DelegationConstrained
+		if obj.Bool("trustedtoauth") {
+			delegationType = priv.DelegationConstrainedTransition
+		}
+		spns := make([]string, 0, len(allowed))
+		for _, spn := range allowed {
+			if s, ok := spn.(string); ok && s != "" {
+				spns = append(spns, s)
+			}
+		}
+		configs = append(configs, &priv.DelegationConfig{DelegationType: delegationType, AllowedToDelegateTo: spns})
+	}
+
+	created, err := i.delegationService.ImportDelegations(ctx, run.ctx, account, configs, run.actor)
+	if err != nil {
+		run.summary.RecordRelationFailure("delegation", obj.ObjectIdentifier, err)
+		return
+	}
+	for n := range configs {
+		run.summary.RecordRelation("delegation", n < created)
+	}
+}
+
+// importTrusts stores the trusts of a domain. Trusted domains outside the
+// collection are added to the project by name.
+func (i *BloodHoundImporter) importTrusts(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	domain := run.node(obj.ObjectIdentifier)
+	if domain == nil {
+		return
+	}
+
+	for _, t := range obj.Trusts {
+		trusted := &rpad.Domain{Name: strings.ToLower(t.TargetDomainName)}
+		if ref := run.node(t.TargetDomainSid); ref != nil && ref.Type == "Domain" {
+			trusted.UID = ref.UID
+		}
+		trust := rpad.NewTrustBuilder().
+			WithTrustType(t.Type()).
+			WithDirection(t.Direction()).
+			WithTransitivity(t.IsTransitive).
+			WithSIDFiltering(t.SidFilteringEnabled).
+			Build()
+
+		result, err := i.trustService.UpsertTrust(ctx, run.ctx, run.projectUID, domain.UID, &trust, trusted, run.actor)
+		if err != nil {
+			run.summary.RecordRelationFailure("trust", t.TargetDomainName, err)
+			continue
+		}
+		run.summary.RecordRelation("trust", result.Metadata.Outcome == res.OutcomeCreated)
+	}
+}
+
+// importGPO writes a GPO of gpos.json into its domain, linked or not, so its
+// ACEs resolve and the links reuse the node.
+func (i *BloodHoundImporter) importGPO(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	defer run.step("gpos")
+
+	domainUID, _ := run.domainParent(obj)
+	if domainUID == nil {
+		run.fail("GPO", obj.ObjectIdentifier, fmt.Errorf("domain %q of gpo is not part of the import", obj.String("domain")))
+		return
+	}
+
+	result, err := i.gpoService.UpsertGPO(ctx, run.ctx, *domainUID, &gpo.GPO{
+		Name:        principalName(obj.String("name")),
+		Description: obj.String("description"),
+	}, run.actor)
+	if err != nil {
+		run.fail("GPO", obj.ObjectIdentifier, err)
+		return
+	}
+	run.record(ctx, i.changeService, "GPO", result.Entity.UID, result.Metadata)
+	run.remember(obj.ObjectIdentifier, result.Entity.UID, "GPO")
+}
+
+// importGPOLinks links GPOs to the domains, OUs and containers that reference
+// them. Linking is idempotent, a replayed import only updates order and
+// enforcement. Linked GPOs become known to the run so their ACEs resolve.
+func (i *BloodHoundImporter) importGPOLinks(ctx context.Context, run *bloodHoundRun, collection *BloodHoundCollection) {
+	gpoNames := make(map[string]string, len(collection.GPOs))
+	for _, obj := range collection.GPOs {
+		gpoNames[strings.ToUpper(obj.ObjectIdentifier)] = principalName(obj.String("name"))
+	}
+
+	for _, objs := range [][]BloodHoundObject{collection.Domains, collection.OUs, collection.Containers} {
+		for _, obj := range objs {
+			container := run.node(obj.ObjectIdentifier)
+			if container == nil {
+				for range obj.Links {
+					run.summary.RecordUnresolved("gpo_link")
+				}
+				continue
+			}
+
+			for order, link := range obj.Links {
+				name, ok := gpoNames[strings.ToUpper(link.GUID)]
+				if !ok {
+					run.summary.RecordUnresolved("gpo_link")
+					continue
+				}
+
+				gpoLink := &gpo.Link{LinkOrder: order + 1, IsEnforced: link.IsEnforced, IsEnabled: true}
+				var result *res.GPOResult[*gpo.Link]
+				var err error
+				if container.Type == "Domain" {
+					result, err = i.domainService.LinkGPO(ctx, run.ctx, gpoLink, &gpo.GPO{Name: name}, container.UID, run.actor)
+				} else {
+					result, err = i.dirNodeService.AddGPOLink(ctx, run.ctx, gpoLink, &gpo.GPO{Name: name}, container.UID, run.actor)
+				}
+				if err != nil {
+					run.summary.RecordRelationFailure("gpo_link", link.GUID, err)
+					continue
+				}
+				run.remember(link.GUID, result.GPO.UID, "GPO")
+				run.summary.RecordRelation("gpo_link", result.Metadata.Outcome == res.OutcomeCreated)
+			}
+		}
+	}
+}
+
+// ── Relations ────────────────────────────────────────────────────────────────
+
+func (i *BloodHoundImporter) importMemberships(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	group := run.node(obj.ObjectIdentifier)
+	if group == nil {
+		return
+	}
+
+	for _, member := range obj.Members {
+		ref := run.node(member.ObjectIdentifier)
+		if ref == nil {
+			run.summary.RecordUnresolved("membership")
+			continue
+		}
+		created, err := i.groupService.AddMember(ctx, group.UID, ref.UID, ref.Type, run.ctx, run.actor)
+		if err != nil {
+			run.summary.RecordRelationFailure("membership", obj.ObjectIdentifier+" → "+member.ObjectIdentifier, err)
+			continue
+		}
+		run.summary.RecordRelation("membership", created)
+	}
+}
+
+func (i *BloodHoundImporter) importSessions(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	host := run.node(obj.ObjectIdentifier)
+	if host == nil {
+		return
+	}
+
+	sessions := make([]BloodHoundSession, 0, len(obj.Sessions.Results)+len(obj.PrivilegedSessions.Results))
+	sessions = append(sessions, obj.Sessions.Results...)
+	sessions = append(sessions, obj.PrivilegedSessions.Results...)
+	seen := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		userSID := strings.ToUpper(session.UserSID)
+		if seen[userSID] {
+			continue
+		}
+		seen[userSID] = true
+
+		user := run.node(userSID)
+		if user == nil {
+			run.summary.RecordUnresolved("session")
+			continue
+		}
+		created, err := i.hostService.AddSession(ctx, run.ctx, host.UID, user.UID, run.actor)
+		if err != nil {
+			run.summary.RecordRelationFailure("session", obj.ObjectIdentifier+" → "+userSID, err)
+			continue
+		}
+		run.summary.RecordRelation("session", created)
+	}
+}
+
+func (i *BloodHoundImporter) importLocalAdmins(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	host := run.node(obj.ObjectIdentifier)
+	if host == nil {
+		return
+	}
+
+	// v5 reports LocalAdmins directly, v6 as the -544 entry of LocalGroups
+	admins := append([]BloodHoundTypedID{}, obj.LocalAdmins.Results...)
+	for _, group := range obj.LocalGroups {
+		if group.IsAdministratorsGroup() {
+			admins = append(admins, group.Results...)
+		}
+	}
+
+	for _, admin := range admins {
+		ref := run.node(admin.ObjectIdentifier)
+		if ref == nil {
+			run.summary.RecordUnresolved("local_admin")
+			continue
+		}
+		created, err := i.hostService.AddAdmin(ctx, run.ctx, host.UID, ref.UID, ref.Type, run.actor)
+		if err != nil {
+			run.summary.RecordRelationFailure("local_admin", obj.ObjectIdentifier+" → "+admin.ObjectIdentifier, err)
+			continue
+		}
+		run.summary.RecordRelation("local_admin", created)
+	}
+}
+
+func (i *BloodHoundImporter) importACEs(ctx context.Context, run *bloodHoundRun, obj BloodHoundObject) {
+	target := run.node(obj.ObjectIdentifier)
+	if target == nil {
+		return
+	}
+
+	for _, ace := range obj.Aces {
+		principal := run.node(ace.PrincipalSID)
+		if principal == nil || ace.RightName == "" {
+			run.summary.RecordUnresolved("ace")
+			continue
+		}
+		created, err := i.aclService.ImportACE(ctx, run.ctx, target, principal, ace.RightName, ace.IsInherited, run.actor)
+		if err != nil {
+			run.summary.RecordRelationFailure("ace", obj.ObjectIdentifier+" "+ace.RightName, err)
+			continue
+		}
+		run.summary.RecordRelation("ace", created)
+	}
+}
+
+// ── Helpers ──────────────────────────────────────────────────────────────────
+
+// domainParent resolves the Domain an object belongs to.
+func (r *bloodHoundRun) domainParent(obj BloodHoundObject) (*string, string) {
+	domain := strings.ToLower(obj.String("domain"))
+	if uid, ok := r.domains[domain]; ok {
+		return &uid, "Domain"
+	}
+	return nil, "Project"
+}
+
+// deriveCapabilities re-derives the capabilities of the project once all
+// ACEs, delegations and GPO links of the collection are written. Stale
+// capabilities of earlier imports are invalidated in the same pass.
+func (i *BloodHoundImporter) deriveCapabilities(ctx context.Context, run *bloodHoundRun) {
+	run.emit(events.ImportProgress, map[string]interface{}{"stage": "capabilities"})
+
+	summaries, err := i.derivation.Run(ctx, run.projectUID, run.actor)
+	if err != nil {
+		run.summary.RecordRelationFailure("capability", run.projectUID, err)
+	}
+	for _, summary := range summaries {
+		for range summary.Derived {
+			run.summary.RecordRelation("capability", true)
+		}
+		for range summary.Kept {
+			run.summary.RecordRelation("capability", false)
+		}
+	}
+}
+
+// resolveForeignPrincipals makes group members from other domains known to
+// the run: foreign security principals are only referenced by SID, the
+// principal itself comes from the collection of its own domain, imported
+// before into the same project.
+func (i *BloodHoundImporter) resolveForeignPrincipals(ctx context.Context, run *bloodHoundRun, collection *BloodHoundCollection) {
+	missing := 0
+	for _, obj := range collection.Groups {
+		for _, member := range obj.Members {
+			if run.node(member.ObjectIdentifier) == nil {
+				missing++
+			}
+		}
+	}
+	if missing == 0 {
+		return
+	}
+
+	known, err := i.membershipService.PrincipalsBySID(ctx, run.projectUID)
+	if err != nil {
+		log.Printf("[%s] Warning: loading known principals failed: %v", run.actor, err)
+		return
+	}
+	for sid, ref := range known {
+		if run.node(sid) == nil {
+			run.remember(sid, ref.UID, ref.Type)
+		}
+	}
+}
+
+// propagatePrivileges writes the privileges of the (nested) group memberships
+// onto the users, after the capabilities are derived so DCSync rights count.
+func (i *BloodHoundImporter) propagatePrivileges(ctx context.Context, run *bloodHoundRun) {
+	if _, err := i.membershipService.PropagatePrivileges(ctx, run.projectUID, run.actor); err != nil {
+		log.Printf("[%s] Warning: propagating privileges failed: %v", run.actor, err)
+	}
+}
+
+// principalParent places an object in its OU/Container if known, otherwise
+// directly in its domain.
+func (r *bloodHoundRun) principalParent(obj BloodHoundObject) (*string, string) {
+	if parentID, ok := r.parents[strings.ToUpper(obj.ObjectIdentifier)]; ok {
+		if ref := r.nodes[parentID]; ref != nil && ref.Type == "DirectoryNode" {
+			uid := ref.UID
+			return &uid, "DirectoryNode"
+		}
+	}
+	return r.domainParent(obj)
+}
+
+// dnDepth is the number of components of the distinguished name of obj.
+func dnDepth(obj BloodHoundObject) int {
+	return strings.Count(obj.String("distinguishedname"), ",")
+}
+
+// principalName strips the "@DOMAIN" suffix BloodHound appends to names.
+func principalName(name string) string {
+	if idx := strings.LastIndex(name, "@"); idx > 0 {
+		return name[:idx]
+	}
+	return name
+}
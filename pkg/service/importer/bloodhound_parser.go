@@ -0,0 +1,298 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// minBloodHoundVersion / maxBloodHoundVersion bound the supported
+	// SharpHound output formats (v5 = BloodHound 4.x, v6 = BloodHound CE).
+	minBloodHoundVersion = 5
+	maxBloodHoundVersion = 6
+
+	// maxArchiveSize limits the decompressed size of all JSON files of an
+	// archive together, in line with the upload limit of the import endpoint.
+	maxArchiveSize = 512 << 20
+)
+
+// BloodHoundCollection holds all objects of one SharpHound collection, grouped
+// by the meta.type of the file they came from.
+type BloodHoundCollection struct {
+	Version    int
+	Files      []string // uploaded file names, kept for the import history
+	Domains    []BloodHoundObject
+	OUs        []BloodHoundObject
+	Containers []BloodHoundObject
+	GPOs       []BloodHoundObject
+	Groups     []BloodHoundObject
+	Users      []BloodHoundObject
+	Computers  []BloodHoundObject
+}
+
+// Count returns the number of objects in the collection.
+func (c *BloodHoundCollection) Count() int {
+	return len(c.Domains) + len(c.OUs) + len(c.Containers) + len(c.GPOs) +
+		len(c.Groups) + len(c.Users) + len(c.Computers)
+}
+
+// BloodHoundObject is the common shape of all SharpHound object types. Fields
+// that do not apply to a type are simply empty.
+type BloodHoundObject struct {
+	ObjectIdentifier string                 `json:"ObjectIdentifier"`
+	Properties       map[string]interface{} `json:"Properties"`
+	Aces             []BloodHoundACE        `json:"Aces"`
+	IsDeleted        bool                   `json:"IsDeleted"`
+
+	// Groups
+	Members []BloodHoundTypedID `json:"Members"`
+
+	// Domains, OUs, Containers
+	ChildObjects []BloodHoundTypedID `json:"ChildObjects"`
+	Links        []BloodHoundGPOLink `json:"Links"`
+
+	// Domains
+	Trusts []BloodHoundTrust `json:"Trusts"`
+
+	// Computers
+	Sessions           BloodHoundSessionList `json:"Sessions"`
+	PrivilegedSessions BloodHoundSessionList `json:"PrivilegedSessions"`
+	LocalAdmins        BloodHoundTypedIDList `json:"LocalAdmins"` // v5
+	LocalGroups        []BloodHoundLocalGrp  `json:"LocalGroups"` // v6
+}
+
+type BloodHoundACE struct {
+	PrincipalSID  string `json:"PrincipalSID"`
+	PrincipalType string `json:"PrincipalType"`
+	RightName     string `json:"RightName"`
+	IsInherited   bool   `json:"IsInherited"`
+}
+
+type BloodHoundTypedID struct {
+	ObjectIdentifier string `json:"ObjectIdentifier"`
+	ObjectType       string `json:"ObjectType"`
+}
+
+type BloodHoundGPOLink struct {
+	GUID       string `json:"GUID"`
+	IsEnforced bool   `json:"IsEnforced"`
+}
+
+// BloodHoundTrust is a trust of a domain. SharpHound v5 writes direction and
+// type as numbers, BloodHound CE as names.
+type BloodHoundTrust struct {
+	TargetDomainSid     string      `json:"TargetDomainSid"`
+	TargetDomainName    string      `json:"TargetDomainName"`
+	IsTransitive        bool        `json:"IsTransitive"`
+	SidFilteringEnabled bool        `json:"SidFilteringEnabled"`
+	TrustDirection      interface{} `json:"TrustDirection"`
+	TrustType           interface{} `json:"TrustType"`
+}
+
+var (
+	bloodHoundTrustDirections = []string{"Disabled", "Inbound", "Outbound", "Bidirectional"}
+	bloodHoundTrustTypes      = []string{"ParentChild", "CrossLink", "Forest", "External", "Unknown"}
+)
+
+// Direction returns the trust direction by name.
+func (t BloodHoundTrust) Direction() string {
+	return bloodHoundEnum(t.TrustDirection, bloodHoundTrustDirections)
+}
+
+// Type returns the trust type by name.
+func (t BloodHoundTrust) Type() string {
+	return bloodHoundEnum(t.TrustType, bloodHoundTrustTypes)
+}
+
+func bloodHoundEnum(v interface{}, names []string) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		if i := int(val); i >= 0 && i < len(names) {
+			return names[i]
+		}
+	}
+	return ""
+}
+
+type BloodHoundSession struct {
+	UserSID     string `json:"UserSID"`
+	ComputerSID string `json:"ComputerSID"`
+}
+
+type BloodHoundSessionList struct {
+	Collected bool                `json:"Collected"`
+	Results   []BloodHoundSession `json:"Results"`
+}
+
+type BloodHoundTypedIDList struct {
+	Collected bool                `json:"Collected"`
+	Results   []BloodHoundTypedID `json:"Results"`
+}
+
+type BloodHoundLocalGrp struct {
+	Name             string              `json:"Name"`
+	ObjectIdentifier string              `json:"ObjectIdentifier"`
+	Collected        bool                `json:"Collected"`
+	Results          []BloodHoundTypedID `json:"Results"`
+}
+
+// IsAdministratorsGroup reports whether the local group is BUILTIN\Administrators.
+func (g BloodHoundLocalGrp) IsAdministratorsGroup() bool {
+	return strings.HasSuffix(strings.ToUpper(g.ObjectIdentifier), "-544")
+}
+
+// String returns a property as string, "" if missing.
+func (o BloodHoundObject) String(key string) string {
+	if v, ok := o.Properties[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Bool returns a property as bool, false if missing.
+func (o BloodHoundObject) Bool(key string) bool {
+	v, _ := o.Properties[key].(bool)
+	return v
+}
+
+// BoolOr returns a property as bool, def if missing.
+func (o BloodHoundObject) BoolOr(key string, def bool) bool {
+	if v, ok := o.Properties[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// Number returns a numeric property; JSON numbers decode as float64.
+func (o BloodHoundObject) Number(key string) (float64, bool) {
+	v, ok := o.Properties[key].(float64)
+	return v, ok
+}
+
+// Time returns a unix timestamp property, zero if missing or never (0 / -1).
+func (o BloodHoundObject) Time(key string) time.Time {
+	if v, ok := o.Number(key); ok && v > 0 {
+		return time.Unix(int64(v), 0).UTC()
+	}
+	return time.Time{}
+}
+
+type bloodHoundFile struct {
+	Data []BloodHoundObject `json:"data"`
+	Meta struct {
+		Type    string `json:"type"`
+		Count   int    `json:"count"`
+		Version int    `json:"version"`
+	} `json:"meta"`
+}
+
+// ParseBloodHound accepts either a SharpHound ZIP archive or a single
+// SharpHound JSON file and returns the merged collection.
+func ParseBloodHound(data []byte) (*BloodHoundCollection, error) {
+	collection := &BloodHoundCollection{}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		if err := parseBloodHoundZip(data, collection); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := parseBloodHoundFile("upload", data, collection); err != nil {
+			return nil, err
+		}
+	}
+
+	if collection.Count() == 0 {
+		return nil, fmt.Errorf("collection contains no supported objects")
+	}
+	return collection, nil
+}
+
+func parseBloodHoundZip(data []byte, collection *BloodHoundCollection) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	remaining := int64(maxArchiveSize)
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !strings.EqualFold(path.Ext(entry.Name), ".json") {
+			continue
+		}
+
+		content, err := readZipEntry(entry, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= int64(len(content))
+		if err := parseBloodHoundFile(entry.Name, content, collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readZipEntry decompresses an entry and fails once it exceeds the bytes
+// remaining of the archive limit.
+func readZipEntry(entry *zip.File, remaining int64) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", entry.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", entry.Name, err)
+	}
+	if int64(len(content)) > remaining {
+		return nil, fmt.Errorf("archive exceeds the maximum decompressed size of %d bytes at %s", maxArchiveSize, entry.Name)
+	}
+	return content, nil
+}
+
+func parseBloodHoundFile(name string, content []byte, collection *BloodHoundCollection) error {
+	// SharpHound writes a UTF-8 BOM on Windows
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	var file bloodHoundFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("%s: invalid json: %w", name, err)
+	}
+
+	version := file.Meta.Version
+	if version < minBloodHoundVersion || version > maxBloodHoundVersion {
+		return fmt.Errorf("%s: unsupported collection version %d (supported: %d-%d)",
+			name, version, minBloodHoundVersion, maxBloodHoundVersion)
+	}
+	if version > collection.Version {
+		collection.Version = version
+	}
+
+	switch strings.ToLower(file.Meta.Type) {
+	case "domains":
+		collection.Domains = append(collection.Domains, file.Data...)
+	case "ous":
+		collection.OUs = append(collection.OUs, file.Data...)
+	case "containers":
+		collection.Containers = append(collection.Containers, file.Data...)
+	case "gpos":
+		collection.GPOs = append(collection.GPOs, file.Data...)
+	case "groups":
+		collection.Groups = append(collection.Groups, file.Data...)
+	case "users":
+		collection.Users = append(collection.Users, file.Data...)
+	case "computers":
+		collection.Computers = append(collection.Computers, file.Data...)
+	default:
+		// certtemplates, aiacas, ... are not modelled yet
+	}
+	return nil
+}
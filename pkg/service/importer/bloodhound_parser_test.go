@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func bloodHoundJSON(typ string, version int, data string) string {
+	return fmt.Sprintf(`{"data": [%s], "meta": {"type": %q, "count": 1, "version": %d}}`, data, typ, version)
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseBloodHound(t *testing.T) {
+	user := `{"ObjectIdentifier": "S-1-5-21-1-1105", "Properties": {"name": "ALICE@CORP.LOCAL", "enabled": true}}`
+	group := `{"ObjectIdentifier": "S-1-5-21-1-512", "Members": [{"ObjectIdentifier": "S-1-5-21-1-1105", "ObjectType": "User"}]}`
+
+	tests := []struct {
+		name       string
+		data       func(t *testing.T) []byte
+		wantUsers  int
+		wantGroups int
+		wantVer    int
+		wantErr    string
+	}{
+		{
+			name:      "single json file with bom",
+			data:      func(t *testing.T) []byte { return []byte("\xef\xbb\xbf" + bloodHoundJSON("users", 5, user)) },
+			wantUsers: 1,
+			wantVer:   5,
+		},
+		{
+			name: "zip archive",
+			data: func(t *testing.T) []byte {
+				return zipArchive(t, map[string]string{
+					"20240101_users.json":  bloodHoundJSON("users", 6, user),
+					"20240101_groups.JSON": bloodHoundJSON("groups", 5, group),
+					"readme.txt":           "not a collection file",
+				})
+			},
+			wantUsers:  1,
+			wantGroups: 1,
+			wantVer:    6,
+		},
+		{
+			name:    "unsupported version",
+			data:    func(t *testing.T) []byte { return []byte(bloodHoundJSON("users", 4, user)) },
+			wantErr: "unsupported collection version 4",
+		},
+		{
+			name:    "only unsupported types",
+			data:    func(t *testing.T) []byte { return []byte(bloodHoundJSON("certtemplates", 6, `{}`)) },
+			wantErr: "no supported objects",
+		},
+		{
+			name:    "invalid json in archive",
+			data:    func(t *testing.T) []byte { return zipArchive(t, map[string]string{"users.json": "{"}) },
+			wantErr: "users.json: invalid json",
+		},
+		{
+			name:    "broken zip",
+			data:    func(t *testing.T) []byte { return []byte("PK\x03\x04garbage") },
+			wantErr: "invalid zip archive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection, err := ParseBloodHound(tt.data(t))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseBloodHound() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBloodHound() error = %v", err)
+			}
+			if len(collection.Users) != tt.wantUsers || len(collection.Groups) != tt.wantGroups {
+				t.Errorf("got %d users and %d groups, want %d and %d",
+					len(collection.Users), len(collection.Groups), tt.wantUsers, tt.wantGroups)
+			}
+			if collection.Version != tt.wantVer {
+				t.Errorf("Version = %d, want %d", collection.Version, tt.wantVer)
+			}
+		})
+	}
+}
+
+func TestReadZipEntryLimit(t *testing.T) {
+	data := zipArchive(t, map[string]string{"users.json": strings.Repeat("x", 64)})
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := archive.File[0]
+
+	if _, err := readZipEntry(entry, 64); err != nil {
+		t.Errorf("readZipEntry() with exact limit error = %v", err)
+	}
+	if _, err := readZipEntry(entry, 63); err == nil || !strings.Contains(err.Error(), "maximum decompressed size") {
+		t.Errorf("readZipEntry() over the limit error = %v", err)
+	}
+}
+
+func TestBloodHoundTrustEnums(t *testing.T) {
+	tests := []struct {
+		trust         BloodHoundTrust
+		wantDirection string
+		wantType      string
+	}{
+		{trust: BloodHoundTrust{TrustDirection: float64(3), TrustType: float64(2)}, wantDirection: "Bidirectional", wantType: "Forest"},
+		{trust: BloodHoundTrust{TrustDirection: "Inbound", TrustType: "External"}, wantDirection: "Inbound", wantType: "External"},
+		{trust: BloodHoundTrust{TrustDirection: float64(9), TrustType: nil}, wantDirection: "", wantType: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.trust.Direction(); got != tt.wantDirection {
+			t.Errorf("Direction() = %q, want %q", got, tt.wantDirection)
+		}
+		if got := tt.trust.Type(); got != tt.wantType {
+			t.Errorf("Type() = %q, want %q", got, tt.wantType)
+		}
+	}
+}
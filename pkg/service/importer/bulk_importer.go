@@ -0,0 +1,1164 @@
+package importer
+
+import (
+	"RedPaths-server/internal/db"
+	adrepo "RedPaths-server/internal/repository/active_directory"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/schema"
+	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/change"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const BulkSource = "BulkImport"
+
+// ErrInvalidBulkImport is wrapped by errors caused by the request itself:
+// unknown entity type, broken mapping or a parent outside the project.
+var ErrInvalidBulkImport = errors.New("invalid bulk import")
+
+const (
+	defaultBulkBatchSize = 100
+	maxBulkBatchSize     = 1000
+
+	// Same thresholds as the upsert services: below bulkDuplicateScore a new
+	// entity is created, from bulkMergeScore on the candidate is merged.
+	bulkDuplicateScore = 0.5
+	bulkMergeScore     = 0.8
+
+	// bulkHostPrefix marks the host columns of Service and Vulnerability rows.
+	bulkHostPrefix = "host."
+)
+
+// Row outcomes of a preview; a committed row reports the res.Outcome instead.
+const (
+	BulkOutcomeCreate    = "create"
+	BulkOutcomeMerge     = "merge"
+	BulkOutcomeDuplicate = "possible_duplicate"
+	BulkOutcomeInvalid   = "invalid"
+	BulkOutcomeFailed    = "failed"
+)
+
+// BulkImportOptions describes how the rows of a bulk import map onto the graph.
+type BulkImportOptions struct {
+	// EntityType is a type of schema.Registry (Host, User, Group ...).
+	EntityType string `json:"entity_type"`
+	// Mapping maps input columns to predicates of the entity type, e.g.
+	// "IP Address" → "host.ip". Service and Vulnerability rows reference
+	// their host through host.* predicates. Unmapped columns are ignored.
+	Mapping map[string]string `json:"mapping"`
+	// ParentUID optionally places every row below an existing node, e.g.
+	// users into a domain. Service and Vulnerability rows apply it to the host.
+	ParentUID  string `json:"parent_uid,omitempty"`
+	ParentType string `json:"parent_type,omitempty"`
+	// BatchSize is the number of rows committed in one transaction.
+	BatchSize int `json:"batch_size,omitempty"`
+	// Files are the uploaded file names, kept for the import history.
+	Files []string `json:"-"`
+}
+
+// BulkMatch is the existing entity an incoming row resolves to.
+type BulkMatch struct {
+	Outcome    string  `json:"outcome"`
+	UID        string  `json:"uid,omitempty"`
+	Score      float64 `json:"score,omitempty"`
+	Candidates int     `json:"candidates,omitempty"` // candidates scoring ≥ 0.5
+	FoundVia   string  `json:"found_via,omitempty"`
+}
+
+// BulkRowResult is the per-row report of a preview or import.
+type BulkRowResult struct {
+	Line int    `json:"line"`
+	Key  string `json:"key,omitempty"`
+	BulkMatch
+	// Host is the match of the host columns of Service and Vulnerability rows.
+	Host *BulkMatch `json:"host,omitempty"`
+	// DuplicateOf is the earlier line of the upload with the same identity.
+	DuplicateOf int      `json:"duplicate_of_line,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// BulkPreview tells what committing the rows would do, without writing.
+type BulkPreview struct {
+	EntityType string          `json:"entity_type"`
+	Total      int             `json:"total"`
+	Create     int             `json:"create"`
+	Merge      int             `json:"merge"`
+	Duplicates int             `json:"possible_duplicates"`
+	Invalid    int             `json:"invalid"`
+	Rows       []BulkRowResult `json:"rows"`
+}
+
+// BulkImportResult is the summary of a committed bulk import plus the
+// per-row report (ImportSummary.Errors is capped, the rows are not).
+type BulkImportResult struct {
+	*ImportSummary
+	Rows []BulkRowResult `json:"rows"`
+}
+
+// bulkType describes how rows of one schema type are matched and written.
+type bulkType struct {
+	model reflect.Type
+	// identifiers are the predicates the repository matches on; every row
+	// needs at least one of them.
+	identifiers []string
+	// parentTypes are the accepted ParentType values, the first is the default.
+	parentTypes []string
+	// withHost rows reference their host through host.* columns.
+	withHost bool
+
+	preview func(i *BulkImporter, ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error)
+	commit  func(i *BulkImporter, ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error)
+}
+
+var hostIdentifiers = []string{"host.ip", "host.dns_host_name", "host.distinguished_name"}
+
+// bulkTypes lists the schema.Registry types that have an upsert path. The
+// others (Computer, ServiceAccount, SecurityPolicy) are written by the
+// collectors only.
+var bulkTypes = map[string]*bulkType{
+	"Host": {
+		model:       reflect.TypeOf(model.Host{}),
+		identifiers: hostIdentifiers,
+		parentTypes: []string{"Domain"},
+		preview:     (*BulkImporter).previewHost,
+		commit:      (*BulkImporter).commitHost,
+	},
+	"Service": {
+		model:       reflect.TypeOf(model.Service{}),
+		identifiers: []string{"service.port"},
+		parentTypes: []string{"Domain"},
+		withHost:    true,
+		preview:     (*BulkImporter).previewService,
+		commit:      (*BulkImporter).commitService,
+	},
+	"Vulnerability": {
+		model:       reflect.TypeOf(model.Vulnerability{}),
+		identifiers: []string{"vulnerability.plugin_id", "vulnerability.name"},
+		parentTypes: []string{"Domain"},
+		withHost:    true,
+		preview:     (*BulkImporter).previewVulnerability,
+		commit:      (*BulkImporter).commitVulnerability,
+	},
+	"ActiveDirectory": {
+		model:       reflect.TypeOf(rpad.ActiveDirectory{}),
+		identifiers: []string{"active_directory.forest_name"},
+		parentTypes: []string{"Project"},
+		preview:     (*BulkImporter).previewActiveDirectory,
+		commit:      (*BulkImporter).commitActiveDirectory,
+	},
+	"Domain": {
+		model:       reflect.TypeOf(rpad.Domain{}),
+		identifiers: []string{"domain.name", "domain.dns_name", "domain.domain_guid"},
+		parentTypes: []string{"ActiveDirectory"},
+		preview:     (*BulkImporter).previewDomain,
+		commit:      (*BulkImporter).commitDomain,
+	},
+	"User": {
+		model:       reflect.TypeOf(rpad.User{}),
+		identifiers: []string{"security_principal.sid", "user.upn", "user.sam_account_name"},
+		parentTypes: []string{"Domain", "DirectoryNode"},
+		preview:     (*BulkImporter).previewUser,
+		commit:      (*BulkImporter).commitUser,
+	},
+	"Group": {
+		model:       reflect.TypeOf(rpad.Group{}),
+		identifiers: []string{"security_principal.sid", "security_principal.name"},
+		parentTypes: []string{"Domain", "DirectoryNode"},
+		preview:     (*BulkImporter).previewGroup,
+		commit:      (*BulkImporter).commitGroup,
+	},
+}
+
+// BulkImporter imports tabular inventories (CSV / JSON exports of CMDBs,
+// spreadsheets ...) for any upsertable type. Rows go through the regular
+// upsert services, so matching and merging work exactly as for scan data.
+type BulkImporter struct {
+	activeDirectoryService *active_directory.ActiveDirectoryService
+	domainService          *active_directory.DomainService
+	userService            *active_directory.UserService
+	groupService           *active_directory.GroupService
+	hostService            *active_directory.HostService
+	vulnService            *active_directory.VulnerabilityService
+
+	adRepo     adrepo.ActiveDirectoryRepository
+	domainRepo adrepo.DomainRepository
+	userRepo   adrepo.UserRepository
+	groupRepo  adrepo.GroupRepository
+	hostRepo   adrepo.HostRepository
+
+	runService    *ImportRunService
+	riskService   *risk.RiskService
+	changeService *change.ChangeService
+	graphRepo     engine.ProjectGraphRepository
+	dgraphCon     *dgo.Dgraph
+	postgresCon   *gorm.DB
+}
+
+func NewBulkImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*BulkImporter, error) {
+	activeDirectoryService, err := active_directory.NewActiveDirectoryService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	domainService, err := active_directory.NewDomainService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	userService, err := active_directory.NewUserService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	groupService, err := active_directory.NewGroupService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	hostService, err := active_directory.NewHostService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	vulnService, err := active_directory.NewVulnerabilityService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	changeService, err := change.NewChangeService(postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkImporter{
+		activeDirectoryService: activeDirectoryService,
+		domainService:          domainService,
+		userService:            userService,
+		groupService:           groupService,
+		hostService:            hostService,
+		vulnService:            vulnService,
+		adRepo:                 adrepo.NewDgraphActiveDirectoryRepository(dgraphCon),
+		domainRepo:             adrepo.NewDgraphDomainRepository(dgraphCon),
+		userRepo:               adrepo.NewDgraphUserRepository(dgraphCon),
+		groupRepo:              adrepo.NewDgraphGroupRepository(dgraphCon),
+		hostRepo:               adrepo.NewDgraphHostRepository(dgraphCon),
+		runService:             NewImportRunService(postgresCon),
+		riskService:            risk.NewRiskService(dgraphCon),
+		changeService:          changeService,
+		graphRepo:              engine.NewDgraphProjectGraphRepository(dgraphCon),
+		dgraphCon:              dgraphCon,
+		postgresCon:            postgresCon,
+	}, nil
+}
+
+// ── Mapping ─────────────────────────────────────────────────────────────────
+
+// bulkTarget is the predicate a column is mapped to.
+type bulkTarget struct {
+	predicate string
+	typ       reflect.Type
+	host      bool // host column of a Service / Vulnerability row
+}
+
+// bulkPlan is a validated set of options.
+type bulkPlan struct {
+	entityType string
+	bt         *bulkType
+	columns    map[string]bulkTarget
+	parentUID  *string
+	parentType string
+	batchSize  int
+}
+
+// bulkRecord is an input row converted into the entity to upsert.
+type bulkRecord struct {
+	line   int
+	key    string
+	entity any
+	host   *model.Host
+	errors []string
+}
+
+// BulkMappableFields returns the predicates the columns of an entity type can
+// be mapped to, for building the mapping dialog.
+func BulkMappableFields(entityType string) ([]string, error) {
+	bt, err := lookupBulkType(entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := sortedKeys(bulkModelFields(bt.model))
+	if bt.withHost {
+		fields = append(fields, sortedKeys(bulkModelFields(reflect.TypeOf(model.Host{})))...)
+	}
+	return fields, nil
+}
+
+func lookupBulkType(entityType string) (*bulkType, error) {
+	if _, err := schema.Get(entityType); err != nil {
+		return nil, err
+	}
+	bt, ok := bulkTypes[entityType]
+	if !ok {
+		return nil, fmt.Errorf("entity type %q is not supported for bulk import", entityType)
+	}
+	return bt, nil
+}
+
+// plan validates the options against the columns of the input. Problems of
+// the request itself are returned as error, problems of single rows are
+// reported per row later.
+func (o BulkImportOptions) plan(columns []string) (*bulkPlan, error) {
+	bt, err := lookupBulkType(o.EntityType)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Mapping) == 0 {
+		return nil, fmt.Errorf("mapping is empty")
+	}
+
+	p := &bulkPlan{
+		entityType: o.EntityType,
+		bt:         bt,
+		columns:    make(map[string]bulkTarget, len(o.Mapping)),
+		parentType: bt.parentTypes[0],
+		batchSize:  o.BatchSize,
+	}
+
+	fields := bulkModelFields(bt.model)
+	var hostFields map[string]reflect.Type
+	if bt.withHost {
+		hostFields = bulkModelFields(reflect.TypeOf(model.Host{}))
+	}
+
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		present[column] = true
+	}
+
+	targets := make(map[string]string, len(o.Mapping))
+	for _, column := range sortedKeys(o.Mapping) {
+		predicate := strings.TrimSpace(o.Mapping[column])
+		if predicate == "" {
+			continue
+		}
+		if !present[column] {
+			return nil, fmt.Errorf("mapped column %q not found in input", column)
+		}
+		if other, ok := targets[predicate]; ok {
+			return nil, fmt.Errorf("columns %q and %q are both mapped to %s", other, column, predicate)
+		}
+		targets[predicate] = column
+
+		if typ, ok := fields[predicate]; ok {
+			p.columns[column] = bulkTarget{predicate: predicate, typ: typ}
+			continue
+		}
+		if typ, ok := hostFields[predicate]; ok && strings.HasPrefix(predicate, bulkHostPrefix) {
+			p.columns[column] = bulkTarget{predicate: predicate, typ: typ, host: true}
+			continue
+		}
+		return nil, fmt.Errorf("column %q: %s is not a predicate of %s", column, predicate, o.EntityType)
+	}
+
+	if !slices.ContainsFunc(bt.identifiers, func(predicate string) bool { _, ok := targets[predicate]; return ok }) {
+		return nil, fmt.Errorf("mapping needs one of %s to match %s entities",
+			strings.Join(bt.identifiers, ", "), o.EntityType)
+	}
+	if bt.withHost && !slices.ContainsFunc(hostIdentifiers, func(predicate string) bool { _, ok := targets[predicate]; return ok }) {
+		return nil, fmt.Errorf("mapping needs one of %s to find the host of a %s",
+			strings.Join(hostIdentifiers, ", "), o.EntityType)
+	}
+
+	if o.ParentUID != "" {
+		if o.EntityType == "ActiveDirectory" {
+			return nil, fmt.Errorf("ActiveDirectory entities are always placed below the project")
+		}
+		parentUID := o.ParentUID
+		p.parentUID = &parentUID
+		if o.ParentType != "" {
+			if !slices.Contains(bt.parentTypes, o.ParentType) {
+				return nil, fmt.Errorf("parent type %q is not valid for %s (expected %s)",
+					o.ParentType, o.EntityType, strings.Join(bt.parentTypes, " or "))
+			}
+			p.parentType = o.ParentType
+		}
+	}
+
+	if p.batchSize <= 0 {
+		p.batchSize = defaultBulkBatchSize
+	}
+	if p.batchSize > maxBulkBatchSize {
+		p.batchSize = maxBulkBatchSize
+	}
+	return p, nil
+}
+
+// planFor validates the options and checks that the parent is a node of the
+// expected type in the project. Errors caused by the request wrap
+// ErrInvalidBulkImport.
+func (i *BulkImporter) planFor(ctx context.Context, projectUID string, columns []string, opts BulkImportOptions) (*bulkPlan, error) {
+	plan, err := opts.plan(columns)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulkImport, err)
+	}
+	if plan.parentUID == nil {
+		return plan, nil
+	}
+
+	parentUID := *plan.parentUID
+	if plan.parentType == "Project" {
+		if parentUID != projectUID {
+			return nil, fmt.Errorf("%w: parent %s is not the project", ErrInvalidBulkImport, parentUID)
+		}
+		return plan, nil
+	}
+
+	graph, err := db.ExecuteRead(ctx, i.dgraphCon, func(tx *dgo.Txn) (*engine.ProjectGraph, error) {
+		return i.graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if node := graph.Nodes[parentUID]; node == nil || node.Type != plan.parentType {
+		return nil, fmt.Errorf("%w: parent %s is no %s of project %s",
+			ErrInvalidBulkImport, parentUID, plan.parentType, projectUID)
+	}
+	return plan, nil
+}
+
+// records converts the rows; conversion errors are kept on the record.
+func (p *bulkPlan) records(rows []BulkRow) []*bulkRecord {
+	records := make([]*bulkRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, p.record(row))
+	}
+	return records
+}
+
+func (p *bulkPlan) record(row BulkRow) *bulkRecord {
+	rec := &bulkRecord{line: row.Line}
+
+	values := make(map[string]interface{})
+	hostValues := make(map[string]interface{})
+	for _, column := range sortedKeys(p.columns) {
+		raw, ok := row.Values[column]
+		if !ok || raw == "" {
+			continue
+		}
+		target := p.columns[column]
+		value, err := convertBulkValue(target.typ, raw)
+		if err != nil {
+			rec.errors = append(rec.errors, fmt.Sprintf("column %q (%s): %v", column, target.predicate, err))
+			continue
+		}
+		if target.host {
+			hostValues[target.predicate] = value
+		} else {
+			values[target.predicate] = value
+		}
+	}
+
+	rec.key = bulkRowKey(values, p.bt.identifiers)
+	if rec.key == "" {
+		rec.errors = append(rec.errors, fmt.Sprintf("row has no value for %s", strings.Join(p.bt.identifiers, ", ")))
+	}
+	if p.bt.withHost {
+		hostKey := bulkRowKey(hostValues, hostIdentifiers)
+		if hostKey == "" {
+			rec.errors = append(rec.errors, fmt.Sprintf("row has no value for %s", strings.Join(hostIdentifiers, ", ")))
+		}
+		rec.key = hostKey + " " + rec.key
+	}
+	if len(rec.errors) > 0 {
+		return rec
+	}
+
+	entity, err := buildBulkEntity(p.bt.model, values)
+	if err != nil {
+		rec.errors = append(rec.errors, err.Error())
+		return rec
+	}
+	rec.entity = entity
+
+	if p.bt.withHost {
+		host, err := buildBulkEntity(reflect.TypeOf(model.Host{}), hostValues)
+		if err != nil {
+			rec.errors = append(rec.errors, err.Error())
+			return rec
+		}
+		rec.host = host.(*model.Host)
+	}
+	return rec
+}
+
+// bulkRowKey identifies a row in the report by its first identifier value.
+func bulkRowKey(values map[string]interface{}, identifiers []string) string {
+	for _, predicate := range identifiers {
+		if s, ok := values[predicate].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// buildBulkEntity decodes the converted values into a new model instance,
+// through the model's JSON mapping so the predicates stay the single source.
+func buildBulkEntity(typ reflect.Type, values map[string]interface{}) (any, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("encoding row: %w", err)
+	}
+	entity := reflect.New(typ).Interface()
+	if err := json.Unmarshal(data, entity); err != nil {
+		return nil, fmt.Errorf("decoding row: %w", err)
+	}
+	return entity, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// bulkModelFields returns the scalar predicates of a model by JSON tag.
+// Relations, uid and dgraph.type are not mappable.
+func bulkModelFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for k := 0; k < typ.NumField(); k++ {
+		field := typ.Field(k)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for predicate, t := range bulkModelFields(field.Type) {
+				fields[predicate] = t
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || name == "uid" || name == "dgraph.type" {
+			continue
+		}
+		if isBulkScalar(field.Type) {
+			fields[name] = field.Type
+		}
+	}
+	return fields
+}
+
+func isBulkScalar(typ reflect.Type) bool {
+	if typ == timeType {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+var bulkTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// convertBulkValue parses a cell into the Go type of the target predicate.
+func convertBulkValue(typ reflect.Type, raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+
+	if typ == timeType {
+		for _, layout := range bulkTimeLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		// FILETIME / generalized time as found in AD exports
+		if t := parseLDAPTime(raw); !t.IsZero() {
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid time %q", raw)
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return raw, nil
+
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes", "y", "x", "ja", "wahr":
+			return true, nil
+		case "0", "false", "no", "n", "nein", "falsch":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q", raw)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", raw)
+		}
+		return n, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return n, nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return f, nil
+
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, bulkListSeparator) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %s", typ)
+}
+
+// ── Preview ─────────────────────────────────────────────────────────────────
+
+// Preview validates the rows and reports for each whether committing it would
+// create an entity, merge into an existing one or flag a possible duplicate,
+// using the same existence check and scoring as the upsert services. Nothing
+// is written. An error is returned only if the options are invalid or the
+// parent cannot be checked.
+func (i *BulkImporter) Preview(
+	ctx context.Context,
+	projectUID string,
+	rows []BulkRow,
+	columns []string,
+	opts BulkImportOptions,
+) (*BulkPreview, error) {
+	plan, err := i.planFor(ctx, projectUID, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &BulkPreview{
+		EntityType: plan.entityType,
+		Total:      len(rows),
+		Rows:       make([]BulkRowResult, 0, len(rows)),
+	}
+
+	seen := make(map[string]int)
+	for _, rec := range plan.records(rows) {
+		row := BulkRowResult{Line: rec.line, Key: rec.key, Errors: rec.errors}
+
+		if len(rec.errors) == 0 {
+			if line, ok := seen[strings.ToLower(rec.key)]; ok {
+				row.DuplicateOf = line
+			} else {
+				seen[strings.ToLower(rec.key)] = rec.line
+			}
+
+			match, err := plan.bt.preview(i, ctx, projectUID, rec)
+			if err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			} else {
+				row.BulkMatch = *match
+			}
+			if rec.host != nil {
+				row.Host, err = i.matchHost(ctx, projectUID, rec.host)
+				if err != nil {
+					row.Errors = append(row.Errors, err.Error())
+				}
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Outcome = BulkOutcomeInvalid
+			preview.Invalid++
+		case row.Outcome == BulkOutcomeMerge:
+			preview.Merge++
+		case row.Outcome == BulkOutcomeDuplicate:
+			preview.Duplicates++
+		default:
+			preview.Create++
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	return preview, nil
+}
+
+// matchExisting runs the repository's existence check and scores the
+// candidates like the upsert services do.
+func matchExisting[T any](
+	ctx context.Context,
+	dgraphCon *dgo.Dgraph,
+	projectUID string,
+	entity T,
+	filters []dgraph.UniqueFieldFilter,
+	find func(ctx context.Context, tx *dgo.Txn, projectUID string, entity T) (*dgraph.ExistenceResult[T], error),
+) (*BulkMatch, error) {
+	existence, err := db.ExecuteRead(ctx, dgraphCon, func(tx *dgo.Txn) (*dgraph.ExistenceResult[T], error) {
+		return find(ctx, tx, projectUID, entity)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("existence check failed: %w", err)
+	}
+
+	match := &BulkMatch{Outcome: BulkOutcomeCreate}
+	if existence == nil || !existence.Found {
+		return match, nil
+	}
+
+	scored := dgraph.ScoreCandidates(existence.Entities, filters, bulkDuplicateScore)
+	if len(scored) == 0 {
+		return match, nil
+	}
+
+	best := scored[0]
+	match.UID = entityUID(best.Result.Entity)
+	match.Score = best.Score
+	match.Candidates = len(scored)
+	match.FoundVia = string(existence.FoundVia)
+	match.Outcome = BulkOutcomeDuplicate
+	if best.Score >= bulkMergeScore {
+		match.Outcome = BulkOutcomeMerge
+	}
+	return match, nil
+}
+
+// entityUID reads the UID field of a model (promoted from BasePrincipal for
+// principals).
+func entityUID(entity any) string {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName("UID"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+func (i *BulkImporter) matchHost(ctx context.Context, projectUID string, host *model.Host) (*BulkMatch, error) {
+	return matchExisting(ctx, i.dgraphCon, projectUID, host, adrepo.BuildHostFilter(host), i.hostRepo.FindExisting)
+}
+
+func (i *BulkImporter) previewHost(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	return i.matchHost(ctx, projectUID, rec.entity.(*model.Host))
+}
+
+// previewService matches services by port on the matched host, like
+// HostService.EnsureService.
+func (i *BulkImporter) previewService(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	service := rec.entity.(*model.Service)
+
+	host, err := i.matchHost(ctx, projectUID, rec.host)
+	if err != nil || host.Outcome != BulkOutcomeMerge {
+		return &BulkMatch{Outcome: BulkOutcomeCreate}, err
+	}
+
+	services, err := i.hostService.GetAllServicesByHost(ctx, host.UID)
+	if err != nil {
+		return nil, fmt.Errorf("loading services of host %s: %w", host.UID, err)
+	}
+	for _, existing := range services {
+		if existing.Entity != nil && existing.Entity.Port == service.Port {
+			return &BulkMatch{Outcome: BulkOutcomeMerge, UID: existing.Entity.UID, Score: 1, Candidates: 1}, nil
+		}
+	}
+	return &BulkMatch{Outcome: BulkOutcomeCreate}, nil
+}
+
+// previewVulnerability matches findings by Vulnerability.Key on the matched
+// host, like VulnerabilityService.AddVulnerability.
+func (i *BulkImporter) previewVulnerability(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	vulnerability := rec.entity.(*model.Vulnerability)
+
+	host, err := i.matchHost(ctx, projectUID, rec.host)
+	if err != nil || host.Outcome != BulkOutcomeMerge {
+		return &BulkMatch{Outcome: BulkOutcomeCreate}, err
+	}
+
+	existing, err := i.vulnService.GetVulnerabilitiesByHost(ctx, host.UID)
+	if err != nil {
+		return nil, fmt.Errorf("loading vulnerabilities of host %s: %w", host.UID, err)
+	}
+	for _, e := range existing {
+		if e.Entity != nil && e.Entity.Key() == vulnerability.Key() {
+			return &BulkMatch{Outcome: BulkOutcomeMerge, UID: e.Entity.UID, Score: 1, Candidates: 1}, nil
+		}
+	}
+	return &BulkMatch{Outcome: BulkOutcomeCreate}, nil
+}
+
+func (i *BulkImporter) previewActiveDirectory(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	ad := rec.entity.(*rpad.ActiveDirectory)
+	return matchExisting(ctx, i.dgraphCon, projectUID, ad, adrepo.BuildActiveDirectoryFilter(ad), i.adRepo.FindExisting)
+}
+
+func (i *BulkImporter) previewDomain(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	domain := rec.entity.(*rpad.Domain)
+	return matchExisting(ctx, i.dgraphCon, projectUID, domain, adrepo.BuildDomainFilter(domain), i.domainRepo.FindExisting)
+}
+
+func (i *BulkImporter) previewUser(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	user := rec.entity.(*rpad.User)
+	return matchExisting(ctx, i.dgraphCon, projectUID, user, adrepo.BuildUserFilter(user), i.userRepo.FindExisting)
+}
+
+func (i *BulkImporter) previewGroup(ctx context.Context, projectUID string, rec *bulkRecord) (*BulkMatch, error) {
+	group := rec.entity.(*rpad.Group)
+	return matchExisting(ctx, i.dgraphCon, projectUID, group, adrepo.BuildGroupFilter(group), i.groupRepo.FindExisting)
+}
+
+// ── Import ──────────────────────────────────────────────────────────────────
+
+// bulkRun holds the state of one import run.
+type bulkRun struct {
+	projectUID string
+	actor      string
+	ctx        assertion.Context
+	parentUID  *string
+	parentType string
+	summary    *ImportSummary
+	logger     *sse.SSELogger
+}
+
+func (r *bulkRun) emit(eventType events.EventType, data map[string]interface{}) {
+	emitImportEvent(r.logger, r.summary, eventType, data)
+}
+
+// Import commits the rows in batches and returns the summary with a per-row
+// report. Invalid rows are reported and skipped, the others are imported. A
+// batch that fails is retried row by row, so only the failing rows are lost.
+// An error is returned only if the options are invalid or the parent cannot
+// be checked.
+func (i *BulkImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	rows []BulkRow,
+	columns []string,
+	opts BulkImportOptions,
+) (*BulkImportResult, error) {
+	plan, err := i.planFor(ctx, projectUID, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	ctx = assertion.WithRunID(ctx, runID)
+
+	run := &bulkRun{
+		projectUID: projectUID,
+		actor:      BulkSource,
+		ctx:        importedContext(),
+		parentUID:  plan.parentUID,
+		parentType: plan.parentType,
+		summary:    newImportSummary(runID, BulkSource, projectUID),
+		logger:     sse.GetLogger(runID, projectUID, i.postgresCon),
+	}
+	result := &BulkImportResult{ImportSummary: run.summary, Rows: make([]BulkRowResult, 0, len(rows))}
+
+	run.emit(events.ImportStart, map[string]interface{}{
+		"entity_type": plan.entityType,
+		"rows":        len(rows),
+	})
+	log.Printf("[%s] Start project=%s type=%s rows=%d", BulkSource, projectUID, plan.entityType, len(rows))
+
+	records := plan.records(rows)
+	for start := 0; start < len(records); start += plan.batchSize {
+		end := min(start+plan.batchSize, len(records))
+
+		if ctx.Err() != nil {
+			for _, rec := range records[start:end] {
+				result.Rows = append(result.Rows, BulkRowResult{
+					Line: rec.line, Key: rec.key,
+					BulkMatch: BulkMatch{Outcome: BulkOutcomeFailed},
+					Errors:    []string{"import cancelled"},
+				})
+				run.summary.RecordFailure(plan.entityType, rec.key, ctx.Err())
+			}
+		} else {
+			result.Rows = append(result.Rows, i.commitBatch(ctx, run, plan, records[start:end])...)
+		}
+
+		run.emit(events.ImportProgress, map[string]interface{}{
+			"stage":    "rows",
+			"batch":    start/plan.batchSize + 1,
+			"progress": fmt.Sprintf("%d/%d", end, len(records)),
+		})
+	}
+
+	i.riskService.Rescore(ctx, run.summary.ProjectUID, BulkSource)
+
+	run.summary.finish()
+	i.runService.Record(ctx, run.summary, opts.Files, BulkSource, true)
+	run.emit(events.ImportComplete, map[string]interface{}{
+		"created":    run.summary.Created,
+		"merged":     run.summary.Merged,
+		"duplicates": run.summary.Duplicates,
+		"failed":     run.summary.Failed,
+		"changed":    run.summary.Changed,
+	})
+	log.Printf("[%s] Done project=%s type=%s created=%d merged=%d duplicates=%d failed=%d changed=%d",
+		BulkSource, projectUID, plan.entityType, run.summary.Created, run.summary.Merged,
+		run.summary.Duplicates, run.summary.Failed, run.summary.Changed)
+
+	return result, nil
+}
+
+// commitBatch upserts the valid records of a batch in one transaction. If a
+// row or the commit fails, nothing of the batch is kept and its rows are
+// retried one by one.
+func (i *BulkImporter) commitBatch(ctx context.Context, run *bulkRun, plan *bulkPlan, batch []*bulkRecord) []BulkRowResult {
+	type committed struct {
+		uid      string
+		metadata *res.ResultMetadata
+	}
+	results := make([]committed, len(batch))
+
+	err := db.ExecuteShared(ctx, i.dgraphCon, func(ctx context.Context) error {
+		for n, rec := range batch {
+			if len(rec.errors) > 0 {
+				continue
+			}
+			uid, metadata, err := plan.bt.commit(i, ctx, run, rec)
+			if err != nil {
+				return fmt.Errorf("line %d (%s): %w", rec.line, rec.key, err)
+			}
+			results[n] = committed{uid: uid, metadata: metadata}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[%s] Batch of %d %s rows failed, retrying row by row: %v", run.actor, len(batch), plan.entityType, err)
+		rows := make([]BulkRowResult, 0, len(batch))
+		for _, rec := range batch {
+			rows = append(rows, i.commitRecord(ctx, run, plan, rec))
+		}
+		return rows
+	}
+
+	rows := make([]BulkRowResult, 0, len(batch))
+	for n, rec := range batch {
+		if len(rec.errors) > 0 {
+			rows = append(rows, invalidRow(run, plan, rec))
+			continue
+		}
+		rows = append(rows, i.committedRow(ctx, run, plan, rec, results[n].uid, results[n].metadata))
+	}
+	return rows
+}
+
+// commitRecord upserts a single record in its own transactions.
+func (i *BulkImporter) commitRecord(ctx context.Context, run *bulkRun, plan *bulkPlan, rec *bulkRecord) BulkRowResult {
+	if len(rec.errors) > 0 {
+		return invalidRow(run, plan, rec)
+	}
+
+	uid, metadata, err := plan.bt.commit(i, ctx, run, rec)
+	if err != nil {
+		run.summary.RecordFailure(plan.entityType, rec.key, err)
+		log.Printf("[%s] %s line %d (%s) failed: %v", run.actor, plan.entityType, rec.line, rec.key, err)
+		run.emit(events.ImportError, map[string]interface{}{
+			"type":  plan.entityType,
+			"key":   rec.key,
+			"line":  rec.line,
+			"error": err.Error(),
+		})
+		return BulkRowResult{
+			Line: rec.line, Key: rec.key,
+			BulkMatch: BulkMatch{Outcome: BulkOutcomeFailed},
+			Errors:    []string{err.Error()},
+		}
+	}
+	return i.committedRow(ctx, run, plan, rec, uid, metadata)
+}
+
+func invalidRow(run *bulkRun, plan *bulkPlan, rec *bulkRecord) BulkRowResult {
+	run.summary.RecordFailure(plan.entityType, fmt.Sprintf("line %d", rec.line), fmt.Errorf("%s", strings.Join(rec.errors, "; ")))
+	return BulkRowResult{
+		Line: rec.line, Key: rec.key,
+		BulkMatch: BulkMatch{Outcome: BulkOutcomeInvalid},
+		Errors:    rec.errors,
+	}
+}
+
+func (i *BulkImporter) committedRow(ctx context.Context, run *bulkRun, plan *bulkPlan, rec *bulkRecord, uid string, metadata *res.ResultMetadata) BulkRowResult {
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, plan.entityType, uid, metadata)
+	row := BulkRowResult{Line: rec.line, Key: rec.key}
+	row.UID = uid
+	row.Outcome = string(res.OutcomeCreated)
+	if metadata != nil && metadata.Outcome != "" {
+		row.Outcome = string(metadata.Outcome)
+	}
+	return row
+}
+
+func (i *BulkImporter) upsertHost(ctx context.Context, run *bulkRun, host *model.Host) (string, *res.ResultMetadata, error) {
+	result, err := i.hostService.UpsertHost(ctx, upsert.Input[*model.Host]{
+		Entity:       host,
+		ProjectUID:   run.projectUID,
+		ParentUID:    run.parentUID,
+		ParentType:   run.parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+// rowHost upserts the host of a Service / Vulnerability row and counts it.
+func (i *BulkImporter) rowHost(ctx context.Context, run *bulkRun, host *model.Host) (string, error) {
+	uid, metadata, err := i.upsertHost(ctx, run, host)
+	if err != nil {
+		return "", fmt.Errorf("host: %w", err)
+	}
+	// counted once the row's transaction is committed
+	db.AfterCommit(ctx, func() {
+		recordUpsert(ctx, i.changeService, run.summary, run.actor, "Host", uid, metadata)
+	})
+	return uid, nil
+}
+
+func (i *BulkImporter) commitHost(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	return i.upsertHost(ctx, run, rec.entity.(*model.Host))
+}
+
+func (i *BulkImporter) commitService(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	hostUID, err := i.rowHost(ctx, run, rec.host)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, err := i.hostService.EnsureService(ctx, run.ctx, run.projectUID, hostUID, rec.entity.(*model.Service), run.actor)
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+func (i *BulkImporter) commitVulnerability(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	vulnerability := rec.entity.(*model.Vulnerability)
+
+	hostUID, err := i.rowHost(ctx, run, rec.host)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var serviceUID string
+	if vulnerability.Port != "" {
+		service := model.NewServiceBuilder().WithPort(vulnerability.Port).Build()
+		result, err := i.hostService.EnsureService(ctx, run.ctx, run.projectUID, hostUID, service, run.actor)
+		if err != nil {
+			return "", nil, fmt.Errorf("service %s: %w", vulnerability.Port, err)
+		}
+		db.AfterCommit(ctx, func() { run.summary.RecordOutcome("Service", result.Metadata) })
+		serviceUID = result.Entity.UID
+	}
+
+	result, err := i.vulnService.AddVulnerability(ctx, run.ctx, run.projectUID, hostUID, serviceUID, vulnerability, run.actor)
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+func (i *BulkImporter) commitActiveDirectory(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	result, err := i.activeDirectoryService.UpsertActiveDirectory(ctx, upsert.Input[*rpad.ActiveDirectory]{
+		Entity:       rec.entity.(*rpad.ActiveDirectory),
+		ProjectUID:   run.projectUID,
+		ParentType:   run.parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+func (i *BulkImporter) commitDomain(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	result, err := i.domainService.UpsertDomain(ctx, upsert.Input[*rpad.Domain]{
+		Entity:       rec.entity.(*rpad.Domain),
+		ProjectUID:   run.projectUID,
+		ParentUID:    run.parentUID,
+		ParentType:   run.parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+func (i *BulkImporter) commitUser(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	result, err := i.userService.UpsertUser(ctx, upsert.Input[*rpad.User]{
+		Entity:       rec.entity.(*rpad.User),
+		ProjectUID:   run.projectUID,
+		ParentUID:    run.parentUID,
+		ParentType:   run.parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
+
+func (i *BulkImporter) commitGroup(ctx context.Context, run *bulkRun, rec *bulkRecord) (string, *res.ResultMetadata, error) {
+	result, err := i.groupService.UpsertGroup(ctx, upsert.Input[*rpad.Group]{
+		Entity:       rec.entity.(*rpad.Group),
+		ProjectUID:   run.projectUID,
+		ParentUID:    run.parentUID,
+		ParentType:   run.parentType,
+		AssertionCtx: run.ctx,
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Entity.UID, result.Metadata, nil
+}
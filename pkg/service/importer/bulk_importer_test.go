@@ -0,0 +1,26 @@
+package importer
+
+import "testing"
+
+func TestPlanBatchSize(t *testing.T) {
+	tests := []struct {
+		batchSize int
+		want      int
+	}{
+		{batchSize: 0, want: defaultBulkBatchSize},
+		{batchSize: -5, want: defaultBulkBatchSize},
+		{batchSize: 25, want: 25},
+		{batchSize: maxBulkBatchSize + 1, want: maxBulkBatchSize},
+	}
+
+	for _, tt := range tests {
+		opts := BulkImportOptions{EntityType: "Host", Mapping: map[string]string{"ip": "host.ip"}, BatchSize: tt.batchSize}
+		plan, err := opts.plan([]string{"ip"})
+		if err != nil {
+			t.Fatalf("plan() error = %v", err)
+		}
+		if plan.batchSize != tt.want {
+			t.Errorf("batch size %d: plan.batchSize = %d, want %d", tt.batchSize, plan.batchSize, tt.want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	BulkFormatCSV  = "csv"
+	BulkFormatJSON = "json"
+
+	// maxBulkRows caps a single upload; larger inventories have to be split.
+	maxBulkRows = 50000
+
+	// bulkListSeparator separates the values of list predicates (SPNs, CVEs,
+	// privileges ...) inside one cell.
+	bulkListSeparator = ";"
+)
+
+// BulkRow is one input row. Line is the CSV line, or the 1-based index of the
+// object in a JSON array, and is what the per-row report refers to.
+type BulkRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// DetectBulkFormat derives the format from the file name, falling back to the
+// content (a JSON upload starts with '[').
+func DetectBulkFormat(name string, data []byte) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".csv"), strings.HasSuffix(lower, ".tsv"):
+		return BulkFormatCSV
+	case strings.HasSuffix(lower, ".json"):
+		return BulkFormatJSON
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), []byte("[")) {
+		return BulkFormatJSON
+	}
+	return BulkFormatCSV
+}
+
+// ParseBulkRows reads a CSV file with header row (delimiter ',', ';' or tab)
+// or a JSON array of flat objects. It returns the rows and the column names.
+func ParseBulkRows(format string, data []byte) ([]BulkRow, []string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var rows []BulkRow
+	var columns []string
+	var err error
+
+	switch strings.ToLower(format) {
+	case BulkFormatCSV:
+		rows, columns, err = parseBulkCSV(data)
+	case BulkFormatJSON:
+		rows, columns, err = parseBulkJSON(data)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q (expected csv or json)", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("input contains no rows")
+	}
+	if len(rows) > maxBulkRows {
+		return nil, nil, fmt.Errorf("input contains %d rows, at most %d can be imported at once", len(rows), maxBulkRows)
+	}
+	return rows, columns, nil
+}
+
+func parseBulkCSV(data []byte) ([]BulkRow, []string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectCSVDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("input is empty")
+		}
+		return nil, nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	seen := make(map[string]bool, len(header))
+	for k, column := range header {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return nil, nil, fmt.Errorf("csv header: column %d has no name", k+1)
+		}
+		if seen[column] {
+			return nil, nil, fmt.Errorf("csv header: duplicate column %q", column)
+		}
+		seen[column] = true
+		header[k] = column
+	}
+
+	var rows []BulkRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string, len(header))
+		for k, cell := range record {
+			if k >= len(header) {
+				return nil, nil, fmt.Errorf("line %d: %d fields, header has %d", line, len(record), len(header))
+			}
+			if cell = strings.TrimSpace(cell); cell != "" {
+				values[header[k]] = cell
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		rows = append(rows, BulkRow{Line: line, Values: values})
+	}
+	return rows, header, nil
+}
+
+// detectCSVDelimiter picks the most frequent candidate of the header line, so
+// exports of German Excel (';') work without configuration.
+func detectCSVDelimiter(data []byte) rune {
+	line := data
+	if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+		line = data[:idx]
+	}
+
+	best, bestCount := ',', 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if count := bytes.Count(line, []byte(string(candidate))); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
+
+func parseBulkJSON(data []byte) ([]BulkRow, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, nil, fmt.Errorf("invalid json (expected an array of objects): %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	var rows []BulkRow
+
+	for k, object := range objects {
+		values := make(map[string]string, len(object))
+		for column, value := range object {
+			cell, err := bulkJSONCell(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: field %q: %w", k+1, column, err)
+			}
+			if cell == "" {
+				continue
+			}
+			values[column] = cell
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		rows = append(rows, BulkRow{Line: k + 1, Values: values})
+	}
+
+	sort.Strings(columns)
+	return rows, columns, nil
+}
+
+// bulkJSONCell flattens a JSON value into the cell representation used for
+// CSV as well; arrays are joined with bulkListSeparator.
+func bulkJSONCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.([]interface{}); nested {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			cell, err := bulkJSONCell(item)
+			if err != nil {
+				return "", err
+			}
+			if cell != "" {
+				parts = append(parts, cell)
+			}
+		}
+		return strings.Join(parts, bulkListSeparator), nil
+	default:
+		return "", fmt.Errorf("nested objects are not supported")
+	}
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectBulkFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want string
+	}{
+		{name: "csv extension", file: "hosts.CSV", data: `[{"a": 1}]`, want: BulkFormatCSV},
+		{name: "tsv extension", file: "hosts.tsv", want: BulkFormatCSV},
+		{name: "json extension", file: "users.json", data: "name\nalice", want: BulkFormatJSON},
+		{name: "json content", file: "export", data: "\xef\xbb\xbf  [{\"name\": \"alice\"}]", want: BulkFormatJSON},
+		{name: "csv content", file: "export", data: "name\nalice", want: BulkFormatCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectBulkFormat(tt.file, []byte(tt.data)); got != tt.want {
+				t.Errorf("DetectBulkFormat(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBulkRows(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		data        string
+		wantRows    []BulkRow
+		wantColumns []string
+		wantErr     bool
+	}{
+		{
+			name:   "csv with comma",
+			format: BulkFormatCSV,
+			data:   "ip,hostname\n10.0.0.1, dc01 \n10.0.0.2,\n",
+			wantRows: []BulkRow{
+				{Line: 2, Values: map[string]string{"ip": "10.0.0.1", "hostname": "dc01"}},
+				{Line: 3, Values: map[string]string{"ip": "10.0.0.2"}},
+			},
+			wantColumns: []string{"ip", "hostname"},
+		},
+		{
+			name:   "csv with semicolon, bom and empty lines",
+			format: BulkFormatCSV,
+			data:   "\xef\xbb\xbfName;Description\nalice;\"Admin; IT\"\n;\nbob;\n",
+			wantRows: []BulkRow{
+				{Line: 2, Values: map[string]string{"Name": "alice", "Description": "Admin; IT"}},
+				{Line: 4, Values: map[string]string{"Name": "bob"}},
+			},
+			wantColumns: []string{"Name", "Description"},
+		},
+		{
+			name:   "csv with tabs",
+			format: BulkFormatCSV,
+			data:   "ip\tos\n10.0.0.1\tWindows Server 2019\n",
+			wantRows: []BulkRow{
+				{Line: 2, Values: map[string]string{"ip": "10.0.0.1", "os": "Windows Server 2019"}},
+			},
+			wantColumns: []string{"ip", "os"},
+		},
+		{name: "csv duplicate column", format: BulkFormatCSV, data: "ip,ip\n1,2\n", wantErr: true},
+		{name: "csv unnamed column", format: BulkFormatCSV, data: "ip,,os\n1,2,3\n", wantErr: true},
+		{name: "csv too many fields", format: BulkFormatCSV, data: "ip\n1,2\n", wantErr: true},
+		{name: "csv header only", format: BulkFormatCSV, data: "ip,os\n", wantErr: true},
+		{name: "csv empty", format: BulkFormatCSV, data: "", wantErr: true},
+		{
+			name:   "json",
+			format: "JSON",
+			data:   `[{"name": "alice", "enabled": true, "spns": ["http/a", "", "cifs/b"], "count": 3, "note": null}, {}]`,
+			wantRows: []BulkRow{
+				{Line: 1, Values: map[string]string{"name": "alice", "enabled": "true", "spns": "http/a;cifs/b", "count": "3"}},
+			},
+			wantColumns: []string{"count", "enabled", "name", "spns"},
+		},
+		{name: "json nested object", format: BulkFormatJSON, data: `[{"host": {"ip": "1"}}]`, wantErr: true},
+		{name: "json nested array", format: BulkFormatJSON, data: `[{"ips": [["1"]]}]`, wantErr: true},
+		{name: "json no array", format: BulkFormatJSON, data: `{"name": "alice"}`, wantErr: true},
+		{name: "json only empty objects", format: BulkFormatJSON, data: `[{}, {"a": ""}]`, wantErr: true},
+		{name: "unknown format", format: "xlsx", data: "a\n1\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, columns, err := ParseBulkRows(tt.format, []byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseBulkRows() = %v, want error", rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBulkRows() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", rows, tt.wantRows)
+			}
+			if !reflect.DeepEqual(columns, tt.wantColumns) {
+				t.Errorf("columns = %v, want %v", columns, tt.wantColumns)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model/engine"
+)
+
+// exploitableCVE describes the capability a reliably exploitable CVE grants.
+type exploitableCVE struct {
+	Capability string
+	Scope      engine.ScopeType
+	RiskLevel  int
+}
+
+const (
+	capRemoteCodeExecution = "Remote Code Execution"
+	capSystemAccess        = "SYSTEM Access"
+	capDomainAdminAccess   = "Domain Admin Access"
+	capNTLMRelay           = "NTLM Relay"
+)
+
+// exploitableCVEs lists CVEs with public, reliable exploits that are relevant
+// for AD environments. Only these derive capabilities during an import; a
+// generic "exploit available" flag from the scanner is not enough.
+var exploitableCVEs = map[string]exploitableCVE{
+	// MS08-067
+	"CVE-2008-4250": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	// MS17-010 (EternalBlue / EternalRomance / EternalChampion)
+	"CVE-2017-0143": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	"CVE-2017-0144": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	"CVE-2017-0145": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	"CVE-2017-0146": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	"CVE-2017-0148": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	// BlueKeep
+	"CVE-2019-0708": {capRemoteCodeExecution, engine.ScopeHost, 10},
+	// SMBGhost
+	"CVE-2020-0796": {capRemoteCodeExecution, engine.ScopeHost, 9},
+	// PrintNightmare
+	"CVE-2021-1675":  {capSystemAccess, engine.ScopeHost, 9},
+	"CVE-2021-34527": {capSystemAccess, engine.ScopeHost, 9},
+	// Zerologon
+	"CVE-2020-1472": {capDomainAdminAccess, engine.ScopeDomain, 10},
+	// noPac / sAMAccountName spoofing
+	"CVE-2021-42278": {capDomainAdminAccess, engine.ScopeDomain, 9},
+	"CVE-2021-42287": {capDomainAdminAccess, engine.ScopeDomain, 9},
+	// Certifried
+	"CVE-2022-26923": {capDomainAdminAccess, engine.ScopeDomain, 8},
+	// Drop the MIC
+	"CVE-2019-1040": {capNTLMRelay, engine.ScopeDomain, 7},
+	// Exchange ProxyLogon / ProxyShell
+	"CVE-2021-26855": {capRemoteCodeExecution, engine.ScopeService, 10},
+	"CVE-2021-27065": {capRemoteCodeExecution, engine.ScopeService, 10},
+	"CVE-2021-34473": {capRemoteCodeExecution, engine.ScopeService, 10},
+	// Log4Shell
+	"CVE-2021-44228": {capRemoteCodeExecution, engine.ScopeService, 10},
+	// Citrix ADC
+	"CVE-2019-19781": {capRemoteCodeExecution, engine.ScopeService, 10},
+	// Shellshock
+	"CVE-2014-6271": {capRemoteCodeExecution, engine.ScopeService, 9},
+}
+
+// derivableCapabilities returns one capability per distinct capability name
+// the CVEs of a finding grant, keeping the highest risk level.
+func derivableCapabilities(cves []string) map[string]exploitableCVE {
+	result := make(map[string]exploitableCVE)
+	for _, cve := range cves {
+		known, ok := exploitableCVEs[cve]
+		if !ok {
+			continue
+		}
+		if existing, ok := result[known.Capability]; !ok || known.RiskLevel > existing.RiskLevel {
+			result[known.Capability] = known
+		}
+	}
+	return result
+}
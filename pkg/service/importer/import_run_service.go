@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/imports"
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// ImportRunService persists the import history of a project.
+type ImportRunService struct {
+	db         *gorm.DB
+	importRepo imports.RedPathsImportRepository
+}
+
+func NewImportRunService(postgresCon *gorm.DB) *ImportRunService {
+	return &ImportRunService{
+		db:         postgresCon,
+		importRepo: imports.NewPostgresRedPathsImportRepository(),
+	}
+}
+
+// Record stores the finished import. Failing to write the history must not
+// fail the import itself, so errors are only logged.
+func (s *ImportRunService) Record(ctx context.Context, summary *ImportSummary, files []string, actor string, success bool) {
+	if s == nil || s.db == nil || summary == nil {
+		return
+	}
+
+	run := &redpaths.ImportRun{
+		ImportID:      summary.ImportID,
+		ProjectUID:    summary.ProjectUID,
+		Source:        summary.Source,
+		Files:         files,
+		Actor:         actor,
+		StartedAt:     summary.StartedAt,
+		FinishedAt:    summary.FinishedAt,
+		WasSuccessful: success,
+		Summary:       summary,
+	}
+
+	err := db.ExecutePostgresInTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		return s.importRepo.AddRun(ctx, tx, run)
+	})
+	if err != nil {
+		log.Printf("[ImportRunService] Failed to record import %s: %v", summary.ImportID, err)
+	}
+}
+
+func (s *ImportRunService) GetAll(ctx context.Context, projectUID string) ([]*redpaths.ImportRun, error) {
+	return db.ExecutePostgresRead(ctx, s.db, func(tx *gorm.DB) ([]*redpaths.ImportRun, error) {
+		return s.importRepo.GetAllImportRuns(ctx, tx, projectUID)
+	})
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/sse"
+	"fmt"
+	"time"
+)
+
+// maxReportedErrors caps the per-row error list so a broken collection does
+// not blow up the response.
+const maxReportedErrors = 200
+
+// EntityCounts counts upsert outcomes for one entity type.
+type EntityCounts struct {
+	Created    int `json:"created"`
+	Merged     int `json:"merged"`
+	Duplicates int `json:"duplicates"`
+	Failed     int `json:"failed"`
+}
+
+// RelationCounts counts relationship edges (memberships, sessions, ACEs ...).
+type RelationCounts struct {
+	Created    int `json:"created"`
+	Existing   int `json:"existing"`
+	Unresolved int `json:"unresolved"`
+	Failed     int `json:"failed"`
+}
+
+// ImportError describes a single object that could not be imported.
+type ImportError struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// ImportSummary is returned to the caller once an import run has finished.
+type ImportSummary struct {
+	ImportID   string    `json:"import_id"`
+	Source     string    `json:"source"`
+	ProjectUID string    `json:"project_uid"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	Created    int `json:"created"`
+	Merged     int `json:"merged"`
+	Duplicates int `json:"duplicates"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped,omitempty"`
+	Changed    int `json:"changed,omitempty"` // merges that modified fields, see change history
+
+	Entities  map[string]*EntityCounts   `json:"entities"`
+	Relations map[string]*RelationCounts `json:"relations"`
+
+	Errors          []ImportError `json:"errors,omitempty"`
+	TruncatedErrors int           `json:"truncated_errors,omitempty"`
+}
+
+func newImportSummary(importID, source, projectUID string) *ImportSummary {
+	return &ImportSummary{
+		ImportID:   importID,
+		Source:     source,
+		ProjectUID: projectUID,
+		StartedAt:  time.Now(),
+		Entities:   make(map[string]*EntityCounts),
+		Relations:  make(map[string]*RelationCounts),
+	}
+}
+
+func (s *ImportSummary) entity(entityType string) *EntityCounts {
+	c, ok := s.Entities[entityType]
+	if !ok {
+		c = &EntityCounts{}
+		s.Entities[entityType] = c
+	}
+	return c
+}
+
+func (s *ImportSummary) relation(name string) *RelationCounts {
+	c, ok := s.Relations[name]
+	if !ok {
+		c = &RelationCounts{}
+		s.Relations[name] = c
+	}
+	return c
+}
+
+// RecordOutcome counts the outcome of a single upsert.
+func (s *ImportSummary) RecordOutcome(entityType string, metadata *res.ResultMetadata) {
+	c := s.entity(entityType)
+
+	outcome := res.OutcomeCreated
+	if metadata != nil && metadata.Outcome != "" {
+		outcome = metadata.Outcome
+	}
+
+	switch outcome {
+	case res.OutcomeMerged:
+		c.Merged++
+		s.Merged++
+	case res.OutcomeDuplicate:
+		c.Duplicates++
+		s.Duplicates++
+	default:
+		c.Created++
+		s.Created++
+	}
+}
+
+// RecordFailure counts a failed entity and keeps the error for the report.
+func (s *ImportSummary) RecordFailure(entityType, key string, err error) {
+	s.entity(entityType).Failed++
+	s.Failed++
+	s.addError(entityType, key, err)
+}
+
+// RecordSkipped counts input that was deliberately not imported (filtered out).
+func (s *ImportSummary) RecordSkipped() {
+	s.Skipped++
+}
+
+// RecordChanged counts a merge that was written to the change history.
+func (s *ImportSummary) RecordChanged() {
+	s.Changed++
+}
+
+// RecordRelation counts a relationship edge; created=false means it was already known.
+func (s *ImportSummary) RecordRelation(name string, created bool) {
+	c := s.relation(name)
+	if created {
+		c.Created++
+	} else {
+		c.Existing++
+	}
+}
+
+// RecordUnresolved counts an edge whose endpoint is not part of the import.
+func (s *ImportSummary) RecordUnresolved(name string) {
+	s.relation(name).Unresolved++
+}
+
+// RecordRelationFailure counts a failed edge and keeps the error for the report.
+func (s *ImportSummary) RecordRelationFailure(name, key string, err error) {
+	s.relation(name).Failed++
+	s.addError(name, key, err)
+}
+
+func (s *ImportSummary) addError(entityType, key string, err error) {
+	if len(s.Errors) >= maxReportedErrors {
+		s.TruncatedErrors++
+		return
+	}
+	s.Errors = append(s.Errors, ImportError{
+		Type:    entityType,
+		Key:     key,
+		Message: fmt.Sprintf("%v", err),
+	})
+}
+
+func (s *ImportSummary) finish() {
+	s.FinishedAt = time.Now()
+}
+
+// emitImportEvent reports on the SSE stream of an import; the import id and
+// source are added to every payload.
+func emitImportEvent(logger *sse.SSELogger, summary *ImportSummary, eventType events.EventType, data map[string]interface{}) {
+	if logger == nil {
+		return
+	}
+	data["import_id"] = summary.ImportID
+	data["source"] = summary.Source
+	data["timestamp"] = time.Now().Unix()
+	sse.NewEvent(eventType).WithPayload(data).Log(logger)
+}
@@ -0,0 +1,418 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// functionalLevels maps msDS-Behavior-Version to the names BloodHound uses.
+var functionalLevels = map[int64]string{
+	0:  "2000 Mixed/Native",
+	1:  "2003 Interim",
+	2:  "2003",
+	3:  "2008",
+	4:  "2008 R2",
+	5:  "2012",
+	6:  "2012 R2",
+	7:  "2016",
+	10: "2025",
+}
+
+// ldapCollection converts the raw entries of a snapshot into the shape of a
+// SharpHound collection, so offline LDAP dumps run through the same import
+// path. Object identifiers are SIDs for principals and domains and the upper
+// case DN for OUs and containers.
+type ldapCollection struct {
+	snapshot   *LDAPSnapshot
+	collection *BloodHoundCollection
+
+	// upper case DN → object identifier / SharpHound type of every principal
+	ids   map[string]string
+	types map[string]string
+	// upper case DN of an OU/container → its object
+	nodes map[string]*BloodHoundObject
+	// group identifier → member identifiers (from member and memberOf)
+	members map[string]map[string]string
+	// upper case domain DN → domain SID
+	domainSIDs map[string]string
+}
+
+// Collection maps the snapshot onto a BloodHoundCollection.
+func (s *LDAPSnapshot) Collection() *BloodHoundCollection {
+	c := &ldapCollection{
+		snapshot:   s,
+		collection: &BloodHoundCollection{Files: s.Files},
+		ids:        make(map[string]string),
+		types:      make(map[string]string),
+		nodes:      make(map[string]*BloodHoundObject),
+		members:    make(map[string]map[string]string),
+		domainSIDs: make(map[string]string),
+	}
+
+	c.indexPrincipals()
+	c.addDomains()
+	for _, e := range s.Entries {
+		switch e.Kind {
+		case LDAPKindOU, LDAPKindContainer:
+			c.node(e.DN, e)
+		case LDAPKindUser:
+			c.addUser(e)
+		case LDAPKindComputer:
+			c.addComputer(e)
+		case LDAPKindGroup:
+			c.addGroup(e)
+		}
+	}
+	c.addMembers()
+
+	for _, dn := range sortedKeys(c.nodes) {
+		node := c.nodes[dn]
+		if rdnType(splitDN(dn)[0]) == "OU" {
+			c.collection.OUs = append(c.collection.OUs, *node)
+		} else {
+			c.collection.Containers = append(c.collection.Containers, *node)
+		}
+	}
+	return c.collection
+}
+
+func (c *ldapCollection) indexPrincipals() {
+	for _, e := range c.snapshot.Entries {
+		var objectType string
+		switch e.Kind {
+		case LDAPKindUser:
+			objectType = "User"
+		case LDAPKindGroup:
+			objectType = "Group"
+		case LDAPKindComputer:
+			objectType = "Computer"
+		case LDAPKindDomain:
+			if sid := e.SID(); sid != "" {
+				c.domainSIDs[strings.ToUpper(domainDN(e.DN))] = sid
+			}
+			continue
+		default:
+			continue
+		}
+		key := strings.ToUpper(e.DN)
+		c.ids[key] = c.identifier(e)
+		c.types[key] = objectType
+	}
+}
+
+// identifier returns the SID, falling back to the DN for objects dumped
+// without objectSid.
+func (c *ldapCollection) identifier(e *LDAPEntry) string {
+	if sid := e.SID(); sid != "" {
+		return sid
+	}
+	return strings.ToUpper(e.DN)
+}
+
+// addDomains creates one domain per DC= suffix seen in the snapshot; the
+// domain object itself (domain_policy.json / domainDNS) adds SID, level and
+// password policy.
+func (c *ldapCollection) addDomains() {
+	domainEntries := make(map[string]*LDAPEntry)
+	// upper case → original domain DN
+	seen := make(map[string]string)
+	for _, e := range c.snapshot.Entries {
+		dn := domainDN(e.DN)
+		if dn == "" {
+			continue
+		}
+		key := strings.ToUpper(dn)
+		if _, ok := seen[key]; !ok {
+			seen[key] = dn
+		}
+		if e.Kind == LDAPKindDomain && strings.EqualFold(e.DN, dn) {
+			domainEntries[key] = e
+		}
+	}
+
+	for _, key := range sortedKeys(seen) {
+		name := strings.ToUpper(dnsNameFromDN(key))
+		obj := BloodHoundObject{
+			ObjectIdentifier: key,
+			Properties: map[string]interface{}{
+				"name":              name,
+				"domain":            name,
+				"distinguishedname": seen[key],
+			},
+		}
+		if sid, ok := c.domainSIDs[key]; ok {
+			obj.ObjectIdentifier = sid
+		}
+
+		if e, ok := domainEntries[key]; ok {
+			if description := e.String("description"); description != "" {
+				obj.Properties["description"] = description
+			}
+			if level, ok := e.Int("msds-behavior-version"); ok {
+				if name, known := functionalLevels[level]; known {
+					obj.Properties["functionallevel"] = name
+				}
+			}
+			addPolicyProperties(obj.Properties, e)
+		}
+		c.collection.Domains = append(c.collection.Domains, obj)
+	}
+}
+
+// addPolicyProperties copies the password and lockout policy of a domain
+// object. Intervals are stored in minutes/days, see SecurityPolicy.
+func addPolicyProperties(props map[string]interface{}, e *LDAPEntry) {
+	for attr, key := range map[string]string{
+		"minpwdlength":     "minpwdlength",
+		"pwdhistorylength": "pwdhistorylength",
+		"lockoutthreshold": "lockoutthreshold",
+	} {
+		if v, ok := e.Int(attr); ok {
+			props[key] = float64(v)
+		}
+	}
+	if v, ok := e.Int("pwdproperties"); ok {
+		props["pwdcomplexity"] = v&pwdPropertiesComplex != 0
+		props["reversibleencryption"] = v&pwdPropertiesStoreCleartext != 0
+	}
+	if d, ok := e.Duration("lockoutduration"); ok {
+		props["lockoutduration"] = d.Minutes()
+	}
+	if d, ok := e.Duration("lockoutobservationwindow"); ok {
+		props["lockoutobservationwindow"] = d.Minutes()
+	}
+	if d, ok := e.Duration("maxpwdage"); ok {
+		props["maxpwdage"] = d.Hours() / 24
+	}
+	if d, ok := e.Duration("minpwdage"); ok {
+		props["minpwdage"] = d.Hours() / 24
+	}
+}
+
+// node returns the OU/container object for a DN, creating it and its parents
+// on first use. entry is nil for containers only known from child DNs.
+func (c *ldapCollection) node(dn string, entry *LDAPEntry) *BloodHoundObject {
+	key := strings.ToUpper(dn)
+	if key == "" || key == strings.ToUpper(domainDN(dn)) {
+		return nil
+	}
+	if _, isPrincipal := c.ids[key]; isPrincipal {
+		return nil
+	}
+
+	node, ok := c.nodes[key]
+	if !ok {
+		domain := strings.ToUpper(dnsNameFromDN(dn))
+		node = &BloodHoundObject{
+			ObjectIdentifier: key,
+			Properties: map[string]interface{}{
+				"name":              strings.ToUpper(rdnValue(splitDN(dn)[0])) + "@" + domain,
+				"domain":            domain,
+				"distinguishedname": dn,
+			},
+		}
+		c.nodes[key] = node
+		c.node(parentDN(dn), nil)
+	}
+	if entry != nil {
+		if description := entry.String("description"); description != "" {
+			node.Properties["description"] = description
+		}
+	}
+	return node
+}
+
+// place registers a principal as child of its OU/container.
+func (c *ldapCollection) place(e *LDAPEntry, id, objectType string) {
+	if parent := c.node(parentDN(e.DN), nil); parent != nil {
+		parent.ChildObjects = append(parent.ChildObjects, BloodHoundTypedID{
+			ObjectIdentifier: id,
+			ObjectType:       objectType,
+		})
+	}
+}
+
+// principalProperties fills the properties shared by users, groups and computers.
+func (c *ldapCollection) principalProperties(e *LDAPEntry) map[string]interface{} {
+	domain := strings.ToUpper(dnsNameFromDN(e.DN))
+	sam := e.String("samaccountname")
+	name := sam
+	if name == "" {
+		name = e.String("name")
+	}
+	if name == "" {
+		name = rdnValue(splitDN(e.DN)[0])
+	}
+
+	props := map[string]interface{}{
+		"name":              strings.ToUpper(name) + "@" + domain,
+		"domain":            domain,
+		"distinguishedname": e.DN,
+		"samaccountname":    sam,
+	}
+	if description := e.String("description"); description != "" {
+		props["description"] = description
+	}
+	if adminCount, ok := e.Int("admincount"); ok {
+		props["admincount"] = adminCount == 1
+	}
+	return props
+}
+
+func (c *ldapCollection) addUser(e *LDAPEntry) {
+	id := c.identifier(e)
+	uac := e.UAC()
+	props := c.principalProperties(e)
+
+	props["enabled"] = uac&uacAccountDisable == 0
+	props["hasspn"] = len(e.Strings("serviceprincipalname")) > 0
+	props["dontreqpreauth"] = uac&uacDontRequirePreauth != 0
+	props["passwordnotreqd"] = uac&uacPasswordNotRequired != 0
+	props["pwdneverexpires"] = uac&uacDontExpirePassword != 0
+	props["sensitive"] = uac&uacNotDelegated != 0
+	props["unconstraineddelegation"] = uac&uacTrustedForDelegation != 0
+	props["trustedtoauth"] = uac&uacTrustedToAuthForDelegation != 0
+	props["lockedout"] = uac&uacLockout != 0 || !e.Time("lockouttime").IsZero()
+	if upn := e.String("userprincipalname"); upn != "" {
+		props["userprincipalname"] = upn
+	}
+	if delegates := e.Strings("msds-allowedtodelegateto"); len(delegates) > 0 {
+		props["allowedtodelegate"] = toInterfaces(delegates)
+	}
+
+	// BloodHound stores timestamps as unix seconds
+	if t := e.Time("pwdlastset"); !t.IsZero() {
+		props["pwdlastset"] = float64(t.Unix())
+	}
+	lastLogon := e.Time("lastlogon")
+	if t := e.Time("lastlogontimestamp"); t.After(lastLogon) {
+		lastLogon = t
+	}
+	if !lastLogon.IsZero() {
+		props["lastlogon"] = float64(lastLogon.Unix())
+	}
+
+	c.collection.Users = append(c.collection.Users, BloodHoundObject{ObjectIdentifier: id, Properties: props})
+	c.place(e, id, "User")
+	c.addMemberOf(e, id, "User")
+}
+
+func (c *ldapCollection) addComputer(e *LDAPEntry) {
+	id := c.identifier(e)
+	uac := e.UAC()
+	props := c.principalProperties(e)
+
+	hostname := e.String("dnshostname")
+	if hostname == "" {
+		hostname = strings.TrimSuffix(e.String("samaccountname"), "$")
+		if hostname == "" {
+			hostname = rdnValue(splitDN(e.DN)[0])
+		}
+		hostname += "." + dnsNameFromDN(e.DN)
+	}
+	props["name"] = strings.ToUpper(hostname)
+	props["enabled"] = uac&uacAccountDisable == 0
+	props["isdc"] = uac&uacServerTrustAccount != 0
+	props["unconstraineddelegation"] = uac&uacTrustedForDelegation != 0
+	props["trustedtoauth"] = uac&uacTrustedToAuthForDelegation != 0
+	props["operatingsystem"] = e.String("operatingsystem")
+	props["operatingsystemversion"] = e.String("operatingsystemversion")
+
+	c.collection.Computers = append(c.collection.Computers, BloodHoundObject{ObjectIdentifier: id, Properties: props})
+	c.place(e, id, "Computer")
+	c.addMemberOf(e, id, "Computer")
+}
+
+func (c *ldapCollection) addGroup(e *LDAPEntry) {
+	id := c.identifier(e)
+	c.collection.Groups = append(c.collection.Groups, BloodHoundObject{
+		ObjectIdentifier: id,
+		Properties:       c.principalProperties(e),
+	})
+	c.place(e, id, "Group")
+	c.addMemberOf(e, id, "Group")
+
+	for _, memberDN := range e.Strings("member") {
+		key := strings.ToUpper(memberDN)
+		memberID, ok := c.ids[key]
+		if sid, foreign := foreignPrincipalSID(key); !ok && foreign {
+			// resolved by SID against principals of other imported domains
+			memberID = sid
+		} else if !ok {
+			// object outside the dump, counted as unresolved
+			memberID = key
+		}
+		c.addMember(id, memberID, c.types[key])
+	}
+}
+
+// foreignPrincipalSID returns the SID of a foreign security principal DN
+// (CN=<SID>,CN=ForeignSecurityPrincipals,DC=...).
+func foreignPrincipalSID(dn string) (string, bool) {
+	rdn, rest, ok := strings.Cut(strings.ToUpper(dn), ",")
+	if !ok || !strings.HasPrefix(rest, "CN=FOREIGNSECURITYPRINCIPALS,") {
+		return "", false
+	}
+	sid := strings.TrimPrefix(rdn, "CN=")
+	return sid, strings.HasPrefix(sid, "S-1-")
+}
+
+// addMemberOf records memberOf and the implicit primary group, which AD does
+// not list in the group's member attribute.
+func (c *ldapCollection) addMemberOf(e *LDAPEntry, id, objectType string) {
+	for _, groupDN := range e.Strings("memberof") {
+		if groupID, ok := c.ids[strings.ToUpper(groupDN)]; ok {
+			c.addMember(groupID, id, objectType)
+		}
+	}
+
+	rid, ok := e.Int("primarygroupid")
+	if !ok {
+		return
+	}
+	if domainSID, ok := c.domainSIDs[strings.ToUpper(domainDN(e.DN))]; ok {
+		c.addMember(fmt.Sprintf("%s-%d", domainSID, rid), id, objectType)
+	} else if sid := e.SID(); strings.Count(sid, "-") > 3 {
+		c.addMember(sid[:strings.LastIndex(sid, "-")]+fmt.Sprintf("-%d", rid), id, objectType)
+	}
+}
+
+func (c *ldapCollection) addMember(groupID, memberID, objectType string) {
+	members, ok := c.members[groupID]
+	if !ok {
+		members = make(map[string]string)
+		c.members[groupID] = members
+	}
+	members[memberID] = objectType
+}
+
+func (c *ldapCollection) addMembers() {
+	for i := range c.collection.Groups {
+		group := &c.collection.Groups[i]
+		members := c.members[group.ObjectIdentifier]
+		for _, memberID := range sortedKeys(members) {
+			group.Members = append(group.Members, BloodHoundTypedID{
+				ObjectIdentifier: memberID,
+				ObjectType:       members[memberID],
+			})
+		}
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
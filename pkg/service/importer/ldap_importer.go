@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+const (
+	LDAPDomainDumpSource = "LDAPDomainDumpImport"
+	ADExplorerSource     = "ADExplorerImport"
+)
+
+// LDAPImporter imports offline LDAP dumps (ldapdomaindump, ADExplorer
+// snapshots) for engagements where running a collector is not allowed. The
+// snapshot is mapped onto a BloodHoundCollection and imported by the same
+// pipeline, so upserts are idempotent and re-importing a newer dump merges
+// into the existing entities and records the changed fields.
+type LDAPImporter struct {
+	collections *BloodHoundImporter
+}
+
+func NewLDAPImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*LDAPImporter, error) {
+	collections, err := NewBloodHoundImporter(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	return &LDAPImporter{collections: collections}, nil
+}
+
+// Import writes the snapshot into the project. runID selects the SSE stream
+// progress is reported on.
+func (i *LDAPImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	snapshot *LDAPSnapshot,
+) (*ImportSummary, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("no snapshot to import")
+	}
+
+	var source string
+	switch snapshot.Source {
+	case ldapDomainDumpSource:
+		source = LDAPDomainDumpSource
+	case adExplorerSource:
+		source = ADExplorerSource
+	default:
+		return nil, fmt.Errorf("unsupported snapshot source %q", snapshot.Source)
+	}
+
+	collection := snapshot.Collection()
+	log.Printf("[%s] Snapshot server=%s taken=%s entries=%d → domains=%d ous=%d containers=%d users=%d groups=%d computers=%d",
+		source, snapshot.Server, snapshot.TakenAt.Format("2006-01-02 15:04"), len(snapshot.Entries),
+		len(collection.Domains), len(collection.OUs), len(collection.Containers),
+		len(collection.Users), len(collection.Groups), len(collection.Computers))
+
+	return i.collections.importCollection(ctx, projectUID, runID, source, collection)
+}
@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userAccountControl flags evaluated by the LDAP importers.
+const (
+	uacAccountDisable             = 0x00000002
+	uacLockout                    = 0x00000010
+	uacPasswordNotRequired        = 0x00000020
+	uacServerTrustAccount         = 0x00002000
+	uacDontExpirePassword         = 0x00010000
+	uacTrustedForDelegation       = 0x00080000
+	uacNotDelegated               = 0x00100000
+	uacDontRequirePreauth         = 0x00400000
+	uacTrustedToAuthForDelegation = 0x01000000
+)
+
+// pwdProperties flags of the domain object.
+const (
+	pwdPropertiesComplex        = 0x00000001
+	pwdPropertiesStoreCleartext = 0x00000010
+)
+
+const (
+	// fileTimeUnixEpochDelta is the number of 100ns ticks between 1601-01-01
+	// (FILETIME epoch) and 1970-01-01.
+	fileTimeUnixEpochDelta = 116444736000000000
+
+	// neverLargeInteger is how AD encodes "never" in interval attributes.
+	neverLargeInteger = math.MinInt64
+
+	// maxPolicyDays caps interval values; anything longer is treated as never.
+	maxPolicyDays = 36500
+)
+
+// LDAPEntryKind is the role an LDAP object plays in the import.
+type LDAPEntryKind string
+
+const (
+	LDAPKindUnknown   LDAPEntryKind = ""
+	LDAPKindDomain    LDAPEntryKind = "domain"
+	LDAPKindOU        LDAPEntryKind = "ou"
+	LDAPKindContainer LDAPEntryKind = "container"
+	LDAPKindUser      LDAPEntryKind = "user"
+	LDAPKindGroup     LDAPEntryKind = "group"
+	LDAPKindComputer  LDAPEntryKind = "computer"
+)
+
+// LDAPEntry is a raw directory object as read from an offline dump. Attribute
+// names are lower case; values keep the type of the source format (string,
+// bool, int64, float64, []byte or time.Time).
+type LDAPEntry struct {
+	DN         string
+	Kind       LDAPEntryKind
+	Attributes map[string][]interface{}
+}
+
+// LDAPSnapshot is the format-independent result of the ldapdomaindump and
+// ADExplorer parsers.
+type LDAPSnapshot struct {
+	Source  string
+	Server  string
+	TakenAt time.Time
+	Files   []string // uploaded file names, kept for the import history
+	Entries []*LDAPEntry
+}
+
+// Count returns the number of entries the importer knows how to map.
+func (s *LDAPSnapshot) Count() int {
+	n := 0
+	for _, e := range s.Entries {
+		if e.Kind != LDAPKindUnknown {
+			n++
+		}
+	}
+	return n
+}
+
+func newLDAPEntry(dn string) *LDAPEntry {
+	return &LDAPEntry{DN: dn, Attributes: make(map[string][]interface{})}
+}
+
+func (e *LDAPEntry) add(attr string, values ...interface{}) {
+	key := strings.ToLower(attr)
+	e.Attributes[key] = append(e.Attributes[key], values...)
+}
+
+// classify derives the kind from objectClass; the most specific class wins
+// (computer objects are users as well).
+func (e *LDAPEntry) classify() LDAPEntryKind {
+	classes := make(map[string]bool)
+	for _, c := range e.Strings("objectclass") {
+		classes[strings.ToLower(c)] = true
+	}
+	switch {
+	case classes["domaindns"]:
+		return LDAPKindDomain
+	case classes["organizationalunit"]:
+		return LDAPKindOU
+	case classes["computer"]:
+		return LDAPKindComputer
+	case classes["group"]:
+		return LDAPKindGroup
+	case classes["user"] && !classes["foreignsecurityprincipal"]:
+		return LDAPKindUser
+	case classes["container"], classes["builtindomain"]:
+		return LDAPKindContainer
+	}
+	return LDAPKindUnknown
+}
+
+// String returns the first value of an attribute as string, "" if missing.
+func (e *LDAPEntry) String(attr string) string {
+	for _, v := range e.Attributes[attr] {
+		switch t := v.(type) {
+		case string:
+			return t
+		case []byte:
+			return string(t)
+		default:
+			return fmt.Sprint(t)
+		}
+	}
+	return ""
+}
+
+// Strings returns all string values of an attribute.
+func (e *LDAPEntry) Strings(attr string) []string {
+	values := e.Attributes[attr]
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Int returns the first value of an attribute as integer.
+func (e *LDAPEntry) Int(attr string) (int64, bool) {
+	for _, v := range e.Attributes[attr] {
+		switch t := v.(type) {
+		case int64:
+			return t, true
+		case float64:
+			return int64(t), true
+		case bool:
+			if t {
+				return 1, true
+			}
+			return 0, true
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// UAC returns userAccountControl, 0 if not collected.
+func (e *LDAPEntry) UAC() int64 {
+	uac, _ := e.Int("useraccountcontrol")
+	return uac
+}
+
+// SID returns objectSid in its string form, decoding binary values.
+func (e *LDAPEntry) SID() string {
+	for _, v := range e.Attributes["objectsid"] {
+		switch t := v.(type) {
+		case string:
+			return strings.ToUpper(t)
+		case []byte:
+			return decodeSID(t)
+		}
+	}
+	return ""
+}
+
+// Time returns a timestamp attribute; the zero time means never/not set.
+func (e *LDAPEntry) Time(attr string) time.Time {
+	for _, v := range e.Attributes[attr] {
+		switch t := v.(type) {
+		case time.Time:
+			if t.Year() > 1601 {
+				return t.UTC()
+			}
+		case int64:
+			return fileTimeToTime(t)
+		case float64:
+			return fileTimeToTime(int64(t))
+		case string:
+			return parseLDAPTime(t)
+		}
+	}
+	return time.Time{}
+}
+
+// Duration returns a policy interval (maxPwdAge, lockoutDuration ...). AD
+// stores them as negative 100ns ticks; ldapdomaindump writes them as Python
+// timedelta strings. ok is false if the attribute is missing, 0 means never.
+func (e *LDAPEntry) Duration(attr string) (time.Duration, bool) {
+	for _, v := range e.Attributes[attr] {
+		switch t := v.(type) {
+		case int64:
+			return ticksToDuration(t), true
+		case float64:
+			return ticksToDuration(int64(t)), true
+		case string:
+			return parseLDAPDuration(t)
+		}
+	}
+	return 0, false
+}
+
+// ── Conversions ─────────────────────────────────────────────────────────────
+
+// decodeSID converts a binary SID into S-1-5-21-... notation.
+func decodeSID(b []byte) string {
+	if len(b) < 8 {
+		return ""
+	}
+	count := int(b[1])
+	if len(b) < 8+4*count {
+		return ""
+	}
+
+	var authority uint64
+	for _, octet := range b[2:8] {
+		authority = authority<<8 | uint64(octet)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "S-%d-%d", b[0], authority)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&sb, "-%d", binary.LittleEndian.Uint32(b[8+4*i:]))
+	}
+	return sb.String()
+}
+
+func fileTimeToTime(ticks int64) time.Time {
+	if ticks <= 0 || ticks == math.MaxInt64 {
+		return time.Time{}
+	}
+	ticks -= fileTimeUnixEpochDelta
+	return time.Unix(ticks/1e7, (ticks%1e7)*100).UTC()
+}
+
+func ticksToDuration(ticks int64) time.Duration {
+	if ticks == neverLargeInteger {
+		return 0
+	}
+	if ticks < 0 {
+		ticks = -ticks
+	}
+	if ticks/1e7 > maxPolicyDays*24*60*60 {
+		return 0
+	}
+	return time.Duration(ticks) * 100
+}
+
+var ldapTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999-07:00",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	"20060102150405.0Z",
+	"20060102150405Z",
+}
+
+func parseLDAPTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if ticks, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return fileTimeToTime(ticks)
+	}
+	for _, layout := range ldapTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if t.Year() <= 1601 {
+				return time.Time{}
+			}
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// timedeltaRe matches str(datetime.timedelta): "42 days, 0:00:00", "0:30:00".
+var timedeltaRe = regexp.MustCompile(`^(?:(-?\d+) days?, )?(\d+):(\d{2}):(\d{2})(?:\.\d+)?$`)
+
+func parseLDAPDuration(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if ticks, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ticksToDuration(ticks), true
+	}
+
+	m := timedeltaRe.FindStringSubmatch(value)
+	if m == nil {
+		return 0, false
+	}
+	days, _ := strconv.Atoi(m[1])
+	hours, _ := strconv.Atoi(m[2])
+	minutes, _ := strconv.Atoi(m[3])
+	seconds, _ := strconv.Atoi(m[4])
+
+	if days < 0 {
+		days = -days
+	}
+	if days > maxPolicyDays {
+		return 0, true // timedelta.max = never
+	}
+	return time.Duration(days)*24*time.Hour +
+		time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second, true
+}
+
+// ── Distinguished names ─────────────────────────────────────────────────────
+
+// splitDN splits a DN into its RDNs, honouring escaped commas.
+func splitDN(dn string) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range dn {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			current.WriteRune(r)
+			escaped = true
+		case r == ',':
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, strings.TrimSpace(current.String()))
+	}
+	return parts
+}
+
+// rdnValue returns the value of an RDN ("OU=Servers" → "Servers").
+func rdnValue(rdn string) string {
+	if idx := strings.Index(rdn, "="); idx >= 0 {
+		return strings.ReplaceAll(rdn[idx+1:], `\,`, ",")
+	}
+	return rdn
+}
+
+func rdnType(rdn string) string {
+	if idx := strings.Index(rdn, "="); idx >= 0 {
+		return strings.ToUpper(strings.TrimSpace(rdn[:idx]))
+	}
+	return ""
+}
+
+// parentDN returns the DN of the containing object, "" for a root.
+func parentDN(dn string) string {
+	parts := splitDN(dn)
+	if len(parts) <= 1 {
+		return ""
+	}
+	return strings.Join(parts[1:], ",")
+}
+
+// domainDN returns the trailing DC= components of a DN.
+func domainDN(dn string) string {
+	parts := splitDN(dn)
+	start := len(parts)
+	for start > 0 && rdnType(parts[start-1]) == "DC" {
+		start--
+	}
+	return strings.Join(parts[start:], ",")
+}
+
+// dnsNameFromDN converts "DC=corp,DC=local" into "corp.local".
+func dnsNameFromDN(dn string) string {
+	var labels []string
+	for _, part := range splitDN(domainDN(dn)) {
+		labels = append(labels, rdnValue(part))
+	}
+	return strings.ToLower(strings.Join(labels, "."))
+}
+
+// isDirectoryPartition reports whether the DN belongs to the configuration or
+// schema partition, which are not part of the domain graph.
+func isDirectoryPartition(dn string) bool {
+	upper := strings.ToUpper(dn)
+	return strings.HasPrefix(upper, "CN=CONFIGURATION,") || strings.Contains(upper, ",CN=CONFIGURATION,") ||
+		strings.HasPrefix(upper, "CN=SCHEMA,") || strings.HasPrefix(upper, "DC=DOMAINDNSZONES,") ||
+		strings.HasPrefix(upper, "DC=FORESTDNSZONES,") || strings.Contains(upper, ",DC=DOMAINDNSZONES,") ||
+		strings.Contains(upper, ",DC=FORESTDNSZONES,")
+}
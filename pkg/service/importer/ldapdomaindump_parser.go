@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const ldapDomainDumpSource = "ldapdomaindump"
+
+// ldapDomainDumpFiles maps the JSON files ldapdomaindump writes to the entry
+// kind they contain. domain_trusts.json is read but not mapped yet.
+var ldapDomainDumpFiles = map[string]LDAPEntryKind{
+	"domain_users":     LDAPKindUser,
+	"domain_groups":    LDAPKindGroup,
+	"domain_computers": LDAPKindComputer,
+	"domain_policy":    LDAPKindDomain,
+	"domain_trusts":    LDAPKindUnknown,
+}
+
+// ldapDomainDumpEntry is one object as serialised by ldap3's entry_to_json.
+type ldapDomainDumpEntry struct {
+	DN         string                     `json:"dn"`
+	Attributes map[string]json.RawMessage `json:"attributes"`
+}
+
+// LDAPDumpFile is one uploaded file of an offline LDAP dump.
+type LDAPDumpFile struct {
+	Name string
+	Data []byte
+}
+
+// ParseLDAPDomainDump reads the JSON output of ldapdomaindump, either as the
+// individual domain_*.json files or as a ZIP archive of the output directory.
+// The file name decides what an entry is, because ldapdomaindump does not
+// export objectClass.
+func ParseLDAPDomainDump(files ...LDAPDumpFile) (*LDAPSnapshot, error) {
+	snapshot := &LDAPSnapshot{Source: ldapDomainDumpSource}
+
+	for _, file := range files {
+		snapshot.Files = append(snapshot.Files, file.Name)
+
+		if bytes.HasPrefix(file.Data, []byte("PK\x03\x04")) {
+			if err := parseLDAPDomainDumpZip(file.Data, snapshot); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			continue
+		}
+		if err := parseLDAPDomainDumpFile(file.Name, file.Data, snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	if snapshot.Count() == 0 {
+		return nil, fmt.Errorf("dump contains no users, groups, computers or domain policy")
+	}
+	return snapshot, nil
+}
+
+func parseLDAPDomainDumpZip(data []byte, snapshot *LDAPSnapshot) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	remaining := int64(maxArchiveSize)
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !strings.EqualFold(path.Ext(entry.Name), ".json") {
+			continue
+		}
+		content, err := readZipEntry(entry, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= int64(len(content))
+		if err := parseLDAPDomainDumpFile(entry.Name, content, snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseLDAPDomainDumpFile(name string, content []byte, snapshot *LDAPSnapshot) error {
+	base := strings.ToLower(strings.TrimSuffix(path.Base(name), path.Ext(name)))
+	kind, known := ldapDomainDumpFiles[base]
+	if known && kind == LDAPKindUnknown {
+		return nil
+	}
+
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	var raw []ldapDomainDumpEntry
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return fmt.Errorf("%s: invalid ldapdomaindump json: %w", name, err)
+	}
+
+	for _, r := range raw {
+		if r.DN == "" {
+			continue
+		}
+		entry := newLDAPEntry(r.DN)
+		for attr, value := range r.Attributes {
+			entry.add(attr, decodeLDAPDomainDumpValue(value)...)
+		}
+
+		entry.Kind = kind
+		if !known {
+			entry.Kind = entry.classify()
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	return nil
+}
+
+// decodeLDAPDomainDumpValue flattens an attribute, which ldap3 writes as list
+// for multi-valued and as scalar for some single-valued attributes. Integral
+// numbers become int64 so large FILETIME values keep their precision.
+func decodeLDAPDomainDumpValue(raw json.RawMessage) []interface{} {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				out = append(out, i)
+			} else if f, err := n.Float64(); err == nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if v != nil {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type nessusClientData struct {
+	XMLName xml.Name `xml:"NessusClientData_v2"`
+	Report  struct {
+		Name  string             `xml:"name,attr"`
+		Hosts []nessusReportHost `xml:"ReportHost"`
+	} `xml:"Report"`
+}
+
+type nessusReportHost struct {
+	Name       string `xml:"name,attr"`
+	Properties struct {
+		Tags []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"tag"`
+	} `xml:"HostProperties"`
+	Items []nessusReportItem `xml:"ReportItem"`
+}
+
+type nessusReportItem struct {
+	Port             string   `xml:"port,attr"`
+	ServiceName      string   `xml:"svc_name,attr"`
+	Protocol         string   `xml:"protocol,attr"`
+	Severity         int      `xml:"severity,attr"`
+	PluginID         string   `xml:"pluginID,attr"`
+	PluginName       string   `xml:"pluginName,attr"`
+	CVEs             []string `xml:"cve"`
+	CVSS3BaseScore   string   `xml:"cvss3_base_score"`
+	CVSS3Vector      string   `xml:"cvss3_vector"`
+	CVSSBaseScore    string   `xml:"cvss_base_score"`
+	CVSSVector       string   `xml:"cvss_vector"`
+	ExploitAvailable string   `xml:"exploit_available"`
+	PluginOutput     string   `xml:"plugin_output"`
+	Synopsis         string   `xml:"synopsis"`
+	Description      string   `xml:"description"`
+	Solution         string   `xml:"solution"`
+}
+
+var nessusSeverities = []string{
+	model.SeverityInfo, model.SeverityLow, model.SeverityMedium, model.SeverityHigh, model.SeverityCritical,
+}
+
+// ParseNessus parses a .nessus (NessusClientData_v2) export.
+func ParseNessus(data []byte) (*VulnerabilityReport, error) {
+	var client nessusClientData
+	if err := xml.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &client); err != nil {
+		return nil, fmt.Errorf("invalid .nessus file: %w", err)
+	}
+
+	report := &VulnerabilityReport{Scanner: ScannerNessus, Name: client.Report.Name}
+	for _, rh := range client.Report.Hosts {
+		tags := make(map[string]string, len(rh.Properties.Tags))
+		for _, t := range rh.Properties.Tags {
+			tags[t.Name] = strings.TrimSpace(t.Value)
+		}
+
+		ip := tags["host-ip"]
+		if ip == "" {
+			ip = rh.Name
+		}
+		host := report.host(ip)
+		host.FQDN = firstNonEmpty(tags["host-fqdn"], host.FQDN)
+		host.NetBIOSName = firstNonEmpty(tags["netbios-name"], host.NetBIOSName)
+		host.OperatingSystem = firstNonEmpty(tags["operating-system"], host.OperatingSystem)
+
+		for _, item := range rh.Items {
+			host.Findings = append(host.Findings, nessusFinding(item))
+		}
+	}
+
+	if len(report.Hosts) == 0 {
+		return nil, fmt.Errorf("report contains no hosts")
+	}
+	return report, nil
+}
+
+func nessusFinding(item nessusReportItem) *VulnerabilityFinding {
+	f := &VulnerabilityFinding{
+		PluginID:         item.PluginID,
+		Name:             item.PluginName,
+		Protocol:         strings.ToLower(item.Protocol),
+		ServiceName:      item.ServiceName,
+		CVEs:             normalizeCVEs(item.CVEs),
+		Evidence:         strings.TrimSpace(item.PluginOutput),
+		Description:      strings.TrimSpace(firstNonEmpty(item.Synopsis, item.Description)),
+		Solution:         strings.TrimSpace(item.Solution),
+		ExploitAvailable: strings.EqualFold(strings.TrimSpace(item.ExploitAvailable), "true"),
+	}
+
+	// port 0 is used for host level findings
+	if item.Port != "" && item.Port != "0" {
+		f.Port = item.Port
+	}
+
+	if score, err := strconv.ParseFloat(strings.TrimSpace(item.CVSS3BaseScore), 64); err == nil {
+		f.CVSS, f.CVSSVector = score, strings.TrimSpace(item.CVSS3Vector)
+	} else if score, err := strconv.ParseFloat(strings.TrimSpace(item.CVSSBaseScore), 64); err == nil {
+		f.CVSS, f.CVSSVector = score, strings.TrimSpace(item.CVSSVector)
+	}
+
+	if item.Severity >= 0 && item.Severity < len(nessusSeverities) {
+		f.Severity = nessusSeverities[item.Severity]
+	} else {
+		f.Severity = model.SeverityFromCVSS(f.CVSS)
+	}
+	return f
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nessusReport = `<?xml version="1.0" ?>
+<NessusClientData_v2>
+<Report name="internal">
+<ReportHost name="dc01">
+<HostProperties>
+<tag name="host-ip">10.0.0.10</tag>
+<tag name="host-fqdn">dc01.corp.local</tag>
+<tag name="netbios-name">DC01</tag>
+<tag name="operating-system">Microsoft Windows Server 2019</tag>
+</HostProperties>
+<ReportItem port="445" svc_name="cifs" protocol="TCP" severity="4" pluginID="97833" pluginName="MS17-010">
+<cve>cve-2017-0143</cve>
+<cve>CVE-2017-0144</cve>
+<cve>CVE-2017-0143</cve>
+<cvss3_base_score>8.1</cvss3_base_score>
+<cvss3_vector>CVSS:3.0/AV:N</cvss3_vector>
+<cvss_base_score>9.3</cvss_base_score>
+<cvss_vector>CVSS2#AV:N</cvss_vector>
+<exploit_available>true</exploit_available>
+<plugin_output> vulnerable </plugin_output>
+<synopsis>Remote code execution</synopsis>
+<description>Long description</description>
+<solution>Apply MS17-010</solution>
+</ReportItem>
+<ReportItem port="0" svc_name="general" protocol="tcp" severity="0" pluginID="19506" pluginName="Scan Information">
+<cvss_base_score>0.0</cvss_base_score>
+</ReportItem>
+</ReportHost>
+<ReportHost name="10.0.0.20">
+<ReportItem port="80" svc_name="www" protocol="tcp" severity="9" pluginID="1" pluginName="Out of range severity">
+<cvss_base_score>5.0</cvss_base_score>
+</ReportItem>
+</ReportHost>
+</Report>
+</NessusClientData_v2>`
+
+func TestParseNessus(t *testing.T) {
+	report, err := ParseNessus([]byte("\xef\xbb\xbf" + nessusReport))
+	if err != nil {
+		t.Fatalf("ParseNessus() error = %v", err)
+	}
+	if report.Scanner != ScannerNessus || report.Name != "internal" {
+		t.Errorf("report = %s %q, want %s %q", report.Scanner, report.Name, ScannerNessus, "internal")
+	}
+	if len(report.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want 2", len(report.Hosts))
+	}
+
+	dc := report.Hosts[0]
+	wantHost := VulnerabilityHost{IP: "10.0.0.10", FQDN: "dc01.corp.local", NetBIOSName: "DC01", OperatingSystem: "Microsoft Windows Server 2019"}
+	if dc.IP != wantHost.IP || dc.FQDN != wantHost.FQDN || dc.NetBIOSName != wantHost.NetBIOSName || dc.OperatingSystem != wantHost.OperatingSystem {
+		t.Errorf("host = %+v, want %+v", *dc, wantHost)
+	}
+	if got := report.Hosts[1].IP; got != "10.0.0.20" {
+		t.Errorf("host without host-ip tag = %q, want the report host name", got)
+	}
+
+	tests := []struct {
+		name string
+		got  *VulnerabilityFinding
+		want *VulnerabilityFinding
+	}{
+		{
+			name: "service finding prefers cvss3",
+			got:  dc.Findings[0],
+			want: &VulnerabilityFinding{
+				PluginID: "97833", Name: "MS17-010", Port: "445", Protocol: "tcp", ServiceName: "cifs",
+				Severity: model.SeverityCritical, CVSS: 8.1, CVSSVector: "CVSS:3.0/AV:N",
+				CVEs:     []string{"CVE-2017-0143", "CVE-2017-0144"},
+				Evidence: "vulnerable", Description: "Remote code execution", Solution: "Apply MS17-010",
+				ExploitAvailable: true,
+			},
+		},
+		{
+			name: "port 0 is a host level finding",
+			got:  dc.Findings[1],
+			want: &VulnerabilityFinding{
+				PluginID: "19506", Name: "Scan Information", Protocol: "tcp", ServiceName: "general",
+				Severity: model.SeverityInfo, CVEs: []string{},
+			},
+		},
+		{
+			name: "unknown severity falls back to cvss",
+			got:  report.Hosts[1].Findings[0],
+			want: &VulnerabilityFinding{
+				PluginID: "1", Name: "Out of range severity", Port: "80", Protocol: "tcp", ServiceName: "www",
+				Severity: model.SeverityMedium, CVSS: 5.0, CVEs: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("finding = %+v, want %+v", *tt.got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNessusErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not xml", data: "plugin,host\n1,2", wantErr: "invalid .nessus file"},
+		{name: "other xml root", data: "<nmaprun></nmaprun>", wantErr: "invalid .nessus file"},
+		{name: "no hosts", data: `<NessusClientData_v2><Report name="empty"></Report></NessusClientData_v2>`, wantErr: "no hosts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseNessus([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseNessus() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/adapter/scan"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/rpsdk"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/service/network"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const NmapSource = "NmapImport"
+
+// NmapFile is one uploaded nmap output file.
+type NmapFile struct {
+	Name   string
+	Result *scan.NmapScanResult
+}
+
+// NmapImporter feeds offline nmap output through the network.ScanProcessor
+// pipeline of the NetworkExplorer module so uploaded scans end up exactly like module scans,
+// except that their assertions are marked as imported.
+type NmapImporter struct {
+	services    *rpsdk.Services
+	runService  *ImportRunService
+	riskService *risk.RiskService
+	postgresCon *gorm.DB
+}
+
+func NewNmapImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) *NmapImporter {
+	return &NmapImporter{
+		services:    rpsdk.NewServicesContainer(dgraphCon, postgresCon),
+		runService:  NewImportRunService(postgresCon),
+		riskService: risk.NewRiskService(dgraphCon),
+		postgresCon: postgresCon,
+	}
+}
+
+// nmapRun adapts the import summary to network.ResultRecorder and reports
+// failures on the SSE stream.
+type nmapRun struct {
+	summary *ImportSummary
+	logger  *sse.SSELogger
+}
+
+func (r *nmapRun) emit(eventType events.EventType, data map[string]interface{}) {
+	emitImportEvent(r.logger, r.summary, eventType, data)
+}
+
+func (r *nmapRun) RecordOutcome(entityType string, metadata *res.ResultMetadata) {
+	r.summary.RecordOutcome(entityType, metadata)
+}
+
+func (r *nmapRun) RecordFailure(entityType, key string, err error) {
+	r.summary.RecordFailure(entityType, key, err)
+	log.Printf("[NmapImport] %s %s failed: %v", entityType, key, err)
+	r.emit(events.ImportError, map[string]interface{}{
+		"type":  entityType,
+		"key":   key,
+		"error": err.Error(),
+	})
+}
+
+// Import writes the hosts of all files into the project and records the run.
+// runID selects the SSE stream progress is reported on.
+func (i *NmapImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	files []NmapFile,
+) (*ImportSummary, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no nmap files to import")
+	}
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	ctx = assertion.WithRunID(ctx, runID)
+
+	run := &nmapRun{
+		summary: newImportSummary(runID, NmapSource, projectUID),
+		logger:  sse.GetLogger(runID, projectUID, i.postgresCon),
+	}
+
+	names := make([]string, 0, len(files))
+	results := make([]*scan.NmapScanResult, 0, len(files))
+	hostCount := 0
+	for _, f := range files {
+		names = append(names, f.Name)
+		results = append(results, f.Result)
+		if f.Result != nil {
+			hostCount += len(f.Result.GetNmapResult().Host)
+		}
+	}
+
+	run.emit(events.ImportStart, map[string]interface{}{
+		"files": names,
+		"hosts": hostCount,
+	})
+	log.Printf("[NmapImport] Start project=%s files=%d hosts=%d", projectUID, len(files), hostCount)
+
+	err := network.ImportNmapResults(ctx, i.services, network.Options{
+		ProjectUID: projectUID,
+		Actor:      NmapSource,
+		Method:     string(core.MethodImported),
+		Logger:     run.logger,
+		Recorder:   run,
+	}, results...)
+
+	i.riskService.Rescore(ctx, run.summary.ProjectUID, NmapSource)
+
+	run.summary.finish()
+	i.runService.Record(ctx, run.summary, names, NmapSource, err == nil)
+
+	if err != nil {
+		run.emit(events.ImportError, map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+
+	run.emit(events.ImportComplete, map[string]interface{}{
+		"created":    run.summary.Created,
+		"merged":     run.summary.Merged,
+		"duplicates": run.summary.Duplicates,
+		"failed":     run.summary.Failed,
+	})
+	log.Printf("[NmapImport] Done project=%s created=%d merged=%d duplicates=%d failed=%d",
+		projectUID, run.summary.Created, run.summary.Merged, run.summary.Duplicates, run.summary.Failed)
+
+	return run.summary, nil
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type openVASResult struct {
+	Name string `xml:"name"`
+	Host struct {
+		IP       string `xml:",chardata"`
+		Hostname string `xml:"hostname"`
+	} `xml:"host"`
+	Port string `xml:"port"`
+	NVT  struct {
+		OID        string `xml:"oid,attr"`
+		Name       string `xml:"name"`
+		CVSSBase   string `xml:"cvss_base"`
+		CVE        string `xml:"cve"` // GMP < 9: comma separated
+		Tags       string `xml:"tags"`
+		Solution   string `xml:"solution"`
+		Severities struct {
+			Severity []struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:"value"`
+			} `xml:"severity"`
+		} `xml:"severities"`
+		Refs struct {
+			Ref []struct {
+				Type string `xml:"type,attr"`
+				ID   string `xml:"id,attr"`
+			} `xml:"ref"`
+		} `xml:"refs"`
+	} `xml:"nvt"`
+	Threat      string `xml:"threat"`
+	Severity    string `xml:"severity"`
+	Description string `xml:"description"`
+}
+
+type openVASHost struct {
+	IP     string `xml:"ip"`
+	Detail []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"detail"`
+}
+
+// ParseOpenVAS parses an OpenVAS / Greenbone XML report (GMP get_reports
+// export). Results are collected wherever they are nested.
+func ParseOpenVAS(data []byte) (*VulnerabilityReport, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	report := &VulnerabilityReport{Scanner: ScannerOpenVAS}
+	sawReport := false
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenVAS report: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "report":
+			sawReport = true
+		case "result":
+			var result openVASResult
+			if err := decoder.DecodeElement(&result, &start); err != nil {
+				return nil, fmt.Errorf("invalid OpenVAS result: %w", err)
+			}
+			ip := strings.TrimSpace(result.Host.IP)
+			if ip == "" || result.NVT.OID == "" {
+				continue
+			}
+			host := report.host(ip)
+			if hostname := strings.TrimSpace(result.Host.Hostname); hostname != "" {
+				host.FQDN = hostname
+			}
+			host.Findings = append(host.Findings, openVASFinding(result))
+		case "host":
+			// report level host details (results decode their own <host>)
+			var h openVASHost
+			if err := decoder.DecodeElement(&h, &start); err != nil {
+				return nil, fmt.Errorf("invalid OpenVAS host: %w", err)
+			}
+			ip := strings.TrimSpace(h.IP)
+			if ip == "" {
+				continue
+			}
+			host := report.host(ip)
+			for _, d := range h.Detail {
+				switch d.Name {
+				case "best_os_txt":
+					host.OperatingSystem = strings.TrimSpace(d.Value)
+				case "hostname":
+					host.FQDN = firstNonEmpty(host.FQDN, strings.TrimSpace(d.Value))
+				}
+			}
+		}
+	}
+
+	if !sawReport {
+		return nil, fmt.Errorf("not an OpenVAS report")
+	}
+	if len(report.Hosts) == 0 {
+		return nil, fmt.Errorf("report contains no hosts")
+	}
+	return report, nil
+}
+
+func openVASFinding(result openVASResult) *VulnerabilityFinding {
+	tags := parseOpenVASTags(result.NVT.Tags)
+
+	f := &VulnerabilityFinding{
+		PluginID:    result.NVT.OID,
+		Name:        firstNonEmpty(strings.TrimSpace(result.NVT.Name), strings.TrimSpace(result.Name)),
+		Evidence:    strings.TrimSpace(result.Description),
+		Description: tags["summary"],
+		Solution:    firstNonEmpty(strings.TrimSpace(result.NVT.Solution), tags["solution"]),
+		CVSSVector:  tags["cvss_base_vector"],
+	}
+
+	// "445/tcp", "general/tcp", "package"
+	if port, proto, ok := strings.Cut(strings.TrimSpace(result.Port), "/"); ok {
+		f.Protocol = strings.ToLower(proto)
+		if _, err := strconv.Atoi(port); err == nil {
+			f.Port = port
+		}
+	}
+
+	var cves []string
+	for _, ref := range result.NVT.Refs.Ref {
+		if strings.EqualFold(ref.Type, "cve") {
+			cves = append(cves, ref.ID)
+		}
+	}
+	if result.NVT.CVE != "" && !strings.EqualFold(result.NVT.CVE, "NOCVE") {
+		cves = append(cves, strings.Split(result.NVT.CVE, ",")...)
+	}
+	f.CVEs = normalizeCVEs(cves)
+
+	for _, sev := range result.NVT.Severities.Severity {
+		if strings.HasPrefix(sev.Type, "cvss_base") && sev.Value != "" {
+			f.CVSSVector = strings.TrimSpace(sev.Value)
+		}
+	}
+
+	if score, err := strconv.ParseFloat(strings.TrimSpace(result.Severity), 64); err == nil && score >= 0 {
+		f.CVSS = score
+	} else if score, err := strconv.ParseFloat(strings.TrimSpace(result.NVT.CVSSBase), 64); err == nil {
+		f.CVSS = score
+	}
+
+	if strings.EqualFold(result.Threat, "Log") {
+		f.Severity = model.SeverityInfo
+	} else {
+		f.Severity = model.SeverityFromCVSS(f.CVSS)
+	}
+	return f
+}
+
+// parseOpenVASTags splits the "key=value|key=value" NVT tag string.
+func parseOpenVASTags(raw string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(raw, "|") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const openVASReport = `<?xml version="1.0" encoding="UTF-8"?>
+<report id="r1">
+<report id="r1">
+<results>
+<result id="1">
+<name>SMB Signing Not Required</name>
+<host>10.0.0.10<hostname>dc01.corp.local</hostname></host>
+<port>445/tcp</port>
+<nvt oid="1.3.6.1.4.1.25623.1.0.1">
+<name>SMB signing not required</name>
+<cvss_base>5.0</cvss_base>
+<cve>CVE-2016-2115, NOCVE</cve>
+<tags>cvss_base_vector=AV:N/AC:L|summary=Signing is not enforced|solution=Enable signing</tags>
+<severities><severity type="cvss_base_v3"><value>CVSS:3.1/AV:N</value></severity></severities>
+<refs><ref type="cve" id="cve-2016-2115"/><ref type="url" id="https://example.org"/></refs>
+</nvt>
+<threat>Medium</threat>
+<severity>5.3</severity>
+<description>Signing is disabled</description>
+</result>
+<result id="2">
+<name>OS Detection</name>
+<host>10.0.0.10</host>
+<port>general/tcp</port>
+<nvt oid="1.3.6.1.4.1.25623.1.0.2"><name>OS Detection Consolidation</name><cve>NOCVE</cve></nvt>
+<threat>Log</threat>
+<severity>0.0</severity>
+</result>
+<result id="3">
+<name>Without host</name>
+<host></host>
+<nvt oid="1.3.6.1.4.1.25623.1.0.3"/>
+</result>
+</results>
+<host><ip>10.0.0.10</ip><detail><name>best_os_txt</name><value>Windows Server 2019</value></detail></host>
+<host><ip>10.0.0.30</ip><detail><name>hostname</name><value>web01.corp.local</value></detail></host>
+</report>
+</report>`
+
+func TestParseOpenVAS(t *testing.T) {
+	report, err := ParseOpenVAS([]byte(openVASReport))
+	if err != nil {
+		t.Fatalf("ParseOpenVAS() error = %v", err)
+	}
+	if len(report.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want 2", len(report.Hosts))
+	}
+
+	dc, web := report.Hosts[0], report.Hosts[1]
+	if dc.IP != "10.0.0.10" || dc.FQDN != "dc01.corp.local" || dc.OperatingSystem != "Windows Server 2019" {
+		t.Errorf("host = %+v", *dc)
+	}
+	if web.IP != "10.0.0.30" || web.FQDN != "web01.corp.local" || len(web.Findings) != 0 {
+		t.Errorf("host details without results = %+v", *web)
+	}
+	if len(dc.Findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(dc.Findings))
+	}
+
+	tests := []struct {
+		name string
+		got  *VulnerabilityFinding
+		want *VulnerabilityFinding
+	}{
+		{
+			name: "service finding",
+			got:  dc.Findings[0],
+			want: &VulnerabilityFinding{
+				PluginID: "1.3.6.1.4.1.25623.1.0.1", Name: "SMB signing not required", Port: "445", Protocol: "tcp",
+				Severity: model.SeverityMedium, CVSS: 5.3, CVSSVector: "CVSS:3.1/AV:N",
+				CVEs:     []string{"CVE-2016-2115"},
+				Evidence: "Signing is disabled", Description: "Signing is not enforced", Solution: "Enable signing",
+			},
+		},
+		{
+			name: "log results are informational",
+			got:  dc.Findings[1],
+			want: &VulnerabilityFinding{
+				PluginID: "1.3.6.1.4.1.25623.1.0.2", Name: "OS Detection Consolidation", Protocol: "tcp",
+				Severity: model.SeverityInfo, CVEs: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("finding = %+v, want %+v", *tt.got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOpenVASErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "broken xml", data: "<report><results>", wantErr: "invalid OpenVAS report"},
+		{name: "other xml", data: "<NessusClientData_v2/>", wantErr: "not an OpenVAS report"},
+		{name: "no hosts", data: "<report><results></results></report>", wantErr: "no hosts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOpenVAS([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseOpenVAS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDomainPolicyGUID is the GPO id of the Default Domain Policy, the one
+// that defines the domain password policy.
+const defaultDomainPolicyGUID = "{31B2F340-016D-11D2-945F-00C04FB984F9}"
+
+// pingCastleHealthcheck covers the parts of ad_hc_<domain>.xml the importer maps.
+type pingCastleHealthcheck struct {
+	XMLName               xml.Name `xml:"HealthcheckData"`
+	GenerationDate        string   `xml:"GenerationDate"`
+	DomainFQDN            string   `xml:"DomainFQDN"`
+	NetBIOSName           string   `xml:"NetBIOSName"`
+	ForestFQDN            string   `xml:"ForestFQDN"`
+	DomainSid             string   `xml:"DomainSid"`
+	DomainFunctionalLevel string   `xml:"DomainFunctionalLevel"`
+	ForestFunctionalLevel string   `xml:"ForestFunctionalLevel"`
+	GlobalScore           int      `xml:"GlobalScore"`
+
+	RiskRules []struct {
+		Points    int      `xml:"Points"`
+		Category  string   `xml:"Category"`
+		Model     string   `xml:"Model"`
+		RiskID    string   `xml:"RiskId"`
+		Rationale string   `xml:"Rationale"`
+		Details   []string `xml:"Details>string"`
+	} `xml:"RiskRules>HealthcheckRiskRule"`
+
+	PasswordPolicies []struct {
+		GPOName    string `xml:"GPOName"`
+		GPOID      string `xml:"GPOId"`
+		Properties []struct {
+			Property string `xml:"Property"`
+			Value    int    `xml:"Value"`
+		} `xml:"Properties>GPPSecurityPolicyProperty"`
+	} `xml:"GPPPasswordPolicy>GPPSecurityPolicy"`
+
+	PrivilegedGroups []struct {
+		GroupName string                 `xml:"GroupName"`
+		Members   []pingCastleMemberData `xml:"Members>HealthCheckGroupMemberData"`
+	} `xml:"PrivilegedGroups>HealthCheckGroupData"`
+
+	AllPrivilegedMembers []pingCastleMemberData `xml:"AllPrivilegedMembers>HealthCheckGroupMemberData"`
+
+	DomainControllers []struct {
+		DCName            string   `xml:"DCName"`
+		OperatingSystem   string   `xml:"OperatingSystem"`
+		DistinguishedName string   `xml:"DistinguishedName"`
+		IPs               []string `xml:"IP>string"`
+	} `xml:"DomainControllers>HealthcheckDomainController"`
+}
+
+type pingCastleMemberData struct {
+	Name                string `xml:"Name"`
+	DistinguishedName   string `xml:"DistinguishedName"`
+	IsEnabled           bool   `xml:"IsEnabled"`
+	IsLocked            bool   `xml:"IsLocked"`
+	DoesPwdNeverExpires bool   `xml:"DoesPwdNeverExpires"`
+	CanBeDelegated      bool   `xml:"CanBeDelegated"`
+	IsService           bool   `xml:"IsService"`
+	LastLogonTimestamp  string `xml:"LastLogonTimestamp"`
+	PwdLastSet          string `xml:"PwdLastSet"`
+}
+
+// ParsePingCastle reads a PingCastle health check report (ad_hc_*.xml).
+func ParsePingCastle(data []byte) (*RiskReport, error) {
+	var hc pingCastleHealthcheck
+	if err := xml.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &hc); err != nil {
+		return nil, fmt.Errorf("invalid PingCastle report: %w", err)
+	}
+	if hc.DomainFQDN == "" {
+		return nil, fmt.Errorf("invalid PingCastle report: DomainFQDN missing")
+	}
+
+	report := &RiskReport{
+		Tool:        ToolPingCastle,
+		GeneratedAt: parsePingCastleTime(hc.GenerationDate),
+		GlobalScore: hc.GlobalScore,
+		Domain: RiskDomain{
+			FQDN:                  strings.ToLower(hc.DomainFQDN),
+			NetBIOSName:           hc.NetBIOSName,
+			SID:                   strings.ToUpper(hc.DomainSid),
+			ForestFQDN:            strings.ToLower(hc.ForestFQDN),
+			DomainFunctionalLevel: hc.DomainFunctionalLevel,
+			ForestFunctionalLevel: hc.ForestFunctionalLevel,
+		},
+	}
+
+	for _, rule := range hc.RiskRules {
+		if rule.RiskID == "" {
+			continue
+		}
+		details := make([]string, 0, len(rule.Details))
+		for _, d := range rule.Details {
+			if d = strings.TrimSpace(d); d != "" {
+				details = append(details, d)
+			}
+		}
+		report.Findings = append(report.Findings, &RiskFinding{
+			RuleID:    rule.RiskID,
+			Category:  rule.Category,
+			Model:     rule.Model,
+			Points:    rule.Points,
+			Rationale: strings.TrimSpace(rule.Rationale),
+			Details:   details,
+		})
+	}
+
+	// Default Domain Policy first, otherwise the first GPO defining a password policy
+	for _, gpo := range hc.PasswordPolicies {
+		properties := make(map[string]int, len(gpo.Properties))
+		for _, p := range gpo.Properties {
+			properties[strings.ToLower(p.Property)] = p.Value
+		}
+		if _, ok := properties["minimumpasswordlength"]; !ok {
+			continue
+		}
+		if report.PasswordPolicy != nil && !strings.EqualFold(gpo.GPOID, defaultDomainPolicyGUID) {
+			continue
+		}
+		report.PasswordPolicy = pingCastlePasswordPolicy(properties)
+	}
+
+	accounts := make(map[string]*RiskAccount)
+	account := func(m pingCastleMemberData) *RiskAccount {
+		key := strings.ToLower(m.Name)
+		if a, ok := accounts[key]; ok {
+			return a
+		}
+		a := &RiskAccount{
+			Name:               m.Name,
+			DN:                 m.DistinguishedName,
+			IsEnabled:          m.IsEnabled,
+			IsLocked:           m.IsLocked,
+			PwdNeverExpires:    m.DoesPwdNeverExpires,
+			CanBeDelegated:     m.CanBeDelegated,
+			IsService:          m.IsService,
+			LastLogonTimestamp: parsePingCastleTime(m.LastLogonTimestamp),
+			PwdLastSet:         parsePingCastleTime(m.PwdLastSet),
+		}
+		accounts[key] = a
+		report.Accounts = append(report.Accounts, a)
+		return a
+	}
+	for _, m := range hc.AllPrivilegedMembers {
+		if m.Name != "" {
+			account(m)
+		}
+	}
+	for _, group := range hc.PrivilegedGroups {
+		for _, m := range group.Members {
+			if m.Name != "" {
+				a := account(m)
+				a.Groups = append(a.Groups, group.GroupName)
+			}
+		}
+	}
+
+	for _, dc := range hc.DomainControllers {
+		if dc.DCName == "" {
+			continue
+		}
+		report.DomainControllers = append(report.DomainControllers, &RiskDomainController{
+			Name:            dc.DCName,
+			OperatingSystem: dc.OperatingSystem,
+			IPs:             dc.IPs,
+			DN:              dc.DistinguishedName,
+		})
+	}
+
+	report.resolveObjects()
+	return report, nil
+}
+
+// pingCastlePasswordPolicy maps the GPO security settings; ages are in days,
+// durations in minutes and -1 means never.
+func pingCastlePasswordPolicy(properties map[string]int) *rpad.SecurityPolicy {
+	positive := func(key string) int {
+		if v := properties[key]; v > 0 {
+			return v
+		}
+		return 0
+	}
+	return &rpad.SecurityPolicy{
+		MinPwdLength:         properties["minimumpasswordlength"],
+		PwdHistoryLength:     positive("passwordhistorysize"),
+		LockoutThreshold:     positive("lockoutbadcount"),
+		LockoutDuration:      positive("lockoutduration"),
+		LockoutWindow:        positive("resetlockoutcount"),
+		MaxPwdAge:            positive("maximumpasswordage"),
+		MinPwdAge:            positive("minimumpasswordage"),
+		PwdComplexity:        properties["passwordcomplexity"] == 1,
+		ReversibleEncryption: properties["cleartextpassword"] == 1,
+	}
+}
+
+func parsePingCastleTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return parseLDAPTime(value)
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.9999999", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if t.Year() <= 1601 {
+				return time.Time{}
+			}
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,203 @@
+package importer
+
+import (
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pingCastleReport = `<?xml version="1.0" encoding="utf-8"?>
+<HealthcheckData>
+  <GenerationDate>2024-05-01T10:00:00.1234567+02:00</GenerationDate>
+  <DomainFQDN>CORP.LOCAL</DomainFQDN>
+  <NetBIOSName>CORP</NetBIOSName>
+  <ForestFQDN>Corp.Local</ForestFQDN>
+  <DomainSid>s-1-5-21-1-2-3</DomainSid>
+  <GlobalScore>55</GlobalScore>
+  <RiskRules>
+    <HealthcheckRiskRule>
+      <Points>20</Points><Category>PrivilegedAccounts</Category><Model>Kerberoasting</Model>
+      <RiskId>P-Kerberoasting</RiskId><Rationale> Kerberoastable admins </Rationale>
+      <Details><string>Account: CORP\svc_sql Member of: Domain Admins</string><string> </string></Details>
+    </HealthcheckRiskRule>
+    <HealthcheckRiskRule><Points>5</Points><RiskId></RiskId></HealthcheckRiskRule>
+    <HealthcheckRiskRule>
+      <Points>50</Points><RiskId>P-UnconstrainedDelegation</RiskId>
+      <Details>
+        <string>Computer: SRV01$</string>
+        <string>CN=DC01,OU=Domain Controllers,DC=corp,DC=local</string>
+        <string>CN=Unknown,OU=Servers,DC=corp,DC=local</string>
+      </Details>
+    </HealthcheckRiskRule>
+    <HealthcheckRiskRule>
+      <Points>10</Points><RiskId>P-OperatorsEmpty</RiskId>
+      <Details><string>Group: Backup Operators</string></Details>
+    </HealthcheckRiskRule>
+  </RiskRules>
+  <GPPPasswordPolicy>
+    <GPPSecurityPolicy>
+      <GPOName>Audit</GPOName><GPOId>{AAAA}</GPOId>
+      <Properties><GPPSecurityPolicyProperty><Property>AuditLogon</Property><Value>1</Value></GPPSecurityPolicyProperty></Properties>
+    </GPPSecurityPolicy>
+    <GPPSecurityPolicy>
+      <GPOName>Servers</GPOName><GPOId>{BBBB}</GPOId>
+      <Properties><GPPSecurityPolicyProperty><Property>MinimumPasswordLength</Property><Value>12</Value></GPPSecurityPolicyProperty></Properties>
+    </GPPSecurityPolicy>
+    <GPPSecurityPolicy>
+      <GPOName>Default Domain Policy</GPOName><GPOId>{31b2f340-016d-11d2-945f-00c04fb984f9}</GPOId>
+      <Properties>
+        <GPPSecurityPolicyProperty><Property>MinimumPasswordLength</Property><Value>7</Value></GPPSecurityPolicyProperty>
+        <GPPSecurityPolicyProperty><Property>PasswordHistorySize</Property><Value>24</Value></GPPSecurityPolicyProperty>
+        <GPPSecurityPolicyProperty><Property>MaximumPasswordAge</Property><Value>-1</Value></GPPSecurityPolicyProperty>
+        <GPPSecurityPolicyProperty><Property>LockoutBadCount</Property><Value>0</Value></GPPSecurityPolicyProperty>
+        <GPPSecurityPolicyProperty><Property>PasswordComplexity</Property><Value>1</Value></GPPSecurityPolicyProperty>
+        <GPPSecurityPolicyProperty><Property>ClearTextPassword</Property><Value>0</Value></GPPSecurityPolicyProperty>
+      </Properties>
+    </GPPSecurityPolicy>
+  </GPPPasswordPolicy>
+  <PrivilegedGroups>
+    <HealthCheckGroupData>
+      <GroupName>Domain Admins</GroupName>
+      <Members>
+        <HealthCheckGroupMemberData><Name>Administrator</Name></HealthCheckGroupMemberData>
+        <HealthCheckGroupMemberData><Name>svc_sql</Name><IsService>true</IsService><PwdLastSet>2023-01-02T03:04:05</PwdLastSet></HealthCheckGroupMemberData>
+      </Members>
+    </HealthCheckGroupData>
+  </PrivilegedGroups>
+  <AllPrivilegedMembers>
+    <HealthCheckGroupMemberData>
+      <Name>Administrator</Name><DistinguishedName>CN=Administrator,CN=Users,DC=corp,DC=local</DistinguishedName>
+      <IsEnabled>true</IsEnabled><LastLogonTimestamp>1601-01-01T01:00:00</LastLogonTimestamp>
+    </HealthCheckGroupMemberData>
+  </AllPrivilegedMembers>
+  <DomainControllers>
+    <HealthcheckDomainController>
+      <DCName>DC01</DCName><OperatingSystem>Windows Server 2019</OperatingSystem>
+      <DistinguishedName>CN=DC01,OU=Domain Controllers,DC=corp,DC=local</DistinguishedName>
+      <IP><string>10.0.0.10</string></IP>
+    </HealthcheckDomainController>
+  </DomainControllers>
+</HealthcheckData>`
+
+func TestParsePingCastle(t *testing.T) {
+	report, err := ParsePingCastle([]byte("\xef\xbb\xbf" + pingCastleReport))
+	if err != nil {
+		t.Fatalf("ParsePingCastle() error = %v", err)
+	}
+
+	wantDomain := RiskDomain{FQDN: "corp.local", NetBIOSName: "CORP", SID: "S-1-5-21-1-2-3", ForestFQDN: "corp.local"}
+	if report.Domain != wantDomain {
+		t.Errorf("Domain = %+v, want %+v", report.Domain, wantDomain)
+	}
+	if want := time.Date(2024, 5, 1, 8, 0, 0, 123456700, time.UTC); !report.GeneratedAt.Equal(want) {
+		t.Errorf("GeneratedAt = %v, want %v", report.GeneratedAt, want)
+	}
+	if report.GlobalScore != 55 {
+		t.Errorf("GlobalScore = %d, want 55", report.GlobalScore)
+	}
+
+	wantPolicy := &rpad.SecurityPolicy{MinPwdLength: 7, PwdHistoryLength: 24, PwdComplexity: true}
+	if !reflect.DeepEqual(report.PasswordPolicy, wantPolicy) {
+		t.Errorf("PasswordPolicy = %+v, want the Default Domain Policy %+v", report.PasswordPolicy, wantPolicy)
+	}
+
+	findings := []struct {
+		rule    string
+		points  int
+		details int
+		objects []RiskObject
+	}{
+		{
+			rule: "P-Kerberoasting", points: 20, details: 1,
+			objects: []RiskObject{{Kind: RiskObjectUser, Name: "svc_sql"}},
+		},
+		{
+			rule: "P-UnconstrainedDelegation", points: 50, details: 3,
+			objects: []RiskObject{
+				{Kind: RiskObjectComputer, Name: "SRV01"},
+				{Kind: RiskObjectComputer, Name: "DC01", DN: "CN=DC01,OU=Domain Controllers,DC=corp,DC=local"},
+			},
+		},
+		{
+			rule: "P-OperatorsEmpty", points: 10, details: 1,
+			objects: []RiskObject{{Kind: RiskObjectGroup, Name: "Backup Operators"}},
+		},
+	}
+	if len(report.Findings) != len(findings) {
+		t.Fatalf("got %d findings, want %d", len(report.Findings), len(findings))
+	}
+	for k, want := range findings {
+		t.Run(want.rule, func(t *testing.T) {
+			got := report.Findings[k]
+			if got.RuleID != want.rule || got.Points != want.points || len(got.Details) != want.details {
+				t.Errorf("finding = %s (%d points, %d details), want %s (%d points, %d details)",
+					got.RuleID, got.Points, len(got.Details), want.rule, want.points, want.details)
+			}
+			if !reflect.DeepEqual(got.Objects, want.objects) {
+				t.Errorf("Objects = %+v, want %+v", got.Objects, want.objects)
+			}
+		})
+	}
+	if got := report.Findings[0].Rationale; got != "Kerberoastable admins" {
+		t.Errorf("Rationale = %q, want it trimmed", got)
+	}
+
+	if len(report.Accounts) != 2 {
+		t.Fatalf("got %d accounts, want 2", len(report.Accounts))
+	}
+	admin, svc := report.Accounts[0], report.Accounts[1]
+	if admin.Name != "Administrator" || !admin.IsEnabled || !admin.LastLogonTimestamp.IsZero() ||
+		!reflect.DeepEqual(admin.Groups, []string{"Domain Admins"}) {
+		t.Errorf("Administrator = %+v", *admin)
+	}
+	if svc.Name != "svc_sql" || !svc.IsService || !svc.PwdLastSet.Equal(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("svc_sql = %+v", *svc)
+	}
+
+	if len(report.DomainControllers) != 1 || report.DomainControllers[0].Name != "DC01" ||
+		!reflect.DeepEqual(report.DomainControllers[0].IPs, []string{"10.0.0.10"}) {
+		t.Errorf("DomainControllers = %+v", report.DomainControllers)
+	}
+}
+
+func TestParsePingCastleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not xml", data: "{}", wantErr: "invalid PingCastle report"},
+		{name: "other report", data: "<NessusClientData_v2/>", wantErr: "invalid PingCastle report"},
+		{name: "no domain", data: "<HealthcheckData><GlobalScore>1</GlobalScore></HealthcheckData>", wantErr: "DomainFQDN missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePingCastle([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParsePingCastle() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRiskObject(t *testing.T) {
+	tests := []struct {
+		kind  RiskObjectKind
+		value string
+		want  RiskObject
+	}{
+		{RiskObjectUser, `CORP\alice`, RiskObject{Kind: RiskObjectUser, Name: "alice"}},
+		{RiskObjectUser, "WS01$", RiskObject{Kind: RiskObjectComputer, Name: "WS01"}},
+		{RiskObjectUser, "bob (S-1-5-21-1-2-3-1106)", RiskObject{Kind: RiskObjectUser, Name: "bob", SID: "S-1-5-21-1-2-3-1106"}},
+		{RiskObjectGroup, "Domain Admins", RiskObject{Kind: RiskObjectGroup, Name: "Domain Admins"}},
+	}
+
+	for _, tt := range tests {
+		if got := riskObject(tt.kind, tt.value); got != tt.want {
+			t.Errorf("riskObject(%s, %q) = %+v, want %+v", tt.kind, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/change"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const PingCastleSource = "PingCastleImport"
+
+// RiskImporter maps AD health check reports onto the project: every triggered
+// rule becomes a finding on the domain (and on the affected computers), the
+// affected users and groups get the rule points as risk score and the rules
+// as risk reasons, and the password policy becomes the domain SecurityPolicy.
+type RiskImporter struct {
+	activeDirectoryService *active_directory.ActiveDirectoryService
+	domainService          *active_directory.DomainService
+	userService            *active_directory.UserService
+	groupService           *active_directory.GroupService
+	hostService            *active_directory.HostService
+	vulnService            *active_directory.VulnerabilityService
+	runService             *ImportRunService
+	riskService            *risk.RiskService
+	changeService          *change.ChangeService
+	postgresCon            *gorm.DB
+}
+
+func NewRiskImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*RiskImporter, error) {
+	activeDirectoryService, err := active_directory.NewActiveDirectoryService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	domainService, err := active_directory.NewDomainService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	userService, err := active_directory.NewUserService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	groupService, err := active_directory.NewGroupService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	hostService, err := active_directory.NewHostService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	vulnService, err := active_directory.NewVulnerabilityService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	changeService, err := change.NewChangeService(postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RiskImporter{
+		activeDirectoryService: activeDirectoryService,
+		domainService:          domainService,
+		userService:            userService,
+		groupService:           groupService,
+		hostService:            hostService,
+		vulnService:            vulnService,
+		runService:             NewImportRunService(postgresCon),
+		riskService:            risk.NewRiskService(dgraphCon),
+		changeService:          changeService,
+		postgresCon:            postgresCon,
+	}, nil
+}
+
+// riskPrincipalContext is used for users and groups named by a report. A
+// health check knows little more than the account name, so the confidence
+// stays below the threshold at which UpsertUser overwrites the account flags
+// (kerberoastable, disabled ...) of an existing user.
+func riskPrincipalContext() assertion.Context {
+	ctx := importedContext()
+	confidence := 0.7
+	ctx.Confidence = &confidence
+	return ctx
+}
+
+// riskRun holds the state of one import run.
+type riskRun struct {
+	projectUID string
+	actor      string
+	report     *RiskReport
+	summary    *ImportSummary
+	logger     *sse.SSELogger
+	domainUID  string
+
+	// computer name (lower case) → Host UID
+	hosts map[string]string
+}
+
+func (r *riskRun) emit(eventType events.EventType, data map[string]interface{}) {
+	emitImportEvent(r.logger, r.summary, eventType, data)
+}
+
+func (r *riskRun) fail(entityType, key string, err error) {
+	r.summary.RecordFailure(entityType, key, err)
+	log.Printf("[%s] %s %s failed: %v", r.actor, entityType, key, err)
+	r.emit(events.ImportError, map[string]interface{}{
+		"type":  entityType,
+		"key":   key,
+		"error": err.Error(),
+	})
+}
+
+// principalRisk accumulates the findings of one user or group.
+type principalRisk struct {
+	object   RiskObject
+	score    int
+	findings []*RiskFinding
+}
+
+func (p *principalRisk) reasons() []string {
+	sort.SliceStable(p.findings, func(a, b int) bool {
+		return p.findings[a].Points > p.findings[b].Points
+	})
+	reasons := make([]string, 0, len(p.findings))
+	for _, f := range p.findings {
+		reasons = append(reasons, f.Reason())
+	}
+	return reasons
+}
+
+// Import writes the report into the project and returns the summary. runID
+// selects the SSE stream progress is reported on.
+func (i *RiskImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	report *RiskReport,
+	files []string,
+) (*ImportSummary, error) {
+	if report == nil {
+		return nil, fmt.Errorf("no report to import")
+	}
+	if report.Tool != ToolPingCastle {
+		return nil, fmt.Errorf("unsupported report tool %q", report.Tool)
+	}
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	ctx = assertion.WithRunID(ctx, runID)
+
+	run := &riskRun{
+		projectUID: projectUID,
+		actor:      PingCastleSource,
+		report:     report,
+		summary:    newImportSummary(runID, PingCastleSource, projectUID),
+		logger:     sse.GetLogger(runID, projectUID, i.postgresCon),
+		hosts:      make(map[string]string),
+	}
+
+	run.emit(events.ImportStart, map[string]interface{}{
+		"domain":       report.Domain.FQDN,
+		"findings":     len(report.Findings),
+		"global_score": report.GlobalScore,
+	})
+	log.Printf("[%s] Start project=%s domain=%s findings=%d score=%d",
+		run.actor, projectUID, report.Domain.FQDN, len(report.Findings), report.GlobalScore)
+
+	if err := i.importDomain(ctx, run); err != nil {
+		run.fail("Domain", report.Domain.FQDN, err)
+	} else {
+		i.importPasswordPolicy(ctx, run)
+		i.importDomainControllers(ctx, run)
+
+		principals := make(map[string]*principalRisk)
+		for n, f := range report.Findings {
+			i.importFinding(ctx, run, f, principals)
+			if (n+1)%progressEvery == 0 || n+1 == len(report.Findings) {
+				run.emit(events.ImportProgress, map[string]interface{}{
+					"stage":    "findings",
+					"progress": fmt.Sprintf("%d/%d", n+1, len(report.Findings)),
+				})
+			}
+		}
+
+		run.emit(events.ImportProgress, map[string]interface{}{"stage": "principals"})
+		for _, key := range sortedKeys(principals) {
+			i.importPrincipalRisk(ctx, run, principals[key])
+		}
+	}
+
+	i.riskService.Rescore(ctx, run.summary.ProjectUID, run.actor)
+
+	run.summary.finish()
+	i.runService.Record(ctx, run.summary, files, run.actor, run.domainUID != "")
+	run.emit(events.ImportComplete, map[string]interface{}{
+		"created":    run.summary.Created,
+		"merged":     run.summary.Merged,
+		"duplicates": run.summary.Duplicates,
+		"failed":     run.summary.Failed,
+		"changed":    run.summary.Changed,
+	})
+	log.Printf("[%s] Done project=%s created=%d merged=%d duplicates=%d failed=%d changed=%d",
+		run.actor, projectUID, run.summary.Created, run.summary.Merged, run.summary.Duplicates,
+		run.summary.Failed, run.summary.Changed)
+
+	return run.summary, nil
+}
+
+// ── Domain ───────────────────────────────────────────────────────────────────
+
+func (i *RiskImporter) importDomain(ctx context.Context, run *riskRun) error {
+	d := run.report.Domain
+
+	forest := d.ForestFQDN
+	if forest == "" {
+		forest = d.FQDN
+	}
+	adResult, err := i.activeDirectoryService.UpsertActiveDirectory(ctx, upsert.Input[*rpad.ActiveDirectory]{
+		Entity: &rpad.ActiveDirectory{
+			ForestName:            forest,
+			ForestFunctionalLevel: d.ForestFunctionalLevel,
+		},
+		ProjectUID:   run.projectUID,
+		ParentType:   "Project",
+		AssertionCtx: importedContext(),
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return fmt.Errorf("active directory %s: %w", forest, err)
+	}
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, "ActiveDirectory", adResult.Entity.UID, adResult.Metadata)
+
+	adUID := adResult.Entity.UID
+	result, err := i.domainService.UpsertDomain(ctx, upsert.Input[*rpad.Domain]{
+		Entity: &rpad.Domain{
+			Name:                  d.FQDN,
+			DNSName:               d.FQDN,
+			NetBiosName:           d.NetBIOSName,
+			DomainSID:             d.SID,
+			DomainFunctionalLevel: d.DomainFunctionalLevel,
+			ForestFunctionalLevel: d.ForestFunctionalLevel,
+		},
+		ProjectUID:   run.projectUID,
+		ParentUID:    &adUID,
+		ParentType:   "ActiveDirectory",
+		AssertionCtx: importedContext(),
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return err
+	}
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, "Domain", result.Entity.UID, result.Metadata)
+	run.domainUID = result.Entity.UID
+	return nil
+}
+
+func (i *RiskImporter) importPasswordPolicy(ctx context.Context, run *riskRun) {
+	if run.report.PasswordPolicy == nil {
+		return
+	}
+	result, err := i.domainService.SetSecurityPolicy(ctx, importedContext(), run.domainUID, run.report.PasswordPolicy, run.actor)
+	if err != nil {
+		run.fail("SecurityPolicy", run.report.Domain.FQDN, err)
+		return
+	}
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, "SecurityPolicy", result.Entity.UID, result.Metadata)
+}
+
+// importDomainControllers upserts the DCs listed in the report, so findings
+// naming a DC land on the host with its IP.
+func (i *RiskImporter) importDomainControllers(ctx context.Context, run *riskRun) {
+	for _, dc := range run.report.DomainControllers {
+		b := model.NewHostBuilder().
+			WithName(strings.ToUpper(dc.Name)).
+			WithDNSHostName(i.dnsHostName(run, dc.Name)).
+			WithDistinguishedName(dc.DN).
+			WithOperatingSystem(dc.OperatingSystem).
+			AsDomainController()
+		if len(dc.IPs) > 0 {
+			b.WithIP(dc.IPs[0])
+		}
+		host, err := b.Build()
+		if err != nil {
+			run.fail("Host", dc.Name, err)
+			continue
+		}
+		if _, err := i.upsertHost(ctx, run, dc.Name, host); err != nil {
+			run.fail("Host", dc.Name, err)
+		}
+	}
+}
+
+func (i *RiskImporter) dnsHostName(run *riskRun, name string) string {
+	name = strings.ToLower(name)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return name + "." + run.report.Domain.FQDN
+}
+
+func (i *RiskImporter) upsertHost(ctx context.Context, run *riskRun, name string, host *model.Host) (string, error) {
+	key := strings.ToLower(name)
+	if uid, ok := run.hosts[key]; ok {
+		return uid, nil
+	}
+
+	domainUID := run.domainUID
+	result, err := i.hostService.UpsertHost(ctx, upsert.Input[*model.Host]{
+		Entity:       host,
+		ProjectUID:   run.projectUID,
+		ParentUID:    &domainUID,
+		ParentType:   "Domain",
+		AssertionCtx: riskPrincipalContext(),
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", err
+	}
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, "Host", result.Entity.UID, result.Metadata)
+	run.hosts[key] = result.Entity.UID
+	return result.Entity.UID, nil
+}
+
+// ── Findings ─────────────────────────────────────────────────────────────────
+
+func (i *RiskImporter) importFinding(ctx context.Context, run *riskRun, f *RiskFinding, principals map[string]*principalRisk) {
+	result, err := i.vulnService.AddDomainVulnerability(
+		ctx, importedContext(), run.projectUID, run.domainUID, f.Vulnerability(run.report.Tool), run.actor,
+	)
+	if err != nil {
+		run.fail("Vulnerability", f.RuleID, err)
+		return
+	}
+	recordUpsert(ctx, i.changeService, run.summary, run.actor, "Vulnerability", result.Entity.UID, result.Metadata)
+
+	if len(f.Details) > 0 && len(f.Objects) == 0 {
+		run.summary.RecordUnresolved("affects")
+	}
+
+	for _, o := range f.Objects {
+		switch o.Kind {
+		case RiskObjectComputer:
+			i.importComputerFinding(ctx, run, f, o)
+		default:
+			p, ok := principals[o.Key()]
+			if !ok {
+				p = &principalRisk{object: o}
+				principals[o.Key()] = p
+			}
+			p.score += f.Points
+			p.findings = append(p.findings, f)
+		}
+	}
+}
+
+// importComputerFinding attaches the finding to the affected computer as well,
+// so it shows up on the host like a scanner finding.
+func (i *RiskImporter) importComputerFinding(ctx context.Context, run *riskRun, f *RiskFinding, o RiskObject) {
+	host, err := model.NewHostBuilder().
+		WithName(strings.ToUpper(o.Name)).
+		WithDNSHostName(i.dnsHostName(run, o.Name)).
+		WithDistinguishedName(o.DN).
+		Build()
+	if err != nil {
+		run.summary.RecordRelationFailure("affects", o.Name, err)
+		return
+	}
+	hostUID, err := i.upsertHost(ctx, run, o.Name, host)
+	if err != nil {
+		run.fail("Host", o.Name, err)
+		return
+	}
+
+	result, err := i.vulnService.AddVulnerability(
+		ctx, importedContext(), run.projectUID, hostUID, "", f.Vulnerability(run.report.Tool), run.actor,
+	)
+	if err != nil {
+		run.summary.RecordRelationFailure("affects", fmt.Sprintf("%s %s", f.RuleID, o.Name), err)
+		return
+	}
+	run.summary.RecordRelation("affects", result.Metadata.Outcome == "created")
+}
+
+// importPrincipalRisk writes the accumulated risk onto the user or group.
+func (i *RiskImporter) importPrincipalRisk(ctx context.Context, run *riskRun, p *principalRisk) {
+	score := min(p.score, risk.MaxRiskScore)
+	domainUID := run.domainUID
+
+	switch p.object.Kind {
+	case RiskObjectUser:
+		user := &rpad.User{
+			BasePrincipal:  core.BasePrincipal{Name: p.object.Name, SID: p.object.SID},
+			SAMAccountName: p.object.Name,
+			RiskScore:      score,
+			RiskReasons:    p.reasons(),
+		}
+		if a := run.report.account(p.object.Name); a != nil {
+			user.PwdLastSet = a.PwdLastSet
+			user.LastLogon = a.LastLogonTimestamp
+		}
+		result, err := i.userService.UpsertUser(ctx, upsert.Input[*rpad.User]{
+			Entity:       user,
+			ProjectUID:   run.projectUID,
+			ParentUID:    &domainUID,
+			ParentType:   "Domain",
+			AssertionCtx: riskPrincipalContext(),
+			Actor:        run.actor,
+		})
+		if err != nil {
+			run.fail("User", p.object.Name, err)
+			return
+		}
+		recordUpsert(ctx, i.changeService, run.summary, run.actor, "User", result.Entity.UID, result.Metadata)
+
+	case RiskObjectGroup:
+		result, err := i.groupService.UpsertGroup(ctx, upsert.Input[*rpad.Group]{
+			Entity: &rpad.Group{
+				BasePrincipal: core.BasePrincipal{Name: p.object.Name, SID: p.object.SID},
+				IsPrivileged:  run.report.isPrivilegedGroup(p.object.Name),
+				RiskScore:     score,
+				RiskReasons:   p.reasons(),
+			},
+			ProjectUID:   run.projectUID,
+			ParentUID:    &domainUID,
+			ParentType:   "Domain",
+			AssertionCtx: riskPrincipalContext(),
+			Actor:        run.actor,
+		})
+		if err != nil {
+			run.fail("Group", p.object.Name, err)
+			return
+		}
+		recordUpsert(ctx, i.changeService, run.summary, run.actor, "Group", result.Entity.UID, result.Metadata)
+	}
+}
+
+// account returns the report's data on a privileged account, nil if unknown.
+func (r *RiskReport) account(name string) *RiskAccount {
+	for _, a := range r.Accounts {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (r *RiskReport) isPrivilegedGroup(name string) bool {
+	for _, a := range r.Accounts {
+		for _, g := range a.Groups {
+			if strings.EqualFold(g, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const ToolPingCastle = "pingcastle"
+
+// RiskObjectKind is the type of directory object a finding refers to.
+type RiskObjectKind string
+
+const (
+	RiskObjectUser     RiskObjectKind = "user"
+	RiskObjectGroup    RiskObjectKind = "group"
+	RiskObjectComputer RiskObjectKind = "computer"
+)
+
+// RiskReport is the tool independent form of an AD health check (PingCastle).
+type RiskReport struct {
+	Tool        string
+	GeneratedAt time.Time
+	Domain      RiskDomain
+	GlobalScore int
+
+	Findings       []*RiskFinding
+	PasswordPolicy *rpad.SecurityPolicy // nil if the report has none
+
+	// Accounts and DCs listed by the report, used to resolve the objects
+	// named in finding details.
+	Accounts          []*RiskAccount
+	DomainControllers []*RiskDomainController
+}
+
+type RiskDomain struct {
+	FQDN                  string
+	NetBIOSName           string
+	SID                   string
+	ForestFQDN            string
+	DomainFunctionalLevel string
+	ForestFunctionalLevel string
+}
+
+// RiskFinding is one triggered rule.
+type RiskFinding struct {
+	RuleID    string
+	Category  string
+	Model     string
+	Points    int
+	Rationale string
+	Details   []string
+	Objects   []RiskObject // affected objects resolved from Details
+}
+
+// RiskObject is a user, group or computer named by a finding.
+type RiskObject struct {
+	Kind RiskObjectKind
+	Name string // sAMAccountName, group name or computer name without '$'
+	SID  string
+	DN   string
+}
+
+// Key identifies the object across findings.
+func (o RiskObject) Key() string {
+	if o.SID != "" {
+		return string(o.Kind) + "|" + strings.ToUpper(o.SID)
+	}
+	return string(o.Kind) + "|" + strings.ToLower(o.Name)
+}
+
+// RiskAccount is a privileged account or group member listed in the report.
+type RiskAccount struct {
+	Name               string
+	DN                 string
+	IsEnabled          bool
+	IsLocked           bool
+	PwdNeverExpires    bool
+	CanBeDelegated     bool
+	IsService          bool
+	LastLogonTimestamp time.Time
+	PwdLastSet         time.Time
+	Groups             []string // privileged groups the account is member of
+}
+
+type RiskDomainController struct {
+	Name            string
+	OperatingSystem string
+	IPs             []string
+	DN              string
+}
+
+// Severity maps rule points onto the vulnerability severities.
+func (f *RiskFinding) Severity() string {
+	switch {
+	case f.Points >= 50:
+		return model.SeverityCritical
+	case f.Points >= 20:
+		return model.SeverityHigh
+	case f.Points >= 10:
+		return model.SeverityMedium
+	case f.Points > 0:
+		return model.SeverityLow
+	}
+	return model.SeverityInfo
+}
+
+// maxFindingEvidence limits the details copied into a finding node.
+const maxFindingEvidence = 100
+
+// Vulnerability converts the finding into the graph model.
+func (f *RiskFinding) Vulnerability(tool string) *model.Vulnerability {
+	description := f.Rationale
+	if f.Category != "" {
+		description = fmt.Sprintf("[%s / %s] %s", f.Category, f.Model, f.Rationale)
+	}
+
+	details := f.Details
+	evidence := ""
+	if len(details) > maxFindingEvidence {
+		evidence = fmt.Sprintf("\n... %d more", len(details)-maxFindingEvidence)
+		details = details[:maxFindingEvidence]
+	}
+	evidence = strings.Join(details, "\n") + evidence
+
+	return &model.Vulnerability{
+		Name:        f.RuleID,
+		Severity:    f.Severity(),
+		PluginID:    f.RuleID,
+		Scanner:     tool,
+		Evidence:    evidence,
+		Description: description,
+	}
+}
+
+// Reason is the entry written to the risk reasons of affected principals.
+func (f *RiskFinding) Reason() string {
+	return fmt.Sprintf("%s (%d): %s", f.RuleID, f.Points, f.Rationale)
+}
+
+// ── Detail resolution ────────────────────────────────────────────────────────
+
+var (
+	// detailKeyRe finds "Key: " labels, e.g. "Account: svc_sql Member of: ...".
+	detailKeyRe = regexp.MustCompile(`(?:^|\s)([A-Z][A-Za-z]*(?: [a-z][A-Za-z]*)?):\s`)
+	sidRe       = regexp.MustCompile(`S-1-5-21(?:-\d+){3,4}`)
+	dnRe        = regexp.MustCompile(`(?i)CN=.+?,DC=[^,\s]+(?:,DC=[^,\s]+)*`)
+)
+
+// detailKinds maps detail labels onto the object kind they name.
+var detailKinds = map[string]RiskObjectKind{
+	"account":           RiskObjectUser,
+	"user":              RiskObjectUser,
+	"samaccountname":    RiskObjectUser,
+	"login":             RiskObjectUser,
+	"group":             RiskObjectGroup,
+	"computer":          RiskObjectComputer,
+	"server":            RiskObjectComputer,
+	"dc":                RiskObjectComputer,
+	"domain controller": RiskObjectComputer,
+}
+
+// detailFields splits a detail line into its labelled values.
+func detailFields(detail string) map[string]string {
+	matches := detailKeyRe.FindAllStringSubmatchIndex(detail, -1)
+	fields := make(map[string]string, len(matches))
+	for k, m := range matches {
+		end := len(detail)
+		if k+1 < len(matches) {
+			end = matches[k+1][0]
+		}
+		key := strings.ToLower(detail[m[2]:m[3]])
+		fields[key] = strings.TrimSpace(detail[m[1]:end])
+	}
+	return fields
+}
+
+// resolveObjects extracts the affected objects of every finding. Labelled
+// values decide the kind; bare DNs are resolved through the accounts and
+// DCs listed in the report.
+func (r *RiskReport) resolveObjects() {
+	byDN := make(map[string]RiskObject)
+	for _, a := range r.Accounts {
+		if a.DN != "" {
+			kind := RiskObjectUser
+			if strings.HasSuffix(a.Name, "$") {
+				kind = RiskObjectComputer
+			}
+			byDN[strings.ToUpper(a.DN)] = RiskObject{Kind: kind, Name: strings.TrimSuffix(a.Name, "$"), DN: a.DN}
+		}
+	}
+	for _, dc := range r.DomainControllers {
+		if dc.DN != "" {
+			byDN[strings.ToUpper(dc.DN)] = RiskObject{Kind: RiskObjectComputer, Name: dc.Name, DN: dc.DN}
+		}
+	}
+
+	for _, f := range r.Findings {
+		seen := make(map[string]bool)
+		add := func(o RiskObject) {
+			if o.Name == "" && o.SID == "" {
+				return
+			}
+			if !seen[o.Key()] {
+				seen[o.Key()] = true
+				f.Objects = append(f.Objects, o)
+			}
+		}
+
+		for _, detail := range f.Details {
+			found := false
+			fields := detailFields(detail)
+			for _, label := range sortedKeys(fields) {
+				value := fields[label]
+				kind, ok := detailKinds[label]
+				if !ok || value == "" {
+					continue
+				}
+				found = true
+				if dn := dnRe.FindString(value); dn != "" {
+					if o, ok := byDN[strings.ToUpper(dn)]; ok {
+						add(o)
+						continue
+					}
+					// the CN of a user is its display name, not the sAMAccountName
+					if kind == RiskObjectUser {
+						continue
+					}
+					value = rdnValue(splitDN(dn)[0])
+				}
+				add(riskObject(kind, value))
+			}
+			if found {
+				continue
+			}
+			for _, dn := range dnRe.FindAllString(detail, -1) {
+				if o, ok := byDN[strings.ToUpper(dn)]; ok {
+					add(o)
+				}
+			}
+		}
+	}
+}
+
+// riskObject normalises a labelled name: "CORP\svc" → "svc", a trailing '$'
+// marks a computer account.
+func riskObject(kind RiskObjectKind, value string) RiskObject {
+	o := RiskObject{Kind: kind}
+	if sid := sidRe.FindString(value); sid != "" {
+		o.SID = strings.ToUpper(sid)
+		value = strings.TrimSpace(strings.Replace(value, sid, "", 1))
+	}
+	if idx := strings.LastIndex(value, `\`); idx >= 0 {
+		value = value[idx+1:]
+	}
+	if fields := strings.Fields(value); len(fields) > 0 && kind != RiskObjectGroup {
+		value = fields[0]
+	}
+	if strings.HasSuffix(value, "$") {
+		o.Kind = RiskObjectComputer
+		value = strings.TrimSuffix(value, "$")
+	}
+	o.Name = strings.TrimSpace(value)
+	return o
+}
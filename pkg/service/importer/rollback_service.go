@@ -0,0 +1,498 @@
+package importer
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/internal/repository/redpaths/imports"
+	"RedPaths-server/internal/repository/redpaths/modules"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/service/change"
+	engineservice "RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/risk"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"gorm.io/gorm"
+)
+
+const RollbackSource = "ImportRollback"
+
+var (
+	ErrRunNotFound     = errors.New("run not found")
+	ErrRunRolledBack   = errors.New("run has already been rolled back")
+	ErrRunOtherProject = errors.New("run belongs to another project")
+
+	// the run is known but its nodes and changes are gone already
+	errNothingLeft = errors.New("run left nothing to roll back")
+)
+
+// RollbackEntity is an entity affected by a rollback.
+type RollbackEntity struct {
+	UID  string `json:"uid"`
+	Type string `json:"type"`
+}
+
+// RollbackRevert restores the fields a run merged into an existing entity.
+type RollbackRevert struct {
+	EntityType string                `json:"entity_type"`
+	EntityUID  string                `json:"entity_uid"`
+	Fields     []history.FieldChange `json:"fields"` // old_value is restored
+	Reason     string                `json:"reason,omitempty"`
+}
+
+// RollbackPlan is the impact of rolling back an import or module run. The
+// preview returns it without changing anything.
+type RollbackPlan struct {
+	RunID        string     `json:"run_id"`
+	ProjectUID   string     `json:"project_uid"`
+	Source       string     `json:"source,omitempty"` // import source, empty for module runs
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+	Committed    bool       `json:"committed"`
+
+	// Assertions created by the run
+	Assertions int `json:"assertions"`
+	// Assertions of other runs that point to deleted entities
+	DanglingAssertions int `json:"dangling_assertions"`
+
+	// Entities that lose their last assertion and are deleted
+	Deleted       []RollbackEntity `json:"deleted"`
+	DeletedByType map[string]int   `json:"deleted_by_type"`
+	// Entities created by the run but confirmed by other runs, they stay
+	Kept []RollbackEntity `json:"kept"`
+
+	// Merged fields restored from the change history
+	Reverted []RollbackRevert `json:"reverted"`
+	// Fields changed again after the run, left untouched
+	Conflicts []RollbackRevert `json:"conflicts"`
+
+	removeUIDs []string
+	keepUIDs   []string
+	values     map[string]map[string]interface{} // entity → predicate → restored value
+}
+
+// RollbackService undoes an import or module run: the assertions the run
+// created are removed, entities that lose their last assertion are deleted
+// and the fields the run merged into existing entities are restored from the
+// change history.
+type RollbackService struct {
+	dgraphCon      *dgo.Dgraph
+	postgresCon    *gorm.DB
+	provenanceRepo engine.ProvenanceRepository
+	importRepo     imports.RedPathsImportRepository
+	moduleRepo     modules.RedPathsModuleRepository
+	changeService  *change.ChangeService
+	derivation     *engineservice.ProjectDerivation
+	riskService    *risk.RiskService
+}
+
+func NewRollbackService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*RollbackService, error) {
+	changeService, err := change.NewChangeService(postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := engineservice.NewProjectDerivation(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RollbackService{
+		dgraphCon:      dgraphCon,
+		postgresCon:    postgresCon,
+		provenanceRepo: engine.NewDgraphProvenanceRepository(dgraphCon),
+		importRepo:     imports.NewPostgresRedPathsImportRepository(),
+		moduleRepo:     modules.NewPostgresRedPathsModuleRepository(),
+		changeService:  changeService,
+		derivation:     derivation,
+		riskService:    risk.NewRiskService(dgraphCon),
+	}, nil
+}
+
+// Preview computes the impact of rolling back runID without changing anything.
+func (s *RollbackService) Preview(ctx context.Context, projectUID, runID string) (*RollbackPlan, error) {
+	plan, err := s.newPlan(ctx, projectUID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.readPlan(ctx, plan)
+	if errors.Is(err, errNothingLeft) && plan.Source != "" {
+		return plan, nil
+	}
+	return plan, err
+}
+
+// Rollback undoes runID. The plan is recomputed inside the write transaction,
+// so it reflects the graph at commit time rather than at preview time.
+func (s *RollbackService) Rollback(ctx context.Context, projectUID, runID, actor string) (*RollbackPlan, error) {
+	plan, err := s.newPlan(ctx, projectUID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.RolledBackAt != nil {
+		return plan, ErrRunRolledBack
+	}
+
+	changes, err := s.changeService.GetChangesByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.ExecuteInTransaction(ctx, s.dgraphCon, func(tx *dgo.Txn) error {
+		if err := s.computePlan(ctx, tx, plan, changes); err != nil {
+			return err
+		}
+		if err := s.provenanceRepo.DeleteNodes(ctx, tx, plan.removeUIDs); err != nil {
+			return fmt.Errorf("deleting nodes: %w", err)
+		}
+		if err := s.provenanceRepo.ClearRunID(ctx, tx, plan.keepUIDs); err != nil {
+			return fmt.Errorf("clearing run tags: %w", err)
+		}
+		for _, revert := range plan.Reverted {
+			if err := s.provenanceRepo.SetValues(ctx, tx, revert.EntityUID, plan.values[revert.EntityUID]); err != nil {
+				return fmt.Errorf("reverting %s %s: %w", revert.EntityType, revert.EntityUID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil && !(errors.Is(err, errNothingLeft) && plan.Source != "") {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	plan.Committed = true
+	plan.RolledBackAt = &now
+
+	// the graph is already rolled back, failing to write the history only logs
+	for _, revert := range plan.Reverted {
+		fields := make([]history.FieldChange, 0, len(revert.Fields))
+		for _, f := range revert.Fields {
+			fields = append(fields, history.FieldChange{Field: f.Field, OldValue: f.NewValue, NewValue: f.OldValue})
+		}
+		err := s.changeService.SaveChange(ctx, &history.Change{
+			EntityType:   revert.EntityType,
+			EntityUID:    revert.EntityUID,
+			ChangeType:   history.ChangeTypeReverted,
+			Changes:      fields,
+			ChangedBy:    actor,
+			ChangeReason: fmt.Sprintf("Rollback of run %s", runID),
+		})
+		if err != nil {
+			log.Printf("[%s] Warning: failed to save change for %s %s: %v", actor, revert.EntityType, revert.EntityUID, err)
+		}
+	}
+
+	err = db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		return s.importRepo.MarkRolledBack(ctx, tx, runID, now)
+	})
+	if err != nil {
+		log.Printf("[%s] Warning: %v", actor, err)
+	}
+
+	// removed ACEs, delegations and GPO links leave capabilities derived by
+	// other runs behind
+	if _, err := s.derivation.Run(ctx, projectUID, actor); err != nil {
+		log.Printf("[%s] Warning: re-deriving capabilities failed: %v", actor, err)
+	}
+	s.riskService.Rescore(ctx, projectUID, actor)
+
+	log.Printf("[%s] Rolled back run %s project=%s assertions=%d dangling=%d deleted=%d kept=%d reverted=%d conflicts=%d",
+		actor, runID, projectUID, plan.Assertions, plan.DanglingAssertions, len(plan.Deleted), len(plan.Kept),
+		len(plan.Reverted), len(plan.Conflicts))
+
+	return plan, nil
+}
+
+// newPlan checks the run against the import and module history. Runs of
+// another project are rejected; module runs without a record are accepted
+// as long as they left something behind in this project.
+func (s *RollbackService) newPlan(ctx context.Context, projectUID, runID string) (*RollbackPlan, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("runID cannot be empty")
+	}
+
+	plan := &RollbackPlan{
+		RunID:         runID,
+		ProjectUID:    projectUID,
+		DeletedByType: make(map[string]int),
+		values:        make(map[string]map[string]interface{}),
+	}
+
+	var run *redpaths.ImportRun
+	var moduleRuns []*redpaths.ModuleRun
+	_, err := db.ExecutePostgresRead(ctx, s.postgresCon, func(tx *gorm.DB) (struct{}, error) {
+		var err error
+		if run, err = s.importRepo.GetImportRun(ctx, tx, runID); err != nil || run != nil {
+			return struct{}{}, err
+		}
+		moduleRuns, err = s.moduleRepo.GetModuleRunsByRun(ctx, tx, runID)
+		return struct{}{}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyRunRecords(plan, run, moduleRuns); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// applyRunRecords takes source and rollback state from the import run and
+// checks that the run belongs to the plan's project.
+func applyRunRecords(plan *RollbackPlan, run *redpaths.ImportRun, moduleRuns []*redpaths.ModuleRun) error {
+	if run != nil {
+		if run.ProjectUID != plan.ProjectUID {
+			return ErrRunOtherProject
+		}
+		plan.Source = run.Source
+		plan.RolledBackAt = run.RolledBackAt
+		return nil
+	}
+	for _, moduleRun := range moduleRuns {
+		if moduleRun.ProjectUID != plan.ProjectUID {
+			return ErrRunOtherProject
+		}
+	}
+	return nil
+}
+
+func (s *RollbackService) readPlan(ctx context.Context, plan *RollbackPlan) error {
+	changes, err := s.changeService.GetChangesByRun(ctx, plan.RunID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecuteRead(ctx, s.dgraphCon, func(tx *dgo.Txn) (struct{}, error) {
+		return struct{}{}, s.computePlan(ctx, tx, plan, changes)
+	})
+	return err
+}
+
+// computePlan decides what happens to every node the run touched. An entity
+// is deleted when no assertion of another run points to it any more; its
+// remaining assertions are removed with it and the entities they point to
+// are checked again, so nothing is left orphaned.
+func (s *RollbackService) computePlan(ctx context.Context, tx *dgo.Txn, plan *RollbackPlan, changes []*history.Change) error {
+	nodes, err := s.provenanceRepo.GetRunNodes(ctx, tx, plan.ProjectUID, plan.RunID)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 && len(changes) == 0 {
+		if plan.Source == "" {
+			return ErrRunNotFound
+		}
+		return errNothingLeft
+	}
+
+	removed := make(map[string]bool)
+	created := make(map[string]string)
+	var candidates []string
+	for _, n := range nodes {
+		if n.IsAssertion() {
+			removed[n.UID] = true
+			plan.Assertions++
+			if n.Object != nil && n.Object.UID != "" {
+				candidates = append(candidates, n.Object.UID)
+			}
+			continue
+		}
+		created[n.UID] = n.Type()
+		candidates = append(candidates, n.UID)
+	}
+
+	deleted := make(map[string]string)
+	for len(candidates) > 0 {
+		links, err := s.provenanceRepo.GetLinks(ctx, tx, uniqueStrings(candidates))
+		if err != nil {
+			return err
+		}
+		candidates = nil
+
+		for _, uid := range sortedKeys(links) {
+			l := links[uid]
+			if _, done := deleted[uid]; done || l.Type == "Project" {
+				continue
+			}
+			remaining := 0
+			for _, a := range l.Incoming {
+				if !removed[a.UID] {
+					remaining++
+				}
+			}
+			if remaining > 0 {
+				continue
+			}
+
+			deleted[uid] = l.Type
+			for _, a := range append(l.Incoming, l.Outgoing...) {
+				if removed[a.UID] {
+					continue
+				}
+				removed[a.UID] = true
+				plan.DanglingAssertions++
+			}
+			for _, a := range l.Outgoing {
+				if a.Other != nil && a.Other.UID != "" {
+					candidates = append(candidates, a.Other.UID)
+				}
+			}
+		}
+	}
+
+	for _, uid := range sortedKeys(removed) {
+		plan.removeUIDs = append(plan.removeUIDs, uid)
+	}
+	for _, uid := range sortedKeys(deleted) {
+		plan.removeUIDs = append(plan.removeUIDs, uid)
+		plan.Deleted = append(plan.Deleted, RollbackEntity{UID: uid, Type: deleted[uid]})
+		plan.DeletedByType[deleted[uid]]++
+	}
+	for _, uid := range sortedKeys(created) {
+		if _, gone := deleted[uid]; !gone {
+			plan.keepUIDs = append(plan.keepUIDs, uid)
+			plan.Kept = append(plan.Kept, RollbackEntity{UID: uid, Type: created[uid]})
+		}
+	}
+
+	return s.planReverts(ctx, tx, plan, changes, deleted)
+}
+
+// planReverts restores the oldest old_value of every field the run merged,
+// provided the field still holds the value the run wrote.
+func (s *RollbackService) planReverts(
+	ctx context.Context,
+	tx *dgo.Txn,
+	plan *RollbackPlan,
+	changes []*history.Change,
+	deleted map[string]string,
+) error {
+	type fieldRevert struct {
+		old, new interface{}
+	}
+	byEntity := make(map[string]map[string]*fieldRevert)
+	entityTypes := make(map[string]string)
+
+	// changes are newest first
+	for k := len(changes) - 1; k >= 0; k-- {
+		c := changes[k]
+		if c.ChangeType != history.ChangeTypeUpdated {
+			continue
+		}
+		if _, gone := deleted[c.EntityUID]; gone {
+			continue
+		}
+		fields, ok := byEntity[c.EntityUID]
+		if !ok {
+			fields = make(map[string]*fieldRevert)
+			byEntity[c.EntityUID] = fields
+			entityTypes[c.EntityUID] = c.EntityType
+		}
+		for _, f := range c.Changes {
+			if f.Field == "last_seen_at" {
+				continue
+			}
+			if r, ok := fields[f.Field]; ok {
+				r.new = f.NewValue
+				continue
+			}
+			fields[f.Field] = &fieldRevert{old: f.OldValue, new: f.NewValue}
+		}
+	}
+
+	for _, uid := range sortedKeys(byEntity) {
+		current, err := s.provenanceRepo.GetValues(ctx, tx, uid)
+		if err != nil {
+			return err
+		}
+
+		revert := RollbackRevert{EntityType: entityTypes[uid], EntityUID: uid}
+		conflict := RollbackRevert{EntityType: entityTypes[uid], EntityUID: uid, Reason: "changed again after the run"}
+		values := make(map[string]interface{})
+
+		fields := byEntity[uid]
+		for _, name := range sortedKeys(fields) {
+			f := fields[name]
+			predicate, value := currentValue(current, name)
+			change := history.FieldChange{Field: name, OldValue: f.old, NewValue: f.new}
+
+			switch {
+			case sameValue(value, f.old):
+				// already back at the old value
+			case predicate != "" && sameValue(value, f.new):
+				values[predicate] = f.old
+				revert.Fields = append(revert.Fields, change)
+			default:
+				conflict.Fields = append(conflict.Fields, change)
+			}
+		}
+
+		if len(revert.Fields) > 0 {
+			plan.Reverted = append(plan.Reverted, revert)
+			plan.values[uid] = values
+		}
+		if len(conflict.Fields) > 0 {
+			plan.Conflicts = append(plan.Conflicts, conflict)
+		}
+	}
+	return nil
+}
+
+// currentValue finds the predicate of a change history field, which is stored
+// without the type prefix ("is_disabled" for "user.is_disabled").
+func currentValue(values map[string]interface{}, field string) (string, interface{}) {
+	if v, ok := values[field]; ok {
+		return field, v
+	}
+	for _, predicate := range sortedKeys(values) {
+		if idx := strings.Index(predicate, "."); idx >= 0 && predicate[idx+1:] == field {
+			return predicate, values[predicate]
+		}
+	}
+	return "", nil
+}
+
+// sameValue compares two values by their JSON form. Zero values equal nil, as
+// Dgraph does not store unset fields, and lists are compared unordered.
+func sameValue(a, b interface{}) bool {
+	return bytes.Equal(normalizedJSON(a), normalizedJSON(b))
+}
+
+func normalizedJSON(v interface{}) []byte {
+	raw, err := json.Marshal(v)
+	if err != nil || change.IsZeroJSON(raw) {
+		return nil
+	}
+
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) != nil {
+		return raw
+	}
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		items = append(items, string(item))
+	}
+	sort.Strings(items)
+	return []byte("[" + strings.Join(items, ",") + "]")
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
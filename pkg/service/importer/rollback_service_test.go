@@ -0,0 +1,337 @@
+package importer
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"RedPaths-server/pkg/model/utils"
+	"cmp"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// fakeProvenance serves the run nodes, links and values of a fixed graph. The
+// run nodes belong to project, "0x1" if unset.
+type fakeProvenance struct {
+	project string
+	nodes   []*engine.RunNode
+	links   map[string]*engine.NodeLinks
+	values  map[string]map[string]interface{}
+}
+
+func (f *fakeProvenance) GetRunNodes(ctx context.Context, tx *dgo.Txn, projectUID, runID string) ([]*engine.RunNode, error) {
+	if project := cmp.Or(f.project, "0x1"); project != projectUID {
+		return nil, nil
+	}
+	return f.nodes, nil
+}
+
+func (f *fakeProvenance) GetLinks(ctx context.Context, tx *dgo.Txn, uids []string) (map[string]*engine.NodeLinks, error) {
+	links := make(map[string]*engine.NodeLinks)
+	for _, uid := range uids {
+		if l, ok := f.links[uid]; ok {
+			links[uid] = l
+		}
+	}
+	return links, nil
+}
+
+func (f *fakeProvenance) GetValues(ctx context.Context, tx *dgo.Txn, uid string) (map[string]interface{}, error) {
+	return f.values[uid], nil
+}
+
+func (f *fakeProvenance) DeleteNodes(ctx context.Context, tx *dgo.Txn, uids []string) error {
+	return nil
+}
+
+func (f *fakeProvenance) ClearRunID(ctx context.Context, tx *dgo.Txn, uids []string) error {
+	return nil
+}
+
+func (f *fakeProvenance) SetValues(ctx context.Context, tx *dgo.Txn, uid string, values map[string]interface{}) error {
+	return nil
+}
+
+func runNode(uid, typ string) *engine.RunNode {
+	return &engine.RunNode{UID: uid, DType: []string{typ}}
+}
+
+func runAssertion(uid, object string) *engine.RunNode {
+	return &engine.RunNode{UID: uid, DType: []string{"Assertion"}, Object: &utils.UIDRef{UID: object}}
+}
+
+func linked(uid, other string) *engine.LinkedAssertion {
+	return &engine.LinkedAssertion{UID: uid, Other: &utils.UIDRef{UID: other}}
+}
+
+func newTestPlan(source string) *RollbackPlan {
+	return &RollbackPlan{
+		RunID:         "run-1",
+		ProjectUID:    "0x1",
+		Source:        source,
+		DeletedByType: make(map[string]int),
+		values:        make(map[string]map[string]interface{}),
+	}
+}
+
+func update(uid, field string, old, new interface{}) *history.Change {
+	return &history.Change{
+		EntityType: "User",
+		EntityUID:  uid,
+		ChangeType: history.ChangeTypeUpdated,
+		Changes:    []history.FieldChange{{Field: field, OldValue: old, NewValue: new}},
+	}
+}
+
+func TestComputePlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		repo      *fakeProvenance
+		wantErr   error
+		deleted   []RollbackEntity
+		kept      []RollbackEntity
+		remove    []string
+		dangling  int
+		asserted  int
+		deletedBy map[string]int
+	}{
+		{
+			name:    "unknown module run",
+			repo:    &fakeProvenance{},
+			wantErr: ErrRunNotFound,
+		},
+		{
+			name:    "import run without nodes",
+			source:  BulkSource,
+			repo:    &fakeProvenance{},
+			wantErr: errNothingLeft,
+		},
+		{
+			name: "entity only held by the run is deleted with its children",
+			repo: &fakeProvenance{
+				nodes: []*engine.RunNode{runAssertion("0xa1", "0x10"), runNode("0x10", "Host")},
+				links: map[string]*engine.NodeLinks{
+					"0x10": {UID: "0x10", Type: "Host", Incoming: []*engine.LinkedAssertion{linked("0xa1", "0x1")},
+						Outgoing: []*engine.LinkedAssertion{linked("0xa2", "0x20")}},
+					"0x20": {UID: "0x20", Type: "Service", Incoming: []*engine.LinkedAssertion{linked("0xa2", "0x10")}},
+				},
+			},
+			deleted:   []RollbackEntity{{UID: "0x10", Type: "Host"}, {UID: "0x20", Type: "Service"}},
+			remove:    []string{"0xa1", "0xa2", "0x10", "0x20"},
+			dangling:  1,
+			asserted:  1,
+			deletedBy: map[string]int{"Host": 1, "Service": 1},
+		},
+		{
+			name: "entity asserted by another run is kept",
+			repo: &fakeProvenance{
+				nodes: []*engine.RunNode{runAssertion("0xa1", "0x10"), runNode("0x10", "User")},
+				links: map[string]*engine.NodeLinks{
+					"0x10": {UID: "0x10", Type: "User", Incoming: []*engine.LinkedAssertion{
+						linked("0xa1", "0x1"), linked("0xb1", "0x2")}},
+				},
+			},
+			kept:      []RollbackEntity{{UID: "0x10", Type: "User"}},
+			remove:    []string{"0xa1"},
+			asserted:  1,
+			deletedBy: map[string]int{},
+		},
+		{
+			name: "project is never deleted",
+			repo: &fakeProvenance{
+				nodes: []*engine.RunNode{runAssertion("0xa1", "0x1")},
+				links: map[string]*engine.NodeLinks{
+					"0x1": {UID: "0x1", Type: "Project", Incoming: []*engine.LinkedAssertion{linked("0xa1", "0x1")}},
+				},
+			},
+			remove:    []string{"0xa1"},
+			asserted:  1,
+			deletedBy: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &RollbackService{provenanceRepo: tt.repo}
+			plan := newTestPlan(tt.source)
+
+			err := s.computePlan(context.Background(), nil, plan, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("computePlan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("computePlan() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(plan.Deleted, tt.deleted) {
+				t.Errorf("Deleted = %v, want %v", plan.Deleted, tt.deleted)
+			}
+			if !reflect.DeepEqual(plan.Kept, tt.kept) {
+				t.Errorf("Kept = %v, want %v", plan.Kept, tt.kept)
+			}
+			if !reflect.DeepEqual(plan.removeUIDs, tt.remove) {
+				t.Errorf("removeUIDs = %v, want %v", plan.removeUIDs, tt.remove)
+			}
+			if plan.DanglingAssertions != tt.dangling {
+				t.Errorf("DanglingAssertions = %d, want %d", plan.DanglingAssertions, tt.dangling)
+			}
+			if plan.Assertions != tt.asserted {
+				t.Errorf("Assertions = %d, want %d", plan.Assertions, tt.asserted)
+			}
+			if !reflect.DeepEqual(plan.DeletedByType, tt.deletedBy) {
+				t.Errorf("DeletedByType = %v, want %v", plan.DeletedByType, tt.deletedBy)
+			}
+		})
+	}
+}
+
+func TestPlanReverts(t *testing.T) {
+	tests := []struct {
+		name      string
+		changes   []*history.Change // newest first
+		current   map[string]interface{}
+		deleted   map[string]string
+		values    map[string]interface{}
+		reverted  []string
+		conflicts []string
+	}{
+		{
+			name:     "field still holds the value of the run",
+			changes:  []*history.Change{update("0x10", "description", "old", "new")},
+			current:  map[string]interface{}{"user.description": "new"},
+			values:   map[string]interface{}{"user.description": "old"},
+			reverted: []string{"description"},
+		},
+		{
+			name:      "field changed again after the run",
+			changes:   []*history.Change{update("0x10", "description", "old", "new")},
+			current:   map[string]interface{}{"user.description": "manual"},
+			conflicts: []string{"description"},
+		},
+		{
+			name:    "field already back at the old value",
+			changes: []*history.Change{update("0x10", "description", "old", "new")},
+			current: map[string]interface{}{"user.description": "old"},
+		},
+		{
+			name: "oldest old value and newest new value win",
+			changes: []*history.Change{
+				update("0x10", "description", "middle", "newest"),
+				update("0x10", "description", "oldest", "middle"),
+			},
+			current:  map[string]interface{}{"user.description": "newest"},
+			values:   map[string]interface{}{"user.description": "oldest"},
+			reverted: []string{"description"},
+		},
+		{
+			name:     "bool field",
+			changes:  []*history.Change{update("0x10", "is_disabled", false, true)},
+			current:  map[string]interface{}{"user.is_disabled": true},
+			values:   map[string]interface{}{"user.is_disabled": false},
+			reverted: []string{"is_disabled"},
+		},
+		{
+			name:    "unset field equals its zero value",
+			changes: []*history.Change{update("0x10", "is_disabled", false, true)},
+			current: map[string]interface{}{},
+		},
+		{
+			name:    "last_seen_at is not reverted",
+			changes: []*history.Change{update("0x10", "last_seen_at", "2024-01-01", "2025-01-01")},
+			current: map[string]interface{}{"user.last_seen_at": "2025-01-01"},
+		},
+		{
+			name:    "deleted entities are skipped",
+			changes: []*history.Change{update("0x10", "description", "old", "new")},
+			current: map[string]interface{}{"user.description": "new"},
+			deleted: map[string]string{"0x10": "User"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &RollbackService{provenanceRepo: &fakeProvenance{
+				values: map[string]map[string]interface{}{"0x10": tt.current},
+			}}
+			plan := newTestPlan(BulkSource)
+
+			if err := s.planReverts(context.Background(), nil, plan, tt.changes, tt.deleted); err != nil {
+				t.Fatalf("planReverts() error = %v", err)
+			}
+
+			if got := revertedFields(plan.Reverted); !reflect.DeepEqual(got, tt.reverted) {
+				t.Errorf("reverted fields = %v, want %v", got, tt.reverted)
+			}
+			if got := revertedFields(plan.Conflicts); !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("conflicting fields = %v, want %v", got, tt.conflicts)
+			}
+			if tt.values != nil && !reflect.DeepEqual(plan.values["0x10"], tt.values) {
+				t.Errorf("restored values = %v, want %v", plan.values["0x10"], tt.values)
+			}
+		})
+	}
+}
+
+func TestRollbackForeignRun(t *testing.T) {
+	foreignNodes := &fakeProvenance{
+		project: "0x2",
+		nodes:   []*engine.RunNode{runAssertion("0xa1", "0x10"), runNode("0x10", "Host")},
+	}
+
+	tests := []struct {
+		name       string
+		importRun  *redpaths.ImportRun
+		moduleRuns []*redpaths.ModuleRun
+		wantErr    error
+	}{
+		{
+			name:      "import run of another project",
+			importRun: &redpaths.ImportRun{ImportID: "run-1", ProjectUID: "0x2", Source: BulkSource},
+			wantErr:   ErrRunOtherProject,
+		},
+		{
+			name:       "module run of another project",
+			moduleRuns: []*redpaths.ModuleRun{{RunUID: "0xm", VectorRunUID: "run-1", ProjectUID: "0x2"}},
+			wantErr:    ErrRunOtherProject,
+		},
+		{
+			name:    "unrecorded run with nodes in another project only",
+			wantErr: ErrRunNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newTestPlan("")
+			err := applyRunRecords(plan, tt.importRun, tt.moduleRuns)
+			if err == nil {
+				s := &RollbackService{provenanceRepo: foreignNodes}
+				err = s.computePlan(context.Background(), nil, plan, nil)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(plan.removeUIDs) > 0 {
+				t.Errorf("removeUIDs = %v, want none", plan.removeUIDs)
+			}
+		})
+	}
+}
+
+func revertedFields(reverts []RollbackRevert) []string {
+	var fields []string
+	for _, r := range reverts {
+		for _, f := range r.Fields {
+			fields = append(fields, f.Field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,343 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/engine"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/service/active_directory"
+	engineservice "RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/risk"
+	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	NessusSource  = "NessusImport"
+	OpenVASSource = "OpenVASImport"
+)
+
+// VulnerabilityImportOptions controls which findings of a report are imported.
+type VulnerabilityImportOptions struct {
+	// MinSeverity drops findings below this severity (default: low, i.e. the
+	// informational plugins are skipped).
+	MinSeverity string
+	// DeriveCapabilities creates capabilities for known exploitable CVEs.
+	DeriveCapabilities bool
+	// Files are the uploaded file names, kept for the import history.
+	Files []string
+}
+
+// VulnerabilityImporter writes Nessus / OpenVAS findings into the project.
+// Hosts are matched by IP and hostname through the regular host upsert, so
+// findings land on hosts already known from scans or other imports.
+type VulnerabilityImporter struct {
+	hostService       *active_directory.HostService
+	vulnService       *active_directory.VulnerabilityService
+	capabilityService *engineservice.CapabilityService
+	runService        *ImportRunService
+	riskService       *risk.RiskService
+	postgresCon       *gorm.DB
+}
+
+func NewVulnerabilityImporter(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) (*VulnerabilityImporter, error) {
+	hostService, err := active_directory.NewHostService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	vulnService, err := active_directory.NewVulnerabilityService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	capabilityService, err := engineservice.NewCapabilityService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VulnerabilityImporter{
+		hostService:       hostService,
+		vulnService:       vulnService,
+		capabilityService: capabilityService,
+		runService:        NewImportRunService(postgresCon),
+		riskService:       risk.NewRiskService(dgraphCon),
+		postgresCon:       postgresCon,
+	}, nil
+}
+
+// vulnerabilityRun holds the state of one import run.
+type vulnerabilityRun struct {
+	projectUID string
+	actor      string
+	scanner    string
+	opts       VulnerabilityImportOptions
+	summary    *ImportSummary
+	logger     *sse.SSELogger
+
+	processed int
+	total     int
+}
+
+func (r *vulnerabilityRun) emit(eventType events.EventType, data map[string]interface{}) {
+	emitImportEvent(r.logger, r.summary, eventType, data)
+}
+
+func (r *vulnerabilityRun) step() {
+	r.processed++
+	if r.processed%progressEvery == 0 || r.processed == r.total {
+		r.emit(events.ImportProgress, map[string]interface{}{
+			"stage":    "findings",
+			"progress": fmt.Sprintf("%d/%d", r.processed, r.total),
+		})
+	}
+}
+
+func (r *vulnerabilityRun) fail(entityType, key string, err error) {
+	r.summary.RecordFailure(entityType, key, err)
+	log.Printf("[%s] %s %s failed: %v", r.actor, entityType, key, err)
+	r.emit(events.ImportError, map[string]interface{}{
+		"type":  entityType,
+		"key":   key,
+		"error": err.Error(),
+	})
+}
+
+// Import writes the report into the project and returns the summary. runID
+// selects the SSE stream progress is reported on.
+func (i *VulnerabilityImporter) Import(
+	ctx context.Context,
+	projectUID string,
+	runID string,
+	report *VulnerabilityReport,
+	opts VulnerabilityImportOptions,
+) (*ImportSummary, error) {
+	if report == nil {
+		return nil, fmt.Errorf("no report to import")
+	}
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	ctx = assertion.WithRunID(ctx, runID)
+	if opts.MinSeverity == "" {
+		opts.MinSeverity = model.SeverityLow
+	}
+
+	actor := NessusSource
+	if report.Scanner == ScannerOpenVAS {
+		actor = OpenVASSource
+	}
+
+	run := &vulnerabilityRun{
+		projectUID: projectUID,
+		actor:      actor,
+		scanner:    report.Scanner,
+		opts:       opts,
+		summary:    newImportSummary(runID, actor, projectUID),
+		logger:     sse.GetLogger(runID, projectUID, i.postgresCon),
+		total:      report.Count(),
+	}
+
+	run.emit(events.ImportStart, map[string]interface{}{
+		"report":   report.Name,
+		"hosts":    len(report.Hosts),
+		"findings": report.Count(),
+	})
+	log.Printf("[%s] Start project=%s hosts=%d findings=%d", actor, projectUID, len(report.Hosts), report.Count())
+
+	for _, host := range report.Hosts {
+		i.importHost(ctx, run, host)
+	}
+
+	i.riskService.Rescore(ctx, run.summary.ProjectUID, actor)
+
+	run.summary.finish()
+	i.runService.Record(ctx, run.summary, opts.Files, actor, true)
+	run.emit(events.ImportComplete, map[string]interface{}{
+		"created":    run.summary.Created,
+		"merged":     run.summary.Merged,
+		"duplicates": run.summary.Duplicates,
+		"failed":     run.summary.Failed,
+		"skipped":    run.summary.Skipped,
+	})
+	log.Printf("[%s] Done project=%s created=%d merged=%d duplicates=%d failed=%d skipped=%d",
+		actor, projectUID, run.summary.Created, run.summary.Merged, run.summary.Duplicates,
+		run.summary.Failed, run.summary.Skipped)
+
+	return run.summary, nil
+}
+
+func (i *VulnerabilityImporter) importHost(ctx context.Context, run *vulnerabilityRun, vh *VulnerabilityHost) {
+	minRank := model.SeverityRank(run.opts.MinSeverity)
+
+	var findings []*VulnerabilityFinding
+	for _, f := range vh.Findings {
+		if model.SeverityRank(f.Severity) < minRank {
+			run.summary.RecordSkipped()
+			run.step()
+			continue
+		}
+		findings = append(findings, f)
+	}
+	if len(findings) == 0 {
+		return
+	}
+
+	hostUID, err := i.upsertHost(ctx, run, vh)
+	if err != nil {
+		run.fail("Host", vh.IP, err)
+		for range findings {
+			run.step()
+		}
+		return
+	}
+
+	// port → service UID, services are only looked up once per host
+	services := make(map[string]string)
+
+	for _, f := range findings {
+		i.importFinding(ctx, run, vh, hostUID, services, f)
+		run.step()
+	}
+}
+
+func (i *VulnerabilityImporter) upsertHost(ctx context.Context, run *vulnerabilityRun, vh *VulnerabilityHost) (string, error) {
+	b := model.NewHostBuilder().WithIP(vh.IP)
+	if vh.NetBIOSName != "" {
+		b.WithName(vh.NetBIOSName)
+	}
+	if vh.FQDN != "" && vh.FQDN != vh.IP {
+		b.WithDNSHostName(strings.ToLower(vh.FQDN))
+	}
+	if vh.OperatingSystem != "" {
+		b.WithOperatingSystem(vh.OperatingSystem)
+	}
+
+	host, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+
+	result, err := i.hostService.UpsertHost(ctx, upsert.Input[*model.Host]{
+		Entity:       host,
+		ProjectUID:   run.projectUID,
+		ParentType:   "Domain",
+		AssertionCtx: importedContext(),
+		Actor:        run.actor,
+	})
+	if err != nil {
+		return "", err
+	}
+	run.summary.RecordOutcome("Host", result.Metadata)
+	return result.Entity.UID, nil
+}
+
+func (i *VulnerabilityImporter) importFinding(
+	ctx context.Context,
+	run *vulnerabilityRun,
+	vh *VulnerabilityHost,
+	hostUID string,
+	services map[string]string,
+	f *VulnerabilityFinding,
+) {
+	key := fmt.Sprintf("%s %s:%s", f.PluginID, vh.IP, f.Port)
+
+	var serviceUID string
+	if f.Port != "" {
+		if cached, ok := services[f.Port]; ok {
+			serviceUID = cached
+		} else {
+			service := model.NewServiceBuilder().WithName(f.ServiceName).WithPort(f.Port).Build()
+			result, err := i.hostService.EnsureService(ctx, importedContext(), run.projectUID, hostUID, service, run.actor)
+			if err != nil {
+				run.fail("Service", fmt.Sprintf("%s:%s", vh.IP, f.Port), err)
+			} else {
+				run.summary.RecordOutcome("Service", result.Metadata)
+				serviceUID = result.Entity.UID
+				services[f.Port] = serviceUID
+			}
+		}
+	}
+
+	result, err := i.vulnService.AddVulnerability(
+		ctx, importedContext(), run.projectUID, hostUID, serviceUID, f.Vulnerability(run.scanner), run.actor,
+	)
+	if err != nil {
+		run.fail("Vulnerability", key, err)
+		return
+	}
+	run.summary.RecordOutcome("Vulnerability", result.Metadata)
+
+	if result.Metadata.Outcome == res.OutcomeCreated {
+		run.emit(events.VulnFound, map[string]interface{}{
+			"ip":       vh.IP,
+			"port":     f.Port,
+			"name":     f.Name,
+			"severity": f.Severity,
+			"cvss":     f.CVSS,
+			"cves":     f.CVEs,
+		})
+	}
+
+	// capabilities are derived on merges too, so findings imported before the
+	// CVE became derivable catch up; linking them again is a no-op
+	if run.opts.DeriveCapabilities {
+		i.deriveCapabilities(ctx, run, vh, result.Entity, f)
+	}
+}
+
+func (i *VulnerabilityImporter) deriveCapabilities(
+	ctx context.Context,
+	run *vulnerabilityRun,
+	vh *VulnerabilityHost,
+	vulnerability *model.Vulnerability,
+	f *VulnerabilityFinding,
+) {
+	capabilities := derivableCapabilities(f.CVEs)
+
+	names := make([]string, 0, len(capabilities))
+	for name := range capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		known := capabilities[name]
+		capability := &engine.Capability{
+			Name:         name,
+			Scope:        known.Scope,
+			SourceType:   engine.SourceCVE,
+			Precondition: fmt.Sprintf("%s on %s", strings.Join(f.CVEs, ", "), vh.IP),
+			RiskLevel:    known.RiskLevel,
+		}
+
+		result, err := i.capabilityService.CreateAndLinkCapability(
+			ctx, importedContext(), capability,
+			vulnerability.UID, "Vulnerability", run.projectUID, run.actor,
+		)
+		if err != nil {
+			run.summary.RecordRelationFailure("derives", vulnerability.UID, err)
+			continue
+		}
+		created := result.Metadata.Outcome == res.OutcomeCreated
+		run.summary.RecordRelation("derives", created)
+		if !created {
+			continue
+		}
+
+		run.emit(events.VulnAnalyzed, map[string]interface{}{
+			"ip":         vh.IP,
+			"name":       f.Name,
+			"capability": name,
+			"risk_level": known.RiskLevel,
+		})
+	}
+}
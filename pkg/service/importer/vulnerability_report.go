@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"RedPaths-server/pkg/model"
+	"strings"
+)
+
+const (
+	ScannerNessus  = "nessus"
+	ScannerOpenVAS = "openvas"
+)
+
+// VulnerabilityReport is the scanner independent form of a Nessus or OpenVAS
+// report.
+type VulnerabilityReport struct {
+	Scanner string
+	Name    string
+	Hosts   []*VulnerabilityHost
+}
+
+// VulnerabilityHost groups the findings of one scanned host.
+type VulnerabilityHost struct {
+	IP              string
+	FQDN            string
+	NetBIOSName     string
+	OperatingSystem string
+	Findings        []*VulnerabilityFinding
+}
+
+// VulnerabilityFinding is a single plugin / NVT result.
+type VulnerabilityFinding struct {
+	PluginID         string
+	Name             string
+	Port             string // "" for host level findings
+	Protocol         string
+	ServiceName      string
+	Severity         string
+	CVSS             float64
+	CVSSVector       string
+	CVEs             []string
+	Evidence         string
+	Description      string
+	Solution         string
+	ExploitAvailable bool
+}
+
+// Count returns the number of findings in the report.
+func (r *VulnerabilityReport) Count() int {
+	n := 0
+	for _, h := range r.Hosts {
+		n += len(h.Findings)
+	}
+	return n
+}
+
+// Vulnerability converts the finding into the graph model.
+func (f *VulnerabilityFinding) Vulnerability(scanner string) *model.Vulnerability {
+	return &model.Vulnerability{
+		Name:             f.Name,
+		CVEs:             f.CVEs,
+		CVSS:             f.CVSS,
+		CVSSVector:       f.CVSSVector,
+		Severity:         f.Severity,
+		PluginID:         f.PluginID,
+		Scanner:          scanner,
+		Port:             f.Port,
+		Protocol:         f.Protocol,
+		Evidence:         f.Evidence,
+		Description:      f.Description,
+		Solution:         f.Solution,
+		ExploitAvailable: f.ExploitAvailable,
+	}
+}
+
+// host returns the entry for ip, creating it on first use.
+func (r *VulnerabilityReport) host(ip string) *VulnerabilityHost {
+	for _, h := range r.Hosts {
+		if h.IP == ip {
+			return h
+		}
+	}
+	h := &VulnerabilityHost{IP: ip}
+	r.Hosts = append(r.Hosts, h)
+	return h
+}
+
+// normalizeCVEs upper-cases, trims and deduplicates CVE identifiers.
+func normalizeCVEs(cves []string) []string {
+	seen := make(map[string]bool, len(cves))
+	result := make([]string, 0, len(cves))
+	for _, cve := range cves {
+		cve = strings.ToUpper(strings.TrimSpace(cve))
+		if !strings.HasPrefix(cve, "CVE-") || seen[cve] {
+			continue
+		}
+		seen[cve] = true
+		result = append(result, cve)
+	}
+	return result
+}
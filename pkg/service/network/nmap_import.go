@@ -0,0 +1,33 @@
+package network
+
+import (
+	"RedPaths-server/pkg/adapter/scan"
+	"RedPaths-server/pkg/model/rpsdk"
+	"context"
+)
+
+// ImportNmapResults runs nmap output that was produced outside a module
+// execution (uploaded XML / grepable files) through the same host, domain,
+// DC detection and service extraction as a live NetworkExplorer scan. The
+// forest/domain cache is shared across all results of one import.
+func ImportNmapResults(
+	ctx context.Context,
+	services *rpsdk.Services,
+	opts Options,
+	results ...*scan.NmapScanResult,
+) error {
+	processor, err := NewScanProcessor(services, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if err := processor.ProcessScanResult(ctx, *result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,675 @@
+package network
+
+import (
+	"RedPaths-server/pkg/adapter/scan"
+	"RedPaths-server/pkg/adapter/serializable"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/events"
+	"RedPaths-server/pkg/model/rpsdk"
+	"RedPaths-server/pkg/model/utils/assertion"
+	"RedPaths-server/pkg/service/network/internal"
+	"RedPaths-server/pkg/service/upsert"
+	"RedPaths-server/pkg/sse"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// ResultRecorder receives the outcome of every upsert the processor performs.
+// Imports use it to build their summary.
+type ResultRecorder interface {
+	RecordOutcome(entityType string, metadata *res.ResultMetadata)
+	RecordFailure(entityType, key string, err error)
+}
+
+// Options configures a ScanProcessor.
+type Options struct {
+	ProjectUID string
+	// Actor is written as assertion source, the module key for live scans.
+	Actor string
+	// Method overrides the assertion method (e.g. "imported").
+	Method   string
+	Logger   *sse.SSELogger
+	Recorder ResultRecorder
+}
+
+// ScanProcessor writes nmap results into a project: forest and domain
+// detection, DC scoring, hosts and services. It is shared by the
+// NetworkExplorer module and the nmap import, so uploaded scans end up exactly
+// like module scans.
+type ScanProcessor struct {
+	services   *rpsdk.Services
+	projectUID string
+	actor      string
+	method     *string
+	logger     *sse.SSELogger
+	recorder   ResultRecorder
+	cache      *scanCache
+}
+
+// scanCache keeps already upserted forests and domains across hosts (and
+// across the per-host nmap runs of a staged scan).
+type scanCache struct {
+	ad     map[string]string
+	domain map[string]string
+}
+
+// NewScanProcessor returns a processor for one scan or import run. Forests and
+// domains are cached for the lifetime of the processor.
+func NewScanProcessor(services *rpsdk.Services, opts Options) (*ScanProcessor, error) {
+	if services == nil {
+		return nil, fmt.Errorf("services cannot be nil")
+	}
+	if opts.ProjectUID == "" {
+		return nil, fmt.Errorf("projectUID cannot be empty")
+	}
+
+	p := &ScanProcessor{
+		services:   services,
+		projectUID: opts.ProjectUID,
+		actor:      opts.Actor,
+		logger:     opts.Logger,
+		recorder:   opts.Recorder,
+		cache: &scanCache{
+			ad:     make(map[string]string),
+			domain: make(map[string]string),
+		},
+	}
+	if p.actor == "" {
+		p.actor = "NetworkExplorer"
+	}
+	if opts.Method != "" {
+		p.method = strPtr(opts.Method)
+	}
+	return p, nil
+}
+
+func (p *ScanProcessor) recordOutcome(entityType string, metadata *res.ResultMetadata) {
+	if p.recorder != nil {
+		p.recorder.RecordOutcome(entityType, metadata)
+	}
+}
+
+func (p *ScanProcessor) recordFailure(entityType, key string, err error) {
+	if p.recorder != nil {
+		p.recorder.RecordFailure(entityType, key, err)
+	}
+}
+
+// ── Assertion contexts ────────────────────────────────────────────────────────
+
+var (
+	assertCtxAD = assertion.Context{
+		Confidence: float64Ptr(0.85),
+		Status:     strPtr("scan_detected"),
+		HighValue:  boolPtr(false),
+	}
+	assertCtxHost = assertion.Context{
+		Confidence: float64Ptr(0.95),
+		Status:     strPtr("scan_detected"),
+		HighValue:  boolPtr(false),
+	}
+	assertCtxService = assertion.Context{
+		Confidence: float64Ptr(0.90),
+		Status:     strPtr("scan_detected"),
+		HighValue:  boolPtr(false),
+	}
+)
+
+// assertCtx applies the assertion method of the processor (imports mark
+// their assertions as imported).
+func (p *ScanProcessor) assertCtx(base assertion.Context) assertion.Context {
+	if p.method != nil {
+		base.Method = p.method
+	}
+	return base
+}
+
+func float64Ptr(v float64) *float64 { return &v }
+func strPtr(v string) *string       { return &v }
+func boolPtr(v bool) *bool          { return &v }
+
+// ── DC port scoring ───────────────────────────────────────────────────────────
+
+type dcPortRule struct {
+	port       string
+	score      int
+	definitive bool
+}
+
+var dcPortRules = []dcPortRule{
+	{port: "88", score: 3, definitive: true},
+	{port: "389", score: 2},
+	{port: "636", score: 2},
+	{port: "3268", score: 2},
+	{port: "3269", score: 2},
+	{port: "464", score: 1},
+	{port: "53", score: 1},
+	{port: "445", score: 0},
+}
+
+const dcScoreThreshold = 3
+
+func isDomainController(document *xmlquery.Node, xb *internal.XPathBuilder) (bool, int, []string) {
+	score := 0
+	var matched []string
+
+	for _, rule := range dcPortRules {
+		if xmlquery.FindOne(document, xb.OpenPort(rule.port)) != nil {
+			matched = append(matched, rule.port)
+			score += rule.score
+			if rule.definitive {
+				return true, score, matched
+			}
+		}
+	}
+
+	return score >= dcScoreThreshold, score, matched
+}
+
+// ── ProcessScanResult ─────────────────────────────────────────────────────────
+
+// ProcessScanResult writes the hosts of an nmap result with their forest,
+// domain and services into the project.
+func (p *ScanProcessor) ProcessScanResult(ctx context.Context, nmapResult scan.NmapScanResult) error {
+	document, err := nmapResult.GetXMLDocument()
+	if err != nil {
+		return fmt.Errorf("failed to parse nmap XML: %w", err)
+	}
+
+	for i, host := range nmapResult.GetNmapResult().Host {
+		p.processHost(ctx, document, i, host)
+	}
+	return nil
+}
+
+func (p *ScanProcessor) processHost(
+	ctx context.Context,
+	document *xmlquery.Node,
+	i int,
+	host serializable.Host,
+) {
+	if len(host.Address) == 0 {
+		return
+	}
+
+	var err error
+	ip := host.Address[0].Addr
+
+	xb := internal.NewXPathBuilder(ip)
+
+	domainName, strategy := p.extractDomainName(document, xb)
+
+	forestRoot := p.extractForestRoot(xb, document)
+	if forestRoot == "" {
+		forestRoot = domainName
+	}
+
+	// ── ActiveDirectory (Forest) ──────────────────────────────────────────
+	var adUID string
+	if forestRoot != "" {
+		if cached, ok := p.cache.ad[forestRoot]; ok {
+			adUID = cached
+		} else {
+			adUID, err = p.upsertActiveDirectory(ctx, forestRoot)
+			if err != nil {
+				log.Printf("[ERROR] host[%d] upsertActiveDirectory failed forest=%s err=%v",
+					i, forestRoot, err)
+			} else {
+				p.cache.ad[forestRoot] = adUID
+			}
+		}
+	}
+
+	// ── Domain ───────────────────────────────────────────────────────────
+	var domainUID string
+	if domainName != "" {
+		if cached, ok := p.cache.domain[domainName]; ok {
+			domainUID = cached
+		} else {
+			domainUID, err = p.upsertDomain(ctx, adUID, domainName, strategy)
+			if err != nil {
+				log.Printf("[ERROR] host[%d] upsertDomain failed domain=%s err=%v",
+					i, domainName, err)
+			} else {
+				p.cache.domain[domainName] = domainUID
+			}
+		}
+	}
+
+	// ── Host ─────────────────────────────────────────────────────────────
+	hostUID, err := p.upsertHost(ctx, document, xb, ip, domainUID)
+	if err != nil {
+		log.Printf("[ERROR] host[%d] upsertHost failed ip=%s err=%v", i, ip, err)
+		return
+	}
+
+	// ── Services ─────────────────────────────────────────────────────────
+	p.upsertServices(ctx, host, hostUID)
+}
+
+// ── UpsertDiscoveredHost ──────────────────────────────────────────────────────
+
+// UpsertDiscoveredHost writes a live host from the discovery stage. Only the IP
+// is known at this point; the nmap stage merges richer data into the same node.
+func (p *ScanProcessor) UpsertDiscoveredHost(ctx context.Context, ip string, ports []string) (string, error) {
+	host, err := model.NewHostBuilder().WithIP(ip).Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build host model: %w", err)
+	}
+
+	result, err := p.services.HostService.UpsertHost(
+		ctx,
+		upsert.Input[*model.Host]{
+			Entity:       host,
+			ProjectUID:   p.projectUID,
+			ParentType:   "Domain",
+			AssertionCtx: p.assertCtx(assertCtxHost),
+			Actor:        p.actor,
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to upsert host: %w", err)
+	}
+
+	sse.NewEvent(events.HostDiscovered).
+		WithData("ip", ip).
+		WithData("stage", "discovery").
+		WithData("timestamp", time.Now().Unix()).
+		Log(p.logger)
+
+	for _, port := range ports {
+		sse.NewEvent(events.PortFound).
+			WithData("ip", ip).
+			WithData("port", port).
+			WithData("timestamp", time.Now().Unix()).
+			Log(p.logger)
+	}
+
+	return result.Entity.UID, nil
+}
+
+// AddDiscoveredServices stores the bare discovered ports as services when the
+// follow-up nmap scan for a host failed.
+func (p *ScanProcessor) AddDiscoveredServices(ctx context.Context, ip, hostUID string, ports []string) {
+	if hostUID == "" {
+		return
+	}
+
+	for _, port := range ports {
+		service := model.NewServiceBuilder().WithPort(port).Build()
+		if _, err := p.services.HostService.AddService(
+			ctx, p.assertCtx(assertCtxService), p.projectUID, hostUID, service, p.actor,
+		); err != nil {
+			log.Printf("[ERROR] addDiscoveredServices: AddService failed ip=%s port=%s err=%v", ip, port, err)
+		}
+	}
+}
+
+// ── upsertActiveDirectory ─────────────────────────────────────────────────────
+
+func (p *ScanProcessor) upsertActiveDirectory(
+	ctx context.Context,
+	forestRoot string,
+) (string, error) {
+	log.Printf("[DEBUG] upsertActiveDirectory: projectUID=%s forestRoot=%s", p.projectUID, forestRoot)
+
+	result, err := p.services.ActiveDirectoryService.UpsertActiveDirectory(
+		ctx,
+		upsert.Input[*active_directory.ActiveDirectory]{
+			Entity:       &active_directory.ActiveDirectory{ForestName: forestRoot},
+			ProjectUID:   p.projectUID,
+			ParentUID:    nil,
+			ParentType:   "Project",
+			AssertionCtx: p.assertCtx(assertCtxAD),
+			Actor:        p.actor,
+		},
+	)
+	if err != nil {
+		log.Printf("[ERROR] upsertActiveDirectory: UpsertActiveDirectory returned err=%v", err)
+		p.recordFailure("ActiveDirectory", forestRoot, err)
+		return "", fmt.Errorf("UpsertActiveDirectory failed for forest %s: %w", forestRoot, err)
+	}
+	p.recordOutcome("ActiveDirectory", result.Metadata)
+
+	log.Printf("[DEBUG] upsertActiveDirectory: result uid=%s", result.Entity.UID)
+
+	sse.NewEvent(events.DomainDiscovered).
+		WithData("type", "active_directory").
+		WithData("forest", forestRoot).
+		WithData("timestamp", time.Now().Unix()).
+		Log(p.logger)
+
+	log.Printf("[NetworkExplorer] AD upserted uid=%s forest=%s", result.Entity.UID, forestRoot)
+	return result.Entity.UID, nil
+}
+
+// ── upsertDomain ──────────────────────────────────────────────────────────────
+
+func (p *ScanProcessor) upsertDomain(
+	ctx context.Context,
+	adUID string,
+	domainName string,
+	strategy string,
+) (string, error) {
+	var parentUID *string
+	parentType := "Project"
+	if adUID != "" {
+		parentUID = &adUID
+		parentType = "ActiveDirectory"
+	}
+
+	log.Printf("[DEBUG] upsertDomain: projectUID=%s adUID=%s domainName=%s parentType=%s",
+		p.projectUID, adUID, domainName, parentType)
+
+	result, err := p.services.DomainService.UpsertDomain(
+		ctx,
+		upsert.Input[*active_directory.Domain]{
+			Entity:       &active_directory.Domain{Name: domainName},
+			ProjectUID:   p.projectUID,
+			ParentUID:    parentUID,
+			ParentType:   parentType,
+			AssertionCtx: p.assertCtx(assertCtxAD),
+			Actor:        p.actor,
+		},
+	)
+	if err != nil {
+		log.Printf("[ERROR] upsertDomain: UpsertDomain returned err=%v", err)
+		p.recordFailure("Domain", domainName, err)
+		return "", fmt.Errorf("UpsertDomain failed for %s: %w", domainName, err)
+	}
+	p.recordOutcome("Domain", result.Metadata)
+
+	log.Printf("[DEBUG] upsertDomain: result uid=%s", result.Entity.UID)
+
+	sse.NewEvent(events.DomainDiscovered).
+		WithData("domain", domainName).
+		WithData("strategy", strategy).
+		WithData("timestamp", time.Now().Unix()).
+		Log(p.logger)
+
+	log.Printf("[NetworkExplorer] Domain upserted name=%s uid=%s strategy=%s",
+		domainName, result.Entity.UID, strategy)
+	return result.Entity.UID, nil
+}
+
+// ── upsertHost ────────────────────────────────────────────────────────────────
+
+func (p *ScanProcessor) upsertHost(
+	ctx context.Context,
+	document *xmlquery.Node,
+	xb *internal.XPathBuilder,
+	ip string,
+	domainUID string,
+) (string, error) {
+	if ip == "" {
+		return "", fmt.Errorf("IP cannot be empty")
+	}
+
+	log.Printf("[DEBUG] upsertHost: ip=%s domainUID=%s projectUID=%s", ip, domainUID, p.projectUID)
+
+	b := model.NewHostBuilder().WithIP(ip)
+
+	// ── Name ──────────────────────────────────────────────────────────────────
+	if hostNode := xmlquery.FindOne(document, xb.Host()); hostNode != nil {
+		if node := xmlquery.FindOne(hostNode, xb.Hostname()); node != nil {
+			if v := strings.TrimSpace(node.InnerText()); v != "" {
+				b.WithName(v)
+			}
+		}
+	}
+	if netbios := firstText(document, xb.NetBIOSComputerNameRDP(), xb.NetBIOSComputerNameSQL()); netbios != "" {
+		b.WithName(netbios)
+	}
+
+	// ── DNS FQDN ──────────────────────────────────────────────────────────────
+	if fqdn := firstText(document, xb.DNSComputerNameRDP(), xb.DNSComputerNameSQL(), xb.SMBFQDN()); fqdn != "" {
+		b.WithDNSHostName(fqdn)
+	}
+
+	// ── Operating System ──────────────────────────────────────────────────────
+	if osStr := firstText(document, xb.SMBOS()); osStr != "" {
+		b.WithOperatingSystem(osStr)
+	} else if osType := firstText(document, xb.ServiceOSType()); osType != "" {
+		b.WithOperatingSystem(osType)
+	}
+
+	// ── OS Version ────────────────────────────────────────────────────────────
+	if version := firstText(document, xb.ProductVersionRDP(), xb.ProductVersionSQL()); version != "" {
+		b.WithOperatingSystemVersion(version)
+	}
+
+	// ── Domain Controller ─────────────────────────────────────────────────────
+	dc, score, matchedPorts := isDomainController(document, xb)
+	if dc {
+		b.AsDomainController()
+		log.Printf("[NetworkExplorer] %s: DC detected (score=%d, ports=%v)", ip, score, matchedPorts)
+	} else {
+		log.Printf("[NetworkExplorer] %s: not a DC (score=%d, ports=%v)", ip, score, matchedPorts)
+	}
+
+	host, err := b.Build()
+	if err != nil {
+		log.Printf("[ERROR] upsertHost: Build failed ip=%s err=%v", ip, err)
+		return "", fmt.Errorf("failed to build host model: %w", err)
+	}
+
+	log.Printf("[DEBUG] upsertHost: host built ip=%s hostname=%s os=%q dc=%v",
+		host.IP, host.DNSHostName, host.OperatingSystem, host.IsDomainController)
+
+	var parentUID *string
+	if domainUID != "" {
+		parentUID = &domainUID
+	}
+
+	log.Printf("[DEBUG] upsertHost: calling HostService.UpsertHost ip=%s parentUID=%v",
+		ip, parentUID)
+
+	result, err := p.services.HostService.UpsertHost(
+		ctx,
+		upsert.Input[*model.Host]{
+			Entity:       host,
+			ProjectUID:   p.projectUID,
+			ParentUID:    parentUID,
+			ParentType:   "Domain",
+			AssertionCtx: p.assertCtx(assertCtxHost),
+			Actor:        p.actor,
+		},
+	)
+	if err != nil {
+		log.Printf("[ERROR] upsertHost: HostService.UpsertHost failed ip=%s err=%v", ip, err)
+		p.recordFailure("Host", ip, err)
+		return "", fmt.Errorf("failed to upsert host: %w", err)
+	}
+	p.recordOutcome("Host", result.Metadata)
+
+	log.Printf("[DEBUG] upsertHost: ok ip=%s uid=%s", ip, result.Entity.UID)
+
+	sse.NewEvent(events.HostDiscovered).
+		WithData("ip", ip).
+		WithData("hostname", host.DNSHostName).
+		WithData("os", host.OperatingSystem).
+		WithData("os_version", host.OperatingSystemVersion).
+		WithData("dc", host.IsDomainController).
+		WithData("timestamp", time.Now().Unix()).
+		Log(p.logger)
+
+	log.Printf("[NetworkExplorer] Host upserted ip=%s dns=%s os=%q dc=%v uid=%s",
+		ip, host.DNSHostName, host.OperatingSystem, host.IsDomainController, result.Entity.UID)
+
+	return result.Entity.UID, nil
+}
+
+// ── upsertServices ────────────────────────────────────────────────────────────
+
+func (p *ScanProcessor) upsertServices(ctx context.Context, host serializable.Host, hostUID string) {
+	if hostUID == "" {
+		return
+	}
+
+	log.Printf("[DEBUG] upsertServices: hostUID=%s portCount=%d", hostUID, len(host.Ports.Port))
+
+	for _, port := range host.Ports.Port {
+		if port.State.State != "open" {
+			continue
+		}
+
+		service := model.NewServiceBuilder().
+			WithName(port.Service.Name).
+			WithPort(port.Portid).
+			Build()
+
+		result, err := p.services.HostService.AddService(
+			ctx,
+			p.assertCtx(assertCtxService),
+			p.projectUID,
+			hostUID,
+			service,
+			p.actor,
+		)
+		if err != nil {
+			log.Printf("[ERROR] upsertServices: AddService failed port=%s host=%s err=%v",
+				port.Portid, hostUID, err)
+			p.recordFailure("Service", port.Portid, err)
+			continue
+		}
+		p.recordOutcome("Service", result.Metadata)
+
+		log.Printf("[DEBUG] upsertServices: ok port=%s service=%s host=%s",
+			port.Portid, port.Service.Name, hostUID)
+
+		sse.NewEvent(events.ServiceDetected).
+			WithData("port", port.Portid).
+			WithData("service", port.Service.Name).
+			WithData("product", port.Service.Product).
+			WithData("version", port.Service.Version).
+			WithData("timestamp", time.Now().Unix()).
+			Log(p.logger)
+	}
+}
+
+// ── Domain / Forest extraction ────────────────────────────────────────────────
+
+// extractDomainName returns the DNS domain name the host belongs to.
+//
+// Strategy priority (highest confidence first):
+//  1. RDP NTLM-Info  — DNS_Domain_Name reflects the host's own domain exactly,
+//     even on DCs where LDAP extrainfo returns the forest root instead.
+//  2. SQL NTLM-Info  — same reliability as RDP, used when RDP is not open.
+//  3. LDAP ExtraInfo — fallback for hosts with LDAP but no RDP/SQL.
+//     NOTE: on forest-root DCs this returns the forest name, not a child domain.
+//  4. SSL Cert CommonName  — extract domain from subject FQDN.
+//  5. SSL Cert SAN-DNS     — extract domain from SAN DNS entry.
+//  6. SSL Cert DomainComponent — issuer DC field as last resort.
+func (p *ScanProcessor) extractDomainName(document *xmlquery.Node, xb *internal.XPathBuilder) (string, string) {
+
+	// Strategy 1: RDP NTLM-Info — most reliable, DNS_Domain_Name = host's own domain
+	if node := xmlquery.FindOne(document, xb.DNSDomainNameRDP()); node != nil {
+		if v := strings.TrimSpace(node.InnerText()); v != "" {
+			log.Printf("[NetworkExplorer] Domain=%s via strategy=RDP NTLM", v)
+			return v, "RDP NTLM"
+		}
+	}
+
+	// Strategy 2: SQL NTLM-Info
+	if node := xmlquery.FindOne(document, xb.DNSDomainNameSQL()); node != nil {
+		if v := strings.TrimSpace(node.InnerText()); v != "" {
+			log.Printf("[NetworkExplorer] Domain=%s via strategy=SQL NTLM", v)
+			return v, "SQL NTLM"
+		}
+	}
+
+	// Strategies 3–6: LDAP-based fallback (hosts without RDP/SQL open)
+	ldapPorts := []string{"389", "636", "3268", "3269"}
+
+	ldapStrategies := []struct {
+		name     string
+		getXPath func(port string) string
+		extract  func(string) string
+	}{
+		{name: "LDAP ExtraInfo", getXPath: xb.LDAPExtraInfo, extract: extractDomainFromExtrainfo},
+		{name: "SSL Cert CommonName", getXPath: xb.SSLCertCommonName, extract: extractDomainFromFQDN},
+		{
+			name:     "SSL Cert SAN-DNS",
+			getXPath: xb.SSLCertSANDNS,
+			extract:  func(text string) string { return extractDomainFromFQDN(strings.TrimPrefix(text, "DNS:")) },
+		},
+		{
+			name:     "SSL Cert DomainComponent",
+			getXPath: xb.SSLCertDomainComponent,
+			extract: func(text string) string {
+				if text == "" {
+					return ""
+				}
+				return text + ".local"
+			},
+		},
+	}
+
+	for _, s := range ldapStrategies {
+		for _, port := range ldapPorts {
+			nodes, err := xmlquery.QueryAll(document, s.getXPath(port))
+			if err != nil || len(nodes) == 0 || nodes[0] == nil {
+				continue
+			}
+			domain := s.extract(nodes[0].InnerText())
+			if domain != "" {
+				log.Printf("[NetworkExplorer] Domain=%s via strategy=%s port=%s", domain, s.name, port)
+				return domain, s.name
+			}
+		}
+	}
+
+	return "", ""
+}
+
+func (p *ScanProcessor) extractForestRoot(xb *internal.XPathBuilder, document *xmlquery.Node) string {
+	if node := xmlquery.FindOne(document, xb.DNSTreeNameRDP()); node != nil {
+		if v := strings.TrimSpace(node.InnerText()); v != "" {
+			return v
+		}
+	}
+	if node := xmlquery.FindOne(document, xb.DNSTreeNameSQL()); node != nil {
+		if v := strings.TrimSpace(node.InnerText()); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ── Helpers ───────────────────────────────────────────────────────────────────
+
+func firstText(document *xmlquery.Node, xpaths ...string) string {
+	for _, xpath := range xpaths {
+		if node := xmlquery.FindOne(document, xpath); node != nil {
+			if v := strings.TrimSpace(node.InnerText()); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func extractDomainFromExtrainfo(info string) string {
+	re := regexp.MustCompile(`Domain:\s*([a-zA-Z0-9.-]+)`)
+	match := re.FindStringSubmatch(info)
+	if len(match) > 1 {
+		return strings.Replace(match[1], ".local0.", ".local", 1)
+	}
+	return ""
+}
+
+func extractDomainFromFQDN(fqdn string) string {
+	fqdn = strings.TrimPrefix(fqdn, "*.")
+	if parts := strings.SplitN(fqdn, ".", 2); len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
@@ -0,0 +1,515 @@
+package paths
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core"
+	engineservice "RedPaths-server/pkg/service/engine"
+	"fmt"
+	"strings"
+)
+
+// Relations of the attack graph. ACE edges use the right name instead
+// (GenericAll, WriteDacl, ...).
+const (
+	RelationMemberOf   = "MemberOf"
+	RelationAdminTo    = "AdminTo"
+	RelationHasSession = "HasSession"
+
+	RelationUnconstrainedDelegation = "UnconstrainedDelegation"
+	RelationAllowedToDelegate       = "AllowedToDelegate"
+
+	RelationSameForestTrust  = "SameForestTrust"
+	RelationCrossForestTrust = "CrossForestTrust"
+
+	RelationGPOAbuse         = "GPOAbuse"
+	RelationGPOLocalAdmin    = "GPOLocalAdmin"
+	RelationGPOScheduledTask = "GPOScheduledTask"
+	RelationGPOUserRight     = "GPOUserRight"
+)
+
+// relationCosts is the base cost of a hop: roughly the effort and noise of
+// abusing it. Membership is free, it only passes on the group's rights.
+var relationCosts = map[string]float64{
+	RelationMemberOf:   0,
+	RelationAdminTo:    1,
+	RelationHasSession: 1,
+
+	RelationSameForestTrust:  2,
+	RelationCrossForestTrust: 3,
+}
+
+// delegationCosts maps the capabilities of the delegation analysis to the
+// relation and cost of abusing them. Resource-based constrained delegation is
+// covered by the ACE edges (AddAllowedToAct, WriteAccountRestrictions, ...).
+var delegationCosts = map[string]struct {
+	relation string
+	cost     float64
+}{
+	engineservice.CapabilityUnconstrainedDelegation: {RelationUnconstrainedDelegation, 3},
+	engineservice.CapabilityProtocolTransition:      {RelationAllowedToDelegate, 2},
+	engineservice.CapabilityConstrainedDelegation:   {RelationAllowedToDelegate, 3},
+}
+
+// gpoCosts maps the capabilities of the GPO analysis to the relation and
+// cost of abusing them. Editing a GPO waits for the next policy refresh and
+// touches every host in scope, so it is the noisiest.
+var gpoCosts = map[string]struct {
+	relation string
+	cost     float64
+}{
+	engineservice.CapabilityGPOEdit:          {RelationGPOAbuse, 2.5},
+	engineservice.CapabilityGPOLocalAdmin:    {RelationGPOLocalAdmin, 1},
+	engineservice.CapabilityGPOScheduledTask: {RelationGPOScheduledTask, 2},
+	engineservice.CapabilityGPOUserRight:     {RelationGPOUserRight, 2},
+}
+
+// aceCosts lists the ACE rights that allow taking over the object, with
+// their cost. Rights not listed here do not become attack edges.
+var aceCosts = map[string]float64{
+	"GenericAll":               1,
+	"AllExtendedRights":        1,
+	"ForceChangePassword":      1,
+	"AddMember":                1,
+	"AddSelf":                  1,
+	"ReadLAPSPassword":         1,
+	"ReadGMSAPassword":         1,
+	"DCSync":                   1,
+	"GetChangesAll":            1.5,
+	"GenericWrite":             2,
+	"WriteDacl":                2,
+	"WriteOwner":               2,
+	"Owns":                     2,
+	"AddKeyCredentialLink":     2,
+	"AddAllowedToAct":          2.5,
+	"WriteAccountRestrictions": 2.5,
+	"WriteSPN":                 3,
+}
+
+const (
+	// confidencePenalty is added for a fully unconfident assertion, scaled
+	// linearly with the missing confidence.
+	confidencePenalty = 1.0
+	// tentativePenalty is added for assertions nobody validated yet.
+	tentativePenalty = 0.5
+)
+
+// PathNode is an entity on an attack path.
+type PathNode struct {
+	UID   string `json:"uid"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// PathAssertion is an assertion backing a hop.
+type PathAssertion struct {
+	UID        string  `json:"uid"`
+	Predicate  string  `json:"predicate"`
+	Subject    string  `json:"subject"`
+	Object     string  `json:"object"`
+	Status     string  `json:"status"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source"`
+}
+
+// AttackEdge is one abusable relation: holding From gives control over To.
+type AttackEdge struct {
+	From        string
+	To          string
+	Relation    string
+	Cost        float64
+	Explanation string
+	Assertions  []*engine.GraphEdge
+}
+
+// AttackGraph is the weighted directed graph the path search runs on.
+type AttackGraph struct {
+	Nodes map[string]*PathNode
+	Out   map[string][]*AttackEdge
+
+	graph    *engine.ProjectGraph
+	outgoing map[string][]*engine.GraphEdge
+	edges    int
+}
+
+// edgeBuilders derive the attack edges from the project graph. Each one
+// covers a kind of relation; new relations are added here.
+var edgeBuilders = []func(g *AttackGraph){
+	(*AttackGraph).addMemberships,
+	(*AttackGraph).addAdminRights,
+	(*AttackGraph).addSessions,
+	(*AttackGraph).addACEs,
+	(*AttackGraph).addDelegations,
+	(*AttackGraph).addTrusts,
+	(*AttackGraph).addGPOs,
+}
+
+// BuildAttackGraph derives the attack graph from the assertions of a project.
+func BuildAttackGraph(graph *engine.ProjectGraph) *AttackGraph {
+	g := &AttackGraph{
+		Nodes:    make(map[string]*PathNode),
+		Out:      make(map[string][]*AttackEdge),
+		graph:    graph,
+		outgoing: make(map[string][]*engine.GraphEdge),
+	}
+	for _, e := range graph.Edges {
+		g.outgoing[e.Subject] = append(g.outgoing[e.Subject], e)
+	}
+	for _, build := range edgeBuilders {
+		build(g)
+	}
+	return g
+}
+
+// Graph returns the project graph the attack graph was built from.
+func (g *AttackGraph) Graph() *engine.ProjectGraph {
+	return g.graph
+}
+
+// EdgeCount returns the number of attack edges.
+func (g *AttackGraph) EdgeCount() int {
+	return g.edges
+}
+
+// Node returns the path node of an entity of the project graph.
+func (g *AttackGraph) Node(uid string) *PathNode {
+	if n, ok := g.Nodes[uid]; ok {
+		return n
+	}
+	gn := g.graph.Nodes[uid]
+	if gn == nil {
+		return nil
+	}
+	n := &PathNode{UID: uid, Type: gn.Type, Label: nodeLabel(gn)}
+	g.Nodes[uid] = n
+	return n
+}
+
+func (g *AttackGraph) addEdge(from, to, relation string, base float64, explanation string, assertions ...*engine.GraphEdge) {
+	if from == to || g.Node(from) == nil || g.Node(to) == nil {
+		return
+	}
+	g.Out[from] = append(g.Out[from], &AttackEdge{
+		From:        from,
+		To:          to,
+		Relation:    relation,
+		Cost:        base + assertionPenalty(assertions),
+		Explanation: explanation,
+		Assertions:  assertions,
+	})
+	g.edges++
+}
+
+// assertionPenalty makes unconfident and unvalidated hops more expensive;
+// the weakest backing assertion counts.
+func assertionPenalty(assertions []*engine.GraphEdge) float64 {
+	penalty := 0.0
+	for _, a := range assertions {
+		p := (1 - clamp(a.Confidence)) * confidencePenalty
+		if a.Status == string(core.StatusTentative) {
+			p += tentativePenalty
+		}
+		penalty = max(penalty, p)
+	}
+	return penalty
+}
+
+func clamp(confidence float64) float64 {
+	return min(max(confidence, 0), 1)
+}
+
+func (g *AttackGraph) nodeType(uid string) string {
+	if n := g.graph.Nodes[uid]; n != nil {
+		return n.Type
+	}
+	return ""
+}
+
+func (g *AttackGraph) label(uid string) string {
+	if n := g.Node(uid); n != nil {
+		return n.Label
+	}
+	return uid
+}
+
+// ── Edge builders ───────────────────────────────────────────────────────────
+
+// addMemberships turns "Group has_member X" into "X -MemberOf-> Group".
+func (g *AttackGraph) addMemberships() {
+	for _, e := range g.graph.Edges {
+		if e.Predicate != string(core.PredicateHasMember) || g.nodeType(e.Subject) != "Group" {
+			continue
+		}
+		g.addEdge(e.Object, e.Subject, RelationMemberOf, relationCosts[RelationMemberOf],
+			fmt.Sprintf("%s is a member of %s and inherits its rights", g.label(e.Object), g.label(e.Subject)), e)
+	}
+}
+
+// addAdminRights turns "X admin_to Host" into "X -AdminTo-> Host".
+func (g *AttackGraph) addAdminRights() {
+	for _, e := range g.graph.Edges {
+		if e.Predicate != string(core.PredicateAdminTo) || g.nodeType(e.Object) != "Host" {
+			continue
+		}
+		g.addEdge(e.Subject, e.Object, RelationAdminTo, relationCosts[RelationAdminTo],
+			fmt.Sprintf("%s is local administrator on %s", g.label(e.Subject), g.label(e.Object)), e)
+	}
+}
+
+// addSessions turns "Host has_session User" into "Host -HasSession-> User":
+// an administrator of the host can take the credentials of the logged on user.
+func (g *AttackGraph) addSessions() {
+	for _, e := range g.graph.Edges {
+		if e.Predicate != string(core.PredicateHasSession) || g.nodeType(e.Subject) != "Host" {
+			continue
+		}
+		g.addEdge(e.Subject, e.Object, RelationHasSession, relationCosts[RelationHasSession],
+			fmt.Sprintf("%s has a session on %s, its credentials can be taken from the host", g.label(e.Object), g.label(e.Subject)), e)
+	}
+}
+
+// addACEs flattens "object has_acl ACL contains ACE granted_to principal"
+// into "principal -[right]-> object" for the abusable rights.
+func (g *AttackGraph) addACEs() {
+	for _, aclEdge := range g.graph.Edges {
+		if aclEdge.Predicate != string(core.PredicateHasACL) {
+			continue
+		}
+		object := aclEdge.Subject
+
+		for _, aceEdge := range g.outgoing[aclEdge.Object] {
+			aceNode := g.graph.Nodes[aceEdge.Object]
+			if aceEdge.Predicate != string(core.PredicateContains) || aceNode == nil || aceNode.Type != "ACE" {
+				continue
+			}
+			right := stringValue(aceNode, "ace.name")
+			accessType := stringValue(aceNode, "ace.access_type")
+			cost, abusable := aceCosts[right]
+			if !abusable || strings.EqualFold(accessType, "Deny") {
+				continue
+			}
+
+			for _, grant := range g.outgoing[aceNode.UID] {
+				if grant.Predicate != string(core.PredicateGrantedTo) {
+					continue
+				}
+				g.addEdge(grant.Object, object, right, cost,
+					fmt.Sprintf("%s holds %s on %s", g.label(grant.Object), right, g.label(object)),
+					aclEdge, aceEdge, grant)
+			}
+		}
+	}
+}
+
+// addDelegations flattens the delegation capabilities "principal
+// has_capability Capability applies_to target" into attack edges. Constrained
+// delegation leads to the target host; unconstrained delegation leads to the
+// Domain Admins groups of the domain, since a coerced domain controller
+// authentication hands over the domain.
+func (g *AttackGraph) addDelegations() {
+	holders := make(map[string]*engine.GraphEdge)
+	for _, e := range g.graph.Edges {
+		if e.Predicate == string(core.PredicateHasCapability) {
+			holders[e.Object] = e
+		}
+	}
+
+	for _, applies := range g.graph.Edges {
+		capability := g.graph.Nodes[applies.Subject]
+		holder := holders[applies.Subject]
+		if applies.Predicate != string(core.PredicateAppliesTo) || capability == nil || holder == nil {
+			continue
+		}
+		delegation, ok := delegationCosts[stringValue(capability, "capability.name")]
+		if !ok {
+			continue
+		}
+
+		switch delegation.relation {
+		case RelationUnconstrainedDelegation:
+			for _, group := range g.domainAdminGroupsOf(applies.Object) {
+				g.addEdge(holder.Subject, group, delegation.relation, delegation.cost,
+					fmt.Sprintf("%s has unconstrained delegation, a coerced domain controller authentication leaks its TGT", g.label(holder.Subject)),
+					holder, applies)
+			}
+		case RelationAllowedToDelegate:
+			g.addEdge(holder.Subject, applies.Object, delegation.relation, delegation.cost,
+				fmt.Sprintf("%s may impersonate users towards services on %s (%s)", g.label(holder.Subject), g.label(applies.Object), stringValue(capability, "capability.name")),
+				holder, applies)
+		}
+	}
+}
+
+// addGPOs flattens the GPO capabilities "principal has_capability
+// Capability applies_to host" into attack edges towards the host.
+func (g *AttackGraph) addGPOs() {
+	holders := make(map[string]*engine.GraphEdge)
+	for _, e := range g.graph.Edges {
+		if e.Predicate == string(core.PredicateHasCapability) {
+			holders[e.Object] = e
+		}
+	}
+
+	for _, applies := range g.graph.Edges {
+		capability := g.graph.Nodes[applies.Subject]
+		holder := holders[applies.Subject]
+		if applies.Predicate != string(core.PredicateAppliesTo) || capability == nil || holder == nil {
+			continue
+		}
+		policy, ok := gpoCosts[stringValue(capability, "capability.name")]
+		if !ok {
+			continue
+		}
+		g.addEdge(holder.Subject, applies.Object, policy.relation, policy.cost,
+			fmt.Sprintf("%s controls %s through group policy (%s)", g.label(holder.Subject), g.label(applies.Object), stringValue(capability, "capability.precondition")),
+			holder, applies)
+	}
+}
+
+// addTrusts links the Domain Admins groups across trusts that do not stop
+// SID history: whoever controls a domain can forge a ticket with the SIDs of
+// the other domain's admins. Inside a forest SID filtering never applies;
+// across forests only if it is disabled on the trust.
+func (g *AttackGraph) addTrusts() {
+	for _, hasTrust := range g.graph.Edges {
+		node := g.graph.Nodes[hasTrust.Object]
+		if hasTrust.Predicate != string(core.PredicateHasTrust) || node == nil || node.Type != "Trust" {
+			continue
+		}
+		trust := &rpad.Trust{
+			TrustType:    stringValue(node, "trust.trust_type"),
+			Direction:    stringValue(node, "trust.direction"),
+			SIDFiltering: boolValue(node, "trust.sid_filtering_enabled"),
+		}
+		relation := RelationSameForestTrust
+		if !trust.WithinForest() {
+			if trust.SIDFiltering {
+				continue
+			}
+			relation = RelationCrossForestTrust
+		}
+
+		for _, trusts := range g.outgoing[node.UID] {
+			if trusts.Predicate != string(core.PredicateTrusts) {
+				continue
+			}
+			domain, trusted := hasTrust.Subject, trusts.Object
+			targetToDomain, domainToTarget := trust.AccessDirections()
+			if targetToDomain {
+				g.addTrustEdges(trusted, domain, relation, trust, hasTrust, trusts)
+			}
+			if domainToTarget {
+				g.addTrustEdges(domain, trusted, relation, trust, hasTrust, trusts)
+			}
+		}
+	}
+}
+
+// addTrustEdges links the Domain Admins of from to those of to.
+func (g *AttackGraph) addTrustEdges(from, to, relation string, trust *rpad.Trust, assertions ...*engine.GraphEdge) {
+	explanation := fmt.Sprintf("%s and %s share a forest, SID filtering does not stop SID history", g.label(from), g.label(to))
+	if relation == RelationCrossForestTrust {
+		explanation = fmt.Sprintf("SID filtering is disabled on the %s trust, %s honours SID history from %s", trust.TrustType, g.label(to), g.label(from))
+	}
+
+	targets := g.domainAdminGroupsOf(to)
+	for _, source := range g.domainAdminGroupsOf(from) {
+		for _, target := range targets {
+			g.addEdge(source, target, relation, relationCosts[relation], explanation, assertions...)
+		}
+	}
+}
+
+// domainAdminGroupsOf returns the Domain Admins groups inside the domain,
+// or all of them if the uid is no domain.
+func (g *AttackGraph) domainAdminGroupsOf(domainUID string) []string {
+	groups := g.domainAdminGroups()
+	if g.nodeType(domainUID) != "Domain" {
+		return groups
+	}
+
+	// everything below the domain
+	below := map[string]bool{domainUID: true}
+	frontier := []string{domainUID}
+	for len(frontier) > 0 {
+		var next []string
+		for _, uid := range frontier {
+			for _, e := range g.outgoing[uid] {
+				if !containerPredicates[e.Predicate] || below[e.Object] {
+					continue
+				}
+				below[e.Object] = true
+				next = append(next, e.Object)
+			}
+		}
+		frontier = next
+	}
+
+	var inDomain []string
+	for _, group := range groups {
+		if below[group] {
+			inDomain = append(inDomain, group)
+		}
+	}
+	return inDomain
+}
+
+// containerPredicates link a domain or directory node to what it contains.
+var containerPredicates = map[string]bool{
+	string(core.PredicateHasGroup): true,
+	string(core.PredicateHasUser):  true,
+	string(core.PredicateHasHost):  true,
+	string(core.PredicateContains): true,
+}
+
+// ── Labels ──────────────────────────────────────────────────────────────────
+
+// labelPredicates are tried in order to find a readable node label.
+var labelPredicates = []string{
+	"user.sam_account_name",
+	"security_principal.name",
+	"host.dns_host_name",
+	"host.hostname",
+	"host.name",
+	"host.ip",
+	"domain.dns_name",
+	"domain.name",
+	"directory_node.name",
+	"gpo.name",
+	"service.name",
+	"capability.name",
+}
+
+func nodeLabel(n *engine.GraphNode) string {
+	for _, pred := range labelPredicates {
+		if v, ok := n.Values[pred].(string); ok && v != "" {
+			return v
+		}
+	}
+	return n.Type + " " + n.UID
+}
+
+// NewPathAssertion converts a backing assertion for the API.
+func NewPathAssertion(e *engine.GraphEdge) *PathAssertion {
+	return &PathAssertion{
+		UID:        e.UID,
+		Predicate:  e.Predicate,
+		Subject:    e.Subject,
+		Object:     e.Object,
+		Status:     e.Status,
+		Confidence: e.Confidence,
+		Source:     e.Source,
+	}
+}
+
+// stringValue reads a scalar string of a graph node.
+func stringValue(n *engine.GraphNode, key string) string {
+	s, _ := n.Values[key].(string)
+	return s
+}
+
+// boolValue reads a scalar bool of a graph node.
+func boolValue(n *engine.GraphNode, key string) bool {
+	b, _ := n.Values[key].(bool)
+	return b
+}
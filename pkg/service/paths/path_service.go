@@ -0,0 +1,376 @@
+package paths
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// Target selectors for ?to=. Anything else is taken as entity UID.
+const (
+	TargetHighValue    = "high-value"
+	TargetDomainAdmins = "domain-admins"
+)
+
+// Search modes: cheapest sums the hop costs, shortest counts the hops.
+const (
+	ModeCheapest = "cheapest"
+	ModeShortest = "shortest"
+)
+
+const (
+	defaultPathLimit = 10
+	maxPathLimit     = 100
+)
+
+var (
+	ErrProjectMissing = errors.New("project not found")
+	ErrUnknownNode    = errors.New("entity not found in project")
+	ErrInvalidQuery   = errors.New("invalid path query")
+)
+
+// domainAdminRIDs are the well-known RIDs of the Domain Admins group;
+// domainAdminNames covers groups without SID (English and German AD).
+var (
+	domainAdminRIDs  = []string{"-512"}
+	domainAdminNames = map[string]bool{"domain admins": true, "domänen-admins": true}
+)
+
+// PathQuery selects start, targets and search mode.
+type PathQuery struct {
+	From  string
+	To    string
+	Mode  string
+	Limit int
+}
+
+// PathHop is one step of a path with the assertions it rests on.
+type PathHop struct {
+	From        *PathNode        `json:"from"`
+	To          *PathNode        `json:"to"`
+	Relation    string           `json:"relation"`
+	Cost        float64          `json:"cost"`
+	Explanation string           `json:"explanation"`
+	Assertions  []*PathAssertion `json:"assertions"`
+}
+
+// AttackPath leads from the start to one target.
+type AttackPath struct {
+	Target *PathNode  `json:"target"`
+	Cost   float64    `json:"cost"`
+	Length int        `json:"length"`
+	Hops   []*PathHop `json:"hops"`
+}
+
+// PathResult lists the best path to each reachable target, best first.
+type PathResult struct {
+	ProjectUID  string        `json:"project_uid"`
+	From        *PathNode     `json:"from"`
+	Mode        string        `json:"mode"`
+	Targets     []*PathNode   `json:"targets"`
+	Paths       []*AttackPath `json:"paths"`
+	Unreachable int           `json:"unreachable"`
+	GraphNodes  int           `json:"graph_nodes"`
+	GraphEdges  int           `json:"graph_edges"`
+}
+
+// -----------------------------------------------------------------------------
+// PathService
+// -----------------------------------------------------------------------------
+
+// PathService computes attack paths over the assertions of a project.
+type PathService struct {
+	db        *dgo.Dgraph
+	graphRepo engine.ProjectGraphRepository
+}
+
+func NewPathService(dgraphCon *dgo.Dgraph) *PathService {
+	return &PathService{
+		db:        dgraphCon,
+		graphRepo: engine.NewDgraphProjectGraphRepository(dgraphCon),
+	}
+}
+
+// LoadAttackGraph reads the project graph in one read-only transaction and
+// derives the attack graph from it.
+func (s *PathService) LoadAttackGraph(ctx context.Context, projectUID string) (*AttackGraph, error) {
+	graph, err := db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) (*engine.ProjectGraph, error) {
+		return s.graphRepo.GetProjectGraph(ctx, tx, projectUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading project graph: %w", err)
+	}
+	if project := graph.Nodes[projectUID]; project == nil || project.Type != "Project" {
+		return nil, ErrProjectMissing
+	}
+	return BuildAttackGraph(graph), nil
+}
+
+// FindPaths returns the cheapest (or shortest) path from the start entity to
+// each target: the given entity, the high-value marked entities, the Domain
+// Admins groups, or by default both of the latter.
+func (s *PathService) FindPaths(ctx context.Context, projectUID string, query PathQuery) (*PathResult, error) {
+	query.Mode = strings.ToLower(strings.TrimSpace(query.Mode))
+	switch query.Mode {
+	case "":
+		query.Mode = ModeCheapest
+	case ModeCheapest, ModeShortest:
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q (supported: %s, %s)", ErrInvalidQuery, query.Mode, ModeCheapest, ModeShortest)
+	}
+	if query.From == "" {
+		return nil, fmt.Errorf("%w: from is required", ErrInvalidQuery)
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultPathLimit
+	}
+	query.Limit = min(query.Limit, maxPathLimit)
+
+	g, err := s.LoadAttackGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := g.Node(query.From)
+	if from == nil {
+		return nil, fmt.Errorf("%w: from %s", ErrUnknownNode, query.From)
+	}
+	targets, err := g.Targets(query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PathResult{
+		ProjectUID: projectUID,
+		From:       from,
+		Mode:       query.Mode,
+		Targets:    targets,
+		Paths:      []*AttackPath{},
+		GraphNodes: len(g.Nodes),
+		GraphEdges: g.EdgeCount(),
+	}
+
+	tree := g.ShortestPaths(from.UID, query.Mode)
+	for _, target := range targets {
+		if target.UID == from.UID {
+			continue
+		}
+		path := tree.PathTo(target.UID)
+		if path == nil {
+			result.Unreachable++
+			continue
+		}
+		result.Paths = append(result.Paths, path)
+	}
+
+	sort.SliceStable(result.Paths, func(i, j int) bool {
+		a, b := result.Paths[i], result.Paths[j]
+		if query.Mode == ModeShortest && a.Length != b.Length {
+			return a.Length < b.Length
+		}
+		if a.Cost != b.Cost {
+			return a.Cost < b.Cost
+		}
+		return a.Length < b.Length
+	})
+	if len(result.Paths) > query.Limit {
+		result.Paths = result.Paths[:query.Limit]
+	}
+
+	log.Printf("[Paths] project=%s from=%s mode=%s targets=%d paths=%d unreachable=%d",
+		projectUID, from.UID, query.Mode, len(targets), len(result.Paths), result.Unreachable)
+
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// Targets
+// -----------------------------------------------------------------------------
+
+// Targets resolves the ?to= selector.
+func (g *AttackGraph) Targets(selector string) ([]*PathNode, error) {
+	selector = strings.TrimSpace(selector)
+	var uids []string
+
+	switch strings.ToLower(selector) {
+	case "":
+		uids = append(g.highValueTargets(), g.domainAdminGroups()...)
+	case TargetHighValue:
+		uids = g.highValueTargets()
+	case TargetDomainAdmins:
+		uids = g.domainAdminGroups()
+	default:
+		if g.Node(selector) == nil {
+			return nil, fmt.Errorf("%w: to %s", ErrUnknownNode, selector)
+		}
+		uids = []string{selector}
+	}
+
+	seen := make(map[string]bool, len(uids))
+	targets := make([]*PathNode, 0, len(uids))
+	for _, uid := range uids {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		if n := g.Node(uid); n != nil {
+			targets = append(targets, n)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].UID < targets[j].UID })
+	return targets, nil
+}
+
+// highValueTargets are the entities any active assertion marks as high value.
+func (g *AttackGraph) highValueTargets() []string {
+	var uids []string
+	for _, e := range g.graph.Edges {
+		if e.HighValue {
+			uids = append(uids, e.Object)
+		}
+	}
+	return uids
+}
+
+func (g *AttackGraph) domainAdminGroups() []string {
+	var uids []string
+	for _, n := range g.graph.NodesOfType("Group") {
+		sid := strings.ToUpper(stringValue(n, "security_principal.sid"))
+		name := strings.ToLower(stringValue(n, "security_principal.name"))
+		if i := strings.LastIndex(name, "\\"); i >= 0 {
+			name = name[i+1:]
+		}
+		if domainAdminNames[name] || hasRID(sid, domainAdminRIDs) {
+			uids = append(uids, n.UID)
+		}
+	}
+	return uids
+}
+
+func hasRID(sid string, rids []string) bool {
+	if !strings.HasPrefix(sid, "S-1-5-21-") {
+		return false
+	}
+	for _, rid := range rids {
+		if strings.HasSuffix(sid, rid) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// Dijkstra
+// -----------------------------------------------------------------------------
+
+// PathTree holds the best predecessor edge of every node reachable from the
+// start.
+type PathTree struct {
+	graph *AttackGraph
+	start string
+	cost  map[string]float64
+	prev  map[string]*AttackEdge
+}
+
+// ShortestPaths runs Dijkstra from start. In shortest mode every hop costs 1.
+func (g *AttackGraph) ShortestPaths(start, mode string) *PathTree {
+	tree := &PathTree{
+		graph: g,
+		start: start,
+		cost:  map[string]float64{start: 0},
+		prev:  make(map[string]*AttackEdge),
+	}
+	done := make(map[string]bool)
+
+	queue := &pathQueue{{uid: start}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(*pathItem)
+		if done[item.uid] {
+			continue
+		}
+		done[item.uid] = true
+
+		for _, e := range g.Out[item.uid] {
+			weight := e.Cost
+			if mode == ModeShortest {
+				weight = 1
+			}
+			cost := item.cost + weight
+			if known, ok := tree.cost[e.To]; ok && known <= cost {
+				continue
+			}
+			tree.cost[e.To] = cost
+			tree.prev[e.To] = e
+			heap.Push(queue, &pathItem{uid: e.To, cost: cost})
+		}
+	}
+	return tree
+}
+
+// Reachable reports whether the start reaches the node.
+func (t *PathTree) Reachable(uid string) bool {
+	_, ok := t.cost[uid]
+	return ok
+}
+
+// PathTo reconstructs the path to target, nil if unreachable.
+func (t *PathTree) PathTo(target string) *AttackPath {
+	if !t.Reachable(target) || target == t.start {
+		return nil
+	}
+
+	var hops []*PathHop
+	total := 0.0
+	for uid := target; uid != t.start; {
+		e := t.prev[uid]
+		hop := &PathHop{
+			From:        t.graph.Node(e.From),
+			To:          t.graph.Node(e.To),
+			Relation:    e.Relation,
+			Cost:        e.Cost,
+			Explanation: e.Explanation,
+		}
+		for _, a := range e.Assertions {
+			hop.Assertions = append(hop.Assertions, NewPathAssertion(a))
+		}
+		hops = append(hops, hop)
+		total += e.Cost
+		uid = e.From
+	}
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+
+	return &AttackPath{
+		Target: t.graph.Node(target),
+		Cost:   total,
+		Length: len(hops),
+		Hops:   hops,
+	}
+}
+
+type pathItem struct {
+	uid  string
+	cost float64
+}
+
+type pathQueue []*pathItem
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(*pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
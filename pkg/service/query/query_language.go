@@ -0,0 +1,387 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The query language selects entities of one type of the project:
+//
+//	users where kerberoastable and member_of* "Domain Admins" limit 20
+//	hosts where operating_system contains "2008" or not risk_score < 50
+//	groups where is_privileged and not has_member* svc_backup
+//
+// A query starts with the entity type, followed by an optional where clause
+// and an optional limit. Conditions are
+//
+//	<field>                   a bool field is true
+//	<field> <op> <value>      op is =, !=, <, <=, >, >= or contains
+//	<relation>[*] <target>    the entity is related to the named target,
+//	                          * follows the relation transitively
+//
+// combined with and, or, not and parentheses. Targets are names of entities
+// of the project; names with spaces may be written without quotes as long as
+// they contain no keyword.
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+	maxQueryLength    = 4096
+)
+
+// -----------------------------------------------------------------------------
+// Lexer
+// -----------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenStar
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) keyword(word string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var keywords = map[string]bool{
+	"where":    true,
+	"and":      true,
+	"or":       true,
+	"not":      true,
+	"limit":    true,
+	"contains": true,
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.@$:", r)
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '*':
+			tokens = append(tokens, token{tokenStar, "*", i})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{tokenOperator, "=", i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokenOperator, string(runes[i : i+2]), i})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, fmt.Errorf("%w: unexpected '!' at %d", ErrInvalidQuery, i)
+			}
+			tokens = append(tokens, token{tokenOperator, string(r), i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("%w: unterminated string at %d", ErrInvalidQuery, start)
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					sb.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == r {
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+			}
+			tokens = append(tokens, token{tokenString, sb.String(), start})
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[start:i]), start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalidQuery, r, i)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// -----------------------------------------------------------------------------
+// AST
+// -----------------------------------------------------------------------------
+
+// Query is a parsed query.
+type Query struct {
+	Source string
+	Entity *entityType
+	Where  condition
+	Limit  int
+}
+
+type condition interface {
+	// scalar reports whether the condition only compares fields and can be
+	// compiled into a single DQL filter.
+	scalar() bool
+}
+
+type andCondition struct{ left, right condition }
+type orCondition struct{ left, right condition }
+type notCondition struct{ inner condition }
+
+// compareCondition compares a field with a literal. A bool field without
+// operator is compared with true.
+type compareCondition struct {
+	field *queryField
+	op    string
+	value string
+}
+
+// relationCondition holds if the entity is related to a target by name.
+type relationCondition struct {
+	relation   *relation
+	transitive bool
+	target     string
+}
+
+func (c *andCondition) scalar() bool      { return c.left.scalar() && c.right.scalar() }
+func (c *orCondition) scalar() bool       { return c.left.scalar() && c.right.scalar() }
+func (c *notCondition) scalar() bool      { return c.inner.scalar() }
+func (c *compareCondition) scalar() bool  { return true }
+func (c *relationCondition) scalar() bool { return false }
+
+// -----------------------------------------------------------------------------
+// Parser
+// -----------------------------------------------------------------------------
+
+// Parse parses and validates a query against the vocabulary. Errors wrap
+// ErrInvalidQuery.
+func Parse(source string) (*Query, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("%w: query is empty", ErrInvalidQuery)
+	}
+	if len(source) > maxQueryLength {
+		return nil, fmt.Errorf("%w: query is longer than %d characters", ErrInvalidQuery, maxQueryLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	head := p.next()
+	if head.kind != tokenWord {
+		return nil, p.errorf(head, "expected an entity type")
+	}
+	entity := lookupEntityType(head.text)
+	if entity == nil {
+		return nil, p.errorf(head, "unknown entity type, expected one of %s", strings.Join(entityTypeNames(), ", "))
+	}
+
+	q := &Query{Source: source, Entity: entity, Limit: defaultQueryLimit}
+
+	if p.peek().keyword("where") {
+		p.next()
+		if q.Where, err = p.parseOr(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().keyword("limit") {
+		p.next()
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != tokenWord || err != nil || limit <= 0 {
+			return nil, p.errorf(t, "expected a positive limit")
+		}
+		if limit > maxQueryLimit {
+			return nil, p.errorf(t, "limit must not exceed %d", maxQueryLimit)
+		}
+		q.Limit = limit
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected")
+	}
+
+	return q, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s %s at %d", ErrInvalidQuery, fmt.Sprintf(format, args...), t, t.pos)
+}
+
+func (p *parser) parseOr(entity *entityType) (condition, error) {
+	left, err := p.parseAnd(entity)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.next()
+		right, err := p.parseAnd(entity)
+		if err != nil {
+			return nil, err
+		}
+		left = &orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(entity *entityType) (condition, error) {
+	left, err := p.parseFactor(entity)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.next()
+		right, err := p.parseFactor(entity)
+		if err != nil {
+			return nil, err
+		}
+		left = &andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor(entity *entityType) (condition, error) {
+	t := p.next()
+
+	switch {
+	case t.keyword("not"):
+		inner, err := p.parseFactor(entity)
+		if err != nil {
+			return nil, err
+		}
+		return &notCondition{inner}, nil
+
+	case t.kind == tokenLParen:
+		inner, err := p.parseOr(entity)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')' instead of")
+		}
+		return inner, nil
+
+	case t.kind != tokenWord || keywords[strings.ToLower(t.text)]:
+		return nil, p.errorf(t, "expected a condition instead of")
+	}
+
+	if rel := entity.relation(t.text); rel != nil {
+		return p.parseRelation(t, rel)
+	}
+
+	field := entity.field(t.text)
+	if field == nil {
+		return nil, p.errorf(t, "%s have no field or relation", entity.Names[0])
+	}
+
+	op := p.peek()
+	if op.kind != tokenOperator && !op.keyword("contains") {
+		if field.Kind != kindBool {
+			return nil, p.errorf(t, "expected an operator after non-bool field")
+		}
+		return &compareCondition{field: field, op: "=", value: "true"}, nil
+	}
+	p.next()
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorf(value, "expected a value instead of")
+	}
+
+	cond := &compareCondition{field: field, op: strings.ToLower(op.text), value: value.text}
+	if err := cond.validate(); err != nil {
+		return nil, p.errorf(t, "%v:", err)
+	}
+	return cond, nil
+}
+
+// parseRelation parses "[*] <target>". A quoted target is taken as is,
+// otherwise the words up to the next keyword or parenthesis form the name.
+func (p *parser) parseRelation(t token, rel *relation) (condition, error) {
+	cond := &relationCondition{relation: rel}
+
+	if p.peek().kind == tokenStar {
+		p.next()
+		if !rel.Transitive {
+			return nil, p.errorf(t, "relation cannot be followed transitively:")
+		}
+		cond.transitive = true
+	}
+
+	if p.peek().kind == tokenString {
+		cond.target = p.next().text
+	} else {
+		var words []string
+		for p.peek().kind == tokenWord && !keywords[strings.ToLower(p.peek().text)] {
+			words = append(words, p.next().text)
+		}
+		cond.target = strings.Join(words, " ")
+	}
+
+	if strings.TrimSpace(cond.target) == "" {
+		return nil, p.errorf(p.peek(), "expected a target name for %s instead of", rel.Name)
+	}
+	return cond, nil
+}
@@ -0,0 +1,191 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		entity string
+		limit  int
+		filter string // compiled DQL filter of a scalar where clause
+	}{
+		{name: "entity only", source: "users", entity: "User", limit: defaultQueryLimit},
+		{name: "alias and limit", source: "  Computers LIMIT 20 ", entity: "Host", limit: 20},
+		{
+			name: "bool field", source: "users where kerberoastable limit 5", entity: "User", limit: 5,
+			filter: "eq(user.kerberoastable, true)",
+		},
+		{
+			name: "or with not", source: `hosts where operating_system contains "2008" or not risk_score < 50`,
+			entity: "Host", limit: defaultQueryLimit,
+			filter: `(allofterms(host.operating_system, "2008") OR NOT (lt(host.risk_score, 50)))`,
+		},
+		{
+			name:   "and binds stronger than or",
+			source: "users where has_spn or asrep_roastable and is_disabled = false",
+			entity: "User", limit: defaultQueryLimit,
+			filter: "(eq(user.has_spn, true) OR (eq(user.asrep_roastable, true) AND eq(user.is_disabled, false)))",
+		},
+		{
+			name:   "parentheses",
+			source: "users where (has_spn or asrep_roastable) and is_disabled != true",
+			entity: "User", limit: defaultQueryLimit,
+			filter: "((eq(user.has_spn, true) OR eq(user.asrep_roastable, true)) AND NOT eq(user.is_disabled, true))",
+		},
+		{
+			name: "date", source: "users where pwd_last_set < 2020-01-01", entity: "User", limit: defaultQueryLimit,
+			filter: `lt(user.pwd_last_set, "2020-01-01T00:00:00Z")`,
+		},
+		{
+			name: "escaped string", source: `hosts where hostname = 'dc"01\'s'`, entity: "Host", limit: defaultQueryLimit,
+			filter: `eq(host.hostname, "dc\"01's")`,
+		},
+		{
+			name: "list contains", source: "groups where privileges contains SeBackupPrivilege", entity: "Group",
+			limit: defaultQueryLimit, filter: `allofterms(group.privileges, "SeBackupPrivilege")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.source, err)
+			}
+			if q.Entity.Type != tt.entity || q.Limit != tt.limit {
+				t.Errorf("Parse(%q) = %s limit %d, want %s limit %d", tt.source, q.Entity.Type, q.Limit, tt.entity, tt.limit)
+			}
+			if tt.filter == "" {
+				if q.Where != nil {
+					t.Errorf("Parse(%q) has a where clause", tt.source)
+				}
+				return
+			}
+			if !q.Where.scalar() {
+				t.Fatalf("Parse(%q) where clause is not scalar", tt.source)
+			}
+			filter, err := dqlFilter(q.Where)
+			if err != nil {
+				t.Fatalf("dqlFilter() error = %v", err)
+			}
+			if filter != tt.filter {
+				t.Errorf("dqlFilter() = %s, want %s", filter, tt.filter)
+			}
+		})
+	}
+}
+
+func TestParseRelations(t *testing.T) {
+	tests := []struct {
+		source     string
+		relation   string
+		transitive bool
+		target     string
+	}{
+		{source: "users where member_of* Domain Admins", relation: "member_of", transitive: true, target: "Domain Admins"},
+		{source: `users where member_of "Domain Admins"`, relation: "member_of", target: "Domain Admins"},
+		{source: "users where admin_to dc01 limit 3", relation: "admin_to", target: "dc01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			q, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.source, err)
+			}
+			rel, ok := q.Where.(*relationCondition)
+			if !ok {
+				t.Fatalf("Parse(%q) where = %T, want a relation", tt.source, q.Where)
+			}
+			if rel.relation.Name != tt.relation || rel.transitive != tt.transitive || rel.target != tt.target {
+				t.Errorf("relation = %s transitive=%v target=%q, want %s transitive=%v target=%q",
+					rel.relation.Name, rel.transitive, rel.target, tt.relation, tt.transitive, tt.target)
+			}
+			if q.Where.scalar() {
+				t.Error("relation conditions must not be scalar")
+			}
+		})
+	}
+
+	q, err := Parse("users where kerberoastable and member_of* Domain Admins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Where.scalar() {
+		t.Error("a condition with a relation must not be compiled into one filter")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{name: "empty", source: "   ", wantErr: "query is empty"},
+		{name: "too long", source: "users where name = " + strings.Repeat("a", maxQueryLength), wantErr: "longer than"},
+		{name: "unknown entity", source: "widgets", wantErr: "unknown entity type"},
+		{name: "missing condition", source: "users where", wantErr: "expected a condition"},
+		{name: "unknown field", source: "users where colour = red", wantErr: "have no field or relation"},
+		{name: "non-bool without operator", source: "users where name", wantErr: "expected an operator"},
+		{name: "bool value", source: "users where kerberoastable = maybe", wantErr: "expects true or false"},
+		{name: "int value", source: "users where risk_score > high", wantErr: "expects a number"},
+		{name: "date value", source: "users where last_logon > yesterday", wantErr: "expects a date"},
+		{name: "unordered field", source: "hosts where operating_system < 5", wantErr: "cannot be ordered"},
+		{name: "contains without term index", source: "users where sid contains S-1-5", wantErr: "contains is not supported"},
+		{name: "list equality", source: "groups where privileges = SeBackupPrivilege", wantErr: "use contains"},
+		{name: "missing value", source: "users where name =", wantErr: "expected a value"},
+		{name: "missing target", source: "users where member_of", wantErr: "expected a target name"},
+		{name: "not transitive", source: "users where admin_to* dc01", wantErr: "cannot be followed transitively"},
+		{name: "unclosed parenthesis", source: "users where (kerberoastable", wantErr: "expected ')'"},
+		{name: "unterminated string", source: `users where name = "alice`, wantErr: "unterminated string"},
+		{name: "lone bang", source: "users where name ! alice", wantErr: "unexpected '!'"},
+		{name: "unexpected character", source: "users where name = alice;", wantErr: "unexpected ';'"},
+		{name: "zero limit", source: "users limit 0", wantErr: "positive limit"},
+		{name: "limit too high", source: "users limit 5000", wantErr: "must not exceed"},
+		{name: "trailing tokens", source: "users kerberoastable", wantErr: "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("Parse(%q) error = %v, want ErrInvalidQuery", tt.source, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDQLFilterErrors(t *testing.T) {
+	users := lookupEntityType("users")
+	tests := []struct {
+		name string
+		cond condition
+	}{
+		{name: "unknown operator", cond: &compareCondition{field: users.field("risk_score"), op: "~", value: "1"}},
+		{name: "relation", cond: &relationCondition{relation: users.relation("member_of"), target: "x"}},
+		{
+			name: "nested",
+			cond: &andCondition{
+				&compareCondition{field: users.field("kerberoastable"), op: "=", value: "true"},
+				&notCondition{&relationCondition{relation: users.relation("member_of"), target: "x"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dqlFilter(tt.cond); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("dqlFilter() error = %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
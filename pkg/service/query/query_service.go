@@ -0,0 +1,553 @@
+package query
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/queries"
+	"RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/redpaths"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSavedQueryNotFound  = errors.New("saved query not found")
+	ErrDuplicateSavedQuery = errors.New("saved query name already in use")
+	ErrInvalidSavedQuery   = errors.New("invalid saved query")
+)
+
+const (
+	// maxRelationDepth caps how far transitive relations are followed.
+	maxRelationDepth  = 10
+	maxSavedQueryName = 200
+)
+
+// QueryResult are the entities a query selected, ordered by name.
+type QueryResult struct {
+	Query     string                   `json:"query"`
+	Type      string                   `json:"type"`
+	Count     int                      `json:"count"`
+	Truncated bool                     `json:"truncated"`
+	Entities  []*res.EntityResult[any] `json:"entities"`
+}
+
+// SavedQueryInput is what a client sends to save or update a query.
+type SavedQueryInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Query       string `json:"query" binding:"required"`
+}
+
+// QueryService runs graph queries against a project and manages the saved
+// queries of projects.
+type QueryService struct {
+	db             *dgo.Dgraph
+	postgresCon    *gorm.DB
+	savedQueryRepo queries.RedPathsSavedQueryRepository
+}
+
+func NewQueryService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB) *QueryService {
+	return &QueryService{
+		db:             dgraphCon,
+		postgresCon:    postgresCon,
+		savedQueryRepo: queries.NewPostgresRedPathsSavedQueryRepository(),
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Run
+// -----------------------------------------------------------------------------
+
+// Run parses the query and selects the matching entities of the project.
+// Only entities reachable through the project's hierarchy are considered,
+// relation targets included.
+func (s *QueryService) Run(ctx context.Context, projectUID, source string) (*QueryResult, error) {
+	q, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecuteRead(ctx, s.db, func(tx *dgo.Txn) (*QueryResult, error) {
+		run := &queryRun{
+			ctx:        ctx,
+			tx:         tx,
+			projectUID: projectUID,
+			entities:   make(map[string]map[string]*res.EntityResult[any]),
+			steps:      make(map[string][]string),
+		}
+		return run.execute(q)
+	})
+}
+
+// queryRun evaluates one query within a read transaction and caches what it
+// loaded on the way.
+type queryRun struct {
+	ctx        context.Context
+	tx         *dgo.Txn
+	projectUID string
+	// entity type → uid → entity, loaded with the identifying fields only
+	entities map[string]map[string]*res.EntityResult[any]
+	// "uid|relation" → objects reached in one step
+	steps map[string][]string
+}
+
+func (r *queryRun) execute(q *Query) (*QueryResult, error) {
+	started := time.Now()
+	entity := q.Entity
+
+	var selected map[string]*res.EntityResult[any]
+	if q.Where == nil || q.Where.scalar() {
+		filter := ""
+		if q.Where != nil {
+			var err error
+			if filter, err = dqlFilter(q.Where); err != nil {
+				return nil, err
+			}
+		}
+		loaded, err := r.load(entity, filter, entity.ResultFields)
+		if err != nil {
+			return nil, err
+		}
+		selected = loaded
+	} else {
+		universe, err := r.load(entity, "", entity.ResultFields)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := r.evaluate(entity, q.Where, universe)
+		if err != nil {
+			return nil, err
+		}
+		selected = make(map[string]*res.EntityResult[any], len(matches))
+		for uid := range matches {
+			if result := universe[uid]; result != nil {
+				selected[uid] = result
+			}
+		}
+	}
+
+	result := &QueryResult{
+		Query:    q.Source,
+		Type:     entity.Type,
+		Count:    len(selected),
+		Entities: make([]*res.EntityResult[any], 0, len(selected)),
+	}
+	for _, e := range selected {
+		result.Entities = append(result.Entities, e)
+	}
+	sort.Slice(result.Entities, func(i, j int) bool {
+		ui, ni := entity.ident(result.Entities[i].Entity)
+		uj, nj := entity.ident(result.Entities[j].Entity)
+		if !strings.EqualFold(ni, nj) {
+			return strings.ToLower(ni) < strings.ToLower(nj)
+		}
+		return ui < uj
+	})
+	if len(result.Entities) > q.Limit {
+		result.Entities = result.Entities[:q.Limit]
+		result.Truncated = true
+	}
+
+	log.Printf("[GraphQuery] %q on project %s selected %d %s entities in %s",
+		q.Source, r.projectUID, result.Count, entity.Type, time.Since(started).Round(time.Millisecond))
+	return result, nil
+}
+
+// load returns the entities of the type in the project matching the DQL
+// filter, merged over all scopes of the type.
+func (r *queryRun) load(entity *entityType, filter string, fields []string) (map[string]*res.EntityResult[any], error) {
+	loaded := make(map[string]*res.EntityResult[any])
+
+	for _, scope := range entity.scopes {
+		hops := append([]dgraph.HopConfig(nil), scope...)
+		hops[len(hops)-1].ObjectFilter = filter
+
+		results, err := entity.fetch(r.ctx, r.tx, r.projectUID, hops, fields)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s entities: %w", entity.Type, err)
+		}
+		for _, result := range results {
+			uid, _ := entity.ident(result.Entity)
+			if uid == "" {
+				continue
+			}
+			existing := loaded[uid]
+			if existing == nil {
+				loaded[uid] = result
+				continue
+			}
+			existing.Assertions = append(existing.Assertions, result.Assertions...)
+			existing.Metadata.AssertionCount = len(existing.Assertions)
+		}
+	}
+
+	return loaded, nil
+}
+
+// project returns all entities of the type in the project with uid and name.
+func (r *queryRun) project(entity *entityType) (map[string]*res.EntityResult[any], error) {
+	if cached, ok := r.entities[entity.Type]; ok {
+		return cached, nil
+	}
+	loaded, err := r.load(entity, "", []string{"uid", entity.Fields[0].Predicate, "dgraph.type"})
+	if err != nil {
+		return nil, err
+	}
+	r.entities[entity.Type] = loaded
+	return loaded, nil
+}
+
+// evaluate returns the uids of the universe the condition holds for.
+// Scalar subconditions are compiled into one DQL filter each.
+func (r *queryRun) evaluate(entity *entityType, c condition, universe map[string]*res.EntityResult[any]) (map[string]bool, error) {
+	if c.scalar() {
+		filter, err := dqlFilter(c)
+		if err != nil {
+			return nil, err
+		}
+		matching, err := r.load(entity, filter, []string{"uid", entity.Fields[0].Predicate, "dgraph.type"})
+		if err != nil {
+			return nil, err
+		}
+		matches := make(map[string]bool, len(matching))
+		for uid := range matching {
+			matches[uid] = true
+		}
+		return matches, nil
+	}
+
+	switch c := c.(type) {
+	case *andCondition:
+		left, err := r.evaluate(entity, c.left, universe)
+		if err != nil || len(left) == 0 {
+			return left, err
+		}
+		right, err := r.evaluate(entity, c.right, universe)
+		if err != nil {
+			return nil, err
+		}
+		for uid := range left {
+			if !right[uid] {
+				delete(left, uid)
+			}
+		}
+		return left, nil
+
+	case *orCondition:
+		left, err := r.evaluate(entity, c.left, universe)
+		if err != nil {
+			return nil, err
+		}
+		right, err := r.evaluate(entity, c.right, universe)
+		if err != nil {
+			return nil, err
+		}
+		for uid := range right {
+			left[uid] = true
+		}
+		return left, nil
+
+	case *notCondition:
+		inner, err := r.evaluate(entity, c.inner, universe)
+		if err != nil {
+			return nil, err
+		}
+		matches := make(map[string]bool)
+		for uid := range universe {
+			if !inner[uid] {
+				matches[uid] = true
+			}
+		}
+		return matches, nil
+
+	case *relationCondition:
+		return r.related(c, universe)
+	}
+
+	return nil, fmt.Errorf("%w: unsupported condition %T", ErrInvalidQuery, c)
+}
+
+// related returns the uids of the universe that have the relation to a
+// target of the condition.
+func (r *queryRun) related(c *relationCondition, universe map[string]*res.EntityResult[any]) (map[string]bool, error) {
+	rel := c.relation
+	matches := make(map[string]bool)
+
+	targets, err := r.targets(rel.Targets, c.target)
+	if err != nil || len(targets) == 0 {
+		return matches, err
+	}
+
+	// transitive steps only pass through entities of the project
+	var boundary map[string]*res.EntityResult[any]
+	if c.transitive {
+		if boundary, err = r.project(entityTypeByType(rel.Via)); err != nil {
+			return nil, err
+		}
+	}
+
+	if rel.Inverse {
+		starts := make([]string, 0, len(targets))
+		for uid := range targets {
+			starts = append(starts, uid)
+		}
+		reached, err := r.closure(starts, rel, c.transitive, boundary)
+		if err != nil {
+			return nil, err
+		}
+		for uid := range reached {
+			if universe[uid] != nil {
+				matches[uid] = true
+			}
+		}
+		return matches, nil
+	}
+
+	for uid := range universe {
+		reached, err := r.closure([]string{uid}, rel, c.transitive, boundary)
+		if err != nil {
+			return nil, err
+		}
+		for target := range targets {
+			if reached[target] {
+				matches[uid] = true
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// targets resolves a target of a relation among the project's entities of
+// the given types, by name (case-insensitive) or uid.
+func (r *queryRun) targets(types []string, target string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	for _, t := range types {
+		entity := entityTypeByType(t)
+		entities, err := r.project(entity)
+		if err != nil {
+			return nil, err
+		}
+		for uid, e := range entities {
+			if _, name := entity.ident(e.Entity); uid == target || strings.EqualFold(name, target) {
+				found[uid] = true
+			}
+		}
+	}
+	return found, nil
+}
+
+// closure follows the relation from the starts, transitively through the
+// boundary if requested, and returns the uids reached.
+func (r *queryRun) closure(starts []string, rel *relation, transitive bool, boundary map[string]*res.EntityResult[any]) (map[string]bool, error) {
+	reached := make(map[string]bool)
+	frontier := starts
+
+	for depth := 0; len(frontier) > 0 && depth < maxRelationDepth; depth++ {
+		var next []string
+		for _, uid := range frontier {
+			objects, err := r.step(uid, rel)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range objects {
+				if reached[object] {
+					continue
+				}
+				reached[object] = true
+				if transitive && boundary[object] != nil {
+					next = append(next, object)
+				}
+			}
+		}
+		if !transitive {
+			break
+		}
+		frontier = next
+	}
+
+	return reached, nil
+}
+
+type nodeRef struct {
+	UID string `json:"uid"`
+}
+
+// step returns the objects the relation's hops lead to from uid, ignoring
+// invalidated and expired assertions.
+func (r *queryRun) step(uid string, rel *relation) ([]string, error) {
+	key := uid + "|" + rel.Name
+	if cached, ok := r.steps[key]; ok {
+		return cached, nil
+	}
+
+	results, err := dgraph.GetEntitiesWithAssertionsNHop[*nodeRef](r.ctx, r.tx, uid, rel.Hops, []string{"uid", "dgraph.type"}, "runGraphQueryRelation")
+	if err != nil {
+		return nil, fmt.Errorf("following %s from %s: %w", rel.Name, uid, err)
+	}
+
+	objects := make([]string, 0, len(results))
+	for _, result := range results {
+		for _, a := range result.Assertions {
+			if a.Status != core.StatusInvalidated && a.Status != core.StatusExpired {
+				objects = append(objects, result.Entity.UID)
+				break
+			}
+		}
+	}
+
+	r.steps[key] = objects
+	return objects, nil
+}
+
+// -----------------------------------------------------------------------------
+// Saved queries
+// -----------------------------------------------------------------------------
+
+func (s *QueryService) GetSavedQueries(ctx context.Context, projectUID string) ([]*redpaths.SavedQuery, error) {
+	return db.ExecutePostgresRead(ctx, s.postgresCon, func(tx *gorm.DB) ([]*redpaths.SavedQuery, error) {
+		return s.savedQueryRepo.GetAll(ctx, tx, projectUID)
+	})
+}
+
+func (s *QueryService) GetSavedQuery(ctx context.Context, projectUID, queryID string) (*redpaths.SavedQuery, error) {
+	saved, err := db.ExecutePostgresRead(ctx, s.postgresCon, func(tx *gorm.DB) (*redpaths.SavedQuery, error) {
+		return s.savedQueryRepo.Get(ctx, tx, projectUID, queryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSavedQueryNotFound, queryID)
+	}
+	return saved, nil
+}
+
+// SaveQuery stores a query under a name unique in the project. The query is
+// parsed first, so only valid queries are saved.
+func (s *QueryService) SaveQuery(ctx context.Context, projectUID string, input SavedQueryInput, actor string) (*redpaths.SavedQuery, error) {
+	name, source, err := validateSavedQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	saved := &redpaths.SavedQuery{
+		QueryID:     uuid.New().String(),
+		ProjectUID:  projectUID,
+		Name:        name,
+		Description: strings.TrimSpace(input.Description),
+		Query:       source,
+		CreatedAt:   now,
+		CreatedBy:   actor,
+		UpdatedAt:   now,
+	}
+
+	err = db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		existing, err := s.savedQueryRepo.GetByName(ctx, tx, projectUID, name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %s", ErrDuplicateSavedQuery, name)
+		}
+		return s.savedQueryRepo.Add(ctx, tx, saved)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[%s] Saved query %q (%s) in project %s", actor, name, saved.QueryID, projectUID)
+	return saved, nil
+}
+
+// UpdateSavedQuery replaces name, description and query of a saved query.
+func (s *QueryService) UpdateSavedQuery(ctx context.Context, projectUID, queryID string, input SavedQueryInput, actor string) (*redpaths.SavedQuery, error) {
+	name, source, err := validateSavedQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved *redpaths.SavedQuery
+	err = db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		existing, err := s.savedQueryRepo.Get(ctx, tx, projectUID, queryID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: %s", ErrSavedQueryNotFound, queryID)
+		}
+
+		sameName, err := s.savedQueryRepo.GetByName(ctx, tx, projectUID, name)
+		if err != nil {
+			return err
+		}
+		if sameName != nil && sameName.QueryID != queryID {
+			return fmt.Errorf("%w: %s", ErrDuplicateSavedQuery, name)
+		}
+
+		existing.Name = name
+		existing.Description = strings.TrimSpace(input.Description)
+		existing.Query = source
+		existing.UpdatedAt = time.Now().UTC()
+		saved = existing
+		return s.savedQueryRepo.Update(ctx, tx, existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[%s] Updated saved query %q (%s) in project %s", actor, name, queryID, projectUID)
+	return saved, nil
+}
+
+func (s *QueryService) DeleteSavedQuery(ctx context.Context, projectUID, queryID string) error {
+	var deleted bool
+	err := db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		var err error
+		deleted, err = s.savedQueryRepo.Delete(ctx, tx, projectUID, queryID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: %s", ErrSavedQueryNotFound, queryID)
+	}
+	return nil
+}
+
+// RunSavedQuery runs a saved query against the current state of the project.
+func (s *QueryService) RunSavedQuery(ctx context.Context, projectUID, queryID string) (*QueryResult, error) {
+	saved, err := s.GetSavedQuery(ctx, projectUID, queryID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Run(ctx, projectUID, saved.Query)
+}
+
+func validateSavedQuery(input SavedQueryInput) (string, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return "", "", fmt.Errorf("%w: name is required", ErrInvalidSavedQuery)
+	}
+	if len(name) > maxSavedQueryName {
+		return "", "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidSavedQuery, maxSavedQueryName)
+	}
+
+	q, err := Parse(input.Query)
+	if err != nil {
+		return "", "", err
+	}
+	return name, q.Source, nil
+}
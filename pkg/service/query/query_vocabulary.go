@@ -0,0 +1,708 @@
+package query
+
+import (
+	"RedPaths-server/internal/repository/util/dgraph"
+	"RedPaths-server/pkg/model"
+	"RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/active_directory/gpo"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/core/res"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// maxContainerDepth is how deep entities are looked up below their domain,
+// counted in "contains" hops through OUs and containers.
+const maxContainerDepth = 8
+
+// ── Fields ──────────────────────────────────────────────────────────────────
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindStringList
+	kindBool
+	kindInt
+	kindTime
+)
+
+var operators = []string{"=", "!=", "<", "<=", ">", ">=", "contains"}
+
+// queryField is a scalar predicate that can be filtered on. Only indexed
+// predicates are listed, Exact and Term tell which string index exists.
+type queryField struct {
+	Name      string
+	Predicate string
+	Kind      fieldKind
+	Exact     bool
+	Term      bool
+}
+
+func (f *queryField) kindName() string {
+	switch f.Kind {
+	case kindStringList:
+		return "string list"
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindTime:
+		return "datetime"
+	}
+	return "string"
+}
+
+func str(name, predicate string, exact, term bool) *queryField {
+	return &queryField{Name: name, Predicate: predicate, Kind: kindString, Exact: exact, Term: term}
+}
+
+func flag(name, predicate string) *queryField {
+	return &queryField{Name: name, Predicate: predicate, Kind: kindBool}
+}
+
+func number(name, predicate string) *queryField {
+	return &queryField{Name: name, Predicate: predicate, Kind: kindInt}
+}
+
+func datetime(name, predicate string) *queryField {
+	return &queryField{Name: name, Predicate: predicate, Kind: kindTime}
+}
+
+// supports reports whether the operator can be used on the field with the
+// index the predicate has.
+func (f *queryField) supports(op string) error {
+	switch op {
+	case "=", "!=":
+		if f.Kind == kindString && !f.Exact && !f.Term {
+			return fmt.Errorf("%s cannot be compared", f.Name)
+		}
+		if f.Kind == kindStringList {
+			return fmt.Errorf("%s is a list, use contains", f.Name)
+		}
+	case "<", "<=", ">", ">=":
+		if f.Kind == kindBool || f.Kind == kindStringList || (f.Kind == kindString && !f.Exact) {
+			return fmt.Errorf("%s cannot be ordered", f.Name)
+		}
+	case "contains":
+		if (f.Kind != kindString && f.Kind != kindStringList) || !f.Term {
+			return fmt.Errorf("contains is not supported on %s", f.Name)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", op)
+	}
+	return nil
+}
+
+// validate checks the operator and the value against the field.
+func (c *compareCondition) validate() error {
+	f := c.field
+	if err := f.supports(c.op); err != nil {
+		return err
+	}
+
+	switch f.Kind {
+	case kindBool:
+		if _, err := strconv.ParseBool(c.value); err != nil {
+			return fmt.Errorf("%s expects true or false", f.Name)
+		}
+	case kindInt:
+		if _, err := strconv.ParseInt(c.value, 10, 64); err != nil {
+			return fmt.Errorf("%s expects a number", f.Name)
+		}
+	case kindTime:
+		if _, err := parseTime(c.value); err != nil {
+			return fmt.Errorf("%s expects a date (2006-01-02) or RFC 3339 timestamp", f.Name)
+		}
+	default:
+		for _, r := range c.value {
+			if r < 0x20 || r == 0x7f {
+				return fmt.Errorf("value of %s contains control characters", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// dqlLiteral renders a validated value as DQL literal. Strings are quoted;
+// control characters were rejected by validate.
+func (c *compareCondition) dqlLiteral() string {
+	switch c.field.Kind {
+	case kindBool:
+		b, _ := strconv.ParseBool(c.value)
+		return strconv.FormatBool(b)
+	case kindInt:
+		return c.value
+	case kindTime:
+		t, _ := parseTime(c.value)
+		return `"` + t.UTC().Format(time.RFC3339) + `"`
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(c.value)
+	return `"` + escaped + `"`
+}
+
+// dqlFilter compiles a scalar condition into a DQL filter expression. Errors
+// wrap ErrInvalidQuery.
+func dqlFilter(c condition) (string, error) {
+	switch c := c.(type) {
+	case *andCondition:
+		left, right, err := dqlFilterPair(c.left, c.right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s AND %s)", left, right), nil
+	case *orCondition:
+		left, right, err := dqlFilterPair(c.left, c.right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s OR %s)", left, right), nil
+	case *notCondition:
+		inner, err := dqlFilter(c.inner)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", inner), nil
+	case *compareCondition:
+		predicate, literal := c.field.Predicate, c.dqlLiteral()
+		switch c.op {
+		case "=":
+			return fmt.Sprintf("eq(%s, %s)", predicate, literal), nil
+		case "!=":
+			return fmt.Sprintf("NOT eq(%s, %s)", predicate, literal), nil
+		case "<":
+			return fmt.Sprintf("lt(%s, %s)", predicate, literal), nil
+		case "<=":
+			return fmt.Sprintf("le(%s, %s)", predicate, literal), nil
+		case ">":
+			return fmt.Sprintf("gt(%s, %s)", predicate, literal), nil
+		case ">=":
+			return fmt.Sprintf("ge(%s, %s)", predicate, literal), nil
+		case "contains":
+			return fmt.Sprintf("allofterms(%s, %s)", predicate, literal), nil
+		}
+		return "", fmt.Errorf("%w: operator %q is not supported for %s", ErrInvalidQuery, c.op, c.field.Name)
+	}
+	return "", fmt.Errorf("%w: condition %T cannot be compiled into a filter", ErrInvalidQuery, c)
+}
+
+func dqlFilterPair(left, right condition) (string, string, error) {
+	l, err := dqlFilter(left)
+	if err != nil {
+		return "", "", err
+	}
+	r, err := dqlFilter(right)
+	if err != nil {
+		return "", "", err
+	}
+	return l, r, nil
+}
+
+// ── Relations ───────────────────────────────────────────────────────────────
+
+// relation is an assertion path between the queried entity and a target.
+// Hops lead from the subject to the object; Inverse means the queried entity
+// is the object, i.e. target -Hops-> entity. Transitive relations may be
+// followed through further entities of the Via type.
+type relation struct {
+	Name        string
+	Description string
+	Hops        []dgraph.HopConfig
+	Inverse     bool
+	Targets     []string
+	Transitive  bool
+	Via         string
+}
+
+var (
+	memberOf = &relation{
+		Name:        "member_of",
+		Description: "member of the target group",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateHasMember}},
+		Inverse:     true,
+		Targets:     []string{"Group"},
+		Transitive:  true,
+		Via:         "Group",
+	}
+	hasMember = &relation{
+		Name:        "has_member",
+		Description: "group with the target as member",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateHasMember}},
+		Targets:     []string{"User", "Group", "Host"},
+		Transitive:  true,
+		Via:         "Group",
+	}
+	adminTo = &relation{
+		Name:        "admin_to",
+		Description: "local administrator on the target host",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateAdminTo}},
+		Targets:     []string{"Host"},
+	}
+	administeredBy = &relation{
+		Name:        "administered_by",
+		Description: "host the target principal is local administrator on",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateAdminTo}},
+		Inverse:     true,
+		Targets:     []string{"User", "Group"},
+	}
+	hasSession = &relation{
+		Name:        "has_session",
+		Description: "host with a session of the target user",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateHasSession}},
+		Targets:     []string{"User"},
+	}
+	sessionOn = &relation{
+		Name:        "session_on",
+		Description: "user with a session on the target host",
+		Hops:        []dgraph.HopConfig{{Predicate: core.PredicateHasSession}},
+		Inverse:     true,
+		Targets:     []string{"Host"},
+	}
+	trusts = &relation{
+		Name:        "trusts",
+		Description: "domain trusting the target domain",
+		Hops: []dgraph.HopConfig{
+			{Predicate: core.PredicateHasTrust},
+			{Predicate: core.PredicateTrusts},
+		},
+		Targets:    []string{"Domain"},
+		Transitive: true,
+		Via:        "Domain",
+	}
+	trustedBy = &relation{
+		Name:        "trusted_by",
+		Description: "domain trusted by the target domain",
+		Hops: []dgraph.HopConfig{
+			{Predicate: core.PredicateHasTrust},
+			{Predicate: core.PredicateTrusts},
+		},
+		Inverse:    true,
+		Targets:    []string{"Domain"},
+		Transitive: true,
+		Via:        "Domain",
+	}
+)
+
+// ── Entity types ────────────────────────────────────────────────────────────
+
+// entityType is a queryable type: its fields and relations and the hop
+// chains under which the project holds its entities.
+type entityType struct {
+	Type      string
+	Names     []string
+	Fields    []*queryField
+	Relations []*relation
+	// ResultFields are loaded for the entities returned.
+	ResultFields []string
+	scopes       [][]dgraph.HopConfig
+	fetch        func(ctx context.Context, tx *dgo.Txn, projectUID string, hops []dgraph.HopConfig, fields []string) ([]*res.EntityResult[any], error)
+	ident        func(entity any) (uid, name string)
+}
+
+func (e *entityType) field(name string) *queryField {
+	name = strings.ToLower(name)
+	for _, f := range e.Fields {
+		if f.Name == name || "is_"+name == f.Name || f.Predicate == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (e *entityType) relation(name string) *relation {
+	name = strings.ToLower(name)
+	for _, r := range e.Relations {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+var principalFields = []*queryField{
+	str("name", "security_principal.name", true, true),
+	str("sid", "security_principal.sid", true, false),
+}
+
+var entityTypes = []*entityType{
+	{
+		Type:  "User",
+		Names: []string{"users", "user"},
+		Fields: append(append([]*queryField{}, principalFields...),
+			str("sam_account_name", "user.sam_account_name", true, false),
+			str("upn", "user.upn", true, false),
+			flag("is_disabled", "user.is_disabled"),
+			flag("is_locked", "user.is_locked"),
+			flag("is_service_account", "user.is_service_account"),
+			datetime("last_logon", "user.last_logon"),
+			datetime("pwd_last_set", "user.pwd_last_set"),
+			flag("allowed_to_delegate", "user.allowed_to_delegate"),
+			flag("has_spn", "user.has_spn"),
+			flag("kerberoastable", "user.kerberoastable"),
+			flag("asrep_roastable", "user.asrep_roastable"),
+			flag("is_local_admin", "user.is_local_admin"),
+			flag("is_domain_admin", "user.is_domain_admin"),
+			flag("is_privileged", "user.is_privileged"),
+			flag("can_dcsync", "user.can_dcsync"),
+			flag("can_rdp", "user.can_rdp"),
+			number("risk_score", "user.risk_score"),
+		),
+		Relations: []*relation{memberOf, adminTo, sessionOn},
+		ResultFields: []string{
+			"uid",
+			"security_principal.name",
+			"security_principal.sid",
+			"security_principal.description",
+			"user.sam_account_name",
+			"user.upn",
+			"user.is_disabled",
+			"user.is_locked",
+			"user.is_service_account",
+			"user.last_logon",
+			"user.pwd_last_set",
+			"user.allowed_to_delegate",
+			"user.has_spn",
+			"user.kerberoastable",
+			"user.asrep_roastable",
+			"user.is_local_admin",
+			"user.is_domain_admin",
+			"user.is_privileged",
+			"user.can_dcsync",
+			"user.can_rdp",
+			"user.risk_score",
+			"user.risk_reasons",
+			"dgraph.type",
+		},
+		scopes: containedScopes("User"),
+		fetch:  fetchAs[*active_directory.User],
+		ident: func(e any) (string, string) {
+			x := e.(*active_directory.User)
+			return x.UID, x.Name
+		},
+	},
+	{
+		Type:  "Group",
+		Names: []string{"groups", "group"},
+		Fields: append(append([]*queryField{}, principalFields...),
+			str("group_scope", "group.group_scope", true, true),
+			str("group_type", "group.group_type", true, true),
+			flag("is_privileged", "group.is_privileged"),
+			flag("is_builtin", "group.is_builtin"),
+			&queryField{Name: "privileges", Predicate: "group.privileges", Kind: kindStringList, Term: true},
+			flag("can_dcsync", "group.can_dcsync"),
+			flag("can_rdp", "group.can_rdp"),
+			flag("can_logon_locally", "group.can_logon_locally"),
+			number("risk_score", "group.risk_score"),
+		),
+		Relations: []*relation{memberOf, hasMember, adminTo},
+		ResultFields: []string{
+			"uid",
+			"security_principal.name",
+			"security_principal.sid",
+			"security_principal.description",
+			"group.group_scope",
+			"group.group_type",
+			"group.is_privileged",
+			"group.is_builtin",
+			"group.privileges",
+			"group.can_dcsync",
+			"group.can_rdp",
+			"group.can_logon_locally",
+			"group.risk_score",
+			"group.risk_reasons",
+			"dgraph.type",
+		},
+		scopes: containedScopes("Group"),
+		fetch:  fetchAs[*active_directory.Group],
+		ident: func(e any) (string, string) {
+			x := e.(*active_directory.Group)
+			return x.UID, x.Name
+		},
+	},
+	{
+		Type:  "Host",
+		Names: []string{"hosts", "host", "computers", "computer"},
+		Fields: []*queryField{
+			str("name", "host.name", false, true),
+			str("hostname", "host.hostname", true, true),
+			str("ip", "host.ip", true, false),
+			flag("is_domain_controller", "host.is_domain_controller"),
+			str("distinguished_name", "host.distinguished_name", true, false),
+			str("dns_host_name", "host.dns_host_name", true, false),
+			str("operating_system", "host.operating_system", false, true),
+			str("operating_system_version", "host.operating_system_version", false, true),
+			number("risk_score", "host.risk_score"),
+		},
+		Relations: []*relation{memberOf, administeredBy, hasSession},
+		ResultFields: []string{
+			"uid",
+			"host.name",
+			"host.ip",
+			"host.hostname",
+			"host.dns_host_name",
+			"host.is_domain_controller",
+			"host.distinguished_name",
+			"host.operating_system",
+			"host.operating_system_version",
+			"host.risk_score",
+			"host.risk_reasons",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		scopes: [][]dgraph.HopConfig{{
+			{Predicate: core.PredicateHasActiveDirectory},
+			{Predicate: core.PredicateHasDomain},
+			{Predicate: core.PredicateHasHost, ObjectType: "Host"},
+		}},
+		fetch: fetchAs[*model.Host],
+		ident: func(e any) (string, string) {
+			x := e.(*model.Host)
+			return x.UID, x.Name
+		},
+	},
+	{
+		Type:  "Domain",
+		Names: []string{"domains", "domain"},
+		Fields: []*queryField{
+			str("name", "domain.name", true, true),
+			str("dns_name", "domain.dns_name", true, false),
+			str("netbios_name", "domain.netbios_name", true, false),
+			str("domain_sid", "domain.domain_sid", true, false),
+			str("domain_functional_level", "domain.domain_functional_level", false, true),
+			str("forest_functional_level", "domain.forest_functional_level", false, true),
+			number("risk_score", "domain.risk_score"),
+		},
+		Relations: []*relation{trusts, trustedBy},
+		ResultFields: []string{
+			"uid",
+			"domain.name",
+			"domain.dns_name",
+			"domain.netbios_name",
+			"domain.domain_sid",
+			"domain.domain_functional_level",
+			"domain.forest_functional_level",
+			"domain.risk_score",
+			"domain.risk_reasons",
+			"created_at",
+			"modified_at",
+			"dgraph.type",
+		},
+		scopes: [][]dgraph.HopConfig{{
+			{Predicate: core.PredicateHasActiveDirectory},
+			{Predicate: core.PredicateHasDomain, ObjectType: "Domain"},
+		}},
+		fetch: fetchAs[*active_directory.Domain],
+		ident: func(e any) (string, string) {
+			x := e.(*active_directory.Domain)
+			return x.UID, x.Name
+		},
+	},
+	{
+		Type:  "DirectoryNode",
+		Names: []string{"ous", "ou", "containers", "container", "directory_nodes"},
+		Fields: []*queryField{
+			str("name", "directory_node.name", true, true),
+			str("distinguished_name", "directory_node.distinguished_name", true, false),
+			str("node_type", "directory_node.node_type", true, true),
+			flag("is_builtin", "directory_node.is_builtin"),
+			flag("blocks_inheritance", "directory_node.blocks_inheritance"),
+		},
+		ResultFields: []string{
+			"uid",
+			"directory_node.name",
+			"directory_node.description",
+			"directory_node.distinguished_name",
+			"directory_node.node_type",
+			"directory_node.is_builtin",
+			"directory_node.blocks_inheritance",
+			"dgraph.type",
+		},
+		scopes: containedScopes("DirectoryNode"),
+		fetch:  fetchAs[*active_directory.DirectoryNode],
+		ident: func(e any) (string, string) {
+			x := e.(*active_directory.DirectoryNode)
+			return x.UID, x.Name
+		},
+	},
+	{
+		Type:  "GPO",
+		Names: []string{"gpos", "gpo"},
+		Fields: []*queryField{
+			str("name", "gpo.name", true, true),
+		},
+		ResultFields: []string{
+			"uid",
+			"gpo.name",
+			"gpo.description",
+			"dgraph.type",
+		},
+		scopes: gpoScopes(),
+		fetch:  fetchAs[*gpo.GPO],
+		ident: func(e any) (string, string) {
+			x := e.(*gpo.GPO)
+			return x.UID, x.Name
+		},
+	},
+}
+
+func lookupEntityType(name string) *entityType {
+	name = strings.ToLower(name)
+	for _, e := range entityTypes {
+		if strings.ToLower(e.Type) == name {
+			return e
+		}
+		for _, n := range e.Names {
+			if n == name {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func entityTypeByType(dgraphType string) *entityType {
+	for _, e := range entityTypes {
+		if e.Type == dgraphType {
+			return e
+		}
+	}
+	return nil
+}
+
+func entityTypeNames() []string {
+	names := make([]string, 0, len(entityTypes))
+	for _, e := range entityTypes {
+		names = append(names, e.Names[0])
+	}
+	return names
+}
+
+// containedScopes are the hop chains to entities placed in a domain or below
+// it in OUs and containers, one chain per depth.
+func containedScopes(objectType string) [][]dgraph.HopConfig {
+	scopes := make([][]dgraph.HopConfig, 0, maxContainerDepth)
+	for depth := 1; depth <= maxContainerDepth; depth++ {
+		hops := []dgraph.HopConfig{
+			{Predicate: core.PredicateHasActiveDirectory},
+			{Predicate: core.PredicateHasDomain},
+		}
+		for i := 0; i < depth; i++ {
+			hops = append(hops, dgraph.HopConfig{Predicate: core.PredicateContains})
+		}
+		hops[len(hops)-1].ObjectType = objectType
+		scopes = append(scopes, hops)
+	}
+	return scopes
+}
+
+// gpoScopes are the hop chains to GPOs linked to a domain or an OU.
+func gpoScopes() [][]dgraph.HopConfig {
+	linked := func(prefix []dgraph.HopConfig) []dgraph.HopConfig {
+		return append(prefix,
+			dgraph.HopConfig{Predicate: core.PredicateHasGPOLink},
+			dgraph.HopConfig{Predicate: core.PredicateLinksTo, ObjectType: "GPO"},
+		)
+	}
+
+	scopes := [][]dgraph.HopConfig{linked([]dgraph.HopConfig{
+		{Predicate: core.PredicateHasActiveDirectory},
+		{Predicate: core.PredicateHasDomain},
+	})}
+	for _, container := range containedScopes("") {
+		scopes = append(scopes, linked(container))
+	}
+	return scopes
+}
+
+func fetchAs[T any](ctx context.Context, tx *dgo.Txn, projectUID string, hops []dgraph.HopConfig, fields []string) ([]*res.EntityResult[any], error) {
+	results, err := dgraph.GetEntitiesWithAssertionsNHop[T](ctx, tx, projectUID, hops, fields, "runGraphQuery")
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]*res.EntityResult[any], 0, len(results))
+	for _, r := range results {
+		converted = append(converted, &res.EntityResult[any]{
+			Entity:     r.Entity,
+			Assertions: r.Assertions,
+			Metadata:   r.Metadata,
+		})
+	}
+	return converted, nil
+}
+
+// ── Vocabulary ──────────────────────────────────────────────────────────────
+
+// Vocabulary describes what queries can select and filter on.
+type Vocabulary struct {
+	Types []VocabularyType `json:"types"`
+}
+
+type VocabularyType struct {
+	Type      string               `json:"type"`
+	Names     []string             `json:"names"`
+	Fields    []VocabularyField    `json:"fields"`
+	Relations []VocabularyRelation `json:"relations,omitempty"`
+}
+
+type VocabularyField struct {
+	Name      string   `json:"name"`
+	Predicate string   `json:"predicate"`
+	Kind      string   `json:"kind"`
+	Operators []string `json:"operators"`
+}
+
+type VocabularyRelation struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Targets     []string `json:"targets"`
+	Transitive  bool     `json:"transitive"`
+}
+
+// GetVocabulary lists the entity types with their fields and relations.
+func GetVocabulary() *Vocabulary {
+	vocabulary := &Vocabulary{}
+	for _, e := range entityTypes {
+		t := VocabularyType{Type: e.Type, Names: e.Names}
+		for _, f := range e.Fields {
+			var fieldOperators []string
+			for _, op := range operators {
+				if f.supports(op) == nil {
+					fieldOperators = append(fieldOperators, op)
+				}
+			}
+			t.Fields = append(t.Fields, VocabularyField{
+				Name:      f.Name,
+				Predicate: f.Predicate,
+				Kind:      f.kindName(),
+				Operators: fieldOperators,
+			})
+		}
+		for _, r := range e.Relations {
+			targets := append([]string(nil), r.Targets...)
+			sort.Strings(targets)
+			t.Relations = append(t.Relations, VocabularyRelation{
+				Name:        r.Name,
+				Description: r.Description,
+				Targets:     targets,
+				Transitive:  r.Transitive,
+			})
+		}
+		vocabulary.Types = append(vocabulary.Types, t)
+	}
+	return vocabulary
+}
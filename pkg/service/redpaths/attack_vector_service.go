@@ -24,6 +24,20 @@ func RunAttackVector(ctx context.Context, postgresCon *gorm.DB, targetModuleKey
 	vectorRunID := uuid.New().String()
 	log.Println("Starting Execution with vectorRunID: " + vectorRunID)
 
+	// Reject the whole vector if a tool of any of its modules is missing,
+	// instead of failing halfway through the run.
+	vectorModules, err := moduleService.GetAttackVectorByKey(ctx, targetModuleKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get attack vector: %w", err)
+	}
+	moduleKeys := make([]string, 0, len(vectorModules))
+	for _, module := range vectorModules {
+		moduleKeys = append(moduleKeys, module.Key)
+	}
+	if err := executor.CheckPrerequisites(moduleKeys); err != nil {
+		return "", err
+	}
+
 	log.Println("Starting Execution by building subgraph for vector run entry")
 	depth := 10
 	subGraph, err := moduleService.GetInheritanceSubgraph(ctx, targetModuleKey, rp.GraphDownstream, &depth)
@@ -0,0 +1,186 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// pdfTimeout limits a single run of the external PDF renderer.
+const pdfTimeout = 2 * time.Minute
+
+var (
+	ErrUnknownFormat = errors.New("unknown report format")
+	ErrNoPDFRenderer = errors.New("no PDF renderer installed (wkhtmltopdf, weasyprint or chromium)")
+	// the PDF renderers resolve file:// URLs, a custom template could embed
+	// files of the server
+	ErrCustomPDFTemplate = errors.New("custom templates are not supported for PDF reports")
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+type formatInfo struct {
+	extension   string
+	contentType string
+	template    string // default template
+}
+
+var formats = map[string]formatInfo{
+	FormatMarkdown: {".md", "text/markdown; charset=utf-8", "templates/report.md.tmpl"},
+	FormatHTML:     {".html", "text/html; charset=utf-8", "templates/report.html.tmpl"},
+	FormatPDF:      {".pdf", "application/pdf", "templates/report.html.tmpl"},
+}
+
+// NormalizeFormat maps the accepted spellings onto the format constants.
+func NormalizeFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "md", FormatMarkdown:
+		return FormatMarkdown, nil
+	case "htm", FormatHTML:
+		return FormatHTML, nil
+	case FormatPDF:
+		return FormatPDF, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// DefaultTemplate returns the built-in template of a format. PDF reports use
+// the HTML template.
+func DefaultTemplate(format string) (string, error) {
+	format, err := NormalizeFormat(format)
+	if err != nil {
+		return "", err
+	}
+	content, err := templateFS.ReadFile(formats[format].template)
+	if err != nil {
+		return "", fmt.Errorf("reading default template: %w", err)
+	}
+	return string(content), nil
+}
+
+// render executes the custom template, or the default one if tmpl is empty.
+// Markdown uses text/template, HTML and PDF use html/template so project data
+// is escaped. PDF reports always use the default template.
+func render(ctx context.Context, format, tmpl string, data *ReportData) ([]byte, error) {
+	if format == FormatPDF && tmpl != "" {
+		return nil, ErrCustomPDFTemplate
+	}
+	if tmpl == "" {
+		var err error
+		if tmpl, err = DefaultTemplate(format); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatMarkdown:
+		t, err := texttemplate.New("report").Funcs(templateFuncs()).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("executing template: %w", err)
+		}
+		return buf.Bytes(), nil
+
+	case FormatHTML, FormatPDF:
+		t, err := htmltemplate.New("report").Funcs(templateFuncs()).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("executing template: %w", err)
+		}
+		if format == FormatHTML {
+			return buf.Bytes(), nil
+		}
+		return htmlToPDF(ctx, buf.Bytes())
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// pdfRenderers are tried in order, the first one found in PATH is used.
+var pdfRenderers = []struct {
+	binary string
+	args   func(in, out string) []string
+}{
+	{"wkhtmltopdf", func(in, out string) []string {
+		return []string{"--quiet", "--disable-local-file-access", in, out}
+	}},
+	{"weasyprint", func(in, out string) []string {
+		return []string{in, out}
+	}},
+	{"chromium", chromeArgs},
+	{"chromium-browser", chromeArgs},
+	{"google-chrome", chromeArgs},
+}
+
+func chromeArgs(in, out string) []string {
+	return []string{"--headless", "--disable-gpu", "--no-pdf-header-footer", "--print-to-pdf=" + out, "file://" + in}
+}
+
+// PDFAvailable reports whether a local PDF renderer is installed.
+func PDFAvailable() bool {
+	_, _, err := findPDFRenderer()
+	return err == nil
+}
+
+func findPDFRenderer() (string, func(in, out string) []string, error) {
+	for _, r := range pdfRenderers {
+		if path, err := exec.LookPath(r.binary); err == nil {
+			return path, r.args, nil
+		}
+	}
+	return "", nil, ErrNoPDFRenderer
+}
+
+func htmlToPDF(ctx context.Context, html []byte) ([]byte, error) {
+	binary, args, err := findPDFRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "redpaths-report-")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "report.html")
+	out := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(in, html, 0o600); err != nil {
+		return nil, fmt.Errorf("writing html: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pdfTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args(in, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(binary), err, strings.TrimSpace(string(output)))
+	}
+
+	pdf, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("reading pdf: %w", err)
+	}
+	return pdf, nil
+}
@@ -0,0 +1,32 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRenderRejectsCustomPDFTemplate(t *testing.T) {
+	_, err := render(context.Background(), FormatPDF, `<iframe src="file:///etc/passwd"></iframe>`, &ReportData{})
+	if !errors.Is(err, ErrCustomPDFTemplate) {
+		t.Fatalf("render() error = %v, want %v", err, ErrCustomPDFTemplate)
+	}
+
+	content, err := render(context.Background(), FormatHTML, `<h1>{{.Title}}</h1>`, &ReportData{Title: "<b>"})
+	if err != nil {
+		t.Fatalf("render() custom HTML template error = %v", err)
+	}
+	if got, want := string(content), "<h1>&lt;b&gt;</h1>"; got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+}
+
+func TestPDFRendererArgs(t *testing.T) {
+	for _, r := range pdfRenderers {
+		args := r.args("/tmp/in.html", "/tmp/out.pdf")
+		if slices.Contains(args, "--no-sandbox") || slices.Contains(args, "--enable-local-file-access") {
+			t.Errorf("%s args %v weaken the renderer", r.binary, args)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package report
+
+import (
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/core/res"
+	"RedPaths-server/pkg/model/engine"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimelineChanges limits the change timeline to the newest entries.
+const maxTimelineChanges = 500
+
+// ReportData is everything a report template can render. Templates get a
+// *ReportData as dot.
+type ReportData struct {
+	Title       string
+	GeneratedAt time.Time
+	GeneratedBy string
+
+	Project           *model.Project
+	ActiveDirectories []*ActiveDirectorySection
+	// Domains without known forest
+	UnassignedDomains []*DomainSection
+	Hosts             []*HostSection
+	Users             []*rpad.User // risk score descending
+	RiskyUsers        []*rpad.User // users with risk score or risk flags
+	// Capabilities not bound to a host (catalog)
+	Capabilities []*engine.Capability
+
+	AttackPaths []*redpaths.VectorRun
+	ModuleRuns  []*redpaths.ModuleRun
+	ImportRuns  []*redpaths.ImportRun
+	Timeline    []*history.Change // newest first, at most maxTimelineChanges
+
+	Stats ReportStats
+}
+
+type ActiveDirectorySection struct {
+	ActiveDirectory *rpad.ActiveDirectory
+	Domains         []*DomainSection
+}
+
+type DomainSection struct {
+	Domain         *rpad.Domain
+	SecurityPolicy *rpad.SecurityPolicy // nil if unknown
+	Findings       []*model.Vulnerability
+}
+
+type HostSection struct {
+	Host            *model.Host
+	Services        []*model.Service
+	Vulnerabilities []*model.Vulnerability
+	Capabilities    []*engine.Capability
+}
+
+type ReportStats struct {
+	ActiveDirectories int
+	Domains           int
+	Hosts             int
+	DomainControllers int
+	Services          int
+	Users             int
+	RiskyUsers        int
+	Capabilities      int
+	Vulnerabilities   int
+	BySeverity        map[string]int
+	AttackPaths       int
+	ModuleRuns        int
+	ImportRuns        int
+}
+
+// collect loads the project. Missing optional parts (policies, per host
+// details, histories) are logged and left empty instead of failing the report.
+func (s *ReportService) collect(ctx context.Context, projectUID string) (*ReportData, error) {
+	project, err := s.projectService.Get(ctx, projectUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading project: %w", err)
+	}
+
+	data := &ReportData{
+		Project: project,
+		Stats:   ReportStats{BySeverity: make(map[string]int)},
+	}
+	var uids []string
+
+	ads, err := s.projectService.GetAllActiveDirectories(ctx, projectUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading active directories: %w", err)
+	}
+	seenDomains := make(map[string]bool)
+	for _, ad := range entities(ads) {
+		section := &ActiveDirectorySection{ActiveDirectory: ad}
+		uids = append(uids, ad.UID)
+
+		domains, err := s.activeDirectoryService.GetAllDomains(ctx, ad.UID)
+		if err != nil {
+			log.Printf("[Report] Loading domains of %s failed: %v", ad.UID, err)
+		}
+		for _, d := range entities(domains) {
+			seenDomains[d.UID] = true
+			section.Domains = append(section.Domains, s.domainSection(ctx, data, d))
+			uids = append(uids, d.UID)
+		}
+		data.ActiveDirectories = append(data.ActiveDirectories, section)
+	}
+
+	domains, err := s.projectService.GetAllDomains(ctx, projectUID)
+	if err != nil {
+		log.Printf("[Report] Loading project domains failed: %v", err)
+	}
+	for _, d := range entities(domains) {
+		if !seenDomains[d.UID] {
+			seenDomains[d.UID] = true
+			data.UnassignedDomains = append(data.UnassignedDomains, s.domainSection(ctx, data, d))
+			uids = append(uids, d.UID)
+		}
+	}
+
+	hosts, err := s.projectService.GetHostsByProject(ctx, projectUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading hosts: %w", err)
+	}
+	for _, h := range entities(hosts) {
+		section := s.hostSection(ctx, data, h)
+		data.Hosts = append(data.Hosts, section)
+		uids = append(uids, h.UID)
+		for _, svc := range section.Services {
+			uids = append(uids, svc.UID)
+		}
+	}
+	sort.SliceStable(data.Hosts, func(i, j int) bool {
+		return hostLabel(data.Hosts[i].Host) < hostLabel(data.Hosts[j].Host)
+	})
+
+	users, err := s.projectService.GetAllUserInProject(ctx, projectUID)
+	if err != nil {
+		return nil, fmt.Errorf("loading users: %w", err)
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].RiskScore != users[j].RiskScore {
+			return users[i].RiskScore > users[j].RiskScore
+		}
+		return strings.ToLower(userLabel(users[i])) < strings.ToLower(userLabel(users[j]))
+	})
+	data.Users = users
+	for _, u := range users {
+		uids = append(uids, u.UID)
+		if u.RiskScore > 0 || len(userFlags(u)) > 0 {
+			data.RiskyUsers = append(data.RiskyUsers, u)
+		}
+	}
+
+	capabilities, err := s.capabilityService.GetCapabilitiesFromCatalog(ctx, projectUID)
+	if err != nil {
+		log.Printf("[Report] Loading capabilities failed: %v", err)
+	}
+	data.Capabilities = entities(capabilities)
+	data.Stats.Capabilities += len(data.Capabilities)
+
+	if data.AttackPaths, err = s.moduleService.GetAllVectorRuns(ctx, projectUID); err != nil {
+		log.Printf("[Report] Loading attack vector runs failed: %v", err)
+	}
+	if data.ModuleRuns, err = s.moduleService.GetAllRunMetadata(ctx, projectUID); err != nil {
+		log.Printf("[Report] Loading module runs failed: %v", err)
+	}
+	if data.ImportRuns, err = s.importRunService.GetAll(ctx, projectUID); err != nil {
+		log.Printf("[Report] Loading import runs failed: %v", err)
+	}
+	if data.Timeline, err = s.changeService.GetChangesByEntities(ctx, uids, maxTimelineChanges); err != nil {
+		log.Printf("[Report] Loading change timeline failed: %v", err)
+	}
+
+	data.Stats.ActiveDirectories = len(data.ActiveDirectories)
+	data.Stats.Domains = len(seenDomains)
+	data.Stats.Hosts = len(data.Hosts)
+	data.Stats.Users = len(data.Users)
+	data.Stats.RiskyUsers = len(data.RiskyUsers)
+	data.Stats.AttackPaths = len(data.AttackPaths)
+	data.Stats.ModuleRuns = len(data.ModuleRuns)
+	data.Stats.ImportRuns = len(data.ImportRuns)
+
+	return data, nil
+}
+
+func (s *ReportService) domainSection(ctx context.Context, data *ReportData, d *rpad.Domain) *DomainSection {
+	section := &DomainSection{Domain: d}
+
+	policy, err := s.domainService.GetSecurityPolicy(ctx, d.UID)
+	if err != nil {
+		log.Printf("[Report] Loading security policy of %s failed: %v", d.UID, err)
+	}
+	if policy != nil {
+		section.SecurityPolicy = policy.Entity
+	}
+
+	findings, err := s.vulnService.GetVulnerabilitiesByDomain(ctx, d.UID)
+	if err != nil {
+		log.Printf("[Report] Loading findings of %s failed: %v", d.UID, err)
+	}
+	section.Findings = sortVulnerabilities(entities(findings))
+	for _, v := range section.Findings {
+		data.Stats.Vulnerabilities++
+		data.Stats.BySeverity[strings.ToLower(v.Severity)]++
+	}
+	return section
+}
+
+func (s *ReportService) hostSection(ctx context.Context, data *ReportData, h *model.Host) *HostSection {
+	section := &HostSection{Host: h}
+	if h.IsDomainController {
+		data.Stats.DomainControllers++
+	}
+
+	services, err := s.hostService.GetAllServicesByHost(ctx, h.UID)
+	if err != nil {
+		log.Printf("[Report] Loading services of %s failed: %v", h.UID, err)
+	}
+	section.Services = entities(services)
+	sort.SliceStable(section.Services, func(i, j int) bool {
+		pi, _ := strconv.Atoi(section.Services[i].Port)
+		pj, _ := strconv.Atoi(section.Services[j].Port)
+		return pi < pj
+	})
+	data.Stats.Services += len(section.Services)
+
+	vulns, err := s.hostService.GetVulnerabilities(ctx, h.UID)
+	if err != nil {
+		log.Printf("[Report] Loading vulnerabilities of %s failed: %v", h.UID, err)
+	}
+	section.Vulnerabilities = sortVulnerabilities(entities(vulns))
+	for _, v := range section.Vulnerabilities {
+		data.Stats.Vulnerabilities++
+		data.Stats.BySeverity[strings.ToLower(v.Severity)]++
+	}
+
+	capabilities, err := s.hostService.GetCapabilities(ctx, h.UID)
+	if err != nil {
+		log.Printf("[Report] Loading capabilities of %s failed: %v", h.UID, err)
+	}
+	section.Capabilities = entities(capabilities)
+	data.Stats.Capabilities += len(section.Capabilities)
+
+	return section
+}
+
+// entities unwraps service results, skipping empty entries.
+func entities[T any](results []*res.EntityResult[T]) []T {
+	out := make([]T, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			out = append(out, r.Entity)
+		}
+	}
+	return out
+}
+
+func sortVulnerabilities(vulns []*model.Vulnerability) []*model.Vulnerability {
+	sort.SliceStable(vulns, func(i, j int) bool {
+		ri, rj := model.SeverityRank(vulns[i].Severity), model.SeverityRank(vulns[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		return vulns[i].Name < vulns[j].Name
+	})
+	return vulns
+}
+
+// userFlags lists the offensive relevant properties of a user.
+func userFlags(u *rpad.User) []string {
+	var flags []string
+	add := func(set bool, flag string) {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	add(u.IsDomainAdmin, "domain admin")
+	add(u.Kerberoastable, "kerberoastable")
+	add(u.ASREPRoastable, "AS-REP roastable")
+	add(u.AllowedToDelegate, "delegation")
+	add(u.IsLocalAdmin, "local admin")
+	add(u.IsServiceAccount, "service account")
+	return flags
+}
+
+func userLabel(u *rpad.User) string {
+	switch {
+	case u.SAMAccountName != "":
+		return u.SAMAccountName
+	case u.UPN != "":
+		return u.UPN
+	}
+	return u.Name
+}
+
+func hostLabel(h *model.Host) string {
+	switch {
+	case h.DNSHostName != "":
+		return h.DNSHostName
+	case h.Hostname != "":
+		return h.Hostname
+	case h.Name != "":
+		return h.Name
+	}
+	return h.IP
+}
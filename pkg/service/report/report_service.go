@@ -0,0 +1,185 @@
+package report
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/reports"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/service/active_directory"
+	"RedPaths-server/pkg/service/change"
+	"RedPaths-server/pkg/service/engine"
+	"RedPaths-server/pkg/service/importer"
+	redpathsService "RedPaths-server/pkg/service/redpaths"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportSource is the default author of generated reports.
+const ReportSource = "ReportGenerator"
+
+// Options control a single report. Template replaces the built-in template of
+// the Markdown and HTML format; PDF reports are always rendered from the
+// built-in HTML template.
+type Options struct {
+	Format   string `json:"format"`
+	Title    string `json:"title,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+// ReportService renders engagement reports of a project and stores the
+// artifacts for later download.
+type ReportService struct {
+	postgresCon *gorm.DB
+	reportRepo  reports.RedPathsReportRepository
+
+	projectService         *active_directory.ProjectService
+	activeDirectoryService *active_directory.ActiveDirectoryService
+	domainService          *active_directory.DomainService
+	hostService            *active_directory.HostService
+	vulnService            *active_directory.VulnerabilityService
+	capabilityService      *engine.CapabilityService
+	changeService          *change.ChangeService
+	importRunService       *importer.ImportRunService
+	moduleService          *redpathsService.ModuleService
+}
+
+func NewReportService(dgraphCon *dgo.Dgraph, postgresCon *gorm.DB, moduleService *redpathsService.ModuleService) (*ReportService, error) {
+	projectService, err := active_directory.NewProjectService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	activeDirectoryService, err := active_directory.NewActiveDirectoryService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	domainService, err := active_directory.NewDomainService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	hostService, err := active_directory.NewHostService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	vulnService, err := active_directory.NewVulnerabilityService(dgraphCon)
+	if err != nil {
+		return nil, err
+	}
+	capabilityService, err := engine.NewCapabilityService(dgraphCon, postgresCon)
+	if err != nil {
+		return nil, err
+	}
+	changeService, err := change.NewChangeService(postgresCon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReportService{
+		postgresCon:            postgresCon,
+		reportRepo:             reports.NewPostgresRedPathsReportRepository(),
+		projectService:         projectService,
+		activeDirectoryService: activeDirectoryService,
+		domainService:          domainService,
+		hostService:            hostService,
+		vulnService:            vulnService,
+		capabilityService:      capabilityService,
+		changeService:          changeService,
+		importRunService:       importer.NewImportRunService(postgresCon),
+		moduleService:          moduleService,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Create
+// -----------------------------------------------------------------------------
+
+// Create renders the project with the given options and stores the artifact.
+// The returned report includes its content.
+func (s *ReportService) Create(ctx context.Context, projectUID string, opts Options, actor string) (*redpaths.Report, error) {
+	format, err := NormalizeFormat(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	if actor == "" {
+		actor = ReportSource
+	}
+
+	data, err := s.collect(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	data.GeneratedAt = time.Now().UTC()
+	data.GeneratedBy = actor
+	data.Title = strings.TrimSpace(opts.Title)
+	if data.Title == "" {
+		data.Title = fmt.Sprintf("Engagement Report %s", defaultString(projectUID, data.Project.Name))
+	}
+
+	content, err := render(ctx, format, opts.Template, data)
+	if err != nil {
+		return nil, err
+	}
+
+	template := "default"
+	if opts.Template != "" {
+		template = "custom"
+	}
+
+	report := &redpaths.Report{
+		ReportID:    uuid.New().String(),
+		ProjectUID:  projectUID,
+		Title:       data.Title,
+		Format:      format,
+		Template:    template,
+		FileName:    fileName(data.Title, data.GeneratedAt, formats[format].extension),
+		ContentType: formats[format].contentType,
+		Size:        int64(len(content)),
+		CreatedAt:   data.GeneratedAt,
+		CreatedBy:   actor,
+		Content:     content,
+	}
+
+	err = db.ExecutePostgresInTransaction(ctx, s.postgresCon, func(tx *gorm.DB) error {
+		return s.reportRepo.Add(ctx, tx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// -----------------------------------------------------------------------------
+// Get
+// -----------------------------------------------------------------------------
+
+// GetAll lists the reports of a project without their content.
+func (s *ReportService) GetAll(ctx context.Context, projectUID string) ([]*redpaths.Report, error) {
+	return db.ExecutePostgresRead(ctx, s.postgresCon, func(tx *gorm.DB) ([]*redpaths.Report, error) {
+		return s.reportRepo.GetAllReports(ctx, tx, projectUID)
+	})
+}
+
+// Get returns the report including its content, nil if it does not exist.
+func (s *ReportService) Get(ctx context.Context, projectUID, reportID string) (*redpaths.Report, error) {
+	return db.ExecutePostgresRead(ctx, s.postgresCon, func(tx *gorm.DB) (*redpaths.Report, error) {
+		return s.reportRepo.GetReport(ctx, tx, projectUID, reportID)
+	})
+}
+
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func fileName(title string, at time.Time, extension string) string {
+	name := strings.Trim(fileNameUnsafe.ReplaceAllString(title, "_"), "_.")
+	if name == "" {
+		name = "report"
+	}
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return fmt.Sprintf("%s_%s%s", name, at.Format("20060102-150405"), extension)
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"RedPaths-server/pkg/model"
+	rpad "RedPaths-server/pkg/model/active_directory"
+	"RedPaths-server/pkg/model/redpaths"
+	"RedPaths-server/pkg/model/redpaths/history"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// severities in report order
+var severities = []string{
+	model.SeverityCritical,
+	model.SeverityHigh,
+	model.SeverityMedium,
+	model.SeverityLow,
+	model.SeverityInfo,
+}
+
+// templateFuncs are available in custom templates as well, so keep the names
+// stable.
+func templateFuncs() map[string]any {
+	return map[string]any{
+		"date":          formatDate,
+		"join":          strings.Join,
+		"upper":         strings.ToUpper,
+		"lower":         strings.ToLower,
+		"default":       defaultString,
+		"cell":          markdownCell,
+		"severities":    func() []string { return severities },
+		"userFlags":     userFlags,
+		"userLabel":     userLabel,
+		"hostLabel":     hostLabel,
+		"domainName":    domainName,
+		"changeSummary": changeSummary,
+		"modulePath":    modulePath,
+		"add":           func(a, b int) int { return a + b },
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func defaultString(fallback, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// markdownCell makes a value safe for a Markdown table cell.
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\r", "")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+func domainName(d *rpad.Domain) string {
+	switch {
+	case d.DNSName != "":
+		return d.DNSName
+	case d.Name != "":
+		return d.Name
+	}
+	return d.NetBiosName
+}
+
+// changeSummary renders the field changes of a timeline entry in one line.
+func changeSummary(c *history.Change) string {
+	parts := make([]string, 0, len(c.Changes))
+	for _, fc := range c.Changes {
+		field := fc.Field
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		if c.ChangeType == history.ChangeTypeCreated || fc.OldValue == nil {
+			parts = append(parts, fmt.Sprintf("%s=%s", field, changeValue(fc.NewValue)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s → %s", field, changeValue(fc.OldValue), changeValue(fc.NewValue)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func changeValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "∅"
+	case string:
+		return val
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// modulePath lists the modules of an attack vector run in execution order.
+func modulePath(run *redpaths.VectorRun) string {
+	if run == nil || run.Graph == nil {
+		return ""
+	}
+	names := make([]string, 0, len(run.Graph.Nodes))
+	for _, m := range run.Graph.Nodes {
+		names = append(names, defaultString(m.Key, m.Name))
+	}
+	return strings.Join(names, " → ")
+}
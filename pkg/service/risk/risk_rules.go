@@ -0,0 +1,609 @@
+package risk
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/active_directory/priv"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/service/paths"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rulePrefix marks the reasons written by the built-in rules. Reasons with
+// other rule ids (e.g. imported health check findings) are kept on rescoring.
+const rulePrefix = "RP-"
+
+const (
+	// highRiskScore is the score from which an entity counts as high risk.
+	highRiskScore = 50
+	// riskyCapabilityLevel is the capability risk level from which holding
+	// the capability is a risk of its own.
+	riskyCapabilityLevel = 8
+	// stalePasswordAge is the password age from which a password is stale.
+	stalePasswordAge = 365 * 24 * time.Hour
+)
+
+// RiskReason is one rule that applies to an entity.
+type RiskReason struct {
+	RuleID      string `json:"rule_id"`
+	Points      int    `json:"points"`
+	Explanation string `json:"explanation"`
+}
+
+// RiskRule is one check of the risk scoring. Evaluate is called for each
+// entity of the Types and returns the reason if the rule applies, nil
+// otherwise. Rules of a type are evaluated after all entities of the types
+// before it in scoredTypes, so domain rules may look at the scores of users,
+// groups and hosts.
+type RiskRule struct {
+	ID       string
+	Title    string
+	Types    []string
+	Evaluate func(rc *RiskContext, node *engine.GraphNode) *RiskReason
+}
+
+func (r RiskRule) appliesTo(nodeType string) bool {
+	for _, t := range r.Types {
+		if t == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRules are the built-in rules. Further rules are added with
+// RiskService.Register.
+var defaultRules = []RiskRule{
+	{
+		ID:       "RP-KERBEROAST",
+		Title:    "Kerberoastable account",
+		Types:    []string{"User"},
+		Evaluate: kerberoastable,
+	},
+	{
+		ID:       "RP-ASREP",
+		Title:    "AS-REP roastable account",
+		Types:    []string{"User"},
+		Evaluate: asrepRoastable,
+	},
+	{
+		ID:       "RP-STALE-PASSWORD",
+		Title:    "Stale password",
+		Types:    []string{"User"},
+		Evaluate: stalePassword,
+	},
+	{
+		ID:       "RP-UNCONSTRAINED-DELEGATION",
+		Title:    "Unconstrained delegation",
+		Types:    []string{"User", "Host"},
+		Evaluate: unconstrainedDelegation,
+	},
+	{
+		ID:       "RP-SMBV1",
+		Title:    "SMBv1 enabled",
+		Types:    []string{"Host"},
+		Evaluate: smbv1,
+	},
+	{
+		ID:       "RP-EOL-OS",
+		Title:    "End-of-life operating system",
+		Types:    []string{"Host"},
+		Evaluate: endOfLifeOS,
+	},
+	{
+		ID:       "RP-RISKY-CAPABILITY",
+		Title:    "Holds high-risk capabilities",
+		Types:    []string{"User", "Group", "Host"},
+		Evaluate: riskyCapabilities,
+	},
+	{
+		ID:       "RP-ROASTABLE-MEMBER",
+		Title:    "Privileged group with roastable members",
+		Types:    []string{"Group"},
+		Evaluate: roastableMembers,
+	},
+	{
+		ID:       "RP-COMPROMISED",
+		Title:    "Compromised",
+		Types:    []string{"User", "Group", "Host", "Domain"},
+		Evaluate: compromised,
+	},
+	{
+		ID:       "RP-FOOTHOLD-REACH",
+		Title:    "Reachable from a foothold",
+		Types:    []string{"User", "Group", "Host", "Domain"},
+		Evaluate: reachableFromFoothold,
+	},
+	{
+		ID:       "RP-HIGH-RISK-ENTITIES",
+		Title:    "Domain contains high-risk entities",
+		Types:    []string{"Domain"},
+		Evaluate: highRiskEntities,
+	},
+}
+
+// -----------------------------------------------------------------------------
+// RiskContext
+// -----------------------------------------------------------------------------
+
+// RiskContext is what rules evaluate against: the project graph, the attack
+// graph and what was derived from both.
+type RiskContext struct {
+	Graph  *engine.ProjectGraph
+	Attack *paths.AttackGraph
+	Now    time.Time
+
+	outgoing  map[string][]*engine.GraphEdge
+	incoming  map[string][]*engine.GraphEdge
+	footholds map[string]bool
+	// uid → cheapest path from any foothold
+	reach map[string]*paths.AttackPath
+	// domain uid → entities placed below it
+	members map[string][]*engine.GraphNode
+	// capability uid → risk level, including the computed ones
+	capabilityRisk map[string]int
+	scores         map[string]*RiskEntry
+}
+
+func newRiskContext(g *paths.AttackGraph, now time.Time) *RiskContext {
+	rc := &RiskContext{
+		Graph:          g.Graph(),
+		Attack:         g,
+		Now:            now,
+		outgoing:       make(map[string][]*engine.GraphEdge),
+		incoming:       make(map[string][]*engine.GraphEdge),
+		footholds:      make(map[string]bool),
+		reach:          make(map[string]*paths.AttackPath),
+		members:        make(map[string][]*engine.GraphNode),
+		capabilityRisk: make(map[string]int),
+		scores:         make(map[string]*RiskEntry),
+	}
+	for _, e := range rc.Graph.Edges {
+		rc.outgoing[e.Subject] = append(rc.outgoing[e.Subject], e)
+		rc.incoming[e.Object] = append(rc.incoming[e.Object], e)
+	}
+	rc.indexFootholds()
+	rc.indexDomainMembers()
+	return rc
+}
+
+// Related returns the nodes uid points to via predicate.
+func (rc *RiskContext) Related(uid string, predicate core.Predicate) []*engine.GraphNode {
+	var nodes []*engine.GraphNode
+	for _, e := range rc.outgoing[uid] {
+		if e.Predicate == string(predicate) && rc.Graph.Nodes[e.Object] != nil {
+			nodes = append(nodes, rc.Graph.Nodes[e.Object])
+		}
+	}
+	return nodes
+}
+
+// Referrers returns the nodes pointing to uid via predicate.
+func (rc *RiskContext) Referrers(uid string, predicate core.Predicate) []*engine.GraphNode {
+	var nodes []*engine.GraphNode
+	for _, e := range rc.incoming[uid] {
+		if e.Predicate == string(predicate) && rc.Graph.Nodes[e.Subject] != nil {
+			nodes = append(nodes, rc.Graph.Nodes[e.Subject])
+		}
+	}
+	return nodes
+}
+
+// IsFoothold reports whether the entity is recorded as compromised.
+func (rc *RiskContext) IsFoothold(uid string) bool {
+	return rc.footholds[uid]
+}
+
+// Reach returns the cheapest attack path from a foothold to the entity, nil
+// if no foothold reaches it.
+func (rc *RiskContext) Reach(uid string) *paths.AttackPath {
+	return rc.reach[uid]
+}
+
+// DomainMembers returns the users, groups and hosts placed below a domain.
+func (rc *RiskContext) DomainMembers(domainUID string) []*engine.GraphNode {
+	return rc.members[domainUID]
+}
+
+// CapabilityRisk returns the risk level of a capability.
+func (rc *RiskContext) CapabilityRisk(uid string) int {
+	return rc.capabilityRisk[uid]
+}
+
+// Score returns the result of an entity scored before, nil if it was not.
+func (rc *RiskContext) Score(uid string) *RiskEntry {
+	return rc.scores[uid]
+}
+
+// IsPrivileged reports whether the entity is a privileged principal, a
+// domain controller or a domain.
+func IsPrivileged(n *engine.GraphNode) bool {
+	switch n.Type {
+	case "User":
+		return boolValue(n, "user.is_privileged") || boolValue(n, "user.is_domain_admin")
+	case "Group":
+		return boolValue(n, "group.is_privileged")
+	case "Host":
+		return boolValue(n, "host.is_domain_controller")
+	case "Domain":
+		return true
+	}
+	return false
+}
+
+// indexFootholds collects the objects of "compromised" assertions.
+func (rc *RiskContext) indexFootholds() {
+	for _, e := range rc.Graph.Edges {
+		if e.Predicate == string(core.PredicateCompromised) {
+			rc.footholds[e.Object] = true
+		}
+	}
+
+	uids := make([]string, 0, len(rc.footholds))
+	for uid := range rc.footholds {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	for _, foothold := range uids {
+		tree := rc.Attack.ShortestPaths(foothold, paths.ModeCheapest)
+		for uid := range rc.Graph.Nodes {
+			if rc.footholds[uid] {
+				continue
+			}
+			path := tree.PathTo(uid)
+			if path == nil {
+				continue
+			}
+			if known := rc.reach[uid]; known == nil || path.Cost < known.Cost {
+				rc.reach[uid] = path
+			}
+		}
+	}
+}
+
+// domainMemberPredicates place entities below a domain, directly or via
+// OUs and containers.
+var domainMemberPredicates = map[string]bool{
+	string(core.PredicateContains): true,
+	string(core.PredicateParent):   true,
+	string(core.PredicateHasHost):  true,
+	string(core.PredicateHasUser):  true,
+	string(core.PredicateHasGroup): true,
+}
+
+func (rc *RiskContext) indexDomainMembers() {
+	for _, domain := range rc.Graph.NodesOfType("Domain") {
+		seen := map[string]bool{domain.UID: true}
+		queue := []string{domain.UID}
+		for len(queue) > 0 {
+			uid := queue[0]
+			queue = queue[1:]
+			for _, e := range rc.outgoing[uid] {
+				child := rc.Graph.Nodes[e.Object]
+				if !domainMemberPredicates[e.Predicate] || child == nil || seen[child.UID] || child.Type == "Domain" {
+					continue
+				}
+				seen[child.UID] = true
+				queue = append(queue, child.UID)
+				switch child.Type {
+				case "User", "Group", "Host":
+					rc.members[domain.UID] = append(rc.members[domain.UID], child)
+				}
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Rules
+// -----------------------------------------------------------------------------
+
+func kerberoastable(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	if boolValue(n, "user.is_disabled") || !(boolValue(n, "user.kerberoastable") || boolValue(n, "user.has_spn")) {
+		return nil
+	}
+	if IsPrivileged(n) {
+		return &RiskReason{Points: 40, Explanation: "Privileged account with an SPN, its password can be cracked offline from a service ticket"}
+	}
+	return &RiskReason{Points: 10, Explanation: "Account with an SPN, its password can be cracked offline from a service ticket"}
+}
+
+func asrepRoastable(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	if boolValue(n, "user.is_disabled") || !boolValue(n, "user.asrep_roastable") {
+		return nil
+	}
+	if IsPrivileged(n) {
+		return &RiskReason{Points: 40, Explanation: "Privileged account without Kerberos pre-authentication, its password can be cracked offline"}
+	}
+	return &RiskReason{Points: 15, Explanation: "Account without Kerberos pre-authentication, its password can be cracked offline"}
+}
+
+func stalePassword(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	lastSet, ok := timeValue(n, "user.pwd_last_set")
+	if !ok || boolValue(n, "user.is_disabled") || rc.Now.Sub(lastSet) < stalePasswordAge {
+		return nil
+	}
+	days := int(rc.Now.Sub(lastSet).Hours() / 24)
+	if IsPrivileged(n) {
+		return &RiskReason{Points: 20, Explanation: fmt.Sprintf("Password of a privileged account unchanged for %d days", days)}
+	}
+	return &RiskReason{Points: 10, Explanation: fmt.Sprintf("Password unchanged for %d days", days)}
+}
+
+// unconstrainedDelegation flags accounts and hosts other than domain
+// controllers (which delegate unconstrained by design) that can impersonate
+// any user authenticating to them.
+func unconstrainedDelegation(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	if boolValue(n, "host.is_domain_controller") {
+		return nil
+	}
+	for _, config := range rc.Related(n.UID, core.PredicateHasDelegation) {
+		if stringValue(config, "delegation_config.delegation_type") == priv.DelegationUnconstrained {
+			return &RiskReason{Points: 30, Explanation: "Unconstrained delegation, TGTs of every user authenticating here can be stolen"}
+		}
+	}
+	return nil
+}
+
+// smbv1Markers identify SMBv1 in service and vulnerability names;
+// smbv1CVEs are the MS17-010 (EternalBlue) vulnerabilities of SMBv1.
+var (
+	smbv1Markers = []string{"smbv1", "smb1", "smb version 1", "ms17-010"}
+	smbv1CVEs    = map[string]bool{
+		"CVE-2017-0143": true, "CVE-2017-0144": true, "CVE-2017-0145": true,
+		"CVE-2017-0146": true, "CVE-2017-0147": true, "CVE-2017-0148": true,
+	}
+)
+
+func smbv1(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	mentions := func(values ...string) bool {
+		for _, v := range values {
+			v = strings.ToLower(v)
+			for _, marker := range smbv1Markers {
+				if strings.Contains(v, marker) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	vulnerabilities := rc.Related(n.UID, core.PredicateHasVulnerability)
+	for _, service := range rc.Related(n.UID, core.PredicateRuns) {
+		if mentions(stringValue(service, "service.name"), stringValue(service, "service.description")) {
+			return &RiskReason{Points: 25, Explanation: fmt.Sprintf("Service %s speaks SMBv1", nodeLabel(service))}
+		}
+		vulnerabilities = append(vulnerabilities, rc.Related(service.UID, core.PredicateHasVulnerability)...)
+	}
+	for _, vuln := range vulnerabilities {
+		name := stringValue(vuln, "vulnerability.name")
+		if mentions(name) {
+			return &RiskReason{Points: 25, Explanation: fmt.Sprintf("SMBv1 enabled (%s)", name)}
+		}
+		for _, cve := range stringsValue(vuln, "vulnerability.cves") {
+			if smbv1CVEs[strings.ToUpper(cve)] {
+				return &RiskReason{Points: 25, Explanation: fmt.Sprintf("SMBv1 vulnerable to %s", strings.ToUpper(cve))}
+			}
+		}
+	}
+	return nil
+}
+
+// eolSystems are operating systems with the end of their extended support.
+// More specific names come first, the first match wins.
+var eolSystems = []struct {
+	match string
+	ends  time.Time
+}{
+	{"windows server 2003", date(2015, 7, 14)},
+	{"windows server 2008", date(2020, 1, 14)},
+	{"windows server 2012", date(2023, 10, 10)},
+	{"windows server 2016", date(2027, 1, 12)},
+	{"windows 2000", date(2010, 7, 13)},
+	{"windows xp", date(2014, 4, 8)},
+	{"windows vista", date(2017, 4, 11)},
+	{"windows 7", date(2020, 1, 14)},
+	{"windows 8.1", date(2023, 1, 10)},
+	{"windows 8", date(2016, 1, 12)},
+	{"windows 10", date(2025, 10, 14)},
+}
+
+func endOfLifeOS(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	os := stringValue(n, "host.operating_system")
+	lower := strings.ToLower(os)
+	// long-term servicing editions are supported for years longer
+	if lower == "" || strings.Contains(lower, "ltsc") || strings.Contains(lower, "ltsb") {
+		return nil
+	}
+	for _, system := range eolSystems {
+		if !strings.Contains(lower, system.match) {
+			continue
+		}
+		if rc.Now.Before(system.ends) {
+			return nil
+		}
+		if IsPrivileged(n) {
+			return &RiskReason{Points: 35, Explanation: fmt.Sprintf("Domain controller runs %s, out of support since %s", os, system.ends.Format("2006-01-02"))}
+		}
+		return &RiskReason{Points: 20, Explanation: fmt.Sprintf("Runs %s, out of support since %s", os, system.ends.Format("2006-01-02"))}
+	}
+	return nil
+}
+
+func riskyCapabilities(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	var names []string
+	for _, capability := range rc.Related(n.UID, core.PredicateHasCapability) {
+		level := rc.CapabilityRisk(capability.UID)
+		if level < riskyCapabilityLevel {
+			continue
+		}
+		name := stringValue(capability, "capability.name")
+		if targets := rc.Related(capability.UID, core.PredicateAppliesTo); len(targets) > 0 {
+			name += " on " + nodeLabel(targets[0])
+		}
+		names = append(names, fmt.Sprintf("%s (%d)", name, level))
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return &RiskReason{Points: 20, Explanation: "Holds high-risk capabilities: " + summarize(names)}
+}
+
+func roastableMembers(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	if !IsPrivileged(n) {
+		return nil
+	}
+	var names []string
+	for _, member := range rc.Related(n.UID, core.PredicateHasMember) {
+		if member.Type != "User" || boolValue(member, "user.is_disabled") {
+			continue
+		}
+		if boolValue(member, "user.kerberoastable") || boolValue(member, "user.has_spn") || boolValue(member, "user.asrep_roastable") {
+			names = append(names, nodeLabel(member))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return &RiskReason{Points: 30, Explanation: "Roastable members: " + summarize(names)}
+}
+
+func compromised(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	if !rc.IsFoothold(n.UID) {
+		return nil
+	}
+	return &RiskReason{Points: 25, Explanation: "Recorded as compromised (foothold)"}
+}
+
+func reachableFromFoothold(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	path := rc.Reach(n.UID)
+	if path == nil || len(path.Hops) == 0 {
+		return nil
+	}
+	explanation := fmt.Sprintf("Reachable from foothold %s, path length %d, last step %s",
+		path.Hops[0].From.Label, path.Length, path.Hops[len(path.Hops)-1].Relation)
+	if IsPrivileged(n) {
+		return &RiskReason{Points: 35, Explanation: explanation}
+	}
+	return &RiskReason{Points: 15, Explanation: explanation}
+}
+
+func highRiskEntities(rc *RiskContext, n *engine.GraphNode) *RiskReason {
+	var names []string
+	for _, member := range rc.DomainMembers(n.UID) {
+		if entry := rc.Score(member.UID); entry != nil && entry.Score >= highRiskScore {
+			names = append(names, fmt.Sprintf("%s (%d)", entry.Entity.Label, entry.Score))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return &RiskReason{
+		Points:      min(10*len(names), 40),
+		Explanation: fmt.Sprintf("%d entities with a risk score of %d or more: %s", len(names), highRiskScore, summarize(names)),
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Reasons
+// -----------------------------------------------------------------------------
+
+// reasonRe parses reasons in the format of formatReason, which is also the
+// format of imported health check findings.
+var reasonRe = regexp.MustCompile(`^(\S+) \((-?\d+)\): (.*)$`)
+
+func formatReason(r *RiskReason) string {
+	return fmt.Sprintf("%s (%d): %s", r.RuleID, r.Points, r.Explanation)
+}
+
+// parseReason reads a stored reason; unknown formats count zero points.
+func parseReason(raw string) *RiskReason {
+	m := reasonRe.FindStringSubmatch(raw)
+	if m == nil {
+		return &RiskReason{Explanation: raw}
+	}
+	points := 0
+	fmt.Sscanf(m[2], "%d", &points)
+	return &RiskReason{RuleID: m[1], Points: points, Explanation: m[3]}
+}
+
+func sortReasons(reasons []*RiskReason) {
+	sort.SliceStable(reasons, func(i, j int) bool {
+		if reasons[i].Points != reasons[j].Points {
+			return reasons[i].Points > reasons[j].Points
+		}
+		return reasons[i].RuleID < reasons[j].RuleID
+	})
+}
+
+// summarize lists at most three names.
+func summarize(names []string) string {
+	if len(names) <= 3 {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:3], ", "), len(names)-3)
+}
+
+// ── Helpers ──────────────────────────────────────────────────────────────────
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func stringValue(n *engine.GraphNode, key string) string {
+	s, _ := n.Values[key].(string)
+	return s
+}
+
+func boolValue(n *engine.GraphNode, key string) bool {
+	b, _ := n.Values[key].(bool)
+	return b
+}
+
+// intValue reads a number, JSON decodes them as float64.
+func intValue(n *engine.GraphNode, key string) int {
+	f, _ := n.Values[key].(float64)
+	return int(f)
+}
+
+func stringsValue(n *engine.GraphNode, key string) []string {
+	switch v := n.Values[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// timeValue reads a datetime; unset (zero) times are reported as missing.
+func timeValue(n *engine.GraphNode, key string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, stringValue(n, key))
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func nodeLabel(n *engine.GraphNode) string {
+	for _, key := range []string{"user.sam_account_name", "security_principal.name", "host.dns_host_name",
+		"host.hostname", "host.name", "domain.dns_name", "domain.name", "service.name"} {
+		if v := stringValue(n, key); v != "" {
+			return v
+		}
+	}
+	return n.Type + " " + n.UID
+}
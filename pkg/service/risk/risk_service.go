@@ -0,0 +1,404 @@
+package risk
+
+import (
+	"RedPaths-server/internal/db"
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	"RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/service/paths"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// MaxRiskScore caps the summed points of an entity. Importers that score
+// entities themselves (PingCastle) cap at the same value.
+const MaxRiskScore = 100
+
+const (
+	defaultTopRiskLimit = 25
+	maxTopRiskLimit     = 500
+)
+
+var (
+	ErrInvalidQuery    = errors.New("invalid risk query")
+	ErrInvalidRule     = errors.New("invalid risk rule")
+	ErrInvalidFoothold = errors.New("invalid foothold")
+)
+
+// scoredTypes are scored in this order, see RiskRule.
+var scoredTypes = []string{"User", "Group", "Host", "Domain"}
+
+// riskFields are the predicates score and reasons are stored in.
+var riskFields = map[string]struct{ score, reasons string }{
+	"User":   {"user.risk_score", "user.risk_reasons"},
+	"Group":  {"group.risk_score", "group.risk_reasons"},
+	"Host":   {"host.risk_score", "host.risk_reasons"},
+	"Domain": {"domain.risk_score", "domain.risk_reasons"},
+}
+
+// footholdTypes are the entities that can be recorded as compromised.
+var footholdTypes = map[string]bool{
+	"User":     true,
+	"Group":    true,
+	"Host":     true,
+	"Computer": true,
+}
+
+// RiskEntry is the score of one entity and the reasons, highest first.
+type RiskEntry struct {
+	Entity  *paths.PathNode `json:"entity"`
+	Score   int             `json:"score"`
+	Reasons []*RiskReason   `json:"reasons"`
+}
+
+// RiskSummary reports one scoring run over a project. Capabilities counts the
+// capabilities that got a computed risk level.
+type RiskSummary struct {
+	ProjectUID   string   `json:"project_uid"`
+	Evaluated    int      `json:"evaluated"`
+	Scored       int      `json:"scored"`
+	HighRisk     int      `json:"high_risk"`
+	Footholds    int      `json:"footholds"`
+	Capabilities int      `json:"capabilities"`
+	Updated      []string `json:"updated"`
+	Failed       int      `json:"failed"`
+}
+
+// RiskQuery selects the entities of the top risks. An empty Type means all
+// scored types.
+type RiskQuery struct {
+	Type  string
+	Limit int
+}
+
+// TopRisks lists the riskiest entities of a project, riskiest first. Total
+// counts all entities with a score above zero.
+type TopRisks struct {
+	ProjectUID string       `json:"project_uid"`
+	Total      int          `json:"total"`
+	Entries    []*RiskEntry `json:"entries"`
+}
+
+// -----------------------------------------------------------------------------
+// RiskService
+// -----------------------------------------------------------------------------
+
+// RiskService scores users, groups, hosts and domains of a project with a set
+// of rules and stores score and reasons on the entities. Capabilities without
+// a risk level get one from what they apply to. Scores are recomputed after
+// imports, module runs and rollbacks.
+type RiskService struct {
+	db             *dgo.Dgraph
+	pathService    *paths.PathService
+	assertionRepo  engine.AssertionRepository
+	provenanceRepo engine.ProvenanceRepository
+	rules          []RiskRule
+}
+
+func NewRiskService(dgraphCon *dgo.Dgraph) *RiskService {
+	return &RiskService{
+		db:             dgraphCon,
+		pathService:    paths.NewPathService(dgraphCon),
+		assertionRepo:  engine.NewDgraphAssertionRepository(dgraphCon),
+		provenanceRepo: engine.NewDgraphProvenanceRepository(dgraphCon),
+		rules:          slices.Clone(defaultRules),
+	}
+}
+
+// Register adds a rule. Rules are registered at startup, before the service
+// scores anything.
+func (s *RiskService) Register(rule RiskRule) error {
+	if rule.ID == "" || rule.Evaluate == nil || len(rule.Types) == 0 {
+		return fmt.Errorf("%w: id, types and evaluate are required", ErrInvalidRule)
+	}
+	for _, t := range rule.Types {
+		if _, ok := riskFields[t]; !ok {
+			return fmt.Errorf("%w: %s cannot be scored", ErrInvalidRule, t)
+		}
+	}
+	for _, known := range s.rules {
+		if known.ID == rule.ID {
+			return fmt.Errorf("%w: rule %s already registered", ErrInvalidRule, rule.ID)
+		}
+	}
+	s.rules = append(s.rules, rule)
+	return nil
+}
+
+// Recompute scores every entity of the project and stores the scores that
+// changed.
+func (s *RiskService) Recompute(ctx context.Context, projectUID, actor string) (*RiskSummary, error) {
+	g, err := s.pathService.LoadAttackGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := newRiskContext(g, time.Now().UTC())
+	summary := &RiskSummary{ProjectUID: projectUID, Footholds: len(rc.footholds), Updated: []string{}}
+
+	for uid, level := range s.capabilityRisks(rc) {
+		err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+			return s.provenanceRepo.SetValues(ctx, tx, uid, map[string]interface{}{"capability.risk_level": level})
+		})
+		if err != nil {
+			log.Printf("[%s] Warning: storing risk level of capability %s failed: %v", actor, uid, err)
+			summary.Failed++
+			continue
+		}
+		summary.Capabilities++
+	}
+
+	for _, nodeType := range scoredTypes {
+		nodes := rc.Graph.NodesOfType(nodeType)
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].UID < nodes[j].UID })
+
+		for _, n := range nodes {
+			entry := s.score(rc, n)
+			rc.scores[n.UID] = entry
+			summary.Evaluated++
+			if entry.Score > 0 {
+				summary.Scored++
+			}
+			if entry.Score >= highRiskScore {
+				summary.HighRisk++
+			}
+
+			values := changedValues(n, entry)
+			if values == nil {
+				continue
+			}
+			err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+				return s.provenanceRepo.SetValues(ctx, tx, n.UID, values)
+			})
+			if err != nil {
+				log.Printf("[%s] Warning: storing risk score of %s %s failed: %v", actor, n.Type, n.UID, err)
+				summary.Failed++
+				continue
+			}
+			summary.Updated = append(summary.Updated, n.UID)
+		}
+	}
+
+	log.Printf("[%s] Risk scoring project=%s evaluated=%d scored=%d high_risk=%d footholds=%d capabilities=%d updated=%d failed=%d",
+		actor, projectUID, summary.Evaluated, summary.Scored, summary.HighRisk, summary.Footholds,
+		summary.Capabilities, len(summary.Updated), summary.Failed)
+
+	return summary, nil
+}
+
+// Rescore recomputes the scores after an import or run. Failing to score must
+// not fail what triggered it, so errors are only logged.
+func (s *RiskService) Rescore(ctx context.Context, projectUID, actor string) {
+	if s == nil || projectUID == "" {
+		return
+	}
+	if _, err := s.Recompute(ctx, projectUID, actor); err != nil {
+		log.Printf("[%s] Warning: risk scoring failed: %v", actor, err)
+	}
+}
+
+// TopRisks returns the stored scores of the project, riskiest first.
+func (s *RiskService) TopRisks(ctx context.Context, projectUID string, query RiskQuery) (*TopRisks, error) {
+	types := scoredTypes
+	if query.Type != "" {
+		if _, ok := riskFields[query.Type]; !ok {
+			return nil, fmt.Errorf("%w: unknown type %q (supported: %s)", ErrInvalidQuery, query.Type, strings.Join(scoredTypes, ", "))
+		}
+		types = []string{query.Type}
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultTopRiskLimit
+	}
+	query.Limit = min(query.Limit, maxTopRiskLimit)
+
+	g, err := s.pathService.LoadAttackGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*RiskEntry
+	for _, nodeType := range types {
+		fields := riskFields[nodeType]
+		for _, n := range g.Graph().NodesOfType(nodeType) {
+			score := intValue(n, fields.score)
+			if score <= 0 {
+				continue
+			}
+			entry := &RiskEntry{Entity: g.Node(n.UID), Score: score, Reasons: []*RiskReason{}}
+			for _, raw := range stringsValue(n, fields.reasons) {
+				entry.Reasons = append(entry.Reasons, parseReason(raw))
+			}
+			sortReasons(entry.Reasons)
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		if entries[i].Entity.Type != entries[j].Entity.Type {
+			return slices.Index(scoredTypes, entries[i].Entity.Type) < slices.Index(scoredTypes, entries[j].Entity.Type)
+		}
+		return entries[i].Entity.Label < entries[j].Entity.Label
+	})
+
+	result := &TopRisks{ProjectUID: projectUID, Total: len(entries), Entries: entries}
+	if len(result.Entries) > query.Limit {
+		result.Entries = result.Entries[:query.Limit]
+	}
+	if result.Entries == nil {
+		result.Entries = []*RiskEntry{}
+	}
+	return result, nil
+}
+
+// AddFoothold records an entity as compromised (Project -compromised->
+// entity) and rescores the project, so everything the entity reaches is
+// scored as reachable from a foothold.
+func (s *RiskService) AddFoothold(ctx context.Context, projectUID, entityUID, actor string) (*RiskSummary, error) {
+	g, err := s.pathService.LoadAttackGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+	n := g.Graph().Nodes[entityUID]
+	if n == nil {
+		return nil, fmt.Errorf("%w: %s", paths.ErrUnknownNode, entityUID)
+	}
+	if !footholdTypes[n.Type] {
+		return nil, fmt.Errorf("%w: %s is a %s", ErrInvalidFoothold, entityUID, n.Type)
+	}
+
+	known := false
+	for _, e := range g.Graph().Edges {
+		if e.Subject == projectUID && e.Object == entityUID && e.Predicate == string(core.PredicateCompromised) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		err := db.ExecuteInTransaction(ctx, s.db, func(tx *dgo.Txn) error {
+			_, err := s.assertionRepo.Create(ctx, tx, &core.Assertion{
+				Predicate:           core.PredicateCompromised,
+				Method:              core.MethodDirectAdd,
+				Source:              actor,
+				Confidence:          1.0,
+				Status:              core.StatusValidated,
+				Timestamp:           time.Now(),
+				HasDiscoveredParent: true,
+				Subject:             &utils.UIDRef{UID: projectUID, Type: "Project"},
+				Object:              &utils.UIDRef{UID: entityUID, Type: n.Type},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("recording foothold %s: %w", entityUID, err)
+		}
+		log.Printf("[%s] Recorded foothold %s %s in project %s", actor, n.Type, entityUID, projectUID)
+	}
+
+	return s.Recompute(ctx, projectUID, actor)
+}
+
+// score evaluates the rules of the node's type. Stored reasons of other
+// sources (e.g. imported health checks) are kept and count towards the score.
+func (s *RiskService) score(rc *RiskContext, n *engine.GraphNode) *RiskEntry {
+	entry := &RiskEntry{Entity: rc.Attack.Node(n.UID), Reasons: []*RiskReason{}}
+
+	for _, raw := range stringsValue(n, riskFields[n.Type].reasons) {
+		if reason := parseReason(raw); !s.owns(reason.RuleID) {
+			entry.Reasons = append(entry.Reasons, reason)
+		}
+	}
+	for _, rule := range s.rules {
+		if !rule.appliesTo(n.Type) {
+			continue
+		}
+		if reason := rule.Evaluate(rc, n); reason != nil {
+			reason.RuleID = rule.ID
+			entry.Reasons = append(entry.Reasons, reason)
+		}
+	}
+	sortReasons(entry.Reasons)
+
+	for _, reason := range entry.Reasons {
+		entry.Score += reason.Points
+	}
+	entry.Score = max(0, min(entry.Score, MaxRiskScore))
+	return entry
+}
+
+// owns reports whether a reason comes from a rule of this service.
+func (s *RiskService) owns(ruleID string) bool {
+	if strings.HasPrefix(ruleID, rulePrefix) {
+		return true
+	}
+	for _, rule := range s.rules {
+		if rule.ID == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilityRisks fills in the risk levels of the capabilities and returns
+// the ones that had none: capabilities on domains, domain controllers or
+// privileged principals are critical, on other hosts high.
+func (s *RiskService) capabilityRisks(rc *RiskContext) map[string]int {
+	computed := make(map[string]int)
+	for _, capability := range rc.Graph.NodesOfType("Capability") {
+		if level := intValue(capability, "capability.risk_level"); level > 0 {
+			rc.capabilityRisk[capability.UID] = level
+			continue
+		}
+		targets := rc.Related(capability.UID, core.PredicateAppliesTo)
+		if len(targets) == 0 {
+			continue
+		}
+		level := 4
+		for _, target := range targets {
+			switch {
+			case IsPrivileged(target):
+				level = max(level, 9)
+			case target.Type == "Host":
+				level = max(level, 6)
+			}
+		}
+		rc.capabilityRisk[capability.UID] = level
+		computed[capability.UID] = level
+	}
+	return computed
+}
+
+// changedValues returns the predicates to store, nil if score and reasons
+// are unchanged. A score of zero removes both.
+func changedValues(n *engine.GraphNode, entry *RiskEntry) map[string]interface{} {
+	fields := riskFields[n.Type]
+
+	reasons := make([]string, 0, len(entry.Reasons))
+	for _, reason := range entry.Reasons {
+		reasons = append(reasons, formatReason(reason))
+	}
+	stored := stringsValue(n, fields.reasons)
+
+	// lists are sets in dgraph, their order is not kept
+	sort.Strings(stored)
+	sorted := slices.Clone(reasons)
+	sort.Strings(sorted)
+	if intValue(n, fields.score) == entry.Score && slices.Equal(stored, sorted) {
+		return nil
+	}
+
+	if entry.Score == 0 && len(reasons) == 0 {
+		return map[string]interface{}{fields.score: nil, fields.reasons: nil}
+	}
+	return map[string]interface{}{fields.score: entry.Score, fields.reasons: reasons}
+}
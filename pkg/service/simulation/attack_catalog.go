@@ -0,0 +1,84 @@
+package simulation
+
+import (
+	"RedPaths-server/pkg/service/paths"
+)
+
+// attackProfile is the catalog entry of an attack technique. Cost is the
+// effort in abstract units, Noise the detection footprint (0 silent .. 1
+// alarms certain), Reliability the chance a single attempt succeeds.
+type attackProfile struct {
+	ID          string
+	Name        string
+	Cost        int
+	Noise       float64
+	Reliability float64
+}
+
+// relationAttacks maps the relations of the attack graph to the attack
+// abusing them.
+var relationAttacks = map[string]attackProfile{
+	paths.RelationMemberOf:   {"group-membership", "Use rights inherited from group membership", 0, 0, 1},
+	paths.RelationAdminTo:    {"lateral-movement-admin", "Lateral movement with local admin rights", 2, 0.4, 0.9},
+	paths.RelationHasSession: {"credential-theft-session", "Steal credentials of a logged on user", 2, 0.6, 0.8},
+
+	paths.RelationUnconstrainedDelegation: {"delegation-unconstrained", "Coerce a domain controller to an unconstrained delegation host", 3, 0.6, 0.75},
+	paths.RelationAllowedToDelegate:       {"delegation-constrained", "Impersonate a user via constrained delegation (S4U)", 2, 0.4, 0.85},
+	paths.RelationSameForestTrust:         {"trust-sid-history", "Forge a ticket with SID history across the forest (ExtraSids)", 2, 0.5, 0.9},
+	paths.RelationCrossForestTrust:        {"trust-sid-history-cross-forest", "Inject SID history over a trust without SID filtering", 3, 0.6, 0.8},
+	paths.RelationGPOAbuse:                {"gpo-abuse", "Push a malicious immediate task through an editable GPO", 3, 0.7, 0.85},
+	paths.RelationGPOLocalAdmin:           {"gpo-restricted-groups", "Use local admin rights granted by a restricted groups policy", 2, 0.4, 0.9},
+	paths.RelationGPOScheduledTask:        {"gpo-scheduled-task", "Hijack a scheduled task deployed by group policy", 2, 0.5, 0.8},
+	paths.RelationGPOUserRight:            {"gpo-user-right", "Escalate to SYSTEM with a privileged user right", 2, 0.5, 0.8},
+
+	"GenericAll":               {"acl-generic-all", "Abuse GenericAll", 1, 0.3, 0.9},
+	"AllExtendedRights":        {"acl-all-extended-rights", "Abuse AllExtendedRights", 1, 0.3, 0.9},
+	"ForceChangePassword":      {"acl-force-change-password", "Reset the password (ForceChangePassword)", 1, 0.5, 0.95},
+	"AddMember":                {"acl-add-member", "Add a member to the group", 1, 0.5, 0.95},
+	"AddSelf":                  {"acl-add-self", "Add oneself to the group", 1, 0.5, 0.95},
+	"ReadLAPSPassword":         {"acl-read-laps", "Read the LAPS password", 1, 0.1, 0.95},
+	"ReadGMSAPassword":         {"acl-read-gmsa", "Read the gMSA password", 1, 0.1, 0.95},
+	"DCSync":                   {"acl-dcsync", "Replicate secrets (DCSync)", 1, 0.7, 0.95},
+	"GetChangesAll":            {"acl-dcsync", "Replicate secrets (DCSync)", 2, 0.7, 0.9},
+	"GenericWrite":             {"acl-generic-write", "Abuse GenericWrite", 2, 0.4, 0.8},
+	"WriteDacl":                {"acl-write-dacl", "Grant oneself rights (WriteDacl)", 2, 0.6, 0.85},
+	"WriteOwner":               {"acl-write-owner", "Take ownership (WriteOwner)", 2, 0.6, 0.85},
+	"Owns":                     {"acl-owns", "Abuse ownership", 2, 0.5, 0.85},
+	"AddKeyCredentialLink":     {"acl-shadow-credentials", "Shadow credentials (AddKeyCredentialLink)", 2, 0.4, 0.85},
+	"AddAllowedToAct":          {"acl-rbcd", "Resource-based constrained delegation", 3, 0.5, 0.8},
+	"WriteAccountRestrictions": {"acl-rbcd", "Resource-based constrained delegation", 3, 0.5, 0.8},
+	"WriteSPN":                 {"acl-targeted-kerberoast", "Targeted Kerberoasting (WriteSPN)", 3, 0.3, 0.6},
+}
+
+// defaultRelationAttack is used for relations without catalog entry.
+var defaultRelationAttack = attackProfile{"relation-abuse", "Abuse relation", 2, 0.5, 0.7}
+
+// exploitAttacks maps the names of CVE derived capabilities to the exploit
+// using them. Exploits only need network access, i.e. any foothold.
+var exploitAttacks = map[string]exploitProfile{
+	"Remote Code Execution": {attackProfile{"exploit-rce", "Exploit remote code execution", 3, 0.7, 0.7}, grantsHost},
+	"SYSTEM Access":         {attackProfile{"exploit-system", "Exploit to SYSTEM", 2, 0.5, 0.8}, grantsHost},
+	"Domain Admin Access":   {attackProfile{"exploit-domain-admin", "Exploit to domain admin", 4, 0.9, 0.6}, grantsDomainAdmins},
+}
+
+type exploitGrant int
+
+const (
+	grantsHost exploitGrant = iota
+	grantsDomainAdmins
+)
+
+type exploitProfile struct {
+	attackProfile
+	Grants exploitGrant
+}
+
+func relationAttack(relation string) attackProfile {
+	if profile, ok := relationAttacks[relation]; ok {
+		return profile
+	}
+	profile := defaultRelationAttack
+	profile.ID += "-" + relation
+	profile.Name += " " + relation
+	return profile
+}
@@ -0,0 +1,421 @@
+package simulation
+
+import (
+	"RedPaths-server/internal/repository/redpaths/engine"
+	"RedPaths-server/pkg/model/core"
+	rpengine "RedPaths-server/pkg/model/engine"
+	"RedPaths-server/pkg/model/utils"
+	"RedPaths-server/pkg/service/paths"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgo/v210"
+)
+
+// defaultNoiseBudget applies when the request sets none: a few loud or many
+// quiet steps.
+const defaultNoiseBudget = 2.0
+
+var ErrInvalidRequest = errors.New("invalid simulation request")
+
+// SimulationRequest configures a run. CostBudget 0 means unlimited.
+type SimulationRequest struct {
+	Footholds      []string `json:"footholds"`
+	NoiseBudget    float64  `json:"noise_budget"`
+	CostBudget     int      `json:"cost_budget,omitempty"`
+	MinReliability float64  `json:"min_reliability,omitempty"`
+}
+
+// Compromise is a compromised entity with the record of how it fell.
+// Chain values add up from the foothold, Probability multiplies the
+// reliability of the attacks on the way.
+type Compromise struct {
+	Record      *rpengine.CompromiseRecord `json:"record"`
+	Entity      *paths.PathNode            `json:"entity"`
+	Parent      string                     `json:"parent,omitempty"`
+	Depth       int                        `json:"depth"`
+	ChainNoise  float64                    `json:"chain_noise"`
+	ChainCost   int                        `json:"chain_cost"`
+	Probability float64                    `json:"probability"`
+	Explanation string                     `json:"explanation,omitempty"`
+	Assertions  []*paths.PathAssertion     `json:"assertions,omitempty"`
+}
+
+// SimulationResult is the final state of a run and everything compromised
+// within the budget, in the order it fell.
+type SimulationResult struct {
+	ProjectUID     string                   `json:"project_uid"`
+	Footholds      []*paths.PathNode        `json:"footholds"`
+	NoiseBudget    float64                  `json:"noise_budget"`
+	CostBudget     int                      `json:"cost_budget,omitempty"`
+	MinReliability float64                  `json:"min_reliability,omitempty"`
+	State          *rpengine.AttackState    `json:"state"`
+	Attacks        []*rpengine.Attack       `json:"attacks"`
+	Results        []*rpengine.AttackResult `json:"results"`
+	Compromises    []*Compromise            `json:"compromises"`
+	OverBudget     int                      `json:"over_budget"`
+	Unreliable     int                      `json:"unreliable"`
+}
+
+// -----------------------------------------------------------------------------
+// SimulationService
+// -----------------------------------------------------------------------------
+
+// SimulationService plays an attacker through the attack graph of a project.
+// Starting from the footholds it repeatedly executes the quietest attack
+// whose required capability (control of its source) is held, until no attack
+// fits into the noise and cost budget any more. Simulations are not stored.
+type SimulationService struct {
+	pathService *paths.PathService
+}
+
+func NewSimulationService(dgraphCon *dgo.Dgraph) *SimulationService {
+	return &SimulationService{
+		pathService: paths.NewPathService(dgraphCon),
+	}
+}
+
+func (s *SimulationService) Simulate(ctx context.Context, projectUID string, req SimulationRequest) (*SimulationResult, error) {
+	if len(req.Footholds) == 0 {
+		return nil, fmt.Errorf("%w: at least one foothold is required", ErrInvalidRequest)
+	}
+	if req.NoiseBudget < 0 || req.CostBudget < 0 || req.MinReliability < 0 || req.MinReliability > 1 {
+		return nil, fmt.Errorf("%w: budgets must be positive, min_reliability within 0..1", ErrInvalidRequest)
+	}
+	if req.NoiseBudget == 0 {
+		req.NoiseBudget = defaultNoiseBudget
+	}
+
+	g, err := s.pathService.LoadAttackGraph(ctx, projectUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := newSimulation(g, req, time.Now().UTC())
+	for _, uid := range req.Footholds {
+		if g.Node(uid) == nil {
+			return nil, fmt.Errorf("%w: foothold %s", paths.ErrUnknownNode, uid)
+		}
+		sim.foothold(uid)
+	}
+	sim.queueExploits()
+	sim.run()
+
+	log.Printf("[Simulation] project=%s footholds=%d compromised=%d noise=%.2f/%.2f cost=%d over_budget=%d",
+		projectUID, len(req.Footholds), len(sim.result.Compromises), sim.state.TotalNoise, req.NoiseBudget,
+		sim.state.TotalCost, sim.result.OverBudget)
+
+	return sim.result, nil
+}
+
+// -----------------------------------------------------------------------------
+// Simulation
+// -----------------------------------------------------------------------------
+
+// candidate is an attack whose requirements are met. parent is the record of
+// the entity it is launched from.
+type candidate struct {
+	profile     attackProfile
+	parent      *Compromise
+	target      string
+	grantedBy   *utils.UIDRef // capability node of exploits
+	explanation string
+	assertions  []*engine.GraphEdge
+	seq         int
+}
+
+type simulation struct {
+	graph  *paths.AttackGraph
+	req    SimulationRequest
+	now    time.Time
+	state  *rpengine.AttackState
+	result *SimulationResult
+
+	held  map[string]*Compromise // by entity uid
+	queue candidateQueue
+	seq   int
+}
+
+func newSimulation(g *paths.AttackGraph, req SimulationRequest, now time.Time) *simulation {
+	state := &rpengine.AttackState{
+		UID:       "_:state",
+		DType:     []string{"AttackState"},
+		Timestamp: now,
+	}
+	return &simulation{
+		graph: g,
+		req:   req,
+		now:   now,
+		state: state,
+		result: &SimulationResult{
+			ProjectUID:     g.Graph().ProjectUID,
+			NoiseBudget:    req.NoiseBudget,
+			CostBudget:     req.CostBudget,
+			MinReliability: req.MinReliability,
+			State:          state,
+			Attacks:        []*rpengine.Attack{},
+			Results:        []*rpengine.AttackResult{},
+			Compromises:    []*Compromise{},
+		},
+		held: make(map[string]*Compromise),
+	}
+}
+
+// foothold marks an entity as initially controlled.
+func (s *simulation) foothold(uid string) {
+	if s.held[uid] != nil {
+		return
+	}
+	node := s.graph.Node(uid)
+	s.result.Footholds = append(s.result.Footholds, node)
+	s.compromise(uid, nil, nil, 0, 0, 1, "Initial foothold", nil)
+}
+
+// queueExploits adds the exploits of CVE derived capabilities; they only
+// need network access, which any foothold provides.
+func (s *simulation) queueExploits() {
+	if len(s.result.Compromises) == 0 {
+		return
+	}
+	origin := s.result.Compromises[0]
+	pg := s.graph.Graph()
+
+	hostsOf := exploitTargets(pg)
+	domainAdmins, _ := s.graph.Targets(paths.TargetDomainAdmins)
+
+	for _, e := range pg.Edges {
+		capNode := pg.Nodes[e.Object]
+		if e.Predicate != string(core.PredicateDerives) || capNode == nil || capNode.Type != "Capability" {
+			continue
+		}
+		name, _ := capNode.Values["capability.name"].(string)
+		profile, ok := exploitAttacks[name]
+		if !ok {
+			continue
+		}
+
+		var targets []string
+		switch profile.Grants {
+		case grantsHost:
+			targets = hostsOf[e.Subject]
+		case grantsDomainAdmins:
+			for _, n := range domainAdmins {
+				targets = append(targets, n.UID)
+			}
+		}
+		for _, target := range targets {
+			s.push(&candidate{
+				profile:     profile.attackProfile,
+				parent:      origin,
+				target:      target,
+				grantedBy:   &utils.UIDRef{UID: capNode.UID, Type: "Capability"},
+				explanation: fmt.Sprintf("%s on %s", name, s.graph.Node(target).Label),
+				assertions:  []*engine.GraphEdge{e},
+			})
+		}
+	}
+}
+
+// exploitTargets resolves the subjects of derives assertions (Host, Service,
+// Vulnerability) to the hosts an exploit would land on.
+func exploitTargets(pg *engine.ProjectGraph) map[string][]string {
+	serviceHost := make(map[string]string)
+	for _, e := range pg.Edges {
+		if e.Predicate == string(core.PredicateRuns) {
+			serviceHost[e.Object] = e.Subject
+		}
+	}
+	hostOf := func(uid string) string {
+		if n := pg.Nodes[uid]; n != nil && n.Type == "Host" {
+			return uid
+		}
+		return serviceHost[uid]
+	}
+
+	targets := make(map[string][]string)
+	for uid, n := range pg.Nodes {
+		if host := hostOf(uid); host != "" && n.Type != "Vulnerability" {
+			targets[uid] = []string{host}
+		}
+	}
+	for _, e := range pg.Edges {
+		if e.Predicate != string(core.PredicateHasVulnerability) {
+			continue
+		}
+		if host := hostOf(e.Subject); host != "" {
+			targets[e.Object] = append(targets[e.Object], host)
+		}
+	}
+	return targets
+}
+
+func (s *simulation) push(c *candidate) {
+	if s.held[c.target] != nil {
+		return
+	}
+	s.seq++
+	c.seq = s.seq
+	heap.Push(&s.queue, c)
+}
+
+// queueFrom adds the attacks launched from a freshly compromised entity.
+func (s *simulation) queueFrom(from *Compromise) {
+	for _, e := range s.graph.Out[from.Entity.UID] {
+		s.push(&candidate{
+			profile:     relationAttack(e.Relation),
+			parent:      from,
+			target:      e.To,
+			explanation: e.Explanation,
+			assertions:  e.Assertions,
+		})
+	}
+}
+
+// run executes the quietest applicable attack until none fits the budget.
+// The budget only shrinks, so an attack that does not fit is dropped.
+func (s *simulation) run() {
+	for s.queue.Len() > 0 {
+		c := heap.Pop(&s.queue).(*candidate)
+		if s.held[c.target] != nil {
+			continue
+		}
+		if c.profile.Reliability < s.req.MinReliability {
+			s.result.Unreliable++
+			continue
+		}
+		if s.state.TotalNoise+c.profile.Noise > s.req.NoiseBudget ||
+			(s.req.CostBudget > 0 && s.state.TotalCost+c.profile.Cost > s.req.CostBudget) {
+			s.result.OverBudget++
+			continue
+		}
+		s.execute(c)
+	}
+
+	sort.Slice(s.state.HasCapability, func(i, j int) bool {
+		return s.state.HasCapability[i].UID < s.state.HasCapability[j].UID
+	})
+}
+
+func (s *simulation) execute(c *candidate) {
+	attack := &rpengine.Attack{
+		UID:         fmt.Sprintf("_:attack-%d", len(s.result.Attacks)+1),
+		DType:       []string{"Attack"},
+		AttackID:    c.profile.ID,
+		Name:        c.profile.Name,
+		Cost:        c.profile.Cost,
+		Noise:       c.profile.Noise,
+		Reliability: c.profile.Reliability,
+		Requires:    []*utils.UIDRef{s.ref(c.parent.Entity.UID)},
+		Grants:      []*utils.UIDRef{s.ref(c.target)},
+		Targets:     []*utils.UIDRef{s.ref(c.target)},
+	}
+	if c.grantedBy != nil {
+		attack.Requires = append(attack.Requires, c.grantedBy)
+	}
+	s.result.Attacks = append(s.result.Attacks, attack)
+
+	result := &rpengine.AttackResult{
+		UID:            fmt.Sprintf("_:result-%d", len(s.result.Results)+1),
+		DType:          []string{"AttackResult"},
+		Success:        true,
+		NoiseGenerated: c.profile.Noise,
+		CreatedAt:      s.now,
+		ExecutedAttack: &utils.UIDRef{UID: attack.UID, Type: "Attack"},
+		Compromised:    s.ref(c.target),
+	}
+	s.result.Results = append(s.result.Results, result)
+	s.state.HasHistory = append(s.state.HasHistory, &utils.UIDRef{UID: result.UID, Type: "AttackResult"})
+	s.state.TotalNoise += c.profile.Noise
+	s.state.TotalCost += c.profile.Cost
+
+	s.compromise(c.target, c.parent, &utils.UIDRef{UID: attack.UID, Type: "Attack"},
+		c.profile.Noise, c.profile.Cost, c.profile.Reliability, c.explanation, c.assertions)
+}
+
+// compromise records the entity as held and queues the attacks it enables.
+func (s *simulation) compromise(
+	uid string,
+	parent *Compromise,
+	via *utils.UIDRef,
+	noise float64,
+	cost int,
+	reliability float64,
+	explanation string,
+	assertions []*engine.GraphEdge,
+) {
+	node := s.graph.Node(uid)
+	record := &rpengine.CompromiseRecord{
+		UID:            fmt.Sprintf("_:record-%d", len(s.result.Compromises)+1),
+		DType:          []string{"CompromiseRecord"},
+		CompromisedAt:  s.now,
+		NoiseGenerated: noise,
+		Cost:           cost,
+		ViaAttack:      via,
+	}
+	if node.Type == "Host" {
+		record.CompromisedHost = s.ref(uid)
+	} else {
+		record.CompromisedPrincipal = s.ref(uid)
+	}
+
+	compromise := &Compromise{
+		Record:      record,
+		Entity:      node,
+		ChainNoise:  noise,
+		ChainCost:   cost,
+		Probability: reliability,
+		Explanation: explanation,
+	}
+	for _, a := range assertions {
+		compromise.Assertions = append(compromise.Assertions, paths.NewPathAssertion(a))
+	}
+	if parent != nil {
+		parent.Record.ParentOf = append(parent.Record.ParentOf, &utils.UIDRef{UID: record.UID, Type: "CompromiseRecord"})
+		compromise.Parent = parent.Record.UID
+		compromise.Depth = parent.Depth + 1
+		compromise.ChainNoise += parent.ChainNoise
+		compromise.ChainCost += parent.ChainCost
+		compromise.Probability *= parent.Probability
+	}
+
+	s.held[uid] = compromise
+	s.result.Compromises = append(s.result.Compromises, compromise)
+	s.state.HasCapability = append(s.state.HasCapability, s.ref(uid))
+
+	s.queueFrom(compromise)
+}
+
+func (s *simulation) ref(uid string) *utils.UIDRef {
+	return &utils.UIDRef{UID: uid, Type: s.graph.Node(uid).Type}
+}
+
+// candidateQueue orders by noise, then cost, then reliability (descending);
+// ties keep the order the attacks became available.
+type candidateQueue []*candidate
+
+func (q candidateQueue) Len() int { return len(q) }
+func (q candidateQueue) Less(i, j int) bool {
+	a, b := q[i].profile, q[j].profile
+	switch {
+	case a.Noise != b.Noise:
+		return a.Noise < b.Noise
+	case a.Cost != b.Cost:
+		return a.Cost < b.Cost
+	case a.Reliability != b.Reliability:
+		return a.Reliability > b.Reliability
+	}
+	return q[i].seq < q[j].seq
+}
+func (q candidateQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *candidateQueue) Push(x any)   { *q = append(*q, x.(*candidate)) }
+func (q *candidateQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
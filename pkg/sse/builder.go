@@ -26,5 +26,8 @@ func (eb *EventBuilder) WithPayload(payload map[string]interface{}) *EventBuilde
 }
 
 func (eb *EventBuilder) Log(logger *SSELogger) {
+	if logger == nil {
+		return
+	}
 	logger.Event(eb.eventType, eb.payload)
 }